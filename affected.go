@@ -0,0 +1,88 @@
+package xdb
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoRowsAffected is matched by RowsAffectedError with errors.Is,
+// when the statement did not affect any rows,
+// for example the row of UPDATE or DELETE is not found
+var ErrNoRowsAffected = errors.New("no rows affected")
+
+// RowsAffectedError is returned by MustAffect,
+// when the statement did not affect the expected number of rows
+type RowsAffectedError struct {
+	// Expected is the expected number of rows
+	Expected int64
+	// Actual is the number of rows reported by the driver
+	Actual int64
+}
+
+func (e *RowsAffectedError) Error() string {
+	if e.Actual == 0 {
+		return fmt.Sprintf("no rows affected, expected %d", e.Expected)
+	}
+	return fmt.Sprintf("%d rows affected, expected %d", e.Actual, e.Expected)
+}
+
+// Is returns true for ErrNoRowsAffected, if no rows were affected
+func (e *RowsAffectedError) Is(target error) bool {
+	return target == ErrNoRowsAffected && e.Actual == 0
+}
+
+/*
+RowsAffected returns the number of rows affected by the statement,
+the result of Exec can be passed as is:
+
+	n, err := xdb.RowsAffected(p.ExecContext(ctx, query, args...))
+*/
+func RowsAffected(res sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return n, nil
+}
+
+/*
+MustAffect returns RowsAffectedError if the statement did not affect exactly n rows:
+
+	res, err := p.ExecContext(ctx, "DELETE FROM org WHERE id = ?", id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err = xdb.MustAffect(res, 1); errors.Is(err, xdb.ErrNoRowsAffected) {
+		// not found
+	}
+
+Note that MySQL reports the rows that were actually changed by UPDATE,
+unless clientFoundRows=true is set in DSN.
+*/
+func MustAffect(res sql.Result, n int64) error {
+	actual, err := RowsAffected(res, nil)
+	if err != nil {
+		return err
+	}
+	if actual != n {
+		return errors.WithStack(&RowsAffectedError{Expected: n, Actual: actual})
+	}
+	return nil
+}
+
+// MustAffectAtLeast returns RowsAffectedError if the statement affected less than n rows
+func MustAffectAtLeast(res sql.Result, n int64) error {
+	actual, err := RowsAffected(res, nil)
+	if err != nil {
+		return err
+	}
+	if actual < n {
+		return errors.WithStack(&RowsAffectedError{Expected: n, Actual: actual})
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package xdb_test
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowsAffected(t *testing.T) {
+	n, err := xdb.RowsAffected(driver.RowsAffected(3), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	_, err = xdb.RowsAffected(nil, errors.New("exec failed"))
+	assert.EqualError(t, err, "exec failed")
+
+	_, err = xdb.RowsAffected(driver.ResultNoRows, nil)
+	assert.Error(t, err)
+}
+
+func TestMustAffect(t *testing.T) {
+	assert.NoError(t, xdb.MustAffect(driver.RowsAffected(1), 1))
+
+	err := xdb.MustAffect(driver.RowsAffected(0), 1)
+	assert.EqualError(t, err, "no rows affected, expected 1")
+	assert.True(t, errors.Is(err, xdb.ErrNoRowsAffected))
+	var rerr *xdb.RowsAffectedError
+	require.True(t, errors.As(err, &rerr))
+	assert.Equal(t, int64(1), rerr.Expected)
+
+	err = xdb.MustAffect(driver.RowsAffected(2), 1)
+	assert.EqualError(t, err, "2 rows affected, expected 1")
+	assert.False(t, errors.Is(err, xdb.ErrNoRowsAffected))
+
+	assert.NoError(t, xdb.MustAffectAtLeast(driver.RowsAffected(2), 1))
+	err = xdb.MustAffectAtLeast(driver.RowsAffected(0), 1)
+	assert.True(t, errors.Is(err, xdb.ErrNoRowsAffected))
+
+	assert.Error(t, xdb.MustAffect(driver.ResultNoRows, 1))
+
+	// stale row is classified as no rows affected
+	assert.True(t, errors.Is(&xdb.StaleRowError{Table: "org", Key: 1}, xdb.ErrNoRowsAffected))
+}
@@ -0,0 +1,119 @@
+package xdb
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// ArrayElement constrains the element types of Array
+type ArrayElement interface {
+	~string | ~int64 | ~int32 | ~int16 | ~float64 | ~float32 | ~bool
+}
+
+// Array de/encodes the slice to/from Postgres array column,
+// the providers without array types store the slice as JSON array.
+// Empty array is stored as NULL, and the value is marshaled to JSON as array.
+type Array[T ArrayElement] []T
+
+// StringArray represents text[] and varchar[] columns
+type StringArray = Array[string]
+
+// Scan implements the Scanner interface.
+// The value can be Postgres array, or JSON array.
+func (n *Array[T]) Scan(value any) error {
+	*n = nil
+	if value == nil {
+		return nil
+	}
+
+	var s string
+	switch vid := value.(type) {
+	case []byte:
+		s = strings.TrimSpace(string(vid))
+	case string:
+		s = strings.TrimSpace(vid)
+	default:
+		return errors.Errorf("unsupported scan type: %T", value)
+	}
+	if strings.HasPrefix(s, "[") {
+		var res Array[T]
+		if err := json.Unmarshal([]byte(s), &res); err != nil {
+			return errors.Wrap(err, "failed to scan array")
+		}
+		if len(res) > 0 {
+			*n = res
+		}
+		return nil
+	}
+
+	var list pq.StringArray
+	if err := list.Scan(s); err != nil {
+		return errors.Wrap(err, "failed to scan array")
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	res := make(Array[T], len(list))
+	for i, val := range list {
+		if err := parseArrayElement(reflect.ValueOf(&res[i]).Elem(), val); err != nil {
+			return errors.WithMessagef(err, "failed to scan array element %d", i)
+		}
+	}
+	*n = res
+	return nil
+}
+
+func parseArrayElement(v reflect.Value, val string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Int64, reflect.Int32, reflect.Int16:
+		i, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.SetInt(i)
+	case reflect.Float64, reflect.Float32:
+		f, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.SetBool(b)
+	}
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (n Array[T]) Value() (driver.Value, error) {
+	if len(n) == 0 {
+		return nil, nil
+	}
+	value, err := pq.GenericArray{A: []T(n)}.Value()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get array value")
+	}
+	return value, nil
+}
+
+// JSON returns JSON array of the values,
+// to be used with the providers without array types, like SQL Server.
+func (n Array[T]) JSON() string {
+	if len(n) == 0 {
+		return "[]"
+	}
+	js, _ := json.Marshal([]T(n))
+	return string(js)
+}
@@ -0,0 +1,91 @@
+package xdb_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrayScan(t *testing.T) {
+	var s xdb.StringArray
+	require.NoError(t, s.Scan(`{a,"b c"}`))
+	assert.Equal(t, xdb.StringArray{"a", "b c"}, s)
+	assert.Error(t, s.Scan(`{a,NULL}`))
+	require.NoError(t, s.Scan([]byte(`["x","y"]`)))
+	assert.Equal(t, xdb.StringArray{"x", "y"}, s)
+	require.NoError(t, s.Scan("{}"))
+	assert.Nil(t, s)
+	require.NoError(t, s.Scan("[]"))
+	assert.Nil(t, s)
+	require.NoError(t, s.Scan(nil))
+	assert.Nil(t, s)
+
+	var i64 xdb.Array[int64]
+	require.NoError(t, i64.Scan("{1,-2,3}"))
+	assert.Equal(t, xdb.Array[int64]{1, -2, 3}, i64)
+	assert.EqualError(t, i64.Scan("{1,x}"), `failed to scan array element 1: strconv.ParseInt: parsing "x": invalid syntax`)
+
+	var i16 xdb.Array[int16]
+	assert.Error(t, i16.Scan("{70000}"))
+
+	var f xdb.Array[float64]
+	require.NoError(t, f.Scan("{1.5,2}"))
+	assert.Equal(t, xdb.Array[float64]{1.5, 2}, f)
+	assert.Error(t, f.Scan("{a}"))
+
+	var b xdb.Array[bool]
+	require.NoError(t, b.Scan("{t,f}"))
+	assert.Equal(t, xdb.Array[bool]{true, false}, b)
+	assert.Error(t, b.Scan("{x}"))
+
+	assert.Error(t, s.Scan("x"))
+	assert.Error(t, s.Scan("[1"))
+	assert.Error(t, s.Scan(42))
+}
+
+func TestArrayValue(t *testing.T) {
+	v, err := xdb.StringArray{}.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = xdb.StringArray{"a", "b c"}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"a","b c"}`, v)
+
+	v, err = xdb.Array[int32]{1, 2}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{1,2}`, v)
+
+	assert.Equal(t, "[]", xdb.StringArray{}.JSON())
+	assert.Equal(t, `["a","b"]`, xdb.StringArray{"a", "b"}.JSON())
+
+	type model struct {
+		Tags xdb.StringArray  `json:"tags"`
+		Nums xdb.Array[int16] `json:"nums,omitempty"`
+	}
+	js, err := json.Marshal(model{Tags: xdb.StringArray{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"tags":["a"]}`, string(js))
+}
+
+func TestBindArray(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE doc (id INTEGER PRIMARY KEY, tags TEXT)")
+	require.NoError(t, err)
+
+	tags := xdb.StringArray{"a", "b"}
+	_, err = p.ExecContext(ctx, "INSERT INTO doc (id, tags) VALUES (?, ?), (?, ?)", 1, tags, 2, xdb.StringArray{})
+	require.NoError(t, err)
+
+	var res xdb.StringArray
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT tags FROM doc WHERE id = ?", 1).Scan(&res))
+	assert.Equal(t, tags, res)
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT tags FROM doc WHERE id = ?", 2).Scan(&res))
+	assert.Nil(t, res)
+}
@@ -0,0 +1,97 @@
+package xdb
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+type backendPIDKey struct{}
+
+// BackendPIDCallback is called with backend process ID of the connection,
+// that is bound to a transaction
+type BackendPIDCallback func(pid int64)
+
+// WithBackendPIDCallback returns a context with a callback,
+// which is invoked by BeginTx with the backend process ID of the transaction connection.
+// The ID can be used by admin tooling to cancel runaway queries with CancelBackend.
+func WithBackendPIDCallback(ctx context.Context, cb BackendPIDCallback) context.Context {
+	return context.WithValue(ctx, backendPIDKey{}, cb)
+}
+
+func backendPIDCallback(ctx context.Context) BackendPIDCallback {
+	if cb, ok := ctx.Value(backendPIDKey{}).(BackendPIDCallback); ok {
+		return cb
+	}
+	return nil
+}
+
+// BackendPID returns the backend process ID of the connection:
+// pg_backend_pid() for Postgres, or @@SPID for SQL Server.
+// Note that without transaction, database/sql may use any connection from the pool
+// for the next statement, use BeginTx to pin the connection.
+func (p *SQLProvider) BackendPID(ctx context.Context) (int64, error) {
+	var query string
+	switch p.name {
+	case "postgres":
+		query = "SELECT pg_backend_pid()"
+	case "sqlserver":
+		query = "SELECT @@SPID"
+	default:
+		return 0, errors.Errorf("backend PID is not supported by %s", p.name)
+	}
+
+	var pid int64
+	err := p.db.QueryRowContext(ctx, query).Scan(&pid)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return pid, nil
+}
+
+// CancelBackend cancels the query running by the backend process:
+// pg_cancel_backend for Postgres, or KILL for SQL Server
+func (p *SQLProvider) CancelBackend(ctx context.Context, pid int64) error {
+	if pid <= 0 {
+		return errors.Errorf("invalid backend PID: %d", pid)
+	}
+
+	switch p.name {
+	case "postgres":
+		var ok bool
+		err := p.db.QueryRowContext(ctx, "SELECT pg_cancel_backend($1)", pid).Scan(&ok)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if !ok {
+			return errors.Errorf("failed to cancel backend: %d", pid)
+		}
+	case "sqlserver":
+		// KILL does not accept parameters
+		_, err := p.db.ExecContext(ctx, "KILL "+strconv.FormatInt(pid, 10))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	default:
+		return errors.Errorf("cancel backend is not supported by %s", p.name)
+	}
+
+	logger.KV(xlog.NOTICE, "reason", "cancel_backend", "pid", pid)
+	return nil
+}
+
+// notifyBackendPID invokes the callback from context, if provided
+func (p *SQLProvider) notifyBackendPID(ctx context.Context) {
+	cb := backendPIDCallback(ctx)
+	if cb == nil {
+		return
+	}
+	pid, err := p.BackendPID(ctx)
+	if err != nil {
+		logger.KV(xlog.DEBUG, "reason", "backend_pid", "err", err.Error())
+		return
+	}
+	cb(pid)
+}
@@ -0,0 +1,30 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendPID(t *testing.T) {
+	p := openSQLite(t)
+
+	called := false
+	ctx := xdb.WithBackendPIDCallback(context.Background(), func(pid int64) {
+		called = true
+	})
+
+	_, err := p.BackendPID(ctx)
+	assert.EqualError(t, err, "backend PID is not supported by sqlite3")
+
+	assert.EqualError(t, p.CancelBackend(ctx, 0), "invalid backend PID: 0")
+	assert.EqualError(t, p.CancelBackend(ctx, 123), "cancel backend is not supported by sqlite3")
+
+	tx, err := p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+	assert.False(t, called)
+}
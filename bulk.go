@@ -0,0 +1,148 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// maxSQLServerRows is the maximum number of row values in INSERT statement on SQL Server
+const maxSQLServerRows = 1000
+
+// BulkInsertOptions specifies options of BulkInsert
+type BulkInsertOptions struct {
+	// BatchSize limits the number of rows per INSERT statement,
+	// by default the batches are split by the parameters limit of the dialect
+	BatchSize int
+	// UseCopy specifies to use COPY FROM on Postgres,
+	// that is faster for large volumes.
+	// It's ignored by other dialects.
+	UseCopy bool
+}
+
+// preparer is implemented by sql.Tx and sql.Conn
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// BulkInsert inserts rows with a multi-row INSERT statement per batch,
+// batches are split by the parameters limit of the dialect, 65535 for Postgres
+// and 2100 for SQL Server, or by opts.BatchSize.
+// rows must be structs or pointers to structs with db tags for every column of the table.
+// With opts.UseCopy the rows are copied with COPY FROM on Postgres,
+// in the transaction of db, or in a new one if db is a Provider without transaction.
+// Use a transaction to apply all batches atomically.
+// Returns the number of inserted rows as reported by the driver.
+func BulkInsert[T any](ctx context.Context, db DB, t UpsertTable, rows []T, opts *BulkInsertOptions) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if opts == nil {
+		opts = &BulkInsertOptions{}
+	}
+
+	columns := t.ColumnNames()
+	values, err := upsertValues(columns, nil, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	dialect := t.SQLDialect()
+	if dialect == nil {
+		dialect = xsql.NoDialect
+	}
+	if opts.UseCopy && dialect.Provider() == "postgres" {
+		return copyIn(ctx, db, t.TableName(), columns, values)
+	}
+
+	maxParams := dialect.Capabilities().MaxParams
+	if maxParams == 0 {
+		maxParams = defaultMaxParams
+	}
+	batchSize := max(maxParams/len(columns), 1)
+	if dialect.Provider() == "sqlserver" {
+		batchSize = min(batchSize, maxSQLServerRows)
+	}
+	if opts.BatchSize > 0 {
+		batchSize = min(batchSize, opts.BatchSize)
+	}
+
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	var total int64
+	for start := 0; start < len(values); start += batchSize {
+		end := min(start+batchSize, len(values))
+		batch := values[start:end]
+
+		query := "INSERT INTO " + t.TableName() + " (" + strings.Join(columns, ", ") + ") VALUES " +
+			strings.TrimSuffix(strings.Repeat(row+", ", len(batch)), ", ")
+		var args []any
+		for _, v := range batch {
+			args = append(args, v...)
+		}
+
+		q := dialect.New(query, args...)
+		res, err := q.Exec(ctx, db)
+		q.Close()
+		if err != nil {
+			return total, errors.WithMessagef(err, "failed to insert %d rows into %s", len(batch), t.TableName())
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			total += n
+		}
+	}
+	return total, nil
+}
+
+// copyIn copies the rows with COPY FROM statement,
+// that requires a transaction
+func copyIn(ctx context.Context, db DB, table string, columns []string, values [][]any) (int64, error) {
+	p, ok := db.(Provider)
+	if ok && p.Tx() == nil {
+		var n int64
+		err := p.WithTx(ctx, nil, func(ctx context.Context, tx Provider) error {
+			var err error
+			n, err = copyIn(ctx, tx, table, columns, values)
+			return err
+		})
+		return n, err
+	}
+
+	var tx preparer
+	if ok {
+		tx, _ = p.Tx().(preparer)
+	} else if _, isDB := db.(*sql.DB); !isDB {
+		tx, _ = db.(preparer)
+	}
+	if tx == nil {
+		return 0, errors.Errorf("COPY requires a transaction: %T", db)
+	}
+
+	query := pq.CopyIn(table, columns...)
+	if schemaName, tableName, ok := strings.Cut(table, "."); ok {
+		query = pq.CopyInSchema(schemaName, tableName, columns...)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed to copy into %s", table)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	for _, v := range values {
+		if _, err = stmt.ExecContext(ctx, v...); err != nil {
+			return 0, errors.WithMessagef(err, "failed to copy into %s", table)
+		}
+	}
+	// flush the buffered rows
+	res, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed to copy into %s", table)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
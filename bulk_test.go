@@ -0,0 +1,67 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/schema"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkInsert(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE settings (org_id INTEGER, name TEXT, value TEXT)")
+	require.NoError(t, err)
+
+	ti := &schema.TableInfo{
+		Name:    "settings",
+		Columns: []string{"org_id", "name", "value"},
+		Dialect: xsql.NoDialect,
+	}
+
+	n, err := xdb.BulkInsert[setting](ctx, p, ti, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	var rows []setting
+	for i := 0; i < 500; i++ {
+		rows = append(rows, setting{OrgID: int64(i), Name: "n", Value: "v"})
+	}
+	// 500 rows * 3 columns are split into 2 batches by 999 parameters
+	n, err = xdb.BulkInsert(ctx, p, ti, rows, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), n)
+
+	n, err = xdb.BulkInsert(ctx, p, ti, rows[:5], &xdb.BulkInsertOptions{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	var count int
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT COUNT(*) FROM settings").Scan(&count))
+	assert.Equal(t, 505, count)
+
+	// COPY is ignored by other dialects
+	n, err = xdb.BulkInsert(ctx, p, ti, rows[:1], &xdb.BulkInsertOptions{UseCopy: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	_, err = xdb.BulkInsert(ctx, p, &schema.TableInfo{Name: "settings", Columns: []string{"missing"}}, rows, nil)
+	assert.EqualError(t, err, "column missing is not mapped in xdb_test.setting")
+
+	pgti := &schema.TableInfo{
+		SchemaName: "public.settings",
+		Name:       "settings",
+		Columns:    []string{"org_id", "name", "value"},
+		Dialect:    xsql.Postgres,
+	}
+	_, err = xdb.BulkInsert(ctx, p.DB(), pgti, rows, &xdb.BulkInsertOptions{UseCopy: true})
+	assert.EqualError(t, err, "COPY requires a transaction: *sql.DB")
+	// COPY is not supported by SQLite
+	_, err = xdb.BulkInsert(ctx, p, pgti, rows, &xdb.BulkInsertOptions{UseCopy: true})
+	assert.ErrorContains(t, err, `failed to copy into public.settings`)
+}
@@ -0,0 +1,264 @@
+package xdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// CacheTable defines a read-only table derived from the SQL query,
+// for example aggregated reports.
+// The table is created by a migration with MigrationUp,
+// and refreshed by CacheRefresher on schedule.
+// Read models for the table are generated as for any other table.
+type CacheTable struct {
+	// Name of the table in schema.name format
+	Name string `json:"name" yaml:"name"`
+	// Query is SELECT statement to populate the table
+	Query string `json:"query" yaml:"query"`
+	// Interval specifies how often the table is refreshed
+	Interval time.Duration `json:"interval" yaml:"interval"`
+}
+
+// LoadCacheTables loads the definitions of cache tables from YAML or JSON file
+func LoadCacheTables(file string) ([]*CacheTable, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load cache tables")
+	}
+	var list []*CacheTable
+	if err = yaml.Unmarshal(data, &list); err != nil {
+		return nil, errors.WithMessagef(err, "failed to load cache tables %s", file)
+	}
+	for i, t := range list {
+		if t == nil || t.Name == "" || t.Query == "" {
+			return nil, errors.Errorf("name and query must be specified in cache table %d", i)
+		}
+	}
+	return list, nil
+}
+
+// MigrationUp returns the statement to create the table from the query
+func (t *CacheTable) MigrationUp(provider string) string {
+	query := strings.TrimRight(strings.TrimSpace(t.Query), ";")
+	if provider == "sqlserver" {
+		return fmt.Sprintf("SELECT * INTO %s FROM (%s) AS src;", t.Name, query)
+	}
+	return fmt.Sprintf("CREATE TABLE %s AS %s;", t.Name, query)
+}
+
+// MigrationDown returns the statement to drop the table
+func (t *CacheTable) MigrationDown() string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", t.Name)
+}
+
+// RefreshCacheTable replaces the rows of the table with the query results in a transaction,
+// so readers see either old or new rows.
+// On Postgres and SQL Server the refresh is protected by the advisory lock,
+// and it's skipped if another instance is refreshing the table.
+// It returns false if the refresh was skipped.
+func RefreshCacheTable(ctx context.Context, p Provider, t *CacheTable) (refreshed bool, err error) {
+	tx, err := p.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil || !refreshed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	locked, err := tryAdvisoryXactLock(ctx, tx, p.Name(), "xdb.cache:"+t.Name)
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed to lock %s", t.Name)
+	}
+	if !locked {
+		return false, nil
+	}
+
+	query := strings.TrimRight(strings.TrimSpace(t.Query), ";")
+	if _, err = tx.ExecContext(ctx, "DELETE FROM "+t.Name); err != nil {
+		return false, errors.WithMessagef(err, "failed to clear %s", t.Name)
+	}
+	if _, err = tx.ExecContext(ctx, "INSERT INTO "+t.Name+" "+query); err != nil {
+		return false, errors.WithMessagef(err, "failed to populate %s", t.Name)
+	}
+	if err = tx.Commit(); err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// tryAdvisoryXactLock acquires the lock released at the end of transaction,
+// providers without advisory locks are always locked
+func tryAdvisoryXactLock(ctx context.Context, tx DB, provider, key string) (bool, error) {
+	var locked bool
+	switch provider {
+	case "postgres":
+		err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock(hashtext($1))", key).Scan(&locked)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+	case "sqlserver":
+		var res int
+		err := tx.QueryRowContext(ctx, `DECLARE @res int;
+EXEC @res = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Transaction', @LockTimeout = 0;
+SELECT @res`, key).Scan(&res)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		locked = res >= 0
+	default:
+		locked = true
+	}
+	return locked, nil
+}
+
+// CacheTableStatus describes the last refresh of the cache table
+type CacheTableStatus struct {
+	Name string
+	// Refreshed is the time of the last successful refresh
+	Refreshed time.Time
+	// Duration of the last successful refresh
+	Duration time.Duration
+	// Skipped is the number of refreshes skipped, as another instance held the lock
+	Skipped int
+	// Err is the error of the last refresh
+	Err error
+}
+
+// CacheRefresher refreshes cache tables on schedule
+type CacheRefresher struct {
+	p      Provider
+	tables []*CacheTable
+
+	lock   sync.RWMutex
+	status map[string]*CacheTableStatus
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewCacheRefresher returns the refresher of the cache tables
+func NewCacheRefresher(p Provider, tables ...*CacheTable) *CacheRefresher {
+	r := &CacheRefresher{
+		p:      p,
+		tables: tables,
+		status: map[string]*CacheTableStatus{},
+	}
+	for _, t := range tables {
+		r.status[t.Name] = &CacheTableStatus{Name: t.Name}
+	}
+	return r
+}
+
+// Start refreshing the tables with positive Interval
+func (r *CacheRefresher) Start() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+
+	for _, t := range r.tables {
+		if t.Interval <= 0 {
+			continue
+		}
+		r.wg.Add(1)
+		go func(t *CacheTable, stop <-chan struct{}) {
+			defer r.wg.Done()
+			ticker := time.NewTicker(t.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					_ = r.Refresh(context.Background(), t)
+				}
+			}
+		}(t, r.stop)
+	}
+}
+
+// Stop refreshing the tables, and wait for the running refreshes
+func (r *CacheRefresher) Stop() {
+	r.lock.Lock()
+	stop := r.stop
+	r.stop = nil
+	r.lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		r.wg.Wait()
+	}
+}
+
+// RefreshAll refreshes all the tables,
+// it returns the first error, but continues with other tables
+func (r *CacheRefresher) RefreshAll(ctx context.Context) error {
+	var first error
+	for _, t := range r.tables {
+		if err := r.Refresh(ctx, t); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Refresh refreshes the table and updates its status
+func (r *CacheRefresher) Refresh(ctx context.Context, t *CacheTable) error {
+	started := time.Now()
+	refreshed, err := RefreshCacheTable(ctx, r.p, t)
+	duration := time.Since(started)
+
+	r.lock.Lock()
+	st := r.status[t.Name]
+	if st == nil {
+		st = &CacheTableStatus{Name: t.Name}
+		r.status[t.Name] = st
+	}
+	st.Err = err
+	switch {
+	case err != nil:
+	case refreshed:
+		st.Refreshed = started
+		st.Duration = duration
+	default:
+		st.Skipped++
+	}
+	r.lock.Unlock()
+
+	if err != nil {
+		logger.KV(xlog.ERROR,
+			"reason", "cache_refresh",
+			"table", t.Name,
+			"err", err)
+		return err
+	}
+	logger.KV(xlog.DEBUG,
+		"reason", "cache_refresh",
+		"table", t.Name,
+		"refreshed", refreshed,
+		"duration", duration)
+	return nil
+}
+
+// Status returns the status of the tables
+func (r *CacheRefresher) Status() []CacheTableStatus {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	list := make([]CacheTableStatus, 0, len(r.tables))
+	for _, t := range r.tables {
+		list = append(list, *r.status[t.Name])
+	}
+	return list
+}
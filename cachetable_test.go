@@ -0,0 +1,94 @@
+package xdb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheTable(t *testing.T) {
+	ctx := context.Background()
+
+	file := filepath.Join(t.TempDir(), "cache.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+- name: order_totals
+  query: SELECT customer, SUM(amount) AS total FROM orders GROUP BY customer;
+  interval: 10ms
+`), 0644))
+	tables, err := xdb.LoadCacheTables(file)
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	ct := tables[0]
+	assert.Equal(t, 10*time.Millisecond, ct.Interval)
+
+	assert.Equal(t, "CREATE TABLE order_totals AS SELECT customer, SUM(amount) AS total FROM orders GROUP BY customer;", ct.MigrationUp("postgres"))
+	assert.Equal(t, "SELECT * INTO order_totals FROM (SELECT customer, SUM(amount) AS total FROM orders GROUP BY customer) AS src;", ct.MigrationUp("sqlserver"))
+	assert.Equal(t, "DROP TABLE IF EXISTS order_totals;", ct.MigrationDown())
+
+	p := openSQLite(t)
+	_, err = p.ExecContext(ctx, "CREATE TABLE orders (customer TEXT, amount INTEGER)")
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, "INSERT INTO orders VALUES ('a', 1), ('a', 2), ('b', 5)")
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, ct.MigrationUp(p.Name()))
+	require.NoError(t, err)
+
+	total := func(customer string) int {
+		var n int
+		require.NoError(t, p.QueryRowContext(ctx, "SELECT total FROM order_totals WHERE customer = ?", customer).Scan(&n))
+		return n
+	}
+	assert.Equal(t, 3, total("a"))
+
+	_, err = p.ExecContext(ctx, "INSERT INTO orders VALUES ('a', 10)")
+	require.NoError(t, err)
+	assert.Equal(t, 3, total("a"))
+
+	r := xdb.NewCacheRefresher(p, ct)
+	require.NoError(t, r.RefreshAll(ctx))
+	assert.Equal(t, 13, total("a"))
+	assert.Equal(t, 5, total("b"))
+
+	status := r.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, "order_totals", status[0].Name)
+	assert.False(t, status[0].Refreshed.IsZero())
+	assert.NoError(t, status[0].Err)
+
+	_, err = p.ExecContext(ctx, "INSERT INTO orders VALUES ('b', 10)")
+	require.NoError(t, err)
+	r.Start()
+	r.Start()
+	assert.Eventually(t, func() bool {
+		var n int
+		_ = p.QueryRowContext(ctx, "SELECT total FROM order_totals WHERE customer = ?", "b").Scan(&n)
+		return n == 15
+	}, time.Second, 10*time.Millisecond)
+	r.Stop()
+	r.Stop()
+
+	bad := &xdb.CacheTable{Name: "missing", Query: "SELECT 1"}
+	r = xdb.NewCacheRefresher(p, bad)
+	assert.Error(t, r.RefreshAll(ctx))
+	assert.Error(t, r.Status()[0].Err)
+}
+
+func TestLoadCacheTables(t *testing.T) {
+	_, err := xdb.LoadCacheTables("notfound.yaml")
+	assert.Error(t, err)
+
+	file := filepath.Join(t.TempDir(), "cache.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`- name: t1`), 0644))
+	_, err = xdb.LoadCacheTables(file)
+	assert.EqualError(t, err, "name and query must be specified in cache table 0")
+
+	require.NoError(t, os.WriteFile(file, []byte(`{`), 0644))
+	_, err = xdb.LoadCacheTables(file)
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package xdb
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time,
+// it can be replaced in tests to produce deterministic timestamps
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock returns the current local time
+var SystemClock Clock = systemClock{}
+
+// FixedClock is a Clock that returns the same time until it's moved,
+// it is safe for concurrent use
+type FixedClock struct {
+	lock sync.RWMutex
+	now  time.Time
+}
+
+// NewFixedClock returns a clock stopped at now
+func NewFixedClock(now time.Time) *FixedClock {
+	return &FixedClock{now: now}
+}
+
+// Now returns the time of the clock
+func (c *FixedClock) Now() time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.now
+}
+
+// Set moves the clock to now
+func (c *FixedClock) Set(now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = now
+}
+
+// Add moves the clock by d
+func (c *FixedClock) Add(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
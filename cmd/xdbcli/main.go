@@ -6,8 +6,13 @@ import (
 
 	"github.com/alecthomas/kong"
 	"github.com/effective-security/x/ctl"
-	"github.com/effective-security/xdb/internal/cli"
+	"github.com/effective-security/xdb/internal/cli/exec"
+	"github.com/effective-security/xdb/internal/cli/migrate"
 	"github.com/effective-security/xdb/internal/cli/schema"
+	"github.com/effective-security/xdb/pkg/cli"
+
+	// register sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
 )
 
 // version is set by the build script
@@ -16,7 +21,18 @@ const version = "v0.2.9"
 type app struct {
 	cli.Cli
 
-	Schema schema.Cmd `cmd:"" help:"SQL schema commands"`
+	Schema  schema.Cmd     `cmd:"" help:"SQL schema commands"`
+	Migrate migrate.Cmd    `cmd:"" help:"database migration commands"`
+	Exec    exec.Cmd       `cmd:"" help:"run SQL statement and print the results"`
+	Plugins cli.PluginsCmd `cmd:"" help:"list xdbcli-* plugins found in PATH"`
+}
+
+// builtinCommands can't be overridden by plugins
+var builtinCommands = map[string]bool{
+	"schema":  true,
+	"migrate": true,
+	"exec":    true,
+	"plugins": true,
 }
 
 func main() {
@@ -34,7 +50,7 @@ func realMain(args []string, out io.Writer, errout io.Writer, exit func(int)) {
 	cl.Cli.WithErrWriter(errout).
 		WithWriter(out)
 
-	parser, err := kong.New(&cl,
+	options := []kong.Option{
 		kong.Name("xdbcli"),
 		kong.Description("SQL schema tool"),
 		//kong.UsageOnError(),
@@ -46,7 +62,16 @@ func realMain(args []string, out io.Writer, errout io.Writer, exit func(int)) {
 		}),
 		kong.Vars{
 			"version": version,
-		})
+		},
+	}
+	for _, p := range cli.FindPlugins() {
+		if builtinCommands[p.Name] {
+			continue
+		}
+		options = append(options, kong.DynamicCommand(p.Name, "plugin "+p.Path, "Plugins", cli.NewPluginCmd(p)))
+	}
+
+	parser, err := kong.New(&cl, options...)
 	if err != nil {
 		panic(err)
 	}
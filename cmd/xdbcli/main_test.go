@@ -2,9 +2,12 @@ package main
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMain(t *testing.T) {
@@ -21,3 +24,28 @@ func TestMain(t *testing.T) {
 	assert.Equal(t, 1, rc)
 	assert.NotEmpty(t, errout.String())
 }
+
+func TestPlugin(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "xdbcli-hello")
+	require.NoError(t, os.WriteFile(fn, []byte("#!/bin/sh\necho \"hello $XDB_DATASOURCE $*\"\n"), 0755))
+	// plugins can't override built-in commands
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "xdbcli-schema"), []byte("#!/bin/sh\necho schema\n"), 0755))
+	t.Setenv("PATH", dir)
+
+	out := bytes.NewBuffer([]byte{})
+	errout := bytes.NewBuffer([]byte{})
+	rc := 0
+	exit := func(c int) {
+		rc = c
+	}
+
+	realMain([]string{"xdbcli", "--sql-source", "test", "hello", "--name", "world"}, out, errout, exit)
+	assert.Equal(t, 0, rc, errout.String())
+	assert.Equal(t, "hello test --name world\n", out.String())
+
+	out.Reset()
+	realMain([]string{"xdbcli", "--sql-source", "test", "plugins"}, out, errout, exit)
+	assert.Equal(t, 0, rc, errout.String())
+	assert.Equal(t, "hello\t"+fn+"\nschema\t"+filepath.Join(dir, "xdbcli-schema")+"\n", out.String())
+}
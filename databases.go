@@ -0,0 +1,192 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"os"
+
+	"github.com/effective-security/x/flake"
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb/migrate"
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMigrationLock is the name of the lock shared by the migrations of the databases
+const DefaultMigrationLock = "xdb.migrate"
+
+// DatabaseConfig defines a logical database owned by the service
+type DatabaseConfig struct {
+	// Name is the logical name of the database, for example main or analytics
+	Name string `json:"name" yaml:"name"`
+	// DataSource is the connection string,
+	// the default data source of DatabasesConfig is used if not specified
+	DataSource string `json:"datasource,omitempty" yaml:"datasource,omitempty"`
+	// Database is the name of the database
+	Database string `json:"database" yaml:"database"`
+	// Migration is optional migration configuration
+	Migration *MigrationConfig `json:"migration,omitempty" yaml:"migration,omitempty"`
+}
+
+// DatabasesConfig defines the logical databases of the service,
+// the databases are migrated in the order of the list
+type DatabasesConfig struct {
+	// DataSource is the default connection string
+	DataSource string `json:"datasource,omitempty" yaml:"datasource,omitempty"`
+	// MigrationLock is the name of the lock held during the migrations,
+	// default: DefaultMigrationLock
+	MigrationLock string `json:"migration_lock,omitempty" yaml:"migration_lock,omitempty"`
+	// Databases to open
+	Databases []*DatabaseConfig `json:"databases" yaml:"databases"`
+}
+
+// LoadDatabasesConfig loads the configuration of the databases from YAML or JSON file
+func LoadDatabasesConfig(file string) (*DatabasesConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load databases config")
+	}
+	cfg := new(DatabasesConfig)
+	if err = yaml.Unmarshal(data, cfg); err != nil {
+		return nil, errors.WithMessagef(err, "failed to load databases config %s", file)
+	}
+	if err = cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *DatabasesConfig) validate() error {
+	names := map[string]bool{}
+	for i, db := range c.Databases {
+		if db == nil || db.Name == "" {
+			return errors.Errorf("name must be specified for database %d", i)
+		}
+		if names[db.Name] {
+			return errors.Errorf("duplicate database name: %s", db.Name)
+		}
+		names[db.Name] = true
+		if c.dataSource(db) == "" {
+			return errors.Errorf("datasource must be specified for database %s", db.Name)
+		}
+	}
+	return nil
+}
+
+func (c *DatabasesConfig) dataSource(db *DatabaseConfig) string {
+	return values.StringsCoalesce(db.DataSource, c.DataSource)
+}
+
+type openedDB struct {
+	cfg      *DatabaseConfig
+	db       *sql.DB
+	provider string
+	connstr  string
+}
+
+func (c *DatabasesConfig) open() ([]*openedDB, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	var list []*openedDB
+	for _, cfg := range c.Databases {
+		d, provider, connstr, err := Open(c.dataSource(cfg), cfg.Database)
+		if err != nil {
+			closeOpened(list)
+			return nil, errors.WithMessagef(err, "failed to open DB %s", cfg.Name)
+		}
+		list = append(list, &openedDB{cfg: cfg, db: d, provider: provider, connstr: connstr})
+	}
+	return list, nil
+}
+
+func closeOpened(list []*openedDB) {
+	for _, o := range list {
+		_ = o.db.Close()
+	}
+}
+
+/*
+NewProviders opens the databases of the service, and returns the providers by name:
+
+	cfg, err := xdb.LoadDatabasesConfig("databases.yaml")
+	...
+	providers, err := xdb.NewProviders(ctx, cfg, idGen)
+	...
+	main, analytics := providers["main"], providers["analytics"]
+
+The databases are migrated in the order of the configuration,
+holding the lock on the first database, so only one instance
+of the service migrates the databases at a time.
+If a migration fails, the next databases are not migrated.
+*/
+func NewProviders(ctx context.Context, cfg *DatabasesConfig, idGen flake.IDGenerator) (map[string]Provider, error) {
+	list, err := cfg.open()
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return map[string]Provider{}, nil
+	}
+
+	first := list[0]
+	unlock, err := migrate.Lock(ctx, first.provider, first.db, values.StringsCoalesce(cfg.MigrationLock, DefaultMigrationLock))
+	if err != nil {
+		closeOpened(list)
+		return nil, err
+	}
+	for _, o := range list {
+		logger.KV(xlog.INFO, "reason", "migrate", "name", o.cfg.Name, "db", o.cfg.Database)
+		if err = migrateDB(ctx, o.provider, o.cfg.Database, o.db, o.cfg.Migration); err != nil {
+			unlock()
+			closeOpened(list)
+			return nil, errors.WithMessagef(err, "unable to migrate DB %s", o.cfg.Name)
+		}
+	}
+	unlock()
+
+	res := make(map[string]Provider, len(list))
+	for _, o := range list {
+		p, err := newProvider(o.provider, o.db, o.connstr, idGen)
+		if err != nil {
+			closeOpened(list)
+			return nil, err
+		}
+		res[o.cfg.Name] = p
+	}
+	return res, nil
+}
+
+// MigrationStatus returns the migration state of the databases with migrations configured,
+// the error of the database is reported in the status
+func MigrationStatus(cfg *DatabasesConfig) ([]*migrate.Status, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	var list []*migrate.Status
+	for _, db := range cfg.Databases {
+		if db.Migration == nil || db.Migration.Source == "" {
+			continue
+		}
+		st := databaseStatus(cfg, db)
+		st.Name = db.Name
+		list = append(list, st)
+	}
+	return list, nil
+}
+
+func databaseStatus(cfg *DatabasesConfig, db *DatabaseConfig) *migrate.Status {
+	d, provider, _, err := Open(cfg.dataSource(db), db.Database)
+	if err != nil {
+		return &migrate.Status{Database: db.Database, Error: err.Error()}
+	}
+	defer d.Close()
+
+	st, err := migrate.GetStatus(provider, db.Database, db.Migration.Source, d)
+	if err != nil {
+		return &migrate.Status{Database: db.Database, Error: err.Error()}
+	}
+	return st
+}
@@ -0,0 +1,102 @@
+package xdb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDatabasesConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "databases.yaml")
+
+	err := os.WriteFile(file, []byte(`
+datasource: sqlite3://`+dir+`
+databases:
+  - name: main
+    database: main.db
+  - name: analytics
+    database: analytics.db
+    migration:
+      source: ./migrations/analytics
+      migrate_version: 2
+`), 0o644)
+	require.NoError(t, err)
+
+	cfg, err := xdb.LoadDatabasesConfig(file)
+	require.NoError(t, err)
+	require.Len(t, cfg.Databases, 2)
+	assert.Equal(t, "main", cfg.Databases[0].Name)
+	assert.Nil(t, cfg.Databases[0].Migration)
+	assert.Equal(t, "analytics.db", cfg.Databases[1].Database)
+	assert.Equal(t, &xdb.MigrationConfig{Source: "./migrations/analytics", MigrateVersion: 2}, cfg.Databases[1].Migration)
+
+	_, err = xdb.LoadDatabasesConfig(filepath.Join(dir, "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to load databases config")
+
+	tcases := map[string]string{
+		"databases: [{database: main}]":                          "name must be specified for database 0",
+		"databases: [{name: main}]":                              "datasource must be specified for database main",
+		"datasource: x\ndatabases: [{name: main}, {name: main}]": "duplicate database name: main",
+	}
+	for content, exp := range tcases {
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+		_, err = xdb.LoadDatabasesConfig(file)
+		assert.EqualError(t, err, exp)
+	}
+}
+
+func TestNewProviders(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &xdb.DatabasesConfig{
+		DataSource: "sqlite3://" + dir,
+		Databases: []*xdb.DatabaseConfig{
+			{Name: "main", Database: "main.db"},
+			{Name: "analytics", Database: "analytics.db", Migration: &xdb.MigrationConfig{Source: dir}},
+		},
+	}
+
+	providers, err := xdb.NewProviders(context.Background(), cfg, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to migrate DB analytics: unsupported provider: sqlite3")
+	assert.Nil(t, providers)
+
+	cfg.Databases[1].Migration = nil
+	providers, err = xdb.NewProviders(context.Background(), cfg, nil)
+	require.NoError(t, err)
+	require.Len(t, providers, 2)
+	for _, p := range providers {
+		assert.Equal(t, "sqlite3", p.Name())
+		assert.NoError(t, p.Close())
+	}
+	assert.FileExists(t, filepath.Join(dir, "analytics.db"))
+}
+
+func TestMigrationStatus(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &xdb.DatabasesConfig{
+		DataSource: "sqlite3://" + dir,
+		Databases: []*xdb.DatabaseConfig{
+			{Name: "main", Database: "main.db"},
+			{Name: "analytics", Database: "analytics.db", Migration: &xdb.MigrationConfig{Source: dir}},
+			{Name: "reports", DataSource: "unknown://host", Database: "reports", Migration: &xdb.MigrationConfig{Source: dir}},
+		},
+	}
+
+	list, err := xdb.MigrationStatus(cfg)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "analytics", list[0].Name)
+	assert.Equal(t, "analytics.db", list[0].Database)
+	assert.Equal(t, "unsupported provider: sqlite3", list[0].Error)
+	assert.Equal(t, "reports", list[1].Name)
+	assert.NotEmpty(t, list[1].Error)
+
+	_, err = xdb.MigrationStatus(&xdb.DatabasesConfig{Databases: []*xdb.DatabaseConfig{{Name: "main"}}})
+	assert.EqualError(t, err, "datasource must be specified for database main")
+}
@@ -0,0 +1,149 @@
+package xdb
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DateFormat is the format of Date.String() and JSON value
+const DateFormat = time.DateOnly
+
+// Date implements DATE column with year, month and day only,
+// the zero value is stored as NULL
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the Date of t in its location
+func DateOf(t time.Time) Date {
+	var d Date
+	if t.IsZero() {
+		return d
+	}
+	d.Year, d.Month, d.Day = t.Date()
+	return d
+}
+
+// Today returns the current Date in UTC
+func Today() Date {
+	return DateOf(time.Now().UTC())
+}
+
+// ParseDate returns Date from "2006-01-02" format,
+// or RFC3339 time value
+func ParseDate(val string) (Date, error) {
+	if val == "" {
+		return Date{}, nil
+	}
+	format := DateFormat
+	if len(val) > len(DateFormat) {
+		format = time.RFC3339Nano
+	}
+	t, err := time.Parse(format, val)
+	if err != nil {
+		return Date{}, errors.WithStack(err)
+	}
+	return DateOf(t), nil
+}
+
+// Time returns the midnight of the Date in UTC
+func (d Date) Time() time.Time {
+	if d.IsZero() {
+		return time.Time{}
+	}
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+}
+
+// IsZero reports whether the Date is not set
+func (d Date) IsZero() bool {
+	return d.Year == 0 && d.Month == 0 && d.Day == 0
+}
+
+// AddDays returns the Date after the number of days
+func (d Date) AddDays(days int) Date {
+	return DateOf(d.Time().AddDate(0, 0, days))
+}
+
+// Before reports whether d is before other
+func (d Date) Before(other Date) bool {
+	return d.Time().Before(other.Time())
+}
+
+// After reports whether d is after other
+func (d Date) After(other Date) bool {
+	return d.Time().After(other.Time())
+}
+
+// String returns string in "2006-01-02" format,
+// if it's Zero date, an empty string is returned
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Time().Format(DateFormat)
+}
+
+// Scan implements the Scanner interface.
+func (d *Date) Scan(value any) error {
+	switch v := value.(type) {
+	case string:
+		return d.parse(v)
+	case []byte:
+		return d.parse(string(v))
+	}
+
+	var v sql.NullTime
+	if err := (&v).Scan(value); err != nil {
+		return errors.WithStack(err)
+	}
+	var zero Date
+	if v.Valid {
+		zero = DateOf(v.Time)
+	}
+	*d = zero
+	return nil
+}
+
+func (d *Date) parse(val string) error {
+	parsed, err := ParseDate(val)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (d Date) Value() (driver.Value, error) {
+	return sql.NullTime{
+		Valid: !d.IsZero(),
+		Time:  d.Time(),
+	}.Value()
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The date is a quoted string in "2006-01-02" format.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// The date is expected to be a quoted string in "2006-01-02" format.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || bytes.Equal([]byte(`""`), data) || bytes.Equal([]byte(`null`), data) {
+		*d = Date{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.WithStack(err)
+	}
+	return d.parse(s)
+}
@@ -0,0 +1,89 @@
+package xdb_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDate(t *testing.T) {
+	d := xdb.DateOf(time.Date(2024, 2, 29, 23, 30, 0, 0, time.FixedZone("PST", -8*3600)))
+	assert.Equal(t, xdb.Date{Year: 2024, Month: time.February, Day: 29}, d)
+	assert.Equal(t, "2024-02-29", d.String())
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), d.Time())
+	assert.Equal(t, xdb.Date{Year: 2024, Month: time.March, Day: 1}, d.AddDays(1))
+	assert.True(t, d.Before(d.AddDays(1)))
+	assert.True(t, d.After(d.AddDays(-1)))
+	assert.False(t, xdb.Today().IsZero())
+
+	var zero xdb.Date
+	assert.True(t, zero.IsZero())
+	assert.Empty(t, zero.String())
+	assert.True(t, zero.Time().IsZero())
+	assert.True(t, xdb.DateOf(time.Time{}).IsZero())
+
+	v, err := zero.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = d.Value()
+	require.NoError(t, err)
+	assert.Equal(t, d.Time(), v)
+
+	parsed, err := xdb.ParseDate("2024-02-29")
+	require.NoError(t, err)
+	assert.Equal(t, d, parsed)
+	parsed, err = xdb.ParseDate("2024-02-29T10:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, d, parsed)
+	parsed, err = xdb.ParseDate("")
+	require.NoError(t, err)
+	assert.True(t, parsed.IsZero())
+	_, err = xdb.ParseDate("2024-02-30")
+	assert.Error(t, err)
+}
+
+func TestDateScan(t *testing.T) {
+	exp := xdb.Date{Year: 2024, Month: time.January, Day: 15}
+	for _, val := range []any{
+		"2024-01-15",
+		[]byte("2024-01-15"),
+		time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	} {
+		var d xdb.Date
+		require.NoError(t, d.Scan(val))
+		assert.Equal(t, exp, d)
+	}
+
+	d := exp
+	require.NoError(t, d.Scan(nil))
+	assert.True(t, d.IsZero())
+
+	assert.Error(t, d.Scan("15/01/2024"))
+	assert.Error(t, d.Scan(42))
+}
+
+func TestDateJSON(t *testing.T) {
+	type model struct {
+		Date  xdb.Date `json:"date"`
+		Empty xdb.Date `json:"empty"`
+	}
+
+	m := model{Date: xdb.Date{Year: 2024, Month: time.January, Day: 15}}
+	js, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, `{"date":"2024-01-15","empty":""}`, string(js))
+
+	var m2 model
+	require.NoError(t, json.Unmarshal(js, &m2))
+	assert.Equal(t, m, m2)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"date":null}`), &m2))
+	assert.True(t, m2.Date.IsZero())
+	assert.Error(t, json.Unmarshal([]byte(`{"date":"bad"}`), &m2))
+	assert.Error(t, json.Unmarshal([]byte(`{"date":42}`), &m2))
+}
@@ -67,6 +67,21 @@ func TestProv(t *testing.T) {
 	assert.Equal(t, "u1", s.User)
 	assert.Equal(t, "p2", s.Password)
 	assert.Equal(t, "testdb", s.Database)
+
+	s, err = xdb.ParseConnectionString("mysql://u1:p2@tcp(127.0.0.1:3306)/testdb?parseTime=true&charset=utf8mb4")
+	require.NoError(t, err)
+	assert.Equal(t, "mysql", s.Driver)
+	assert.Equal(t, "127.0.0.1:3306", s.Host)
+	assert.Equal(t, "u1", s.User)
+	assert.Equal(t, "p2", s.Password)
+	assert.Equal(t, "testdb", s.Database)
+	assert.Equal(t, map[string]string{"parseTime": "true", "charset": "utf8mb4"}, s.Params)
+
+	s, err = xdb.ParseConnectionString("sqlite3://testdata/test.db?mode=ro")
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite3", s.Driver)
+	assert.Equal(t, "testdata/test.db", s.Database)
+	assert.Equal(t, map[string]string{"mode": "ro"}, s.Params)
 }
 
 func TestPG(t *testing.T) {
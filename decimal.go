@@ -0,0 +1,286 @@
+package xdb
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Decimal implements NUMERIC, DECIMAL and MONEY columns
+// with arbitrary precision, for values like money that can't be
+// represented exactly by float64.
+// The value is stored as unscaled integer and the number of digits
+// after the decimal point, so the scale is preserved: "12.50" stays "12.50".
+// The zero value is stored as NULL, use NewDecimal(0, 0) for 0.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+var bigTen = big.NewInt(10)
+
+// NewDecimal returns Decimal of unscaled * 10^-scale,
+// for example NewDecimal(1250, 2) is 12.50
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	if scale < 0 {
+		v := new(big.Int).Mul(big.NewInt(unscaled), pow10(-scale))
+		return Decimal{unscaled: v}
+	}
+	return Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+}
+
+// ParseDecimal returns Decimal from the string,
+// in "-123.45" or "1.2345e2" format.
+// Empty string returns NULL value.
+func ParseDecimal(val string) (Decimal, error) {
+	s := strings.TrimSpace(val)
+	if s == "" {
+		return Decimal{}, nil
+	}
+
+	exp := int64(0)
+	if pos := strings.IndexAny(s, "eE"); pos >= 0 {
+		var err error
+		if exp, err = strconv.ParseInt(s[pos+1:], 10, 32); err != nil {
+			return Decimal{}, errors.Errorf("invalid decimal: %q", val)
+		}
+		s = s[:pos]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	digits := intPart + fracPart
+	if digits == "" || digits == "-" || digits == "+" {
+		return Decimal{}, errors.Errorf("invalid decimal: %q", val)
+	}
+	for i, c := range digits {
+		if (c < '0' || c > '9') && !(i == 0 && (c == '-' || c == '+')) {
+			return Decimal{}, errors.Errorf("invalid decimal: %q", val)
+		}
+	}
+
+	unscaled, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, errors.Errorf("invalid decimal: %q", val)
+	}
+
+	scale := int64(len(fracPart)) - exp
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(int32(-scale)))
+		scale = 0
+	}
+	return Decimal{unscaled: unscaled, scale: int32(scale)}, nil
+}
+
+// MustParseDecimal returns Decimal from the string, or panics
+func MustParseDecimal(val string) Decimal {
+	d, err := ParseDecimal(val)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// IsNull reports whether the value is not set
+func (d Decimal) IsNull() bool {
+	return d.unscaled == nil
+}
+
+// Scale returns the number of digits after the decimal point
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// rescale returns the unscaled value with the scale
+func (d Decimal) rescale(scale int32) *big.Int {
+	v := new(big.Int).Set(d.int())
+	if scale > d.scale {
+		v.Mul(v, pow10(scale-d.scale))
+	}
+	return v
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the value,
+// NULL value is 0
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// Cmp compares the values and returns -1, 0 or +1,
+// NULL value is compared as 0
+func (d Decimal) Cmp(other Decimal) int {
+	scale := max(d.scale, other.scale)
+	return d.rescale(scale).Cmp(other.rescale(scale))
+}
+
+// Equal reports whether the values are equal regardless of the scale
+func (d Decimal) Equal(other Decimal) bool {
+	return d.Cmp(other) == 0
+}
+
+// Add returns d + other
+func (d Decimal) Add(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	v := d.rescale(scale)
+	return Decimal{unscaled: v.Add(v, other.rescale(scale)), scale: scale}
+}
+
+// Sub returns d - other
+func (d Decimal) Sub(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	v := d.rescale(scale)
+	return Decimal{unscaled: v.Sub(v, other.rescale(scale)), scale: scale}
+}
+
+// Mul returns d * other
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.int(), other.int()), scale: d.scale + other.scale}
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Round returns the value rounded half away from zero to the number of digits
+// after the decimal point
+func (d Decimal) Round(places int32) Decimal {
+	if places >= d.scale {
+		return Decimal{unscaled: d.rescale(places), scale: places}
+	}
+
+	div := pow10(d.scale - places)
+	q, r := new(big.Int).QuoRem(d.int(), div, new(big.Int))
+	// |r| * 2 >= div
+	r.Abs(r).Lsh(r, 1)
+	if r.Cmp(div) >= 0 {
+		if d.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if places < 0 {
+		q.Mul(q, pow10(-places))
+		places = 0
+	}
+	return Decimal{unscaled: q, scale: places}
+}
+
+// Rat returns the value as big.Rat
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.int(), pow10(d.scale))
+}
+
+// Float64 returns the nearest float64 value
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// String returns the value with the digits of the scale,
+// if it's NULL, an empty string is returned
+func (d Decimal) String() string {
+	if d.unscaled == nil {
+		return ""
+	}
+
+	s := new(big.Int).Abs(d.unscaled).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(s); pad > 0 {
+			s = strings.Repeat("0", pad) + s
+		}
+		pos := len(s) - int(d.scale)
+		s = s[:pos] + "." + s[pos:]
+	}
+	if d.unscaled.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// Scan implements the Scanner interface.
+func (d *Decimal) Scan(value any) error {
+	var err error
+	var res Decimal
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		res, err = ParseDecimal(trimMoney(string(v)))
+	case string:
+		res, err = ParseDecimal(trimMoney(v))
+	case int64:
+		res = NewDecimal(v, 0)
+	case float64:
+		res, err = ParseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+	case float32:
+		res, err = ParseDecimal(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	default:
+		return errors.Errorf("unsupported scan type: %T", value)
+	}
+	if err != nil {
+		return err
+	}
+	*d = res
+	return nil
+}
+
+// trimMoney removes the currency sign and group separators
+// of Postgres money values, like "-$1,234.50"
+func trimMoney(s string) string {
+	if !strings.ContainsAny(s, "$,") {
+		return s
+	}
+	return strings.NewReplacer("$", "", ",", "").Replace(s)
+}
+
+// Value implements the driver Valuer interface,
+// the value is passed as string to preserve the precision.
+func (d Decimal) Value() (driver.Value, error) {
+	if d.unscaled == nil {
+		return nil, nil
+	}
+	return d.String(), nil
+}
+
+// MarshalJSON implements json.Marshaler interface,
+// the value is a quoted string to preserve the precision.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface,
+// the value can be a quoted string or a number.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || bytes.Equal([]byte(`null`), data) {
+		*d = Decimal{}
+		return nil
+	}
+	s := string(data)
+	if data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	res, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = res
+	return nil
+}
@@ -0,0 +1,153 @@
+package xdb_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tcases := []struct {
+		val   string
+		exp   string
+		scale int32
+	}{
+		{"0", "0", 0},
+		{"12.50", "12.50", 2},
+		{"-0.05", "-0.05", 2},
+		{"+3.1", "3.1", 1},
+		{".5", "0.5", 1},
+		{"-.5", "-0.5", 1},
+		{"1.", "1", 0},
+		{"1.2345e2", "123.45", 2},
+		{"12e3", "12000", 0},
+		{"5E-3", "0.005", 3},
+		{"123456789012345678901234567890.123456789", "123456789012345678901234567890.123456789", 9},
+		{" 42 ", "42", 0},
+	}
+	for _, tc := range tcases {
+		d, err := xdb.ParseDecimal(tc.val)
+		require.NoError(t, err, tc.val)
+		assert.Equal(t, tc.exp, d.String(), tc.val)
+		assert.Equal(t, tc.scale, d.Scale(), tc.val)
+		assert.False(t, d.IsNull())
+	}
+
+	for _, val := range []string{"-", "+", ".", "1.2.3", "1-2", "abc", "1e", "1ex", "--1", "1,5"} {
+		_, err := xdb.ParseDecimal(val)
+		assert.Error(t, err, val)
+	}
+
+	d, err := xdb.ParseDecimal("")
+	require.NoError(t, err)
+	assert.True(t, d.IsNull())
+
+	assert.Panics(t, func() { xdb.MustParseDecimal("bad") })
+}
+
+func TestDecimalMath(t *testing.T) {
+	a := xdb.MustParseDecimal("10.25")
+	b := xdb.MustParseDecimal("0.1")
+
+	assert.Equal(t, "10.35", a.Add(b).String())
+	assert.Equal(t, "10.15", a.Sub(b).String())
+	assert.Equal(t, "1.025", a.Mul(b).String())
+	assert.Equal(t, "-10.25", a.Neg().String())
+	assert.Equal(t, 1, a.Cmp(b))
+	assert.Equal(t, -1, b.Cmp(a))
+	assert.True(t, xdb.MustParseDecimal("1.50").Equal(xdb.MustParseDecimal("1.5")))
+	assert.Equal(t, 1, a.Sign())
+	assert.Equal(t, -1, a.Neg().Sign())
+	assert.Equal(t, 0, xdb.Decimal{}.Sign())
+
+	// 0.1 + 0.2 is exact
+	assert.Equal(t, "0.3", b.Add(xdb.MustParseDecimal("0.2")).String())
+
+	assert.Equal(t, "12.50", xdb.NewDecimal(1250, 2).String())
+	assert.Equal(t, "1200", xdb.NewDecimal(12, -2).String())
+	assert.Equal(t, "0", xdb.NewDecimal(0, 0).String())
+	assert.Equal(t, "-0.007", xdb.NewDecimal(-7, 3).String())
+
+	tcases := []struct {
+		val    string
+		places int32
+		exp    string
+	}{
+		{"1.005", 2, "1.01"},
+		{"1.004", 2, "1.00"},
+		{"-1.005", 2, "-1.01"},
+		{"-1.004", 2, "-1.00"},
+		{"1.5", 0, "2"},
+		{"1.5", 3, "1.500"},
+		{"1250", -2, "1300"},
+		{"0.0049", 2, "0.00"},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.exp, xdb.MustParseDecimal(tc.val).Round(tc.places).String(), tc.val)
+	}
+
+	assert.Equal(t, big.NewRat(41, 4), a.Rat())
+	assert.Equal(t, 10.25, a.Float64())
+}
+
+func TestDecimalScan(t *testing.T) {
+	tcases := []struct {
+		val any
+		exp string
+	}{
+		{"12.50", "12.50"},
+		{[]byte("-1234.5678"), "-1234.5678"},
+		{"$1,234.50", "1234.50"},
+		{"-$1,234.50", "-1234.50"},
+		{int64(42), "42"},
+		{float64(0.1), "0.1"},
+		{float32(2.5), "2.5"},
+	}
+	for _, tc := range tcases {
+		var d xdb.Decimal
+		require.NoError(t, d.Scan(tc.val))
+		assert.Equal(t, tc.exp, d.String())
+	}
+
+	d := xdb.MustParseDecimal("1")
+	require.NoError(t, d.Scan(nil))
+	assert.True(t, d.IsNull())
+
+	assert.Error(t, d.Scan("bad"))
+	assert.Error(t, d.Scan(true))
+
+	v, err := xdb.Decimal{}.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = xdb.MustParseDecimal("12.50").Value()
+	require.NoError(t, err)
+	assert.Equal(t, "12.50", v)
+}
+
+func TestDecimalJSON(t *testing.T) {
+	type model struct {
+		Amount xdb.Decimal `json:"amount"`
+		Empty  xdb.Decimal `json:"empty"`
+	}
+
+	m := model{Amount: xdb.MustParseDecimal("123456789.10")}
+	js, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, `{"amount":"123456789.10","empty":""}`, string(js))
+
+	var m2 model
+	require.NoError(t, json.Unmarshal(js, &m2))
+	assert.Equal(t, m, m2)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":1.25,"empty":null}`), &m2))
+	assert.Equal(t, "1.25", m2.Amount.String())
+	assert.True(t, m2.Empty.IsNull())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"bad"}`), &m2))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":true}`), &m2))
+}
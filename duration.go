@@ -0,0 +1,285 @@
+package xdb
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Calendar units of interval values, the same as Postgres uses
+// to extract the epoch of interval
+const (
+	durationDay   = 24 * time.Hour
+	durationMonth = 30 * durationDay
+	durationYear  = durationDay * 36525 / 100
+)
+
+// Duration implements Postgres interval and SQL Server time columns,
+// the zero value is stored as NULL.
+// The calendar units of interval are converted with 30 days per month,
+// and 365.25 days per year.
+type Duration time.Duration
+
+// Duration returns time.Duration
+func (v Duration) Duration() time.Duration {
+	return time.Duration(v)
+}
+
+// String returns the value in [-]HH:MM:SS[.ffffff] format,
+// accepted by interval and time columns
+func (v Duration) String() string {
+	d := time.Duration(v)
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	res := fmt.Sprintf("%s%02d:%02d:%02d", sign, h, m, s)
+	if frac := d % time.Second; frac > 0 {
+		res += strings.TrimRight(fmt.Sprintf(".%09d", frac), "0")
+	}
+	return res
+}
+
+// ISO8601 returns the value in ISO-8601 duration format, like PT1H2M3.5S
+func (v Duration) ISO8601() string {
+	d := time.Duration(v)
+	if d == 0 {
+		return "PT0S"
+	}
+
+	var sb strings.Builder
+	if d < 0 {
+		sb.WriteByte('-')
+		d = -d
+	}
+	sb.WriteString("PT")
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&sb, "%dH", h)
+	}
+	if m := (d % time.Hour) / time.Minute; m > 0 {
+		fmt.Fprintf(&sb, "%dM", m)
+	}
+	if s := d % time.Minute; s > 0 {
+		sb.WriteString(strconv.FormatFloat(s.Seconds(), 'f', -1, 64))
+		sb.WriteByte('S')
+	}
+	return sb.String()
+}
+
+// ParseDuration returns Duration from ISO-8601 format, like P1DT2H,
+// or Postgres interval format, like "1 year 2 mons 3 days 04:05:06.5".
+// Empty string returns zero value.
+func ParseDuration(val string) (Duration, error) {
+	s := strings.TrimSpace(val)
+	if s == "" {
+		return 0, nil
+	}
+	if s[0] == 'P' || strings.HasPrefix(s, "-P") {
+		return parseISODuration(s)
+	}
+	return parseIntervalDuration(s)
+}
+
+func parseISODuration(val string) (Duration, error) {
+	s := val
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "P")
+	if s == "" || s == "T" {
+		return 0, errors.Errorf("invalid duration: %q", val)
+	}
+
+	var d time.Duration
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			if inTime {
+				return 0, errors.Errorf("invalid duration: %q", val)
+			}
+			inTime = true
+			s = s[1:]
+			continue
+		}
+
+		pos := strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if pos <= 0 {
+			return 0, errors.Errorf("invalid duration: %q", val)
+		}
+		n, err := strconv.ParseFloat(s[:pos], 64)
+		if err != nil {
+			return 0, errors.Errorf("invalid duration: %q", val)
+		}
+
+		var unit time.Duration
+		switch {
+		case !inTime && s[pos] == 'Y':
+			unit = durationYear
+		case !inTime && s[pos] == 'M':
+			unit = durationMonth
+		case !inTime && s[pos] == 'W':
+			unit = 7 * durationDay
+		case !inTime && s[pos] == 'D':
+			unit = durationDay
+		case inTime && s[pos] == 'H':
+			unit = time.Hour
+		case inTime && s[pos] == 'M':
+			unit = time.Minute
+		case inTime && s[pos] == 'S':
+			unit = time.Second
+		default:
+			return 0, errors.Errorf("invalid duration: %q", val)
+		}
+		d += time.Duration(n * float64(unit))
+		s = s[pos+1:]
+	}
+	if neg {
+		d = -d
+	}
+	return Duration(d), nil
+}
+
+func parseIntervalDuration(val string) (Duration, error) {
+	var d time.Duration
+	fields := strings.Fields(val)
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if strings.Contains(f, ":") {
+			t, err := parseClock(f)
+			if err != nil {
+				return 0, errors.Errorf("invalid duration: %q", val)
+			}
+			d += t
+			continue
+		}
+
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil || i+1 == len(fields) {
+			return 0, errors.Errorf("invalid duration: %q", val)
+		}
+		i++
+
+		var unit time.Duration
+		switch strings.TrimSuffix(fields[i], "s") {
+		case "year":
+			unit = durationYear
+		case "mon", "month":
+			unit = durationMonth
+		case "week":
+			unit = 7 * durationDay
+		case "day":
+			unit = durationDay
+		case "hour":
+			unit = time.Hour
+		case "min", "minute":
+			unit = time.Minute
+		case "sec", "second":
+			unit = time.Second
+		default:
+			return 0, errors.Errorf("invalid duration: %q", val)
+		}
+		d += time.Duration(n * float64(unit))
+	}
+	return Duration(d), nil
+}
+
+// parseClock parses [-+]HH:MM[:SS[.ffffff]]
+func parseClock(s string) (time.Duration, error) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "-+")
+
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, errors.Errorf("invalid time: %q", s)
+	}
+
+	h, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	m, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute
+	if len(parts) == 3 {
+		sec, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		d += time.Duration(sec * float64(time.Second)).Round(time.Microsecond)
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// Scan implements the Scanner interface.
+func (v *Duration) Scan(value any) error {
+	var res Duration
+	var err error
+	switch vid := value.(type) {
+	case nil:
+	case []byte:
+		res, err = ParseDuration(string(vid))
+	case string:
+		res, err = ParseDuration(vid)
+	case int64:
+		// the number of nanoseconds
+		res = Duration(vid)
+	case time.Time:
+		// SQL Server time columns are returned as time on 0001-01-01
+		y, m, d := vid.Date()
+		res = Duration(vid.Sub(time.Date(y, m, d, 0, 0, 0, 0, vid.Location())))
+	default:
+		return errors.Errorf("unsupported scan type: %T", value)
+	}
+	if err != nil {
+		return err
+	}
+	*v = res
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (v Duration) Value() (driver.Value, error) {
+	if v == 0 {
+		return nil, nil
+	}
+	return v.String(), nil
+}
+
+// MarshalJSON implements json.Marshaler interface,
+// the value is encoded in ISO-8601 duration format
+func (v Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.ISO8601())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface,
+// the value is expected in ISO-8601 duration format
+func (v *Duration) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*v = 0
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.WithStack(err)
+	}
+	res, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*v = res
+	return nil
+}
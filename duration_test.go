@@ -0,0 +1,124 @@
+package xdb_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	const day = 24 * time.Hour
+
+	tcases := []struct {
+		val string
+		exp time.Duration
+	}{
+		{"", 0},
+		{"00:00:00", 0},
+		{"01:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"-00:00:01.5", -1500 * time.Millisecond},
+		{"26:00", 26 * time.Hour},
+		{"1 day", day},
+		{"3 days 04:05:06.000123", 3*day + 4*time.Hour + 5*time.Minute + 6*time.Second + 123*time.Microsecond},
+		{"-1 days +02:00:00", -day + 2*time.Hour},
+		{"1 mon", 30 * day},
+		{"1 year 2 mons", 365*day + 6*time.Hour + 60*day},
+		{"2 hours 30 mins", 2*time.Hour + 30*time.Minute},
+		{"PT0S", 0},
+		{"PT1H2M3.5S", time.Hour + 2*time.Minute + 3500*time.Millisecond},
+		{"P1DT2H", day + 2*time.Hour},
+		{"P2W", 14 * day},
+		{"P1M", 30 * day},
+		{"-PT30M", -30 * time.Minute},
+	}
+	for _, tc := range tcases {
+		d, err := xdb.ParseDuration(tc.val)
+		require.NoError(t, err, tc.val)
+		assert.Equal(t, tc.exp, d.Duration(), tc.val)
+	}
+
+	for _, val := range []string{"P", "PT", "P1H", "PT1D", "PTT1H", "P1", "1", "1 fortnight", "1:2:3:4", "a:00", "00:b", "00:00:c"} {
+		_, err := xdb.ParseDuration(val)
+		assert.Error(t, err, val)
+	}
+}
+
+func TestDurationFormat(t *testing.T) {
+	tcases := []struct {
+		d   time.Duration
+		str string
+		iso string
+	}{
+		{0, "00:00:00", "PT0S"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "01:02:03", "PT1H2M3S"},
+		{26*time.Hour + 500*time.Millisecond, "26:00:00.5", "PT26H0.5S"},
+		{-90 * time.Second, "-00:01:30", "-PT1M30S"},
+		{123 * time.Microsecond, "00:00:00.000123", "PT0.000123S"},
+	}
+	for _, tc := range tcases {
+		d := xdb.Duration(tc.d)
+		assert.Equal(t, tc.str, d.String())
+		assert.Equal(t, tc.iso, d.ISO8601())
+
+		parsed, err := xdb.ParseDuration(d.String())
+		require.NoError(t, err)
+		assert.Equal(t, d, parsed)
+		parsed, err = xdb.ParseDuration(d.ISO8601())
+		require.NoError(t, err)
+		assert.Equal(t, d, parsed)
+	}
+}
+
+func TestDurationScan(t *testing.T) {
+	tcases := []struct {
+		val any
+		exp time.Duration
+	}{
+		{nil, 0},
+		{"1 day 01:00:00", 25 * time.Hour},
+		{[]byte("00:00:02"), 2 * time.Second},
+		{int64(time.Minute), time.Minute},
+		{time.Date(1, 1, 1, 10, 30, 0, 0, time.UTC), 10*time.Hour + 30*time.Minute},
+	}
+	for _, tc := range tcases {
+		d := xdb.Duration(time.Hour)
+		require.NoError(t, d.Scan(tc.val))
+		assert.Equal(t, tc.exp, d.Duration())
+	}
+
+	var d xdb.Duration
+	assert.Error(t, d.Scan("bad"))
+	assert.Error(t, d.Scan(1.5))
+
+	v, err := d.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = xdb.Duration(90 * time.Minute).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "01:30:00", v)
+}
+
+func TestDurationJSON(t *testing.T) {
+	type model struct {
+		Timeout xdb.Duration `json:"timeout"`
+	}
+
+	m := model{Timeout: xdb.Duration(36 * time.Hour)}
+	js, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, `{"timeout":"PT36H"}`, string(js))
+
+	var m2 model
+	require.NoError(t, json.Unmarshal(js, &m2))
+	assert.Equal(t, m, m2)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"timeout":null}`), &m2))
+	assert.Zero(t, m2.Timeout)
+	assert.Error(t, json.Unmarshal([]byte(`{"timeout":"bad"}`), &m2))
+	assert.Error(t, json.Unmarshal([]byte(`{"timeout":1}`), &m2))
+}
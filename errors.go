@@ -0,0 +1,327 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+var badConnMessages = []string{
+	"bad connection",
+	"broken pipe",
+	"connection reset",
+	"connection refused",
+	"unexpected eof",
+	"use of closed network connection",
+	"i/o timeout",
+}
+
+// IsBadConnectionError returns true, if error indicates that the connection is broken,
+// for example idle connection dropped by a firewall, or reset by the server.
+// The operation can be retried on a new connection.
+func IsBadConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range badConnMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// transientPostgresCodes are SQLSTATE codes of serialization failure and deadlock
+var transientPostgresCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+}
+
+// transientSQLServerNumbers are error numbers of deadlock victim and snapshot conflicts
+var transientSQLServerNumbers = map[int32]bool{
+	1205: true, // deadlock victim
+	3960: true, // snapshot isolation update conflict
+}
+
+// transientMySQLNumbers are error numbers of lock wait timeout and deadlock
+var transientMySQLNumbers = map[uint16]bool{
+	1205: true, // lock wait timeout exceeded
+	1213: true, // deadlock found
+}
+
+// sqlServerError is implemented by SQL Server driver errors
+type sqlServerError interface {
+	SQLErrorNumber() int32
+}
+
+// IsTransientError returns true, if error is reported by the database
+// for serialization failure or deadlock, and the transaction can be retried:
+// 40001, 40P01 on Postgres, 1205 on SQL Server, 1205 and 1213 on MySQL.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return transientPostgresCodes[pqErr.Code]
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return transientMySQLNumbers[myErr.Number]
+	}
+	var msErr sqlServerError
+	if errors.As(err, &msErr) {
+		return transientSQLServerNumbers[msErr.SQLErrorNumber()]
+	}
+	return false
+}
+
+// IsRetriableError returns true, if error is transient and the operation can be retried
+func IsRetriableError(err error) bool {
+	return IsBadConnectionError(err) || IsTransientError(err)
+}
+
+// IsExecRetriableError returns true, if the statement that modifies data can be retried:
+// the driver reported the bad connection before the statement was sent,
+// or the error is transient, for example serialization failure or deadlock.
+// Other connection errors may occur after the statement was applied by the server.
+func IsExecRetriableError(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || IsTransientError(err)
+}
+
+// ErrStaleRow is matched by StaleRowError with errors.Is
+var ErrStaleRow = errors.New("stale row")
+
+// StaleRowError is returned by the update with optimistic lock,
+// when the row was modified or deleted after it was read.
+// It's matched by errors.Is with ErrStaleRow and ErrNoRowsAffected.
+type StaleRowError struct {
+	// Table is the name of the table
+	Table string
+	// Key is the primary key of the row
+	Key any
+}
+
+func (e *StaleRowError) Error() string {
+	return fmt.Sprintf("stale row: %s %v was modified or deleted", e.Table, e.Key)
+}
+
+// Is returns true for ErrStaleRow and ErrNoRowsAffected
+func (e *StaleRowError) Is(target error) bool {
+	return target == ErrStaleRow || target == ErrNoRowsAffected
+}
+
+// ErrorConflict provides the details of unique or foreign key violation,
+// Table and Constraint are set if reported by the driver
+type ErrorConflict struct {
+	// ForeignKey is true for foreign key violation, false for unique violation
+	ForeignKey bool
+	// Table is the name of the table
+	Table string
+	// Constraint is the name of the constraint or unique index
+	Constraint string
+	// Err is the error of the driver
+	Err error
+}
+
+func (e *ErrorConflict) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the driver
+func (e *ErrorConflict) Unwrap() error {
+	return e.Err
+}
+
+var (
+	mssqlConstraintRegex = regexp.MustCompile(`(?i)(?:constraint|unique index) ['"]([^'"]+)['"]`)
+	mssqlTableRegex      = regexp.MustCompile(`(?:object '([^']+)'|table "([^"]+)")`)
+	mysqlKeyRegex        = regexp.MustCompile(`for key '([^']+)'`)
+	mysqlFKRegex         = regexp.MustCompile("\\(`[^`]+`\\.`([^`]+)`, CONSTRAINT `([^`]+)`")
+	sqliteTableRegex     = regexp.MustCompile(`constraint failed: ([^.]+)\.`)
+)
+
+// AsConflictError returns ErrorConflict for unique or foreign key violation,
+// or nil for other errors:
+// 23505 and 23503 on Postgres, 2627, 2601 and 547 on SQL Server,
+// 1062, 1451 and 1452 on MySQL, and the constraint errors of SQLite.
+func AsConflictError(err error) *ErrorConflict {
+	if err == nil {
+		return nil
+	}
+	var conflict *ErrorConflict
+	if errors.As(err, &conflict) {
+		return conflict
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505", "23503":
+			table := pqErr.Table
+			if table != "" && pqErr.Schema != "" {
+				table = pqErr.Schema + "." + table
+			}
+			return &ErrorConflict{
+				ForeignKey: pqErr.Code == "23503",
+				Table:      table,
+				Constraint: pqErr.Constraint,
+				Err:        err,
+			}
+		}
+		return nil
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1062:
+			res := &ErrorConflict{Err: err}
+			if m := mysqlKeyRegex.FindStringSubmatch(myErr.Message); m != nil {
+				// MySQL 8 reports the key as table.key
+				if i := strings.LastIndex(m[1], "."); i >= 0 {
+					res.Table = m[1][:i]
+					res.Constraint = m[1][i+1:]
+				} else {
+					res.Constraint = m[1]
+				}
+			}
+			return res
+		case 1451, 1452:
+			res := &ErrorConflict{ForeignKey: true, Err: err}
+			if m := mysqlFKRegex.FindStringSubmatch(myErr.Message); m != nil {
+				res.Table = m[1]
+				res.Constraint = m[2]
+			}
+			return res
+		}
+		return nil
+	}
+
+	var msErr sqlServerError
+	if errors.As(err, &msErr) {
+		msg := err.Error()
+		var res *ErrorConflict
+		switch msErr.SQLErrorNumber() {
+		case 2627, 2601:
+			res = &ErrorConflict{Err: err}
+		case 547:
+			// 547 is also reported for CHECK constraint
+			if !strings.Contains(msg, "FOREIGN KEY") {
+				return nil
+			}
+			res = &ErrorConflict{ForeignKey: true, Err: err}
+		default:
+			return nil
+		}
+		if m := mssqlConstraintRegex.FindStringSubmatch(msg); m != nil {
+			res.Constraint = m[1]
+		}
+		if m := mssqlTableRegex.FindStringSubmatch(msg); m != nil {
+			res.Table = m[1] + m[2]
+		}
+		return res
+	}
+
+	// SQLite errors are matched by message, to not depend on cgo driver
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"):
+		res := &ErrorConflict{Err: err}
+		if m := sqliteTableRegex.FindStringSubmatch(msg); m != nil {
+			res.Table = m[1]
+		}
+		return res
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		return &ErrorConflict{ForeignKey: true, Err: err}
+	}
+	return nil
+}
+
+// IsConflictError returns true, if error is unique violation,
+// use AsConflictError to get the table and constraint
+func IsConflictError(err error) bool {
+	c := AsConflictError(err)
+	return c != nil && !c.ForeignKey
+}
+
+// IsForeignKeyError returns true, if error is foreign key violation,
+// use AsConflictError to get the table and constraint
+func IsForeignKeyError(err error) bool {
+	c := AsConflictError(err)
+	return c != nil && c.ForeignKey
+}
+
+// IsSerializationError returns true, if the transaction failed on concurrent update
+// in serializable or snapshot isolation: 40001 on Postgres, 3960 on SQL Server.
+// Use IsTransientError to include deadlocks.
+func IsSerializationError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001"
+	}
+	var msErr sqlServerError
+	if errors.As(err, &msErr) {
+		return msErr.SQLErrorNumber() == 3960
+	}
+	return false
+}
+
+// IsTimeoutError returns true, if the statement or the lock wait is timed out,
+// or the context deadline is exceeded:
+// 57014 and 55P03 on Postgres, 1222 on SQL Server, 1205 and 3024 on MySQL,
+// and the busy timeout of SQLite.
+func IsTimeoutError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "57014" || pqErr.Code == "55P03"
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1205 || myErr.Number == 3024
+	}
+	var msErr sqlServerError
+	if errors.As(err, &msErr) {
+		return msErr.SQLErrorNumber() == 1222
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return strings.Contains(err.Error(), "database is locked")
+}
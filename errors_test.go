@@ -0,0 +1,217 @@
+package xdb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsBadConnectionError(t *testing.T) {
+	tcases := []struct {
+		err error
+		exp bool
+	}{
+		{nil, false},
+		{errors.New("syntax error"), false},
+		{sql.ErrNoRows, false},
+		{driver.ErrBadConn, true},
+		{errors.WithStack(driver.ErrBadConn), true},
+		{sql.ErrConnDone, true},
+		{io.EOF, true},
+		{io.ErrUnexpectedEOF, true},
+		{syscall.ECONNRESET, true},
+		{errors.WithMessage(syscall.EPIPE, "write failed"), true},
+		{&net.OpError{Op: "read", Err: errors.New("timeout")}, true},
+		{errors.New("write tcp 127.0.0.1:5432: write: broken pipe"), true},
+		{errors.New("read: connection reset by peer"), true},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.exp, xdb.IsBadConnectionError(tc.err), "%v", tc.err)
+		assert.Equal(t, tc.exp, xdb.IsRetriableError(tc.err), "%v", tc.err)
+	}
+}
+
+type sqlServerError int32
+
+func (e sqlServerError) Error() string {
+	return "mssql error"
+}
+
+func (e sqlServerError) SQLErrorNumber() int32 {
+	return int32(e)
+}
+
+func TestIsTransientError(t *testing.T) {
+	tcases := []struct {
+		err error
+		exp bool
+	}{
+		{nil, false},
+		{errors.New("syntax error"), false},
+		{&pq.Error{Code: "40001"}, true},
+		{errors.WithStack(&pq.Error{Code: "40P01"}), true},
+		{&pq.Error{Code: "23505"}, false},
+		{sqlServerError(1205), true},
+		{errors.WithMessage(sqlServerError(1205), "exec"), true},
+		{sqlServerError(2627), false},
+		{&mysql.MySQLError{Number: 1213}, true},
+		{&mysql.MySQLError{Number: 1062}, false},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.exp, xdb.IsTransientError(tc.err), "%v", tc.err)
+		assert.Equal(t, tc.exp, xdb.IsRetriableError(tc.err), "%v", tc.err)
+	}
+}
+
+func TestStaleRowError(t *testing.T) {
+	err := errors.WithStack(&xdb.StaleRowError{Table: "public.org", Key: int64(1001)})
+	assert.EqualError(t, err, "stale row: public.org 1001 was modified or deleted")
+	assert.True(t, errors.Is(err, xdb.ErrStaleRow))
+	assert.False(t, errors.Is(errors.New("stale row"), xdb.ErrStaleRow))
+
+	var stale *xdb.StaleRowError
+	assert.True(t, errors.As(err, &stale))
+	assert.Equal(t, "public.org", stale.Table)
+}
+
+// sqlServerMessageError mimics SQL Server driver error with the message
+type sqlServerMessageError struct {
+	number int32
+	msg    string
+}
+
+func (e *sqlServerMessageError) Error() string {
+	return "mssql: " + e.msg
+}
+
+func (e *sqlServerMessageError) SQLErrorNumber() int32 {
+	return e.number
+}
+
+func TestAsConflictError(t *testing.T) {
+	tcases := []struct {
+		err        error
+		foreignKey bool
+		table      string
+		constraint string
+	}{
+		{&pq.Error{Code: "23505", Schema: "public", Table: "org", Constraint: "unique_orgs_name"}, false, "public.org", "unique_orgs_name"},
+		{errors.WithStack(&pq.Error{Code: "23503", Table: "orgmember", Constraint: "fk_orgmember_org"}), true, "orgmember", "fk_orgmember_org"},
+		{&sqlServerMessageError{2627, "Violation of UNIQUE KEY constraint 'UQ_org_name'. Cannot insert duplicate key in object 'dbo.org'. The duplicate key value is (acme)."}, false, "dbo.org", "UQ_org_name"},
+		{&sqlServerMessageError{2601, "Cannot insert duplicate key row in object 'dbo.org' with unique index 'IX_org_name'. The duplicate key value is (acme)."}, false, "dbo.org", "IX_org_name"},
+		{&sqlServerMessageError{547, `The INSERT statement conflicted with the FOREIGN KEY constraint "FK_orgmember_org". The conflict occurred in database "testdb", table "dbo.org", column 'id'.`}, true, "dbo.org", "FK_orgmember_org"},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'acme' for key 'org.uq_org_name'"}, false, "org", "uq_org_name"},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'acme' for key 'uq_org_name'"}, false, "", "uq_org_name"},
+		{&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`testdb`.`orgmember`, CONSTRAINT `fk_orgmember_org` FOREIGN KEY (`org_id`) REFERENCES `org` (`id`))"}, true, "orgmember", "fk_orgmember_org"},
+	}
+	for _, tc := range tcases {
+		c := xdb.AsConflictError(tc.err)
+		require.NotNil(t, c, "%v", tc.err)
+		assert.Equal(t, tc.foreignKey, c.ForeignKey, "%v", tc.err)
+		assert.Equal(t, tc.table, c.Table, "%v", tc.err)
+		assert.Equal(t, tc.constraint, c.Constraint, "%v", tc.err)
+		assert.Equal(t, !tc.foreignKey, xdb.IsConflictError(tc.err), "%v", tc.err)
+		assert.Equal(t, tc.foreignKey, xdb.IsForeignKeyError(tc.err), "%v", tc.err)
+		assert.Equal(t, tc.err.Error(), c.Error())
+
+		// the classified error is returned as is
+		assert.Same(t, c, xdb.AsConflictError(errors.WithStack(c)))
+	}
+
+	for _, err := range []error{
+		nil,
+		errors.New("syntax error"),
+		&pq.Error{Code: "40001"},
+		&mysql.MySQLError{Number: 1213},
+		&sqlServerMessageError{547, `The INSERT statement conflicted with the CHECK constraint "CK_org_status".`},
+		sqlServerError(1205),
+	} {
+		assert.Nil(t, xdb.AsConflictError(err), "%v", err)
+		assert.False(t, xdb.IsConflictError(err), "%v", err)
+		assert.False(t, xdb.IsForeignKeyError(err), "%v", err)
+	}
+}
+
+func TestConflictErrorSQLite(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+	for _, ddl := range []string{
+		"PRAGMA foreign_keys = ON",
+		"CREATE TABLE org (id INTEGER PRIMARY KEY, name TEXT UNIQUE)",
+		"CREATE TABLE orgmember (id INTEGER PRIMARY KEY, org_id INTEGER REFERENCES org(id))",
+		"INSERT INTO org (id, name) VALUES (1, 'acme')",
+	} {
+		_, err := p.ExecContext(ctx, ddl)
+		require.NoError(t, err)
+	}
+
+	_, err := p.ExecContext(ctx, "INSERT INTO org (id, name) VALUES (2, 'acme')")
+	require.Error(t, err)
+	assert.True(t, xdb.IsConflictError(err))
+	assert.Equal(t, "org", xdb.AsConflictError(err).Table)
+
+	_, err = p.ExecContext(ctx, "INSERT INTO orgmember (org_id) VALUES (3)")
+	require.Error(t, err)
+	assert.True(t, xdb.IsForeignKeyError(err))
+	assert.False(t, xdb.IsConflictError(err))
+}
+
+func TestIsSerializationError(t *testing.T) {
+	tcases := []struct {
+		err error
+		exp bool
+	}{
+		{nil, false},
+		{errors.New("syntax error"), false},
+		{errors.WithStack(&pq.Error{Code: "40001"}), true},
+		{&pq.Error{Code: "40P01"}, false},
+		{sqlServerError(3960), true},
+		{sqlServerError(1205), false},
+		{&mysql.MySQLError{Number: 1213}, false},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.exp, xdb.IsSerializationError(tc.err), "%v", tc.err)
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTimeoutError(t *testing.T) {
+	tcases := []struct {
+		err error
+		exp bool
+	}{
+		{nil, false},
+		{errors.New("syntax error"), false},
+		{errors.WithStack(context.DeadlineExceeded), true},
+		{context.Canceled, false},
+		{&pq.Error{Code: "57014"}, true},
+		{&pq.Error{Code: "55P03"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{sqlServerError(1222), true},
+		{sqlServerError(1205), false},
+		{&mysql.MySQLError{Number: 1205}, true},
+		{&mysql.MySQLError{Number: 3024}, true},
+		{&mysql.MySQLError{Number: 1062}, false},
+		{&net.OpError{Op: "read", Err: timeoutError{}}, true},
+		{errors.New("database is locked"), true},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.exp, xdb.IsTimeoutError(tc.err), "%v", tc.err)
+	}
+}
@@ -0,0 +1,145 @@
+package xdb
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType defines the type of the provider lifecycle event
+type EventType int
+
+const (
+	// EventConnected is published when the connection is restored after the failure
+	EventConnected EventType = iota + 1
+	// EventDisconnected is published when the keep-alive ping fails
+	EventDisconnected
+	// EventMigrationStarted is published before the migration of the database
+	EventMigrationStarted
+	// EventMigrationFinished is published after the migration of the database,
+	// Err is set if the migration failed
+	EventMigrationFinished
+	// EventTxCommitted is published when the transaction is committed
+	EventTxCommitted
+	// EventSlowQuery is published when the statement took longer than the threshold,
+	// see WithSlowQueryThreshold
+	EventSlowQuery
+)
+
+// String returns the event type name
+func (t EventType) String() string {
+	switch t {
+	case EventConnected:
+		return "connected"
+	case EventDisconnected:
+		return "disconnected"
+	case EventMigrationStarted:
+		return "migration_started"
+	case EventMigrationFinished:
+		return "migration_finished"
+	case EventTxCommitted:
+		return "tx_committed"
+	case EventSlowQuery:
+		return "slow_query"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(t))
+	}
+}
+
+// Event describes the provider lifecycle event
+type Event struct {
+	Type EventType
+	Time time.Time
+	// Provider is the name of the provider: postgres, sqlserver, etc
+	Provider string
+	// Pool is the name of the child pool, empty for the default pool
+	Pool string
+	// Database is the name of the migrated database
+	Database string
+	// Name is the statement name, for EventSlowQuery
+	Name string
+	// SQL is the statement, for EventSlowQuery
+	SQL string
+	// Duration of the migration, transaction or statement
+	Duration time.Duration
+	// Err is the failure, for EventDisconnected and EventMigrationFinished
+	Err error
+}
+
+// EventHandler is called for every published event,
+// the handler must not block
+type EventHandler func(Event)
+
+type eventBus struct {
+	lock     sync.RWMutex
+	handlers map[uint64]EventHandler
+	next     uint64
+	count    atomic.Int32
+}
+
+var events = &eventBus{handlers: map[uint64]EventHandler{}}
+
+// Subscribe registers the handler of the events published by all providers
+// in the process, and returns the function to unsubscribe the handler
+func Subscribe(handler EventHandler) (unsubscribe func()) {
+	events.lock.Lock()
+	defer events.lock.Unlock()
+
+	events.next++
+	id := events.next
+	events.handlers[id] = handler
+	events.count.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			events.lock.Lock()
+			defer events.lock.Unlock()
+			delete(events.handlers, id)
+			events.count.Add(-1)
+		})
+	}
+}
+
+// EventChannel returns the handler that sends the events to the channel,
+// the events are dropped if the channel is full
+func EventChannel(ch chan<- Event) EventHandler {
+	return func(e Event) {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// publishEvent calls the subscribed handlers
+func publishEvent(e Event) {
+	if events.count.Load() == 0 {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	events.lock.RLock()
+	handlers := make([]EventHandler, 0, len(events.handlers))
+	for _, h := range events.handlers {
+		handlers = append(handlers, h)
+	}
+	events.lock.RUnlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+// event returns the event of the provider
+func (p *SQLProvider) event(t EventType) Event {
+	return Event{
+		Type:     t,
+		Time:     p.Clock().Now(),
+		Provider: p.name,
+		Pool:     p.pool,
+	}
+}
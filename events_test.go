@@ -0,0 +1,95 @@
+package xdb_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type eventRecorder struct {
+	lock   sync.Mutex
+	events []xdb.Event
+}
+
+func (r *eventRecorder) handle(e xdb.Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) byType(t xdb.EventType) []xdb.Event {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var list []xdb.Event
+	for _, e := range r.events {
+		if e.Type == t {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	rec := &eventRecorder{}
+	unsubscribe := xdb.Subscribe(rec.handle)
+	defer unsubscribe()
+
+	dir := t.TempDir()
+	_, err := xdb.NewProviders(ctx, &xdb.DatabasesConfig{
+		DataSource: "sqlite3://" + dir,
+		Databases: []*xdb.DatabaseConfig{
+			{Name: "main", Database: "main.db", Migration: &xdb.MigrationConfig{Source: dir}},
+		},
+	}, nil)
+	require.Error(t, err)
+
+	started := rec.byType(xdb.EventMigrationStarted)
+	require.Len(t, started, 1)
+	assert.Equal(t, "main.db", started[0].Database)
+	assert.Equal(t, "sqlite3", started[0].Provider)
+	finished := rec.byType(xdb.EventMigrationFinished)
+	require.Len(t, finished, 1)
+	assert.EqualError(t, finished[0].Err, "unsupported provider: sqlite3")
+
+	p := openSQLite(t).WithSlowQueryThreshold(time.Nanosecond)
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		_, err := tx.ExecContext(xdb.WithStatementName(ctx, "create"), "CREATE TABLE t (id INTEGER)")
+		return err
+	})
+	require.NoError(t, err)
+
+	committed := rec.byType(xdb.EventTxCommitted)
+	require.Len(t, committed, 1)
+	assert.Equal(t, "sqlite3", committed[0].Provider)
+	assert.Greater(t, committed[0].Duration, time.Duration(0))
+
+	slow := rec.byType(xdb.EventSlowQuery)
+	require.Len(t, slow, 1)
+	assert.Equal(t, "create", slow[0].Name)
+	assert.Equal(t, "CREATE TABLE t (id INTEGER)", slow[0].SQL)
+	assert.Equal(t, "slow_query", slow[0].Type.String())
+
+	ch := make(chan xdb.Event, 1)
+	unsubscribeCh := xdb.Subscribe(xdb.EventChannel(ch))
+	var n int
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT COUNT(*) FROM t").Scan(&n))
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT COUNT(*) FROM t").Scan(&n))
+	unsubscribeCh()
+	unsubscribeCh()
+	// the second event is dropped
+	require.Len(t, ch, 1)
+	assert.Equal(t, xdb.EventSlowQuery, (<-ch).Type)
+
+	unsubscribe()
+	_, err = p.ExecContext(ctx, "DELETE FROM t")
+	require.NoError(t, err)
+	assert.Len(t, rec.byType(xdb.EventSlowQuery), 3)
+
+	assert.Equal(t, "EventType(100)", xdb.EventType(100).String())
+}
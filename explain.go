@@ -0,0 +1,107 @@
+package xdb
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+)
+
+// DefaultExplainCacheSize is the default number of query plans in ExplainCache
+const DefaultExplainCacheSize = 1000
+
+// ExplainCache caches the number of full table scans in query plans by statement text,
+// EXPLAIN is executed once for each statement.
+// Postgres and SQLite are supported.
+type ExplainCache struct {
+	db       DB
+	provider string
+	size     int
+
+	lock  sync.RWMutex
+	plans map[string]int
+}
+
+// NewExplainCache returns the cache of query plans,
+// size limits the number of cached plans, the cache is reset when it's full
+func NewExplainCache(p Provider, size int) *ExplainCache {
+	if size <= 0 {
+		size = DefaultExplainCacheSize
+	}
+	return &ExplainCache{
+		db:       p,
+		provider: p.Name(),
+		size:     size,
+		plans:    map[string]int{},
+	}
+}
+
+// FullScans returns the number of full table scans in the plan of the statement
+func (c *ExplainCache) FullScans(ctx context.Context, q xsql.Builder) (int, error) {
+	query := q.String()
+
+	c.lock.RLock()
+	n, ok := c.plans[query]
+	c.lock.RUnlock()
+	if ok {
+		return n, nil
+	}
+
+	n, err := c.explain(ctx, query, q.Args())
+	if err != nil {
+		return 0, err
+	}
+
+	c.lock.Lock()
+	if len(c.plans) >= c.size {
+		c.plans = map[string]int{}
+	}
+	c.plans[query] = n
+	c.lock.Unlock()
+	return n, nil
+}
+
+func (c *ExplainCache) explain(ctx context.Context, query string, args []any) (int, error) {
+	var isFullScan func(line string) bool
+	switch c.provider {
+	case "postgres":
+		isFullScan = func(line string) bool {
+			return strings.Contains(line, "Seq Scan")
+		}
+	case "sqlite3":
+		isFullScan = func(line string) bool {
+			return strings.HasPrefix(line, "SCAN ") && !strings.Contains(line, " USING ")
+		}
+	default:
+		return 0, errors.Errorf("explain is not supported for %s", c.provider)
+	}
+
+	plan, err := xsql.ExplainQuery(ctx, c.db, c.provider, query, args, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, line := range plan.Lines {
+		if isFullScan(line) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// EstimateCost returns the estimated complexity of the statement,
+// with the number of full table scans from the query plan, if cache is provided
+func EstimateCost(ctx context.Context, q xsql.Builder, cache *ExplainCache) (xsql.Cost, error) {
+	c := q.Cost()
+	if cache != nil {
+		n, err := cache.FullScans(ctx, q)
+		if err != nil {
+			return c, err
+		}
+		c.FullScans = n
+	}
+	return c, nil
+}
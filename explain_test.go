@@ -0,0 +1,50 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainCache(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+	_, err := p.ExecContext(ctx, "CREATE TABLE person (id INTEGER PRIMARY KEY, name TEXT, email TEXT)")
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, "CREATE INDEX ix_person_email ON person (email)")
+	require.NoError(t, err)
+
+	cache := xdb.NewExplainCache(p, 1)
+
+	q := xsql.NoDialect.From("person").Select("id").Where("email = ?", "a@b.c")
+	defer q.Close()
+	c, err := xdb.EstimateCost(ctx, q, cache)
+	require.NoError(t, err)
+	assert.Equal(t, 0, c.FullScans)
+	assert.True(t, c.HasWhere)
+
+	q2 := xsql.NoDialect.From("person").Select("id").Where("name LIKE ?", "%smith%")
+	defer q2.Close()
+	c, err = xdb.EstimateCost(ctx, q2, cache)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.FullScans)
+	assert.Equal(t, 1, c.LeadingWildcards)
+
+	// cached
+	n, err := cache.FullScans(ctx, q2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	c, err = xdb.EstimateCost(ctx, q2, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, c.FullScans)
+
+	bad := xsql.NoDialect.From("missing").Select("id")
+	defer bad.Close()
+	_, err = xdb.EstimateCost(ctx, bad, cache)
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package xdb
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture describes rows to insert into a table for tests.
+// String values with relative time, such as "@now", "@now-24h" or "@-24h",
+// are resolved against the provider's clock when the fixture is seeded,
+// see ParseRelativeTime.
+type Fixture struct {
+	Table string           `json:"table" yaml:"table"`
+	Rows  []map[string]any `json:"rows" yaml:"rows"`
+}
+
+// Fixtures is a list of fixtures, seeded in order
+type Fixtures []*Fixture
+
+// LoadFixtures loads fixtures from YAML or JSON file
+func LoadFixtures(file string) (Fixtures, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load fixtures")
+	}
+	f, err := ParseFixtures(data)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load fixtures %s", file)
+	}
+	return f, nil
+}
+
+// ParseFixtures parses fixtures in YAML or JSON format,
+// as a list of fixtures, or as a map of the table name to the rows:
+//
+//	org:
+//	  - id: 1001
+//	    name: acme
+//
+// The map keeps the order of the tables in the document.
+func ParseFixtures(data []byte) (Fixtures, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	var f Fixtures
+	root := doc.Content[0]
+	if root.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			fx := &Fixture{Table: root.Content[i].Value}
+			if err := root.Content[i+1].Decode(&fx.Rows); err != nil {
+				return nil, errors.WithMessagef(err, "invalid rows of %s", fx.Table)
+			}
+			f = append(f, fx)
+		}
+	} else if err := root.Decode(&f); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i, fx := range f {
+		if fx == nil || fx.Table == "" {
+			return nil, errors.Errorf("table is not specified in fixture %d", i)
+		}
+	}
+	return f, nil
+}
+
+// ParseRelativeTime returns the time relative to now,
+// if val is "@now", or "@" followed by a signed duration optionally prefixed with "now",
+// for example "@now-24h", "@-24h" or "@now+1h30m".
+// The "@" prefix distinguishes relative time from text values.
+// It returns false if val is not a relative time.
+func ParseRelativeTime(val string, now time.Time) (time.Time, bool) {
+	s, ok := strings.CutPrefix(strings.TrimSpace(val), "@")
+	if !ok {
+		return time.Time{}, false
+	}
+	if strings.HasPrefix(s, "now") {
+		s = strings.TrimSpace(s[3:])
+		if s == "" {
+			return now, true
+		}
+	}
+	if !strings.HasPrefix(s, "-") && !strings.HasPrefix(s, "+") {
+		return time.Time{}, false
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return now.Add(d), true
+}
+
+// Resolve returns a copy of the fixtures
+// with relative time values resolved against now
+func (f Fixtures) Resolve(now time.Time) Fixtures {
+	res := make(Fixtures, 0, len(f))
+	for _, fx := range f {
+		c := &Fixture{
+			Table: fx.Table,
+			Rows:  make([]map[string]any, 0, len(fx.Rows)),
+		}
+		for _, row := range fx.Rows {
+			r := make(map[string]any, len(row))
+			for k, v := range row {
+				if s, ok := v.(string); ok {
+					if t, ok := ParseRelativeTime(s, now); ok {
+						v = t
+					}
+				}
+				r[k] = v
+			}
+			c.Rows = append(c.Rows, r)
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+// SeedFixtures inserts the fixtures rows,
+// relative time values are resolved against p.Now(),
+// so the provider configured WithClock produces deterministic timestamps
+func SeedFixtures(ctx context.Context, p *SQLProvider, fixtures Fixtures) error {
+	now := time.Time(p.Now())
+	dialect := xsql.DialectFor(p.Name())
+
+	for _, fx := range fixtures.Resolve(now) {
+		for _, row := range fx.Rows {
+			columns := make([]string, 0, len(row))
+			for k := range row {
+				columns = append(columns, k)
+			}
+			sort.Strings(columns)
+
+			q := dialect.InsertInto(fx.Table)
+			for _, c := range columns {
+				v := row[c]
+				if t, ok := v.(time.Time); ok {
+					v = p.UTC(t)
+				}
+				q.Set(c, v)
+			}
+			if _, err := q.ExecAndClose(ctx, p); err != nil {
+				return errors.WithMessagef(err, "failed to seed %s", fx.Table)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package xdb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eventsFixture = `
+- table: event
+  rows:
+    - id: 1
+      name: old
+      created_at: "@now-48h"
+    - id: 2
+      name: recent
+      created_at: "@-1h"
+    - id: 3
+      name: now
+      created_at: "@now"
+    - id: 4
+      name: absolute
+      created_at: 2024-01-02T03:04:05Z
+`
+
+func TestParseRelativeTime(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tcases := []struct {
+		val string
+		exp time.Time
+		ok  bool
+	}{
+		{"@now", now, true},
+		{" @now ", now, true},
+		{"@now-24h", now.Add(-24 * time.Hour), true},
+		{"@now+1h30m", now.Add(90 * time.Minute), true},
+		{"@-24h", now.Add(-24 * time.Hour), true},
+		{"@+5m", now.Add(5 * time.Minute), true},
+		{"now", time.Time{}, false},
+		{"-24h", time.Time{}, false},
+		{"@24h", time.Time{}, false},
+		{"@nowhere", time.Time{}, false},
+		{"@-abc", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.val, func(t *testing.T) {
+			v, ok := xdb.ParseRelativeTime(tc.val, now)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.exp, v)
+		})
+	}
+}
+
+func TestSeedFixtures(t *testing.T) {
+	ctx := context.Background()
+
+	file := filepath.Join(t.TempDir(), "events.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(eventsFixture), 0644))
+	fixtures, err := xdb.LoadFixtures(file)
+	require.NoError(t, err)
+	require.Len(t, fixtures, 1)
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := xdb.NewFixedClock(now)
+
+	p := openSQLite(t).WithClock(clock)
+	_, err = p.ExecContext(ctx, "CREATE TABLE event (id INTEGER, name TEXT, created_at TIMESTAMP)")
+	require.NoError(t, err)
+	require.NoError(t, xdb.SeedFixtures(ctx, p, fixtures))
+
+	assert.Equal(t, xdb.Time(now), p.Now())
+
+	names := func(since xdb.Time) []string {
+		rows, err := p.QueryContext(ctx, "SELECT name FROM event WHERE created_at >= ? ORDER BY id", since)
+		require.NoError(t, err)
+		defer rows.Close()
+		var list []string
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			list = append(list, name)
+		}
+		require.NoError(t, rows.Err())
+		return list
+	}
+	assert.Equal(t, []string{"recent", "now"}, names(p.Now().Add(-24*time.Hour)))
+	assert.Equal(t, []string{"old", "recent", "now"}, names(p.Now().Add(-72*time.Hour)))
+
+	var created xdb.Time
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT created_at FROM event WHERE id = 1").Scan(&created))
+	assert.Equal(t, xdb.Time(now.Add(-48*time.Hour)), created)
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT created_at FROM event WHERE id = 4").Scan(&created))
+	assert.Equal(t, xdb.Time(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)), created)
+
+	// the same fixtures produce the window relative to the moved clock
+	clock.Add(72 * time.Hour)
+	_, err = p.ExecContext(ctx, "DELETE FROM event")
+	require.NoError(t, err)
+	require.NoError(t, xdb.SeedFixtures(ctx, p, fixtures))
+	assert.Equal(t, []string{"recent", "now"}, names(p.Now().Add(-24*time.Hour)))
+
+	// fixtures are not modified
+	assert.Equal(t, "@now-48h", fixtures[0].Rows[0]["created_at"])
+}
+
+func TestParseFixtures(t *testing.T) {
+	f, err := xdb.ParseFixtures([]byte(`[{"table":"t","rows":[{"a":1}]}]`))
+	require.NoError(t, err)
+	require.Len(t, f, 1)
+	assert.Equal(t, "t", f[0].Table)
+
+	f, err = xdb.ParseFixtures([]byte("user:\n  - id: 1\n    org_id: 1001\norg:\n  - id: 1001\n  - id: 1002\n"))
+	require.NoError(t, err)
+	require.Len(t, f, 2)
+	assert.Equal(t, "user", f[0].Table)
+	assert.Equal(t, []map[string]any{{"id": 1, "org_id": 1001}}, f[0].Rows)
+	assert.Equal(t, "org", f[1].Table)
+	assert.Len(t, f[1].Rows, 2)
+
+	f, err = xdb.ParseFixtures(nil)
+	require.NoError(t, err)
+	assert.Empty(t, f)
+
+	_, err = xdb.ParseFixtures([]byte(`org: acme`))
+	assert.Error(t, err)
+
+	_, err = xdb.ParseFixtures([]byte(`- rows: []`))
+	assert.EqualError(t, err, "table is not specified in fixture 0")
+
+	_, err = xdb.ParseFixtures([]byte(`{`))
+	assert.Error(t, err)
+
+	_, err = xdb.LoadFixtures("notfound.yaml")
+	assert.Error(t, err)
+}
@@ -194,13 +194,26 @@ func IDArrayFromStrings(vals []string) IDArray {
 	return ids
 }
 
-// Scan implements the Scanner interface for IDs
+// Scan implements the Scanner interface for IDs.
+// The value can be Postgres array, JSON array stored by the providers
+// without array types, like SQL Server, or comma separated list.
 func (n *IDArray) Scan(value any) error {
 	*n = nil
 	if value == nil {
 		return nil
 	}
 
+	var s string
+	switch vid := value.(type) {
+	case []byte:
+		s = strings.TrimSpace(string(vid))
+	case string:
+		s = strings.TrimSpace(vid)
+	}
+	if s != "" && s[0] != '{' {
+		return n.parse(s)
+	}
+
 	var int64Array pq.Int64Array
 	err := int64Array.Scan(value)
 	if err != nil {
@@ -238,6 +251,43 @@ func (n IDArray) Value() (driver.Value, error) {
 	return int64Array, nil
 }
 
+// parse parses JSON array of numbers or strings,
+// or comma separated list of IDs
+func (n *IDArray) parse(s string) error {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+
+	var ids IDArray
+	for _, val := range strings.Split(s, ",") {
+		val = strings.Trim(strings.TrimSpace(val), `"`)
+		if val == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return errors.Errorf("failed to scan IDs: invalid ID %q", val)
+		}
+		ids = append(ids, NewID(id))
+	}
+	*n = ids
+	return nil
+}
+
+// JSON returns JSON array of IDs, like [1,2,3],
+// to be used with the providers without array types, like SQL Server,
+// where the array is stored in NVARCHAR column and expanded with OPENJSON.
+func (n IDArray) JSON() string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, id := range n {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatUint(id.UInt64(), 10))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
 // Strings returns string list representation of IDs
 func (n IDArray) Strings() []string {
 	var list []string
@@ -126,6 +126,10 @@ func TestIDsScan(t *testing.T) {
 	}{
 		{val: "{1,2}", exp: xdb.IDArray{xdb.NewID(1), xdb.NewID(2)}},
 		{val: "{}", exp: nil},
+		{val: "[1,2]", exp: xdb.IDArray{xdb.NewID(1), xdb.NewID(2)}},
+		{val: `["1", "2"]`, exp: xdb.IDArray{xdb.NewID(1), xdb.NewID(2)}},
+		{val: "[]", exp: nil},
+		{val: "1, 2", exp: xdb.IDArray{xdb.NewID(1), xdb.NewID(2)}},
 	}
 
 	for _, tc := range tcases {
@@ -140,6 +144,17 @@ func TestIDsScan(t *testing.T) {
 	assert.EqualError(t, err, "failed to scan IDs: pq: unable to parse array; expected '{' at offset 0")
 	err = val.Scan("{abc}")
 	assert.EqualError(t, err, "failed to scan IDs: pq: parsing array element index 0: strconv.ParseInt: parsing \"abc\": invalid syntax")
+
+	err = val.Scan("[1,abc]")
+	assert.EqualError(t, err, "failed to scan IDs: invalid ID \"abc\"")
+	err = val.Scan([]byte("[3]"))
+	require.NoError(t, err)
+	assert.Equal(t, xdb.IDArray{xdb.NewID(3)}, val)
+}
+
+func TestIDsJSON(t *testing.T) {
+	assert.Equal(t, "[]", xdb.IDArray{}.JSON())
+	assert.Equal(t, "[1,18446744073709551615]", xdb.NewIDArray([]uint64{1, 18446744073709551615}).JSON())
 }
 
 func TestIDsString(t *testing.T) {
@@ -0,0 +1,248 @@
+// Package idempotency provides operation keys recorded in the caller's transaction,
+// so retried writes don't apply their side effects twice.
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/xdb", "idempotency")
+
+const (
+	// DefaultTable is the name of the table to store the keys
+	DefaultTable = "xdb_idempotency_keys"
+	// DefaultTTL specifies how long the keys are kept
+	DefaultTTL = 24 * time.Hour
+	// DefaultRetries specifies how many times Do retries the transaction
+	DefaultRetries = 3
+)
+
+// ErrDuplicate is returned when the operation key is already recorded
+var ErrDuplicate = errors.New("duplicate operation key")
+
+// IsDuplicate returns true if the error indicates the operation was already applied
+func IsDuplicate(err error) bool {
+	return errors.Is(err, ErrDuplicate)
+}
+
+// key is the row of the keys table
+type key struct {
+	Key       string    `db:"idempotency_key"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+var keyColumns = []string{"idempotency_key", "created_at", "expires_at"}
+
+// Store records the operation keys
+type Store struct {
+	p       xdb.Provider
+	table   string
+	ttl     time.Duration
+	retries int
+	clock   xdb.Clock
+}
+
+// New returns the store of the keys in DefaultTable
+func New(p xdb.Provider) *Store {
+	return &Store{
+		p:       p,
+		table:   DefaultTable,
+		ttl:     DefaultTTL,
+		retries: DefaultRetries,
+		clock:   xdb.SystemClock,
+	}
+}
+
+// WithTable sets the name of the table in schema.name format
+func (s *Store) WithTable(table string) *Store {
+	s.table = table
+	return s
+}
+
+// WithTTL sets how long the keys are kept,
+// the expired key can be recorded again
+func (s *Store) WithTTL(ttl time.Duration) *Store {
+	s.ttl = ttl
+	return s
+}
+
+// WithRetries sets how many times Do retries the transaction on retriable errors
+func (s *Store) WithRetries(retries int) *Store {
+	s.retries = retries
+	return s
+}
+
+// WithClock sets the clock, tests can inject xdb.FixedClock
+func (s *Store) WithClock(clock xdb.Clock) *Store {
+	s.clock = clock
+	return s
+}
+
+// TableName returns the name of the table
+func (s *Store) TableName() string {
+	return s.table
+}
+
+// ColumnNames returns the columns of the table
+func (s *Store) ColumnNames() []string {
+	return keyColumns
+}
+
+// SQLDialect returns the dialect of the provider
+func (s *Store) SQLDialect() xsql.SQLDialect {
+	return xsql.DialectFor(s.p.Name())
+}
+
+// MigrationUp returns the statement to create the table
+func (s *Store) MigrationUp() string {
+	var keyType, timeType string
+	switch s.p.Name() {
+	case "postgres":
+		keyType, timeType = "varchar(256)", "timestamp with time zone"
+	case "sqlserver":
+		keyType, timeType = "nvarchar(256)", "datetime2"
+	case "mysql":
+		keyType, timeType = "varchar(255)", "datetime(6)"
+	default:
+		keyType, timeType = "text", "timestamp"
+	}
+	return fmt.Sprintf(`CREATE TABLE %[1]s (
+	idempotency_key %[2]s NOT NULL PRIMARY KEY,
+	created_at %[3]s NOT NULL,
+	expires_at %[3]s NOT NULL
+);
+CREATE INDEX %[4]s_expires_at ON %[1]s (expires_at);`,
+		s.table, keyType, timeType, indexPrefix(s.table))
+}
+
+// MigrationDown returns the statement to drop the table
+func (s *Store) MigrationDown() string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", s.table)
+}
+
+// indexPrefix returns the table name without schema
+func indexPrefix(table string) string {
+	return table[strings.LastIndex(table, ".")+1:]
+}
+
+/*
+Record records the key inside the caller's transaction,
+so the key is committed or rolled back with the operation:
+
+	tx, err := p.BeginTx(ctx, nil)
+	...
+	if err = store.Record(ctx, tx, req.RequestID); err != nil {
+		_ = tx.Rollback()
+		if idempotency.IsDuplicate(err) {
+			// already applied
+		}
+		return err
+	}
+
+It returns ErrDuplicate if the key is recorded and not expired.
+The concurrent transaction with the same key waits
+until the first one is committed or rolled back.
+*/
+func (s *Store) Record(ctx context.Context, tx xdb.Provider, opKey string) error {
+	now := s.clock.Now().UTC()
+
+	// the expired key can be recorded again
+	q := s.SQLDialect().DeleteFrom(s.table).
+		Where("idempotency_key = ?", opKey).
+		Where("expires_at < ?", now)
+	_, err := q.Exec(ctx, tx)
+	q.Close()
+	if err != nil {
+		return errors.WithMessagef(err, "failed to delete expired key")
+	}
+
+	n, err := xdb.UpsertAll(ctx, tx, s, []key{{
+		Key:       opKey,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}}, keyColumns[:1], nil)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.WithStack(ErrDuplicate)
+	}
+	return nil
+}
+
+/*
+Do runs fn in the transaction with the key recorded,
+and commits the transaction if fn succeeds:
+
+	err := store.Do(ctx, req.RequestID, func(ctx context.Context, tx xdb.Provider) error {
+		return createOrder(ctx, tx, req)
+	})
+
+The transaction is retried on xdb.IsRetriableError,
+if the retry finds the key recorded by the previous attempt,
+that failed to report the commit, the operation is considered applied.
+It returns ErrDuplicate if the key was recorded by another call.
+*/
+func (s *Store) Do(ctx context.Context, opKey string, fn func(ctx context.Context, tx xdb.Provider) error) error {
+	var err error
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		err = s.do(ctx, opKey, fn)
+		if attempt > 0 && IsDuplicate(err) {
+			// the previous attempt was committed
+			return nil
+		}
+		if !xdb.IsRetriableError(err) || ctx.Err() != nil {
+			return err
+		}
+		logger.KV(xlog.DEBUG,
+			"reason", "retry",
+			"key", opKey,
+			"attempt", attempt+1,
+			"err", err.Error())
+	}
+	return err
+}
+
+func (s *Store) do(ctx context.Context, opKey string, fn func(ctx context.Context, tx xdb.Provider) error) (err error) {
+	tx, err := s.p.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = s.Record(ctx, tx, opKey); err != nil {
+		return err
+	}
+	if err = fn(ctx, tx); err != nil {
+		return err
+	}
+	return errors.WithStack(tx.Commit())
+}
+
+// Cleanup deletes the expired keys, and returns the number of deleted keys.
+// Run it on schedule to keep the table small.
+func (s *Store) Cleanup(ctx context.Context) (int64, error) {
+	q := s.SQLDialect().DeleteFrom(s.table).
+		Where("expires_at < ?", s.clock.Now().UTC())
+	defer q.Close()
+
+	res, err := q.Exec(ctx, s.p)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed to delete expired keys")
+	}
+	n, err := res.RowsAffected()
+	return n, errors.WithStack(err)
+}
@@ -0,0 +1,146 @@
+package idempotency_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/idempotency"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openSQLite(t *testing.T) *xdb.SQLProvider {
+	t.Helper()
+	d, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// :memory: database is per connection
+	d.SetMaxOpenConns(1)
+
+	p, err := xdb.New("sqlite3", d, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = p.Close()
+	})
+	return p
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+	clock := xdb.NewFixedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	store := idempotency.New(p).WithTTL(time.Hour).WithClock(clock)
+	_, err := p.ExecContext(ctx, store.MigrationUp())
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, "CREATE TABLE orders (id integer PRIMARY KEY, name text)")
+	require.NoError(t, err)
+
+	createOrder := func(name string) func(ctx context.Context, tx xdb.Provider) error {
+		return func(ctx context.Context, tx xdb.Provider) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO orders (name) VALUES (?)", name)
+			return err
+		}
+	}
+	countOrders := func() int {
+		var count int
+		require.NoError(t, p.QueryRowContext(ctx, "SELECT COUNT(*) FROM orders").Scan(&count))
+		return count
+	}
+
+	require.NoError(t, store.Do(ctx, "req1", createOrder("first")))
+	err = store.Do(ctx, "req1", createOrder("first"))
+	assert.True(t, idempotency.IsDuplicate(err))
+	assert.Equal(t, 1, countOrders())
+
+	// the key is rolled back with the failed operation
+	err = store.Do(ctx, "req2", func(context.Context, xdb.Provider) error {
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	require.NoError(t, store.Do(ctx, "req2", createOrder("second")))
+	assert.Equal(t, 2, countOrders())
+
+	// the commit of the first attempt was not reported
+	attempts := 0
+	err = store.Do(ctx, "req3", func(ctx context.Context, tx xdb.Provider) error {
+		attempts++
+		if attempts == 1 {
+			if err := createOrder("third")(ctx, tx); err != nil {
+				return err
+			}
+			require.NoError(t, tx.Commit())
+			return driver.ErrBadConn
+		}
+		return createOrder("third")(ctx, tx)
+	})
+	require.NoError(t, err)
+	// the retry found the key committed by the first attempt
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, 3, countOrders())
+
+	// the connection failed before the commit
+	attempts = 0
+	err = store.Do(ctx, "req4", func(ctx context.Context, tx xdb.Provider) error {
+		attempts++
+		if attempts == 1 {
+			return driver.ErrBadConn
+		}
+		return createOrder("fourth")(ctx, tx)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 4, countOrders())
+
+	// the expired key can be recorded again
+	clock.Add(time.Hour + time.Second)
+	require.NoError(t, store.Do(ctx, "req1", createOrder("first again")))
+	assert.Equal(t, 5, countOrders())
+
+	deleted, err := store.Cleanup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	_, err = p.ExecContext(ctx, store.MigrationDown())
+	require.NoError(t, err)
+}
+
+func TestRecord(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+
+	store := idempotency.New(p)
+	_, err := p.ExecContext(ctx, store.MigrationUp())
+	require.NoError(t, err)
+
+	tx, err := p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.Record(ctx, tx, "op1"))
+	err = store.Record(ctx, tx, "op1")
+	assert.True(t, idempotency.IsDuplicate(err))
+	assert.EqualError(t, err, "duplicate operation key")
+	require.NoError(t, tx.Rollback())
+
+	tx, err = p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.Record(ctx, tx, "op1"))
+	require.NoError(t, tx.Commit())
+}
+
+func TestMigration(t *testing.T) {
+	p, err := xdb.New("postgres", nil, nil)
+	require.NoError(t, err)
+	store := idempotency.New(p).WithTable("public.idempotency")
+	assert.Equal(t, `CREATE TABLE public.idempotency (
+	idempotency_key varchar(256) NOT NULL PRIMARY KEY,
+	created_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone NOT NULL
+);
+CREATE INDEX idempotency_expires_at ON public.idempotency (expires_at);`, store.MigrationUp())
+	assert.Equal(t, "DROP TABLE IF EXISTS public.idempotency;", store.MigrationDown())
+}
@@ -8,7 +8,7 @@ import (
 
 	"github.com/alecthomas/kong"
 	"github.com/effective-security/x/ctl"
-	"github.com/effective-security/xdb/internal/cli"
+	"github.com/effective-security/xdb/pkg/cli"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -0,0 +1,99 @@
+// Package exec provides CLI command to run ad-hoc SQL statements
+package exec
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/effective-security/xdb/pkg/cli"
+	"github.com/effective-security/xdb/pkg/print"
+	"github.com/pkg/errors"
+)
+
+// Cmd runs SQL statement and prints the results
+type Cmd struct {
+	DB    string `help:"database name" required:""`
+	Query string `arg:"" optional:"" help:"SQL statement"`
+	File  string `help:"path to SQL file, instead of the statement argument" type:"existingfile"`
+}
+
+// Run the command
+func (a *Cmd) Run(ctx *cli.Cli) error {
+	query := a.Query
+	if a.File != "" {
+		if query != "" {
+			return errors.Errorf("use either the statement or --file")
+		}
+		b, err := os.ReadFile(a.File)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		query = string(b)
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return errors.Errorf("use the statement argument or --file")
+	}
+
+	db, err := ctx.DB(a.DB)
+	if err != nil {
+		return err
+	}
+
+	if !returnsRows(query) {
+		res, err := db.ExecContext(ctx.Context(), query)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		n, _ := res.RowsAffected()
+		fmt.Fprintf(ctx.Writer(), "rows affected: %d\n", n)
+		return nil
+	}
+
+	rows, err := db.QueryContext(ctx.Context(), query)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	res := &print.QueryResult{
+		Columns: columns,
+		Rows:    [][]any{},
+	}
+	for rows.Next() {
+		vals := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err = rows.Scan(ptrs...); err != nil {
+			return errors.WithStack(err)
+		}
+		for i, v := range vals {
+			// text columns are returned as bytes by some drivers
+			if b, ok := v.([]byte); ok {
+				vals[i] = string(b)
+			}
+		}
+		res.Rows = append(res.Rows, vals)
+	}
+	if err = rows.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+	return ctx.Print(res)
+}
+
+// returnsRows returns true for the statements that return rows
+func returnsRows(query string) bool {
+	q := strings.ToUpper(query)
+	switch strings.Fields(q)[0] {
+	case "SELECT", "WITH", "SHOW", "VALUES", "TABLE", "EXPLAIN", "PRAGMA":
+		return true
+	}
+	return strings.Contains(q, "RETURNING") || strings.Contains(q, "OUTPUT INSERTED.") || strings.Contains(q, "OUTPUT DELETED.")
+}
@@ -0,0 +1,81 @@
+package exec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/effective-security/xdb/internal/cli/clisuite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	// register sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type testSuite struct {
+	clisuite.TestSuite
+}
+
+func TestExec(t *testing.T) {
+	suite.Run(t, new(testSuite))
+}
+
+func (s *testSuite) TestExec() {
+	require := s.Require()
+
+	dir := s.T().TempDir()
+	source := s.Ctl.SQLSource
+	s.Ctl.SQLSource = "sqlite3://" + dir
+	defer func() {
+		s.Ctl.Close()
+		s.Ctl.SQLSource = source
+	}()
+
+	cmd := Cmd{DB: "main.db"}
+	s.EqualError(cmd.Run(s.Ctl), "use the statement argument or --file")
+
+	cmd.Query = "CREATE TABLE org (id integer PRIMARY KEY, name text, email text)"
+	require.NoError(cmd.Run(s.Ctl))
+	s.EqualOut("rows affected: 0\n")
+
+	s.Out.Reset()
+	cmd.Query = "INSERT INTO org VALUES (1, 'acme', 'info@acme.com'), (2, 'beta', NULL)"
+	require.NoError(cmd.Run(s.Ctl))
+	s.EqualOut("rows affected: 2\n")
+
+	s.Out.Reset()
+	cmd.Query = "SELECT id, name, email FROM org ORDER BY id"
+	require.NoError(cmd.Run(s.Ctl))
+	s.EqualOut("  id | name |     email      \n" +
+		"-----+------+----------------\n" +
+		"  1  | acme | info@acme.com  \n" +
+		"  2  | beta | NULL           \n" +
+		"(2 rows)\n")
+
+	s.Out.Reset()
+	s.Ctl.O = "json"
+	file := filepath.Join(dir, "query.sql")
+	require.NoError(os.WriteFile(file, []byte("\nSELECT name FROM org WHERE id = 1;\n"), 0o644))
+	cmd = Cmd{DB: "main.db", File: file}
+	require.NoError(cmd.Run(s.Ctl))
+	s.EqualOut("{\n  \"Columns\": [\n    \"name\"\n  ],\n  \"Rows\": [\n    [\n      \"acme\"\n    ]\n  ]\n}\n")
+
+	cmd.Query = "SELECT 1"
+	s.EqualError(cmd.Run(s.Ctl), "use either the statement or --file")
+}
+
+func TestReturnsRows(t *testing.T) {
+	tcases := map[string]bool{
+		"SELECT 1":                                         true,
+		"select\n*\nFROM org":                              true,
+		"WITH t AS (SELECT 1) SELECT * FROM t":             true,
+		"INSERT INTO org (name) VALUES ('a')":              false,
+		"INSERT INTO org (name) VALUES ('a') RETURNING id": true,
+		"DELETE FROM org":                                  false,
+		"EXPLAIN SELECT 1":                                 true,
+	}
+	for query, exp := range tcases {
+		assert.Equal(t, exp, returnsRows(query), query)
+	}
+}
@@ -0,0 +1,218 @@
+// Package migrate provides CLI commands for database migrations
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/effective-security/x/slices"
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/migrate"
+	"github.com/effective-security/xdb/pkg/cli"
+	"github.com/pkg/errors"
+)
+
+// Cmd base command for migrate
+type Cmd struct {
+	Up      UpCmd      `cmd:"" help:"apply migrations up to the latest or specified version"`
+	Down    DownCmd    `cmd:"" help:"roll back migrations"`
+	Force   ForceCmd   `cmd:"" help:"set migration version without running migrations"`
+	Version VersionCmd `cmd:"" help:"print current migration version"`
+	Status  StatusCmd  `cmd:"" help:"print migration status of the databases"`
+}
+
+// UpCmd applies migrations
+type UpCmd struct {
+	DB     string `help:"database name" required:""`
+	Source string `help:"migrations directory" required:""`
+	Target int    `help:"optional, target version, default: latest"`
+	DryRun bool   `help:"print migrations without applying"`
+}
+
+// Run the command
+func (a *UpCmd) Run(ctx *cli.Cli) error {
+	return run(ctx, a.DB, a.Source, &migrate.Options{
+		MigrateVersion: a.Target,
+		DryRun:         a.DryRun,
+	})
+}
+
+// DownCmd rolls back migrations
+type DownCmd struct {
+	DB     string `help:"database name" required:""`
+	Source string `help:"migrations directory" required:""`
+	Steps  int    `help:"number of migrations to roll back" default:"1"`
+	DryRun bool   `help:"print migrations without applying"`
+}
+
+// Run the command
+func (a *DownCmd) Run(ctx *cli.Cli) error {
+	if a.Steps < 1 {
+		return errors.Errorf("invalid --steps: %d", a.Steps)
+	}
+	return run(ctx, a.DB, a.Source, &migrate.Options{
+		DownSteps: a.Steps,
+		DryRun:    a.DryRun,
+	})
+}
+
+// ForceCmd sets migration version
+type ForceCmd struct {
+	DB      string `help:"database name" required:""`
+	Source  string `help:"migrations directory" required:""`
+	Version int    `arg:"" help:"version to set"`
+	DryRun  bool   `help:"print the version without setting"`
+}
+
+// Run the command
+func (a *ForceCmd) Run(ctx *cli.Cli) error {
+	if a.DryRun {
+		fmt.Fprintf(ctx.Writer(), "would force version %d\n", a.Version)
+		return nil
+	}
+
+	d, provider, err := open(ctx, a.DB)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err = migrate.Force(provider, a.Source, d, a.Version); err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.Writer(), "version: %d\n", a.Version)
+	return nil
+}
+
+// VersionCmd prints migration version
+type VersionCmd struct {
+	DB     string `help:"database name" required:""`
+	Source string `help:"migrations directory" required:""`
+}
+
+// Run the command
+func (a *VersionCmd) Run(ctx *cli.Cli) error {
+	st, err := getStatus(ctx, a.DB, a.Source)
+	if err != nil {
+		return err
+	}
+	if ctx.O == "json" || ctx.O == "yaml" {
+		st.Migrations = nil
+		return ctx.Print(st)
+	}
+	fmt.Fprintln(ctx.Writer(), versionString(st))
+	return nil
+}
+
+// StatusCmd prints migration status of the database,
+// or the databases in the config
+type StatusCmd struct {
+	DB     string   `help:"database name"`
+	Source string   `help:"migrations directory"`
+	Config string   `help:"config file of the named databases"`
+	All    bool     `help:"print status of all databases in the config"`
+	Name   []string `help:"optional, list of database names from the config"`
+}
+
+// Run the command
+func (a *StatusCmd) Run(ctx *cli.Cli) error {
+	if a.Config == "" {
+		if a.Source == "" {
+			return errors.Errorf("use --source or --config to specify the migrations")
+		}
+		st, err := getStatus(ctx, a.DB, a.Source)
+		if err != nil {
+			return err
+		}
+		return ctx.Print(st)
+	}
+
+	if !a.All && len(a.Name) == 0 {
+		return errors.Errorf("use --all or --name to specify the databases")
+	}
+
+	cfg, err := xdb.LoadDatabasesConfig(a.Config)
+	if err != nil {
+		return err
+	}
+	cfg.DataSource = values.StringsCoalesce(cfg.DataSource, ctx.SQLSource)
+
+	if !a.All {
+		var list []*xdb.DatabaseConfig
+		for _, db := range cfg.Databases {
+			if slices.ContainsString(a.Name, db.Name) {
+				list = append(list, db)
+			}
+		}
+		if len(list) != len(a.Name) {
+			return errors.Errorf("databases not found in the config: %v", a.Name)
+		}
+		cfg.Databases = list
+	}
+
+	res, err := xdb.MigrationStatus(cfg)
+	if err != nil {
+		return err
+	}
+	return ctx.Print(res)
+}
+
+func open(ctx *cli.Cli, dbName string) (*sql.DB, string, error) {
+	d, provider, _, err := xdb.Open(ctx.SQLSource, dbName)
+	if err != nil {
+		return nil, "", err
+	}
+	return d, provider, nil
+}
+
+func run(ctx *cli.Cli, dbName, source string, opts *migrate.Options) error {
+	d, provider, err := open(ctx, dbName)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	w := ctx.Writer()
+	var count int
+	opts.OnProgress = func(p migrate.Progress) {
+		count++
+		if opts.DryRun {
+			fmt.Fprintf(w, "would migrate %s %d/%s\n", p.Direction, p.Version, p.Name)
+		} else {
+			fmt.Fprintf(w, "migrated %s %d/%s in %s\n", p.Direction, p.Version, p.Name, p.Duration)
+		}
+	}
+	if err = migrate.MigrateContext(ctx.Context(), provider, dbName, source, d, opts); err != nil {
+		return err
+	}
+	if count == 0 {
+		fmt.Fprintln(w, "no change")
+	}
+	if opts.DryRun {
+		return nil
+	}
+
+	st, err := migrate.GetStatus(provider, dbName, source, d)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, versionString(st))
+	return nil
+}
+
+func getStatus(ctx *cli.Cli, dbName, source string) (*migrate.Status, error) {
+	d, provider, err := open(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	return migrate.GetStatus(provider, dbName, source, d)
+}
+
+func versionString(st *migrate.Status) string {
+	if st.Version == 0 {
+		return "version: none"
+	}
+	return fmt.Sprintf("version: %d%s", st.Version, values.Select(st.Dirty, " (dirty)", ""))
+}
@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/effective-security/xdb/internal/cli/clisuite"
+	"github.com/stretchr/testify/suite"
+
+	// register sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type testSuite struct {
+	clisuite.TestSuite
+}
+
+func TestMigrate(t *testing.T) {
+	suite.Run(t, new(testSuite))
+}
+
+func (s *testSuite) TestStatus() {
+	require := s.Require()
+
+	dir := s.T().TempDir()
+	file := filepath.Join(dir, "databases.yaml")
+	err := os.WriteFile(file, []byte(`
+datasource: sqlite3://`+dir+`
+databases:
+  - name: main
+    database: main.db
+    migration:
+      source: `+dir+`
+  - name: analytics
+    database: analytics.db
+    migration:
+      source: `+dir+`
+`), 0o644)
+	require.NoError(err)
+
+	cmd := StatusCmd{Config: file}
+	err = cmd.Run(s.Ctl)
+	s.EqualError(err, "use --all or --name to specify the databases")
+
+	cmd.Name = []string{"reports"}
+	err = cmd.Run(s.Ctl)
+	s.EqualError(err, "databases not found in the config: [reports]")
+
+	cmd.Name = []string{"analytics"}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText("analytics | analytics.db | 0       | 0      | 0       |       | unsupported provider: sqlite3")
+	s.NotContains(s.Out.String(), "main.db")
+
+	s.Out.Reset()
+	s.Ctl.O = "json"
+	cmd = StatusCmd{Config: file, All: true}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText(`"name": "main"`, `"name": "analytics"`, `"error": "unsupported provider: sqlite3"`)
+}
+
+func (s *testSuite) TestCommands() {
+	dir := s.T().TempDir()
+	sqlSource := s.Ctl.SQLSource
+	defer func() { s.Ctl.SQLSource = sqlSource }()
+	s.Ctl.SQLSource = "sqlite3://" + dir
+
+	up := UpCmd{DB: "test.db", Source: dir}
+	s.EqualError(up.Run(s.Ctl), "unsupported provider: sqlite3")
+
+	down := DownCmd{DB: "test.db", Source: dir, Steps: 0}
+	s.EqualError(down.Run(s.Ctl), "invalid --steps: 0")
+	down.Steps = 1
+	s.EqualError(down.Run(s.Ctl), "unsupported provider: sqlite3")
+
+	force := ForceCmd{DB: "test.db", Source: dir, Version: 3}
+	s.EqualError(force.Run(s.Ctl), "unsupported provider: sqlite3")
+
+	force.DryRun = true
+	s.Require().NoError(force.Run(s.Ctl))
+	s.EqualOut("would force version 3\n")
+
+	version := VersionCmd{DB: "test.db", Source: dir}
+	s.EqualError(version.Run(s.Ctl), "unsupported provider: sqlite3")
+
+	status := StatusCmd{DB: "test.db"}
+	s.EqualError(status.Run(s.Ctl), "use --source or --config to specify the migrations")
+	status.Source = dir
+	s.EqualError(status.Run(s.Ctl), "unsupported provider: sqlite3")
+
+	s.Ctl.SQLSource = "unknown://host"
+	s.Error(up.Run(s.Ctl))
+}
@@ -0,0 +1,45 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/cli"
+	dbschema "github.com/effective-security/xdb/schema"
+)
+
+// CacheMigrationCmd generates migration files for cache tables
+type CacheMigrationCmd struct {
+	Defs     string `help:"path to cache tables definition file" required:""`
+	Provider string `help:"database provider: postgres|sqlserver|mysql" enum:"postgres,sqlserver,mysql" default:"postgres"`
+	Number   uint   `help:"number of the migration, the version prefix of the file names" required:""`
+	Name     string `help:"name of the migration" default:"cache_tables"`
+	Out      string `help:"optional, folder name to store migration files, default: stdout"`
+}
+
+// Run the command
+func (a *CacheMigrationCmd) Run(ctx *cli.Cli) error {
+	tables, err := xdb.LoadCacheTables(a.Defs)
+	if err != nil {
+		return err
+	}
+
+	var names dbschema.Tables
+	for _, t := range tables {
+		schemaName, name, _ := strings.Cut(t.Name, ".")
+		names = append(names, &dbschema.Table{Schema: schemaName, Name: name})
+	}
+	printNameWarnings(ctx, dbschema.ValidateNames(a.Provider, names, nil), nil)
+
+	var up, down []string
+	for _, t := range tables {
+		up = append(up, t.MigrationUp(a.Provider))
+	}
+	for i := len(tables) - 1; i >= 0; i-- {
+		down = append(down, tables[i].MigrationDown())
+	}
+
+	name := fmt.Sprintf("%06d_%s", a.Number, a.Name)
+	return writeMigration(ctx, a.Out, name, up, down)
+}
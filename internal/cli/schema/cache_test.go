@@ -0,0 +1,50 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func (s *testSuite) TestCacheMigration() {
+	require := s.Require()
+
+	dir := s.T().TempDir()
+	defs := filepath.Join(dir, "cache.yaml")
+	err := os.WriteFile(defs, []byte(`
+- name: reports.order_totals
+  query: SELECT customer, SUM(amount) AS total FROM orders GROUP BY customer
+  interval: 1h
+- name: reports.daily_orders
+  query: SELECT created_at::date AS day, COUNT(*) AS count FROM orders GROUP BY 1
+  interval: 24h
+`), 0644)
+	require.NoError(err)
+
+	cmd := CacheMigrationCmd{
+		Defs:     defs,
+		Provider: "postgres",
+		Number:   12,
+		Name:     "cache_tables",
+	}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText(
+		"-- 000012_cache_tables.up.sql\nCREATE TABLE reports.order_totals AS SELECT customer",
+		"-- 000012_cache_tables.down.sql\nDROP TABLE IF EXISTS reports.daily_orders;\nDROP TABLE IF EXISTS reports.order_totals;\n",
+	)
+
+	cmd.Out = filepath.Join(dir, "migrations")
+	cmd.Provider = "sqlserver"
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+
+	up, err := os.ReadFile(filepath.Join(cmd.Out, "000012_cache_tables.up.sql"))
+	require.NoError(err)
+	s.Contains(string(up), "SELECT * INTO reports.order_totals FROM (SELECT customer")
+	_, err = os.Stat(filepath.Join(cmd.Out, "000012_cache_tables.down.sql"))
+	require.NoError(err)
+
+	cmd.Defs = filepath.Join(dir, "missing.yaml")
+	err = cmd.Run(s.Ctl)
+	require.Error(err)
+}
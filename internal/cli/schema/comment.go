@@ -0,0 +1,159 @@
+package schema
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/effective-security/xdb/pkg/cli"
+	"github.com/effective-security/xdb/schema"
+	"github.com/pkg/errors"
+)
+
+// CommentOnCmd prints statements to update the database comments
+// from the doc comments edited in generated Go model
+type CommentOnCmd struct {
+	DB     string   `help:"database name" required:""`
+	Schema string   `help:"optional schema name to filter"`
+	Table  []string `help:"optional, list of tables, default: all tables"`
+	Models string   `help:"folder name with generated model files" required:""`
+}
+
+// Run the command
+func (a *CommentOnCmd) Run(ctx *cli.Cli) error {
+	r, err := ctx.SchemaProvider(a.DB)
+	if err != nil {
+		return err
+	}
+	if schema.CommentOnTable(r.Name(), &schema.Table{}, "") == "" {
+		return errors.Errorf("comments are not supported by %s", r.Name())
+	}
+
+	res, err := r.ListTables(ctx.Context(), a.Schema, a.Table, false)
+	if err != nil {
+		return err
+	}
+
+	comments, err := modelComments(a.Models)
+	if err != nil {
+		return err
+	}
+
+	list := commentStatements(r.Name(), res, comments)
+	if len(list) == 0 {
+		fmt.Fprintln(ctx.Writer(), "-- no changes")
+		return nil
+	}
+	fmt.Fprintln(ctx.Writer(), strings.Join(list, "\n"))
+	return nil
+}
+
+// tableComments provides the doc comments of the model struct
+type tableComments struct {
+	Comment string
+	// Columns provides the comments by lower case column name
+	Columns map[string]string
+}
+
+// commentStatements returns the statements for the tables,
+// where the comments in the model differ from the database
+func commentStatements(provider string, tables schema.Tables, comments map[string]*tableComments) []string {
+	var list []string
+	for _, t := range tables {
+		tc := comments[strings.ToLower(t.Schema+"."+t.Name)]
+		if tc == nil {
+			continue
+		}
+		if tc.Comment != normalizeComment(t.Comment) {
+			list = append(list, schema.CommentOnTable(provider, t, tc.Comment))
+		}
+		for _, c := range t.Columns {
+			comment, ok := tc.Columns[strings.ToLower(c.Name)]
+			if ok && comment != normalizeComment(c.Comment) {
+				list = append(list, schema.CommentOnColumn(provider, t, c, comment))
+			}
+		}
+	}
+	return list
+}
+
+// modelComments parses the model files and returns the doc comments
+// of the model structs by lower case table name in schema.table format.
+func modelComments(folder string) (map[string]*tableComments, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, folder, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to parse models")
+	}
+
+	res := map[string]*tableComments{}
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					doc := ts.Doc
+					if doc == nil {
+						doc = gd.Doc
+					}
+					if doc == nil {
+						continue
+					}
+					m := rowCommentRegex.FindStringSubmatch(doc.Text())
+					if m == nil {
+						continue
+					}
+
+					tc := &tableComments{
+						Comment: docComment(doc),
+						Columns: map[string]string{},
+					}
+					for _, field := range st.Fields.List {
+						column := dbColumnName(field)
+						if column == "" || field.Doc == nil {
+							continue
+						}
+						tc.Columns[strings.ToLower(column)] = docComment(field.Doc)
+					}
+					res[strings.ToLower(m[1])] = tc
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// docComment returns the paragraphs that follow the generated summary of the doc,
+// the indented blocks, like the list of indexes, are skipped
+func docComment(doc *ast.CommentGroup) string {
+	paragraphs := strings.Split(doc.Text(), "\n\n")
+	var comment []string
+	for _, p := range paragraphs[1:] {
+		if !strings.HasPrefix(p, "\t") {
+			comment = append(comment, p)
+		}
+	}
+	return normalizeComment(strings.Join(comment, "\n\n"))
+}
+
+func normalizeComment(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRightFunc(l, unicode.IsSpace)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/effective-security/x/configloader"
+	"github.com/effective-security/xdb/mocks/mockschema"
+	dbschema "github.com/effective-security/xdb/schema"
+	"github.com/golang/mock/gomock"
+)
+
+func (s *testSuite) TestCommentOn() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	res[0].Comment = "Registered organizations"
+	res[0].Columns[1].Comment = "Display name,\nshown in UI"
+
+	dir := s.T().TempDir()
+	models := filepath.Join(dir, "model")
+	schemas := filepath.Join(dir, "schema")
+
+	gen := GenerateCmd{
+		DB:        "org",
+		OutModel:  models,
+		OutSchema: schemas,
+	}
+	err = gen.generate(s.Ctl, "postgres", "org", res)
+	require.NoError(err)
+
+	fn := filepath.Join(models, modelFileName)
+	code, err := os.ReadFile(fn)
+	require.NoError(err)
+	s.Contains(string(code), "//\tunique_orgs_name: UNIQUE [name]\n//\n// Registered organizations\ntype Org struct {")
+	s.Contains(string(code), "\t// Name represents 'name' column of 'character varying'\n\t//\n\t// Display name,\n\t// shown in UI\n\tName string")
+
+	scode, err := os.ReadFile(filepath.Join(schemas, schemaFileName))
+	require.NoError(err)
+	s.Contains(string(scode), "// OrgTable provides table info for 'org'\n//\n// Registered organizations\nvar OrgTable")
+	s.Contains(string(scode), `Comment:    "Registered organizations",`)
+
+	ctrl := gomock.NewController(s.T())
+	mock := mockschema.NewMockProvider(ctrl)
+	s.Ctl.WithSchemaProvider(mock)
+	mock.EXPECT().Name().Return("postgres").AnyTimes()
+	mock.EXPECT().ListTables(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(res, nil).AnyTimes()
+
+	cmd := CommentOnCmd{
+		DB:     "org",
+		Models: models,
+	}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.Equal("-- no changes\n", s.Out.String())
+
+	edited := strings.Replace(string(code), "// Registered organizations", "// Customer's organizations", 1)
+	edited = strings.Replace(edited, "\t//\n\t// Display name,\n\t// shown in UI\n", "", 1)
+	edited = strings.Replace(edited,
+		"\t// Email represents 'email' column of 'character varying'\n",
+		"\t// Email represents 'email' column of 'character varying'\n\t//\n\t// Contact email\n", 1)
+	require.NoError(os.WriteFile(fn, []byte(edited), 0666))
+
+	s.Out.Reset()
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.Equal("COMMENT ON TABLE public.org IS 'Customer''s organizations';\n"+
+		"COMMENT ON COLUMN public.org.name IS NULL;\n"+
+		"COMMENT ON COLUMN public.org.email IS 'Contact email';\n", s.Out.String())
+}
+
+func (s *testSuite) TestCommentOnNotSupported() {
+	ctrl := gomock.NewController(s.T())
+	mock := mockschema.NewMockProvider(ctrl)
+	s.Ctl.WithSchemaProvider(mock)
+	mock.EXPECT().Name().Return("sqlite3").AnyTimes()
+
+	cmd := CommentOnCmd{
+		DB:     "org",
+		Models: s.T().TempDir(),
+	}
+	s.EqualError(cmd.Run(s.Ctl), "comments are not supported by sqlite3")
+}
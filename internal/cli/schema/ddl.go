@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb/pkg/cli"
+	"github.com/effective-security/xdb/schema"
+)
+
+// DDLCmd prints CREATE statements for database schema
+type DDLCmd struct {
+	DB           string   `help:"database name" required:""`
+	Schema       string   `help:"optional schema name to filter"`
+	Table        []string `help:"optional, list of tables, default: all tables"`
+	Dependencies bool     `help:"optional, to discover all dependencies"`
+	Target       string   `help:"optional, target provider: postgres|sqlserver|mysql|sqlite3, default: provider of the database" enum:",postgres,sqlserver,mysql,sqlite3" default:""`
+	TargetSchema string   `help:"optional, schema name of the created objects, default: source schema"`
+}
+
+// Run the command
+func (a *DDLCmd) Run(ctx *cli.Cli) error {
+	r, err := ctx.SchemaProvider(a.DB)
+	if err != nil {
+		return err
+	}
+
+	tables, err := r.ListTables(ctx.Context(), a.Schema, a.Table, a.Dependencies)
+	if err != nil {
+		return err
+	}
+	fks, err := r.ListForeignKeys(ctx.Context(), a.Schema, a.Table)
+	if err != nil {
+		return err
+	}
+
+	if a.TargetSchema != "" {
+		for _, t := range tables {
+			t.Schema = a.TargetSchema
+		}
+		for _, fk := range fks {
+			fk.Schema = a.TargetSchema
+			fk.RefSchema = a.TargetSchema
+		}
+	}
+
+	target := values.StringsCoalesce(a.Target, r.Name())
+	list := schema.CreateDDL(r.Name(), target, tables, fks)
+	if len(list) == 0 {
+		fmt.Fprintln(ctx.Writer(), "-- no tables")
+		return nil
+	}
+	fmt.Fprintln(ctx.Writer(), strings.Join(list, "\n\n"))
+	return nil
+}
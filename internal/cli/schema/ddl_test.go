@@ -0,0 +1,58 @@
+package schema
+
+import (
+	"github.com/effective-security/x/configloader"
+	"github.com/effective-security/xdb/mocks/mockschema"
+	dbschema "github.com/effective-security/xdb/schema"
+	"github.com/golang/mock/gomock"
+)
+
+func (s *testSuite) TestDDL() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	fks := dbschema.ForeignKeys{
+		{
+			Name:      "fk_orgmember_org",
+			Schema:    "public",
+			Table:     "orgmember",
+			Column:    "org_id",
+			RefSchema: "public",
+			RefTable:  "org",
+			RefColumn: "id",
+		},
+	}
+
+	ctrl := gomock.NewController(s.T())
+	mock := mockschema.NewMockProvider(ctrl)
+	s.Ctl.WithSchemaProvider(mock)
+	mock.EXPECT().Name().Return("postgres").AnyTimes()
+	mock.EXPECT().ListTables(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(res, nil).AnyTimes()
+	mock.EXPECT().ListForeignKeys(gomock.Any(), gomock.Any(), gomock.Any()).Return(fks, nil).AnyTimes()
+
+	cmd := DDLCmd{DB: "org"}
+	require.NoError(cmd.Run(s.Ctl))
+	s.HasText(
+		"CREATE TABLE public.org (\n\tid bigint NOT NULL,\n\tname character varying(64) NOT NULL,",
+		"\tquota jsonb,\n\tsettings jsonb,\n\tPRIMARY KEY (id)\n);",
+		"CREATE UNIQUE INDEX membership ON public.orgmember (org_id, user_id);",
+		"ALTER TABLE public.orgmember ADD CONSTRAINT fk_orgmember_org FOREIGN KEY (org_id) REFERENCES public.org (id);",
+	)
+	s.NotContains(s.Out.String(), "pkey")
+
+	s.Out.Reset()
+	cmd.Target = "sqlserver"
+	cmd.TargetSchema = "dbo"
+	require.NoError(cmd.Run(s.Ctl))
+	s.HasText(
+		"CREATE TABLE dbo.org (\n\tid bigint NOT NULL,\n\tname nvarchar(64) NOT NULL,",
+		"\tcreated_at datetimeoffset,",
+		"\tquota nvarchar(max),",
+		"\tdirty bit NOT NULL,",
+		"CREATE UNIQUE INDEX unique_users_email ON dbo.user (email);",
+		"ALTER TABLE dbo.orgmember ADD CONSTRAINT fk_orgmember_org FOREIGN KEY (org_id) REFERENCES dbo.org (id);",
+	)
+	s.NotContains(s.Out.String(), "public.")
+}
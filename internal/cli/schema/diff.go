@@ -0,0 +1,120 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb/pkg/cli"
+	"github.com/effective-security/xdb/schema"
+	"github.com/pkg/errors"
+)
+
+// DiffCmd prints migration statements from the previous schema to the target schema,
+// each schema is loaded from a snapshot file or from a database
+type DiffCmd struct {
+	DB      string `help:"database name of the target schema, if --to is not specified"`
+	Schema  string `help:"optional schema name to filter"`
+	From    string `help:"snapshot file of the previous schema"`
+	FromDB  string `name:"from-db" help:"database name of the previous schema, instead of --from snapshot"`
+	To      string `help:"snapshot file of the target schema, instead of --db"`
+	Renames string `help:"optional, path to column renames file"`
+}
+
+// Run the command
+func (a *DiffCmd) Run(ctx *cli.Cli) error {
+	if (a.From == "") == (a.FromDB == "") {
+		return errors.New("either --from or --from-db must be specified")
+	}
+	if (a.To == "") == (a.DB == "") {
+		return errors.New("either --to or --db must be specified")
+	}
+
+	fromProvider, from, err := a.listTables(ctx, a.From, a.FromDB)
+	if err != nil {
+		return err
+	}
+	toProvider, to, err := a.listTables(ctx, a.To, a.DB)
+	if err != nil {
+		return err
+	}
+	// the legacy snapshot does not specify the provider
+	provider := values.StringsCoalesce(toProvider, fromProvider)
+
+	var renames *schema.Renames
+	if a.Renames != "" {
+		renames, err = schema.LoadRenames(a.Renames)
+		if err != nil {
+			return err
+		}
+	}
+
+	// warn about the names of new objects only
+	existing := map[string]bool{}
+	for _, t := range from {
+		table := t.Schema + "." + t.Name
+		existing[strings.ToLower(table)] = true
+		for _, c := range t.Columns {
+			existing[strings.ToLower(table+"."+c.Name)] = true
+		}
+		for _, idx := range t.Indexes {
+			existing[strings.ToLower(table+"."+idx.Name)] = true
+		}
+	}
+	printNameWarnings(ctx, schema.ValidateNames(provider, to, nil), existing)
+
+	list := schema.Diff(provider, from, to, renames)
+	if len(list) == 0 {
+		fmt.Fprintln(ctx.Writer(), "-- no changes")
+		return nil
+	}
+	fmt.Fprintln(ctx.Writer(), strings.Join(list, "\n\n"))
+	return nil
+}
+
+// listTables returns the provider name and the tables of the schema
+// from the snapshot file, or from the database
+func (a *DiffCmd) listTables(ctx *cli.Cli, snapshotFile, dbName string) (string, schema.Tables, error) {
+	var r schema.Provider
+	if snapshotFile != "" {
+		snapshot, err := schema.LoadSnapshot(snapshotFile)
+		if err != nil {
+			return "", nil, err
+		}
+		r = schema.NewSnapshotProvider(snapshot)
+	} else {
+		var err error
+		r, err = ctx.SchemaProvider(dbName)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	tables, err := r.ListTables(ctx.Context(), a.Schema, nil, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return r.Name(), tables, nil
+}
+
+// RenameColumnCmd records the column rename in the renames file
+type RenameColumnCmd struct {
+	Renames string `help:"path to column renames file" required:""`
+	Column  string `arg:"" help:"old column name in schema.table.column format"`
+	To      string `arg:"" help:"new column name"`
+}
+
+// Run the command
+func (a *RenameColumnCmd) Run(ctx *cli.Cli) error {
+	renames, err := schema.LoadRenames(a.Renames)
+	if err != nil {
+		return err
+	}
+	if err = renames.AddColumn(a.Column, a.To); err != nil {
+		return err
+	}
+	if err = renames.Save(a.Renames); err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.Writer(), "%s renamed to %s\n", a.Column, a.To)
+	return nil
+}
@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/effective-security/x/configloader"
+	"github.com/effective-security/xdb/mocks/mockschema"
+	dbschema "github.com/effective-security/xdb/schema"
+	"github.com/golang/mock/gomock"
+)
+
+func (s *testSuite) TestDiff() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	// rename user.name to display_name
+	res[3].Columns[3].Name = "display_name"
+
+	ctrl := gomock.NewController(s.T())
+	mock := mockschema.NewMockProvider(ctrl)
+	s.Ctl.WithSchemaProvider(mock)
+	mock.EXPECT().Name().Return("postgres").AnyTimes()
+	mock.EXPECT().ListTables(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(res, nil).AnyTimes()
+
+	renames := filepath.Join(s.T().TempDir(), "renames.yaml")
+
+	cmd := DiffCmd{
+		DB:      "org",
+		From:    "testdata/pg_columns.json",
+		Renames: renames,
+	}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText(
+		"ALTER TABLE public.user ADD COLUMN display_name character varying(64) NOT NULL;",
+		"ALTER TABLE public.user DROP COLUMN name;",
+	)
+
+	s.Out.Reset()
+	rename := RenameColumnCmd{
+		Renames: renames,
+		Column:  "public.user.name",
+		To:      "display_name",
+	}
+	err = rename.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText("public.user.name renamed to display_name")
+
+	s.Out.Reset()
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText("ALTER TABLE public.user RENAME COLUMN name TO display_name;")
+	s.NotContains(s.Out.String(), "DROP COLUMN")
+
+	rename.Column = "user.name"
+	err = rename.Run(s.Ctl)
+	s.EqualError(err, `invalid column name "user.name", expected schema.table.column format`)
+
+	s.Out.Reset()
+	gen := GenerateCmd{
+		PkgModel:  "model",
+		PkgSchema: "schema",
+		DB:        "testdb",
+		Renames:   renames,
+	}
+	err = gen.generate(s.Ctl, "postgres", "org", res)
+	require.NoError(err)
+	s.HasText(
+		"// Name returns 'display_name' column, that was renamed from 'name'.\n//\n// Deprecated: use DisplayName field.\nfunc (m *User) Name() string {\n\treturn m.DisplayName\n}",
+		"// Deprecated: 'name' column was renamed to 'display_name', use DisplayName\n\tName schema.Column\n",
+		"\tName:          schema.Column{Name: \"display_name\"",
+	)
+
+	// the old field name is used by another column
+	s.Out.Reset()
+	res[3].Columns = append(res[3].Columns, &dbschema.Column{Name: "name", Type: "text", Nullable: true})
+	err = gen.generate(s.Ctl, "postgres", "org", res)
+	require.NoError(err)
+	s.NotContains(s.Out.String(), "Deprecated: 'name'")
+}
+
+func (s *testSuite) TestDiffFromTo() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	// the email is extended, and the name is nullable
+	res[3].Columns[1].MaxLength = 256
+	res[3].Columns[3].Nullable = true
+
+	to := filepath.Join(s.T().TempDir(), "to.json")
+	f, err := os.Create(to)
+	require.NoError(err)
+	err = (&dbschema.Snapshot{
+		Version:  dbschema.SnapshotVersion,
+		Provider: "sqlserver",
+		Tables:   res,
+	}).Save(f)
+	require.NoError(err)
+	require.NoError(f.Close())
+
+	cmd := DiffCmd{
+		From: "testdata/pg_columns.json",
+		To:   to,
+	}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.EqualOut("ALTER TABLE public.user ALTER COLUMN email character varying(256) NOT NULL;\n\n" +
+		"ALTER TABLE public.user ALTER COLUMN name character varying(64) NULL;\n")
+
+	// the previous schema from the database
+	ctrl := gomock.NewController(s.T())
+	mock := mockschema.NewMockProvider(ctrl)
+	s.Ctl.WithSchemaProvider(mock)
+	mock.EXPECT().Name().Return("postgres").AnyTimes()
+	mock.EXPECT().ListTables(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(res, nil).AnyTimes()
+
+	s.Out.Reset()
+	cmd = DiffCmd{
+		FromDB: "org",
+		To:     to,
+	}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.EqualOut("-- no changes\n")
+
+	cmd = DiffCmd{DB: "org"}
+	s.EqualError(cmd.Run(s.Ctl), "either --from or --from-db must be specified")
+	cmd = DiffCmd{From: "testdata/pg_columns.json"}
+	s.EqualError(cmd.Run(s.Ctl), "either --to or --db must be specified")
+	cmd = DiffCmd{From: "testdata/pg_columns.json", To: to, DB: "org"}
+	s.EqualError(cmd.Run(s.Ctl), "either --to or --db must be specified")
+}
@@ -0,0 +1,297 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/effective-security/xdb/pkg/cli"
+	"github.com/effective-security/xdb/schema"
+	"github.com/pkg/errors"
+)
+
+// DocsCmd generates documentation for database schema
+type DocsCmd struct {
+	DB     string   `help:"database name" required:""`
+	Schema string   `help:"optional schema name to filter"`
+	Table  []string `help:"optional, list of tables, default: all tables"`
+	Views  bool     `help:"optional, to include views"`
+	Format string   `help:"output format: markdown|html" enum:"markdown,html" default:"markdown"`
+	Out    string   `help:"optional, folder name to store documentation files, one per schema, default: stdout"`
+}
+
+// Run the command
+func (a *DocsCmd) Run(ctx *cli.Cli) error {
+	r, err := ctx.SchemaProvider(a.DB)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.ListTables(ctx.Context(), a.Schema, a.Table, false)
+	if err != nil {
+		return err
+	}
+
+	if a.Views {
+		views, err := r.ListViews(ctx.Context(), a.Schema, a.Table)
+		if err != nil {
+			return err
+		}
+		res = append(res, views...)
+	}
+
+	fks, err := r.ListForeignKeys(ctx.Context(), a.Schema, nil)
+	if err != nil {
+		return err
+	}
+
+	ext := ".md"
+	if a.Format == "html" {
+		ext = ".html"
+	}
+
+	for _, doc := range buildDocs(a.DB, res, fks, ext) {
+		code, err := a.render(doc)
+		if err != nil {
+			return err
+		}
+		if err = writeCode(ctx, a.Out, doc.Schema+ext, code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *DocsCmd) render(doc *schemaDoc) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if a.Format == "html" {
+		err = htmlDocsTemplate.Execute(&buf, doc)
+	} else {
+		err = markdownDocsTemplate.Execute(&buf, doc)
+	}
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to render docs for schema %s", doc.Schema)
+	}
+	return buf.Bytes(), nil
+}
+
+// schemaDoc provides documentation model for one schema
+type schemaDoc struct {
+	DB     string
+	Schema string
+	Tables []*tableDoc
+}
+
+type tableDoc struct {
+	*schema.Table
+	Columns      []*columnDoc
+	ForeignKeys  []*fkDoc
+	ReferencedBy []*fkDoc
+}
+
+type columnDoc struct {
+	*schema.Column
+	Ref *fkDoc
+}
+
+// fkDoc describes the link to the referenced column
+type fkDoc struct {
+	*schema.ForeignKey
+	// Link to the referenced or referencing table
+	Link string
+}
+
+// buildDocs groups tables by schema, and links FKs between tables,
+// ext is used to link tables in other schemas
+func buildDocs(dbName string, tables schema.Tables, fks schema.ForeignKeys, ext string) []*schemaDoc {
+	fkMap := map[string]*schema.ForeignKey{}
+	refMap := map[string][]*schema.ForeignKey{}
+	for _, fk := range fks {
+		fkMap[strings.ToLower(fk.ColumnSchemaName())] = fk
+		key := strings.ToLower(fk.RefSchema + "." + fk.RefTable)
+		refMap[key] = append(refMap[key], fk)
+	}
+
+	docs := map[string]*schemaDoc{}
+	for _, t := range tables {
+		doc := docs[t.Schema]
+		if doc == nil {
+			doc = &schemaDoc{DB: dbName, Schema: t.Schema}
+			docs[t.Schema] = doc
+		}
+
+		td := &tableDoc{Table: t}
+		for _, c := range t.Columns {
+			cd := &columnDoc{Column: c}
+			fk := fkMap[strings.ToLower(t.Schema+"."+t.Name+"."+c.Name)]
+			if fk != nil {
+				cd.Ref = &fkDoc{
+					ForeignKey: fk,
+					Link:       docLink(t.Schema, fk.RefSchema, fk.RefTable, ext),
+				}
+				td.ForeignKeys = append(td.ForeignKeys, cd.Ref)
+			}
+			td.Columns = append(td.Columns, cd)
+		}
+		for _, fk := range refMap[strings.ToLower(t.Schema+"."+t.Name)] {
+			td.ReferencedBy = append(td.ReferencedBy, &fkDoc{
+				ForeignKey: fk,
+				Link:       docLink(t.Schema, fk.Schema, fk.Table, ext),
+			})
+		}
+		sort.Slice(td.ReferencedBy, func(i, j int) bool {
+			return td.ReferencedBy[i].ColumnSchemaName() < td.ReferencedBy[j].ColumnSchemaName()
+		})
+
+		doc.Tables = append(doc.Tables, td)
+	}
+
+	var list []*schemaDoc
+	for _, doc := range docs {
+		sort.Slice(doc.Tables, func(i, j int) bool {
+			return doc.Tables[i].Name < doc.Tables[j].Name
+		})
+		list = append(list, doc)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Schema < list[j].Schema
+	})
+	return list
+}
+
+// docLink returns the link to the table anchor,
+// tables in other schemas are documented in other files
+func docLink(fromSchema, schemaName, table, ext string) string {
+	anchor := "#" + docAnchor(table)
+	if strings.EqualFold(fromSchema, schemaName) {
+		return anchor
+	}
+	return schemaName + ext + anchor
+}
+
+func docAnchor(table string) string {
+	return strings.ToLower(table)
+}
+
+// mdEscape escapes the value to be used in Markdown table cell
+func mdEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var docsFuncMap = map[string]any{
+	"anchor":   docAnchor,
+	"md":       mdEscape,
+	"join":     strings.Join,
+	"yesno":    func(v bool) string { return map[bool]string{true: "YES", false: "NO"}[v] },
+	"typeName": docTypeName,
+}
+
+func docTypeName(c *schema.Column) string {
+	typ := c.Type
+	if c.UdtType != "" && c.UdtType != c.Type {
+		typ += " (" + c.UdtType + ")"
+	}
+	if c.MaxLength > 0 {
+		typ += fmt.Sprintf("[%d]", c.MaxLength)
+	}
+	return typ
+}
+
+var markdownDocsTemplate = template.Must(template.New("markdown").Funcs(docsFuncMap).Parse(markdownDocsTemplateText))
+
+var htmlDocsTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(docsFuncMap).Parse(htmlDocsTemplateText))
+
+var markdownDocsTemplateText = `<!-- DO NOT EDIT! This file is MACHINE GENERATED -->
+# Schema {{ .Schema }}
+
+Database: {{ .DB }}
+
+| Name | Type | Columns |
+|------|------|---------|
+{{- range .Tables }}
+| [{{ .Name }}](#{{ anchor .Name }}) | {{ if .IsView }}view{{ else }}table{{ end }} | {{ len .Columns }} |
+{{- end }}
+{{ range .Tables }}
+<a name="{{ anchor .Name }}"></a>
+
+## {{ .Schema }}.{{ .Name }}
+{{ if .PrimaryKey }}
+Primary key: {{ .PrimaryKey.Name }}
+{{ end }}
+| Column | Type | Nullable | Default | References | Comment |
+|--------|------|----------|---------|------------|---------|
+{{- range .Columns }}
+| {{ .Name }} | {{ md (typeName .Column) }} | {{ yesno .Nullable }} | {{ md .Default }} | {{ with .Ref }}[{{ .RefSchema }}.{{ .RefTable }}.{{ .RefColumn }}]({{ .Link }}){{ end }} | {{ md .Comment }} |
+{{- end }}
+{{- if .Indexes }}
+
+### Indexes
+
+| Name | Columns | Primary | Unique |
+|------|---------|---------|--------|
+{{- range .Indexes }}
+| {{ .Name }} | {{ join .ColumnNames ", " }} | {{ yesno .IsPrimary }} | {{ yesno .IsUnique }} |
+{{- end }}
+{{- end }}
+{{- if .ReferencedBy }}
+
+### Referenced by
+{{ range .ReferencedBy }}
+- [{{ .Schema }}.{{ .Table }}.{{ .Column }}]({{ .Link }}) ({{ .Name }})
+{{- end }}
+{{- end }}
+{{ end }}`
+
+var htmlDocsTemplateText = `<!DOCTYPE html>
+<!-- DO NOT EDIT! This file is MACHINE GENERATED -->
+<html>
+<head>
+<meta charset="utf-8">
+<title>Schema {{ .Schema }}</title>
+</head>
+<body>
+<h1>Schema {{ .Schema }}</h1>
+<p>Database: {{ .DB }}</p>
+<table>
+<tr><th>Name</th><th>Type</th><th>Columns</th></tr>
+{{- range .Tables }}
+<tr><td><a href="#{{ anchor .Name }}">{{ .Name }}</a></td><td>{{ if .IsView }}view{{ else }}table{{ end }}</td><td>{{ len .Columns }}</td></tr>
+{{- end }}
+</table>
+{{ range .Tables }}
+<h2 id="{{ anchor .Name }}">{{ .Schema }}.{{ .Name }}</h2>
+{{- if .PrimaryKey }}
+<p>Primary key: {{ .PrimaryKey.Name }}</p>
+{{- end }}
+<table>
+<tr><th>Column</th><th>Type</th><th>Nullable</th><th>Default</th><th>References</th><th>Comment</th></tr>
+{{- range .Columns }}
+<tr><td>{{ .Name }}</td><td>{{ typeName .Column }}</td><td>{{ yesno .Nullable }}</td><td>{{ .Default }}</td><td>{{ with .Ref }}<a href="{{ .Link }}">{{ .RefSchema }}.{{ .RefTable }}.{{ .RefColumn }}</a>{{ end }}</td><td>{{ .Comment }}</td></tr>
+{{- end }}
+</table>
+{{- if .Indexes }}
+<h3>Indexes</h3>
+<table>
+<tr><th>Name</th><th>Columns</th><th>Primary</th><th>Unique</th></tr>
+{{- range .Indexes }}
+<tr><td>{{ .Name }}</td><td>{{ join .ColumnNames ", " }}</td><td>{{ yesno .IsPrimary }}</td><td>{{ yesno .IsUnique }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .ReferencedBy }}
+<h3>Referenced by</h3>
+<ul>
+{{- range .ReferencedBy }}
+<li><a href="{{ .Link }}">{{ .Schema }}.{{ .Table }}.{{ .Column }}</a> ({{ .Name }})</li>
+{{- end }}
+</ul>
+{{- end }}
+{{ end }}
+</body>
+</html>
+`
@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/effective-security/x/configloader"
+	"github.com/effective-security/xdb/mocks/mockschema"
+	dbschema "github.com/effective-security/xdb/schema"
+	"github.com/golang/mock/gomock"
+)
+
+func (s *testSuite) TestDocs() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	res[0].Columns[0].Comment = "unique | id"
+	res[0].Columns[12].Default = "now()"
+
+	fks := dbschema.ForeignKeys{
+		{
+			Name:      "fk_orgmember_org",
+			Schema:    "public",
+			Table:     "orgmember",
+			Column:    "org_id",
+			RefSchema: "public",
+			RefTable:  "org",
+			RefColumn: "id",
+		},
+		{
+			Name:      "fk_orgmember_user",
+			Schema:    "public",
+			Table:     "orgmember",
+			Column:    "user_id",
+			RefSchema: "auth",
+			RefTable:  "user",
+			RefColumn: "id",
+		},
+	}
+	views := dbschema.Tables{
+		{
+			Schema: "auth",
+			Name:   "vwUser",
+			IsView: true,
+			Columns: dbschema.Columns{
+				{Name: "id", Type: "bigint", Position: 1},
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(s.T())
+	mock := mockschema.NewMockProvider(ctrl)
+	s.Ctl.WithSchemaProvider(mock)
+	mock.EXPECT().ListTables(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(res, nil).AnyTimes()
+	mock.EXPECT().ListViews(gomock.Any(), gomock.Any(), gomock.Any()).Return(views, nil).AnyTimes()
+	mock.EXPECT().ListForeignKeys(gomock.Any(), gomock.Any(), gomock.Any()).Return(fks, nil).AnyTimes()
+
+	cmd := DocsCmd{
+		DB:     "testdb",
+		Format: "markdown",
+	}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText(
+		"# Schema public\n",
+		"| [org](#org) | table | 15 |",
+		"<a name=\"org\"></a>\n\n## public.org\n\nPrimary key: id\n",
+		"| id | bigint (int8) | NO |  |  | unique \\| id |",
+		"| updated_at | timestamp with time zone (timestamptz) | YES | now() |  |  |",
+		"| orgs_pkey | id | YES | YES |",
+		"| org_id | bigint (int8) | NO |  | [public.org.id](#org) |  |",
+		"[auth.user.id](auth.md#user)",
+		"### Referenced by\n\n- [public.orgmember.org_id](#orgmember) (fk_orgmember_org)",
+	)
+	s.NotContains(s.Out.String(), "vwUser")
+
+	dir := s.T().TempDir()
+	cmd = DocsCmd{
+		DB:     "testdb",
+		Views:  true,
+		Format: "html",
+		Out:    dir,
+	}
+	s.Out.Reset()
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.Empty(s.Out.String())
+
+	html, err := os.ReadFile(filepath.Join(dir, "public.html"))
+	require.NoError(err)
+	s.Contains(string(html), `<h2 id="org">public.org</h2>`)
+	s.Contains(string(html), `<td>unique | id</td>`)
+	s.Contains(string(html), `<a href="auth.html#user">auth.user.id</a>`)
+
+	html, err = os.ReadFile(filepath.Join(dir, "auth.html"))
+	require.NoError(err)
+	s.Contains(string(html), `<tr><td><a href="#vwuser">vwUser</a></td><td>view</td><td>1</td></tr>`)
+}
@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/effective-security/xdb/schema"
+	"github.com/pkg/errors"
+)
+
+// declaredIndex provides the index declared in types definition
+type declaredIndex struct {
+	// Name of the index, default: idx_<table>_<columns>
+	Name    string   `json:"name" yaml:"name"`
+	Columns []string `json:"columns" yaml:"columns"`
+	Unique  bool     `json:"unique" yaml:"unique"`
+}
+
+// indexesMap provides the declared indexes by table in schema.table format
+var indexesMap = map[string][]*declaredIndex{}
+
+// indexMigration provides the statements to create the declared indexes
+// that are missing in the database
+type indexMigration struct {
+	Up   []string
+	Down []string
+}
+
+// applyDeclaredIndexes adds the declared indexes missing in the table to the model,
+// and returns the created indexes.
+// The declared index is found by name, or by the same list of columns.
+func applyDeclaredIndexes(t *schema.Table) (schema.Indexes, error) {
+	var created schema.Indexes
+	tableName := t.Schema + "." + t.Name
+	for _, d := range indexesMap[tableName] {
+		if len(d.Columns) == 0 {
+			return nil, errors.Errorf("index %q on %s: columns must be specified", d.Name, tableName)
+		}
+		name := d.Name
+		if name == "" {
+			name = strings.ToLower(fmt.Sprintf("idx_%s_%s", t.Name, strings.Join(d.Columns, "_")))
+		}
+		if findIndex(t.Indexes, name, d.Columns) != nil {
+			continue
+		}
+
+		var cols schema.Columns
+		for _, cn := range d.Columns {
+			var col *schema.Column
+			for _, c := range t.Columns {
+				if strings.EqualFold(c.Name, cn) {
+					col = c
+					break
+				}
+			}
+			if col == nil {
+				return nil, errors.Errorf("index %q on %s: column %q not found", name, tableName, cn)
+			}
+			cols = append(cols, col)
+		}
+
+		idx := &schema.Index{
+			Name:        name,
+			IsUnique:    d.Unique,
+			ColumnNames: cols.Names(),
+			SchemaName:  tableName + "." + name,
+		}
+		for _, c := range cols {
+			c.Indexes = append(c.Indexes, idx)
+		}
+		t.Indexes = append(t.Indexes, idx)
+		created = append(created, idx)
+	}
+	return created, nil
+}
+
+// findIndex returns the index by name, or by the list of columns
+func findIndex(list schema.Indexes, name string, columns []string) *schema.Index {
+	for _, idx := range list {
+		if strings.EqualFold(idx.Name, name) {
+			return idx
+		}
+		if len(idx.ColumnNames) != len(columns) {
+			continue
+		}
+		same := true
+		for i, cn := range idx.ColumnNames {
+			if !strings.EqualFold(cn, columns[i]) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return idx
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/effective-security/xdb/schema"
+	"github.com/pkg/errors"
+)
+
+const (
+	jsonSchemaDraft        = "https://json-schema.org/draft/2020-12/schema"
+	jsonSchemaExt          = ".schema.json"
+	jsonSchemaComponents   = "components.json"
+	jsonSchemaIntegerType  = "integer"
+	jsonSchemaNumberType   = "number"
+	jsonSchemaStringType   = "string"
+	jsonSchemaBooleanType  = "boolean"
+	jsonSchemaArrayType    = "array"
+	jsonSchemaNullableType = "null"
+)
+
+// jsonSchema provides JSON Schema definition of the model,
+// that is also used as OpenAPI 3.1 component schema
+type jsonSchema struct {
+	Schema      string              `json:"$schema,omitempty"`
+	ID          string              `json:"$id,omitempty"`
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Type        any                 `json:"type,omitempty"`
+	Format      string              `json:"format,omitempty"`
+	MaxLength   uint32              `json:"maxLength,omitempty"`
+	Enum        []any               `json:"enum,omitempty"`
+	MediaType   string              `json:"contentMediaType,omitempty"`
+	Items       *jsonSchema         `json:"items,omitempty"`
+	Properties  *jsonSchemaProperty `json:"properties,omitempty"`
+}
+
+// jsonSchemaProperty provides the properties of the object in the order of columns
+type jsonSchemaProperty struct {
+	Names  []string
+	Values map[string]*jsonSchema
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (p *jsonSchemaProperty) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, name := range p.Names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		val, err := json.Marshal(p.Values[name])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonSchemaByGoType maps the Go types of the model to JSON Schema types,
+// as the types are serialized to JSON
+var jsonSchemaByGoType = map[string]jsonSchema{
+	"xdb.ID":           {Type: jsonSchemaIntegerType, Format: "int64"},
+	"xdb.ID32":         {Type: jsonSchemaIntegerType, Format: "int32"},
+	"int64":            {Type: jsonSchemaIntegerType, Format: "int64"},
+	"xdb.Int64":        {Type: jsonSchemaIntegerType, Format: "int64"},
+	"int32":            {Type: jsonSchemaIntegerType, Format: "int32"},
+	"int16":            {Type: jsonSchemaIntegerType, Format: "int32"},
+	"int8":             {Type: jsonSchemaIntegerType, Format: "int32"},
+	"xdb.Int32":        {Type: jsonSchemaIntegerType, Format: "int32"},
+	"float64":          {Type: jsonSchemaNumberType, Format: "double"},
+	"xdb.Float":        {Type: jsonSchemaNumberType, Format: "double"},
+	"xdb.Decimal":      {Type: jsonSchemaStringType, Format: "decimal"},
+	"float32":          {Type: jsonSchemaNumberType, Format: "float"},
+	"bool":             {Type: jsonSchemaBooleanType},
+	"xdb.Bool":         {Type: jsonSchemaBooleanType},
+	"string":           {Type: jsonSchemaStringType},
+	"xdb.NULLString":   {Type: jsonSchemaStringType},
+	"xdb.UUID":         {Type: jsonSchemaStringType, Format: "uuid"},
+	"xdb.Time":         {Type: jsonSchemaStringType, Format: "date-time"},
+	"xdb.Date":         {Type: jsonSchemaStringType, Format: "date"},
+	"xdb.Duration":     {Type: jsonSchemaStringType, Format: "duration"},
+	"[]byte":           {Type: jsonSchemaStringType, Format: "byte"},
+	"xdb.IDArray":      {Type: jsonSchemaArrayType, Items: &jsonSchema{Type: jsonSchemaIntegerType, Format: "int64"}},
+	"xdb.Array[int64]": {Type: jsonSchemaArrayType, Items: &jsonSchema{Type: jsonSchemaIntegerType, Format: "int64"}},
+	"xdb.Array[int32]": {Type: jsonSchemaArrayType, Items: &jsonSchema{Type: jsonSchemaIntegerType, Format: "int32"}},
+	"xdb.StringArray":  {Type: jsonSchemaArrayType, Items: &jsonSchema{Type: jsonSchemaStringType}},
+}
+
+// columnJSONSchema returns JSON Schema of the column,
+// the type of nullable column allows null,
+// and the custom types from the types definition allow any value
+func columnJSONSchema(c *schema.Column) *jsonSchema {
+	var res jsonSchema
+	if len(c.Enum) > 0 {
+		res.Type = jsonSchemaStringType
+		for _, v := range c.Enum {
+			res.Enum = append(res.Enum, v)
+		}
+	} else if def, ok := jsonSchemaByGoType[toGoType(c)]; ok {
+		res = def
+		if isJSONColumn(c) {
+			res.MediaType = "application/json"
+		}
+	}
+
+	if res.Type == jsonSchemaStringType && res.Format == "" && c.MaxLength > 0 {
+		res.MaxLength = c.MaxLength
+	}
+	if c.Nullable && res.Type != nil {
+		res.Type = []string{res.Type.(string), jsonSchemaNullableType}
+		if res.Enum != nil {
+			res.Enum = append(res.Enum, nil)
+		}
+	}
+	res.Description = strings.TrimSpace(c.Comment)
+	return &res
+}
+
+// modelJSONSchema returns JSON Schema of the model,
+// the properties are named by JSON names of the model fields
+func modelJSONSchema(td *tableDefinition) *jsonSchema {
+	props := &jsonSchemaProperty{
+		Values: map[string]*jsonSchema{},
+	}
+	for _, c := range td.Columns {
+		name := columnStructName(c)
+		props.Names = append(props.Names, name)
+		props.Values[name] = columnJSONSchema(c)
+	}
+	return &jsonSchema{
+		Title:       td.StructName,
+		Description: strings.TrimSpace(td.Comment),
+		Type:        "object",
+		Properties:  props,
+	}
+}
+
+// renderJSONSchema returns JSON Schema documents by file name,
+// one per model, and OpenAPI components with all models
+func renderJSONSchema(defs []*tableDefinition) (map[string][]byte, error) {
+	res := map[string][]byte{}
+	components := &jsonSchemaProperty{
+		Values: map[string]*jsonSchema{},
+	}
+	for _, td := range defs {
+		s := modelJSONSchema(td)
+		components.Names = append(components.Names, td.StructName)
+		components.Values[td.StructName] = s
+
+		doc := *s
+		doc.Schema = jsonSchemaDraft
+		doc.ID = td.StructName + jsonSchemaExt
+		js, err := json.MarshalIndent(&doc, "", "  ")
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to generate JSON schema for %s", td.StructName)
+		}
+		res[doc.ID] = append(js, '\n')
+	}
+
+	js, err := json.MarshalIndent(map[string]any{
+		"components": map[string]any{
+			"schemas": components,
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to generate OpenAPI components")
+	}
+	res[jsonSchemaComponents] = append(js, '\n')
+	return res, nil
+}
@@ -0,0 +1,57 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb/pkg/cli"
+	"github.com/effective-security/xdb/schema"
+)
+
+// LintCmd validates the names of database objects
+type LintCmd struct {
+	DB       string   `help:"database name" required:""`
+	Schema   string   `help:"optional schema name to filter"`
+	Table    []string `help:"optional, list of tables, default: all tables"`
+	Provider string   `help:"optional, target database provider to validate names against, postgres|sqlserver|mysql|sqlite3, default: provider of the database"`
+}
+
+// Run the command
+func (a *LintCmd) Run(ctx *cli.Cli) error {
+	r, err := ctx.SchemaProvider(a.DB)
+	if err != nil {
+		return err
+	}
+	tables, err := r.ListTables(ctx.Context(), a.Schema, a.Table, false)
+	if err != nil {
+		return err
+	}
+	fks, err := r.ListForeignKeys(ctx.Context(), a.Schema, a.Table)
+	if err != nil {
+		return err
+	}
+
+	list := schema.ValidateNames(values.StringsCoalesce(a.Provider, r.Name()), tables, fks)
+	if ctx.O == "json" || ctx.O == "yaml" {
+		return ctx.Print(list)
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(ctx.Writer(), "no issues")
+		return nil
+	}
+	for _, w := range list {
+		fmt.Fprintln(ctx.Writer(), w.String())
+	}
+	return nil
+}
+
+// printNameWarnings prints the warnings as SQL comments to the error output,
+// the warnings for the objects with names in skip are ignored
+func printNameWarnings(ctx *cli.Cli, list []*schema.NameWarning, skip map[string]bool) {
+	for _, w := range list {
+		if !skip[strings.ToLower(w.Name)] {
+			fmt.Fprintf(ctx.ErrWriter(), "-- warning: %s\n", w.String())
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/effective-security/x/configloader"
+	"github.com/effective-security/xdb/mocks/mockschema"
+	dbschema "github.com/effective-security/xdb/schema"
+	"github.com/golang/mock/gomock"
+)
+
+func (s *testSuite) TestLint() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+
+	ctrl := gomock.NewController(s.T())
+	mock := mockschema.NewMockProvider(ctrl)
+	s.Ctl.WithSchemaProvider(mock)
+	mock.EXPECT().Name().Return("postgres").AnyTimes()
+	mock.EXPECT().ListTables(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(res, nil).AnyTimes()
+	mock.EXPECT().ListForeignKeys(gomock.Any(), gomock.Any(), gomock.Any()).Return(dbschema.ForeignKeys{
+		{Schema: "public", Table: "orgmember", Name: "orgmember_org_id_fkey_" + strings.Repeat("x", 50)},
+	}, nil).AnyTimes()
+
+	cmd := LintCmd{DB: "org"}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText(
+		"table public.user: \"user\" is reserved keyword of postgres and must be quoted\n",
+		"foreign key public.orgmember.orgmember_org_id_fkey_xxx",
+		": exceeds 63 bytes limit of postgres, suggested: orgmember_org_id_fkey_xxx",
+	)
+
+	s.Out.Reset()
+	cmd.Provider = "sqlserver"
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.EqualOut("table public.user: \"user\" is reserved keyword of sqlserver and must be quoted\n")
+
+	s.Out.Reset()
+	cmd.Provider = "sqlite3"
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.EqualOut("no issues\n")
+}
+
+func (s *testSuite) TestDiffNameWarnings() {
+	require := s.Require()
+
+	res := dbschema.Tables{
+		{
+			Schema:  "public",
+			Name:    "order",
+			Columns: dbschema.Columns{{Name: "id", Type: "bigint"}, {Name: "limit", Type: "int"}},
+		},
+	}
+
+	ctrl := gomock.NewController(s.T())
+	mock := mockschema.NewMockProvider(ctrl)
+	s.Ctl.WithSchemaProvider(mock)
+	mock.EXPECT().Name().Return("postgres").AnyTimes()
+	mock.EXPECT().ListTables(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(res, nil).AnyTimes()
+
+	cmd := DiffCmd{
+		DB:   "org",
+		From: "testdata/pg_columns.json",
+	}
+	err := cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText(
+		"-- warning: table public.order: \"order\" is reserved keyword of postgres and must be quoted\n",
+		"-- warning: column public.order.limit: \"limit\" is reserved keyword of postgres and must be quoted\n",
+		"CREATE TABLE public.order (",
+	)
+	// the existing tables are not reported
+	s.NotContains(s.Out.String(), "table public.user:")
+}
@@ -0,0 +1,147 @@
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/effective-security/xdb/schema"
+	"github.com/ettle/strcase"
+	"github.com/pkg/errors"
+)
+
+const protoFileName = "model.proto"
+
+const (
+	protoTimestamp = "google.protobuf.Timestamp"
+	protoDuration  = "google.protobuf.Duration"
+	protoStruct    = "google.protobuf.Struct"
+)
+
+// protoTypeByGoType maps the Go types of the model to protobuf types
+var protoTypeByGoType = map[string]string{
+	"xdb.ID":           "uint64",
+	"xdb.ID32":         "uint32",
+	"int64":            "int64",
+	"xdb.Int64":        "int64",
+	"int32":            "int32",
+	"int16":            "int32",
+	"int8":             "int32",
+	"xdb.Int32":        "int32",
+	"float64":          "double",
+	"xdb.Float":        "double",
+	"xdb.Decimal":      "string",
+	"float32":          "float",
+	"bool":             "bool",
+	"xdb.Bool":         "bool",
+	"string":           "string",
+	"xdb.NULLString":   "string",
+	"xdb.UUID":         "string",
+	"[]byte":           "bytes",
+	"xdb.Time":         protoTimestamp,
+	"xdb.Date":         "string",
+	"xdb.Duration":     protoDuration,
+	"xdb.IDArray":      "repeated uint64",
+	"xdb.Array[int64]": "repeated int64",
+	"xdb.Array[int32]": "repeated int32",
+	"xdb.StringArray":  "repeated string",
+}
+
+// protoType returns protobuf type of the column,
+// the custom types from the types definition are mapped to string
+func protoType(c *schema.Column) string {
+	goType := toGoType(c)
+	if isJSONColumn(c) || strings.HasPrefix(goType, "xdb.JSON[") {
+		return protoStruct
+	}
+	if res, ok := protoTypeByGoType[goType]; ok {
+		return res
+	}
+	return "string"
+}
+
+// protoFieldName returns the name of the message field for the column
+func protoFieldName(c *schema.Column) string {
+	return strcase.ToSnake(c.Name)
+}
+
+type protoDefinition struct {
+	DB       string
+	Package  string
+	Imports  []string
+	Messages []*tableDefinition
+}
+
+var protoTemplateText = `// DO NOT EDIT!
+// This file is MACHINE GENERATED
+// DB: {{ .DB }}
+
+syntax = "proto3";
+
+package {{ .Package }};
+{{- if .Imports }}
+{{ range .Imports }}
+import "{{ . }}";
+{{- end }}
+{{- end }}
+{{- range .Messages }}
+
+// {{ .StructName }} represents one row from table '{{ .SchemaName }}.{{ .TableName }}'.
+{{- with .Comment }}
+//
+{{ goComment "" . }}
+{{- end }}
+message {{ .StructName }} {
+{{- range $i, $c := .Columns }}
+{{- with $c.Comment }}
+{{ goComment "    " . }}
+{{- end }}
+    {{ protoType $c }} {{ protoFieldName $c }} = {{ inc $i }};
+{{- end }}
+}
+{{- end }}
+`
+
+// renderProto returns .proto file with messages for the table models
+func renderProto(dbName, pkg string, defs []*tableDefinition) ([]byte, error) {
+	var protoTemplate = template.Must(template.New("proto").Funcs(templateFuncMap).Funcs(template.FuncMap{
+		"protoType":      protoType,
+		"protoFieldName": protoFieldName,
+		"inc":            func(i int) int { return i + 1 },
+	}).Parse(protoTemplateText))
+
+	pd := &protoDefinition{
+		DB:       dbName,
+		Package:  strings.ReplaceAll(pkg, "-", "_"),
+		Messages: defs,
+	}
+
+	var timestamp, duration, structs bool
+	for _, td := range defs {
+		for _, c := range td.Columns {
+			switch protoType(c) {
+			case protoTimestamp:
+				timestamp = true
+			case protoDuration:
+				duration = true
+			case protoStruct:
+				structs = true
+			}
+		}
+	}
+	if duration {
+		pd.Imports = append(pd.Imports, "google/protobuf/duration.proto")
+	}
+	if structs {
+		pd.Imports = append(pd.Imports, "google/protobuf/struct.proto")
+	}
+	if timestamp {
+		pd.Imports = append(pd.Imports, "google/protobuf/timestamp.proto")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := protoTemplate.Execute(buf, pd); err != nil {
+		return nil, errors.WithMessagef(err, "failed to generate proto")
+	}
+	return buf.Bytes(), nil
+}
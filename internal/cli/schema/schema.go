@@ -8,14 +8,17 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/effective-security/x/configloader"
 	"github.com/effective-security/x/slices"
 	"github.com/effective-security/x/values"
-	"github.com/effective-security/xdb/internal/cli"
+	"github.com/effective-security/xdb/pkg/cli"
 	"github.com/effective-security/xdb/schema"
+	"github.com/effective-security/xdb/xsql"
 	"github.com/ettle/strcase"
 	"github.com/gertd/go-pluralize"
 	"github.com/pkg/errors"
@@ -25,11 +28,20 @@ var pluralizeClient = pluralize.NewClient()
 
 // Cmd base command for schema
 type Cmd struct {
-	Generate    GenerateCmd     `cmd:"" help:"generate Go model for database schema"`
-	Columns     PrintColumnsCmd `cmd:"" help:"prints database schema"`
-	Tables      PrintTablesCmd  `cmd:"" help:"prints database tables and dependencies"`
-	Views       PrintViewsCmd   `cmd:"" help:"prints database views and dependencies"`
-	ForeignKeys PrintFKCmd      `cmd:"" help:"prints Foreign Keys"`
+	Generate       GenerateCmd       `cmd:"" help:"generate Go model for database schema"`
+	Columns        PrintColumnsCmd   `cmd:"" help:"prints database schema"`
+	Tables         PrintTablesCmd    `cmd:"" help:"prints database tables and dependencies"`
+	Views          PrintViewsCmd     `cmd:"" help:"prints database views and dependencies"`
+	ForeignKeys    PrintFKCmd        `cmd:"" help:"prints Foreign Keys"`
+	Verify         VerifyCmd         `cmd:"" help:"verify generated Go model against database schema"`
+	Snapshot       SnapshotCmd       `cmd:"" aliases:"dump" help:"save database schema snapshot for offline use"`
+	Docs           DocsCmd           `cmd:"" help:"generate Markdown or HTML documentation for database schema"`
+	Diff           DiffCmd           `cmd:"" help:"print migration statements from the previous schema to the target schema"`
+	DDL            DDLCmd            `cmd:"" name:"ddl" help:"print CREATE statements for database schema, optionally for another provider"`
+	RenameColumn   RenameColumnCmd   `cmd:"" help:"record column rename for diff and generated models"`
+	CommentOn      CommentOnCmd      `cmd:"" help:"print statements to update database comments from generated Go model"`
+	Lint           LintCmd           `cmd:"" help:"validate names of database objects against identifier length limits and reserved keywords"`
+	CacheMigration CacheMigrationCmd `cmd:"" help:"generate migration files for cache tables"`
 }
 
 // PrintColumnsCmd prints database schema
@@ -47,15 +59,25 @@ func (a *PrintColumnsCmd) Run(ctx *cli.Cli) error {
 	if err != nil {
 		return err
 	}
-	res, err := r.ListTables(ctx.Context(), a.Schema, a.Table, a.Dependencies)
-	if err != nil {
-		return err
-	}
 
-	_ = ctx.Print(res)
+	// print tables as they are read, the dependencies need the whole catalog
+	if s, ok := r.(schema.TableStreamer); ok && !a.Dependencies && ctx.O != "json" && ctx.O != "yaml" {
+		err = s.StreamTables(ctx.Context(), a.Schema, a.Table, func(t *schema.Table) error {
+			return ctx.Print(t)
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		res, err := r.ListTables(ctx.Context(), a.Schema, a.Table, a.Dependencies)
+		if err != nil {
+			return err
+		}
+		_ = ctx.Print(res)
+	}
 
 	if a.Views {
-		res, err = r.ListViews(ctx.Context(), a.Schema, a.Table)
+		res, err := r.ListViews(ctx.Context(), a.Schema, a.Table)
 		if err != nil {
 			return err
 		}
@@ -99,7 +121,11 @@ func (a *PrintTablesCmd) Run(ctx *cli.Cli) error {
 		return ctx.Print(res)
 	}
 	for _, t := range res {
-		fmt.Fprintf(w, "%s.%s\n", t.Schema, t.Name)
+		if t.Comment != "" {
+			fmt.Fprintf(w, "%s.%s\t-- %s\n", t.Schema, t.Name, strings.ReplaceAll(t.Comment, "\n", " "))
+		} else {
+			fmt.Fprintf(w, "%s.%s\n", t.Schema, t.Name)
+		}
 	}
 
 	return nil
@@ -156,19 +182,27 @@ func (a *PrintFKCmd) Run(ctx *cli.Cli) error {
 
 // GenerateCmd generates database schema
 type GenerateCmd struct {
-	DB           string   `help:"database name" required:""`
-	Schema       string   `help:"optional schema name to filter"`
-	Table        []string `help:"optional, list of tables, default: all tables"`
-	View         []string `help:"optional, list of views"`
-	Dependencies bool     `help:"optional, to discover all dependencies"`
-	OutModel     string   `help:"folder name to store model files"`
-	OutSchema    string   `help:"folder name to store schema files"`
-	PkgModel     string   `help:"package name to override from --out-model path"`
-	PkgSchema    string   `help:"package name to override from --out-schema path"`
-	StructSuffix string   `help:"optional, suffix for struct names"`
-	Imports      []string `help:"optional go imports"`
-	UseSchema    bool     `help:"optional, use schema name in table name"`
-	TypesDef     string   `help:"optional, path to types definition file"`
+	DB            string   `help:"database name" required:""`
+	Schema        string   `help:"optional schema name to filter"`
+	Table         []string `help:"optional, list of tables, default: all tables"`
+	View          []string `help:"optional, list of views"`
+	Dependencies  bool     `help:"optional, to discover all dependencies"`
+	OutModel      string   `help:"folder name to store model files"`
+	OutSchema     string   `help:"folder name to store schema files"`
+	OutProto      string   `help:"optional, folder name to store .proto file with messages for the models"`
+	OutJSONSchema string   `name:"out-jsonschema" help:"optional, folder name to store JSON Schema files for the models, and OpenAPI components"`
+	PkgModel      string   `help:"package name to override from --out-model path"`
+	PkgSchema     string   `help:"package name to override from --out-schema path"`
+	PkgProto      string   `help:"proto package name to override from --out-proto path"`
+	StructSuffix  string   `help:"optional, suffix for struct names"`
+	Imports       []string `help:"optional go imports"`
+	UseSchema     bool     `help:"optional, use schema name in table name"`
+	TypesDef      string   `help:"optional, path to types definition file"`
+	Renames       string   `help:"optional, path to column renames file, to keep deprecated aliases of renamed columns"`
+	GenCrud       bool     `help:"optional, generate CRUD repository for tables with primary key"`
+	// IndexMigration is the number of the migration for the indexes declared in types definition
+	IndexMigration uint   `help:"optional, number of the migration to create the indexes declared in types definition, that are missing in the database"`
+	OutMigration   string `help:"optional, folder name to store the index migration files, default: stdout"`
 }
 
 // Run the command
@@ -220,6 +254,100 @@ func tableStructName(t *schema.Table) string {
 	return goName(pluralizeClient.Singular(name)) + "Table"
 }
 
+// renamedColumns returns deprecated aliases for the renamed columns of the table,
+// the alias is skipped if the old field name is used by another column
+func renamedColumns(t *schema.Table, renames *schema.Renames) []*renamedColumn {
+	renamed := renames.TableColumns(t.Schema, t.Name)
+	if len(renamed) == 0 {
+		return nil
+	}
+
+	fields := map[string]bool{}
+	for _, c := range t.Columns {
+		fields[columnStructName(c)] = true
+	}
+
+	var list []*renamedColumn
+	for oldName, newName := range renamed {
+		var c *schema.Column
+		for _, col := range t.Columns {
+			if strings.EqualFold(col.Name, newName) {
+				c = col
+				break
+			}
+		}
+		oldField := goName(oldName)
+		if c == nil || fields[oldField] {
+			continue
+		}
+		fields[oldField] = true
+		list = append(list, &renamedColumn{
+			OldName:  oldName,
+			OldField: oldField,
+			Column:   c,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].OldField < list[j].OldField
+	})
+	return list
+}
+
+// softDeleteColumn returns the soft delete column of the table from types definition,
+// or nil if the table has no soft delete
+func softDeleteColumn(t *schema.Table) (*schema.Column, error) {
+	tableName := t.Schema + "." + t.Name
+	name, ok := softDeleteMap[tableName]
+	if !ok {
+		return nil, nil
+	}
+	for _, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			if !c.Nullable {
+				return nil, errors.Errorf("soft delete column %q on %s must be nullable", name, tableName)
+			}
+			return c, nil
+		}
+	}
+	return nil, errors.Errorf("soft delete column %q does not exist in %s", name, tableName)
+}
+
+// optimisticLockColumn returns the version column of the table from types definition,
+// or nil if the table has no optimistic lock
+func optimisticLockColumn(t *schema.Table) (*schema.Column, error) {
+	tableName := t.Schema + "." + t.Name
+	name, ok := optimisticLockMap[tableName]
+	if !ok {
+		return nil, nil
+	}
+	for _, c := range t.Columns {
+		if !strings.EqualFold(c.Name, name) {
+			continue
+		}
+		if lockNextValue(c) == "" {
+			return nil, errors.Errorf("optimistic lock column %q on %s must be integer or timestamp", name, tableName)
+		}
+		if c.Nullable {
+			// NULL version never matches the WHERE clause
+			return nil, errors.Errorf("optimistic lock column %q on %s must be NOT NULL", name, tableName)
+		}
+		return c, nil
+	}
+	return nil, errors.Errorf("optimistic lock column %q does not exist in %s", name, tableName)
+}
+
+// lockNextValue returns Go expression of the next value of the version column,
+// or empty string if the type of the column is not supported
+func lockNextValue(c *schema.Column) string {
+	switch toGoType(c) {
+	case "int", "int16", "int32", "int64", "uint32", "uint64":
+		return "m." + columnStructName(c) + " + 1"
+	case "xdb.Time":
+		return "xdb.Now()"
+	}
+	return ""
+}
+
 func tableInfoStructName(t *schema.TableInfo) string {
 	name := t.Name
 	if res, ok := tableNamesMap[t.SchemaName]; ok {
@@ -238,6 +366,20 @@ func columnStructName(c *schema.Column) string {
 	return goName(name)
 }
 
+// goComment formats the text as lines of Go comment
+func goComment(indent, text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, l := range lines {
+		l = strings.TrimRightFunc(l, unicode.IsSpace)
+		if l == "" {
+			lines[i] = indent + "//"
+		} else {
+			lines[i] = indent + "// " + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 var templateFuncMap = template.FuncMap{
 	"goName":              goName,
 	"tableStructName":     tableStructName,
@@ -246,9 +388,11 @@ var templateFuncMap = template.FuncMap{
 	"concat": func(args ...string) string {
 		return strings.Join(args, "")
 	},
-	"join":        strings.Join,
-	"lower":       strings.ToLower,
-	"sqlToGoType": toGoType,
+	"join":         strings.Join,
+	"goComment":    goComment,
+	"lower":        strings.ToLower,
+	"sqlToGoType":  toGoType,
+	"isJSONColumn": isJSONColumn,
 }
 
 type override struct {
@@ -256,23 +400,127 @@ type override struct {
 	Fields    map[string]string `json:"fields" yaml:"fields"`
 	Types     map[string]string `json:"types" yaml:"types"`
 	WithCache []string          `json:"with_cached_props" yaml:"with_cached_props"`
+	// Stream specifies large columns in schema.table.column format,
+	// to generate streaming accessors
+	Stream []string `json:"stream_columns" yaml:"stream_columns"`
+	// Indexes declares the indexes by table in schema.table format,
+	// the indexes missing in the database are added to the model and the index migration
+	Indexes map[string][]*declaredIndex `json:"indexes" yaml:"indexes"`
+	// SoftDelete maps the tables in schema.table format to the timestamp column,
+	// that marks the deleted rows instead of DELETE
+	SoftDelete map[string]string `json:"soft_delete" yaml:"soft_delete"`
+	// OptimisticLock maps the tables in schema.table format to the version column,
+	// that is checked and bumped by Update of the repository
+	OptimisticLock map[string]string `json:"optimistic_lock" yaml:"optimistic_lock"`
+}
+
+const (
+	modelFileName  = "model.gen.go"
+	schemaFileName = "schema.gen.go"
+)
+
+// generatedCode provides formatted source of the generated files
+type generatedCode struct {
+	Model  []byte
+	Schema []byte
+	// Proto is set if --out-proto is specified
+	Proto []byte
+	// JSONSchema provides the files by name, if --out-jsonschema is specified
+	JSONSchema map[string][]byte
+	// Indexes provides the statements for the declared indexes missing in the database
+	Indexes indexMigration
+	Defs    []*tableDefinition
 }
 
 func (a *GenerateCmd) generate(ctx *cli.Cli, provider, dbName string, res schema.Tables) error {
+	code, err := a.render(provider, dbName, res)
+	if err != nil {
+		return err
+	}
+	err = writeCode(ctx, a.OutModel, modelFileName, code.Model)
+	if err != nil {
+		return err
+	}
+	err = writeCode(ctx, a.OutSchema, schemaFileName, code.Schema)
+	if err != nil {
+		return err
+	}
+	if code.Proto != nil {
+		err = writeCode(ctx, a.OutProto, protoFileName, code.Proto)
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range values.OrderedMapKeys(code.JSONSchema) {
+		err = writeCode(ctx, a.OutJSONSchema, name, code.JSONSchema[name])
+		if err != nil {
+			return err
+		}
+	}
+	if len(code.Indexes.Up) > 0 {
+		if a.IndexMigration == 0 {
+			for _, stmt := range code.Indexes.Up {
+				fmt.Fprintf(ctx.ErrWriter(), "-- warning: declared index is missing in the database, use --index-migration: %s\n", stmt)
+			}
+			return nil
+		}
+		name := fmt.Sprintf("%06d_indexes", a.IndexMigration)
+		return writeMigration(ctx, a.OutMigration, name, code.Indexes.Up, code.Indexes.Down)
+	}
+	return nil
+}
+
+// writeMigration writes up and down migration files to the folder,
+// or prints the statements if the folder is not specified
+func writeMigration(ctx *cli.Cli, folder, name string, up, down []string) error {
+	if folder == "" {
+		fmt.Fprintf(ctx.Writer(), "-- %s.up.sql\n%s\n\n-- %s.down.sql\n%s\n",
+			name, strings.Join(up, "\n\n"),
+			name, strings.Join(down, "\n"))
+		return nil
+	}
+
+	err := writeCode(ctx, folder, name+".up.sql", []byte(strings.Join(up, "\n\n")+"\n"))
+	if err != nil {
+		return err
+	}
+	err = writeCode(ctx, folder, name+".down.sql", []byte(strings.Join(down, "\n")+"\n"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.Writer(), "%s\n%s\n",
+		filepath.Join(folder, name+".up.sql"),
+		filepath.Join(folder, name+".down.sql"))
+	return nil
+}
+
+func writeCode(ctx *cli.Cli, folder, name string, code []byte) error {
+	if folder == "" {
+		_, _ = ctx.Writer().Write(code)
+		return nil
+	}
+	_ = os.MkdirAll(folder, 0777)
+	return errors.WithStack(os.WriteFile(filepath.Join(folder, name), code, 0666))
+}
+
+func (a *GenerateCmd) render(provider, dbName string, res schema.Tables) (*generatedCode, error) {
 	var headerTemplate = template.Must(template.New("rowCode").Funcs(templateFuncMap).Parse(codeHeaderTemplateText))
 	var rowCodeTemplate = template.Must(template.New("rowCode").Funcs(templateFuncMap).Parse(codeModelTemplateText))
+	var crudCodeTemplate = template.Must(template.New("crudCode").Funcs(templateFuncMap).Parse(codeCRUDTemplateText))
 
 	modelPkg := values.StringsCoalesce(a.PkgModel, packageName(a.OutModel))
 	schemaPkg := values.StringsCoalesce(a.PkgSchema, packageName(a.OutSchema))
 
 	var dialect string
 	imports := a.Imports
-	if provider == "postgres" {
-		imports = append(imports, "github.com/lib/pq")
+	switch provider {
+	case "postgres":
 		dialect = "xsql.Postgres"
-	} else if provider == "sqlserver" {
+	case "sqlserver":
 		dialect = "xsql.SQLServer"
-	} else {
+	case "mysql":
+		dialect = "xsql.MySQL"
+	default:
 		dialect = "xsql.NoDialect"
 	}
 
@@ -280,7 +528,7 @@ func (a *GenerateCmd) generate(ctx *cli.Cli, provider, dbName string, res schema
 		var defs override
 		err := configloader.Unmarshal(a.TypesDef, &defs)
 		if err != nil {
-			return errors.WithMessagef(err, "failed to load types definition")
+			return nil, errors.WithMessagef(err, "failed to load types definition")
 		}
 		for k, v := range defs.Types {
 			typesMap[k] = v
@@ -294,32 +542,57 @@ func (a *GenerateCmd) generate(ctx *cli.Cli, provider, dbName string, res schema
 		for _, v := range defs.WithCache {
 			modelWithCacheMap[v] = true
 		}
+		for _, v := range defs.Stream {
+			streamColumnsMap[v] = true
+		}
+		for k, v := range defs.Indexes {
+			indexesMap[k] = v
+		}
+		for k, v := range defs.SoftDelete {
+			softDeleteMap[k] = v
+		}
+		for k, v := range defs.OptimisticLock {
+			optimisticLockMap[k] = v
+		}
+	}
+
+	var renames *schema.Renames
+	if a.Renames != "" {
+		var err error
+		renames, err = schema.LoadRenames(a.Renames)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	streamColumns := map[*schema.Table]schema.Columns{}
+	for _, t := range res {
+		for _, c := range t.Columns {
+			if streamColumnsMap[t.Schema+"."+t.Name+"."+c.Name] {
+				streamColumns[t] = append(streamColumns[t], c)
+			}
+		}
+	}
+	if (len(streamColumns) > 0 || a.GenCrud) && !slices.ContainsString(imports, "context") {
+		imports = append(imports, "context")
+	}
+
+	var schemaNames []string
 	schemas := map[string]schema.Tables{}
 	for _, t := range res {
+		if _, ok := schemas[t.Schema]; !ok {
+			schemaNames = append(schemaNames, t.Schema)
+		}
 		schemas[t.Schema] = append(schemas[t.Schema], t)
 	}
 
 	var err error
 	var tableInfos []*schema.TableInfo
 	var tableDefs []*tableDefinition
+	var indexes indexMigration
 
-	w := ctx.Writer()
 	buf := &bytes.Buffer{}
 
-	if a.OutModel != "" {
-		_ = os.MkdirAll(a.OutModel, 0777)
-		fn := filepath.Join(a.OutModel, "model.gen.go")
-		f, err := os.OpenFile(fn, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
-		if err != nil {
-			return err
-		}
-		defer func() {
-			_ = f.Close()
-		}()
-		w = f
-	}
 	err = headerTemplate.Execute(buf, &tableDefinition{
 		DB:      dbName,
 		Package: modelPkg,
@@ -327,25 +600,52 @@ func (a *GenerateCmd) generate(ctx *cli.Cli, provider, dbName string, res schema
 		Dialect: dialect,
 	})
 	if err != nil {
-		return errors.WithMessagef(err, "failed to generate header")
+		return nil, errors.WithMessagef(err, "failed to generate header")
 	}
 
-	for schemaName, tables := range schemas {
+	// iterate in the order of tables, to produce stable output
+	for _, schemaName := range schemaNames {
+		tables := schemas[schemaName]
 		sName := strcase.ToGoPascal(schemaName)
 		for _, t := range tables {
+			if !t.IsView {
+				created, err := applyDeclaredIndexes(t)
+				if err != nil {
+					return nil, err
+				}
+				for _, idx := range created {
+					indexes.Up = append(indexes.Up, schema.CreateIndexDDL(provider, t, idx))
+					// drop in the reverse order
+					indexes.Down = append([]string{schema.DropIndexDDL(provider, t, idx)}, indexes.Down...)
+				}
+			}
+
+			softDelete, err := softDeleteColumn(t)
+			if err != nil {
+				return nil, err
+			}
+
 			tName := strcase.ToGoPascal(pluralizeClient.Singular(t.Name))
 			if a.StructSuffix != "" {
 				tName += t.Name + strcase.ToGoPascal(a.StructSuffix)
 			}
 
 			tableInfos = append(tableInfos, &schema.TableInfo{
-				Schema:     t.Schema,
-				Name:       t.Name,
-				SchemaName: t.SchemaName,
-				Columns:    t.Columns.Names(),
-				Indexes:    t.Indexes.Names(),
-				PrimaryKey: t.PrimaryKeyName(),
+				Schema:       t.Schema,
+				Name:         t.Name,
+				SchemaName:   t.SchemaName,
+				Columns:      t.Columns.Names(),
+				Indexes:      t.Indexes.Names(),
+				PrimaryKey:   t.PrimaryKeyName(),
+				PartitionKey: t.PartitionKey,
+				Comment:      t.Comment,
+				QuoteNames:   hasReservedNames(provider, t),
+				Types:        columnTypes(t),
+				References:   tableReferences(t),
 			})
+			if softDelete != nil {
+				tableInfos[len(tableInfos)-1].SoftDeleteColumn = softDelete.Name
+			}
 			prefix := ""
 			if a.UseSchema && !slices.ContainsStringEqualFold([]string{"dbo", "public"}, schemaName) {
 				prefix = sName
@@ -361,47 +661,54 @@ func (a *GenerateCmd) generate(ctx *cli.Cli, provider, dbName string, res schema
 				SchemaName:      t.Schema,
 				TableName:       t.Name,
 				TableStructName: tableStructName(t),
+				Comment:         t.Comment,
 				Columns:         t.Columns,
 				Indexes:         t.Indexes,
 				PrimaryKey:      t.PrimaryKey,
 				WithCache:       modelWithCacheMap[t.SchemaName],
+				StreamColumns:   streamColumns[t],
+				Renamed:         renamedColumns(t, renames),
 			}
 
 			if res, ok := tableNamesMap[t.SchemaName]; ok {
 				td.StructName = res
 			}
+			td.Enums = enumColumns(td.StructName, t.Columns)
+			if a.GenCrud && !t.IsView && t.PrimaryKey != nil {
+				lock, err := optimisticLockColumn(t)
+				if err != nil {
+					return nil, err
+				}
+				td.CRUD = crudStatements(xsql.DialectFor(provider), t, softDelete, lock)
+			}
 
 			err = rowCodeTemplate.Execute(buf, td)
 			if err != nil {
-				return errors.WithMessagef(err, "failed to generate model for %s.%s", t.Schema, t.Name)
+				return nil, errors.WithMessagef(err, "failed to generate model for %s.%s", t.Schema, t.Name)
+			}
+			if td.CRUD != nil {
+				err = crudCodeTemplate.Execute(buf, td)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "failed to generate repository for %s.%s", t.Schema, t.Name)
+				}
 			}
 			tableDefs = append(tableDefs, td)
 		}
 	}
 
-	code, err := format.Source(buf.Bytes())
+	code := &generatedCode{
+		Indexes: indexes,
+		Defs:    tableDefs,
+	}
+	code.Model, err = format.Source(buf.Bytes())
 	if err != nil {
-		return errors.WithMessagef(err, "failed to format")
+		return nil, errors.WithMessagef(err, "failed to format")
 	}
-	_, _ = w.Write(code)
 
 	var schemaCodeTemplate = template.Must(template.New("schemaCode").Funcs(templateFuncMap).Parse(codeSchemaTemplateText))
 	var collsCodeTemplate = template.Must(template.New("collsCode").Funcs(templateFuncMap).Parse(codeTableColTemplateText))
 
 	buf.Reset()
-	w = ctx.Writer()
-	if a.OutSchema != "" {
-		_ = os.MkdirAll(a.OutSchema, 0777)
-		fn := filepath.Join(a.OutSchema, "schema.gen.go")
-		f, err := os.OpenFile(fn, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
-		if err != nil {
-			return err
-		}
-		defer func() {
-			_ = f.Close()
-		}()
-		w = f
-	}
 	td := schemaDefinition{
 		DB:      dbName,
 		Package: schemaPkg,
@@ -412,20 +719,155 @@ func (a *GenerateCmd) generate(ctx *cli.Cli, provider, dbName string, res schema
 	}
 	err = schemaCodeTemplate.Execute(buf, td)
 	if err != nil {
-		return errors.WithMessagef(err, "failed to generate schema")
+		return nil, errors.WithMessagef(err, "failed to generate schema")
 	}
 
 	for _, ctd := range tableDefs {
 		err = collsCodeTemplate.Execute(buf, ctd)
 		if err != nil {
-			return errors.WithMessagef(err, "failed to generate schema")
+			return nil, errors.WithMessagef(err, "failed to generate schema")
 		}
 	}
-	code, err = format.Source(buf.Bytes())
+	code.Schema, err = format.Source(buf.Bytes())
 	if err != nil {
-		return errors.WithMessagef(err, "failed to format")
+		return nil, errors.WithMessagef(err, "failed to format")
 	}
-	_, _ = w.Write(code)
 
-	return nil
+	if a.OutProto != "" {
+		protoPkg := values.StringsCoalesce(a.PkgProto, packageName(a.OutProto))
+		code.Proto, err = renderProto(dbName, protoPkg, tableDefs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if a.OutJSONSchema != "" {
+		code.JSONSchema, err = renderJSONSchema(tableDefs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return code, nil
+}
+
+// columnTypes returns the UDT types of the table columns
+func columnTypes(t *schema.Table) []string {
+	list := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		list[i] = c.UdtType
+	}
+	return list
+}
+
+// tableReferences returns the sorted list of the tables
+// referenced by the foreign keys of the table, excluding the table itself
+func tableReferences(t *schema.Table) []string {
+	var list []string
+	for _, c := range t.Columns {
+		if c.Ref == nil {
+			continue
+		}
+		ref := c.Ref.RefSchema + "." + c.Ref.RefTable
+		if ref != t.SchemaName && !slices.ContainsString(list, ref) {
+			list = append(list, ref)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// hasReservedNames returns true if the name of the table or its columns
+// is the keyword reserved by the provider
+func hasReservedNames(provider string, t *schema.Table) bool {
+	if schema.IsReservedWord(provider, t.Name) {
+		return true
+	}
+	for _, c := range t.Columns {
+		if schema.IsReservedWord(provider, c.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// crudStatements returns SQL statements of the repository for the table,
+// the primary key is the last argument of Update statement.
+// For tables with softDelete column, Get and List filter the deleted rows,
+// and Delete sets the column instead of deleting the row.
+// For tables with lock column, Update sets the next version of the column,
+// the current version is the last argument.
+// It returns nil if the table has no columns besides the primary key.
+func crudStatements(dialect xsql.SQLDialect, t *schema.Table, softDelete, lock *schema.Column) *crudDefinition {
+	if len(t.Columns) < 2 {
+		return nil
+	}
+	// the names that are reserved keywords are quoted
+	quote := func(name string) string {
+		if schema.IsReservedWord(dialect.Provider(), name) {
+			return dialect.Quote(name)
+		}
+		return name
+	}
+	pk := quote(t.PrimaryKey.Name)
+	table := quote(t.Schema) + "." + quote(t.Name)
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = quote(c.Name)
+	}
+	columns := strings.Join(names, ", ")
+	build := func(q xsql.Builder) string {
+		defer q.Close()
+		return q.String()
+	}
+
+	get := dialect.From(table).
+		Select(columns).
+		Where(pk+" = ?", nil)
+	list := dialect.From(table).
+		Select(columns)
+	if softDelete != nil {
+		get.Where(quote(softDelete.Name) + " IS NULL")
+		list.Where(quote(softDelete.Name) + " IS NULL")
+	}
+
+	crud := &crudDefinition{
+		Get: build(get),
+		List: build(list.
+			OrderBy(pk).
+			Limit(nil).
+			Offset(nil)),
+		SoftDelete: softDelete,
+	}
+	if softDelete != nil {
+		crud.Delete = build(dialect.Update(table).
+			Set(quote(softDelete.Name), nil).
+			Where(pk+" = ?", nil))
+	} else {
+		crud.Delete = build(dialect.DeleteFrom(table).
+			Where(pk+" = ?", nil))
+	}
+
+	insert := dialect.InsertInto(table)
+	update := dialect.Update(table)
+	for _, c := range t.Columns {
+		// the values of identity columns are generated by the database
+		if c.Identity {
+			continue
+		}
+		insert.Set(quote(c.Name), nil)
+		crud.CreateColumns = append(crud.CreateColumns, c)
+		if !strings.EqualFold(c.Name, t.PrimaryKey.Name) && c != lock {
+			update.Set(quote(c.Name), nil)
+			crud.UpdateColumns = append(crud.UpdateColumns, c)
+		}
+	}
+	crud.Create = build(insert)
+	update.Where(pk+" = ?", nil)
+	if lock != nil {
+		crud.Lock = lock
+		crud.LockNext = lockNextValue(lock)
+		update.Set(quote(lock.Name), nil).Where(quote(lock.Name)+" = ?", nil)
+	}
+	crud.Update = build(update)
+	return crud
 }
@@ -1,6 +1,8 @@
 package schema
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/effective-security/x/configloader"
@@ -94,6 +96,11 @@ func (s *testSuite) TestPrintTablesCmd() {
 				},
 			},
 		},
+		{
+			Name:    "org",
+			Schema:  "dbo",
+			Comment: "organizations\nof users",
+		},
 	}
 
 	mock.EXPECT().ListTables(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(res, nil).Times(1)
@@ -107,7 +114,7 @@ func (s *testSuite) TestPrintTablesCmd() {
 
 	err := cmd.Run(s.Ctl)
 	require.NoError(err)
-	s.Equal("dbo.test\n", s.Out.String())
+	s.Equal("dbo.test\ndbo.org\t-- organizations of users\n", s.Out.String())
 
 	err = cmd.Run(s.Ctl)
 	s.EqualError(err, "query failed")
@@ -205,15 +212,29 @@ func (s *testSuite) TestGenerate() {
 	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
 	require.NoError(err)
 
+	typesDef := filepath.Join(s.T().TempDir(), "types.yaml")
+	err = os.WriteFile(typesDef, []byte("stream_columns:\n  - public.org.settings\n"), 0666)
+	require.NoError(err)
+
 	cmd := GenerateCmd{
 		PkgModel:  "model",
 		PkgSchema: "schema",
 		Schema:    "dbo",
 		DB:        "testdb",
 		Table:     []string{"Transaction"},
+		TypesDef:  typesDef,
 	}
 	err = cmd.generate(s.Ctl, "postgres", "org", res)
 	require.NoError(err)
+	s.HasText(
+		"func (m *Org) DecodeQuota(v any) error {\n\treturn xdb.DecodeJSON(string(m.Quota), v)\n}",
+		"func (m *Org) DecodeSettings(v any) error {",
+		"\t\"context\"\n",
+		"func (m *Org) OpenSettingsReader(ctx context.Context, p xdb.Provider) *xdb.ColumnReader {\n"+
+			"\treturn xdb.NewColumnReader(ctx, p, \"public.org\", \"settings\", \"id = ?\", m.ID)\n}",
+	)
+	s.NotContains(s.Out.String(), "OpenQuotaReader")
+	cmd.TypesDef = ""
 
 	ctrl := gomock.NewController(s.T())
 	mock := mockschema.NewMockProvider(ctrl)
@@ -221,8 +242,9 @@ func (s *testSuite) TestGenerate() {
 
 	ret := dbschema.Tables{
 		{
-			Name:   "test",
-			Schema: "dbo",
+			Name:         "test",
+			Schema:       "dbo",
+			PartitionKey: "ID",
 			Columns: dbschema.Columns{
 				{
 					Name:     "ID",
@@ -238,4 +260,465 @@ func (s *testSuite) TestGenerate() {
 	err = cmd.Run(s.Ctl)
 	require.NoError(err)
 	s.HasText("DO NOT EDIT!", s.Out.String())
+	s.HasText(`PartitionKey: "ID",`)
+}
+
+func (s *testSuite) TestGenerateCRUD() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	// org.id is generated by the database
+	res[0].Columns[0].Identity = true
+
+	cmd := GenerateCmd{
+		PkgModel: "model",
+		DB:       "testdb",
+		GenCrud:  true,
+	}
+	err = cmd.generate(s.Ctl, "postgres", "org", res)
+	require.NoError(err)
+	s.HasText(
+		"\t\"context\"\n",
+		`sqlOrgGet    = "SELECT id, name, email, billing_email, company, street_address, city, postal_code, region, country, phone, created_at, updated_at, quota, settings \nFROM public.org \nWHERE id = $1"`,
+		`sqlOrgList   = "SELECT id, name, email, billing_email, company, street_address, city, postal_code, region, country, phone, created_at, updated_at, quota, settings \nFROM public.org \nORDER BY id \nLIMIT $1 \nOFFSET $2"`,
+		`sqlOrgDelete = "DELETE FROM public.org \nWHERE id = $1"`,
+		`sqlSchemaMigrationUpdate = "UPDATE public.schema_migrations \nSET dirty=$1 \nWHERE version = $2"`,
+		"func NewOrgRepository(db xdb.DB) *OrgRepository {",
+		"func (r *OrgRepository) Get(ctx context.Context, id xdb.ID) (*Org, error) {\n"+
+			"\treturn xdb.QueryRow[Org](ctx, r.db, sqlOrgGet, id)\n}",
+		"err := xdb.ExecuteQueryWithPagination[Org](ctx, r.db, res, sqlOrgList, limit, offset)",
+		"_, err := r.db.ExecContext(ctx, sqlSchemaMigrationUpdate,\n\t\tm.Dirty,\n\t\tm.Version,\n\t)",
+		"func (r *OrgRepository) Delete(ctx context.Context, id xdb.ID) error {",
+		`sqlOrgCreate = "INSERT INTO public.org \n( name, email, billing_email, company, street_address, city, postal_code, region, country, phone, created_at, updated_at, quota, settings \n) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14 \n)"`,
+		"_, err := r.db.ExecContext(ctx, sqlOrgCreate,\n\t\tm.Name,\n",
+	)
+
+	s.Out.Reset()
+	cmd.GenCrud = false
+	err = cmd.generate(s.Ctl, "postgres", "org", res)
+	require.NoError(err)
+	s.NotContains(s.Out.String(), "Repository")
+}
+
+func (s *testSuite) TestGenerateProto() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+
+	tmp := s.T().TempDir()
+	cmd := GenerateCmd{
+		PkgModel: "model",
+		DB:       "testdb",
+		OutProto: filepath.Join(tmp, "dbproto"),
+	}
+	err = cmd.generate(s.Ctl, "postgres", "org", res)
+	require.NoError(err)
+
+	proto, err := os.ReadFile(filepath.Join(tmp, "dbproto", protoFileName))
+	require.NoError(err)
+	for _, exp := range []string{
+		"package dbproto;\n",
+		"import \"google/protobuf/struct.proto\";\nimport \"google/protobuf/timestamp.proto\";\n",
+		"// Org represents one row from table 'public.org'.\nmessage Org {\n    uint64 id = 1;\n    string name = 2;\n",
+		"    google.protobuf.Timestamp created_at = 12;\n",
+		"    google.protobuf.Struct quota = 14;\n",
+		"message SchemaMigration {\n    int64 version = 1;\n    bool dirty = 2;\n}\n",
+	} {
+		s.Contains(string(proto), exp)
+	}
+
+	cmd.PkgProto = "acme.db.v1"
+	res[0].Comment = "organizations"
+	res[0].Columns[1].Comment = "display name"
+	code, err := cmd.render("postgres", "org", res)
+	require.NoError(err)
+	s.Contains(string(code.Proto), "package acme.db.v1;\n")
+	s.Contains(string(code.Proto), "// Org represents one row from table 'public.org'.\n//\n// organizations\nmessage Org {\n"+
+		"    uint64 id = 1;\n    // display name\n    string name = 2;\n")
+
+	typesMap["public.org.settings"] = "xdb.JSON[Settings]"
+	defer delete(typesMap, "public.org.settings")
+	s.Equal(protoStruct, protoType(&dbschema.Column{Name: "settings", Type: "jsonb", SchemaName: "public.org.settings"}))
+
+	proto, err = renderProto("testdb", "dbproto", []*tableDefinition{{
+		StructName: "Job",
+		TableName:  "job",
+		Columns: dbschema.Columns{
+			{Name: "timeout", Type: "interval", UdtType: "interval", Nullable: true},
+		},
+	}})
+	require.NoError(err)
+	s.Contains(string(proto), "import \"google/protobuf/duration.proto\";\n")
+	s.Contains(string(proto), "    google.protobuf.Duration timeout = 1;\n")
+}
+
+func (s *testSuite) TestGenerateJSONSchema() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	res[0].Comment = "organizations"
+	res[3].Columns = append(res[3].Columns, &dbschema.Column{
+		Name:     "status",
+		Type:     "USER-DEFINED",
+		UdtType:  "user_status",
+		Nullable: true,
+		Enum:     []string{"active", "disabled"},
+	})
+
+	tmp := s.T().TempDir()
+	cmd := GenerateCmd{
+		PkgModel:      "model",
+		DB:            "testdb",
+		OutModel:      filepath.Join(tmp, "model"),
+		OutSchema:     filepath.Join(tmp, "schema"),
+		OutJSONSchema: filepath.Join(tmp, "jsonschema"),
+	}
+	err = cmd.generate(s.Ctl, "postgres", "org", res)
+	require.NoError(err)
+
+	org, err := os.ReadFile(filepath.Join(tmp, "jsonschema", "Org.schema.json"))
+	require.NoError(err)
+	for _, exp := range []string{
+		"\"$schema\": \"https://json-schema.org/draft/2020-12/schema\",\n  \"$id\": \"Org.schema.json\",\n  \"title\": \"Org\",\n  \"description\": \"organizations\",\n",
+		"\"properties\": {\n    \"ID\": {\n      \"type\": \"integer\",\n      \"format\": \"int64\"\n    },\n    \"Name\": {\n      \"type\": \"string\",\n      \"maxLength\": 64\n    },",
+		"\"CreatedAt\": {\n      \"type\": [\n        \"string\",\n        \"null\"\n      ],\n      \"format\": \"date-time\"\n    },",
+		"\"Quota\": {\n      \"type\": [\n        \"string\",\n        \"null\"\n      ],\n      \"contentMediaType\": \"application/json\"\n    },",
+	} {
+		s.Contains(string(org), exp)
+	}
+
+	user, err := os.ReadFile(filepath.Join(tmp, "jsonschema", "User.schema.json"))
+	require.NoError(err)
+	s.Contains(string(user), "\"Status\": {\n      \"type\": [\n        \"string\",\n        \"null\"\n      ],\n      \"enum\": [\n        \"active\",\n        \"disabled\",\n        null\n      ]\n    }")
+
+	components, err := os.ReadFile(filepath.Join(tmp, "jsonschema", "components.json"))
+	require.NoError(err)
+	s.Contains(string(components), "{\n  \"components\": {\n    \"schemas\": {\n      \"Org\": {\n        \"title\": \"Org\",\n")
+	s.Contains(string(components), "\"SchemaMigration\": {")
+
+	// enum columns are generated as strings in the model
+	model, err := os.ReadFile(filepath.Join(tmp, "model", modelFileName))
+	require.NoError(err)
+	s.Contains(string(model), "Status xdb.NULLString `db:\"status,user_status,null\" json:\",omitempty\"`")
+}
+
+func (s *testSuite) TestGenerateEnums() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	res[3].Columns = append(res[3].Columns,
+		&dbschema.Column{
+			Name:     "status",
+			Type:     "USER-DEFINED",
+			UdtType:  "user_status",
+			Nullable: true,
+			Enum:     []string{"active", "disabled"},
+		},
+		// from CHECK (kind IN ('admin', 'read-only', ''))
+		&dbschema.Column{
+			Name:    "kind",
+			Type:    "character varying",
+			UdtType: "varchar",
+			Enum:    []string{"admin", "read-only", ""},
+		},
+	)
+
+	cmd := GenerateCmd{
+		PkgModel: "model",
+		DB:       "testdb",
+	}
+	code, err := cmd.render("postgres", "org", res)
+	require.NoError(err)
+
+	model := string(code.Model)
+	for _, exp := range []string{
+		"// Allowed values of 'status' column.\nconst (\n\tUserStatusActive   xdb.NULLString = \"active\"\n\tUserStatusDisabled xdb.NULLString = \"disabled\"\n)\n",
+		"var UserStatusValues = []xdb.NULLString{\n\tUserStatusActive,\n\tUserStatusDisabled,\n}\n",
+		"\tUserKindAdmin    string = \"admin\"\n\tUserKindReadOnly string = \"read-only\"\n\tUserKindValue2   string = \"\"\n",
+		"func (m *User) Validate() error {\n" +
+			"\tswitch m.Status {\n\tcase UserStatusActive, UserStatusDisabled, \"\":\n\tdefault:\n" +
+			"\t\treturn errors.Errorf(\"invalid value of 'status' column: %q\", m.Status)\n\t}\n" +
+			"\tswitch m.Kind {\n\tcase UserKindAdmin, UserKindReadOnly, UserKindValue2:\n",
+	} {
+		s.Contains(model, exp)
+	}
+	s.NotContains(model, "func (m *Org) Validate() error")
+}
+
+func (s *testSuite) TestGenerateSoftDelete() {
+	require := s.Require()
+	defer func() {
+		softDeleteMap = map[string]string{}
+	}()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	res[3].Columns = append(res[3].Columns, &dbschema.Column{
+		Name:     "deleted_at",
+		Type:     "timestamp with time zone",
+		UdtType:  "timestamptz",
+		Nullable: true,
+	})
+
+	tmp := s.T().TempDir()
+	typesDef := filepath.Join(tmp, "types.yaml")
+	err = os.WriteFile(typesDef, []byte(`
+soft_delete:
+  public.user: deleted_at
+`), 0644)
+	require.NoError(err)
+
+	cmd := GenerateCmd{
+		PkgModel: "model",
+		DB:       "testdb",
+		TypesDef: typesDef,
+		GenCrud:  true,
+	}
+	code, err := cmd.render("postgres", "org", res)
+	require.NoError(err)
+
+	model := string(code.Model)
+	for _, exp := range []string{
+		`sqlUserGet    = "SELECT id, email, email_verified, name, deleted_at \nFROM public.\"user\" \nWHERE id = $1 AND deleted_at IS NULL"`,
+		`sqlUserList   = "SELECT id, email, email_verified, name, deleted_at \nFROM public.\"user\" \nWHERE deleted_at IS NULL \nORDER BY id \nLIMIT $1 \nOFFSET $2"`,
+		`sqlUserDelete = "UPDATE public.\"user\" \nSET deleted_at=$1 \nWHERE id = $2"`,
+		"// Delete marks the row by 'id' primary key as deleted,\n// by setting 'deleted_at' column.\n" +
+			"func (r *UserRepository) Delete(ctx context.Context, id xdb.ID) error {\n" +
+			"\t_, err := r.db.ExecContext(ctx, sqlUserDelete, xdb.Now(), id)\n",
+		`sqlOrgDelete = "DELETE FROM public.org \nWHERE id = $1"`,
+	} {
+		s.Contains(model, exp)
+	}
+	s.Contains(string(code.Schema), "\tSoftDeleteColumn: \"deleted_at\",\n")
+
+	cmd.TypesDef = ""
+	softDeleteMap["public.user"] = "removed_at"
+	_, err = cmd.render("postgres", "org", res)
+	s.EqualError(err, `soft delete column "removed_at" does not exist in public.user`)
+
+	softDeleteMap["public.user"] = "email"
+	_, err = cmd.render("postgres", "org", res)
+	s.EqualError(err, `soft delete column "email" on public.user must be nullable`)
+}
+
+func (s *testSuite) TestGenerateReservedNames() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	res[3].Columns = append(res[3].Columns, &dbschema.Column{
+		Name:     "order",
+		Type:     "integer",
+		UdtType:  "int4",
+		Nullable: true,
+	})
+
+	cmd := GenerateCmd{
+		PkgModel: "model",
+		DB:       "testdb",
+		GenCrud:  true,
+	}
+	code, err := cmd.render("postgres", "org", res)
+	require.NoError(err)
+
+	model := string(code.Model)
+	for _, exp := range []string{
+		`sqlUserGet    = "SELECT id, email, email_verified, name, \"order\" \nFROM public.\"user\" \nWHERE id = $1"`,
+		`sqlUserUpdate = "UPDATE public.\"user\" \nSET email=$1, email_verified=$2, name=$3, \"order\"=$4 \nWHERE id = $5"`,
+		`sqlOrgDelete = "DELETE FROM public.org \nWHERE id = $1"`,
+	} {
+		s.Contains(model, exp)
+	}
+	s.Contains(string(code.Schema), "\"users_pkey\"},\n\tQuoteNames: true,\n")
+	s.NotContains(string(code.Schema), "\"unique_orgs_name\"},\n\tQuoteNames")
+}
+
+func (s *testSuite) TestGenerateTypesAndReferences() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	res[1].Columns[1].Ref = &dbschema.ForeignKey{RefSchema: "public", RefTable: "org", RefColumn: "id"}
+	res[1].Columns[2].Ref = &dbschema.ForeignKey{RefSchema: "public", RefTable: "user", RefColumn: "id"}
+
+	cmd := GenerateCmd{
+		PkgModel: "model",
+		DB:       "testdb",
+	}
+	code, err := cmd.render("postgres", "org", res)
+	require.NoError(err)
+
+	sch := string(code.Schema)
+	s.Contains(sch, "\tTypes:      []string{\"int8\", \"int8\", \"int8\", \"varchar\"},\n\tReferences: []string{\"public.org\", \"public.user\"},\n")
+	s.Contains(sch, "\tTypes:      []string{\"int8\", \"bool\"},\n\tDialect:    xsql.Postgres,\n")
+}
+
+func (s *testSuite) TestGenerateOptimisticLock() {
+	require := s.Require()
+	defer func() {
+		optimisticLockMap = map[string]string{}
+	}()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	res[3].Columns = append(res[3].Columns, &dbschema.Column{
+		Name:    "version",
+		Type:    "integer",
+		UdtType: "int4",
+	})
+	res[0].Columns[12].Nullable = false
+
+	tmp := s.T().TempDir()
+	typesDef := filepath.Join(tmp, "types.yaml")
+	err = os.WriteFile(typesDef, []byte(`
+optimistic_lock:
+  public.user: version
+  public.org: updated_at
+`), 0644)
+	require.NoError(err)
+
+	cmd := GenerateCmd{
+		PkgModel: "model",
+		DB:       "testdb",
+		TypesDef: typesDef,
+		GenCrud:  true,
+	}
+	code, err := cmd.render("postgres", "org", res)
+	require.NoError(err)
+
+	model := string(code.Model)
+	for _, exp := range []string{
+		`sqlUserUpdate = "UPDATE public.\"user\" \nSET email=$1, email_verified=$2, name=$3, version=$4 \nWHERE id = $5 AND version = $6"`,
+		"func (r *UserRepository) Update(ctx context.Context, m *User) error {\n" +
+			"\tversion := m.Version + 1\n" +
+			"\tres, err := r.db.ExecContext(ctx, sqlUserUpdate,\n\t\tm.Email,\n\t\tm.EmailVerified,\n\t\tm.Name,\n\t\tversion,\n\t\tm.ID,\n\t\tm.Version,\n\t)\n",
+		"\tif err = xdb.MustAffect(res, 1); errors.Is(err, xdb.ErrNoRowsAffected) {\n" +
+			"\t\treturn errors.WithStack(&xdb.StaleRowError{Table: \"public.user\", Key: m.ID})\n" +
+			"\t} else if err != nil {\n\t\treturn err\n\t}\n\tm.Version = version\n",
+		`"UPDATE public.org \nSET name=$1, email=$2, billing_email=$3, company=$4, street_address=$5, city=$6, postal_code=$7, region=$8, country=$9, phone=$10, created_at=$11, quota=$12, settings=$13, updated_at=$14 \nWHERE id = $15 AND updated_at = $16"`,
+		"func (r *OrgRepository) Update(ctx context.Context, m *Org) error {\n\tversion := xdb.Now()\n",
+		"func (r *OrgmemberRepository) Update(ctx context.Context, m *Orgmember) error {\n\t_, err := r.db.ExecContext(ctx, sqlOrgmemberUpdate,\n",
+	} {
+		s.Contains(model, exp)
+	}
+
+	cmd.TypesDef = ""
+	optimisticLockMap = map[string]string{"public.user": "name"}
+	_, err = cmd.render("postgres", "org", res)
+	s.EqualError(err, `optimistic lock column "name" on public.user must be integer or timestamp`)
+
+	optimisticLockMap = map[string]string{"public.org": "created_at"}
+	_, err = cmd.render("postgres", "org", res)
+	s.EqualError(err, `optimistic lock column "created_at" on public.org must be NOT NULL`)
+
+	optimisticLockMap = map[string]string{"public.org": "revision"}
+	_, err = cmd.render("postgres", "org", res)
+	s.EqualError(err, `optimistic lock column "revision" does not exist in public.org`)
+}
+
+func (s *testSuite) TestPrintColumnsCmdStream() {
+	require := s.Require()
+
+	s.Ctl.WithSchemaProvider(dbschema.NewSnapshotProvider(&dbschema.Snapshot{
+		Version:  dbschema.SnapshotVersion,
+		Provider: "postgres",
+		Tables: dbschema.Tables{
+			{Schema: "public", Name: "org", SchemaName: "public.org", Columns: dbschema.Columns{{Name: "id", Type: "bigint"}}},
+			{Schema: "public", Name: "user", SchemaName: "public.user", Columns: dbschema.Columns{{Name: "id", Type: "bigint"}}},
+		},
+	}))
+
+	cmd := PrintColumnsCmd{
+		DB:    "org",
+		Table: []string{"ORG"},
+	}
+	err := cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.Equal(`Schema: public
+Table: org
+
+  ORD | NAME |  TYPE  | UDT | NULL | MAX | INDEX | REF  
+------+------+--------+-----+------+-----+-------+------
+  0   | id   | bigint |     |      |     |       |      
+
+`, s.Out.String())
+}
+
+func (s *testSuite) TestGenerateIndexes() {
+	require := s.Require()
+	defer func() {
+		indexesMap = map[string][]*declaredIndex{}
+	}()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+
+	tmp := s.T().TempDir()
+	typesDef := filepath.Join(tmp, "types.yaml")
+	err = os.WriteFile(typesDef, []byte(`
+indexes:
+  public.org:
+    - columns: [email]
+    - columns: [company, city]
+    - name: uq_org_region
+      columns: [region]
+      unique: true
+`), 0644)
+	require.NoError(err)
+
+	cmd := GenerateCmd{
+		PkgModel: "model",
+		DB:       "testdb",
+		TypesDef: typesDef,
+		OutModel: filepath.Join(tmp, "model"),
+	}
+	code, err := cmd.render("postgres", "org", res)
+	require.NoError(err)
+	s.Equal([]string{
+		"CREATE INDEX idx_org_company_city ON public.org (company, city);",
+		"CREATE UNIQUE INDEX uq_org_region ON public.org (region);",
+	}, code.Indexes.Up)
+	s.Equal([]string{
+		"DROP INDEX IF EXISTS public.uq_org_region;",
+		"DROP INDEX IF EXISTS public.idx_org_company_city;",
+	}, code.Indexes.Down)
+	s.Contains(string(code.Model), "//\tuq_org_region: UNIQUE [region]\n")
+	s.Contains(string(code.Model), "Company string `db:\"company,varchar,max:64,index\"")
+
+	// the indexes are created in the database
+	err = configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	err = cmd.generate(s.Ctl, "postgres", "org", res)
+	require.NoError(err)
+	s.HasText("-- warning: declared index is missing in the database, use --index-migration: CREATE INDEX idx_org_company_city ON public.org (company, city);\n")
+
+	s.Out.Reset()
+	cmd.IndexMigration = 7
+	cmd.OutMigration = filepath.Join(tmp, "migrations")
+	err = configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+	err = cmd.generate(s.Ctl, "postgres", "org", res)
+	require.NoError(err)
+	up, err := os.ReadFile(filepath.Join(tmp, "migrations", "000007_indexes.up.sql"))
+	require.NoError(err)
+	s.Equal("CREATE INDEX idx_org_company_city ON public.org (company, city);\n\nCREATE UNIQUE INDEX uq_org_region ON public.org (region);\n", string(up))
+	s.FileExists(filepath.Join(tmp, "migrations", "000007_indexes.down.sql"))
+
+	cmd.TypesDef = ""
+	indexesMap["public.org"] = []*declaredIndex{{Columns: []string{"unknown"}}}
+	_, err = cmd.render("postgres", "org", res)
+	s.EqualError(err, `index "idx_org_unknown" on public.org: column "unknown" not found`)
 }
@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/effective-security/xdb/pkg/cli"
+	"github.com/effective-security/xdb/schema"
+	"github.com/pkg/errors"
+)
+
+// SnapshotCmd saves database schema snapshot
+type SnapshotCmd struct {
+	DB     string `help:"database name" required:""`
+	Schema string `help:"optional schema name to filter"`
+	Out    string `help:"optional, file name to store the snapshot, default: stdout"`
+	Format string `help:"optional, snapshot format: json|yaml, default: by --out extension or json" enum:",json,yaml" default:""`
+}
+
+// Run the command
+func (a *SnapshotCmd) Run(ctx *cli.Cli) error {
+	r, err := ctx.SchemaProvider(a.DB)
+	if err != nil {
+		return err
+	}
+	snapshot, err := schema.NewSnapshot(ctx.Context(), r, a.Schema)
+	if err != nil {
+		return err
+	}
+
+	save := snapshot.Save
+	ext := filepath.Ext(a.Out)
+	if a.Format == "yaml" || (a.Format == "" && (ext == ".yaml" || ext == ".yml")) {
+		save = snapshot.SaveYAML
+	}
+
+	if a.Out == "" {
+		return save(ctx.Writer())
+	}
+
+	f, err := os.Create(a.Out)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	return save(f)
+}
@@ -0,0 +1,63 @@
+package schema
+
+import (
+	"path/filepath"
+
+	"github.com/effective-security/x/configloader"
+	"github.com/effective-security/xdb/mocks/mockschema"
+	dbschema "github.com/effective-security/xdb/schema"
+	"github.com/golang/mock/gomock"
+)
+
+func (s *testSuite) TestSnapshot() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+
+	ctrl := gomock.NewController(s.T())
+	mock := mockschema.NewMockProvider(ctrl)
+	s.Ctl.WithSchemaProvider(mock)
+	mock.EXPECT().Name().Return("postgres").AnyTimes()
+	mock.EXPECT().ListTables(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(res, nil).AnyTimes()
+	mock.EXPECT().ListViews(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mock.EXPECT().ListForeignKeys(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	fn := filepath.Join(s.T().TempDir(), "snapshot.json")
+	cmd := SnapshotCmd{
+		DB:  "org",
+		Out: fn,
+	}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+
+	snapshot, err := dbschema.LoadSnapshot(fn)
+	require.NoError(err)
+	s.Equal("postgres", snapshot.Provider)
+	s.Len(snapshot.Tables, len(res))
+
+	cmd.Out = ""
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText(`"version": 1`, `"provider": "postgres"`)
+
+	s.Out.Reset()
+	cmd.Format = "yaml"
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.HasText("version: 1\n", "provider: postgres\n")
+
+	// the format is selected by the extension
+	cmd.Format = ""
+	cmd.Out = filepath.Join(s.T().TempDir(), "snapshot.yaml")
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+
+	snapshot, err = dbschema.LoadSnapshot(cmd.Out)
+	require.NoError(err)
+	s.Equal("postgres", snapshot.Provider)
+	require.Len(snapshot.Tables, len(res))
+	s.Equal("public.org", snapshot.Tables[0].SchemaName)
+	s.Equal(res[0].Columns, snapshot.Tables[0].Columns)
+}
@@ -14,10 +14,55 @@ type tableDefinition struct {
 	SchemaName      string
 	TableName       string
 	TableStructName string
+	Comment         string
 	Columns         schema.Columns
 	Indexes         schema.Indexes
 	PrimaryKey      *schema.Column
 	WithCache       bool
+	StreamColumns   schema.Columns
+	Renamed         []*renamedColumn
+	Enums           []*enumColumn
+	CRUD            *crudDefinition
+}
+
+// crudDefinition provides SQL statements of the generated repository
+type crudDefinition struct {
+	Get    string
+	List   string
+	Create string
+	Update string
+	Delete string
+	// CreateColumns are set by Create statement, the identity columns are excluded
+	CreateColumns schema.Columns
+	// UpdateColumns are set by Update statement, the primary key and identity columns are excluded
+	UpdateColumns schema.Columns
+	// SoftDelete is the column set by Delete statement, for tables with soft delete
+	SoftDelete *schema.Column
+	// Lock is the version column checked and set by Update statement, for tables with optimistic lock
+	Lock *schema.Column
+	// LockNext is Go expression of the next version
+	LockNext string
+}
+
+// renamedColumn provides deprecated alias of the renamed column
+type renamedColumn struct {
+	OldName  string
+	OldField string
+	Column   *schema.Column
+}
+
+// enumColumn provides typed constants of the column with the list of allowed values
+type enumColumn struct {
+	Column *schema.Column
+	Field  string
+	GoType string
+	Values []*enumValue
+}
+
+// enumValue provides the name of the constant for the value
+type enumValue struct {
+	Name  string
+	Value string
 }
 
 type schemaDefinition struct {
@@ -67,12 +112,19 @@ var {{ .StructName }} = struct {
 {{- range .Columns }}
 	{{columnStructName .}} schema.Column // {{.Name}} {{.Type}}
 {{- end }}
+{{- range .Renamed }}
+	// Deprecated: '{{ .OldName }}' column was renamed to '{{ .Column.Name }}', use {{ columnStructName .Column }}
+	{{ .OldField }} schema.Column
+{{- end }}
 }{
 	Table: &{{.TableStructName}},
 
 	{{- range .Columns }}
 	{{ columnStructName .}}: schema.Column{{.StructString}},
 	{{- end }}
+	{{- range .Renamed }}
+	{{ .OldField }}: schema.Column{{ .Column.StructString }},
+	{{- end }}
 }
 `
 
@@ -88,10 +140,18 @@ var codeModelTemplateText = `
 //   {{ .Name }}:{{if .IsPrimary }} PRIMARY{{end}}{{if .IsUnique }} UNIQUE{{end}} [{{ join .ColumnNames "," }}]
 {{- end }}
 {{- end }}
+{{- with .Comment }}
+//
+{{ goComment "" . }}
+{{- end }}
 type {{ .StructName }} struct {
 {{- range .Columns }}
 {{- $fieldName := columnStructName . }}
 	// {{$fieldName}} represents '{{.Name}}' column of '{{.Type}}'
+{{- with .Comment }}
+	//
+{{ goComment "\t" . }}
+{{- end }}
 	{{$fieldName}} {{ sqlToGoType . }} ` + "`" + `{{ .Tag }}` + "`" + `
 {{- end }}
 {{- if .WithCache }}
@@ -125,6 +185,71 @@ func(m *{{ .StructName }}) ScanRow(rows xdb.Row) error {
 	}
 	return nil
 }
+{{- $structName := .StructName }}
+{{- range .Renamed }}
+{{- $fieldName := columnStructName .Column }}
+
+// {{ .OldField }} returns '{{ .Column.Name }}' column, that was renamed from '{{ .OldName }}'.
+//
+// Deprecated: use {{ $fieldName }} field.
+func(m *{{ $structName }}) {{ .OldField }}() {{ sqlToGoType .Column }} {
+	return m.{{ $fieldName }}
+}
+{{- end }}
+{{- range .Columns }}
+{{- if isJSONColumn . }}
+{{- $fieldName := columnStructName . }}
+
+// Decode{{$fieldName}} decodes '{{.Name}}' JSON column into v.
+func(m *{{ $structName }}) Decode{{$fieldName}}(v any) error {
+	return xdb.DecodeJSON(string(m.{{$fieldName}}), v)
+}
+{{- end }}
+{{- end }}
+{{- range .Enums }}
+
+// Allowed values of '{{ .Column.Name }}' column.
+const (
+{{- $goType := .GoType }}
+{{- range .Values }}
+	{{ .Name }} {{ $goType }} = {{ printf "%q" .Value }}
+{{- end }}
+)
+
+// {{ $structName }}{{ .Field }}Values provides the allowed values of '{{ .Column.Name }}' column.
+var {{ $structName }}{{ .Field }}Values = []{{ .GoType }}{
+{{- range .Values }}
+	{{ .Name }},
+{{- end }}
+}
+{{- end }}
+{{- if .Enums }}
+
+// Validate returns error if the columns have values that are not allowed.
+func(m *{{ $structName }}) Validate() error {
+{{- range .Enums }}
+	switch m.{{ .Field }} {
+	case {{ range $i, $v := .Values }}{{ if $i }}, {{ end }}{{ $v.Name }}{{ end }}{{ if .Column.Nullable }}, ""{{ end }}:
+	default:
+		return errors.Errorf("invalid value of '{{ .Column.Name }}' column: %q", m.{{ .Field }})
+	}
+{{- end }}
+	return nil
+}
+{{- end }}
+{{- if .PrimaryKey }}
+{{- $pk := .PrimaryKey }}
+{{- $table := concat .SchemaName "." .TableName }}
+{{- range .StreamColumns }}
+{{- $fieldName := columnStructName . }}
+
+// Open{{$fieldName}}Reader returns a reader that streams '{{.Name}}' column in chunks.
+// Exclude the column from the select list to avoid loading it in memory.
+func(m *{{ $structName }}) Open{{$fieldName}}Reader(ctx context.Context, p xdb.Provider) *xdb.ColumnReader {
+	return xdb.NewColumnReader(ctx, p, "{{ $table }}", "{{.Name}}", "{{ $pk.Name }} = ?", m.{{ columnStructName $pk }})
+}
+{{- end }}
+{{- end }}
 
 type {{ .StructName }}Slice []*{{ .StructName }}
 type {{ .StructName }}Result struct {
@@ -169,6 +294,10 @@ var Dialect = {{ .Dialect }}
 {{ range .Tables }}
 {{- $tableName := tableInfoStructName . }}
 // {{ $tableName }} provides table info for '{{ .Name }}'
+{{- with .Comment }}
+//
+{{ goComment "" . }}
+{{- end }}
 var {{ $tableName }} = schema.TableInfo{
 	SchemaName : "{{ .SchemaName }}",
 	Schema     : "{{ .Schema }}",
@@ -176,6 +305,22 @@ var {{ $tableName }} = schema.TableInfo{
 	PrimaryKey : "{{ .PrimaryKey }}", 
 	Columns    : []string{ {{- range .Columns }}"{{ . }}", {{ end -}} },
 	Indexes    : []string{ {{- range .Indexes }}"{{ . }}", {{ end -}} },
+{{- if .PartitionKey }}
+	PartitionKey: "{{ .PartitionKey }}",
+{{- end }}
+{{- if .Comment }}
+	Comment    : {{ printf "%q" .Comment }},
+{{- end }}
+{{- if .SoftDeleteColumn }}
+	SoftDeleteColumn: "{{ .SoftDeleteColumn }}",
+{{- end }}
+{{- if .QuoteNames }}
+	QuoteNames : true,
+{{- end }}
+	Types      : []string{ {{- range .Types }}"{{ . }}", {{ end -}} },
+{{- if .References }}
+	References : []string{ {{- range .References }}"{{ . }}", {{ end -}} },
+{{- end }}
 	Dialect    : {{ $dialect }},
 }
 {{ end }}
@@ -187,3 +332,123 @@ var {{ goName .DB }}Tables = map[string]*schema.TableInfo{
 {{- end }}
 }
 `
+
+var codeCRUDTemplateText = `
+{{- $structName := .StructName }}
+{{- $table := concat .SchemaName "." .TableName }}
+{{- $pk := .PrimaryKey }}
+{{- $pkField := columnStructName $pk }}
+{{- with .CRUD }}
+
+const (
+	sql{{ $structName }}Get    = {{ printf "%q" .Get }}
+	sql{{ $structName }}List   = {{ printf "%q" .List }}
+	sql{{ $structName }}Create = {{ printf "%q" .Create }}
+	sql{{ $structName }}Update = {{ printf "%q" .Update }}
+	sql{{ $structName }}Delete = {{ printf "%q" .Delete }}
+)
+
+// {{ $structName }}Repository provides CRUD operations for table '{{ $table }}'.
+type {{ $structName }}Repository struct {
+	db xdb.DB
+}
+
+// New{{ $structName }}Repository returns the repository for table '{{ $table }}'.
+func New{{ $structName }}Repository(db xdb.DB) *{{ $structName }}Repository {
+	return &{{ $structName }}Repository{db: db}
+}
+
+// Get returns the row by '{{ $pk.Name }}' primary key.
+{{- with .SoftDelete }}
+// The deleted rows are not returned.
+{{- end }}
+func (r *{{ $structName }}Repository) Get(ctx context.Context, id {{ sqlToGoType $pk }}) (*{{ $structName }}, error) {
+	return xdb.QueryRow[{{ $structName }}](ctx, r.db, sql{{ $structName }}Get, id)
+}
+
+// List returns the page of rows ordered by '{{ $pk.Name }}' primary key.
+{{- with .SoftDelete }}
+// The deleted rows are not returned.
+{{- end }}
+func (r *{{ $structName }}Repository) List(ctx context.Context, params xdb.PageableByOffset) (*{{ $structName }}Result, error) {
+	limit, offset := params.Page()
+	if limit == 0 {
+		limit = xdb.DefaultPageSize
+	}
+	res := new({{ $structName }}Result)
+	err := xdb.ExecuteQueryWithPagination[{{ $structName }}](ctx, r.db, res, sql{{ $structName }}List, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Create inserts the row.
+func (r *{{ $structName }}Repository) Create(ctx context.Context, m *{{ $structName }}) error {
+	_, err := r.db.ExecContext(ctx, sql{{ $structName }}Create,
+{{- range .CreateColumns }}
+		m.{{ columnStructName . }},
+{{- end }}
+	)
+	return errors.WithStack(err)
+}
+
+{{- if .Lock }}
+{{- $lockField := columnStructName .Lock }}
+
+// Update updates the row by '{{ $pk.Name }}' primary key and '{{ .Lock.Name }}' version,
+// and sets the next version of the model.
+// xdb.StaleRowError is returned if the row was modified or deleted after it was read.
+func (r *{{ $structName }}Repository) Update(ctx context.Context, m *{{ $structName }}) error {
+	version := {{ .LockNext }}
+	res, err := r.db.ExecContext(ctx, sql{{ $structName }}Update,
+{{- range .UpdateColumns }}
+		m.{{ columnStructName . }},
+{{- end }}
+		version,
+		m.{{ $pkField }},
+		m.{{ $lockField }},
+	)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err = xdb.MustAffect(res, 1); errors.Is(err, xdb.ErrNoRowsAffected) {
+		return errors.WithStack(&xdb.StaleRowError{Table: "{{ $table }}", Key: m.{{ $pkField }}})
+	} else if err != nil {
+		return err
+	}
+	m.{{ $lockField }} = version
+	return nil
+}
+{{- else }}
+
+// Update updates the row by '{{ $pk.Name }}' primary key.
+func (r *{{ $structName }}Repository) Update(ctx context.Context, m *{{ $structName }}) error {
+	_, err := r.db.ExecContext(ctx, sql{{ $structName }}Update,
+{{- range .UpdateColumns }}
+		m.{{ columnStructName . }},
+{{- end }}
+		m.{{ $pkField }},
+	)
+	return errors.WithStack(err)
+}
+{{- end }}
+
+{{- if .SoftDelete }}
+
+// Delete marks the row by '{{ $pk.Name }}' primary key as deleted,
+// by setting '{{ .SoftDelete.Name }}' column.
+func (r *{{ $structName }}Repository) Delete(ctx context.Context, id {{ sqlToGoType $pk }}) error {
+	_, err := r.db.ExecContext(ctx, sql{{ $structName }}Delete, xdb.Now(), id)
+	return errors.WithStack(err)
+}
+{{- else }}
+
+// Delete deletes the row by '{{ $pk.Name }}' primary key.
+func (r *{{ $structName }}Repository) Delete(ctx context.Context, id {{ sqlToGoType $pk }}) error {
+	_, err := r.db.ExecContext(ctx, sql{{ $structName }}Delete, id)
+	return errors.WithStack(err)
+}
+{{- end }}
+{{- end }}
+`
@@ -48,11 +48,11 @@ func TestSqlToGoType(t *testing.T) {
 		},
 		{
 			col: dbschema.Column{Type: "decimal", Nullable: false},
-			exp: "float64",
+			exp: "xdb.Decimal",
 		},
 		{
 			col: dbschema.Column{Type: "decimal", Nullable: true},
-			exp: "xdb.Float",
+			exp: "xdb.Decimal",
 		},
 		{
 			col: dbschema.Column{Type: "bit", Nullable: false},
@@ -159,11 +159,19 @@ func TestPgToGoType(t *testing.T) {
 		},
 		{
 			col: dbschema.Column{Type: "decimal", Nullable: false},
-			exp: "float64",
+			exp: "xdb.Decimal",
 		},
 		{
 			col: dbschema.Column{Type: "decimal", Nullable: true},
-			exp: "xdb.Float",
+			exp: "xdb.Decimal",
+		},
+		{
+			col: dbschema.Column{Type: "numeric", UdtType: "numeric", Nullable: true},
+			exp: "xdb.Decimal",
+		},
+		{
+			col: dbschema.Column{Type: "money", UdtType: "money", Nullable: false},
+			exp: "xdb.Decimal",
 		},
 		{
 			col: dbschema.Column{Type: "real", Nullable: false},
@@ -197,6 +205,22 @@ func TestPgToGoType(t *testing.T) {
 			col: dbschema.Column{Type: "timestamp without time zone", UdtType: "timestamp", Nullable: true},
 			exp: "xdb.Time",
 		},
+		{
+			col: dbschema.Column{Type: "date", UdtType: "date", Nullable: false},
+			exp: "xdb.Date",
+		},
+		{
+			col: dbschema.Column{Type: "interval", UdtType: "interval", Nullable: false},
+			exp: "xdb.Duration",
+		},
+		{
+			col: dbschema.Column{Type: "interval", UdtType: "interval", Nullable: true},
+			exp: "xdb.Duration",
+		},
+		{
+			col: dbschema.Column{Type: "date", UdtType: "date", Nullable: true},
+			exp: "xdb.Date",
+		},
 		{
 			col: dbschema.Column{Type: "jsonb", Nullable: false},
 			exp: "xdb.NULLString",
@@ -211,7 +235,7 @@ func TestPgToGoType(t *testing.T) {
 		},
 		{
 			col: dbschema.Column{Type: "ARRAY", UdtType: "_int8", Nullable: true},
-			exp: "pq.Int64Array",
+			exp: "xdb.Array[int64]",
 		},
 		{
 			col: dbschema.Column{Type: "ARRAY", UdtType: "_int8", Nullable: true, Name: "test_ids"},
@@ -219,7 +243,7 @@ func TestPgToGoType(t *testing.T) {
 		},
 		{
 			col: dbschema.Column{Type: "ARRAY", UdtType: "_varchar", Nullable: true},
-			exp: "pq.StringArray",
+			exp: "xdb.StringArray",
 		},
 		{
 			col: dbschema.Column{Type: "uniqueidentifier", Nullable: false},
@@ -248,6 +272,31 @@ func TestPgToGoType(t *testing.T) {
 	assert.Panics(t, func() { toGoType(&dbschema.Column{Type: "unknown"}) }, "toGoType(unknown) should panic")
 }
 
+func TestMySQLToGoType(t *testing.T) {
+	tcases := []struct {
+		col dbschema.Column
+		exp string
+	}{
+		{col: dbschema.Column{Name: "id", Type: "bigint", UdtType: "bigint"}, exp: "xdb.ID"},
+		{col: dbschema.Column{Type: "mediumint", UdtType: "mediumint"}, exp: "int32"},
+		{col: dbschema.Column{Type: "mediumint", UdtType: "mediumint", Nullable: true}, exp: "xdb.Int32"},
+		{col: dbschema.Column{Type: "double", UdtType: "double"}, exp: "float64"},
+		{col: dbschema.Column{Type: "float", UdtType: "float", Nullable: true}, exp: "xdb.Float"},
+		{col: dbschema.Column{Type: "longtext", UdtType: "longtext"}, exp: "string"},
+		{col: dbschema.Column{Type: "mediumtext", UdtType: "mediumtext", Nullable: true}, exp: "xdb.NULLString"},
+		{col: dbschema.Column{Type: "enum", UdtType: "enum"}, exp: "string"},
+		{col: dbschema.Column{Type: "json", UdtType: "json", Nullable: true}, exp: "xdb.NULLString"},
+		{col: dbschema.Column{Type: "varbinary", UdtType: "varbinary"}, exp: "[]byte"},
+		{col: dbschema.Column{Type: "datetime", UdtType: "datetime", Nullable: true}, exp: "xdb.Time"},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.col.Type, func(t *testing.T) {
+			assert.Equal(t, tc.exp, toGoType(&tc.col))
+		})
+	}
+}
+
 func TestGoName(t *testing.T) {
 
 	tcases := map[string]string{
@@ -3,21 +3,29 @@ package schema
 import (
 	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/effective-security/x/values"
 	"github.com/effective-security/xdb/schema"
+	"github.com/ettle/strcase"
 )
 
 var typesMap = map[string]string{}
 var fieldNamesMap = map[string]string{}
 var tableNamesMap = map[string]string{}
 var modelWithCacheMap = map[string]bool{}
+var streamColumnsMap = map[string]bool{}
+var softDeleteMap = map[string]string{}
+var optimisticLockMap = map[string]string{}
 
 var typeByColumnType = map[string]string{
 	"id bigint":      "xdb.ID",
 	"id bigint NULL": "xdb.ID",
 	"id int8":        "xdb.ID",
 	"id int8 NULL":   "xdb.ID",
+	// SQLite
+	"id integer":      "xdb.ID",
+	"id integer NULL": "xdb.ID",
 
 	"id int":       "xdb.ID32",
 	"id int NULL":  "xdb.ID32",
@@ -26,6 +34,9 @@ var typeByColumnType = map[string]string{
 
 	"bigint":      "int64",
 	"bigint NULL": "xdb.Int64",
+	// SQLite
+	"integer":      "int64",
+	"integer NULL": "xdb.Int64",
 
 	"int8":     "int64",
 	"int4":     "int32",
@@ -33,19 +44,34 @@ var typeByColumnType = map[string]string{
 	"int2":     "int16",
 	"smallint": "int16",
 	"tinyint":  "int8",
+	// MySQL
+	"mediumint": "int32",
+	"year":      "int16",
 
-	"decimal": "float64",
-	"numeric": "float64",
-	"real":    "float32",
-	"float4":  "float32",
-	"float8":  "float64",
+	"decimal":    "xdb.Decimal",
+	"numeric":    "xdb.Decimal",
+	"money":      "xdb.Decimal",
+	"smallmoney": "xdb.Decimal",
+	"real":       "float32",
+	"float4":     "float32",
+	"float8":     "float64",
+	"float":      "float64",
+	"double":     "float64",
 
 	"bool":    "bool",
 	"boolean": "bool",
 	"bit":     "bool",
 
 	"jsonb": "xdb.NULLString",
+	"json":  "xdb.NULLString",
 	"bytea": "[]byte",
+	// MySQL
+	"blob":       "[]byte",
+	"tinyblob":   "[]byte",
+	"mediumblob": "[]byte",
+	"longblob":   "[]byte",
+	"binary":     "[]byte",
+	"varbinary":  "[]byte",
 
 	"nchar":    "string",
 	"nvarchar": "string",
@@ -53,6 +79,11 @@ var typeByColumnType = map[string]string{
 	"varchar":  "string",
 	"bpchar":   "string",
 	"text":     "string",
+	// MySQL
+	"tinytext":   "string",
+	"mediumtext": "string",
+	"longtext":   "string",
+	"enum":       "string",
 
 	"int8 NULL":     "xdb.Int64",
 	"int4 NULL":     "xdb.Int32",
@@ -60,30 +91,41 @@ var typeByColumnType = map[string]string{
 	"int2 NULL":     "xdb.Int32",
 	"smallint NULL": "xdb.Int32",
 	"tinyint NULL":  "xdb.Int32",
+	// MySQL
+	"mediumint NULL": "xdb.Int32",
+	"year NULL":      "xdb.Int32",
 
 	"bool NULL":    "xdb.Bool",
 	"boolean NULL": "xdb.Bool",
 	"bit NULL":     "xdb.Bool",
 
-	"decimal NULL": "xdb.Float",
-	"numeric NULL": "xdb.Float",
-	"real NULL":    "xdb.Float",
-	"float4 NULL":  "xdb.Float",
-	"float8 NULL":  "xdb.Float",
+	"real NULL":   "xdb.Float",
+	"float4 NULL": "xdb.Float",
+	"float8 NULL": "xdb.Float",
+	"float NULL":  "xdb.Float",
+	"double NULL": "xdb.Float",
 
 	"time":        "xdb.Time",
-	"date":        "xdb.Time",
+	"date":        "xdb.Date",
 	"datetime":    "xdb.Time",
 	"datetime2":   "xdb.Time",
 	"timestamp":   "xdb.Time",
 	"timestamptz": "xdb.Time",
 
+	"interval":      "xdb.Duration",
+	"interval NULL": "xdb.Duration",
+
 	"nchar NULL":    "xdb.NULLString",
 	"nvarchar NULL": "xdb.NULLString",
 	"char NULL":     "xdb.NULLString",
 	"bpchar NULL":   "xdb.NULLString",
 	"varchar NULL":  "xdb.NULLString",
 	"text NULL":     "xdb.NULLString",
+	// MySQL
+	"tinytext NULL":   "xdb.NULLString",
+	"mediumtext NULL": "xdb.NULLString",
+	"longtext NULL":   "xdb.NULLString",
+	"enum NULL":       "xdb.NULLString",
 
 	"uniqueidentifier":      "xdb.UUID",
 	"uuid":                  "xdb.UUID",
@@ -98,6 +140,67 @@ func isID(c *schema.Column) bool {
 		strings.HasSuffix(c.Name, "ID")
 }
 
+// isJSONColumn returns true for JSON columns mapped to string types,
+// the generator emits typed decoders for such columns
+func isJSONColumn(c *schema.Column) bool {
+	typ := strings.ToLower(values.StringsCoalesce(c.UdtType, c.Type))
+	if typ != "json" && typ != "jsonb" {
+		return false
+	}
+	goType := toGoType(c)
+	return goType == "xdb.NULLString" || goType == "string"
+}
+
+// enumColumns returns the columns with the list of allowed values,
+// from enum types or check constraints, that are mapped to string types
+func enumColumns(structName string, cols schema.Columns) []*enumColumn {
+	var list []*enumColumn
+	for _, c := range cols {
+		if len(c.Enum) == 0 {
+			continue
+		}
+		goType := toGoType(c)
+		if goType != "string" && goType != "xdb.NULLString" {
+			continue
+		}
+		ec := &enumColumn{
+			Column: c,
+			Field:  columnStructName(c),
+			GoType: goType,
+		}
+		names := map[string]bool{}
+		for i, v := range c.Enum {
+			name := enumValueName(v)
+			if name == "" || names[name] {
+				name = fmt.Sprintf("Value%d", i)
+			}
+			names[name] = true
+			ec.Values = append(ec.Values, &enumValue{
+				Name:  structName + ec.Field + name,
+				Value: v,
+			})
+		}
+		list = append(list, ec)
+	}
+	return list
+}
+
+// enumValueName returns Go name for the enum value,
+// the characters that are not valid in Go identifiers are replaced by underscore
+func enumValueName(v string) string {
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, v)
+	s = strings.Trim(s, "_")
+	if s == "" {
+		return ""
+	}
+	return strcase.ToGoPascal(s)
+}
+
 func toGoType(c *schema.Column) string {
 	if res, ok := typesMap[c.Name]; ok {
 		return res
@@ -109,6 +212,11 @@ func toGoType(c *schema.Column) string {
 		return res
 	}
 
+	// user-defined enum types are mapped to strings
+	if len(c.Enum) > 0 {
+		return values.Select(c.Nullable, "xdb.NULLString", "string")
+	}
+
 	if c.Type == "ARRAY" {
 		typeName := "[]"
 		switch c.UdtType {
@@ -116,10 +224,12 @@ func toGoType(c *schema.Column) string {
 			if strings.HasSuffix(c.Name, "_ids") {
 				typeName = "xdb.IDArray"
 			} else {
-				typeName = "pq.Int64Array"
+				typeName = "xdb.Array[int64]"
 			}
+		case "_int4":
+			typeName = "xdb.Array[int32]"
 		case "_text", "_varchar":
-			typeName = "pq.StringArray"
+			typeName = "xdb.StringArray"
 		default:
 			panic(fmt.Sprintf("don't know how to convert ARRAY: %s [%s]", c.UdtType, c.Name))
 		}
@@ -0,0 +1,262 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/effective-security/xdb/pkg/cli"
+	"github.com/pkg/errors"
+)
+
+// VerifyCmd verifies that generated Go model matches database schema
+type VerifyCmd struct {
+	DB           string   `help:"database name" required:""`
+	Schema       string   `help:"optional schema name to filter"`
+	Table        []string `help:"optional, list of tables, default: all tables"`
+	View         []string `help:"optional, list of views"`
+	Dependencies bool     `help:"optional, to discover all dependencies"`
+	Models       string   `help:"folder name with generated model files" required:""`
+	Schemas      string   `help:"optional, folder name with generated schema files"`
+	PkgModel     string   `help:"package name to override from --models path"`
+	PkgSchema    string   `help:"package name to override from --schemas path"`
+	StructSuffix string   `help:"optional, suffix for struct names"`
+	Imports      []string `help:"optional go imports"`
+	UseSchema    bool     `help:"optional, use schema name in table name"`
+	TypesDef     string   `help:"optional, path to types definition file"`
+	GenCrud      bool     `help:"optional, verify CRUD repository generated with --gen-crud"`
+}
+
+// Run the command
+func (a *VerifyCmd) Run(ctx *cli.Cli) error {
+	r, err := ctx.SchemaProvider(a.DB)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.ListTables(ctx.Context(), a.Schema, a.Table, a.Dependencies)
+	if err != nil {
+		return err
+	}
+
+	if len(a.View) > 0 {
+		res2, err := r.ListViews(ctx.Context(), a.Schema, a.View)
+		if err != nil {
+			return err
+		}
+		res = append(res, res2...)
+	}
+
+	gen := &GenerateCmd{
+		DB:           a.DB,
+		Schema:       a.Schema,
+		Table:        a.Table,
+		View:         a.View,
+		Dependencies: a.Dependencies,
+		OutModel:     a.Models,
+		OutSchema:    a.Schemas,
+		PkgModel:     a.PkgModel,
+		PkgSchema:    a.PkgSchema,
+		StructSuffix: a.StructSuffix,
+		Imports:      a.Imports,
+		UseSchema:    a.UseSchema,
+		TypesDef:     a.TypesDef,
+		GenCrud:      a.GenCrud,
+	}
+	code, err := gen.render(r.Name(), a.DB, res)
+	if err != nil {
+		return err
+	}
+
+	mismatches, err := a.verify(code)
+	if err != nil {
+		return err
+	}
+
+	if len(mismatches) > 0 {
+		w := ctx.Writer()
+		for _, m := range mismatches {
+			fmt.Fprintln(w, m)
+		}
+		return errors.Errorf("verification failed: %d mismatches", len(mismatches))
+	}
+	return nil
+}
+
+func (a *VerifyCmd) verify(code *generatedCode) ([]string, error) {
+	var mismatches []string
+
+	mismatches = append(mismatches, diffFile(filepath.Join(a.Models, modelFileName), code.Model)...)
+	if a.Schemas != "" {
+		mismatches = append(mismatches, diffFile(filepath.Join(a.Schemas, schemaFileName), code.Schema)...)
+	}
+
+	fields, err := verifyFields(a.Models, code.Defs)
+	if err != nil {
+		return nil, err
+	}
+	return append(mismatches, fields...), nil
+}
+
+// diffFile compares the file with expected content,
+// and reports the range of lines that differ
+func diffFile(fn string, expected []byte) []string {
+	actual, err := os.ReadFile(fn)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %s", fn, err.Error())}
+	}
+	if bytes.Equal(actual, expected) {
+		return nil
+	}
+
+	exp := strings.Split(string(expected), "\n")
+	act := strings.Split(string(actual), "\n")
+
+	start := 0
+	for start < len(exp) && start < len(act) && exp[start] == act[start] {
+		start++
+	}
+	endExp, endAct := len(exp), len(act)
+	for endExp > start && endAct > start && exp[endExp-1] == act[endAct-1] {
+		endExp--
+		endAct--
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s:%d: generated code does not match database schema", fn, start+1)
+	for _, l := range act[start:endAct] {
+		fmt.Fprintf(&sb, "\n\t- %s", l)
+	}
+	for _, l := range exp[start:endExp] {
+		fmt.Fprintf(&sb, "\n\t+ %s", l)
+	}
+	return []string{sb.String()}
+}
+
+var rowCommentRegex = regexp.MustCompile(`represents one row from table '([^']+)'`)
+
+// verifyFields checks that every db-tagged field of model structs
+// maps to an existing column of the table
+func verifyFields(folder string, defs []*tableDefinition) ([]string, error) {
+	tables := map[string]*tableDefinition{}
+	structs := map[string]*tableDefinition{}
+	for _, td := range defs {
+		tables[td.SchemaName+"."+td.TableName] = td
+		structs[td.StructName] = td
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, folder, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to parse models")
+	}
+
+	var mismatches []string
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+
+					doc := ts.Doc
+					if doc == nil {
+						doc = gd.Doc
+					}
+
+					var td *tableDefinition
+					var tableName string
+					if doc != nil {
+						if m := rowCommentRegex.FindStringSubmatch(doc.Text()); m != nil {
+							tableName = m[1]
+							td = tables[tableName]
+						}
+					}
+					if td == nil && tableName == "" {
+						td = structs[ts.Name.Name]
+					}
+
+					if td == nil {
+						if tableName != "" && hasDBFields(st) {
+							pos := fset.Position(ts.Pos())
+							mismatches = append(mismatches, fmt.Sprintf("%s:%d: %s: table '%s' does not exist",
+								pos.Filename, pos.Line, ts.Name.Name, tableName))
+						}
+						// not a model struct
+						continue
+					}
+
+					for _, field := range st.Fields.List {
+						column := dbColumnName(field)
+						if column == "" || hasColumn(td, column) {
+							continue
+						}
+						pos := fset.Position(field.Pos())
+						mismatches = append(mismatches, fmt.Sprintf("%s:%d: %s.%s: column '%s' does not exist in '%s.%s'",
+							pos.Filename, pos.Line, ts.Name.Name, fieldName(field), column, td.SchemaName, td.TableName))
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(mismatches)
+	return mismatches, nil
+}
+
+func hasColumn(td *tableDefinition, name string) bool {
+	for _, c := range td.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDBFields(st *ast.StructType) bool {
+	for _, field := range st.Fields.List {
+		if dbColumnName(field) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func dbColumnName(field *ast.Field) string {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	name, _, _ := strings.Cut(reflect.StructTag(tag).Get("db"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func fieldName(field *ast.Field) string {
+	if len(field.Names) > 0 {
+		return field.Names[0].Name
+	}
+	return fmt.Sprintf("%v", field.Type)
+}
@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/effective-security/x/configloader"
+	"github.com/effective-security/xdb/mocks/mockschema"
+	dbschema "github.com/effective-security/xdb/schema"
+	"github.com/golang/mock/gomock"
+)
+
+func (s *testSuite) TestVerify() {
+	require := s.Require()
+
+	var res dbschema.Tables
+	err := configloader.Unmarshal("testdata/pg_columns.json", &res)
+	require.NoError(err)
+
+	dir := s.T().TempDir()
+	models := filepath.Join(dir, "model")
+	schemas := filepath.Join(dir, "schema")
+
+	gen := GenerateCmd{
+		DB:        "org",
+		OutModel:  models,
+		OutSchema: schemas,
+	}
+	err = gen.generate(s.Ctl, "postgres", "org", res)
+	require.NoError(err)
+
+	ctrl := gomock.NewController(s.T())
+	mock := mockschema.NewMockProvider(ctrl)
+	s.Ctl.WithSchemaProvider(mock)
+	mock.EXPECT().Name().Return("postgres").AnyTimes()
+	mock.EXPECT().ListTables(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(res, nil).AnyTimes()
+
+	cmd := VerifyCmd{
+		DB:      "org",
+		Models:  models,
+		Schemas: schemas,
+	}
+	err = cmd.Run(s.Ctl)
+	require.NoError(err)
+	s.Empty(s.Out.String())
+
+	fn := filepath.Join(models, modelFileName)
+	code, err := os.ReadFile(fn)
+	require.NoError(err)
+	require.NoError(os.WriteFile(fn, []byte(string(code)+"\n// manual edit\n"), 0666))
+
+	extra := `package model
+
+// Custom represents one row from table 'public.org'.
+type Custom struct {
+	ID    int64  ` + "`db:\"id\"`" + `
+	Extra string ` + "`db:\"extra_column\"`" + `
+	Skip  string ` + "`db:\"-\"`" + `
+}
+
+// Gone represents one row from table 'public.gone'.
+type Gone struct {
+	ID int64 ` + "`db:\"id\"`" + `
+}
+
+type Other struct {
+	Name string ` + "`db:\"name\"`" + `
+}
+`
+	require.NoError(os.WriteFile(filepath.Join(models, "custom.go"), []byte(extra), 0666))
+
+	s.Out.Reset()
+	err = cmd.Run(s.Ctl)
+	s.EqualError(err, "verification failed: 3 mismatches")
+	s.HasText(
+		fn+":",
+		"generated code does not match database schema\n\t- // manual edit",
+		"custom.go:6: Custom.Extra: column 'extra_column' does not exist in 'public.org'",
+		"custom.go:11: Gone: table 'public.gone' does not exist",
+	)
+	s.NotContains(s.Out.String(), "Other")
+}
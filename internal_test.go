@@ -2,6 +2,7 @@ package xdb
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -27,3 +28,99 @@ func TestID(t *testing.T) {
 	assert.Equal(t, id2.String(), id3.String())
 	assert.Equal(t, id2, id3)
 }
+
+func TestUpsertQuery(t *testing.T) {
+	cols := []string{"id", "name", "value"}
+
+	assert.Equal(t,
+		"INSERT INTO s.t (id, name, value) VALUES (?, ?, ?), (?, ?, ?) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, value = EXCLUDED.value",
+		upsertQuery("postgres", "s.t", cols, 2, []string{"id"}, []string{"name", "value"}))
+	assert.Equal(t,
+		"INSERT INTO s.t (id, name, value) VALUES (?, ?, ?) ON CONFLICT (id, name) DO NOTHING",
+		upsertQuery("postgres", "s.t", cols, 1, []string{"id", "name"}, nil))
+	assert.Equal(t,
+		"INSERT INTO s.t (id, name, value) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE value = VALUES(value)",
+		upsertQuery("mysql", "s.t", cols, 1, []string{"id"}, []string{"value"}))
+	assert.Equal(t,
+		"INSERT INTO s.t (id, name, value) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE id = id",
+		upsertQuery("mysql", "s.t", cols, 1, []string{"id"}, nil))
+	assert.Equal(t,
+		"MERGE INTO s.t WITH (HOLDLOCK) AS target USING (VALUES (?, ?, ?), (?, ?, ?)) AS source (id, name, value) ON target.id = source.id AND target.name = source.name WHEN MATCHED THEN UPDATE SET target.value = source.value WHEN NOT MATCHED THEN INSERT (id, name, value) VALUES (source.id, source.name, source.value);",
+		upsertQuery("sqlserver", "s.t", cols, 2, []string{"id", "name"}, []string{"value"}))
+	assert.Equal(t,
+		"MERGE INTO s.t WITH (HOLDLOCK) AS target USING (VALUES (?, ?, ?)) AS source (id, name, value) ON target.id = source.id WHEN NOT MATCHED THEN INSERT (id, name, value) VALUES (source.id, source.name, source.value);",
+		upsertQuery("sqlserver", "s.t", cols, 1, []string{"id"}, nil))
+}
+
+func TestInsertReturningQuery(t *testing.T) {
+	cols := []string{"name", "value"}
+
+	assert.Equal(t,
+		"INSERT INTO s.t (name, value) VALUES (?, ?), (?, ?) RETURNING id",
+		insertReturningQuery("postgres", "s.t", cols, 2, "id"))
+	assert.Equal(t,
+		"MERGE INTO s.t AS target USING (VALUES (?, ?, 0), (?, ?, 1)) AS source (name, value, xdb_ord) ON 1 = 0 WHEN NOT MATCHED THEN INSERT (name, value) VALUES (source.name, source.value) OUTPUT source.xdb_ord, inserted.id;",
+		insertReturningQuery("sqlserver", "s.t", cols, 2, "id"))
+}
+
+func TestPartitionDDL(t *testing.T) {
+	from, to := PartitionMonthly.Range(time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC))
+	assert.Equal(t,
+		"CREATE TABLE IF NOT EXISTS public.orders_p2024_12 PARTITION OF public.orders FOR VALUES FROM ('2024-12-01 00:00:00Z') TO ('2025-01-01 00:00:00Z')",
+		partitionDDL("public.orders", "public.orders_p2024_12", from, to))
+}
+
+func TestSessionStatements(t *testing.T) {
+	list, err := sessionStatements("sqlite3", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	settings := map[string]string{"app.user_id": "2", "app.tenant_id": "1"}
+	list, err = sessionStatements("postgres", settings)
+	assert.NoError(t, err)
+	assert.Equal(t, []sessionStatement{
+		{Query: "SELECT set_config($1, $2, true)", Args: []any{"app.tenant_id", "1"}},
+		{Query: "SELECT set_config($1, $2, true)", Args: []any{"app.user_id", "2"}},
+	}, list)
+
+	list, err = sessionStatements("sqlserver", map[string]string{"tenant_id": "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []sessionStatement{
+		{Query: "EXEC sp_set_session_context @key = @p1, @value = @p2", Args: []any{"tenant_id", "1"}},
+	}, list)
+
+	_, err = sessionStatements("postgres", map[string]string{"": "1"})
+	assert.EqualError(t, err, "session setting name is empty")
+	_, err = sessionStatements("sqlite3", settings)
+	assert.EqualError(t, err, "session settings are not supported by sqlite3")
+}
+
+func TestIsReadQuery(t *testing.T) {
+	for _, q := range []string{
+		"SELECT id FROM users",
+		"  -- list\n/* users */ select id, updated_at, last_update FROM users",
+		"(SELECT id FROM a) UNION (SELECT id FROM b)",
+		"WITH t AS (SELECT id FROM users) SELECT * FROM t",
+		"SELECT id FROM users WHERE name = 'x' ORDER BY id LIMIT 10",
+	} {
+		assert.True(t, isReadQuery(q), q)
+	}
+	for _, q := range []string{
+		"INSERT INTO users (id) VALUES ($1) RETURNING id",
+		"UPDATE users SET name = $1 WHERE id = $2 RETURNING id",
+		"DELETE FROM users OUTPUT DELETED.id WHERE id = ?",
+		"MERGE INTO users USING (VALUES (?)) AS s (id) ON 1 = 0 WHEN NOT MATCHED THEN INSERT (id) VALUES (s.id) OUTPUT INSERTED.id;",
+		"WITH d AS (DELETE FROM users RETURNING id) SELECT id FROM d",
+		"SELECT id FROM users WHERE id = $1 FOR UPDATE SKIP LOCKED",
+		"SELECT id FROM users FOR NO KEY UPDATE",
+		"SELECT id FROM users FOR SHARE",
+		"SELECT TOP (?) id FROM users WITH (UPDLOCK, ROWLOCK, READPAST)",
+		"SELECT id FROM users LOCK IN SHARE MODE",
+		"SELECT id INTO archive FROM users",
+		"SELECT nextval('users_seq')",
+		"CALL refresh()",
+		"",
+	} {
+		assert.False(t, isReadQuery(q), q)
+	}
+}
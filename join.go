@@ -0,0 +1,57 @@
+package xdb
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ScanJoined scans a row of a join query into multiple models,
+// so join queries don't require composite structs:
+//
+//	q := schema.OrgTable.SelectAliased("o", nil).
+//		Select(schema.OrgMemberTable.AliasedColumns("m", nil)).
+//		Join("public.orgmember m", "m.org_id = o.id")
+//
+//	for rows.Next() {
+//		org, member := new(model.Org), new(model.Orgmember)
+//		err = xdb.ScanJoined(rows, org, member)
+//	}
+//
+// The columns of the row are split between models in the order of models,
+// each model receives as many columns as its ScanRow scans,
+// that is the number of columns in its TableInfo for generated models.
+func ScanJoined(row Row, models ...RowScanner) error {
+	var dest []any
+	for _, m := range models {
+		c := &destCollector{}
+		if err := m.ScanRow(c); err != nil {
+			return err
+		}
+		dest = append(dest, c.dest...)
+	}
+
+	if cr, ok := row.(interface{ Columns() ([]string, error) }); ok {
+		cols, err := cr.Columns()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if len(cols) != len(dest) {
+			return errors.Errorf("joined row has %d columns, models expect %d", len(cols), len(dest))
+		}
+	}
+
+	return errors.WithStack(row.Scan(dest...))
+}
+
+// destCollector implements Row to collect scan destinations of a model
+type destCollector struct {
+	dest []any
+}
+
+func (c *destCollector) Scan(dest ...any) error {
+	c.dest = append(c.dest, dest...)
+	return nil
+}
+
+func (c *destCollector) Err() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type membership struct {
+	ID     int64  `db:"id"`
+	UserID int64  `db:"user_id"`
+	Role   string `db:"role"`
+}
+
+func (m *membership) ScanRow(row xdb.Row) error {
+	err := row.Scan(
+		&m.ID,
+		&m.UserID,
+		&m.Role,
+	)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func TestScanJoined(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	for _, stmt := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, email_verified BOOLEAN, name TEXT)",
+		"CREATE TABLE membership (id INTEGER PRIMARY KEY, user_id INTEGER, role TEXT)",
+		"INSERT INTO users VALUES (1, 'a@x', 1, 'A'), (2, 'b@x', 0, 'B')",
+		"INSERT INTO membership VALUES (10, 1, 'admin'), (20, 2, 'user')",
+	} {
+		_, err := p.ExecContext(ctx, stmt)
+		require.NoError(t, err)
+	}
+
+	q := xsql.NoDialect.From("users u").
+		Select("u.id, u.email, u.email_verified, u.name").
+		Select("m.id, m.user_id, m.role").
+		Join("membership m", "m.user_id = u.id").
+		OrderBy("u.id")
+	defer q.Close()
+
+	rows, err := p.QueryContext(ctx, q.String(), q.Args()...)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var users []*user
+	var members []*membership
+	for rows.Next() {
+		u, m := new(user), new(membership)
+		require.NoError(t, xdb.ScanJoined(rows, u, m))
+		users = append(users, u)
+		members = append(members, m)
+	}
+	require.NoError(t, rows.Err())
+	require.Len(t, users, 2)
+	assert.Equal(t, "A", users[0].Name)
+	assert.True(t, users[0].EmailVerified)
+	assert.Equal(t, int64(10), members[0].ID)
+	assert.Equal(t, "admin", members[0].Role)
+	assert.Equal(t, "B", users[1].Name)
+	assert.Equal(t, int64(2), members[1].UserID)
+
+	// single row
+	u, m := new(user), new(membership)
+	err = xdb.ScanJoined(p.QueryRowContext(ctx, q.String(), q.Args()...), u, m)
+	require.NoError(t, err)
+	assert.Equal(t, "a@x", u.Email)
+	assert.Equal(t, "admin", m.Role)
+
+	// column count mismatch
+	rows2, err := p.QueryContext(ctx, "SELECT id, email, email_verified, name FROM users")
+	require.NoError(t, err)
+	defer rows2.Close()
+	require.True(t, rows2.Next())
+	err = xdb.ScanJoined(rows2, new(user), new(membership))
+	assert.EqualError(t, err, "joined row has 4 columns, models expect 7")
+}
@@ -0,0 +1,92 @@
+package xdb
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// JSON de/encodes the value of T to/from json and jsonb columns,
+// the zero value is stored as NULL:
+//
+//	type Org struct {
+//		ID       xdb.ID
+//		Settings xdb.JSON[Settings] `db:"settings,jsonb,null"`
+//	}
+type JSON[T any] struct {
+	V     T
+	Valid bool
+}
+
+// NewJSON returns JSON with the value
+func NewJSON[T any](v T) JSON[T] {
+	return JSON[T]{V: v, Valid: true}
+}
+
+// Get returns the value, or zero value of T if it's NULL
+func (n JSON[T]) Get() T {
+	return n.V
+}
+
+// Ptr returns pointer to the value, or nil if it's NULL
+func (n *JSON[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	return &n.V
+}
+
+// Scan implements the Scanner interface.
+func (n *JSON[T]) Scan(value any) error {
+	var s []byte
+	switch vid := value.(type) {
+	case nil:
+	case []byte:
+		s = vid
+	case string:
+		s = []byte(vid)
+	default:
+		return errors.Errorf("unsupported scan type: %T", value)
+	}
+	return n.decode(s)
+}
+
+func (n *JSON[T]) decode(s []byte) error {
+	var res JSON[T]
+	if len(s) > 0 && !bytes.Equal(s, []byte(`null`)) {
+		if err := json.Unmarshal(s, &res.V); err != nil {
+			return errors.WithStack(err)
+		}
+		res.Valid = true
+	}
+	*n = res
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (n JSON[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	value, err := json.Marshal(n.V)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return string(value), nil
+}
+
+// MarshalJSON implements json.Marshaler interface,
+// NULL value is encoded as null
+func (n JSON[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte(`null`), nil
+	}
+	return json.Marshal(n.V)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (n *JSON[T]) UnmarshalJSON(data []byte) error {
+	return n.decode(data)
+}
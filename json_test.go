@@ -0,0 +1,74 @@
+package xdb_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonSettings struct {
+	Theme  string   `json:"theme"`
+	Limits []uint32 `json:"limits,omitempty"`
+}
+
+func TestJSON(t *testing.T) {
+	var n xdb.JSON[jsonSettings]
+	assert.Nil(t, n.Ptr())
+
+	v, err := n.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	for _, val := range []any{
+		`{"theme":"dark","limits":[1,2]}`,
+		[]byte(`{"theme":"dark","limits":[1,2]}`),
+	} {
+		require.NoError(t, n.Scan(val))
+		assert.True(t, n.Valid)
+		assert.Equal(t, jsonSettings{Theme: "dark", Limits: []uint32{1, 2}}, n.Get())
+		assert.Equal(t, "dark", n.Ptr().Theme)
+	}
+
+	v, err = n.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"theme":"dark","limits":[1,2]}`, v)
+
+	for _, val := range []any{nil, "", []byte("null")} {
+		require.NoError(t, n.Scan(val))
+		assert.False(t, n.Valid)
+		assert.Equal(t, jsonSettings{}, n.V)
+	}
+
+	assert.Error(t, n.Scan(`{"theme":1}`))
+	assert.Error(t, n.Scan(42))
+
+	m, err := xdb.NewJSON(map[string]int{"a": 1}).Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, m)
+
+	_, err = xdb.NewJSON(make(chan int)).Value()
+	assert.Error(t, err)
+}
+
+func TestJSONMarshal(t *testing.T) {
+	type model struct {
+		Settings xdb.JSON[jsonSettings]   `json:"settings"`
+		Tags     xdb.JSON[[]string]       `json:"tags"`
+		Empty    xdb.JSON[map[string]any] `json:"empty"`
+	}
+
+	m := model{
+		Settings: xdb.NewJSON(jsonSettings{Theme: "light"}),
+		Tags:     xdb.NewJSON([]string{"a", "b"}),
+	}
+	js, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, `{"settings":{"theme":"light"},"tags":["a","b"],"empty":null}`, string(js))
+
+	var m2 model
+	require.NoError(t, json.Unmarshal(js, &m2))
+	assert.Equal(t, m, m2)
+}
@@ -0,0 +1,177 @@
+package xdb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+)
+
+/*
+KeysetPager implements keyset pagination over the ordered columns,
+that seeks the rows after the last row of the previous page
+instead of skipping them with OFFSET:
+
+	pager := xdb.NewKeysetPager("created_at", "id")
+
+	q := xsql.From("orders").
+		Select("id, created_at, amount").
+		Where("org_id = ?", orgID)
+	q, err := pager.ApplyCursor(q, cursor, 100)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	err = xdb.ExecuteQueryWithCursor(ctx, db, func(last *order) string {
+		return pager.Cursor(last.CreatedAt, last.ID)
+	}, res, q.String(), q)
+
+produces
+
+	SELECT id, created_at, amount FROM orders
+	WHERE org_id = ? AND (created_at > ? OR (created_at = ? AND id > ?))
+	ORDER BY created_at, id LIMIT ?
+
+The cursor is encoded by EncodeCursor with the values of the columns,
+keyed by the column names without the table alias.
+The last column must be unique, such as the primary key,
+for the rows to be ordered deterministically.
+*/
+type KeysetPager struct {
+	columns []string
+	desc    bool
+}
+
+// NewKeysetPager returns KeysetPager for the ordered columns
+func NewKeysetPager(columns ...string) *KeysetPager {
+	return &KeysetPager{columns: columns}
+}
+
+// WithDescending specifies to order the rows in descending order
+func (p *KeysetPager) WithDescending(desc bool) *KeysetPager {
+	p.desc = desc
+	return p
+}
+
+// Columns returns the ordered columns
+func (p *KeysetPager) Columns() []string {
+	return p.columns
+}
+
+// ApplyCursor decodes the cursor and applies it to the statement, see Apply.
+// Empty cursor starts from the first page.
+func (p *KeysetPager) ApplyCursor(q xsql.Builder, cursor string, limit uint32) (xsql.Builder, error) {
+	var m values.MapAny
+	if cursor != "" {
+		js, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return q, errors.Wrapf(err, "failed to decode cursor")
+		}
+		// the numbers are decoded as json.Number to preserve the precision of IDs
+		dec := json.NewDecoder(bytes.NewReader(js))
+		dec.UseNumber()
+		if err = dec.Decode(&m); err != nil {
+			return q, errors.Wrapf(err, "failed to unmarshal cursor")
+		}
+	}
+	return p.Apply(q, m, limit)
+}
+
+// Apply adds the predicate to seek the rows after the decoded cursor,
+// ORDER BY clause of the columns and LIMIT, if limit is not zero.
+// Empty cursor starts from the first page.
+func (p *KeysetPager) Apply(q xsql.Builder, cursor values.MapAny, limit uint32) (xsql.Builder, error) {
+	if len(p.columns) == 0 {
+		return q, errors.New("keyset columns are not specified")
+	}
+
+	if len(cursor) > 0 {
+		vals := make([]any, len(p.columns))
+		for i, col := range p.columns {
+			v, ok := cursor[cursorKey(col)]
+			if !ok {
+				return q, errors.Errorf("invalid cursor: missing %s", cursorKey(col))
+			}
+			vals[i] = cursorValue(v)
+		}
+		q.WhereExpr(p.seek(vals))
+	}
+
+	order := make([]string, len(p.columns))
+	for i, col := range p.columns {
+		order[i] = col
+		if p.desc {
+			order[i] += " DESC"
+		}
+	}
+	q.OrderBy(order...)
+
+	if limit > 0 {
+		q.Limit(limit)
+	}
+	return q, nil
+}
+
+// seek returns (a > ? OR (a = ? AND b > ?)) predicate,
+// the row value comparison is expanded as it's not supported by SQL Server
+func (p *KeysetPager) seek(vals []any) xsql.Expression {
+	op := " > ?"
+	if p.desc {
+		op = " < ?"
+	}
+
+	conds := make([]any, len(p.columns))
+	for i, col := range p.columns {
+		and := make([]any, 0, i+1)
+		for j := 0; j < i; j++ {
+			and = append(and, xsql.Raw(p.columns[j]+" = ?", vals[j]))
+		}
+		and = append(and, xsql.Raw(col+op, vals[i]))
+		conds[i] = xsql.And(and...)
+	}
+	return xsql.Or(conds...)
+}
+
+// Cursor returns the cursor to the next page,
+// the values of the columns from the last row are in the order of the columns
+func (p *KeysetPager) Cursor(vals ...any) string {
+	m := values.MapAny{}
+	for i, col := range p.columns {
+		if i < len(vals) {
+			m[cursorKey(col)] = vals[i]
+		}
+	}
+	return EncodeCursor(m)
+}
+
+// cursorKey returns the column name without the table alias
+func cursorKey(col string) string {
+	if pos := strings.LastIndexByte(col, '.'); pos >= 0 {
+		return col[pos+1:]
+	}
+	return col
+}
+
+// cursorValue returns int64 or float64 for the numbers decoded from JSON,
+// to bind the identifiers as integers
+func cursorValue(v any) any {
+	switch n := v.(type) {
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		f, _ := n.Float64()
+		return f
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<63 {
+			return int64(n)
+		}
+	}
+	return v
+}
@@ -0,0 +1,103 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scoreRow struct {
+	ID    int64
+	Score int64
+}
+
+func (m *scoreRow) ScanRow(row xdb.Row) error {
+	return row.Scan(&m.ID, &m.Score)
+}
+
+type scoreResult struct {
+	Rows        []*scoreRow
+	HasNextPage bool
+	Cursor      string
+}
+
+func (r *scoreResult) SetResultWithCursor(rows []*scoreRow, hasNextPage bool, cursor func(lastRow *scoreRow) string) {
+	r.Rows = rows
+	r.HasNextPage = hasNextPage
+	r.Cursor = ""
+	if hasNextPage && len(rows) > 0 {
+		r.Cursor = cursor(rows[len(rows)-1])
+	}
+}
+
+func TestKeysetPager(t *testing.T) {
+	pager := xdb.NewKeysetPager("s.score", "s.id")
+	assert.Equal(t, []string{"s.score", "s.id"}, pager.Columns())
+
+	q := xsql.Postgres.From("scores s").
+		Select("s.id, s.score").
+		Where("s.org_id = ?", 1)
+	defer q.Close()
+	_, err := pager.Apply(q, values.MapAny{"score": float64(10), "id": "3"}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT s.id, s.score \nFROM scores s \nWHERE s.org_id = $1 AND (s.score > $2 OR (s.score = $3 AND s.id > $4)) \nORDER BY s.score, s.id \nLIMIT $5", q.String())
+	assert.Equal(t, []any{1, int64(10), int64(10), "3", uint32(2)}, q.Args())
+
+	desc := xdb.NewKeysetPager("id").WithDescending(true)
+	q2 := xsql.Postgres.From("scores").Select("id")
+	defer q2.Close()
+	_, err = desc.ApplyCursor(q2, desc.Cursor(int64(1234567890123456789)), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id \nFROM scores \nWHERE id < $1 \nORDER BY id DESC", q2.String())
+	assert.Equal(t, []any{int64(1234567890123456789)}, q2.Args())
+
+	q3 := xsql.From("scores").Select("id")
+	defer q3.Close()
+	_, err = pager.Apply(q3, values.MapAny{"score": 1}, 0)
+	assert.EqualError(t, err, "invalid cursor: missing id")
+	_, err = pager.ApplyCursor(q3, "!", 0)
+	assert.Error(t, err)
+	_, err = xdb.NewKeysetPager().Apply(q3, nil, 0)
+	assert.EqualError(t, err, "keyset columns are not specified")
+}
+
+func TestKeysetPagerQuery(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE scores (id INTEGER PRIMARY KEY, score INTEGER)")
+	require.NoError(t, err)
+	for i, score := range []int64{30, 10, 20, 10, 30} {
+		_, err = p.ExecContext(ctx, "INSERT INTO scores (id, score) VALUES (?, ?)", i+1, score)
+		require.NoError(t, err)
+	}
+
+	pager := xdb.NewKeysetPager("score", "id")
+	var ids []int64
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		q, err := pager.ApplyCursor(xsql.NoDialect.From("scores").Select("id, score"), cursor, 2)
+		require.NoError(t, err)
+
+		res := &scoreResult{}
+		err = xdb.ExecuteQueryWithCursor(ctx, p, func(last *scoreRow) string {
+			return pager.Cursor(last.Score, last.ID)
+		}, res, q.String(), q)
+		q.Close()
+		require.NoError(t, err)
+
+		for _, r := range res.Rows {
+			ids = append(ids, r.ID)
+		}
+		if !res.HasNextPage {
+			break
+		}
+		cursor = res.Cursor
+	}
+	assert.Equal(t, []int64{2, 4, 3, 1, 5}, ids)
+}
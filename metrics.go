@@ -0,0 +1,81 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// MetricsRegistrar receives the metrics of the provider,
+// see pkg/prommetrics for Prometheus implementation
+type MetricsRegistrar interface {
+	// ObserveQuery is called after every statement executed by the provider,
+	// name is the statement name, or empty for unnamed statements
+	ObserveQuery(provider, pool, name string, duration time.Duration, err error)
+	// SetPoolStats is called periodically with the stats of the connection pool
+	SetPoolStats(provider, pool string, stats sql.DBStats)
+}
+
+// DefaultPoolStatsPeriod is the default period to report the stats of the connection pool
+const DefaultPoolStatsPeriod = 15 * time.Second
+
+// WithMetrics sets the registrar of the query latency,
+// and reports the stats of the connection pool every period,
+// DefaultPoolStatsPeriod is used if period is zero.
+// Use xsql.Builder.SetName or WithStatementName to name the statements.
+func (p *SQLProvider) WithMetrics(registrar MetricsRegistrar, period time.Duration) *SQLProvider {
+	p.stopPoolStats()
+	p.metrics = registrar
+	if period <= 0 {
+		period = DefaultPoolStatsPeriod
+	}
+	p.statsPeriod = period
+	p.startPoolStats()
+	return p
+}
+
+// startPoolStats starts reporting the stats of the connection pool
+func (p *SQLProvider) startPoolStats() {
+	if p.metrics == nil || p.statsPeriod <= 0 || p.conn == nil || p.tx != nil {
+		return
+	}
+	stop := make(chan struct{})
+	p.statsStop = stop
+	registrar, conn, period := p.metrics, p.conn, p.statsPeriod
+
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			registrar.SetPoolStats(p.name, p.pool, conn.Stats())
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopPoolStats stops reporting the stats of the connection pool
+func (p *SQLProvider) stopPoolStats() {
+	if p.statsStop != nil {
+		close(p.statsStop)
+		p.statsStop = nil
+	}
+}
+
+// observeQuery reports the statement to the metrics registrar,
+// and reports the statement that took longer than the slow query threshold
+func (p *SQLProvider) observeQuery(ctx context.Context, query string, args []any, started time.Time, err error) {
+	if p.metrics == nil && p.slowQuery <= 0 {
+		return
+	}
+	d := time.Since(started)
+	if p.metrics != nil {
+		p.metrics.ObserveQuery(p.name, p.pool, StatementName(ctx), d, err)
+	}
+	if p.slowQuery > 0 && d >= p.slowQuery {
+		p.slowQueryDone(ctx, query, args, d)
+	}
+}
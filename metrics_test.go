@@ -0,0 +1,59 @@
+package xdb_test
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type metricsRecorder struct {
+	lock    sync.Mutex
+	queries []string
+	errors  int
+	stats   chan sql.DBStats
+}
+
+func (m *metricsRecorder) ObserveQuery(provider, pool, name string, duration time.Duration, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.queries = append(m.queries, provider+":"+name)
+	if err != nil {
+		m.errors++
+	}
+}
+
+func (m *metricsRecorder) SetPoolStats(provider, pool string, stats sql.DBStats) {
+	select {
+	case m.stats <- stats:
+	default:
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	ctx := context.Background()
+	m := &metricsRecorder{stats: make(chan sql.DBStats, 1)}
+	p := openSQLite(t).WithMetrics(m, 10*time.Millisecond)
+
+	select {
+	case stats := <-m.stats:
+		assert.Equal(t, 1, stats.MaxOpenConnections)
+	case <-time.After(time.Second):
+		t.Fatal("pool stats are not reported")
+	}
+
+	_, err := xsql.NoDialect.New("CREATE TABLE t (id INTEGER)").SetName("t.create").ExecAndClose(ctx, p)
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, "DELETE FROM missing")
+	require.Error(t, err)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	assert.Equal(t, []string{"sqlite3:t.create", "sqlite3:"}, m.queries)
+	assert.Equal(t, 1, m.errors)
+}
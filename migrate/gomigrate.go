@@ -0,0 +1,255 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/pkg/errors"
+)
+
+// Tx provides interface for the Go migrations,
+// the migration is executed in the transaction
+type Tx interface {
+	xsql.Executor
+}
+
+// GoMigrationFunc is the Go code of the migration,
+// the changes are committed if it returns nil
+type GoMigrationFunc func(ctx context.Context, tx Tx) error
+
+// GoMigration describes the migration implemented in Go,
+// for data backfills that can't be expressed in SQL
+type GoMigration struct {
+	// Version of the migration, must not be used by the SQL files
+	Version uint
+	// Name of the migration
+	Name string
+	// Up applies the migration
+	Up GoMigrationFunc
+	// Down rolls back the migration, optional,
+	// the migration without Down can't be rolled back
+	Down GoMigrationFunc
+}
+
+var (
+	goMigrationsLock sync.RWMutex
+	goMigrations     = map[string][]*GoMigration{}
+)
+
+// Register registers the Go migrations for the migrations directory,
+// the directory must be the same as the migrations source of the database.
+// The Go migrations are applied with the SQL files in the order of versions,
+// and tracked in the same schema_migrations table.
+// Register is expected to be called from init function,
+// and panics if the version is not specified, duplicated, or Up is nil.
+func Register(migrationsDir string, list ...*GoMigration) {
+	dir := filepath.Clean(migrationsDir)
+
+	goMigrationsLock.Lock()
+	defer goMigrationsLock.Unlock()
+	for _, gm := range list {
+		if gm.Version == 0 || gm.Up == nil {
+			panic("migrate: version and Up must be specified for Go migration " + gm.Name)
+		}
+		for _, r := range goMigrations[dir] {
+			if r.Version == gm.Version {
+				panic("migrate: duplicate Go migration " + gm.Name)
+			}
+		}
+		goMigrations[dir] = append(goMigrations[dir], gm)
+	}
+}
+
+// registered returns the Go migrations registered for the migrations directory
+func registered(migrationsDir string) []*GoMigration {
+	goMigrationsLock.RLock()
+	defer goMigrationsLock.RUnlock()
+	return goMigrations[filepath.Clean(migrationsDir)]
+}
+
+// goSource is the migrations source,
+// that interleaves the Go migrations with the SQL files of the source by version.
+// The source returns empty migration for Go versions,
+// that are executed by runGoStep.
+type goSource struct {
+	source.Driver
+
+	versions   []uint
+	migrations map[uint]*GoMigration
+
+	db  *sql.DB
+	drv database.Driver
+}
+
+// withGoMigrations returns the source with the Go migrations,
+// or the original source if there are no Go migrations
+func withGoMigrations(src source.Driver, db *sql.DB, drv database.Driver, list []*GoMigration) (source.Driver, error) {
+	if len(list) == 0 {
+		return src, nil
+	}
+
+	s := &goSource{
+		Driver:     src,
+		migrations: make(map[uint]*GoMigration, len(list)),
+		db:         db,
+		drv:        drv,
+	}
+
+	v, err := src.First()
+	for err == nil {
+		s.versions = append(s.versions, v)
+		v, err = src.Next(v)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.WithStack(err)
+	}
+
+	for _, gm := range list {
+		if s.index(gm.Version) >= 0 {
+			return nil, errors.Errorf("version %d of Go migration %s is used by SQL migration", gm.Version, gm.Name)
+		}
+		s.migrations[gm.Version] = gm
+	}
+	for v := range s.migrations {
+		s.versions = append(s.versions, v)
+	}
+	sort.Slice(s.versions, func(i, j int) bool { return s.versions[i] < s.versions[j] })
+	return s, nil
+}
+
+// index returns the index of the version, or -1 if not found
+func (s *goSource) index(version uint) int {
+	idx := sort.Search(len(s.versions), func(i int) bool { return s.versions[i] >= version })
+	if idx < len(s.versions) && s.versions[idx] == version {
+		return idx
+	}
+	return -1
+}
+
+// First implements source.Driver
+func (s *goSource) First() (uint, error) {
+	if len(s.versions) == 0 {
+		return 0, &fs.PathError{Op: "first", Path: "go", Err: fs.ErrNotExist}
+	}
+	return s.versions[0], nil
+}
+
+// Prev implements source.Driver
+func (s *goSource) Prev(version uint) (uint, error) {
+	idx := s.index(version)
+	if idx <= 0 {
+		return 0, &fs.PathError{Op: "prev for version " + strconv.FormatUint(uint64(version), 10), Path: "go", Err: fs.ErrNotExist}
+	}
+	return s.versions[idx-1], nil
+}
+
+// Next implements source.Driver
+func (s *goSource) Next(version uint) (uint, error) {
+	idx := s.index(version)
+	if idx < 0 || idx == len(s.versions)-1 {
+		return 0, &fs.PathError{Op: "next for version " + strconv.FormatUint(uint64(version), 10), Path: "go", Err: fs.ErrNotExist}
+	}
+	return s.versions[idx+1], nil
+}
+
+// ReadUp implements source.Driver
+func (s *goSource) ReadUp(version uint) (io.ReadCloser, string, error) {
+	if gm := s.migrations[version]; gm != nil {
+		return io.NopCloser(strings.NewReader("")), gm.Name, nil
+	}
+	return s.Driver.ReadUp(version)
+}
+
+// ReadDown implements source.Driver
+func (s *goSource) ReadDown(version uint) (io.ReadCloser, string, error) {
+	if gm := s.migrations[version]; gm != nil {
+		return io.NopCloser(strings.NewReader("")), gm.Name, nil
+	}
+	return s.Driver.ReadDown(version)
+}
+
+// goMigration returns the Go migration of the step, or nil
+func goMigration(src source.Driver, version uint) (*goSource, *GoMigration) {
+	s, ok := src.(*goSource)
+	if !ok {
+		return nil, nil
+	}
+	gm := s.migrations[version]
+	if gm == nil {
+		return nil, nil
+	}
+	return s, gm
+}
+
+// runGoStep runs the Go migration in the transaction,
+// and updates the version in schema_migrations table.
+// The version is marked dirty while the migration runs,
+// and restored if the migration fails, as the transaction is rolled back.
+// The running migration is not interrupted by the context cancellation.
+func (s *goSource) runGoStep(ctx context.Context, gm *GoMigration, direction string, timeout time.Duration) error {
+	if direction == "down" && gm.Down == nil {
+		return errors.Errorf("Go migration %d has no Down", gm.Version)
+	}
+
+	if err := s.drv.Lock(); err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		_ = s.drv.Unlock()
+	}()
+
+	before := database.NilVersion
+	if prev, err := s.Prev(gm.Version); err == nil {
+		before = int(prev)
+	}
+	current, target, fn := before, int(gm.Version), gm.Up
+	if direction == "down" {
+		current, target, fn = int(gm.Version), before, gm.Down
+	}
+
+	if err := s.drv.SetVersion(target, true); err != nil {
+		return errors.WithStack(err)
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if err := s.exec(ctx, fn); err != nil {
+		if rerr := s.drv.SetVersion(current, false); rerr != nil {
+			return errors.WithMessagef(err, "failed to restore version %d: %s", current, rerr.Error())
+		}
+		return err
+	}
+	return errors.WithStack(s.drv.SetVersion(target, false))
+}
+
+// exec executes the migration function in the transaction
+func (s *goSource) exec(ctx context.Context, fn GoMigrationFunc) error {
+	if fn == nil {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err = fn(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return errors.WithStack(tx.Commit())
+}
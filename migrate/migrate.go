@@ -1,16 +1,20 @@
 package migrate
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/effective-security/xlog"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/database/sqlserver"
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/pkg/errors"
 
 	// register Postgres driver
@@ -21,85 +25,211 @@ import (
 
 var logger = xlog.NewPackageLogger("github.com/effective-security/xdb", "migrate")
 
+// Progress describes a completed migration step
+type Progress struct {
+	// Version of the migration
+	Version uint
+	// Name of the migration, from the file name
+	Name string
+	// Direction is up or down
+	Direction string
+	// Duration of the migration
+	Duration time.Duration
+	// Err is the migration error, if failed
+	Err error
+}
+
+// ProgressFunc is called after each migration step
+type ProgressFunc func(p Progress)
+
+// Options provides migration options
+type Options struct {
+	// ForceVersion sets the version without running migrations, if specified
+	ForceVersion int
+	// MigrateVersion specifies the target version, or the latest if not specified
+	MigrateVersion int
+	// DownSteps specifies the number of migrations to roll back,
+	// MigrateVersion is ignored if specified
+	DownSteps int
+	// DryRun reports the migrations through OnProgress without running them
+	DryRun bool
+	// StepTimeout limits duration of a single migration.
+	// On Postgres it's enforced by statement_timeout, and for Go migrations by the context.
+	// On other providers the SQL migration can't be interrupted,
+	// and the warning is logged for the migration that exceeded the timeout.
+	StepTimeout time.Duration
+	// OnProgress is called after each migration step
+	OnProgress ProgressFunc
+}
+
 // Migrate performs db migration
 func Migrate(provider, dbName, migrationsDir string, forceVersion, migrateVersion int, db *sql.DB) error {
+	return MigrateContext(context.Background(), provider, dbName, migrationsDir, db, &Options{
+		ForceVersion:   forceVersion,
+		MigrateVersion: migrateVersion,
+	})
+}
+
+// MigrateContext performs db migration one step at a time,
+// the context is checked before each step to stop migrations during deploys.
+// The running migration is not interrupted by the context cancellation,
+// so the database is not left in dirty state.
+func MigrateContext(ctx context.Context, provider, dbName, migrationsDir string, db *sql.DB, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
 	logger.KV(xlog.INFO,
 		"provider", provider,
 		"db", dbName,
 		"status", "load",
 		"directory", migrationsDir,
-		"forceVersion", forceVersion,
-		"migrateVersion", migrateVersion,
+		"forceVersion", opts.ForceVersion,
+		"migrateVersion", opts.MigrateVersion,
 	)
 	if len(migrationsDir) == 0 {
 		return nil
 	}
 
+	m, src, err := open(provider, migrationsDir, db, opts)
+	if err != nil {
+		return err
+	}
+	return run(ctx, m, src, provider, dbName, opts)
+}
+
+// open returns the migration instance for the database
+func open(provider, migrationsDir string, db *sql.DB, opts *Options) (*migrate.Migrate, source.Driver, error) {
 	if _, err := os.Stat(migrationsDir); err != nil {
-		return errors.WithMessagef(err, "directory %q inaccessible", migrationsDir)
+		return nil, nil, errors.WithMessagef(err, "directory %q inaccessible", migrationsDir)
 	}
 
 	var driver database.Driver
 	var err error
 	switch provider {
 	case "postgres", "pgsql":
-		driver, err = postgres.WithInstance(db, &postgres.Config{})
+		driver, err = postgres.WithInstance(db, &postgres.Config{
+			StatementTimeout: opts.StepTimeout,
+		})
 		if err != nil {
-			return errors.WithStack(err)
+			return nil, nil, errors.WithStack(err)
 		}
 	case "sqlserver":
 		driver, err = sqlserver.WithInstance(db, &sqlserver.Config{})
 		if err != nil {
-			return errors.WithStack(err)
+			return nil, nil, errors.WithStack(err)
 		}
 	default:
-		return errors.Errorf("unsupported provider: %s", provider)
+		return nil, nil, errors.Errorf("unsupported provider: %s", provider)
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", migrationsDir),
-		provider,
-		driver)
+	src, err := source.Open(fmt.Sprintf("file://%s", migrationsDir))
 	if err != nil {
-		return errors.WithStack(err)
+		return nil, nil, errors.WithStack(err)
+	}
+	src, err = withGoMigrations(src, db, driver, registered(migrationsDir))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := migrate.NewWithInstance("file", src, provider, driver)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
 	}
+	return m, src, nil
+}
+
+func run(ctx context.Context, m *migrate.Migrate, src source.Driver, provider, dbName string, opts *Options) error {
 	m.Log = migrateLog{}
 
 	version, _, err := m.Version()
 	if err != nil && err != migrate.ErrNilVersion {
 		return errors.WithStack(err)
 	}
-	if err == migrate.ErrNilVersion {
+	hasVersion := err == nil
+	if !hasVersion {
 		logger.KV(xlog.INFO, "db", dbName, "reason", "initial_state", "version", "nil")
 	} else {
 		logger.KV(xlog.INFO, "db", dbName, "reason", "initial_state", "version", version)
 	}
 
-	if forceVersion > 0 {
-		logger.KV(xlog.NOTICE, "db", dbName, "forceVersion", forceVersion)
-		err = m.Force(forceVersion)
-		if err != nil {
-			return errors.WithStack(err)
+	if opts.ForceVersion > 0 {
+		logger.KV(xlog.NOTICE, "db", dbName, "forceVersion", opts.ForceVersion, "dryRun", opts.DryRun)
+		if !opts.DryRun {
+			err = m.Force(opts.ForceVersion)
+			if err != nil {
+				return errors.WithStack(err)
+			}
 		}
+		version, hasVersion = uint(opts.ForceVersion), true
 	}
 
-	if migrateVersion > 0 {
-		logger.KV(xlog.NOTICE, "db", dbName, "migrateVersion", migrateVersion)
-		err = m.Migrate(uint(migrateVersion))
+	var steps []step
+	if opts.DownSteps > 0 {
+		logger.KV(xlog.NOTICE, "db", dbName, "downSteps", opts.DownSteps)
+		steps, err = planDown(src, version, hasVersion, opts.DownSteps)
 	} else {
-		err = m.Up()
+		if opts.MigrateVersion > 0 {
+			logger.KV(xlog.NOTICE, "db", dbName, "migrateVersion", opts.MigrateVersion)
+		}
+		steps, err = plan(src, version, hasVersion, opts.MigrateVersion)
 	}
-
 	if err != nil {
-		if strings.Contains(err.Error(), "no change") {
-			logger.KV(xlog.INFO, "db", dbName, "status", "no_change", "version", version)
-			return nil
+		return err
+	}
+	if len(steps) == 0 {
+		logger.KV(xlog.INFO, "db", dbName, "status", "no_change", "version", version)
+		return nil
+	}
+
+	for _, st := range steps {
+		if err = ctx.Err(); err != nil {
+			return errors.WithMessagef(err, "migration stopped before %d/%s", st.Version, st.Name)
 		}
-		return errors.WithStack(err)
+
+		if opts.DryRun {
+			if opts.OnProgress != nil {
+				opts.OnProgress(Progress{Version: st.Version, Name: st.Name, Direction: st.Direction})
+			}
+			continue
+		}
+
+		started := time.Now()
+		if gs, gm := goMigration(src, st.Version); gm != nil {
+			err = gs.runGoStep(ctx, gm, st.Direction, opts.StepTimeout)
+		} else {
+			err = runStep(ctx, m, st.Direction, opts.StepTimeout)
+		}
+		p := Progress{
+			Version:   st.Version,
+			Name:      st.Name,
+			Direction: st.Direction,
+			Duration:  time.Since(started),
+			Err:       err,
+		}
+
+		logger.KV(xlog.NOTICE,
+			"db", dbName,
+			"status", "migrated",
+			"version", p.Version,
+			"name", p.Name,
+			"direction", p.Direction,
+			"duration", p.Duration.String(),
+			"err", err)
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(p)
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "failed to migrate %d/%s", st.Version, st.Name)
+		}
+	}
+
+	if opts.DryRun {
+		return nil
 	}
 
 	version, _, err = m.Version()
-	if err != nil {
+	if err != nil && err != migrate.ErrNilVersion {
 		return errors.WithStack(err)
 	}
 
@@ -112,6 +242,126 @@ func Migrate(provider, dbName, migrationsDir string, forceVersion, migrateVersio
 	return nil
 }
 
+// runStep runs a single migration, and waits for its completion
+// if the context is cancelled or the timeout is exceeded,
+// as the applied migration must be reported by its result
+func runStep(ctx context.Context, m *migrate.Migrate, direction string, timeout time.Duration) error {
+	n := 1
+	if direction == "down" {
+		n = -1
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Steps(n)
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+
+	select {
+	case err := <-done:
+		return errors.WithStack(err)
+	case <-ctx.Done():
+		// the context is checked before the next step
+		return errors.WithStack(<-done)
+	case <-expired:
+		logger.KV(xlog.WARNING,
+			"reason", "step_timeout",
+			"direction", direction,
+			"timeout", timeout)
+		return errors.WithStack(<-done)
+	}
+}
+
+type step struct {
+	Version   uint
+	Name      string
+	Direction string
+}
+
+// plan returns the list of migrations to reach the target version,
+// or the latest version if target is not specified
+func plan(src source.Driver, current uint, hasCurrent bool, target int) ([]step, error) {
+	var steps []step
+
+	if hasCurrent && target > 0 && uint(target) < current {
+		// migrate down to the target
+		v := current
+		for v != uint(target) {
+			steps = append(steps, step{Version: v, Name: migrationName(src, v, "down"), Direction: "down"})
+			prev, err := src.Prev(v)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "version %d not found", target)
+			}
+			v = prev
+		}
+		return steps, nil
+	}
+
+	var v uint
+	var err error
+	if hasCurrent {
+		v, err = src.Next(current)
+	} else {
+		v, err = src.First()
+	}
+	for err == nil && (target <= 0 || v <= uint(target)) {
+		steps = append(steps, step{Version: v, Name: migrationName(src, v, "up"), Direction: "up"})
+		v, err = src.Next(v)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.WithStack(err)
+	}
+
+	if target > 0 && len(steps) > 0 && steps[len(steps)-1].Version != uint(target) {
+		return nil, errors.Errorf("version %d not found", target)
+	}
+	return steps, nil
+}
+
+// planDown returns the list of migrations to roll back n steps from the current version
+func planDown(src source.Driver, current uint, hasCurrent bool, n int) ([]step, error) {
+	var steps []step
+	if !hasCurrent {
+		return steps, nil
+	}
+
+	v := current
+	for len(steps) < n {
+		steps = append(steps, step{Version: v, Name: migrationName(src, v, "down"), Direction: "down"})
+		prev, err := src.Prev(v)
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		v = prev
+	}
+	return steps, nil
+}
+
+func migrationName(src source.Driver, version uint, direction string) string {
+	var r io.ReadCloser
+	var name string
+	var err error
+	if direction == "down" {
+		r, name, err = src.ReadDown(version)
+	} else {
+		r, name, err = src.ReadUp(version)
+	}
+	if err != nil {
+		return strconv.FormatUint(uint64(version), 10)
+	}
+	_ = r.Close()
+	return name
+}
+
 type migrateLog struct{}
 
 func (migrateLog) Verbose() bool { return true }
@@ -0,0 +1,347 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMigrate(t *testing.T) (*migrate.Migrate, source.Driver) {
+	dir := t.TempDir()
+	for i, name := range []string{"users", "orgs", "members"} {
+		prefix := filepath.Join(dir, fmt.Sprintf("%06d_%s", i+1, name))
+		require.NoError(t, os.WriteFile(prefix+".up.sql", []byte("CREATE TABLE "+name+" (id INTEGER);"), 0644))
+		require.NoError(t, os.WriteFile(prefix+".down.sql", []byte("DROP TABLE "+name+";"), 0644))
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	require.NoError(t, err)
+
+	src, err := source.Open("file://" + dir)
+	require.NoError(t, err)
+
+	m, err := migrate.NewWithInstance("file", src, "sqlite3", driver)
+	require.NoError(t, err)
+	return m, src
+}
+
+func TestPlan(t *testing.T) {
+	_, src := newTestMigrate(t)
+
+	steps, err := plan(src, 0, false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []step{
+		{Version: 1, Name: "users", Direction: "up"},
+		{Version: 2, Name: "orgs", Direction: "up"},
+		{Version: 3, Name: "members", Direction: "up"},
+	}, steps)
+
+	steps, err = plan(src, 1, true, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []step{{Version: 2, Name: "orgs", Direction: "up"}}, steps)
+
+	steps, err = plan(src, 3, true, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []step{
+		{Version: 3, Name: "members", Direction: "down"},
+		{Version: 2, Name: "orgs", Direction: "down"},
+	}, steps)
+
+	steps, err = plan(src, 3, true, 0)
+	require.NoError(t, err)
+	assert.Empty(t, steps)
+
+	_, err = plan(src, 0, false, 5)
+	assert.EqualError(t, err, "version 5 not found")
+}
+
+func TestRun(t *testing.T) {
+	m, src := newTestMigrate(t)
+
+	var progress []Progress
+	opts := &Options{
+		MigrateVersion: 2,
+		OnProgress: func(p Progress) {
+			progress = append(progress, p)
+		},
+	}
+	err := run(context.Background(), m, src, "sqlite3", "test", opts)
+	require.NoError(t, err)
+	require.Len(t, progress, 2)
+	assert.Equal(t, "users", progress[0].Name)
+	assert.Equal(t, "orgs", progress[1].Name)
+	assert.Equal(t, "up", progress[1].Direction)
+	assert.NoError(t, progress[1].Err)
+
+	version, _, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), version)
+
+	// no change
+	progress = nil
+	err = run(context.Background(), m, src, "sqlite3", "test", opts)
+	require.NoError(t, err)
+	assert.Empty(t, progress)
+
+	// cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts.MigrateVersion = 0
+	err = run(ctx, m, src, "sqlite3", "test", opts)
+	assert.EqualError(t, err, "migration stopped before 3/members: context canceled")
+
+	// down
+	opts.MigrateVersion = 1
+	err = run(context.Background(), m, src, "sqlite3", "test", opts)
+	require.NoError(t, err)
+	require.Len(t, progress, 1)
+	assert.Equal(t, "down", progress[0].Direction)
+	assert.Equal(t, uint(2), progress[0].Version)
+
+	version, _, err = m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), version)
+}
+
+func TestStatus(t *testing.T) {
+	m, src := newTestMigrate(t)
+
+	migrations := func(applied int) []*Migration {
+		var list []*Migration
+		for i, name := range []string{"users", "orgs", "members"} {
+			list = append(list, &Migration{Version: uint(i + 1), Name: name, Applied: i < applied})
+		}
+		return list
+	}
+
+	st, err := status(m, src, "test")
+	require.NoError(t, err)
+	assert.Equal(t, &Status{Database: "test", Version: 0, Latest: 3, Pending: 3, Migrations: migrations(0)}, st)
+
+	err = run(context.Background(), m, src, "sqlite3", "test", &Options{MigrateVersion: 2})
+	require.NoError(t, err)
+	st, err = status(m, src, "test")
+	require.NoError(t, err)
+	assert.Equal(t, &Status{Database: "test", Version: 2, Latest: 3, Pending: 1, Migrations: migrations(2)}, st)
+
+	err = run(context.Background(), m, src, "sqlite3", "test", &Options{})
+	require.NoError(t, err)
+	st, err = status(m, src, "test")
+	require.NoError(t, err)
+	assert.Equal(t, &Status{Database: "test", Version: 3, Latest: 3, Migrations: migrations(3)}, st)
+
+	_, err = GetStatus("sqlite3", "test", t.TempDir(), nil)
+	assert.EqualError(t, err, "unsupported provider: sqlite3")
+	err = Force("sqlite3", t.TempDir(), nil, 1)
+	assert.EqualError(t, err, "unsupported provider: sqlite3")
+
+	unlock, err := Lock(context.Background(), "sqlite3", nil, "xdb.migrate")
+	require.NoError(t, err)
+	unlock()
+}
+
+func TestRunDown(t *testing.T) {
+	m, src := newTestMigrate(t)
+
+	var progress []Progress
+	opts := &Options{
+		DryRun: true,
+		OnProgress: func(p Progress) {
+			progress = append(progress, p)
+		},
+	}
+	err := run(context.Background(), m, src, "sqlite3", "test", opts)
+	require.NoError(t, err)
+	require.Len(t, progress, 3)
+	assert.Equal(t, Progress{Version: 3, Name: "members", Direction: "up"}, progress[2])
+
+	_, _, err = m.Version()
+	assert.Equal(t, migrate.ErrNilVersion, err)
+
+	// nothing to roll back
+	progress = nil
+	opts.DownSteps = 1
+	err = run(context.Background(), m, src, "sqlite3", "test", opts)
+	require.NoError(t, err)
+	assert.Empty(t, progress)
+
+	err = run(context.Background(), m, src, "sqlite3", "test", &Options{})
+	require.NoError(t, err)
+
+	// dry run down
+	opts.DownSteps = 2
+	err = run(context.Background(), m, src, "sqlite3", "test", opts)
+	require.NoError(t, err)
+	assert.Equal(t, []Progress{
+		{Version: 3, Name: "members", Direction: "down"},
+		{Version: 2, Name: "orgs", Direction: "down"},
+	}, progress)
+
+	version, _, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), version)
+
+	// roll back all
+	opts.DryRun = false
+	opts.DownSteps = 5
+	progress = nil
+	err = run(context.Background(), m, src, "sqlite3", "test", opts)
+	require.NoError(t, err)
+	require.Len(t, progress, 3)
+	assert.Equal(t, uint(1), progress[2].Version)
+
+	_, _, err = m.Version()
+	assert.Equal(t, migrate.ErrNilVersion, err)
+}
+
+func TestRunStepTimeout(t *testing.T) {
+	m, _ := newTestMigrate(t)
+
+	// the applied migration is not reported as failed
+	err := runStep(context.Background(), m, "up", time.Nanosecond)
+	require.NoError(t, err)
+	version, dirty, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), version)
+	assert.False(t, dirty)
+}
+
+func TestGoMigrations(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []struct {
+		version uint
+		name    string
+		up      string
+		down    string
+	}{
+		{1, "users", "CREATE TABLE users (id INTEGER, name TEXT);", "DROP TABLE users;"},
+		{3, "orgs", "CREATE TABLE orgs (id INTEGER);", "DROP TABLE orgs;"},
+	} {
+		prefix := filepath.Join(dir, fmt.Sprintf("%06d_%s", f.version, f.name))
+		require.NoError(t, os.WriteFile(prefix+".up.sql", []byte(f.up), 0644))
+		require.NoError(t, os.WriteFile(prefix+".down.sql", []byte(f.down), 0644))
+	}
+
+	Register(dir+"/", &GoMigration{
+		Version: 2,
+		Name:    "backfill_users",
+		Up: func(ctx context.Context, tx Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO users (id, name) VALUES (1, 'admin')")
+			return err
+		},
+		Down: func(ctx context.Context, tx Tx) error {
+			_, err := tx.ExecContext(ctx, "DELETE FROM users")
+			return err
+		},
+	})
+	assert.Len(t, registered(dir), 1)
+	assert.Panics(t, func() {
+		Register(dir, &GoMigration{Version: 2, Name: "duplicate", Up: func(context.Context, Tx) error { return nil }})
+	})
+	assert.Panics(t, func() {
+		Register(dir, &GoMigration{Version: 5, Name: "no_up"})
+	})
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	require.NoError(t, err)
+	fileSrc, err := source.Open("file://" + dir)
+	require.NoError(t, err)
+
+	_, err = withGoMigrations(fileSrc, db, driver, []*GoMigration{{Version: 3, Name: "conflict"}})
+	assert.EqualError(t, err, "version 3 of Go migration conflict is used by SQL migration")
+
+	src, err := withGoMigrations(fileSrc, db, driver, registered(dir))
+	require.NoError(t, err)
+	m, err := migrate.NewWithInstance("file", src, "sqlite3", driver)
+	require.NoError(t, err)
+
+	steps, err := plan(src, 0, false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []step{
+		{Version: 1, Name: "users", Direction: "up"},
+		{Version: 2, Name: "backfill_users", Direction: "up"},
+		{Version: 3, Name: "orgs", Direction: "up"},
+	}, steps)
+
+	ctx := context.Background()
+	require.NoError(t, run(ctx, m, src, "sqlite3", "test", &Options{}))
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n))
+	assert.Equal(t, 1, n)
+	version, dirty, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), version)
+	assert.False(t, dirty)
+
+	// roll back the SQL and Go migrations
+	require.NoError(t, run(ctx, m, src, "sqlite3", "test", &Options{DownSteps: 2}))
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n))
+	assert.Equal(t, 0, n)
+	version, _, err = m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), version)
+
+	// the failed Go migration is rolled back, and the version is restored
+	gs, gm := goMigration(src, 2)
+	require.NotNil(t, gm)
+	failed := &GoMigration{
+		Version: 2,
+		Name:    "backfill_users",
+		Up: func(ctx context.Context, tx Tx) error {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO users (id, name) VALUES (2, 'guest')"); err != nil {
+				return err
+			}
+			return errors.New("backfill failed")
+		},
+	}
+	gs.migrations[2] = failed
+	err = run(ctx, m, src, "sqlite3", "test", &Options{})
+	assert.EqualError(t, err, "failed to migrate 2/backfill_users: backfill failed")
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n))
+	assert.Equal(t, 0, n)
+	version, dirty, err = m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), version)
+	assert.False(t, dirty)
+
+	st, err := status(m, src, "test")
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), st.Latest)
+	assert.Equal(t, 2, st.Pending)
+
+	// the Go migration without Down can't be rolled back
+	gs.migrations[2] = &GoMigration{
+		Version: 2,
+		Name:    "backfill_users",
+		Up:      gm.Up,
+	}
+	require.NoError(t, run(ctx, m, src, "sqlite3", "test", &Options{}))
+	err = run(ctx, m, src, "sqlite3", "test", &Options{DownSteps: 2})
+	assert.EqualError(t, err, "failed to migrate 2/backfill_users: Go migration 2 has no Down")
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n))
+	assert.Equal(t, 1, n)
+	version, dirty, err = m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), version)
+	assert.False(t, dirty)
+}
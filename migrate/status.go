@@ -0,0 +1,135 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/pkg/errors"
+)
+
+// Status describes the migration state of the database
+type Status struct {
+	// Name is the logical name of the database in the service
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Database is the name of the database
+	Database string `json:"database" yaml:"database"`
+	// Version is the current version, 0 if no migrations applied
+	Version uint `json:"version" yaml:"version"`
+	// Dirty is set if the last migration failed
+	Dirty bool `json:"dirty,omitempty" yaml:"dirty,omitempty"`
+	// Latest is the version of the last available migration
+	Latest uint `json:"latest" yaml:"latest"`
+	// Pending is the number of migrations to apply
+	Pending int `json:"pending" yaml:"pending"`
+	// Error is set if the status can't be retrieved
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Migrations is the list of available migrations
+	Migrations []*Migration `json:"migrations,omitempty" yaml:"migrations,omitempty"`
+}
+
+// Migration describes the migration from the source
+type Migration struct {
+	// Version of the migration
+	Version uint `json:"version" yaml:"version"`
+	// Name of the migration, from the file name
+	Name string `json:"name" yaml:"name"`
+	// Applied is set if the migration is applied to the database
+	Applied bool `json:"applied" yaml:"applied"`
+}
+
+// GetStatus returns the migration state of the database
+func GetStatus(provider, dbName, migrationsDir string, db *sql.DB) (*Status, error) {
+	m, src, err := open(provider, migrationsDir, db, &Options{})
+	if err != nil {
+		return nil, err
+	}
+	return status(m, src, dbName)
+}
+
+func status(m *migrate.Migrate, src source.Driver, dbName string) (*Status, error) {
+	st := &Status{Database: dbName}
+
+	version, dirty, err := m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return nil, errors.WithStack(err)
+	}
+	hasVersion := err == nil
+	if hasVersion {
+		st.Version, st.Dirty = version, dirty
+	}
+
+	steps, err := plan(src, version, hasVersion, 0)
+	if err != nil {
+		return nil, err
+	}
+	st.Pending = len(steps)
+	if st.Pending > 0 {
+		st.Latest = steps[st.Pending-1].Version
+	} else {
+		st.Latest = st.Version
+	}
+
+	v, err := src.First()
+	for err == nil {
+		st.Migrations = append(st.Migrations, &Migration{
+			Version: v,
+			Name:    migrationName(src, v, "up"),
+			Applied: hasVersion && v <= version,
+		})
+		v, err = src.Next(v)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.WithStack(err)
+	}
+	return st, nil
+}
+
+// Force sets the version of the database without running migrations,
+// and clears the dirty state after the failed migration is fixed manually
+func Force(provider, migrationsDir string, db *sql.DB, version int) error {
+	m, _, err := open(provider, migrationsDir, db, &Options{})
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(m.Force(version))
+}
+
+// Lock acquires the session lock on the database,
+// that is shared by the migrations of several databases of the service,
+// so only one instance migrates the databases at a time.
+// The returned function releases the lock.
+// Providers without advisory locks are not locked.
+func Lock(ctx context.Context, provider string, db *sql.DB, key string) (func(), error) {
+	var lock, unlock string
+	switch provider {
+	case "postgres", "pgsql":
+		lock = "SELECT pg_advisory_lock(hashtext($1))"
+		unlock = "SELECT pg_advisory_unlock(hashtext($1))"
+	case "sqlserver":
+		lock = "EXEC sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session'"
+		unlock = "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'"
+	default:
+		return func() {}, nil
+	}
+
+	// the session lock is held by the connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, err = conn.ExecContext(ctx, lock, key); err != nil {
+		_ = conn.Close()
+		return nil, errors.WithMessagef(err, "failed to lock %s", key)
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), unlock, key); err != nil {
+			// discard the connection to release the lock with the session
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		_ = conn.Close()
+	}, nil
+}
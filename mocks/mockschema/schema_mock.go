@@ -93,3 +93,40 @@ func (mr *MockProviderMockRecorder) Name() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockProvider)(nil).Name))
 }
+
+// MockTableStreamer is a mock of TableStreamer interface.
+type MockTableStreamer struct {
+	ctrl     *gomock.Controller
+	recorder *MockTableStreamerMockRecorder
+}
+
+// MockTableStreamerMockRecorder is the mock recorder for MockTableStreamer.
+type MockTableStreamerMockRecorder struct {
+	mock *MockTableStreamer
+}
+
+// NewMockTableStreamer creates a new mock instance.
+func NewMockTableStreamer(ctrl *gomock.Controller) *MockTableStreamer {
+	mock := &MockTableStreamer{ctrl: ctrl}
+	mock.recorder = &MockTableStreamerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTableStreamer) EXPECT() *MockTableStreamerMockRecorder {
+	return m.recorder
+}
+
+// StreamTables mocks base method.
+func (m *MockTableStreamer) StreamTables(ctx context.Context, schemaName string, tableNames []string, fn func(*schema.Table) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamTables", ctx, schemaName, tableNames, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamTables indicates an expected call of StreamTables.
+func (mr *MockTableStreamerMockRecorder) StreamTables(ctx, schemaName, tableNames, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamTables", reflect.TypeOf((*MockTableStreamer)(nil).StreamTables), ctx, schemaName, tableNames, fn)
+}
@@ -469,6 +469,21 @@ func (m *MockProvider) EXPECT() *MockProviderMockRecorder {
 	return m.recorder
 }
 
+// BackendPID mocks base method.
+func (m *MockProvider) BackendPID(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BackendPID", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BackendPID indicates an expected call of BackendPID.
+func (mr *MockProviderMockRecorder) BackendPID(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BackendPID", reflect.TypeOf((*MockProvider)(nil).BackendPID), ctx)
+}
+
 // BeginTx mocks base method.
 func (m *MockProvider) BeginTx(ctx context.Context, opts *sql.TxOptions) (xdb.Provider, error) {
 	m.ctrl.T.Helper()
@@ -484,6 +499,20 @@ func (mr *MockProviderMockRecorder) BeginTx(ctx, opts any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTx", reflect.TypeOf((*MockProvider)(nil).BeginTx), ctx, opts)
 }
 
+// CancelBackend mocks base method.
+func (m *MockProvider) CancelBackend(ctx context.Context, pid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelBackend", ctx, pid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelBackend indicates an expected call of CancelBackend.
+func (mr *MockProviderMockRecorder) CancelBackend(ctx, pid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelBackend", reflect.TypeOf((*MockProvider)(nil).CancelBackend), ctx, pid)
+}
+
 // Close mocks base method.
 func (m *MockProvider) Close() error {
 	m.ctrl.T.Helper()
@@ -668,3 +697,33 @@ func (mr *MockProviderMockRecorder) Tx() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tx", reflect.TypeOf((*MockProvider)(nil).Tx))
 }
+
+// WithSnapshot mocks base method.
+func (m *MockProvider) WithSnapshot(ctx context.Context) (context.Context, xdb.Provider, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithSnapshot", ctx)
+	ret0, _ := ret[0].(context.Context)
+	ret1, _ := ret[1].(xdb.Provider)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// WithSnapshot indicates an expected call of WithSnapshot.
+func (mr *MockProviderMockRecorder) WithSnapshot(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithSnapshot", reflect.TypeOf((*MockProvider)(nil).WithSnapshot), ctx)
+}
+
+// WithTx mocks base method.
+func (m *MockProvider) WithTx(ctx context.Context, opts *sql.TxOptions, fn xdb.TxFunc) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, opts, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockProviderMockRecorder) WithTx(ctx, opts, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockProvider)(nil).WithTx), ctx, opts, fn)
+}
@@ -0,0 +1,166 @@
+package xdb
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+)
+
+// modelFields returns the fields of the struct with "db" tag,
+// the tag options are provided by the generated models:
+//
+//	ID   int64  `db:"id,int8,index,primary"`
+//	Name string `db:"name,varchar,max:64"`
+//
+// and omitempty option marks the columns with default values.
+func modelFields(typ reflect.Type) []*xsql.StructField {
+	// the struct type is checked by modelValue
+	fields, _ := xsql.StructFields(typ)
+	return fields
+}
+
+// isPrimary returns true for the columns of the primary key
+func isPrimary(f *xsql.StructField) bool {
+	return f.HasOption("primary")
+}
+
+// isGenerated returns true for the columns with values generated by the database,
+// primary key or omitempty, that are skipped on insert when empty
+func isGenerated(f *xsql.StructField) bool {
+	return isPrimary(f) || f.HasOption("omitempty")
+}
+
+// modelValue returns the struct value of the model,
+// that must be a pointer to struct
+func modelValue(model any) (reflect.Value, error) {
+	val := reflect.ValueOf(model)
+	if val.Kind() != reflect.Pointer || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, errors.Errorf("expected pointer to struct: %T", model)
+	}
+	return val.Elem(), nil
+}
+
+/*
+InsertModel inserts the model into the table,
+the columns are taken from "db" tags of the model fields:
+
+	err := xdb.InsertModel(ctx, p, model.OrgTable.TableName(), &model.Org{Name: "acme"})
+
+The empty fields of the primary key, and the fields tagged with omitempty,
+are skipped to be generated by the database,
+and the generated values are returned to the model with RETURNING clause,
+or from LastInsertId for a single integer primary key,
+if the dialect does not support RETURNING.
+*/
+func InsertModel(ctx context.Context, p Provider, table string, model any) error {
+	val, err := modelValue(model)
+	if err != nil {
+		return err
+	}
+
+	dialect := xsql.DialectFor(p.Name())
+	q := dialect.InsertInto(table)
+	defer q.Close()
+
+	var returning []*xsql.StructField
+	for _, f := range modelFields(val.Type()) {
+		fv := val.FieldByIndex(f.Index)
+		if isGenerated(f) && fv.IsZero() {
+			returning = append(returning, f)
+			continue
+		}
+		q.Set(f.Column, fv.Interface())
+	}
+
+	if len(returning) == 0 {
+		_, err = q.Exec(ctx, p)
+		return errors.WithStack(err)
+	}
+
+	if dialect.Capabilities().Returning {
+		for _, f := range returning {
+			q.Returning(f.Column).To(val.FieldByIndex(f.Index).Addr().Interface())
+		}
+		return errors.WithStack(q.QueryRow(ctx, p))
+	}
+
+	res, err := q.Exec(ctx, p)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, f := range returning {
+		fv := val.FieldByIndex(f.Index)
+		if !isPrimary(f) || (!fv.CanInt() && !fv.CanUint()) {
+			continue
+		}
+		// ignore the error, if the driver does not support LastInsertId
+		if id, err := res.LastInsertId(); err == nil {
+			if fv.CanInt() {
+				fv.SetInt(id)
+			} else {
+				fv.SetUint(uint64(id))
+			}
+		}
+		break
+	}
+	return nil
+}
+
+/*
+UpdateModel updates the row of the table identified by the key columns,
+with the values of other columns of the model, the columns are taken
+from "db" tags of the model fields:
+
+	updated, err := xdb.UpdateModel(ctx, p, model.OrgTable.TableName(), org)
+
+If keyColumns are not provided, the primary key of the model is used.
+It returns the number of updated rows.
+*/
+func UpdateModel(ctx context.Context, p Provider, table string, model any, keyColumns ...string) (int64, error) {
+	val, err := modelValue(model)
+	if err != nil {
+		return 0, err
+	}
+
+	fields := modelFields(val.Type())
+	isKey := func(f *xsql.StructField) bool {
+		if len(keyColumns) == 0 {
+			return isPrimary(f)
+		}
+		for _, k := range keyColumns {
+			if strings.EqualFold(k, f.Column) {
+				return true
+			}
+		}
+		return false
+	}
+
+	q := xsql.DialectFor(p.Name()).Update(table)
+	defer q.Close()
+
+	var keys, sets int
+	for _, f := range fields {
+		if !isKey(f) {
+			q.Set(f.Column, val.FieldByIndex(f.Index).Interface())
+			sets++
+		}
+	}
+	for _, f := range fields {
+		if isKey(f) {
+			q.Where(f.Column+" = ?", val.FieldByIndex(f.Index).Interface())
+			keys++
+		}
+	}
+
+	if keys == 0 || (len(keyColumns) > 0 && keys != len(keyColumns)) {
+		return 0, errors.Errorf("key columns are not found in %T", model)
+	}
+	if sets == 0 {
+		return 0, errors.Errorf("no columns to update in %T", model)
+	}
+
+	return RowsAffected(q.Exec(ctx, p))
+}
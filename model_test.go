@@ -0,0 +1,112 @@
+package xdb_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type modelAudit struct {
+	CreatedBy string `db:"created_by"`
+}
+
+type modelOrg struct {
+	ID   int64  `db:"id,int8,index,primary"`
+	Name string `db:"name,varchar,max:64"`
+	// Plan has the default value in the table
+	Plan string `db:"plan,omitempty"`
+	modelAudit
+	Skip string `db:"-"`
+	Note string
+}
+
+const modelOrgTable = "CREATE TABLE org (id INTEGER PRIMARY KEY, name TEXT NOT NULL, plan TEXT DEFAULT 'free', created_by TEXT)"
+
+func TestInsertModel(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+	_, err := p.ExecContext(ctx, modelOrgTable)
+	require.NoError(t, err)
+
+	org := &modelOrg{Name: "acme", modelAudit: modelAudit{CreatedBy: "admin"}}
+	require.NoError(t, xdb.InsertModel(ctx, p, "org", org))
+	assert.Equal(t, int64(1), org.ID)
+	// the default is not returned without RETURNING support
+	assert.Empty(t, org.Plan)
+
+	org2 := &modelOrg{ID: 10, Name: "initech", Plan: "pro"}
+	require.NoError(t, xdb.InsertModel(ctx, p, "org", org2))
+	assert.Equal(t, int64(10), org2.ID)
+
+	var name, plan, createdBy string
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT name, plan, created_by FROM org WHERE id = 1").Scan(&name, &plan, &createdBy))
+	assert.Equal(t, "acme", name)
+	assert.Equal(t, "free", plan)
+	assert.Equal(t, "admin", createdBy)
+
+	err = xdb.InsertModel(ctx, p, "org", *org)
+	assert.EqualError(t, err, "expected pointer to struct: xdb_test.modelOrg")
+	err = xdb.InsertModel(ctx, p, "missing", org)
+	assert.Error(t, err)
+}
+
+func TestInsertModelReturning(t *testing.T) {
+	ctx := context.Background()
+	d, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	d.SetMaxOpenConns(1)
+	// SQLite supports RETURNING and $N placeholders used by Postgres dialect
+	p, err := xdb.New("postgres", d, nil)
+	require.NoError(t, err)
+	defer p.Close()
+
+	_, err = p.ExecContext(ctx, modelOrgTable)
+	require.NoError(t, err)
+
+	org := &modelOrg{Name: "acme"}
+	require.NoError(t, xdb.InsertModel(ctx, p, "org", org))
+	assert.Equal(t, int64(1), org.ID)
+	assert.Equal(t, "free", org.Plan)
+}
+
+func TestUpdateModel(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+	_, err := p.ExecContext(ctx, modelOrgTable)
+	require.NoError(t, err)
+
+	org := &modelOrg{Name: "acme", Plan: "free"}
+	require.NoError(t, xdb.InsertModel(ctx, p, "org", org))
+
+	org.Name = "acme inc"
+	org.Plan = "pro"
+	n, err := xdb.UpdateModel(ctx, p, "org", org)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	var name, plan string
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT name, plan FROM org WHERE id = ?", org.ID).Scan(&name, &plan))
+	assert.Equal(t, "acme inc", name)
+	assert.Equal(t, "pro", plan)
+
+	org.Plan = "enterprise"
+	n, err = xdb.UpdateModel(ctx, p, "org", org, "Name")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	missing := &modelOrg{ID: 100, Name: "none"}
+	n, err = xdb.UpdateModel(ctx, p, "org", missing)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	_, err = xdb.UpdateModel(ctx, p, "org", org, "name", "unknown")
+	assert.EqualError(t, err, "key columns are not found in *xdb_test.modelOrg")
+	_, err = xdb.UpdateModel(ctx, p, "org", &modelAudit{CreatedBy: "admin"}, "created_by")
+	assert.EqualError(t, err, "no columns to update in *xdb_test.modelAudit")
+	_, err = xdb.UpdateModel(ctx, p, "org", &modelAudit{CreatedBy: "admin"})
+	assert.EqualError(t, err, "key columns are not found in *xdb_test.modelAudit")
+}
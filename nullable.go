@@ -0,0 +1,100 @@
+package xdb
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Nullable represents the value of T that may be NULL,
+// unlike Int64, Int32, Float and Bool types, where zero value is stored as NULL,
+// Nullable stores zero value of T when Valid is true:
+//
+//	count := xdb.NewNullable[int64](0)
+//
+// NULL value is marshaled to JSON as null.
+type Nullable[T any] struct {
+	V     T
+	Valid bool
+}
+
+// NewNullable returns valid Nullable with the value
+func NewNullable[T any](v T) Nullable[T] {
+	return Nullable[T]{V: v, Valid: true}
+}
+
+// NullableFromPtr returns Nullable with the value of the pointer,
+// or NULL if the pointer is nil
+func NullableFromPtr[T any](v *T) Nullable[T] {
+	if v == nil {
+		return Nullable[T]{}
+	}
+	return NewNullable(*v)
+}
+
+// Get returns the value, or zero value of T if it's NULL
+func (n Nullable[T]) Get() T {
+	return n.V
+}
+
+// GetOr returns the value, or def if it's NULL
+func (n Nullable[T]) GetOr(def T) T {
+	if !n.Valid {
+		return def
+	}
+	return n.V
+}
+
+// Ptr returns pointer to the copy of the value, or nil if it's NULL
+func (n Nullable[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	v := n.V
+	return &v
+}
+
+// Scan implements the Scanner interface.
+func (n *Nullable[T]) Scan(value any) error {
+	var v sql.Null[T]
+	if err := v.Scan(value); err != nil {
+		return errors.WithStack(err)
+	}
+	*n = Nullable[T]{V: v.V, Valid: v.Valid}
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (n Nullable[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	if v, ok := any(n.V).(driver.Valuer); ok {
+		return v.Value()
+	}
+	return driver.DefaultParameterConverter.ConvertValue(n.V)
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte(`null`), nil
+	}
+	return json.Marshal(n.V)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	var res Nullable[T]
+	if len(data) > 0 && !bytes.Equal(data, []byte(`null`)) {
+		if err := json.Unmarshal(data, &res.V); err != nil {
+			return errors.WithStack(err)
+		}
+		res.Valid = true
+	}
+	*n = res
+	return nil
+}
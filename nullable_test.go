@@ -0,0 +1,115 @@
+package xdb_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullable(t *testing.T) {
+	var n xdb.Nullable[int64]
+	assert.Nil(t, n.Ptr())
+	assert.Equal(t, int64(7), n.GetOr(7))
+
+	v, err := n.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	zero := xdb.NewNullable[int64](0)
+	v, err = zero.Value()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), v)
+	assert.Equal(t, int64(0), zero.GetOr(7))
+	assert.Equal(t, int64(0), *zero.Ptr())
+
+	assert.False(t, xdb.NullableFromPtr[string](nil).Valid)
+	s := "x"
+	assert.Equal(t, xdb.NewNullable("x"), xdb.NullableFromPtr(&s))
+
+	require.NoError(t, n.Scan(int64(42)))
+	assert.Equal(t, xdb.NewNullable[int64](42), n)
+	require.NoError(t, n.Scan(nil))
+	assert.False(t, n.Valid)
+	assert.Error(t, n.Scan("x"))
+
+	var b xdb.Nullable[bool]
+	require.NoError(t, b.Scan(false))
+	assert.True(t, b.Valid)
+	assert.False(t, b.Get())
+
+	// types that implement Scanner and Valuer
+	var id xdb.Nullable[xdb.ID]
+	require.NoError(t, id.Scan(int64(5)))
+	assert.Equal(t, uint64(5), id.Get().UInt64())
+	v, err = id.Value()
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), v)
+
+	u := xdb.NewNullable[uint32](3)
+	v, err = u.Value()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), v)
+
+	_, err = xdb.NewNullable(struct{}{}).Value()
+	assert.Error(t, err)
+}
+
+func TestNullableJSON(t *testing.T) {
+	type model struct {
+		Count  xdb.Nullable[int64]   `json:"count"`
+		Active xdb.Nullable[bool]    `json:"active"`
+		Name   xdb.Nullable[string]  `json:"name"`
+		Score  xdb.Nullable[float64] `json:"score,omitempty"`
+	}
+
+	m := model{
+		Count:  xdb.NewNullable[int64](0),
+		Active: xdb.NewNullable(false),
+	}
+	js, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, `{"count":0,"active":false,"name":null,"score":null}`, string(js))
+
+	var m2 model
+	require.NoError(t, json.Unmarshal(js, &m2))
+	assert.Equal(t, m, m2)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"count":"x"}`), &m2))
+}
+
+func TestNullableRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE counter (id INTEGER PRIMARY KEY, cnt INTEGER NULL, flag BOOLEAN NULL)")
+	require.NoError(t, err)
+
+	_, err = p.ExecContext(ctx, "INSERT INTO counter (id, cnt, flag) VALUES (?, ?, ?), (?, ?, ?)",
+		1, xdb.NewNullable[int64](0), xdb.NewNullable(false),
+		2, xdb.Nullable[int64]{}, xdb.Nullable[bool]{})
+	require.NoError(t, err)
+
+	// xdb.Int64 stores 0 as NULL
+	_, err = p.ExecContext(ctx, "INSERT INTO counter (id, cnt) VALUES (?, ?)", 3, xdb.Int64(0))
+	require.NoError(t, err)
+
+	var cnt xdb.Nullable[int64]
+	var flag xdb.Nullable[bool]
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT cnt, flag FROM counter WHERE id = ?", 1).Scan(&cnt, &flag))
+	assert.Equal(t, xdb.NewNullable[int64](0), cnt)
+	assert.Equal(t, xdb.NewNullable(false), flag)
+
+	for _, id := range []int{2, 3} {
+		require.NoError(t, p.QueryRowContext(ctx, "SELECT cnt FROM counter WHERE id = ?", id).Scan(&cnt))
+		assert.False(t, cnt.Valid)
+	}
+
+	var ts xdb.Nullable[time.Time]
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT NULL").Scan(&ts))
+	assert.False(t, ts.Valid)
+}
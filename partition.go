@@ -0,0 +1,219 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/x/slices"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// PartitionedTable describes the table partitioned by range of time,
+// schema.TableInfo implements the interface
+type PartitionedTable interface {
+	// TableName returns FQN of the table in schema.name format
+	TableName() string
+	// PartitionColumn returns the column of the partition key
+	PartitionColumn() string
+}
+
+// PartitionInterval specifies the time range of one partition
+type PartitionInterval int
+
+const (
+	// PartitionMonthly creates one partition per calendar month
+	PartitionMonthly PartitionInterval = iota
+	// PartitionDaily creates one partition per day
+	PartitionDaily
+)
+
+// Range returns the bounds of the partition for t in UTC,
+// from is inclusive and to is exclusive
+func (i PartitionInterval) Range(t time.Time) (from, to time.Time) {
+	t = t.UTC()
+	switch i {
+	case PartitionDaily:
+		from = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 0, 1)
+	default:
+		from = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 1, 0)
+	}
+	return
+}
+
+// PartitionName returns the name of the partition for t,
+// for example "public.orders_p2024_03" for monthly partitions
+func (i PartitionInterval) PartitionName(table string, t time.Time) string {
+	from, _ := i.Range(t)
+	if i == PartitionDaily {
+		return table + from.Format("_p2006_01_02")
+	}
+	return table + from.Format("_p2006_01")
+}
+
+// ValidatePartitionKey returns an error if the partition key
+// of the partitioned table is not present in the insert columns
+func ValidatePartitionKey(t PartitionedTable, columns []string) error {
+	key := t.PartitionColumn()
+	if key == "" {
+		return nil
+	}
+	if !slices.ContainsStringEqualFold(columns, key) {
+		return errors.Errorf("partition key %s is missing in insert into %s", key, t.TableName())
+	}
+	return nil
+}
+
+// EnsureTimePartition creates the partition of the table for ts, if it does not exist.
+// The partition is created in a transaction holding the advisory lock on the table,
+// so concurrent writers and migrations do not race to create the same partition.
+// It returns true if the partition was created.
+// Only Postgres is supported.
+func EnsureTimePartition(ctx context.Context, p Provider, t PartitionedTable, interval PartitionInterval, ts time.Time) (created bool, err error) {
+	if p.Name() != "postgres" {
+		return false, errors.Errorf("time partitions are not supported for %s", p.Name())
+	}
+
+	name := interval.PartitionName(t.TableName(), ts)
+	from, to := interval.Range(ts)
+
+	tx, err := p.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// the lock is released on commit or rollback
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "xdb.partition:"+t.TableName())
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed to lock %s", t.TableName())
+	}
+
+	var exists sql.NullString
+	err = tx.QueryRowContext(ctx, "SELECT to_regclass($1)::text", name).Scan(&exists)
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed to check partition %s", name)
+	}
+
+	if !exists.Valid {
+		_, err = tx.ExecContext(ctx, partitionDDL(t.TableName(), name, from, to))
+		if err != nil {
+			return false, errors.WithMessagef(err, "failed to create partition %s", name)
+		}
+		created = true
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	if created {
+		logger.KV(xlog.NOTICE,
+			"reason", "partition_created",
+			"table", t.TableName(),
+			"partition", name,
+			"from", from,
+			"to", to)
+	}
+	return created, nil
+}
+
+func partitionDDL(table, partition string, from, to time.Time) string {
+	const layout = "2006-01-02 15:04:05Z07:00"
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+		partition, table, from.Format(layout), to.Format(layout))
+}
+
+// PartitionWriter inserts rows into a partitioned table,
+// validating the partition key, and optionally creating missing partitions
+type PartitionWriter struct {
+	table      PartitionedTable
+	interval   PartitionInterval
+	autoCreate bool
+
+	lock  sync.Mutex
+	known map[string]bool
+}
+
+// NewPartitionWriter returns the writer for the table
+func NewPartitionWriter(t PartitionedTable, interval PartitionInterval) *PartitionWriter {
+	return &PartitionWriter{
+		table:    t,
+		interval: interval,
+		known:    map[string]bool{},
+	}
+}
+
+// WithAutoCreate enables creation of missing partitions on insert
+func (w *PartitionWriter) WithAutoCreate(enable bool) *PartitionWriter {
+	w.autoCreate = enable
+	return w
+}
+
+// Insert inserts the row with column values into the table
+func (w *PartitionWriter) Insert(ctx context.Context, p Provider, values map[string]any) (sql.Result, error) {
+	columns := make([]string, 0, len(values))
+	for k := range values {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+
+	if err := ValidatePartitionKey(w.table, columns); err != nil {
+		return nil, err
+	}
+
+	if w.autoCreate && w.table.PartitionColumn() != "" {
+		if err := w.ensure(ctx, p, values); err != nil {
+			return nil, err
+		}
+	}
+
+	q := xsql.DialectFor(p.Name()).InsertInto(w.table.TableName())
+	for _, c := range columns {
+		q.Set(c, values[c])
+	}
+	return q.ExecAndClose(ctx, p)
+}
+
+func (w *PartitionWriter) ensure(ctx context.Context, p Provider, values map[string]any) error {
+	key := w.table.PartitionColumn()
+	var ts time.Time
+	for k, v := range values {
+		if !strings.EqualFold(key, k) {
+			continue
+		}
+		switch tv := v.(type) {
+		case time.Time:
+			ts = tv
+		case Time:
+			ts = time.Time(tv)
+		default:
+			return errors.Errorf("partition key %s must be time, got %T", key, v)
+		}
+	}
+
+	name := w.interval.PartitionName(w.table.TableName(), ts)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.known[name] {
+		return nil
+	}
+	if _, err := EnsureTimePartition(ctx, p, w.table, w.interval, ts); err != nil {
+		return err
+	}
+	w.known[name] = true
+	return nil
+}
@@ -0,0 +1,56 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionInterval(t *testing.T) {
+	ts := time.Date(2024, 2, 29, 23, 30, 0, 0, time.FixedZone("PST", -8*3600))
+
+	from, to := xdb.PartitionMonthly.Range(ts)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), to)
+	assert.Equal(t, "public.orders_p2024_03", xdb.PartitionMonthly.PartitionName("public.orders", ts))
+
+	from, to = xdb.PartitionDaily.Range(ts)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), to)
+	assert.Equal(t, "orders_p2024_03_01", xdb.PartitionDaily.PartitionName("orders", ts))
+}
+
+func TestPartitionWriter(t *testing.T) {
+	ctx := context.Background()
+
+	table := &schema.TableInfo{Name: "orders", PartitionKey: "created_at"}
+	assert.NoError(t, xdb.ValidatePartitionKey(table, []string{"id", "Created_At"}))
+	assert.EqualError(t, xdb.ValidatePartitionKey(table, []string{"id"}),
+		"partition key created_at is missing in insert into orders")
+	assert.NoError(t, xdb.ValidatePartitionKey(&schema.TableInfo{Name: "orders"}, []string{"id"}))
+
+	p := openSQLite(t)
+	_, err := p.ExecContext(ctx, "CREATE TABLE orders (id INTEGER, created_at TIMESTAMP)")
+	require.NoError(t, err)
+
+	w := xdb.NewPartitionWriter(table, xdb.PartitionMonthly)
+	_, err = w.Insert(ctx, p, map[string]any{"id": 1, "created_at": p.Now()})
+	require.NoError(t, err)
+	_, err = w.Insert(ctx, p, map[string]any{"id": 2})
+	assert.EqualError(t, err, "partition key created_at is missing in insert into orders")
+
+	w.WithAutoCreate(true)
+	_, err = w.Insert(ctx, p, map[string]any{"id": 3, "created_at": "2024-01-01"})
+	assert.EqualError(t, err, "partition key created_at must be time, got string")
+	_, err = w.Insert(ctx, p, map[string]any{"id": 3, "created_at": time.Now()})
+	assert.EqualError(t, err, "time partitions are not supported for sqlite3")
+
+	var count int
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT COUNT(*) FROM orders").Scan(&count))
+	assert.Equal(t, 1, count)
+}
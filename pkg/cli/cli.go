@@ -19,10 +19,11 @@ import (
 // Cli provides CLI context to run commands
 type Cli struct {
 	Version ctl.VersionFlag `name:"version" help:"Print version information and quit" hidden:""`
-	Debug   bool            `short:"D" help:"Enable debug mode"`
-	O       string          `help:"Print output format: json|yaml|table" default:"table"`
+	Debug   bool            `short:"D" help:"Enable debug mode" env:"XDB_DEBUG"`
+	O       string          `help:"Print output format: json|yaml|table" default:"table" env:"XDB_OUTPUT"`
 
 	SQLSource string `help:"SQL sources, if not provided, will be used from XDB_DATASOURCE env var"`
+	Snapshot  string `help:"optional, path to schema snapshot file to use instead of database" env:"XDB_SNAPSHOT"`
 
 	// Stdin is the source to read from, typically set to os.Stdin
 	stdin io.Reader
@@ -104,7 +105,7 @@ func (c *Cli) AfterApply(_ *kong.Kong, _ kong.Vars) error {
 	}
 
 	c.SQLSource = values.StringsCoalesce(c.SQLSource, os.Getenv("XDB_DATASOURCE"))
-	if c.SQLSource == "" {
+	if c.SQLSource == "" && c.Snapshot == "" {
 		return errors.Errorf("use --sql-source or set XDB_DATASOURCE")
 	}
 
@@ -125,6 +126,13 @@ func (c *Cli) DB(dbname string) (xdb.Provider, error) {
 
 // SchemaProvider returns schema.Provider
 func (c *Cli) SchemaProvider(dbname string) (schema.Provider, error) {
+	if c.schema == nil && c.Snapshot != "" {
+		s, err := schema.LoadSnapshot(c.Snapshot)
+		if err != nil {
+			return nil, err
+		}
+		c.schema = schema.NewSnapshotProvider(s)
+	}
 	if c.schema == nil {
 		prov, err := c.DB(dbname)
 		if err != nil {
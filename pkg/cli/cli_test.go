@@ -2,6 +2,7 @@ package cli
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/alecthomas/kong"
@@ -95,3 +96,26 @@ func mustNew(t *testing.T, cli any, options ...kong.Option) *kong.Kong {
 
 	return parser
 }
+
+func TestSnapshotProvider(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, os.WriteFile(fn, []byte(`{
+	"version": 1,
+	"provider": "postgres",
+	"tables": [{"Schema": "public", "Name": "org", "Columns": [{"Name": "id", "Type": "bigint"}]}]
+}`), 0600))
+
+	c := Cli{Snapshot: fn}
+	p, err := c.SchemaProvider("testdb")
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", p.Name())
+
+	tables, err := p.ListTables(c.Context(), "", nil, false)
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	assert.Equal(t, "public.org", tables[0].SchemaName)
+
+	c = Cli{Snapshot: fn + ".missing"}
+	_, err = c.SchemaProvider("testdb")
+	assert.ErrorContains(t, err, "failed to load snapshot")
+}
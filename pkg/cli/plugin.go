@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PluginPrefix is the prefix of executable names for xdbcli plugins,
+// for example xdbcli-codegen is available as `xdbcli codegen` command
+const PluginPrefix = "xdbcli-"
+
+// Plugin describes an external command
+type Plugin struct {
+	// Name of the command
+	Name string
+	// Path to the executable
+	Path string
+}
+
+// FindPlugins returns plugins discovered in PATH,
+// the first executable wins if the name is found in multiple folders
+func FindPlugins() []Plugin {
+	found := map[string]bool{}
+	var list []Plugin
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			fn := e.Name()
+			if e.IsDir() || !strings.HasPrefix(fn, PluginPrefix) {
+				continue
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(fn, PluginPrefix), filepath.Ext(fn))
+			if name == "" || found[name] {
+				continue
+			}
+			path := filepath.Join(dir, fn)
+			if !isExecutable(path) {
+				continue
+			}
+			found[name] = true
+			list = append(list, Plugin{Name: name, Path: path})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func isExecutable(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil || fi.IsDir() {
+		return false
+	}
+	return fi.Mode()&0111 != 0
+}
+
+// PluginEnv returns environment variables to pass the global flags to plugins.
+// A plugin that embeds Cli reads them as defaults of its own flags.
+func (c *Cli) PluginEnv() []string {
+	return []string{
+		"XDB_DATASOURCE=" + c.SQLSource,
+		"XDB_SNAPSHOT=" + c.Snapshot,
+		"XDB_OUTPUT=" + c.O,
+		"XDB_DEBUG=" + strconv.FormatBool(c.Debug),
+	}
+}
+
+// RunPlugin executes the plugin with args,
+// the plugin shares the input and output streams of the current process
+func (c *Cli) RunPlugin(path string, args ...string) error {
+	cmd := exec.CommandContext(c.Context(), path, args...)
+	cmd.Stdin = c.Reader()
+	cmd.Stdout = c.Writer()
+	cmd.Stderr = c.ErrWriter()
+	cmd.Env = append(os.Environ(), c.PluginEnv()...)
+
+	if err := cmd.Run(); err != nil {
+		return errors.WithMessagef(err, "plugin %s failed", filepath.Base(path))
+	}
+	return nil
+}
+
+// PluginCmd runs an external plugin
+type PluginCmd struct {
+	Args []string `arg:"" optional:"" passthrough:"" help:"arguments passed to the plugin"`
+
+	path string
+}
+
+// NewPluginCmd returns a command to run the plugin
+func NewPluginCmd(p Plugin) *PluginCmd {
+	return &PluginCmd{path: p.Path}
+}
+
+// Run the command
+func (a *PluginCmd) Run(ctx *Cli) error {
+	return ctx.RunPlugin(a.path, a.Args...)
+}
+
+// PluginsCmd prints discovered plugins
+type PluginsCmd struct{}
+
+// Run the command
+func (a *PluginsCmd) Run(ctx *Cli) error {
+	list := FindPlugins()
+	if ctx.O == "json" || ctx.O == "yaml" {
+		return ctx.Print(list)
+	}
+	w := ctx.Writer()
+	for _, p := range list {
+		fmt.Fprintf(w, "%s\t%s\n", p.Name, p.Path)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePlugin(t *testing.T, dir, name, script string) string {
+	fn := filepath.Join(dir, PluginPrefix+name)
+	require.NoError(t, os.WriteFile(fn, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	return fn
+}
+
+func TestFindPlugins(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+
+	hello := writePlugin(t, dir1, "hello", "echo hello")
+	writePlugin(t, dir2, "hello", "echo shadowed")
+	codegen := writePlugin(t, dir2, "codegen", "echo codegen")
+	require.NoError(t, os.WriteFile(filepath.Join(dir2, PluginPrefix+"noexec"), []byte("text"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir2, PluginPrefix+"dir"), 0755))
+
+	t.Setenv("PATH", dir1+string(os.PathListSeparator)+dir2+string(os.PathListSeparator)+filepath.Join(dir1, "missing"))
+
+	list := FindPlugins()
+	assert.Equal(t, []Plugin{
+		{Name: "codegen", Path: codegen},
+		{Name: "hello", Path: hello},
+	}, list)
+}
+
+func TestRunPlugin(t *testing.T) {
+	dir := t.TempDir()
+	fn := writePlugin(t, dir, "env", `echo "$XDB_DATASOURCE|$XDB_SNAPSHOT|$XDB_OUTPUT|$XDB_DEBUG|$*"`)
+	failed := writePlugin(t, dir, "fail", "exit 3")
+
+	var out bytes.Buffer
+	c := Cli{
+		SQLSource: "postgres://localhost",
+		Snapshot:  "snapshot.json",
+		O:         "json",
+	}
+	c.WithWriter(&out).WithErrWriter(&out)
+
+	cmd := NewPluginCmd(Plugin{Name: "env", Path: fn})
+	cmd.Args = []string{"--flag", "value"}
+	require.NoError(t, cmd.Run(&c))
+	assert.Equal(t, "postgres://localhost|snapshot.json|json|false|--flag value\n", out.String())
+
+	err := c.RunPlugin(failed)
+	assert.EqualError(t, err, "plugin xdbcli-fail failed: exit status 3")
+
+	t.Setenv("PATH", dir)
+	out.Reset()
+	c.O = "table"
+	require.NoError(t, new(PluginsCmd).Run(&c))
+	assert.Equal(t, "env\t"+fn+"\nfail\t"+failed+"\n", out.String())
+
+	out.Reset()
+	c.O = "json"
+	require.NoError(t, new(PluginsCmd).Run(&c))
+	assert.Contains(t, out.String(), `"Name": "env"`)
+}
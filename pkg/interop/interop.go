@@ -0,0 +1,121 @@
+// Package interop provides adapters to use generated xdb models and TableInfo
+// with sqlx and GORM during incremental migrations.
+//
+// Generated models are annotated with `db` tags, that are understood by sqlx,
+// and implement sql.Scanner and driver.Valuer for xdb types, that are understood by GORM.
+// The adapters cover the differences: named queries for sqlx,
+// and table and column names for GORM.
+package interop
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/effective-security/xdb/schema"
+	"github.com/effective-security/xdb/xsql"
+)
+
+// Columns returns the list of column names from `db` tags of the model
+func Columns(model any) []string {
+	var list []string
+	walkFields(reflect.ValueOf(model), func(f *xsql.StructField, _ reflect.Value) {
+		list = append(list, f.Column)
+	})
+	return list
+}
+
+// NamedArgs returns a map of column names to values from `db` tags of the model,
+// to be used as an argument of sqlx.NamedExec and sqlx.NamedQuery
+func NamedArgs(model any) map[string]any {
+	args := map[string]any{}
+	walkFields(reflect.ValueOf(model), func(f *xsql.StructField, v reflect.Value) {
+		args[f.Column] = v.Interface()
+	})
+	return args
+}
+
+// NamedInsert returns INSERT statement with named parameters for the table,
+// to be used with sqlx.NamedExec:
+//
+//	INSERT INTO public.org (id, name) VALUES (:id, :name)
+func NamedInsert(t *schema.TableInfo) string {
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = ":" + c
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		t.SchemaName, strings.Join(t.Columns, ", "), strings.Join(names, ", "))
+}
+
+// NamedUpdate returns UPDATE statement with named parameters for the table,
+// filtered by the key columns, or by the primary key if keys are not provided,
+// to be used with sqlx.NamedExec:
+//
+//	UPDATE public.org SET name = :name WHERE id = :id
+func NamedUpdate(t *schema.TableInfo, keys ...string) string {
+	if len(keys) == 0 && t.PrimaryKey != "" {
+		keys = []string{t.PrimaryKey}
+	}
+
+	isKey := map[string]bool{}
+	where := make([]string, len(keys))
+	for i, k := range keys {
+		isKey[k] = true
+		where[i] = k + " = :" + k
+	}
+
+	var set []string
+	for _, c := range t.Columns {
+		if !isKey[c] {
+			set = append(set, c+" = :"+c)
+		}
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s", t.SchemaName, strings.Join(set, ", "))
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	return query
+}
+
+// Raw returns SQL and arguments of the statement,
+// to be used with sqlx.DB.Select or gorm.DB.Raw:
+//
+//	db.Raw(interop.Raw(q)).Scan(&rows)
+//
+// The statement is not closed.
+func Raw(q xsql.Builder) (string, []any) {
+	return q.String(), q.Args()
+}
+
+// GormTableName returns the table name for GORM Tabler interface,
+// implement TableName method on the model to use the generated metadata:
+//
+//	func (m *Org) TableName() string { return interop.GormTableName(&schema.Org) }
+func GormTableName(t *schema.TableInfo) string {
+	return t.SchemaName
+}
+
+// GormColumns returns a map of struct field names to column names from `db` tags,
+// to configure GORM naming strategy or verify `gorm:"column:..."` tags
+func GormColumns(model any) map[string]string {
+	res := map[string]string{}
+	walkFields(reflect.ValueOf(model), func(f *xsql.StructField, _ reflect.Value) {
+		res[f.Name] = f.Column
+	})
+	return res
+}
+
+// walkFields calls fn for each exported field with `db` tag,
+// including fields of embedded structs
+func walkFields(v reflect.Value, fn func(f *xsql.StructField, v reflect.Value)) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	fields, _ := xsql.StructFields(v.Type())
+	for _, f := range fields {
+		fn(f, v.FieldByIndex(f.Index))
+	}
+}
@@ -0,0 +1,70 @@
+package interop_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/interop"
+	"github.com/effective-security/xdb/schema"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+type Base struct {
+	ID xdb.ID `db:"id,int8,index,primary" json:",omitempty"`
+}
+
+type org struct {
+	Base
+	Name    string         `db:"name,varchar,max:64" json:",omitempty"`
+	Email   xdb.NULLString `db:"email,varchar,null" json:",omitempty"`
+	Ignored string         `db:"-"`
+	NoTag   string
+	private string `db:"private"`
+}
+
+var orgTable = schema.TableInfo{
+	Schema:     "public",
+	Name:       "org",
+	SchemaName: "public.org",
+	PrimaryKey: "id",
+	Columns:    []string{"id", "name", "email"},
+	Dialect:    xsql.Postgres,
+}
+
+func TestModel(t *testing.T) {
+	m := &org{
+		Base:  Base{ID: xdb.NewID(1)},
+		Name:  "test",
+		Email: "a@x",
+	}
+	_ = m.private
+
+	assert.Equal(t, []string{"id", "name", "email"}, interop.Columns(m))
+	assert.Equal(t, map[string]any{
+		"id":    xdb.NewID(1),
+		"name":  "test",
+		"email": xdb.NULLString("a@x"),
+	}, interop.NamedArgs(m))
+	assert.Equal(t, map[string]string{
+		"ID":    "id",
+		"Name":  "name",
+		"Email": "email",
+	}, interop.GormColumns(m))
+
+	assert.Empty(t, interop.Columns("test"))
+	assert.Empty(t, interop.GormColumns(1))
+}
+
+func TestNamedQueries(t *testing.T) {
+	assert.Equal(t, "INSERT INTO public.org (id, name, email) VALUES (:id, :name, :email)", interop.NamedInsert(&orgTable))
+	assert.Equal(t, "UPDATE public.org SET name = :name, email = :email WHERE id = :id", interop.NamedUpdate(&orgTable))
+	assert.Equal(t, "UPDATE public.org SET id = :id, email = :email WHERE name = :name", interop.NamedUpdate(&orgTable, "name"))
+	assert.Equal(t, "public.org", interop.GormTableName(&orgTable))
+
+	q := orgTable.Select("id").Where("name = ?", "test")
+	defer q.Close()
+	query, args := interop.Raw(q)
+	assert.Equal(t, "SELECT id \nFROM public.org \nWHERE name = $1", query)
+	assert.Equal(t, []any{"test"}, args)
+}
@@ -0,0 +1,227 @@
+package notifier
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/effective-security/xdb/schema"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// The operations reported by the change triggers
+const (
+	OpInsert = "INSERT"
+	OpUpdate = "UPDATE"
+	OpDelete = "DELETE"
+)
+
+/*
+ChangeTrigger generates Postgres trigger, that notifies the channel
+on the changes of the table rows, to be added to the migrations:
+
+	tr := notifier.NewChangeTrigger(model.OrgTable, "org_changes")
+	up, down := tr.MigrationUp(), tr.MigrationDown()
+
+The payload is JSON object with schema, table, op, key and row,
+the row is the new row for INSERT and UPDATE, and the old row for DELETE.
+The row is omitted if the payload exceeds MaxPayloadSize,
+use the key to load the row in this case.
+See Subscribe to receive the changes.
+*/
+type ChangeTrigger struct {
+	table     string
+	keyColumn string
+	channel   string
+	ops       []string
+}
+
+// NewChangeTrigger returns the trigger for the table, that notifies the channel
+// on INSERT, UPDATE and DELETE
+func NewChangeTrigger(t *schema.TableInfo, channel string) *ChangeTrigger {
+	return &ChangeTrigger{
+		table:     t.TableName(),
+		keyColumn: t.PrimaryKey,
+		channel:   channel,
+		ops:       []string{OpInsert, OpUpdate, OpDelete},
+	}
+}
+
+// WithOperations specifies the operations to notify
+func (tr *ChangeTrigger) WithOperations(ops ...string) *ChangeTrigger {
+	tr.ops = ops
+	return tr
+}
+
+// WithKeyColumn specifies the key column, the primary key by default
+func (tr *ChangeTrigger) WithKeyColumn(column string) *ChangeTrigger {
+	tr.keyColumn = column
+	return tr
+}
+
+// FunctionName returns the name of the trigger function in schema.name format
+func (tr *ChangeTrigger) FunctionName() string {
+	return tr.table + "_notify_change"
+}
+
+// TriggerName returns the name of the trigger
+func (tr *ChangeTrigger) TriggerName() string {
+	return tr.table[strings.LastIndex(tr.table, ".")+1:] + "_notify_change"
+}
+
+// MigrationUp returns the statements to create the trigger function and the trigger
+func (tr *ChangeTrigger) MigrationUp() string {
+	key := "NULL"
+	if tr.keyColumn != "" {
+		key = "rec." + tr.keyColumn + "::text"
+	}
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s() RETURNS trigger AS $$
+DECLARE
+	rec record;
+	payload text;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		rec := OLD;
+	ELSE
+		rec := NEW;
+	END IF;
+	payload := json_build_object('schema', TG_TABLE_SCHEMA, 'table', TG_TABLE_NAME, 'op', TG_OP,
+		'key', %[2]s, 'row', row_to_json(rec))::text;
+	IF octet_length(payload) > %[3]d THEN
+		payload := json_build_object('schema', TG_TABLE_SCHEMA, 'table', TG_TABLE_NAME, 'op', TG_OP,
+			'key', %[2]s)::text;
+	END IF;
+	PERFORM pg_notify('%[4]s', payload);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER %[5]s AFTER %[6]s ON %[7]s
+	FOR EACH ROW EXECUTE FUNCTION %[1]s();`,
+		tr.FunctionName(), key, MaxPayloadSize,
+		strings.ReplaceAll(tr.channel, "'", "''"),
+		tr.TriggerName(), strings.Join(tr.ops, " OR "), tr.table)
+}
+
+// MigrationDown returns the statements to drop the trigger and the trigger function
+func (tr *ChangeTrigger) MigrationDown() string {
+	return fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;\nDROP FUNCTION IF EXISTS %s();",
+		tr.TriggerName(), tr.table, tr.FunctionName())
+}
+
+// Change is the change of the table row, reported by ChangeTrigger
+type Change[T any] struct {
+	Schema string
+	Table  string
+	// Op is OpInsert, OpUpdate or OpDelete
+	Op string
+	// Key is the value of the key column
+	Key string
+	// Row is the new row for INSERT and UPDATE, and the old row for DELETE,
+	// or nil if the row was omitted from the payload
+	Row *T
+}
+
+type changePayload struct {
+	Schema string                     `json:"schema"`
+	Table  string                     `json:"table"`
+	Op     string                     `json:"op"`
+	Key    *string                    `json:"key"`
+	Row    map[string]json.RawMessage `json:"row"`
+}
+
+// DecodeChange decodes the notification sent by ChangeTrigger.
+// The row columns are mapped to the fields of T by "db" tag,
+// so the generated models can be used.
+func DecodeChange[T any](n *Notification) (*Change[T], error) {
+	var p changePayload
+	if err := json.Unmarshal([]byte(n.RawPayload), &p); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode change: %s", n.Channel)
+	}
+	if p.Op == "" || p.Table == "" {
+		return nil, errors.Errorf("invalid change payload: %s", n.Channel)
+	}
+
+	c := &Change[T]{
+		Schema: p.Schema,
+		Table:  p.Table,
+		Op:     p.Op,
+	}
+	if p.Key != nil {
+		c.Key = *p.Key
+	}
+	if p.Row != nil {
+		c.Row = new(T)
+		if err := decodeRow(p.Row, c.Row); err != nil {
+			return nil, errors.WithMessagef(err, "failed to decode row: %s", p.Table)
+		}
+	}
+	return c, nil
+}
+
+// decodeRow sets the fields of the struct by "db" tag from JSON values of the columns.
+// The values that can't be decoded from JSON are scanned, if the field implements sql.Scanner.
+func decodeRow(row map[string]json.RawMessage, dest any) error {
+	v := reflect.ValueOf(dest).Elem()
+	if v.Kind() != reflect.Struct {
+		return errors.Errorf("unsupported row type: %s", v.Type().String())
+	}
+
+	fields, err := xsql.StructFields(v.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		name := f.Column
+		raw, ok := row[name]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+
+		field := v.FieldByIndex(f.Index).Addr().Interface()
+		err := json.Unmarshal(raw, field)
+		if err != nil {
+			scanner, ok := field.(sql.Scanner)
+			var s string
+			if !ok || json.Unmarshal(raw, &s) != nil {
+				return errors.Wrapf(err, "failed to decode column: %s", name)
+			}
+			if err = scanner.Scan(s); err != nil {
+				return errors.WithMessagef(err, "failed to decode column: %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Subscribe listens to the channel of ChangeTrigger, and calls fn
+with the changes decoded into T, until the context is done:
+
+	err := notifier.Subscribe(ctx, l, "org_changes", func(c *notifier.Change[model.Org]) {
+		if c.Op == notifier.OpDelete {
+			cache.Delete(c.Key)
+		} else if c.Row != nil {
+			cache.Set(c.Key, c.Row)
+		}
+	})
+
+The notifications that can't be decoded are logged and skipped.
+*/
+func Subscribe[T any](ctx context.Context, l Listener, channel string, fn func(c *Change[T])) error {
+	return l.Listen(ctx, channel, func(n *Notification) {
+		c, err := DecodeChange[T](n)
+		if err != nil {
+			logger.KV(xlog.ERROR,
+				"reason", "decode_change",
+				"channel", channel,
+				"err", err.Error())
+			return
+		}
+		fn(c)
+	})
+}
@@ -0,0 +1,116 @@
+package notifier_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/notifier"
+	"github.com/effective-security/xdb/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type org struct {
+	ID        xdb.ID         `db:"id,bigint,index,primary" json:",omitempty"`
+	Name      string         `db:"name,varchar" json:",omitempty"`
+	Email     xdb.NULLString `db:"email,varchar,null" json:",omitempty"`
+	Quota     int32          `db:"quota,integer" json:",omitempty"`
+	CreatedAt xdb.Time       `db:"created_at,timestamp" json:",omitempty"`
+}
+
+var orgTable = &schema.TableInfo{
+	Schema:     "public",
+	Name:       "org",
+	SchemaName: "public.org",
+	PrimaryKey: "id",
+	Columns:    []string{"id", "name", "email", "quota", "created_at"},
+}
+
+func TestChangeTrigger(t *testing.T) {
+	tr := notifier.NewChangeTrigger(orgTable, "org_changes").
+		WithOperations(notifier.OpInsert, notifier.OpDelete)
+	assert.Equal(t, "public.org_notify_change", tr.FunctionName())
+	assert.Equal(t, "org_notify_change", tr.TriggerName())
+
+	up := tr.MigrationUp()
+	assert.Contains(t, up, "CREATE OR REPLACE FUNCTION public.org_notify_change() RETURNS trigger AS $$")
+	assert.Contains(t, up, "'key', rec.id::text, 'row', row_to_json(rec))::text;")
+	assert.Contains(t, up, "IF octet_length(payload) > 7999 THEN")
+	assert.Contains(t, up, "PERFORM pg_notify('org_changes', payload);")
+	assert.Contains(t, up, "CREATE TRIGGER org_notify_change AFTER INSERT OR DELETE ON public.org\n\tFOR EACH ROW EXECUTE FUNCTION public.org_notify_change();")
+
+	assert.Equal(t, "DROP TRIGGER IF EXISTS org_notify_change ON public.org;\nDROP FUNCTION IF EXISTS public.org_notify_change();",
+		tr.MigrationDown())
+}
+
+func TestDecodeChange(t *testing.T) {
+	c, err := notifier.DecodeChange[org](&notifier.Notification{
+		Channel: "org_changes",
+		RawPayload: `{"schema":"public","table":"org","op":"INSERT","key":"1234567890123456789",
+			"row":{"id":1234567890123456789,"name":"acme","email":null,"quota":10,"created_at":"2024-01-02T03:04:05+00:00"}}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "public", c.Schema)
+	assert.Equal(t, "org", c.Table)
+	assert.Equal(t, notifier.OpInsert, c.Op)
+	assert.Equal(t, "1234567890123456789", c.Key)
+	require.NotNil(t, c.Row)
+	assert.Equal(t, uint64(1234567890123456789), c.Row.ID.UInt64())
+	assert.Equal(t, "acme", c.Row.Name)
+	assert.Empty(t, c.Row.Email)
+	assert.Equal(t, int32(10), c.Row.Quota)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), c.Row.CreatedAt.UTC())
+
+	// the row is omitted from the large payload
+	c, err = notifier.DecodeChange[org](&notifier.Notification{
+		Channel:    "org_changes",
+		RawPayload: `{"schema":"public","table":"org","op":"DELETE","key":"1"}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, notifier.OpDelete, c.Op)
+	assert.Equal(t, "1", c.Key)
+	assert.Nil(t, c.Row)
+
+	_, err = notifier.DecodeChange[org](&notifier.Notification{Channel: "org_changes", RawPayload: `{}`})
+	assert.EqualError(t, err, "invalid change payload: org_changes")
+
+	_, err = notifier.DecodeChange[org](&notifier.Notification{
+		Channel:    "org_changes",
+		RawPayload: `{"table":"org","op":"UPDATE","row":{"quota":"many"}}`,
+	})
+	assert.EqualError(t, err, "failed to decode row: org: failed to decode column: quota: json: cannot unmarshal string into Go value of type int32")
+}
+
+type mockListener struct {
+	callbacks map[string]func(n *notifier.Notification)
+}
+
+func (l *mockListener) Close() error {
+	return nil
+}
+
+func (l *mockListener) Listen(_ context.Context, topic string, callback func(n *notifier.Notification)) error {
+	l.callbacks[topic] = callback
+	return nil
+}
+
+func TestSubscribe(t *testing.T) {
+	l := &mockListener{callbacks: map[string]func(n *notifier.Notification){}}
+
+	var changes []*notifier.Change[org]
+	err := notifier.Subscribe(context.Background(), l, "org_changes", func(c *notifier.Change[org]) {
+		changes = append(changes, c)
+	})
+	require.NoError(t, err)
+
+	callback := l.callbacks["org_changes"]
+	require.NotNil(t, callback)
+	callback(&notifier.Notification{Channel: "org_changes", RawPayload: "invalid"})
+	callback(&notifier.Notification{Channel: "org_changes", RawPayload: `{"table":"org","op":"UPDATE","key":"2","row":{"id":2,"name":"new"}}`})
+
+	require.Len(t, changes, 1)
+	assert.Equal(t, notifier.OpUpdate, changes[0].Op)
+	assert.Equal(t, "new", changes[0].Row.Name)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/effective-security/x/values"
@@ -28,18 +30,143 @@ type Notification struct {
 }
 
 // Listener interface connects to the database and allows callers to listen to a
-// particular topic by issuing a LISTEN command. WaitForNotification blocks
-// until receiving a notification or until the supplied context expires. The
-// default implementation is tightly coupled to pgx (following River's
-// implementation), but callers may implement their own listeners for any
-// backend they'd like.
+// particular topic by issuing a LISTEN command. The callback is called
+// for each notification of the topic, until the supplied context expires.
+// The default implementation is based on lib/pq Listener,
+// the alternative one on pgx native LISTEN support, see WithPgx.
+// Both re-issue LISTEN commands after reconnects, but callers may implement
+// their own listeners for any backend they'd like.
 type Listener interface {
 	io.Closer
 	Listen(ctx context.Context, topic string, callback func(n *Notification)) error
 }
 
+// ListenerOption specifies an option of the listener, see NewListener
+type ListenerOption func(*listenerOptions)
+
+type listenerOptions struct {
+	pgx bool
+}
+
+// WithPgx returns the listener based on pgx native LISTEN support,
+// instead of lib/pq Listener
+func WithPgx() ListenerOption {
+	return func(o *listenerOptions) {
+		o.pgx = true
+	}
+}
+
+// channelConn is the connection that issues LISTEN and UNLISTEN commands
+type channelConn interface {
+	Listen(channel string) error
+	Unlisten(channel string) error
+}
+
+// subscription is the callback registered for a channel
+type subscription struct {
+	callback func(n *Notification)
+}
+
+// dispatcher routes the notifications of all channels,
+// that are received by one connection, to the callbacks of the channels
+type dispatcher struct {
+	conn channelConn
+
+	// connLock serializes LISTEN and UNLISTEN of a channel with the callbacks
+	connLock  sync.Mutex
+	lock      sync.RWMutex
+	callbacks map[string]*subscription
+}
+
+func newDispatcher(conn channelConn) dispatcher {
+	return dispatcher{
+		conn:      conn,
+		callbacks: map[string]*subscription{},
+	}
+}
+
+func (d *dispatcher) listen(ctx context.Context, topic string, callback func(n *Notification)) error {
+	d.connLock.Lock()
+	defer d.connLock.Unlock()
+
+	sub := &subscription{callback: callback}
+	d.lock.Lock()
+	if _, ok := d.callbacks[topic]; ok {
+		d.lock.Unlock()
+		return errors.Errorf("already listening to channel: %s", topic)
+	}
+	d.callbacks[topic] = sub
+	d.lock.Unlock()
+
+	err := d.conn.Listen(topic)
+	if err != nil {
+		d.remove(topic, sub)
+		return errors.Wrapf(err, "failed to listen to channel: %s", topic)
+	}
+
+	go func() {
+		<-ctx.Done()
+		logger.KV(xlog.INFO,
+			"reason", "context_done",
+			"channel", topic)
+		d.unlisten(topic, sub)
+	}()
+	return nil
+}
+
+func (d *dispatcher) unlisten(topic string, sub *subscription) {
+	d.connLock.Lock()
+	defer d.connLock.Unlock()
+
+	if !d.remove(topic, sub) {
+		return
+	}
+	err := d.conn.Unlisten(topic)
+	if err != nil {
+		logger.KV(xlog.ERROR,
+			"reason", "unlisten",
+			"channel", topic,
+			"error", err.Error())
+	}
+}
+
+// remove removes the subscription, if it's still registered for the topic
+func (d *dispatcher) remove(topic string, sub *subscription) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.callbacks[topic] != sub {
+		return false
+	}
+	delete(d.callbacks, topic)
+	return true
+}
+
+// channels returns the channels with registered callbacks
+func (d *dispatcher) channels() []string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	list := make([]string, 0, len(d.callbacks))
+	for topic := range d.callbacks {
+		list = append(list, topic)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// dispatch calls the callback of the notification channel
+func (d *dispatcher) dispatch(n *Notification) {
+	d.lock.RLock()
+	sub := d.callbacks[n.Channel]
+	d.lock.RUnlock()
+	if sub != nil {
+		sub.callback(n)
+	}
+}
+
 type listener struct {
+	dispatcher
 	listener *pq.Listener
+	run      sync.Once
 }
 
 func eventCallBack(ev pq.ListenerEventType, err error) {
@@ -63,14 +190,25 @@ func eventCallBack(ev pq.ListenerEventType, err error) {
 	}
 }
 
-func NewListener(p xdb.Provider, minReconnectInterval time.Duration, maxReconnectInterval time.Duration) Listener {
+// NewListener returns the listener for the Postgres provider,
+// based on lib/pq Listener by default, see WithPgx
+func NewListener(p xdb.Provider, minReconnectInterval time.Duration, maxReconnectInterval time.Duration, opts ...ListenerOption) Listener {
 	minReconnectInterval = values.NumbersCoalesce(minReconnectInterval, DefaultMinReconnectInterval)
 	maxReconnectInterval = values.NumbersCoalesce(maxReconnectInterval, DefaultMaxReconnectInterval)
 
+	o := listenerOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.pgx {
+		return newPgxListener(p.ConnectionString(), minReconnectInterval, maxReconnectInterval)
+	}
+
 	lp := pq.NewListener(p.ConnectionString(), minReconnectInterval, maxReconnectInterval, eventCallBack)
 
 	l := &listener{
-		listener: lp,
+		dispatcher: newDispatcher(lp),
+		listener:   lp,
 	}
 
 	return l
@@ -87,63 +225,60 @@ func (l *listener) Close() error {
 }
 
 func (l *listener) Listen(ctx context.Context, topic string, callback func(n *Notification)) error {
-	err := l.listener.Listen(topic)
+	err := l.listen(ctx, topic, callback)
 	if err != nil {
-		return errors.Wrapf(err, "failed to listen to channel: %s", topic)
+		return err
 	}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				logger.KV(xlog.INFO,
-					"reason", "context_done",
-					"channel", topic)
-				err = l.listener.Unlisten(topic)
+	// the notifications of all channels are received by one connection,
+	// and dispatched to the callbacks of the channels
+	l.run.Do(func() {
+		go l.receive()
+	})
+	return nil
+}
+
+// receive dispatches the notifications until the listener is closed
+func (l *listener) receive() {
+	for {
+		select {
+		case n, ok := <-l.listener.Notify:
+			if !ok {
+				return
+			}
+			// nil is sent after reconnect, when notifications may be lost
+			if n == nil {
+				continue
+			}
+			l.dispatch(newNotification(n.Channel, n.Extra))
+		case <-time.After(time.Minute):
+			go func() {
+				err := l.listener.Ping()
 				if err != nil {
 					logger.KV(xlog.ERROR,
-						"reason", "unlisten",
-						"channel", topic,
+						"reason", "ping",
 						"error", err.Error())
 				}
-				return
-			case n := <-l.listener.Notify:
-				if n != nil {
-					callback(parsePayload(n))
-				}
-			case <-time.After(time.Minute):
-				go func() {
-					err := l.listener.Ping()
-					if err != nil {
-						logger.KV(xlog.ERROR,
-							"reason", "ping",
-							"error", err.Error())
-					}
-				}()
-				// Check if there's more work available, just in case it takes
-				// a while for the Listener to notice connection loss and
-				// reconnect.
-				logger.KV(xlog.DEBUG,
-					"reason", "no_events",
-					"channel", topic)
-			}
+			}()
+			// Check if there's more work available, just in case it takes
+			// a while for the Listener to notice connection loss and
+			// reconnect.
+			logger.KV(xlog.DEBUG, "reason", "no_events")
 		}
-	}()
-	return nil
+	}
 }
 
-func parsePayload(in *pq.Notification) *Notification {
+func newNotification(channel, payload string) *Notification {
 	n := &Notification{
-		Channel:    in.Channel,
-		RawPayload: in.Extra,
+		Channel:    channel,
+		RawPayload: payload,
 	}
-	s := in.Extra
-	if s != "" && s != "{}" && s != "[]" {
-		err := json.Unmarshal([]byte(s), &n.Payload)
+	if payload != "" && payload != "{}" && payload != "[]" {
+		err := json.Unmarshal([]byte(payload), &n.Payload)
 		if err != nil {
 			logger.KV(xlog.DEBUG,
 				"reason", "unmarshal",
-				"val", s,
+				"val", payload,
 				"err", err.Error())
 		}
 	}
@@ -0,0 +1,226 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChannelConn struct {
+	lock     sync.Mutex
+	commands []string
+	err      error
+}
+
+func (c *fakeChannelConn) Listen(channel string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.commands = append(c.commands, "LISTEN "+channel)
+	return nil
+}
+
+func (c *fakeChannelConn) Unlisten(channel string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.commands = append(c.commands, "UNLISTEN "+channel)
+	return nil
+}
+
+func (c *fakeChannelConn) list() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]string{}, c.commands...)
+}
+
+// collector records the payloads received by the callbacks
+type collector struct {
+	lock     sync.Mutex
+	received []string
+}
+
+func (c *collector) callback(n *Notification) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.received = append(c.received, n.Channel+":"+n.RawPayload)
+}
+
+func (c *collector) list() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]string{}, c.received...)
+}
+
+func TestDispatcher(t *testing.T) {
+	conn := &fakeChannelConn{}
+	d := newDispatcher(conn)
+	ctx := context.Background()
+
+	var orders, users collector
+	ctxOrders, cancelOrders := context.WithCancel(ctx)
+	defer cancelOrders()
+	require.NoError(t, d.listen(ctxOrders, "orders", orders.callback))
+	require.NoError(t, d.listen(ctx, "users", users.callback))
+
+	err := d.listen(ctx, "orders", orders.callback)
+	assert.EqualError(t, err, "already listening to channel: orders")
+	assert.Equal(t, []string{"orders", "users"}, d.channels())
+
+	d.dispatch(newNotification("orders", `{"id":1}`))
+	d.dispatch(newNotification("users", `{"id":2}`))
+	d.dispatch(newNotification("unknown", `{"id":3}`))
+	assert.Equal(t, []string{`orders:{"id":1}`}, orders.list())
+	assert.Equal(t, []string{`users:{"id":2}`}, users.list())
+
+	// the channel is unlistened when the context is done
+	cancelOrders()
+	require.Eventually(t, func() bool {
+		return len(conn.list()) == 3
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"LISTEN orders", "LISTEN users", "UNLISTEN orders"}, conn.list())
+	assert.Equal(t, []string{"users"}, d.channels())
+
+	d.dispatch(newNotification("orders", `{"id":4}`))
+	assert.Len(t, orders.list(), 1)
+
+	// the channel can be listened again
+	require.NoError(t, d.listen(ctx, "orders", orders.callback))
+	d.dispatch(newNotification("orders", `{"id":5}`))
+	assert.Len(t, orders.list(), 2)
+}
+
+func TestDispatcherListenFailed(t *testing.T) {
+	conn := &fakeChannelConn{err: errors.New("connection refused")}
+	d := newDispatcher(conn)
+
+	var c collector
+	err := d.listen(context.Background(), "orders", c.callback)
+	assert.EqualError(t, err, "failed to listen to channel: orders: connection refused")
+	assert.Empty(t, d.channels())
+}
+
+func TestNewNotification(t *testing.T) {
+	n := newNotification("orders", `{"id":1}`)
+	assert.Equal(t, "orders", n.Channel)
+	assert.EqualValues(t, 1, n.Payload["id"])
+
+	n = newNotification("orders", "invalid")
+	assert.Equal(t, "invalid", n.RawPayload)
+	assert.Nil(t, n.Payload)
+}
+
+type fakePgxConn struct {
+	lock     sync.Mutex
+	commands []string
+	closed   bool
+	notify   chan *pgconn.Notification
+}
+
+func newFakePgxConn() *fakePgxConn {
+	return &fakePgxConn{notify: make(chan *pgconn.Notification)}
+}
+
+func (c *fakePgxConn) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.commands = append(c.commands, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *fakePgxConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case n, ok := <-c.notify:
+		if !ok {
+			_ = c.Close(ctx)
+			return nil, errors.New("connection reset by peer")
+		}
+		return n, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *fakePgxConn) IsClosed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.closed
+}
+
+func (c *fakePgxConn) Close(_ context.Context) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakePgxConn) list() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]string{}, c.commands...)
+}
+
+func TestPgxListener(t *testing.T) {
+	conns := make(chan *fakePgxConn)
+	l := startPgxListener(func(ctx context.Context) (pgxConn, error) {
+		select {
+		case c := <-conns:
+			return c, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}, time.Millisecond, 10*time.Millisecond)
+
+	// the channels are listened after connect
+	ctx := context.Background()
+	var orders, users collector
+	ctxUsers, cancelUsers := context.WithCancel(ctx)
+	defer cancelUsers()
+	require.NoError(t, l.Listen(ctx, "orders", orders.callback))
+	require.NoError(t, l.Listen(ctxUsers, "users", users.callback))
+
+	first := newFakePgxConn()
+	conns <- first
+	first.notify <- &pgconn.Notification{Channel: "orders", Payload: `{"id":1}`}
+	first.notify <- &pgconn.Notification{Channel: "users", Payload: `{"id":2}`}
+	require.Eventually(t, func() bool {
+		return len(users.list()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{`orders:{"id":1}`}, orders.list())
+	assert.Equal(t, []string{`users:{"id":2}`}, users.list())
+
+	// the wait is interrupted to execute the commands
+	var invoices collector
+	require.NoError(t, l.Listen(ctx, "invoices", invoices.callback))
+	cancelUsers()
+	require.Eventually(t, func() bool {
+		return len(first.list()) == 4
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{`LISTEN "orders"`, `LISTEN "users"`, `LISTEN "invoices"`, `UNLISTEN "users"`}, first.list())
+
+	// the channels are listened again after reconnect
+	close(first.notify)
+	second := newFakePgxConn()
+	conns <- second
+	require.Eventually(t, func() bool {
+		return len(second.list()) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{`LISTEN "invoices"`, `LISTEN "orders"`}, second.list())
+
+	second.notify <- &pgconn.Notification{Channel: "invoices", Payload: `{"id":3}`}
+	require.Eventually(t, func() bool {
+		return len(invoices.list()) == 1
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, l.Close())
+	assert.True(t, second.IsClosed())
+	err := l.Listen(ctx, "users", users.callback)
+	assert.EqualError(t, err, "listener is closed")
+}
@@ -0,0 +1,127 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxPayloadSize is the maximum size of NOTIFY payload in Postgres,
+	// the payload must be shorter than 8000 bytes
+	MaxPayloadSize = 7999
+
+	// DefaultSpillTable is the default table for the payloads exceeding MaxPayloadSize
+	DefaultSpillTable = "public.notifications"
+
+	// SpillIDKey is the key of the payload that refers the row in the spill table
+	SpillIDKey = "notification_id"
+)
+
+// Notifier interface sends notifications to the listeners of a channel
+type Notifier interface {
+	// Notify sends the payload encoded as JSON to the channel.
+	// If the context carries a transaction, see xdb.ContextWithTx,
+	// the notification is delivered when the transaction is committed.
+	Notify(ctx context.Context, channel string, payload values.MapAny) error
+}
+
+/*
+PgNotifier sends notifications with pg_notify.
+
+The payloads exceeding MaxPayloadSize are stored in the spill table,
+and the notification carries only the ID of the row in SpillIDKey,
+use Resolve to load the payload on the listener side.
+The spill table must be created by the migrations:
+
+	CREATE TABLE public.notifications (
+		id bigint NOT NULL PRIMARY KEY,
+		channel varchar(64) NOT NULL,
+		payload text NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now()
+	);
+
+The rows are not deleted by the notifier, as a notification can be
+received by many listeners, use a retention job to clean up the table.
+*/
+type PgNotifier struct {
+	p     xdb.Provider
+	table string
+}
+
+var _ Notifier = (*PgNotifier)(nil)
+
+// NewNotifier returns Notifier for the Postgres provider
+func NewNotifier(p xdb.Provider) *PgNotifier {
+	return &PgNotifier{
+		p:     p,
+		table: DefaultSpillTable,
+	}
+}
+
+// WithSpillTable specifies the table for the payloads exceeding MaxPayloadSize
+func (n *PgNotifier) WithSpillTable(table string) *PgNotifier {
+	n.table = table
+	return n
+}
+
+// Notify sends the payload encoded as JSON to the channel,
+// see Notifier interface
+func (n *PgNotifier) Notify(ctx context.Context, channel string, payload values.MapAny) error {
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode payload")
+	}
+
+	msg := string(js)
+	if len(msg) > MaxPayloadSize {
+		id := n.p.NextID()
+		_, err = n.p.ExecContext(ctx,
+			"INSERT INTO "+n.table+" (id, channel, payload) VALUES ($1, $2, $3)",
+			id, channel, msg)
+		if err != nil {
+			return errors.Wrapf(err, "failed to store payload: %s", channel)
+		}
+		logger.KV(xlog.DEBUG,
+			"reason", "payload_spilled",
+			"channel", channel,
+			"size", len(msg),
+			"id", id)
+		msg = values.MapAny{SpillIDKey: id.String()}.JSON()
+	}
+
+	_, err = n.p.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, msg)
+	if err != nil {
+		return errors.Wrapf(err, "failed to notify: %s", channel)
+	}
+	return nil
+}
+
+// Resolve loads the payload of the notification from the spill table,
+// if it was stored by Notify. Other notifications are not changed.
+func (n *PgNotifier) Resolve(ctx context.Context, nt *Notification) error {
+	id, ok := nt.Payload[SpillIDKey].(string)
+	if !ok || len(nt.Payload) != 1 {
+		return nil
+	}
+
+	var msg string
+	err := n.p.QueryRowContext(ctx,
+		"SELECT payload FROM "+n.table+" WHERE id = $1",
+		id).Scan(&msg)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load payload: %s", id)
+	}
+
+	var payload values.MapAny
+	if err = json.Unmarshal([]byte(msg), &payload); err != nil {
+		return errors.Wrapf(err, "failed to decode payload: %s", id)
+	}
+	nt.Payload = payload
+	nt.RawPayload = msg
+	return nil
+}
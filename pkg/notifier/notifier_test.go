@@ -0,0 +1,141 @@
+package notifier_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/notifier"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openSQLite(t *testing.T) *xdb.SQLProvider {
+	t.Helper()
+	d, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// :memory: database is per connection
+	d.SetMaxOpenConns(1)
+
+	p, err := xdb.New("sqlite3", d, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = p.Close()
+	})
+	return p
+}
+
+// pgNotify records pg_notify calls,
+// other statements are executed by the provider
+type pgNotify struct {
+	xdb.Provider
+	notified [][]any
+	err      error
+}
+
+func (p *pgNotify) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if !strings.HasPrefix(query, "SELECT pg_notify(") {
+		return p.Provider.ExecContext(ctx, query, args...)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.notified = append(p.notified, args)
+	return driver.RowsAffected(1), nil
+}
+
+const spillTable = `CREATE TABLE notifications (
+	id bigint NOT NULL PRIMARY KEY,
+	channel varchar(64) NOT NULL,
+	payload text NOT NULL
+)`
+
+func TestNotify(t *testing.T) {
+	ctx := context.Background()
+	p := &pgNotify{Provider: openSQLite(t)}
+	n := notifier.NewNotifier(p)
+
+	require.NoError(t, n.Notify(ctx, "orders", values.MapAny{"id": 1}))
+	assert.Equal(t, [][]any{{"orders", `{"id":1}`}}, p.notified)
+
+	p.err = errors.New("connection refused")
+	err := n.Notify(ctx, "orders", values.MapAny{"id": 1})
+	assert.EqualError(t, err, "failed to notify: orders: connection refused")
+}
+
+func TestNotifySpill(t *testing.T) {
+	ctx := context.Background()
+	p := &pgNotify{Provider: openSQLite(t)}
+	_, err := p.ExecContext(ctx, spillTable)
+	require.NoError(t, err)
+
+	n := notifier.NewNotifier(p).WithSpillTable("notifications")
+	large := values.MapAny{"name": strings.Repeat("x", notifier.MaxPayloadSize)}
+	payload := large.JSON()
+
+	// the payload exceeding the limit is stored in the table,
+	// and the notification refers the row
+	require.NoError(t, n.Notify(ctx, "orders", large))
+	require.Len(t, p.notified, 1)
+	assert.Equal(t, "orders", p.notified[0][0])
+
+	var ref values.MapAny
+	require.NoError(t, json.Unmarshal([]byte(p.notified[0][1].(string)), &ref))
+	require.Len(t, ref, 1)
+	id := ref.String(notifier.SpillIDKey)
+	require.NotEmpty(t, id)
+
+	nt := &notifier.Notification{
+		Channel: "orders",
+		Payload: values.MapAny{notifier.SpillIDKey: id},
+	}
+	require.NoError(t, n.Resolve(ctx, nt))
+	assert.Equal(t, payload, nt.RawPayload)
+	assert.Equal(t, large.String("name"), nt.Payload.String("name"))
+
+	// other notifications are not changed
+	nt = &notifier.Notification{
+		Channel: "orders",
+		Payload: values.MapAny{notifier.SpillIDKey: id, "name": "small"},
+	}
+	require.NoError(t, n.Resolve(ctx, nt))
+	assert.Equal(t, "small", nt.Payload.String("name"))
+
+	_, err = p.ExecContext(ctx, "INSERT INTO notifications (id, channel, payload) VALUES (1002, 'orders', 'invalid')")
+	require.NoError(t, err)
+	nt = &notifier.Notification{
+		Channel: "orders",
+		Payload: values.MapAny{notifier.SpillIDKey: "1002"},
+	}
+	err = n.Resolve(ctx, nt)
+	assert.EqualError(t, err, "failed to decode payload: 1002: invalid character 'i' looking for beginning of value")
+}
+
+func TestNotifySpillFailed(t *testing.T) {
+	ctx := context.Background()
+	p := &pgNotify{Provider: openSQLite(t)}
+
+	n := notifier.NewNotifier(p).WithSpillTable("notifications")
+	large := values.MapAny{"name": strings.Repeat("x", notifier.MaxPayloadSize)}
+
+	// the notification is not sent if the payload is not stored
+	err := n.Notify(ctx, "orders", large)
+	assert.EqualError(t, err, "failed to store payload: orders: no such table: notifications")
+	assert.Empty(t, p.notified)
+
+	_, err = p.ExecContext(ctx, spillTable)
+	require.NoError(t, err)
+	nt := &notifier.Notification{
+		Channel: "orders",
+		Payload: values.MapAny{notifier.SpillIDKey: "1001"},
+	}
+	err = n.Resolve(ctx, nt)
+	assert.EqualError(t, err, "failed to load payload: 1001: sql: no rows in result set")
+}
@@ -0,0 +1,378 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultOutboxTable is the name of the table to store the events
+	DefaultOutboxTable = "xdb_outbox"
+	// DefaultOutboxMaxAttempts specifies how many times an event is published,
+	// before it's moved to the dead letters
+	DefaultOutboxMaxAttempts = 10
+	// DefaultOutboxBatchSize specifies how many events are published per poll
+	DefaultOutboxBatchSize = 100
+	// DefaultOutboxPollInterval specifies how often the outbox is polled
+	DefaultOutboxPollInterval = time.Second
+	// DefaultOutboxRetryInterval specifies the delay before the first retry,
+	// the delay is doubled on each attempt
+	DefaultOutboxRetryInterval = time.Second
+	// maxRetryShift limits the exponential backoff
+	maxRetryShift = 16
+)
+
+var outboxColumns = "id, topic, payload, attempts, next_attempt_at, last_error, dead, created_at"
+
+// OutboxEvent is the row of the outbox table
+type OutboxEvent struct {
+	ID            xdb.ID
+	Topic         string
+	Payload       values.MapAny
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     xdb.NULLString
+	Dead          bool
+	CreatedAt     time.Time
+}
+
+// PublishFunc publishes the event, the event is retried if an error is returned
+type PublishFunc func(ctx context.Context, e *OutboxEvent) error
+
+/*
+Outbox implements the transactional outbox with at-least-once delivery:
+the events are recorded in the caller's transaction,
+and published by the dispatcher after the transaction is committed.
+
+	ob := notifier.NewOutbox(p).WithNotifier(notifier.NewNotifier(p))
+
+	err := p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		...
+		_, err := ob.Enqueue(ctx, tx, "orders", values.MapAny{"id": order.ID})
+		return err
+	})
+
+	go ob.Run(ctx)
+
+The failed events are retried with exponential backoff,
+and moved to the dead letters after the maximum number of attempts,
+see DeadLetters and Requeue.
+An event may be published more than once, the consumers must be idempotent.
+*/
+type Outbox struct {
+	p             xdb.Provider
+	table         string
+	publish       PublishFunc
+	maxAttempts   int32
+	batchSize     int
+	pollInterval  time.Duration
+	retryInterval time.Duration
+	clock         xdb.Clock
+}
+
+// NewOutbox returns the outbox of the events in DefaultOutboxTable
+func NewOutbox(p xdb.Provider) *Outbox {
+	return &Outbox{
+		p:             p,
+		table:         DefaultOutboxTable,
+		maxAttempts:   DefaultOutboxMaxAttempts,
+		batchSize:     DefaultOutboxBatchSize,
+		pollInterval:  DefaultOutboxPollInterval,
+		retryInterval: DefaultOutboxRetryInterval,
+		clock:         xdb.SystemClock,
+	}
+}
+
+// WithTable sets the name of the table in schema.name format
+func (o *Outbox) WithTable(table string) *Outbox {
+	o.table = table
+	return o
+}
+
+// WithPublisher sets the function to publish the events
+func (o *Outbox) WithPublisher(fn PublishFunc) *Outbox {
+	o.publish = fn
+	return o
+}
+
+// WithNotifier publishes the events with the notifier,
+// the topic of the event is used as the channel
+func (o *Outbox) WithNotifier(n Notifier) *Outbox {
+	return o.WithPublisher(func(ctx context.Context, e *OutboxEvent) error {
+		return n.Notify(ctx, e.Topic, e.Payload)
+	})
+}
+
+// WithMaxAttempts sets how many times an event is published,
+// before it's moved to the dead letters
+func (o *Outbox) WithMaxAttempts(attempts int32) *Outbox {
+	o.maxAttempts = attempts
+	return o
+}
+
+// WithBatchSize sets how many events are published per poll
+func (o *Outbox) WithBatchSize(size int) *Outbox {
+	o.batchSize = size
+	return o
+}
+
+// WithPollInterval sets how often the outbox is polled
+func (o *Outbox) WithPollInterval(interval time.Duration) *Outbox {
+	o.pollInterval = interval
+	return o
+}
+
+// WithRetryInterval sets the delay before the first retry
+func (o *Outbox) WithRetryInterval(interval time.Duration) *Outbox {
+	o.retryInterval = interval
+	return o
+}
+
+// WithClock sets the clock, tests can inject xdb.FixedClock
+func (o *Outbox) WithClock(clock xdb.Clock) *Outbox {
+	o.clock = clock
+	return o
+}
+
+// TableName returns the name of the table
+func (o *Outbox) TableName() string {
+	return o.table
+}
+
+// SQLDialect returns the dialect of the provider
+func (o *Outbox) SQLDialect() xsql.SQLDialect {
+	return xsql.DialectFor(o.p.Name())
+}
+
+// MigrationUp returns the statement to create the table
+func (o *Outbox) MigrationUp() string {
+	var topicType, textType, timeType, boolType string
+	switch o.p.Name() {
+	case "postgres":
+		topicType, textType, timeType, boolType = "varchar(64)", "text", "timestamp with time zone", "boolean"
+	case "sqlserver":
+		topicType, textType, timeType, boolType = "nvarchar(64)", "nvarchar(max)", "datetime2", "bit"
+	case "mysql":
+		topicType, textType, timeType, boolType = "varchar(64)", "text", "datetime(6)", "boolean"
+	default:
+		topicType, textType, timeType, boolType = "text", "text", "timestamp", "boolean"
+	}
+	return fmt.Sprintf(`CREATE TABLE %[1]s (
+	id bigint NOT NULL PRIMARY KEY,
+	topic %[2]s NOT NULL,
+	payload %[3]s NOT NULL,
+	attempts integer NOT NULL,
+	next_attempt_at %[4]s NOT NULL,
+	last_error %[3]s NULL,
+	dead %[5]s NOT NULL,
+	created_at %[4]s NOT NULL
+);
+CREATE INDEX %[6]s_next_attempt_at ON %[1]s (dead, next_attempt_at);`,
+		o.table, topicType, textType, timeType, boolType, o.table[strings.LastIndex(o.table, ".")+1:])
+}
+
+// MigrationDown returns the statement to drop the table
+func (o *Outbox) MigrationDown() string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", o.table)
+}
+
+/*
+Enqueue records the event inside the caller's transaction,
+so the event is committed or rolled back with the operation:
+
+	tx, err := p.BeginTx(ctx, nil)
+	...
+	if _, err = ob.Enqueue(ctx, tx, "orders", payload); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+Returns the ID of the event.
+*/
+func (o *Outbox) Enqueue(ctx context.Context, tx xdb.Provider, topic string, payload values.MapAny) (xdb.ID, error) {
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return xdb.ID{}, errors.Wrapf(err, "failed to encode payload")
+	}
+
+	id := o.p.NextID()
+	now := o.clock.Now().UTC()
+	_, err = o.SQLDialect().InsertInto(o.table).
+		Set("id", id).
+		Set("topic", topic).
+		Set("payload", string(js)).
+		Set("attempts", 0).
+		Set("next_attempt_at", now).
+		Set("dead", false).
+		Set("created_at", now).
+		ExecAndClose(ctx, tx)
+	if err != nil {
+		return xdb.ID{}, errors.WithMessagef(err, "failed to enqueue event: %s", topic)
+	}
+	return id, nil
+}
+
+// Run polls the outbox and publishes the events until the context is done
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := o.Dispatch(ctx)
+		if err != nil {
+			logger.KV(xlog.ERROR,
+				"reason", "dispatch",
+				"table", o.table,
+				"err", err.Error())
+		}
+		// the full batch indicates more pending events
+		if err == nil && n >= o.batchSize && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch publishes a batch of the due events in a transaction,
+// and returns the number of processed events.
+// The published events are deleted, the failed events are scheduled for retry.
+func (o *Outbox) Dispatch(ctx context.Context) (int, error) {
+	if o.publish == nil {
+		return 0, errors.New("outbox publisher is not specified")
+	}
+
+	var count int
+	err := o.p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		dialect := o.SQLDialect()
+		q := o.selectEvents(o.batchSize).
+			Where("dead = ?", false).
+			Where("next_attempt_at <= ?", o.clock.Now().UTC()).
+			OrderBy("id")
+		if dialect.Capabilities().SkipLocked {
+			// the concurrent dispatchers skip the events locked by this one,
+			// WITH (UPDLOCK, ROWLOCK, READPAST) on SQL Server
+			q.ForUpdate(xsql.SkipLocked())
+		}
+		list, err := o.list(ctx, tx, q)
+		if err != nil {
+			return err
+		}
+
+		count = 0
+		for _, e := range list {
+			if err = o.publish(ctx, e); err != nil {
+				err = o.retry(ctx, tx, e, err)
+			} else {
+				_, err = dialect.DeleteFrom(o.table).
+					Where("id = ?", e.ID).
+					ExecAndClose(ctx, tx)
+				err = errors.WithMessagef(err, "failed to delete event: %s", e.ID)
+			}
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// retry schedules the failed event for retry, or moves it to the dead letters
+func (o *Outbox) retry(ctx context.Context, tx xdb.Provider, e *OutboxEvent, publishErr error) error {
+	e.Attempts++
+	e.LastError = xdb.NULLString(publishErr.Error())
+	e.Dead = e.Attempts >= o.maxAttempts
+	e.NextAttemptAt = o.clock.Now().UTC().Add(o.retryInterval << min(e.Attempts-1, maxRetryShift))
+
+	level := xlog.WARNING
+	if e.Dead {
+		level = xlog.ERROR
+	}
+	logger.KV(level,
+		"reason", "publish",
+		"topic", e.Topic,
+		"id", e.ID,
+		"attempts", e.Attempts,
+		"dead", e.Dead,
+		"err", publishErr.Error())
+
+	_, err := o.SQLDialect().Update(o.table).
+		Set("attempts", e.Attempts).
+		Set("next_attempt_at", e.NextAttemptAt).
+		Set("last_error", e.LastError).
+		Set("dead", e.Dead).
+		Where("id = ?", e.ID).
+		ExecAndClose(ctx, tx)
+	return errors.WithMessagef(err, "failed to update event: %s", e.ID)
+}
+
+// DeadLetters returns the events that exceeded the maximum number of attempts
+func (o *Outbox) DeadLetters(ctx context.Context, limit int) ([]*OutboxEvent, error) {
+	q := o.selectEvents(limit).
+		Where("dead = ?", true).
+		OrderBy("id")
+	return o.list(ctx, o.p, q)
+}
+
+// Requeue resets the attempts of the dead letter to publish it again
+func (o *Outbox) Requeue(ctx context.Context, id xdb.ID) error {
+	_, err := o.SQLDialect().Update(o.table).
+		Set("attempts", 0).
+		Set("next_attempt_at", o.clock.Now().UTC()).
+		Set("dead", false).
+		Where("id = ?", id).
+		ExecAndClose(ctx, o.p)
+	return errors.WithMessagef(err, "failed to requeue event: %s", id)
+}
+
+// selectEvents returns SELECT statement of the events limited to the number of rows,
+// rendered as TOP on SQL Server
+func (o *Outbox) selectEvents(limit int) xsql.Builder {
+	dialect := o.SQLDialect()
+	if dialect.Provider() == "sqlserver" {
+		return dialect.From(o.table).Select("TOP (?) "+outboxColumns, limit)
+	}
+	return dialect.From(o.table).Select(outboxColumns).Limit(limit)
+}
+
+func (o *Outbox) list(ctx context.Context, db xdb.DB, q xsql.Builder) ([]*OutboxEvent, error) {
+	defer q.Close()
+
+	rows, err := db.QueryContext(ctx, q.String(), q.Args()...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query events")
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var list []*OutboxEvent
+	for rows.Next() {
+		e := new(OutboxEvent)
+		var payload string
+		err = rows.Scan(&e.ID, &e.Topic, &payload, &e.Attempts, &e.NextAttemptAt, &e.LastError, &e.Dead, &e.CreatedAt)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err = json.Unmarshal([]byte(payload), &e.Payload); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode payload: %s", e.ID)
+		}
+		list = append(list, e)
+	}
+	return list, errors.WithStack(rows.Err())
+}
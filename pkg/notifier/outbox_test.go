@@ -0,0 +1,201 @@
+package notifier_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/notifier"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+	clock := xdb.NewFixedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var published []string
+	fail := true
+	ob := notifier.NewOutbox(p).
+		WithClock(clock).
+		WithMaxAttempts(2).
+		WithPublisher(func(_ context.Context, e *notifier.OutboxEvent) error {
+			if fail && e.Topic == "failed" {
+				return errors.New("unavailable")
+			}
+			published = append(published, e.Topic+":"+e.Payload.String("name"))
+			return nil
+		})
+	_, err := p.ExecContext(ctx, ob.MigrationUp())
+	require.NoError(t, err)
+
+	_, err = notifier.NewOutbox(p).Dispatch(ctx)
+	assert.EqualError(t, err, "outbox publisher is not specified")
+
+	// the event is rolled back with the operation
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		_, err := ob.Enqueue(ctx, tx, "orders", values.MapAny{"name": "rolled back"})
+		require.NoError(t, err)
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		if _, err := ob.Enqueue(ctx, tx, "orders", values.MapAny{"name": "first"}); err != nil {
+			return err
+		}
+		_, err := ob.Enqueue(ctx, tx, "failed", values.MapAny{"name": "second"})
+		return err
+	})
+	require.NoError(t, err)
+
+	n, err := ob.Dispatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"orders:first"}, published)
+
+	// the failed event is not due before the retry interval
+	n, err = ob.Dispatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	clock.Add(notifier.DefaultOutboxRetryInterval)
+	n, err = ob.Dispatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	dead, err := ob.DeadLetters(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "failed", dead[0].Topic)
+	assert.Equal(t, int32(2), dead[0].Attempts)
+	assert.Equal(t, "unavailable", string(dead[0].LastError))
+	assert.Equal(t, "second", dead[0].Payload.String("name"))
+
+	// the dead letters are not dispatched
+	clock.Add(time.Hour)
+	n, err = ob.Dispatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	fail = false
+	require.NoError(t, ob.Requeue(ctx, dead[0].ID))
+	n, err = ob.Dispatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"orders:first", "failed:second"}, published)
+
+	dead, err = ob.DeadLetters(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+}
+
+func TestOutboxRun(t *testing.T) {
+	p := openSQLite(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan string, 1)
+	ob := notifier.NewOutbox(p).
+		WithPollInterval(10 * time.Millisecond).
+		WithPublisher(func(_ context.Context, e *notifier.OutboxEvent) error {
+			done <- e.Topic
+			return nil
+		})
+	_, err := p.ExecContext(ctx, ob.MigrationUp())
+	require.NoError(t, err)
+
+	stopped := make(chan struct{})
+	go func() {
+		ob.Run(ctx)
+		close(stopped)
+	}()
+
+	_, err = ob.Enqueue(ctx, p, "orders", values.MapAny{"id": 1})
+	require.NoError(t, err)
+
+	select {
+	case topic := <-done:
+		assert.Equal(t, "orders", topic)
+	case <-ctx.Done():
+		t.Fatal("event is not published")
+	}
+
+	cancel()
+	<-stopped
+}
+
+// dialectProvider records the queries rendered for the dialect of the provider,
+// the queries are not executed
+type dialectProvider struct {
+	xdb.Provider
+	name    string
+	queries []string
+	args    [][]any
+}
+
+func (p *dialectProvider) Name() string {
+	return p.name
+}
+
+func (p *dialectProvider) WithTx(ctx context.Context, _ *sql.TxOptions, fn xdb.TxFunc) error {
+	return fn(ctx, p)
+}
+
+func (p *dialectProvider) QueryContext(_ context.Context, query string, args ...any) (*sql.Rows, error) {
+	p.queries = append(p.queries, query)
+	// the arguments are reused by the statement
+	p.args = append(p.args, append([]any(nil), args...))
+	return nil, errors.New("not executed")
+}
+
+func TestOutboxDialects(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tcases := []struct {
+		provider string
+		claim    string
+		dead     string
+		args     []any
+	}{
+		{
+			provider: "postgres",
+			claim:    `^SELECT id, topic, .+\s+FROM xdb_outbox\s+WHERE dead = \$1 AND next_attempt_at <= \$2\s+ORDER BY id\s+LIMIT \$3\s+FOR UPDATE SKIP LOCKED$`,
+			dead:     `^SELECT id, topic, .+\s+FROM xdb_outbox\s+WHERE dead = \$1\s+ORDER BY id\s+LIMIT \$2$`,
+			args:     []any{false, now, 5},
+		},
+		{
+			provider: "sqlserver",
+			claim:    `^SELECT TOP \(\?\) id, topic, .+\s+FROM xdb_outbox WITH \(UPDLOCK, ROWLOCK, READPAST\)\s+WHERE dead = \? AND next_attempt_at <= \?\s+ORDER BY id$`,
+			dead:     `^SELECT TOP \(\?\) id, topic, .+\s+FROM xdb_outbox\s+WHERE dead = \?\s+ORDER BY id$`,
+			args:     []any{5, false, now},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.provider, func(t *testing.T) {
+			p := &dialectProvider{name: tc.provider}
+			ob := notifier.NewOutbox(p).
+				WithClock(xdb.NewFixedClock(now)).
+				WithBatchSize(5).
+				WithPublisher(func(context.Context, *notifier.OutboxEvent) error {
+					return nil
+				})
+
+			_, err := ob.Dispatch(ctx)
+			assert.EqualError(t, err, "failed to query events: not executed")
+			_, err = ob.DeadLetters(ctx, 10)
+			assert.EqualError(t, err, "failed to query events: not executed")
+
+			require.Len(t, p.queries, 2)
+			assert.Regexp(t, tc.claim, p.queries[0])
+			assert.Equal(t, tc.args, p.args[0])
+			assert.Regexp(t, tc.dead, p.queries[1])
+		})
+	}
+}
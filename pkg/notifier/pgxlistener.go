@@ -0,0 +1,252 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+var errListenerClosed = errors.New("listener is closed")
+
+// pgxConn is the subset of pgx.Conn used by the listener
+type pgxConn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	IsClosed() bool
+	Close(ctx context.Context) error
+}
+
+// pgxCommand is LISTEN or UNLISTEN command executed by the connection loop
+type pgxCommand struct {
+	sql    string
+	result chan error
+}
+
+// pgxListener implements Listener with pgx native LISTEN support.
+// The connection is owned by one goroutine, that waits for the notifications,
+// and is interrupted to execute LISTEN and UNLISTEN commands.
+// After a reconnect, LISTEN is issued for all channels with callbacks.
+type pgxListener struct {
+	dispatcher
+
+	connect              func(ctx context.Context) (pgxConn, error)
+	minReconnectInterval time.Duration
+	maxReconnectInterval time.Duration
+
+	cmds      chan *pgxCommand
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newPgxListener(connString string, minReconnectInterval, maxReconnectInterval time.Duration) *pgxListener {
+	return startPgxListener(func(ctx context.Context) (pgxConn, error) {
+		return pgx.Connect(ctx, connString)
+	}, minReconnectInterval, maxReconnectInterval)
+}
+
+func startPgxListener(connect func(ctx context.Context) (pgxConn, error), minReconnectInterval, maxReconnectInterval time.Duration) *pgxListener {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &pgxListener{
+		connect:              connect,
+		minReconnectInterval: minReconnectInterval,
+		maxReconnectInterval: maxReconnectInterval,
+		cmds:                 make(chan *pgxCommand),
+		cancel:               cancel,
+		done:                 make(chan struct{}),
+	}
+	l.dispatcher = newDispatcher(pgxChannels{l: l})
+
+	go l.run(ctx)
+	return l
+}
+
+// Close closes the connection and stops the listener
+func (l *pgxListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.cancel()
+		<-l.done
+	})
+	return nil
+}
+
+// Listen calls the callback for each notification of the topic,
+// until the context expires, see Listener interface
+func (l *pgxListener) Listen(ctx context.Context, topic string, callback func(n *Notification)) error {
+	select {
+	case <-l.done:
+		return errListenerClosed
+	default:
+	}
+	return l.listen(ctx, topic, callback)
+}
+
+// pgxChannels implements channelConn with the commands
+// executed by the connection loop of the listener
+type pgxChannels struct {
+	l *pgxListener
+}
+
+func (c pgxChannels) Listen(channel string) error {
+	return c.l.exec("LISTEN " + pgx.Identifier{channel}.Sanitize())
+}
+
+func (c pgxChannels) Unlisten(channel string) error {
+	return c.l.exec("UNLISTEN " + pgx.Identifier{channel}.Sanitize())
+}
+
+func (l *pgxListener) exec(sql string) error {
+	cmd := &pgxCommand{
+		sql:    sql,
+		result: make(chan error, 1),
+	}
+	select {
+	case l.cmds <- cmd:
+	case <-l.done:
+		return errListenerClosed
+	}
+	select {
+	case err := <-cmd.result:
+		return err
+	case <-l.done:
+		return errListenerClosed
+	}
+}
+
+// run connects and dispatches the notifications until the listener is closed
+func (l *pgxListener) run(ctx context.Context) {
+	defer close(l.done)
+
+	backoff := l.minReconnectInterval
+	for {
+		conn, err := l.dial(ctx)
+		if err == nil {
+			logger.KV(xlog.DEBUG, "event", "connected")
+			backoff = l.minReconnectInterval
+			err = l.serve(ctx, conn)
+			_ = conn.Close(context.Background())
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		logger.KV(xlog.ERROR,
+			"event", "disconnected",
+			"retry_in", backoff.String(),
+			"error", err.Error())
+
+		if !l.sleep(ctx, backoff) {
+			return
+		}
+		backoff *= 2
+		if backoff > l.maxReconnectInterval {
+			backoff = l.maxReconnectInterval
+		}
+	}
+}
+
+// dial connects to the database, the commands are acknowledged
+// while connecting, as the channels are listened after connect
+func (l *pgxListener) dial(ctx context.Context) (pgxConn, error) {
+	type result struct {
+		conn pgxConn
+		err  error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		conn, err := l.connect(ctx)
+		resc <- result{conn: conn, err: err}
+	}()
+	for {
+		select {
+		case r := <-resc:
+			return r.conn, r.err
+		case cmd := <-l.cmds:
+			cmd.result <- nil
+		}
+	}
+}
+
+// sleep waits for the reconnect interval, the commands are acknowledged
+// while disconnected, as the channels are listened after the reconnect
+func (l *pgxListener) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case cmd := <-l.cmds:
+			cmd.result <- nil
+		}
+	}
+}
+
+// serve listens the channels and dispatches the notifications,
+// until the connection is lost or the context is done
+func (l *pgxListener) serve(ctx context.Context, conn pgxConn) error {
+	for _, channel := range l.channels() {
+		_, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+		if err != nil {
+			return errors.Wrapf(err, "failed to listen to channel: %s", channel)
+		}
+	}
+
+	for {
+		n, cmd, err := l.wait(ctx, conn)
+		if n != nil {
+			l.dispatch(newNotification(n.Channel, n.Payload))
+		}
+		if cmd != nil {
+			_, cerr := conn.Exec(ctx, cmd.sql)
+			cmd.result <- cerr
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if conn.IsClosed() {
+			if err == nil {
+				err = errors.New("connection closed")
+			}
+			return err
+		}
+		if err != nil && cmd == nil {
+			return err
+		}
+	}
+}
+
+// wait waits for a notification, the wait is interrupted by a command
+func (l *pgxListener) wait(ctx context.Context, conn pgxConn) (*pgconn.Notification, *pgxCommand, error) {
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	received := make(chan *pgxCommand, 1)
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case cmd := <-l.cmds:
+			received <- cmd
+			cancel()
+		case <-waitCtx.Done():
+		}
+	}()
+
+	n, err := conn.WaitForNotification(waitCtx)
+	cancel()
+	<-exited
+
+	var cmd *pgxCommand
+	select {
+	case cmd = <-received:
+	default:
+	}
+	return n, cmd, err
+}
@@ -0,0 +1,59 @@
+package print
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb/migrate"
+	"github.com/olekukonko/tablewriter"
+)
+
+// MigrationStatus prints the migration state of the databases
+func MigrationStatus(w io.Writer, r []*migrate.Status) {
+	table := tablewriter.NewWriter(w)
+	table.SetBorder(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Name", "Database", "Version", "Latest", "Pending", "Dirty", "Error"})
+	table.SetHeaderLine(true)
+
+	for _, s := range r {
+		table.Append([]string{
+			s.Name,
+			s.Database,
+			fmt.Sprintf("%d", s.Version),
+			fmt.Sprintf("%d", s.Latest),
+			fmt.Sprintf("%d", s.Pending),
+			values.Select(s.Dirty, "YES", ""),
+			s.Error,
+		})
+	}
+
+	table.Render()
+	fmt.Fprintln(w)
+}
+
+// Migrations prints the migrations of the database
+func Migrations(w io.Writer, r *migrate.Status) {
+	fmt.Fprintf(w, "Database: %s\nVersion: %d%s\nPending: %d\n\n",
+		r.Database, r.Version, values.Select(r.Dirty, " (dirty)", ""), r.Pending)
+
+	table := tablewriter.NewWriter(w)
+	table.SetBorder(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Version", "Name", "Applied"})
+	table.SetHeaderLine(true)
+
+	for _, m := range r.Migrations {
+		table.Append([]string{
+			fmt.Sprintf("%d", m.Version),
+			m.Name,
+			values.Select(m.Applied, "YES", ""),
+		})
+	}
+
+	table.Render()
+	fmt.Fprintln(w)
+}
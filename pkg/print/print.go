@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 
+	"github.com/effective-security/xdb/migrate"
 	"github.com/effective-security/xdb/schema"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
@@ -55,6 +56,12 @@ func Print(w io.Writer, value any) {
 		SchemaForeingKeys(w, t)
 	case schema.Indexes:
 		SchemaIndexes(w, t)
+	case []*migrate.Status:
+		MigrationStatus(w, t)
+	case *migrate.Status:
+		Migrations(w, t)
+	case *QueryResult:
+		QueryRows(w, t)
 
 	default:
 		_ = JSON(w, value)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/effective-security/xdb/migrate"
 	"github.com/effective-security/xdb/pkg/print"
 	"github.com/effective-security/xdb/schema"
 	"github.com/stretchr/testify/assert"
@@ -112,6 +113,30 @@ Table: test
   0   | ID   | uint64 | int8    |      |     |       |      
   0   | Name | string | varchar | YES  | 255 |       |      
 
+`,
+		)
+	})
+
+	t.Run("Comments", func(t *testing.T) {
+		o := schema.Table{
+			Name:    "test",
+			Schema:  "dbo",
+			Comment: "test table",
+			Columns: schema.Columns{
+				{Name: "ID", Type: "uint64", UdtType: "int8"},
+				{Name: "Name", Type: "string", UdtType: "varchar", Comment: "display\nname"},
+			},
+		}
+		checkEqual(t, &o,
+			`Schema: dbo
+Table: test
+Comment: test table
+
+  ORD | NAME |  TYPE  |   UDT   | NULL | MAX | INDEX | REF |   COMMENT     
+------+------+--------+---------+------+-----+-------+-----+---------------
+  0   | ID   | uint64 | int8    |      |     |       |     |               
+  0   | Name | string | varchar |      |     |       |     | display name  
+
 `,
 		)
 	})
@@ -151,3 +176,43 @@ Table: test
 `)
 	})
 }
+
+func TestPrintMigrationStatus(t *testing.T) {
+	o := []*migrate.Status{
+		{Name: "main", Database: "orgs", Version: 3, Latest: 3},
+		{Name: "analytics", Database: "analytics", Version: 1, Latest: 2, Pending: 1, Dirty: true},
+	}
+	checkEqual(t, o,
+		`    NAME    | DATABASE  | VERSION | LATEST | PENDING | DIRTY | ERROR  
+------------+-----------+---------+--------+---------+-------+--------
+  main      | orgs      | 3       | 3      | 0       |       |        
+  analytics | analytics | 1       | 2      | 1       | YES   |        
+
+`,
+	)
+}
+
+func TestPrintMigrations(t *testing.T) {
+	o := &migrate.Status{
+		Database: "orgs",
+		Version:  1,
+		Latest:   2,
+		Pending:  1,
+		Migrations: []*migrate.Migration{
+			{Version: 1, Name: "users", Applied: true},
+			{Version: 2, Name: "orgs"},
+		},
+	}
+	checkEqual(t, o,
+		`Database: orgs
+Version: 1
+Pending: 1
+
+  VERSION | NAME  | APPLIED  
+----------+-------+----------
+  1       | users | YES      
+  2       | orgs  |          
+
+`,
+	)
+}
@@ -0,0 +1,50 @@
+package print
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// QueryResult is the result of SQL query
+type QueryResult struct {
+	Columns []string
+	Rows    [][]any
+}
+
+// QueryRows prints the rows of the query result
+func QueryRows(w io.Writer, r *QueryResult) {
+	table := tablewriter.NewWriter(w)
+	table.SetBorder(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader(r.Columns)
+	table.SetHeaderLine(true)
+
+	for _, row := range r.Rows {
+		vals := make([]string, len(row))
+		for i, v := range row {
+			vals[i] = queryValue(v)
+		}
+		table.Append(vals)
+	}
+
+	table.Render()
+	fmt.Fprintf(w, "(%d rows)\n", len(r.Rows))
+}
+
+func queryValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(t)
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
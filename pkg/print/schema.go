@@ -18,13 +18,30 @@ func SchemaTables(w io.Writer, r schema.Tables) {
 
 // SchemaTable prints schema.Table
 func SchemaTable(w io.Writer, r *schema.Table) {
-	fmt.Fprintf(w, "Schema: %s\nTable: %s\n\n", r.Schema, r.Name)
+	fmt.Fprintf(w, "Schema: %s\nTable: %s\n", r.Schema, r.Name)
+	if r.Comment != "" {
+		fmt.Fprintf(w, "Comment: %s\n", r.Comment)
+	}
+	fmt.Fprintln(w)
+
+	// the comments are printed only if described in the database
+	withComment := false
+	for _, c := range r.Columns {
+		if c.Comment != "" {
+			withComment = true
+			break
+		}
+	}
 
 	table := tablewriter.NewWriter(w)
 	table.SetBorder(false)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 	table.SetAutoWrapText(false)
-	table.SetHeader([]string{"Ord", "Name", "Type", "UDT", "NULL", "Max", "Index", "Ref"})
+	header := []string{"Ord", "Name", "Type", "UDT", "NULL", "Max", "Index", "Ref"}
+	if withComment {
+		header = append(header, "Comment")
+	}
+	table.SetHeader(header)
 	table.SetHeaderLine(true)
 
 	for _, c := range r.Columns {
@@ -38,7 +55,7 @@ func SchemaTable(w io.Writer, r *schema.Table) {
 			ref = c.Ref.RefColumnSchemaName()
 		}
 
-		table.Append([]string{
+		row := []string{
 			fmt.Sprintf("%d", c.Position),
 			c.Name,
 			c.Type,
@@ -47,7 +64,11 @@ func SchemaTable(w io.Writer, r *schema.Table) {
 			maxL,
 			values.Select(c.IsIndex(), "YES", ""),
 			ref,
-		})
+		}
+		if withComment {
+			row = append(row, strings.ReplaceAll(c.Comment, "\n", " "))
+		}
+		table.Append(row)
 	}
 
 	table.Render()
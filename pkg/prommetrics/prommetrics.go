@@ -0,0 +1,107 @@
+// Package prommetrics provides Prometheus metrics of xdb providers
+package prommetrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultNamespace is the default namespace of the metrics
+const DefaultNamespace = "xdb"
+
+// unnamed is the label of statements without name
+const unnamed = "unnamed"
+
+// Metrics implements xdb.MetricsRegistrar with Prometheus collectors
+type Metrics struct {
+	queries  *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	open     *prometheus.GaugeVec
+	inUse    *prometheus.GaugeVec
+	idle     *prometheus.GaugeVec
+	waitCnt  *prometheus.GaugeVec
+	waitTime *prometheus.GaugeVec
+}
+
+var _ xdb.MetricsRegistrar = (*Metrics)(nil)
+
+/*
+New creates the metrics, and registers the collectors with the registerer:
+
+	m, err := prommetrics.New(prometheus.DefaultRegisterer, "")
+	...
+	p.WithMetrics(m, 0)
+
+Empty namespace uses DefaultNamespace.
+*/
+func New(reg prometheus.Registerer, namespace string) (*Metrics, error) {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	pool := []string{"provider", "pool"}
+	gauge := func(name, help string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "pool",
+			Name:      name,
+			Help:      help,
+		}, pool)
+	}
+
+	m := &Metrics{
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "queries_total",
+			Help:      "Number of executed statements by name and status.",
+		}, []string{"provider", "pool", "name", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "query_duration_seconds",
+			Help:      "Latency of executed statements by name.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+		}, []string{"provider", "pool", "name"}),
+		open:     gauge("open_connections", "Number of established connections, in use and idle."),
+		inUse:    gauge("in_use_connections", "Number of connections currently in use."),
+		idle:     gauge("idle_connections", "Number of idle connections."),
+		waitCnt:  gauge("wait_count", "Total number of connections waited for."),
+		waitTime: gauge("wait_duration_seconds", "Total time blocked waiting for a new connection."),
+	}
+
+	for _, c := range []prometheus.Collector{m.queries, m.latency, m.open, m.inUse, m.idle, m.waitCnt, m.waitTime} {
+		if err := reg.Register(c); err != nil {
+			return nil, errors.WithMessagef(err, "failed to register metrics")
+		}
+	}
+	return m, nil
+}
+
+// Queries returns the counter of executed statements
+func (m *Metrics) Queries() *prometheus.CounterVec {
+	return m.queries
+}
+
+// ObserveQuery records the latency and status of the statement
+func (m *Metrics) ObserveQuery(provider, pool, name string, duration time.Duration, err error) {
+	if name == "" {
+		name = unnamed
+	}
+	status := "ok"
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		status = "error"
+	}
+	m.queries.WithLabelValues(provider, pool, name, status).Inc()
+	m.latency.WithLabelValues(provider, pool, name).Observe(duration.Seconds())
+}
+
+// SetPoolStats records the stats of the connection pool
+func (m *Metrics) SetPoolStats(provider, pool string, stats sql.DBStats) {
+	m.open.WithLabelValues(provider, pool).Set(float64(stats.OpenConnections))
+	m.inUse.WithLabelValues(provider, pool).Set(float64(stats.InUse))
+	m.idle.WithLabelValues(provider, pool).Set(float64(stats.Idle))
+	m.waitCnt.WithLabelValues(provider, pool).Set(float64(stats.WaitCount))
+	m.waitTime.WithLabelValues(provider, pool).Set(stats.WaitDuration.Seconds())
+}
@@ -0,0 +1,56 @@
+package prommetrics_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/prommetrics"
+	"github.com/effective-security/xdb/xsql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+	m, err := prommetrics.New(reg, "")
+	require.NoError(t, err)
+
+	_, err = prommetrics.New(reg, "")
+	assert.ErrorContains(t, err, "failed to register metrics")
+
+	d, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	d.SetMaxOpenConns(1)
+	p, err := xdb.New("sqlite3", d, nil)
+	require.NoError(t, err)
+	defer p.Close()
+	p.WithMetrics(m, time.Hour)
+
+	_, err = p.ExecContext(xdb.WithStatementName(ctx, "t.create"), "CREATE TABLE t (id INTEGER)")
+	require.NoError(t, err)
+
+	q := xsql.NoDialect.From("t").Select("id").Where("id = ?", 1).SetName("t.get")
+	defer q.Close()
+	var id int
+	err = p.QueryRowContext(ctx, q.String(), q).Scan(&id)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	_, err = p.QueryContext(ctx, "SELECT id FROM missing")
+	require.Error(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.Queries().WithLabelValues("sqlite3", "", "t.create", "ok")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.Queries().WithLabelValues("sqlite3", "", "t.get", "ok")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.Queries().WithLabelValues("sqlite3", "", "unnamed", "error")))
+
+	m.SetPoolStats("sqlite3", "", sql.DBStats{OpenConnections: 2, InUse: 1, Idle: 1, WaitCount: 5})
+	count, err := testutil.GatherAndCount(reg, "xdb_pool_open_connections", "xdb_query_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
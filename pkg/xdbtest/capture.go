@@ -0,0 +1,229 @@
+package xdbtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+// CapturedQuery describes the captured statement
+type CapturedQuery struct {
+	// Name of the statement, set by xsql.Builder.SetName or xdb.WithStatementName
+	Name string
+	SQL  string
+	Args []any
+}
+
+// String returns the statement description for test logs
+func (q CapturedQuery) String() string {
+	name := q.Name
+	if name == "" {
+		name = "<unnamed>"
+	}
+	return fmt.Sprintf("%s: %s %v", name, q.SQL, q.Args)
+}
+
+type recorder struct {
+	lock    sync.Mutex
+	queries []CapturedQuery
+}
+
+// record stores the statement, the args are copied,
+// as the args of xsql.Builder are reused by the pool after Close
+func (r *recorder) record(ctx context.Context, query string, args []any) {
+	q := CapturedQuery{
+		Name: xdb.StatementName(ctx),
+		SQL:  query,
+		Args: append([]any(nil), args...),
+	}
+	if len(args) == 1 {
+		if b, ok := args[0].(xsql.Builder); ok {
+			q.SQL = b.String()
+			q.Args = append([]any(nil), b.Args()...)
+			if q.Name == "" {
+				q.Name = b.Name()
+			}
+		}
+	}
+
+	r.lock.Lock()
+	r.queries = append(r.queries, q)
+	r.lock.Unlock()
+}
+
+// Capture is the Provider that records the statements executed by the wrapped Provider,
+// including the statements executed in transactions started by BeginTx and WithSnapshot.
+type Capture struct {
+	xdb.Provider
+
+	t   testing.TB
+	rec *recorder
+}
+
+// CaptureQueries returns the Provider that records all statements executed during the test,
+// the code under test must use the returned Provider.
+// The captured statements are logged if the test fails.
+func CaptureQueries(t testing.TB, provider xdb.Provider) *Capture {
+	c := &Capture{
+		Provider: provider,
+		t:        t,
+		rec:      &recorder{},
+	}
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("captured queries:\n%s", c.dump())
+		}
+	})
+	return c
+}
+
+// Queries returns the captured statements in the order of execution
+func (c *Capture) Queries() []CapturedQuery {
+	c.rec.lock.Lock()
+	defer c.rec.lock.Unlock()
+	return append([]CapturedQuery(nil), c.rec.queries...)
+}
+
+// Reset clears the captured statements
+func (c *Capture) Reset() {
+	c.rec.lock.Lock()
+	c.rec.queries = nil
+	c.rec.lock.Unlock()
+}
+
+// ExpectQuery returns the expectation for the statements with the name
+func (c *Capture) ExpectQuery(name string) *CaptureExpectation {
+	return &CaptureExpectation{c: c, name: name}
+}
+
+// QueryContext records the statement and executes it by the wrapped Provider
+func (c *Capture) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	c.rec.record(ctx, query, args)
+	return c.Provider.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext records the statement and executes it by the wrapped Provider
+func (c *Capture) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	c.rec.record(ctx, query, args)
+	return c.Provider.QueryRowContext(ctx, query, args...)
+}
+
+// ExecContext records the statement and executes it by the wrapped Provider
+func (c *Capture) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	c.rec.record(ctx, query, args)
+	return c.Provider.ExecContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction, the statements executed in the transaction are captured
+func (c *Capture) BeginTx(ctx context.Context, opts *sql.TxOptions) (xdb.Provider, error) {
+	tx, err := c.Provider.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Capture{Provider: tx, t: c.t, rec: c.rec}, nil
+}
+
+// WithTx runs fn in a transaction, the statements executed in the transaction are captured
+func (c *Capture) WithTx(ctx context.Context, opts *sql.TxOptions, fn xdb.TxFunc) error {
+	return c.Provider.WithTx(ctx, opts, func(ctx context.Context, tx xdb.Provider) error {
+		return fn(ctx, &Capture{Provider: tx, t: c.t, rec: c.rec})
+	})
+}
+
+// WithSnapshot begins a snapshot transaction, the statements executed in the snapshot are captured
+func (c *Capture) WithSnapshot(ctx context.Context) (context.Context, xdb.Provider, error) {
+	ctx, tx, err := c.Provider.WithSnapshot(ctx)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return ctx, &Capture{Provider: tx, t: c.t, rec: c.rec}, nil
+}
+
+func (c *Capture) dump() string {
+	var sb strings.Builder
+	for i, q := range c.Queries() {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, q.String())
+	}
+	return sb.String()
+}
+
+// CaptureExpectation asserts the captured statements with the name
+type CaptureExpectation struct {
+	c    *Capture
+	name string
+	args []any
+}
+
+// WithArg narrows the expectation to the statements with all the args
+func (e *CaptureExpectation) WithArg(args ...any) *CaptureExpectation {
+	e.args = append(e.args, args...)
+	return e
+}
+
+// Count returns the number of matching statements
+func (e *CaptureExpectation) Count() int {
+	count := 0
+	for _, q := range e.c.Queries() {
+		if e.matches(q) {
+			count++
+		}
+	}
+	return count
+}
+
+// Times asserts the number of matching statements
+func (e *CaptureExpectation) Times(n int) bool {
+	e.c.t.Helper()
+	count := e.Count()
+	if count == n {
+		return true
+	}
+	e.c.t.Errorf("expected %d executions of %s, got %d", n, e.String(), count)
+	return false
+}
+
+// Once asserts the statement was executed once
+func (e *CaptureExpectation) Once() bool {
+	e.c.t.Helper()
+	return e.Times(1)
+}
+
+// Never asserts the statement was not executed
+func (e *CaptureExpectation) Never() bool {
+	e.c.t.Helper()
+	return e.Times(0)
+}
+
+// String returns the expectation description
+func (e *CaptureExpectation) String() string {
+	if len(e.args) == 0 {
+		return fmt.Sprintf("%q", e.name)
+	}
+	return fmt.Sprintf("%q with args %v", e.name, e.args)
+}
+
+func (e *CaptureExpectation) matches(q CapturedQuery) bool {
+	if q.Name != e.name {
+		return false
+	}
+	for _, want := range e.args {
+		found := false
+		for _, arg := range q.Args {
+			if assert.ObjectsAreEqual(want, arg) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
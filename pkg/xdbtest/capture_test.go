@@ -0,0 +1,127 @@
+package xdbtest_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/xdbtest"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	// register sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openSQLite(t *testing.T) *xdb.SQLProvider {
+	t.Helper()
+	d, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// :memory: database is per connection
+	d.SetMaxOpenConns(1)
+
+	p, err := xdb.New("sqlite3", d, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = p.Close()
+	})
+	return p
+}
+
+// recordingT records the errors reported by expectations
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCaptureQueries(t *testing.T) {
+	ctx := context.Background()
+	p := xdbtest.CaptureQueries(t, openSQLite(t))
+	var _ xdb.Provider = p
+
+	_, err := p.ExecContext(xdb.WithStatementName(ctx, "user.create"), "CREATE TABLE user (id INTEGER, name TEXT)")
+	require.NoError(t, err)
+
+	tx, err := p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	for i, name := range []string{"alice", "bob"} {
+		_, err = tx.ExecContext(xdb.WithStatementName(ctx, "user.insert"), "INSERT INTO user VALUES (?, ?)", i+1, name)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit())
+
+	q := xsql.NoDialect.From("user").Select("name").Where("id = ?", 2).SetName("user.get")
+	defer q.Close()
+	var name string
+	require.NoError(t, p.QueryRowContext(ctx, q.String(), q).Scan(&name))
+	assert.Equal(t, "bob", name)
+
+	p.ExpectQuery("user.create").Once()
+	p.ExpectQuery("user.insert").Times(2)
+	p.ExpectQuery("user.insert").WithArg("alice").Once()
+	p.ExpectQuery("user.insert").WithArg(2, "bob").Once()
+	p.ExpectQuery("user.insert").WithArg("carol").Never()
+	p.ExpectQuery("user.get").WithArg(2).Once()
+	p.ExpectQuery("user.delete").Never()
+
+	list := p.Queries()
+	require.Len(t, list, 4)
+	assert.Equal(t, "user.get", list[3].Name)
+	assert.Equal(t, q.String(), list[3].SQL)
+	assert.Equal(t, []any{2}, list[3].Args)
+
+	p.Reset()
+	assert.Empty(t, p.Queries())
+	p.ExpectQuery("user.create").Never()
+
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		_, err := tx.ExecContext(xdb.WithStatementName(ctx, "user.insert"), "INSERT INTO user VALUES (?, ?)", 3, "carol")
+		return err
+	})
+	require.NoError(t, err)
+	p.ExpectQuery("user.insert").WithArg("carol").Once()
+
+	// the args are kept after the statement is closed
+	p.Reset()
+	_, err = xsql.NoDialect.InsertInto("user").
+		Set("id", 4).
+		Set("name", "dave").
+		SetName("user.insert").
+		ExecAndClose(ctx, p)
+	require.NoError(t, err)
+	err = xsql.NoDialect.From("user").
+		Select("name").
+		Where("id = ?", 4).
+		SetName("user.get").
+		To(&name).
+		QueryRowAndClose(ctx, p)
+	require.NoError(t, err)
+	assert.Equal(t, "dave", name)
+	p.ExpectQuery("user.insert").WithArg(4, "dave").Once()
+	p.ExpectQuery("user.get").WithArg(4).Once()
+}
+
+func TestCaptureQueriesFailure(t *testing.T) {
+	ctx := context.Background()
+	rt := &recordingT{TB: t}
+	p := xdbtest.CaptureQueries(rt, openSQLite(t))
+
+	var one int
+	require.NoError(t, p.QueryRowContext(xdb.WithStatementName(ctx, "one"), "SELECT 1").Scan(&one))
+
+	assert.False(t, p.ExpectQuery("one").Times(2))
+	assert.False(t, p.ExpectQuery("one").WithArg(1).Once())
+	assert.Equal(t, []string{
+		`expected 2 executions of "one", got 1`,
+		`expected 1 executions of "one" with args [1], got 0`,
+	}, rt.errors)
+}
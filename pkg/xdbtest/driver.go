@@ -0,0 +1,222 @@
+package xdbtest
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// connector opens the connections to the fake database
+type connector struct {
+	fake *Fake
+}
+
+// Connect implements driver.Connector
+func (c *connector) Connect(_ context.Context) (driver.Conn, error) {
+	return &conn{fake: c.fake}, nil
+}
+
+// Driver implements driver.Connector
+func (c *connector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+// fakeDriver is returned by connector.Driver, the connections are opened by connector
+type fakeDriver struct{}
+
+// Open implements driver.Driver
+func (fakeDriver) Open(_ string) (driver.Conn, error) {
+	return nil, errors.New("use xdbtest.New to open the fake database")
+}
+
+// conn is the connection to the fake database
+type conn struct {
+	fake *Fake
+}
+
+// Prepare implements driver.Conn
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+// Close implements driver.Conn
+func (c *conn) Close() error {
+	return nil
+}
+
+// Begin implements driver.Conn
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx
+func (c *conn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	e, err := c.fake.match(ctx, KindBegin, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if e != nil && e.err != nil {
+		return nil, e.err
+	}
+	return &tx{conn: c}, nil
+}
+
+// Ping implements driver.Pinger
+func (c *conn) Ping(_ context.Context) error {
+	return nil
+}
+
+// CheckNamedValue implements driver.NamedValueChecker,
+// the arguments are passed to the expectations as is
+func (c *conn) CheckNamedValue(_ *driver.NamedValue) error {
+	return nil
+}
+
+// QueryContext implements driver.QueryerContext
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.fake.match(ctx, KindQuery, query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &rows{columns: e.columns, rows: e.rows}, nil
+}
+
+// ExecContext implements driver.ExecerContext
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.fake.match(ctx, KindExec, query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.result == nil {
+		return result{}, nil
+	}
+	return e.result, nil
+}
+
+// values returns the values of the arguments
+func values(args []driver.NamedValue) []any {
+	list := make([]any, len(args))
+	for i, arg := range args {
+		list[i] = arg.Value
+	}
+	return list
+}
+
+// stmt is the prepared statement, executed by the connection
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+// Close implements driver.Stmt
+func (s *stmt) Close() error {
+	return nil
+}
+
+// NumInput implements driver.Stmt, the number of arguments is not checked
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+// Exec implements driver.Stmt
+func (s *stmt) Exec(_ []driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+
+// Query implements driver.Stmt
+func (s *stmt) Query(_ []driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
+
+// ExecContext implements driver.StmtExecContext
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+// QueryContext implements driver.StmtQueryContext
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+// tx is the transaction of the fake database
+type tx struct {
+	conn *conn
+}
+
+// Commit implements driver.Tx
+func (t *tx) Commit() error {
+	return t.end(KindCommit)
+}
+
+// Rollback implements driver.Tx
+func (t *tx) Rollback() error {
+	return t.end(KindRollback)
+}
+
+func (t *tx) end(kind string) error {
+	e, err := t.conn.fake.match(context.Background(), kind, "", nil)
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		return e.err
+	}
+	return nil
+}
+
+// rows iterates over the rows of the expectation
+type rows struct {
+	columns []string
+	rows    [][]any
+	pos     int
+}
+
+// Columns implements driver.Rows
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+// Close implements driver.Rows
+func (r *rows) Close() error {
+	return nil
+}
+
+// Next implements driver.Rows
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	row := normalize(r.rows[r.pos])
+	r.pos++
+	if len(row) != len(dest) {
+		return errors.Errorf("row %d has %d values, expected %d columns", r.pos, len(row), len(dest))
+	}
+	for i, v := range row {
+		dest[i] = v
+	}
+	return nil
+}
+
+// result is the result of the statement
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+// LastInsertId implements driver.Result
+func (r result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+// RowsAffected implements driver.Result
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
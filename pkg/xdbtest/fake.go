@@ -0,0 +1,276 @@
+/*
+Package xdbtest provides a fake database for unit tests,
+that returns the rows and results enqueued by the test,
+without a database server.
+
+The statements are matched by the statement name, see xdb.WithStatementName
+and xsql.Builder.SetName, or by the regular expression of SQL text:
+
+	fake := xdbtest.New("postgres")
+	p := fake.Provider()
+	defer p.Close()
+
+	fake.ExpectQuery("GetOrg").
+		WithArgs(1001).
+		WillReturnRows([]string{"id", "name"}, [][]any{{1001, "acme"}})
+	fake.ExpectExec(`^UPDATE public\.org`).
+		WillReturnResult(0, 1)
+
+	// the code under test uses p as xdb.Provider
+	...
+	require.NoError(t, fake.ExpectationsWereMet())
+
+The provider is xdb.SQLProvider over the fake driver,
+so it implements xdb.Provider and xdb.DB, including transactions.
+Begin, Commit and Rollback succeed, unless expected by ExpectBegin, ExpectCommit or ExpectRollback.
+
+CaptureQueries wraps a provider to record the executed statements,
+and to assert the number of executions of the named statements.
+
+For the integration tests, Start runs Postgres or SQL Server in a container,
+or connects to the provided server, and Server.Provider returns the migrated database for the test.
+*/
+package xdbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/effective-security/xdb"
+	"github.com/pkg/errors"
+)
+
+// The kinds of expected operations
+const (
+	KindQuery    = "query"
+	KindExec     = "exec"
+	KindBegin    = "begin"
+	KindCommit   = "commit"
+	KindRollback = "rollback"
+)
+
+// Expectation describes the expected operation and its result
+type Expectation struct {
+	kind  string
+	query string
+	re    *regexp.Regexp
+	args  []any
+	times int
+
+	columns []string
+	rows    [][]any
+	result  driver.Result
+	err     error
+
+	calls int
+}
+
+// WithArgs specifies the expected arguments of the statement,
+// the statement with other arguments does not match the expectation
+func (e *Expectation) WithArgs(args ...any) *Expectation {
+	e.args = normalize(args)
+	return e
+}
+
+// WillReturnRows specifies the columns and rows returned by the query
+func (e *Expectation) WillReturnRows(columns []string, rows [][]any) *Expectation {
+	e.columns = columns
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult specifies the result of the statement
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.result = result{lastInsertID: lastInsertID, rowsAffected: rowsAffected}
+	return e
+}
+
+// WillReturnError specifies the error returned by the operation
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Times specifies the number of times the expectation is matched, default is 1
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// String returns the description of the expectation
+func (e *Expectation) String() string {
+	s := e.kind
+	if e.query != "" {
+		s += " " + e.query
+	}
+	if e.args != nil {
+		s += fmt.Sprintf(" with args %v", e.args)
+	}
+	return s
+}
+
+// matches returns true if the operation matches the expectation
+func (e *Expectation) matches(kind, name, query string, args []any) bool {
+	if e.kind != kind || e.calls >= e.times {
+		return false
+	}
+	if e.query != "" && e.query != name && (e.re == nil || !e.re.MatchString(query)) {
+		return false
+	}
+	return e.args == nil || reflect.DeepEqual(e.args, args)
+}
+
+// Fake is a fake database, see package documentation
+type Fake struct {
+	name string
+
+	lock         sync.Mutex
+	expectations []*Expectation
+	unexpected   []string
+	provider     *xdb.SQLProvider
+}
+
+// New returns a fake database of the provider:
+// postgres, sqlserver, mysql or sqlite3
+func New(provider string) *Fake {
+	return &Fake{name: provider}
+}
+
+// Provider returns the provider over the fake database,
+// the provider is created on the first call
+func (f *Fake) Provider() *xdb.SQLProvider {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.provider == nil {
+		f.provider, _ = xdb.New(f.name, sql.OpenDB(&connector{fake: f}), nil)
+	}
+	return f.provider
+}
+
+// ExpectQuery adds the expectation of the query,
+// matched by the statement name or the regular expression of SQL text
+func (f *Fake) ExpectQuery(query string) *Expectation {
+	return f.expect(KindQuery, query)
+}
+
+// ExpectExec adds the expectation of the statement that does not return rows,
+// matched by the statement name or the regular expression of SQL text
+func (f *Fake) ExpectExec(query string) *Expectation {
+	return f.expect(KindExec, query)
+}
+
+// ExpectBegin adds the expectation of the transaction start
+func (f *Fake) ExpectBegin() *Expectation {
+	return f.expect(KindBegin, "")
+}
+
+// ExpectCommit adds the expectation of the transaction commit
+func (f *Fake) ExpectCommit() *Expectation {
+	return f.expect(KindCommit, "")
+}
+
+// ExpectRollback adds the expectation of the transaction rollback
+func (f *Fake) ExpectRollback() *Expectation {
+	return f.expect(KindRollback, "")
+}
+
+func (f *Fake) expect(kind, query string) *Expectation {
+	e := &Expectation{kind: kind, query: query, times: 1}
+	if query != "" {
+		// the query can be the statement name, that is not a valid expression
+		e.re, _ = regexp.Compile(query)
+	}
+	f.lock.Lock()
+	f.expectations = append(f.expectations, e)
+	f.lock.Unlock()
+	return e
+}
+
+// ExpectationsWereMet returns an error, if some expectations were not matched,
+// or unexpected statements were executed
+func (f *Fake) ExpectationsWereMet() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var issues []string
+	for _, e := range f.expectations {
+		if e.calls < e.times {
+			issues = append(issues, fmt.Sprintf("expected %s: called %d of %d times", e, e.calls, e.times))
+		}
+	}
+	issues = append(issues, f.unexpected...)
+	if len(issues) > 0 {
+		return errors.New(strings.Join(issues, "\n"))
+	}
+	return nil
+}
+
+// Reset removes the expectations and unexpected statements
+func (f *Fake) Reset() {
+	f.lock.Lock()
+	f.expectations = nil
+	f.unexpected = nil
+	f.lock.Unlock()
+}
+
+// match returns the expectation of the operation,
+// or nil if transaction operation is not expected
+func (f *Fake) match(ctx context.Context, kind, query string, args []any) (*Expectation, error) {
+	name := xdb.StatementName(ctx)
+	args = normalize(args)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	hasKind := false
+	for _, e := range f.expectations {
+		if e.kind != kind {
+			continue
+		}
+		hasKind = true
+		if e.matches(kind, name, query, args) {
+			e.calls++
+			return e, nil
+		}
+	}
+
+	switch kind {
+	case KindBegin, KindCommit, KindRollback:
+		if !hasKind {
+			return nil, nil
+		}
+	}
+	msg := fmt.Sprintf("unexpected %s", kind)
+	if query != "" {
+		msg += ": " + query
+	}
+	if name != "" {
+		msg += " (" + name + ")"
+	}
+	if len(args) > 0 {
+		msg += fmt.Sprintf(" with args %v", args)
+	}
+	f.unexpected = append(f.unexpected, msg)
+	return nil, errors.New(msg)
+}
+
+// normalize converts the values to the driver values, if supported,
+// so int and int64 arguments are equal
+func normalize(values []any) []any {
+	if values == nil {
+		return nil
+	}
+	res := make([]any, len(values))
+	for i, v := range values {
+		if dv, err := driver.DefaultParameterConverter.ConvertValue(v); err == nil {
+			v = dv
+		}
+		res[i] = v
+	}
+	return res
+}
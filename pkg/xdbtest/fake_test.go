@@ -0,0 +1,115 @@
+package xdbtest_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/xdbtest"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type org struct {
+	ID   xdb.ID `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestQuery(t *testing.T) {
+	fake := xdbtest.New("postgres")
+	p := fake.Provider()
+	defer p.Close()
+	assert.Same(t, p, fake.Provider())
+	assert.Equal(t, "postgres", p.Name())
+
+	ctx := context.Background()
+	fake.ExpectQuery("ListOrgs").
+		WillReturnRows([]string{"id", "name"}, [][]any{{1001, "acme"}, {1002, "corp"}})
+	fake.ExpectQuery(`^SELECT id, name FROM org WHERE id = \$1`).
+		WithArgs(xdb.NewID(1001)).
+		WillReturnRows([]string{"id", "name"}, [][]any{{1001, "acme"}})
+
+	q := xsql.Postgres.From("org").Select("id, name").SetName("ListOrgs")
+	defer q.Close()
+	list, err := xdb.ExecuteListQuery[org](ctx, p, q.String(), q)
+	require.NoError(t, err)
+	assert.Equal(t, []*org{{ID: xdb.NewID(1001), Name: "acme"}, {ID: xdb.NewID(1002), Name: "corp"}}, list)
+
+	o, err := xdb.QueryRow[org](ctx, p, "SELECT id, name FROM org WHERE id = $1", 1001)
+	require.NoError(t, err)
+	assert.Equal(t, &org{ID: xdb.NewID(1001), Name: "acme"}, o)
+	require.NoError(t, fake.ExpectationsWereMet())
+
+	// the expectation is consumed
+	_, err = xdb.QueryRow[org](ctx, p, "SELECT id, name FROM org WHERE id = $1", 1001)
+	assert.EqualError(t, err, "unexpected query: SELECT id, name FROM org WHERE id = $1 with args [1001]")
+	assert.EqualError(t, fake.ExpectationsWereMet(), "unexpected query: SELECT id, name FROM org WHERE id = $1 with args [1001]")
+
+	fake.Reset()
+	fake.ExpectQuery("GetOrg").WithArgs(2).WillReturnRows([]string{"id", "name"}, nil).Times(2)
+	_, err = xdb.QueryRow[org](xdb.WithStatementName(ctx, "GetOrg"), p, "SELECT id, name FROM org WHERE id = $1", 2)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.EqualError(t, fake.ExpectationsWereMet(), "expected query GetOrg with args [2]: called 1 of 2 times")
+}
+
+func TestExec(t *testing.T) {
+	fake := xdbtest.New("postgres")
+	p := fake.Provider()
+	defer p.Close()
+
+	ctx := context.Background()
+	fake.ExpectExec(`^UPDATE org`).WillReturnResult(0, 1)
+	fake.ExpectExec(`^DELETE FROM org`).WillReturnError(errors.New("boom"))
+
+	res, err := p.ExecContext(ctx, "UPDATE org SET name = $1 WHERE id = $2", "acme", 1)
+	require.NoError(t, err)
+	require.NoError(t, xdb.MustAffect(res, 1))
+
+	_, err = p.ExecContext(ctx, "DELETE FROM org WHERE id = $1", 1)
+	assert.EqualError(t, err, "boom")
+
+	_, err = p.ExecContext(ctx, "INSERT INTO org (name) VALUES ($1)", "corp")
+	assert.EqualError(t, err, "unexpected exec: INSERT INTO org (name) VALUES ($1) with args [corp]")
+}
+
+func TestTx(t *testing.T) {
+	fake := xdbtest.New("sqlserver")
+	p := fake.Provider()
+	defer p.Close()
+
+	ctx := context.Background()
+	fake.ExpectExec("UpdateOrg").WillReturnResult(0, 1)
+
+	// the transaction operations are not expected
+	err := p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		_, err := tx.ExecContext(xdb.WithStatementName(ctx, "UpdateOrg"), "UPDATE org SET name = @p1", "acme")
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, fake.ExpectationsWereMet())
+
+	fake.ExpectBegin()
+	fake.ExpectExec("UpdateOrg").WillReturnError(errors.New("conflict"))
+	fake.ExpectRollback()
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		_, err := tx.ExecContext(xdb.WithStatementName(ctx, "UpdateOrg"), "UPDATE org SET name = @p1", "acme")
+		return err
+	})
+	assert.EqualError(t, err, "conflict")
+	require.NoError(t, fake.ExpectationsWereMet())
+
+	fake.Reset()
+	fake.ExpectBegin().WillReturnError(errors.New("no connection"))
+	_, err = p.BeginTx(ctx, nil)
+	assert.EqualError(t, err, "no connection")
+
+	fake.Reset()
+	fake.ExpectCommit().WillReturnError(errors.New("serialization failure"))
+	tx, err := p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	assert.EqualError(t, tx.Commit(), "serialization failure")
+	require.NoError(t, fake.ExpectationsWereMet())
+}
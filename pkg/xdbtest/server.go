@@ -0,0 +1,399 @@
+package xdbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/pkg/errors"
+)
+
+// DataSourceEnv is the environment variable with the data source of the test server,
+// used when Config.DataSource is empty
+const DataSourceEnv = "XDB_TEST_DATASOURCE"
+
+// The default images of the containers
+const (
+	DefaultPostgresImage  = "postgres:16-alpine"
+	DefaultSQLServerImage = "mcr.microsoft.com/mssql/server:2022-latest"
+)
+
+// containerPassword is the password of the admin user of the container
+const containerPassword = "xdbTest_P4ss"
+
+// Config of the test database server
+type Config struct {
+	// Provider is postgres or sqlserver
+	Provider string
+	// DataSource of the server, if empty the value of XDB_TEST_DATASOURCE is used,
+	// or the container is started with docker
+	DataSource string
+	// Image of the container, default: DefaultPostgresImage or DefaultSQLServerImage
+	Image string
+	// Migrations is the directory of migrations applied to the test databases
+	Migrations string
+	// Truncate specifies to share one database between the tests,
+	// and truncate the tables after each test, instead of creating a database per test
+	Truncate bool
+	// StartTimeout limits the time to wait for the server, default: 2 minutes
+	StartTimeout time.Duration
+}
+
+// Server is the database server for the tests, see Start
+type Server struct {
+	cfg         Config
+	dataSource  string
+	containerID string
+	admin       *sql.DB
+
+	lock   sync.Mutex
+	shared xdb.Provider
+}
+
+/*
+Start starts the test database server, or connects to the provided data source.
+Use it in TestMain, and Provider in the tests:
+
+	var server *xdbtest.Server
+
+	func TestMain(m *testing.M) {
+		var err error
+		server, err = xdbtest.Start(context.Background(), &xdbtest.Config{
+			Provider:   "postgres",
+			Migrations: "../sql/postgres/migrations",
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		code := m.Run()
+		_ = server.Close()
+		os.Exit(code)
+	}
+
+	func TestOrg(t *testing.T) {
+		p := server.Provider(t)
+		...
+	}
+
+The container is started with docker CLI, and removed by Close.
+*/
+func Start(ctx context.Context, cfg *Config) (*Server, error) {
+	s := &Server{cfg: *cfg}
+	if s.cfg.StartTimeout <= 0 {
+		s.cfg.StartTimeout = 2 * time.Minute
+	}
+	if s.cfg.Image == "" {
+		s.cfg.Image = defaultImage(s.cfg.Provider)
+	}
+	if s.cfg.Image == "" {
+		return nil, errors.Errorf("unsupported provider: %s", s.cfg.Provider)
+	}
+
+	s.dataSource = s.cfg.DataSource
+	if s.dataSource == "" {
+		s.dataSource = os.Getenv(DataSourceEnv)
+	}
+	if s.dataSource == "" {
+		if err := s.startContainer(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.connect(ctx); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// DataSource returns the data source of the server
+func (s *Server) DataSource() string {
+	return s.dataSource
+}
+
+// Close closes the connections, and removes the container
+func (s *Server) Close() error {
+	var err error
+	if s.shared != nil {
+		err = s.shared.Close()
+		if derr := s.dropDatabase(context.Background(), s.sharedName()); err == nil {
+			err = derr
+		}
+		s.shared = nil
+	}
+	if s.admin != nil {
+		_ = s.admin.Close()
+		s.admin = nil
+	}
+	if s.containerID != "" {
+		if out, rerr := exec.Command("docker", "rm", "-f", s.containerID).CombinedOutput(); rerr != nil && err == nil {
+			err = errors.Errorf("failed to remove container: %s", strings.TrimSpace(string(out)))
+		}
+		s.containerID = ""
+	}
+	return err
+}
+
+// Provider returns the provider of the migrated database for the test.
+// The database is created for the test and dropped after it,
+// or the tables of the shared database are truncated after the test, if Config.Truncate is set.
+func (s *Server) Provider(t testing.TB) xdb.Provider {
+	t.Helper()
+	ctx := context.Background()
+
+	if s.cfg.Truncate {
+		p, err := s.sharedProvider(ctx)
+		if err != nil {
+			t.Fatalf("failed to open test database: %+v", err)
+		}
+		t.Cleanup(func() {
+			if err := s.truncate(ctx, p); err != nil {
+				t.Errorf("failed to truncate test database: %+v", err)
+			}
+		})
+		return p
+	}
+
+	name := "xdbtest_" + randomSuffix()
+	p, err := s.open(ctx, name)
+	if err != nil {
+		t.Fatalf("failed to open test database: %+v", err)
+	}
+	t.Cleanup(func() {
+		_ = p.Close()
+		if err := s.dropDatabase(ctx, name); err != nil {
+			t.Errorf("failed to drop test database: %+v", err)
+		}
+	})
+	return p
+}
+
+func (s *Server) sharedName() string {
+	return "xdbtest_shared"
+}
+
+func (s *Server) sharedProvider(ctx context.Context) (xdb.Provider, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.shared == nil {
+		// the database of the previous run is recreated
+		if err := s.dropDatabase(ctx, s.sharedName()); err != nil {
+			return nil, err
+		}
+		p, err := s.open(ctx, s.sharedName())
+		if err != nil {
+			return nil, err
+		}
+		s.shared = p
+	}
+	return s.shared, nil
+}
+
+// open creates the database and applies the migrations
+func (s *Server) open(ctx context.Context, name string) (xdb.Provider, error) {
+	if _, err := s.admin.ExecContext(ctx, "CREATE DATABASE "+name); err != nil {
+		return nil, errors.WithMessagef(err, "failed to create database %s", name)
+	}
+	var migrations *xdb.MigrationConfig
+	if s.cfg.Migrations != "" {
+		migrations = &xdb.MigrationConfig{Source: s.cfg.Migrations}
+	}
+	p, err := xdb.NewProvider(s.dataSource, name, nil, migrations)
+	if err != nil {
+		_ = s.dropDatabase(ctx, name)
+		return nil, err
+	}
+	return p, nil
+}
+
+// dropDatabase drops the database, if exists
+func (s *Server) dropDatabase(ctx context.Context, name string) error {
+	for _, stmt := range dropDatabaseStatements(s.cfg.Provider, name) {
+		if _, err := s.admin.ExecContext(ctx, stmt); err != nil {
+			return errors.WithMessagef(err, "failed to drop database %s", name)
+		}
+	}
+	return nil
+}
+
+// truncate deletes the rows of the tables, except the migrations
+func (s *Server) truncate(ctx context.Context, p xdb.Provider) error {
+	var query string
+	switch s.cfg.Provider {
+	case "postgres":
+		query = `SELECT schemaname || '.' || tablename FROM pg_tables
+			WHERE schemaname NOT IN ('pg_catalog', 'information_schema') AND tablename <> 'schema_migrations'`
+	case "sqlserver":
+		query = `SELECT s.name + '.' + t.name FROM sys.tables t
+			JOIN sys.schemas s ON s.schema_id = t.schema_id
+			WHERE t.is_ms_shipped = 0 AND t.name <> 'schema_migrations'`
+	}
+
+	rows, err := p.QueryContext(ctx, query)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err = rows.Scan(&table); err != nil {
+			_ = rows.Close()
+			return errors.WithStack(err)
+		}
+		tables = append(tables, table)
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, stmt := range truncateStatements(s.cfg.Provider, tables) {
+		if _, err = p.ExecContext(ctx, stmt); err != nil {
+			return errors.WithMessagef(err, "failed to execute: %s", stmt)
+		}
+	}
+	return nil
+}
+
+// startContainer starts the container, and sets the data source of the server
+func (s *Server) startContainer(ctx context.Context) error {
+	args, port := containerArgs(s.cfg.Provider, s.cfg.Image)
+	out, err := exec.CommandContext(ctx, "docker", args...).Output()
+	if err != nil {
+		return errors.WithMessagef(dockerError(err), "failed to start container %s", s.cfg.Image)
+	}
+	s.containerID = strings.TrimSpace(string(out))
+
+	// the host port is assigned by docker
+	out, err = exec.CommandContext(ctx, "docker", "port", s.containerID, port).Output()
+	if err != nil {
+		return errors.WithMessagef(dockerError(err), "failed to get port of container %s", s.containerID)
+	}
+	addr, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	_, hostPort, ok := strings.Cut(addr, ":")
+	if !ok {
+		return errors.Errorf("unexpected port of container %s: %s", s.containerID, addr)
+	}
+	s.dataSource = containerDataSource(s.cfg.Provider, hostPort)
+	return nil
+}
+
+// connect opens the admin connection, and waits for the server to accept it
+func (s *Server) connect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.StartTimeout)
+	defer cancel()
+
+	for {
+		d, _, _, err := xdb.Open(s.dataSource, "")
+		if err == nil {
+			s.admin = d
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.WithMessagef(err, "server is not ready")
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// defaultImage returns the container image of the provider
+func defaultImage(provider string) string {
+	switch provider {
+	case "postgres":
+		return DefaultPostgresImage
+	case "sqlserver":
+		return DefaultSQLServerImage
+	default:
+		return ""
+	}
+}
+
+// containerArgs returns the arguments of docker run, and the port of the server
+func containerArgs(provider, image string) ([]string, string) {
+	args := []string{"run", "--detach", "--rm"}
+	var port string
+	switch provider {
+	case "postgres":
+		port = "5432/tcp"
+		args = append(args, "--env", "POSTGRES_PASSWORD="+containerPassword)
+	case "sqlserver":
+		port = "1433/tcp"
+		args = append(args, "--env", "ACCEPT_EULA=Y", "--env", "MSSQL_SA_PASSWORD="+containerPassword)
+	}
+	args = append(args, "--publish", "127.0.0.1::"+port, image)
+	return args, port
+}
+
+// containerDataSource returns the data source of the server in the container
+func containerDataSource(provider, port string) string {
+	switch provider {
+	case "sqlserver":
+		return fmt.Sprintf("sqlserver://127.0.0.1:%s?user id=sa&password=%s", port, containerPassword)
+	default:
+		return fmt.Sprintf("postgres://postgres:%s@127.0.0.1:%s?sslmode=disable", containerPassword, port)
+	}
+}
+
+// dropDatabaseStatements returns the statements that drop the database,
+// closing the connections to it
+func dropDatabaseStatements(provider, name string) []string {
+	switch provider {
+	case "sqlserver":
+		return []string{
+			fmt.Sprintf("IF DB_ID('%s') IS NOT NULL ALTER DATABASE %s SET SINGLE_USER WITH ROLLBACK IMMEDIATE", name, name),
+			fmt.Sprintf("IF DB_ID('%s') IS NOT NULL DROP DATABASE %s", name, name),
+		}
+	default:
+		return []string{"DROP DATABASE IF EXISTS " + name + " WITH (FORCE)"}
+	}
+}
+
+// truncateStatements returns the statements that delete the rows of the tables
+func truncateStatements(provider string, tables []string) []string {
+	if len(tables) == 0 {
+		return nil
+	}
+	switch provider {
+	case "sqlserver":
+		// TRUNCATE is not allowed for the tables referenced by foreign keys
+		var list []string
+		for _, t := range tables {
+			list = append(list, "ALTER TABLE "+t+" NOCHECK CONSTRAINT ALL")
+		}
+		for _, t := range tables {
+			list = append(list, "DELETE FROM "+t)
+		}
+		for _, t := range tables {
+			list = append(list, "ALTER TABLE "+t+" WITH CHECK CHECK CONSTRAINT ALL")
+		}
+		return list
+	default:
+		return []string{"TRUNCATE TABLE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE"}
+	}
+}
+
+// dockerError returns the error with the output of the failed command
+func dockerError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return errors.WithStack(err)
+}
+
+// randomSuffix returns the random suffix of the database name
+func randomSuffix() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
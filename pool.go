@@ -0,0 +1,157 @@
+package xdb
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// DefaultPoolName is the name of the provider's own pool in PoolStats
+const DefaultPoolName = "default"
+
+// PoolOptions specifies the limits of the connection pool,
+// zero values keep the defaults of database/sql
+type PoolOptions struct {
+	// MaxOpenConns limits the number of open connections
+	MaxOpenConns int
+	// MaxIdleConns limits the number of idle connections
+	MaxIdleConns int
+	// ConnMaxLifetime limits the time a connection may be reused
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime limits the time a connection may be idle
+	ConnMaxIdleTime time.Duration
+}
+
+// PoolStats describes the statistics of the named connection pool
+type PoolStats struct {
+	Name string
+	sql.DBStats
+}
+
+type childPools struct {
+	lock  sync.RWMutex
+	pools map[string]*SQLProvider
+}
+
+// NewPool creates a named child provider that shares the connection string
+// and settings, but maintains a separate connection pool with its own limits,
+// for example to isolate "batch" jobs from the pool used by "api" requests.
+// The child pools are closed with the provider.
+func (p *SQLProvider) NewPool(name string, opts PoolOptions) (*SQLProvider, error) {
+	if name == "" || name == DefaultPoolName {
+		return nil, errors.Errorf("invalid pool name %q", name)
+	}
+	if p.tx != nil {
+		return nil, errors.New("pool can not be created in transaction")
+	}
+	if p.connstr == "" {
+		return nil, errors.New("connection string is not set")
+	}
+
+	if p.pools == nil {
+		return nil, errors.New("pools are not supported by the provider")
+	}
+
+	p.pools.lock.Lock()
+	defer p.pools.lock.Unlock()
+	if p.pools.pools[name] != nil {
+		return nil, errors.Errorf("pool %q already exists", name)
+	}
+
+	d, err := sql.Open(p.name, p.connstr)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "unable to open DB")
+	}
+	if opts.MaxOpenConns > 0 {
+		d.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		d.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	d.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	d.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	child := p.clone()
+	child.pool = name
+	child.conn = d
+	child.db = d
+	child.stmtCache = nil
+	if p.stmtCache != nil {
+		// prepared statements are bound to the connection pool
+		child.WithStmtCache(p.stmtCache.MaxEntries())
+	}
+	child.keepAlive(60 * time.Second)
+	child.startPoolStats()
+	p.pools.pools[name] = child
+
+	logger.KV(xlog.INFO,
+		"reason", "new_pool",
+		"pool", name,
+		"max_open", opts.MaxOpenConns,
+		"max_idle", opts.MaxIdleConns)
+
+	return child, nil
+}
+
+// Pool returns the child provider created by NewPool,
+// or nil if the pool does not exist
+func (p *SQLProvider) Pool(name string) *SQLProvider {
+	if p.pools == nil {
+		return nil
+	}
+	p.pools.lock.RLock()
+	defer p.pools.lock.RUnlock()
+	return p.pools.pools[name]
+}
+
+// PoolName returns the name of the provider's pool
+func (p *SQLProvider) PoolName() string {
+	if p.pool == "" {
+		return DefaultPoolName
+	}
+	return p.pool
+}
+
+// PoolStats returns the statistics of the provider's pool,
+// followed by the child pools sorted by name,
+// to be reported by the application metrics
+func (p *SQLProvider) PoolStats() []PoolStats {
+	var list []PoolStats
+	if p.conn != nil {
+		list = append(list, PoolStats{Name: p.PoolName(), DBStats: p.conn.Stats()})
+	}
+	if p.pools == nil {
+		return list
+	}
+
+	p.pools.lock.RLock()
+	defer p.pools.lock.RUnlock()
+	names := make([]string, 0, len(p.pools.pools))
+	for name := range p.pools.pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if c := p.pools.pools[name]; c.conn != nil {
+			list = append(list, PoolStats{Name: name, DBStats: c.conn.Stats()})
+		}
+	}
+	return list
+}
+
+// closePools closes the child pools
+func (p *SQLProvider) closePools() {
+	if p.pools == nil {
+		return
+	}
+	p.pools.lock.Lock()
+	defer p.pools.lock.Unlock()
+	for name, c := range p.pools.pools {
+		_ = c.Close()
+		delete(p.pools.pools, name)
+	}
+}
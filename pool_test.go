@@ -0,0 +1,71 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPool(t *testing.T) {
+	ctx := context.Background()
+	prov, err := xdb.NewProvider("sqlite3://"+t.TempDir(), "pool.db", nil, nil)
+	require.NoError(t, err)
+	p := prov.(*xdb.SQLProvider).WithStmtCache(10)
+	defer p.Close()
+
+	_, err = p.ExecContext(ctx, "CREATE TABLE node (name TEXT)")
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, "INSERT INTO node VALUES ('primary')")
+	require.NoError(t, err)
+
+	batch, err := p.NewPool("batch", xdb.PoolOptions{MaxOpenConns: 1, MaxIdleConns: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "batch", batch.PoolName())
+	assert.Equal(t, xdb.DefaultPoolName, p.PoolName())
+	assert.Same(t, batch, p.Pool("batch"))
+	assert.Nil(t, p.Pool("api"))
+
+	// the child pool prepares the statements on its own connections
+	require.NotNil(t, batch.StmtCache())
+	assert.NotSame(t, p.StmtCache(), batch.StmtCache())
+	assert.Equal(t, 10, batch.StmtCache().MaxEntries())
+
+	// the pools share the database
+	assert.Equal(t, "primary", nodeName(t, ctx, batch))
+
+	_, err = p.NewPool("batch", xdb.PoolOptions{})
+	assert.EqualError(t, err, `pool "batch" already exists`)
+	_, err = p.NewPool(xdb.DefaultPoolName, xdb.PoolOptions{})
+	assert.EqualError(t, err, `invalid pool name "default"`)
+	_, err = batch.NewPool("nested", xdb.PoolOptions{})
+	assert.EqualError(t, err, "pools are not supported by the provider")
+
+	api, err := p.NewPool("api", xdb.PoolOptions{MaxOpenConns: 4})
+	require.NoError(t, err)
+
+	tx, err := api.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "api", tx.(*xdb.SQLProvider).PoolName())
+	assert.Same(t, api.StmtCache(), tx.(*xdb.SQLProvider).StmtCache())
+	_, err = tx.(*xdb.SQLProvider).NewPool("tx", xdb.PoolOptions{})
+	assert.EqualError(t, err, "pool can not be created in transaction")
+
+	stats := p.PoolStats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, xdb.DefaultPoolName, stats[0].Name)
+	assert.Equal(t, "api", stats[1].Name)
+	assert.Equal(t, 4, stats[1].MaxOpenConnections)
+	assert.Equal(t, 1, stats[1].InUse)
+	assert.Equal(t, "batch", stats[2].Name)
+	assert.Equal(t, 1, stats[2].MaxOpenConnections)
+	require.NoError(t, tx.Rollback())
+
+	_, err = openSQLite(t).NewPool("batch", xdb.PoolOptions{})
+	assert.EqualError(t, err, "connection string is not set")
+
+	require.NoError(t, p.Close())
+	assert.Nil(t, p.Pool("batch"))
+}
@@ -3,9 +3,11 @@ package xdb
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"time"
 
 	"github.com/effective-security/xdb/pkg/flake"
+	"github.com/effective-security/xdb/xsql"
 	"github.com/effective-security/xlog"
 	"github.com/pkg/errors"
 )
@@ -21,6 +23,39 @@ type SQLProvider struct {
 	idGen   flake.IDGenerator
 	tx      Tx
 	ticker  *time.Ticker
+
+	// pingTimeout specifies timeout to verify connection on checkout
+	pingTimeout time.Duration
+	// timePrecision specifies precision of Time values produced by the provider,
+	// nil uses DefaultTrucate
+	timePrecision *time.Duration
+	// maxRows specifies the LIMIT appended to unbounded SELECT statements,
+	// zero disables the policy
+	maxRows uint32
+	// clock provides the time for Now, nil uses SystemClock
+	clock Clock
+	// pool is the name of the child pool, empty for the default pool
+	pool string
+	// pools are the child pools created by NewPool
+	pools *childPools
+	// retry is the policy of statements failed with transient errors,
+	// nil disables the retry
+	retry *RetryPolicy
+	// slowQuery is the duration of statements to log and to publish EventSlowQuery,
+	// zero disables the event
+	slowQuery time.Duration
+	// slowQueryArgs logs the arguments of slow statements, nil disables the arguments
+	slowQueryArgs ArgsRedactor
+	// started is the time the transaction was started
+	started time.Time
+	// metrics is the registrar of the query latency and pool stats
+	metrics MetricsRegistrar
+	// statsPeriod is the period to report the pool stats
+	statsPeriod time.Duration
+	// statsStop stops reporting the pool stats
+	statsStop chan struct{}
+	// stmtCache is the cache of prepared named statements
+	stmtCache *xsql.StmtCache
 }
 
 // New creates a Provider instance
@@ -33,6 +68,7 @@ func New(name string, db *sql.DB, idGen flake.IDGenerator) (*SQLProvider, error)
 		conn:  db,
 		db:    db,
 		idGen: idGen,
+		pools: &childPools{pools: map[string]*SQLProvider{}},
 	}
 
 	p.keepAlive(60 * time.Second)
@@ -45,6 +81,143 @@ func (p *SQLProvider) WithConnectionString(connstr string) *SQLProvider {
 	return p
 }
 
+// WithPingOnCheckout enables connection verification before executing a statement.
+// A connection that fails to respond within the timeout is evicted from the pool,
+// so idle connections dropped by firewalls don't surface as request failures.
+// Zero timeout disables the verification.
+func (p *SQLProvider) WithPingOnCheckout(timeout time.Duration) *SQLProvider {
+	p.pingTimeout = timeout
+	return p
+}
+
+// WithTimePrecision sets precision of Time values produced by Now and UTC,
+// for databases configured with precision different from DefaultTrucate.
+// Zero precision preserves nanoseconds.
+func (p *SQLProvider) WithTimePrecision(precision time.Duration) *SQLProvider {
+	p.timePrecision = &precision
+	return p
+}
+
+// WithClock sets the clock used by Now,
+// tests can inject FixedClock to produce deterministic timestamps
+func (p *SQLProvider) WithClock(clock Clock) *SQLProvider {
+	p.clock = clock
+	return p
+}
+
+// WithRetry enables the retry of statements failed with transient errors,
+// for example serialization failures, deadlocks or connection resets.
+// ExecContext is retried on connection errors only if the context is marked
+// with Idempotent, see RetryPolicy.DoExec.
+// The statements executed in a transaction are not retried,
+// use RetryPolicy.Do to retry the whole transaction. Nil disables the retry.
+func (p *SQLProvider) WithRetry(policy *RetryPolicy) *SQLProvider {
+	p.retry = policy
+	return p
+}
+
+// WithStmtCache enables the cache of prepared statements for named statements,
+// executed outside of transactions, see xsql.StmtCache.
+// The statement is named by xsql.Builder.SetName or WithStatementName.
+func (p *SQLProvider) WithStmtCache(maxEntries int) *SQLProvider {
+	if p.tx != nil || p.conn == nil {
+		return p
+	}
+	if p.stmtCache != nil {
+		_ = p.stmtCache.Close()
+	}
+	p.stmtCache = xsql.NewStmtCache(p.conn, maxEntries)
+	p.db = p.stmtCache
+	return p
+}
+
+// StmtCache returns the cache of prepared statements, or nil if not enabled
+func (p *SQLProvider) StmtCache() *xsql.StmtCache {
+	return p.stmtCache
+}
+
+// RetryPolicy returns the policy of statements failed with transient errors
+func (p *SQLProvider) RetryPolicy() *RetryPolicy {
+	return p.retry
+}
+
+// statementRetry returns the retry policy for statements outside of transaction
+func (p *SQLProvider) statementRetry() *RetryPolicy {
+	if p.tx != nil {
+		return nil
+	}
+	return p.retry
+}
+
+// Clock returns the clock used by Now
+func (p *SQLProvider) Clock() Clock {
+	if p.clock != nil {
+		return p.clock
+	}
+	return SystemClock
+}
+
+// WithMaxRows sets the maximum number of rows returned by SELECT statements
+// built with xsql.Builder and passed as the only query argument.
+// LIMIT is appended to statements that do not have one,
+// unless marked with Unbounded. Zero disables the policy.
+func (p *SQLProvider) WithMaxRows(limit uint32) *SQLProvider {
+	p.maxRows = limit
+	return p
+}
+
+// MaxRows returns the LIMIT appended to unbounded SELECT statements
+func (p *SQLProvider) MaxRows() uint32 {
+	return p.maxRows
+}
+
+// limitRows returns a copy of unbounded SELECT statement with LIMIT,
+// or nil if the policy does not apply to the statement
+func (p *SQLProvider) limitRows(q xsql.Builder) xsql.Builder {
+	if p.maxRows == 0 || !q.IsSelect() || q.HasLimit() || q.IsUnbounded() {
+		return nil
+	}
+	logger.KV(xlog.DEBUG, "reason", "limit_injected", "limit", p.maxRows)
+	return q.CapRows(p.maxRows)
+}
+
+// TimePrecision returns precision of Time values produced by the provider
+func (p *SQLProvider) TimePrecision() time.Duration {
+	if p.timePrecision != nil {
+		return *p.timePrecision
+	}
+	return DefaultTrucate
+}
+
+// Now returns current Time in UTC with the provider's precision
+func (p *SQLProvider) Now() Time {
+	return TruncateTime(p.Clock().Now(), p.TimePrecision())
+}
+
+// UTC returns Time in UTC with the provider's precision
+func (p *SQLProvider) UTC(t time.Time) Time {
+	return TruncateTime(t, p.TimePrecision())
+}
+
+// ParseTime returns Time from RFC3339 format with the provider's precision
+func (p *SQLProvider) ParseTime(val string) Time {
+	if val == "" {
+		return Time{}
+	}
+	return TruncateTime(parseTime(val), p.TimePrecision())
+}
+
+// FormatTime returns t in RFC3339 format,
+// with the number of fraction digits of the provider's precision,
+// if it's Zero time, an empty string is returned
+func (p *SQLProvider) FormatTime(t Time) string {
+	tm := inZone(time.Time(t))
+	if tm.IsZero() {
+		return ""
+	}
+	return tm.Format(timeFormat(p.TimePrecision()))
+}
+
 func (p *SQLProvider) ConnectionString() string {
 	return p.connstr
 }
@@ -60,18 +233,62 @@ func (p *SQLProvider) keepAlive(period time.Duration) {
 
 	// Go function
 	go func() {
+		connected := true
 		// Using for loop
 		for range ch {
 			err := p.conn.Ping()
 			if err != nil {
 				logger.KV(xlog.ERROR, "reason", "ping", "err", err.Error())
+				if connected {
+					connected = false
+					e := p.event(EventDisconnected)
+					e.Err = err
+					publishEvent(e)
+				}
 				continue
 			}
+			if !connected {
+				connected = true
+				publishEvent(p.event(EventConnected))
+			}
 		}
 		logger.KV(xlog.TRACE, "status", "stopped")
 	}()
 }
 
+// maxCheckoutAttempts limits number of broken connections evicted on a single checkout
+const maxCheckoutAttempts = 3
+
+// checkout verifies that an idle connection is alive before it's used by a statement,
+// broken connections are evicted from the pool.
+func (p *SQLProvider) checkout(ctx context.Context) {
+	if p.pingTimeout <= 0 || p.tx != nil || p.conn == nil {
+		return
+	}
+
+	for i := 0; i < maxCheckoutAttempts; i++ {
+		c, err := p.conn.Conn(ctx)
+		if err != nil {
+			// let the statement to report the error
+			return
+		}
+
+		pctx, cancel := context.WithTimeout(ctx, p.pingTimeout)
+		err = c.PingContext(pctx)
+		cancel()
+
+		if err == nil || ctx.Err() != nil {
+			_ = c.Close()
+			return
+		}
+
+		logger.KV(xlog.WARNING, "reason", "evict_connection", "err", err.Error())
+		// returning ErrBadConn discards the connection from the pool
+		_ = c.Raw(func(any) error { return driver.ErrBadConn })
+		_ = c.Close()
+	}
+}
+
 // BeginTx starts a transaction.
 //
 // The provided context is used until the transaction is committed or rolled back.
@@ -82,37 +299,70 @@ func (p *SQLProvider) keepAlive(period time.Duration) {
 // The provided TxOptions is optional and may be nil if defaults should be used.
 // If a non-default isolation level is used that the driver doesn't support,
 // an error will be returned.
-func (p *SQLProvider) BeginTx(ctx context.Context, _ *sql.TxOptions) (Provider, error) {
+//
+// The session settings from WithSessionSettings are applied at the transaction start.
+func (p *SQLProvider) BeginTx(ctx context.Context, opts *sql.TxOptions) (Provider, error) {
 	if p.tx != nil {
 		return nil, errors.New("transaction already started")
 	}
-	tx, err := p.conn.BeginTx(ctx, nil)
+	p.checkout(ctx)
+	tx, err := p.conn.BeginTx(ctx, opts)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	txProv := &SQLProvider{
-		name:  p.name,
-		conn:  p.conn,
-		db:    tx,
-		idGen: p.idGen,
-		tx:    tx,
+	txProv := p.clone()
+	txProv.db = tx
+	txProv.tx = tx
+	txProv.started = time.Now()
+	if err = txProv.applySessionSettings(ctx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
 	}
+	txProv.notifyBackendPID(ctx)
 	return txProv, nil
 }
 
+// clone returns a provider with the same connection and settings,
+// used for transactions and child pools
+func (p *SQLProvider) clone() *SQLProvider {
+	return &SQLProvider{
+		name:          p.name,
+		conn:          p.conn,
+		connstr:       p.connstr,
+		db:            p.db,
+		idGen:         p.idGen,
+		pingTimeout:   p.pingTimeout,
+		timePrecision: p.timePrecision,
+		maxRows:       p.maxRows,
+		clock:         p.clock,
+		pool:          p.pool,
+		retry:         p.retry,
+		slowQuery:     p.slowQuery,
+		slowQueryArgs: p.slowQueryArgs,
+		metrics:       p.metrics,
+		statsPeriod:   p.statsPeriod,
+		stmtCache:     p.stmtCache,
+	}
+}
+
 // Close connection and release resources
 func (p *SQLProvider) Close() (err error) {
 	if p.ticker != nil {
 		p.ticker.Stop()
 		p.ticker = nil
 	}
+	p.stopPoolStats()
 	if p.conn == nil {
 		return nil
 	}
 	if p.tx != nil {
 		return p.Rollback()
 	}
+	p.closePools()
+	if p.stmtCache != nil {
+		_ = p.stmtCache.Close()
+	}
 
 	if err = p.conn.Close(); err != nil {
 		logger.KV(xlog.ERROR, "err", err)
@@ -145,8 +395,24 @@ func (p *SQLProvider) IDTime(id uint64) time.Time {
 
 // QueryContext executes a query that returns rows, typically a SELECT.
 // The args are for any placeholder parameters in the query.
-func (p *SQLProvider) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	return p.db.QueryContext(ctx, query, args...)
+// If xsql.Builder is passed as the only argument, its arguments are used.
+func (p *SQLProvider) QueryContext(ctx context.Context, query string, args ...any) (rows *sql.Rows, err error) {
+	ctx, query, args = stmtQuery(ctx, p, query, args)
+	args = p.bindArgs(args)
+	defer func(started time.Time) {
+		p.observeQuery(ctx, query, args, started, err)
+	}(time.Now())
+	db, ok := p.contextDB(ctx)
+	if ok {
+		return db.QueryContext(ctx, query, args...)
+	}
+	err = p.statementRetry().Do(ctx, func(ctx context.Context) error {
+		p.checkout(ctx)
+		var err error
+		rows, err = db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
 }
 
 // QueryRowContext executes a query that is expected to return at most one row.
@@ -155,21 +421,92 @@ func (p *SQLProvider) QueryContext(ctx context.Context, query string, args ...an
 // If the query selects no rows, the *Row's Scan will return ErrNoRows.
 // Otherwise, the *Row's Scan scans the first selected row and discards
 // the rest.
-func (p *SQLProvider) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
-	return p.db.QueryRowContext(ctx, query, args...)
+// If xsql.Builder is passed as the only argument, its arguments are used.
+func (p *SQLProvider) QueryRowContext(ctx context.Context, query string, args ...any) (row *sql.Row) {
+	ctx, query, args = stmtQuery(ctx, p, query, args)
+	args = p.bindArgs(args)
+	defer func(started time.Time) {
+		p.observeQuery(ctx, query, args, started, row.Err())
+	}(time.Now())
+	db, ok := p.contextDB(ctx)
+	if ok {
+		return db.QueryRowContext(ctx, query, args...)
+	}
+	_ = p.statementRetry().Do(ctx, func(ctx context.Context) error {
+		p.checkout(ctx)
+		row = db.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
 }
 
 // ExecContext executes a query without returning any rows.
 // The args are for any placeholder parameters in the query.
-func (p *SQLProvider) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return p.db.ExecContext(ctx, query, args...)
+// If xsql.Builder is passed as the only argument, its arguments are used.
+func (p *SQLProvider) ExecContext(ctx context.Context, query string, args ...any) (res sql.Result, err error) {
+	ctx, query, args = stmtQuery(ctx, p, query, args)
+	args = p.bindArgs(args)
+	defer func(started time.Time) {
+		p.observeQuery(ctx, query, args, started, err)
+	}(time.Now())
+	db, ok := p.contextDB(ctx)
+	if ok {
+		return db.ExecContext(ctx, query, args...)
+	}
+	err = p.statementRetry().DoExec(ctx, func(ctx context.Context) error {
+		p.checkout(ctx)
+		var err error
+		res, err = db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+// jsonArray is implemented by the array types,
+// that are bound as JSON array on the providers without array types
+type jsonArray interface {
+	JSON() string
+}
+
+// bindArgs returns the arguments supported by the provider,
+// IDArray and Array are bound as JSON array on the providers without array types
+func (p *SQLProvider) bindArgs(args []any) []any {
+	if xsql.DialectFor(p.name).Capabilities().Arrays {
+		return args
+	}
+	var res []any
+	for i, arg := range args {
+		a, ok := arg.(jsonArray)
+		if !ok {
+			continue
+		}
+		if res == nil {
+			res = make([]any, len(args))
+			copy(res, args)
+		}
+		if js := a.JSON(); js == "[]" {
+			res[i] = nil
+		} else {
+			res[i] = js
+		}
+	}
+	if res == nil {
+		return args
+	}
+	return res
 }
 
 func (p *SQLProvider) Commit() error {
 	if p.tx == nil {
 		return errors.New("no transaction started")
 	}
-	return p.tx.Commit()
+	if err := p.tx.Commit(); err != nil {
+		return err
+	}
+	e := p.event(EventTxCommitted)
+	e.Duration = time.Since(p.started)
+	publishEvent(e)
+	return nil
 }
 
 func (p *SQLProvider) Rollback() error {
@@ -0,0 +1,197 @@
+package xdb_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openSQLite(t *testing.T) *xdb.SQLProvider {
+	t.Helper()
+	d, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// :memory: database is per connection
+	d.SetMaxOpenConns(1)
+
+	p, err := xdb.New("sqlite3", d, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = p.Close()
+	})
+	return p
+}
+
+func TestPingOnCheckout(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t).WithPingOnCheckout(time.Second)
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, "INSERT INTO t (id, name) VALUES (?, ?)", 1, "one")
+	require.NoError(t, err)
+
+	var name string
+	err = p.QueryRowContext(ctx, "SELECT name FROM t WHERE id = ?", 1).Scan(&name)
+	require.NoError(t, err)
+	assert.Equal(t, "one", name)
+
+	tx, err := p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM t")
+	require.NoError(t, err)
+	assert.True(t, rows.Next())
+	require.NoError(t, rows.Close())
+	require.NoError(t, tx.Commit())
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = p.QueryContext(cctx, "SELECT name FROM t")
+	assert.Error(t, err)
+}
+
+func TestTimePrecision(t *testing.T) {
+	p := openSQLite(t)
+	assert.Equal(t, xdb.DefaultTrucate, p.TimePrecision())
+
+	d := time.Date(2019, 1, 2, 3, 4, 5, 1234567, time.UTC)
+	assert.Equal(t, time.Date(2019, 1, 2, 3, 4, 5, 1000000, time.UTC), p.UTC(d).UTC())
+
+	p.WithTimePrecision(0)
+	assert.Equal(t, time.Duration(0), p.TimePrecision())
+	assert.Equal(t, d, p.UTC(d).UTC())
+
+	now := p.Now()
+	assert.Equal(t, time.Time(now).Round(0), time.Time(now))
+
+	tx, err := p.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	assert.Equal(t, time.Duration(0), tx.(*xdb.SQLProvider).TimePrecision())
+
+	// the values are formatted and parsed with the provider's precision
+	d = time.Date(2019, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	tcases := []struct {
+		precision time.Duration
+		exp       string
+	}{
+		{xdb.PrecisionMillisecond, "2019-01-02T03:04:05.123Z"},
+		{xdb.PrecisionMicrosecond, "2019-01-02T03:04:05.123456Z"},
+		{xdb.PrecisionDatetime2, "2019-01-02T03:04:05.1234567Z"},
+		{xdb.PrecisionNanosecond, "2019-01-02T03:04:05.123456789Z"},
+		{0, "2019-01-02T03:04:05.123456789Z"},
+		{time.Second, "2019-01-02T03:04:05Z"},
+	}
+	for _, tc := range tcases {
+		p.WithTimePrecision(tc.precision)
+		s := p.FormatTime(xdb.Time(d))
+		assert.Equal(t, tc.exp, s)
+		assert.Equal(t, tc.exp, p.FormatTime(p.ParseTime(s)))
+		assert.Equal(t, p.UTC(d), p.ParseTime(s))
+	}
+	assert.Empty(t, p.FormatTime(xdb.Time{}))
+	assert.True(t, p.ParseTime("").IsZero())
+
+	// the package functions keep the default precision
+	assert.Equal(t, "2019-01-02T03:04:05.123Z", xdb.ParseTime("2019-01-02T03:04:05.123456789Z").String())
+}
+
+func TestWithStmtCache(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+	assert.Nil(t, p.StmtCache())
+	p.WithStmtCache(10)
+	require.NotNil(t, p.StmtCache())
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		_, err = p.ExecContext(xdb.WithStatementName(ctx, "t.insert"), "INSERT INTO t (id, name) VALUES (?, ?)", i, "n")
+		require.NoError(t, err)
+	}
+
+	q := xsql.NoDialect.From("t").Select("COUNT(*)").SetName("t.count")
+	defer q.Close()
+	var count int
+	require.NoError(t, p.QueryRowContext(ctx, q.String(), q).Scan(&count))
+	assert.Equal(t, 3, count)
+
+	stats := p.StmtCache().Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+
+	// statements in transaction are not cached
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		_, err := tx.ExecContext(xdb.WithStatementName(ctx, "t.delete"), "DELETE FROM t")
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.StmtCache().Stats().Size)
+}
+
+func TestExecBuilder(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t).WithStmtCache(10)
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+
+	q := xsql.NoDialect.InsertInto("t").
+		Set("id", 1).
+		Set("name", "n").
+		SetName("t.insert")
+	defer q.Close()
+	res, err := p.ExecContext(ctx, q.String(), q)
+	require.NoError(t, err)
+	n, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	// the statement name is taken from the builder
+	assert.Equal(t, 1, p.StmtCache().Stats().Size)
+
+	var name string
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT name FROM t WHERE id = ?", 1).Scan(&name))
+	assert.Equal(t, "n", name)
+}
+
+func TestBindIDArray(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE grp (id INTEGER PRIMARY KEY, members TEXT)")
+	require.NoError(t, err)
+
+	ids := xdb.NewIDArray([]uint64{10, 20, 30})
+	_, err = p.ExecContext(ctx, "INSERT INTO grp (id, members) VALUES (?, ?), (?, ?)", 1, ids, 2, xdb.IDArray{})
+	require.NoError(t, err)
+
+	var raw sql.NullString
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT members FROM grp WHERE id = ?", 1).Scan(&raw))
+	assert.Equal(t, "[10,20,30]", raw.String)
+
+	var members xdb.IDArray
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT members FROM grp WHERE id = ?", 1).Scan(&members))
+	assert.Equal(t, ids, members)
+
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT members FROM grp WHERE id = ?", 2).Scan(&members))
+	assert.Empty(t, members)
+
+	// the array is expanded with json_each, as OPENJSON on SQL Server
+	rows, err := p.QueryContext(ctx, "SELECT value FROM json_each(?) ORDER BY value", ids)
+	require.NoError(t, err)
+	defer rows.Close()
+	var list []uint64
+	for rows.Next() {
+		var id uint64
+		require.NoError(t, rows.Scan(&id))
+		list = append(list, id)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, ids.List(), list)
+}
@@ -6,31 +6,128 @@ import (
 	"encoding/json"
 
 	"github.com/effective-security/x/values"
+	"github.com/effective-security/xdb/xsql"
 	"github.com/pkg/errors"
 )
 
 // DefaultPageSize is the default page size
 const DefaultPageSize = 500
 
-// RowPointer defines a generic interface to scan a single row
+// RowPointer defines a generic pointer to the model of a single row.
+// The model is scanned by ScanRow, if it implements RowScanner,
+// or by "db" tags of the fields, so ad-hoc structs can be used without code generation:
+//
+//	type orgCount struct {
+//		OrgID xdb.ID `db:"org_id"`
+//		Count int64  `db:"count"`
+//	}
+//	list, err := xdb.ExecuteListQuery[orgCount](ctx, p, "SELECT org_id, COUNT(*) AS count FROM orgmember GROUP BY org_id")
 type RowPointer[T any] interface {
 	*T
-	RowScanner
 }
 
-// QueryRow runs a query and returns a single model
+// rowsLimiter is implemented by providers with the LIMIT policy
+type rowsLimiter interface {
+	limitRows(q xsql.Builder) xsql.Builder
+}
+
+// stmtQuery returns the query and arguments of xsql.Builder,
+// if it's passed as the only argument.
+// The LIMIT policy of the provider is applied to a copy of the statement,
+// and the statement name and idempotent hint are added to the context for routing.
+func stmtQuery(ctx context.Context, db DB, query string, args []any) (context.Context, string, []any) {
+	if len(args) == 1 {
+		if q, ok := args[0].(xsql.Builder); ok {
+			if name := q.Name(); name != "" && StatementName(ctx) == "" {
+				ctx = WithStatementName(ctx, name)
+			}
+			if q.IsIdempotent() {
+				ctx = Idempotent(ctx)
+			}
+			if l, ok := db.(rowsLimiter); ok {
+				if capped := l.limitRows(q); capped != nil {
+					// the copy is returned to the pool, its query and arguments are kept
+					query, args = capped.String(), append([]any(nil), capped.Args()...)
+					capped.Close()
+					return ctx, query, args
+				}
+			}
+			return ctx, query, q.Args()
+		}
+	}
+	return ctx, query, args
+}
+
+// QueryRow runs a query and returns a single model.
+// args can be a xsql.Builder or a list of arguments.
 func QueryRow[T any, TPointer RowPointer[T]](ctx context.Context, sql DB, query string, args ...any) (TPointer, error) {
-	row := sql.QueryRowContext(ctx, query, args...)
+	ctx, query, args = stmtQuery(ctx, sql, query, args)
 	var m TPointer = new(T)
-	err := m.ScanRow(row)
+	if rs, ok := any(m).(RowScanner); ok {
+		err := rs.ScanRow(sql.QueryRowContext(ctx, query, args...))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return m, nil
+	}
+
+	// the columns of sql.Row are not available to map the fields
+	rows, err := sql.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	scanner := &rowScanner{rows: rows}
+	if err = scanner.first(); err != nil {
+		return nil, err
+	}
+	if err = scanner.scan(m); err != nil {
+		return nil, errors.WithStack(err)
+	}
 	return m, nil
 }
 
-// ExecuteListQuery runs a query and returns a list of models
+type listCapacityKey struct{}
+
+// WithListCapacity returns a context with the initial capacity of the list
+// allocated by ExecuteListQuery, instead of DefaultPageSize.
+// Use it for the queries that return a few rows, or to preallocate large results.
+func WithListCapacity(ctx context.Context, capacity int) context.Context {
+	return context.WithValue(ctx, listCapacityKey{}, capacity)
+}
+
+// listCapacity returns the initial capacity of the list,
+// which does not exceed the row limit of the context
+func listCapacity(ctx context.Context) int {
+	capacity, ok := ctx.Value(listCapacityKey{}).(int)
+	if !ok || capacity <= 0 {
+		capacity = DefaultPageSize
+	}
+	if limit, ok := ResultLimitFromContext(ctx); ok && limit.MaxRows > 0 && limit.MaxRows < capacity {
+		capacity = limit.MaxRows
+	}
+	return capacity
+}
+
+// ExecuteListQuery runs a query and returns a list of models.
+// args can be a xsql.Builder or a list of arguments.
+// The list is allocated with DefaultPageSize capacity, or the capacity set by WithListCapacity.
+// The query is aborted with ResultTooLargeError,
+// if the rows exceed the limit set by WithResultLimit.
 func ExecuteListQuery[T any, TPointer RowPointer[T]](ctx context.Context, sql DB, query string, args ...any) ([]TPointer, error) {
+	return AppendListQuery[T, TPointer](ctx, sql, make([]TPointer, 0, listCapacity(ctx)), query, args...)
+}
+
+// AppendListQuery runs a query and appends the models to the list,
+// pass list[:0] to reuse the slice in hot paths.
+// args can be a xsql.Builder or a list of arguments.
+// The query is aborted with ResultTooLargeError,
+// if the rows exceed the limit set by WithResultLimit,
+// the rows in the list before the call are not counted.
+func AppendListQuery[T any, TPointer RowPointer[T]](ctx context.Context, sql DB, list []TPointer, query string, args ...any) ([]TPointer, error) {
+	ctx, query, args = stmtQuery(ctx, sql, query, args)
 	rows, err := sql.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -39,16 +136,23 @@ func ExecuteListQuery[T any, TPointer RowPointer[T]](ctx context.Context, sql DB
 		_ = rows.Close()
 	}()
 
-	list := make([]TPointer, 0, DefaultPageSize)
+	counter := newResultCounter(ctx)
+	scanner := &rowScanner{rows: rows}
 
 	for rows.Next() {
 		var m TPointer = new(T)
-		err = m.ScanRow(rows)
+		err = scanner.scan(m)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
+		if err = counter.add(m); err != nil {
+			return nil, err
+		}
 		list = append(list, m)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
 	return list, nil
 }
 
@@ -66,16 +170,28 @@ type SetCursor[T any, TPointer RowPointer[T]] func(lastRow TPointer) string
 
 // ExecuteQueryWithPagination runs a query and populates the result with a list of models and the next offset,
 // if there are more rows to fetch.
-// args can be a QueryParams or a list of arguments followed by the limit and offset.
+// args can be a QueryParams, a xsql.Builder or a list of arguments followed by the limit and offset.
+// The limit and offset of xsql.Builder are taken from its LIMIT and OFFSET clauses.
 func ExecuteQueryWithPagination[T any, TPointer RowPointer[T]](ctx context.Context, sql DB, res Result[T, TPointer], query string, args ...any) error {
 	var (
 		limit  uint32
 		offset uint32
 	)
 	if len(args) == 1 {
-		if qp, ok := args[0].(QueryParams); ok {
-			limit, offset = qp.Page()
-			args = qp.Args()
+		switch a := args[0].(type) {
+		case QueryParams:
+			limit, offset = a.Page()
+			args = a.Args()
+		case xsql.Builder:
+			// the statement may have other arguments after LIMIT and OFFSET
+			l, o := a.PageArgs()
+			if l == nil {
+				return errors.New("paginated statement must have LIMIT clause")
+			}
+			limit = PageParam(l)
+			if o != nil {
+				offset = PageParam(o)
+			}
 		}
 	} else if len(args) >= 2 {
 		clen := len(args)
@@ -83,6 +199,7 @@ func ExecuteQueryWithPagination[T any, TPointer RowPointer[T]](ctx context.Conte
 		limit = PageParam(args[clen-2])
 		offset = PageParam(args[clen-1])
 	}
+	ctx, query, args = stmtQuery(ctx, sql, query, args)
 
 	list, err := ExecuteListQuery[T, TPointer](ctx, sql, query, args...)
 	if err != nil {
@@ -100,15 +217,23 @@ func ExecuteQueryWithPagination[T any, TPointer RowPointer[T]](ctx context.Conte
 
 // ExecuteQueryWithCursor runs a query and populates the result with a list of models and the next cursor,
 // if there are more rows to fetch.
-// args can be a QueryParams or a list of arguments followed by the limit and offset.
+// args can be a QueryParams, a xsql.Builder or a list of arguments followed by the cursor and limit.
+// The limit of xsql.Builder is taken from its LIMIT clause.
 func ExecuteQueryWithCursor[T any, TPointer RowPointer[T]](ctx context.Context, sql DB, cursor SetCursor[T, TPointer], res ResultWithCursor[T, TPointer], query string, args ...any) error {
 	var (
 		limit uint32
 	)
 	if len(args) == 1 {
-		if qp, ok := args[0].(QueryParams); ok {
-			limit, _ = qp.Cursor()
-			args = qp.Args()
+		switch a := args[0].(type) {
+		case QueryParams:
+			limit, _ = a.Cursor()
+			args = a.Args()
+		case xsql.Builder:
+			l, _ := a.PageArgs()
+			if l == nil {
+				return errors.New("paginated statement must have LIMIT clause")
+			}
+			limit = PageParam(l)
 		}
 	} else if len(args) >= 2 {
 		clen := len(args)
@@ -116,6 +241,7 @@ func ExecuteQueryWithCursor[T any, TPointer RowPointer[T]](ctx context.Context,
 		// cursor = PageParam(args[clen-2])
 		limit = PageParam(args[clen-1])
 	}
+	ctx, query, args = stmtQuery(ctx, sql, query, args)
 
 	list, err := ExecuteListQuery[T, TPointer](ctx, sql, query, args...)
 	if err != nil {
@@ -131,7 +257,7 @@ func ExecuteQueryWithCursor[T any, TPointer RowPointer[T]](ctx context.Context,
 }
 
 // ExecuteQuery runs a query and populates the result with a list of models.
-// args can be a QueryParams or a list of arguments
+// args can be a QueryParams, a xsql.Builder or a list of arguments
 func ExecuteQuery[T any, TPointer RowPointer[T]](ctx context.Context, sql DB, res Result[T, TPointer], query string, args ...any) error {
 	if len(args) == 1 {
 		if qp, ok := args[0].(QueryParams); ok {
@@ -148,6 +274,28 @@ func ExecuteQuery[T any, TPointer RowPointer[T]](ctx context.Context, sql DB, re
 	return nil
 }
 
+// ExecuteQueryWithTotal runs a SELECT statement and the companion COUNT(*) query
+// over the same FROM and WHERE clauses, see xsql.Builder.CountQuery,
+// and returns a list of models and the total number of rows,
+// regardless of LIMIT and OFFSET of the statement.
+func ExecuteQueryWithTotal[T any, TPointer RowPointer[T]](ctx context.Context, sql DB, q xsql.Builder) ([]TPointer, int64, error) {
+	cq := q.CountQuery()
+	defer cq.Close()
+
+	list, err := ExecuteListQuery[T, TPointer](ctx, sql, q.String(), q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	cctx, query, args := stmtQuery(ctx, sql, cq.String(), []any{cq})
+	err = sql.QueryRowContext(cctx, query, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	return list, total, nil
+}
+
 // EncodeCursor encodes the offset or value into a cursor
 func EncodeCursor(val values.MapAny) string {
 	return base64.RawURLEncoding.EncodeToString([]byte(val.JSON()))
@@ -0,0 +1,368 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/xdbtest"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ xsql.Executor = xdb.Provider(nil)
+	_ xsql.Executor = xdb.Tx(nil)
+	_ xdb.DB        = xsql.Executor(nil)
+)
+
+type userResult struct {
+	Rows        []*user
+	HasNextPage bool
+	NextOffset  uint32
+}
+
+func (r *userResult) SetResult(rows []*user, hasNextPage bool, nextOffset uint32) {
+	r.Rows = rows
+	r.HasNextPage = hasNextPage
+	r.NextOffset = nextOffset
+}
+
+func TestQueryWithBuilder(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := xsql.NoDialect.New("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, email_verified BOOLEAN, name TEXT)").
+		ExecAndClose(ctx, p)
+	require.NoError(t, err)
+
+	for i, name := range []string{"A", "B", "C"} {
+		_, err = xsql.NoDialect.InsertInto("users").
+			Set("id", i+1).
+			Set("email", name+"@x").
+			Set("email_verified", false).
+			Set("name", name).
+			ExecAndClose(ctx, p)
+		require.NoError(t, err)
+	}
+
+	tx, err := p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	var count int
+	err = xsql.NoDialect.From("users").Select("COUNT(*)").To(&count).QueryRowAndClose(ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	require.NoError(t, tx.Commit())
+
+	q := xsql.NoDialect.From("users").
+		Select("id, email, email_verified, name").
+		Where("id > ?", 1).
+		OrderBy("id")
+	defer q.Close()
+
+	list, err := xdb.ExecuteListQuery[user](ctx, p, q.String(), q)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "B", list[0].Name)
+
+	m, err := xdb.QueryRow[user](ctx, p, q.String(), q)
+	require.NoError(t, err)
+	assert.Equal(t, "B", m.Name)
+
+	pq := xsql.NoDialect.From("users").
+		Select("id, email, email_verified, name").
+		OrderBy("id").
+		Limit(2).
+		Offset(0)
+	defer pq.Close()
+
+	var res userResult
+	err = xdb.ExecuteQueryWithPagination[user](ctx, p, &res, pq.String(), pq)
+	require.NoError(t, err)
+	assert.Len(t, res.Rows, 2)
+	assert.True(t, res.HasNextPage)
+	assert.Equal(t, uint32(2), res.NextOffset)
+
+	// the page is taken from LIMIT and OFFSET, not from the last arguments
+	wq := xsql.NoDialect.From("users").
+		Select("id, email, email_verified, name").
+		Where("id > ?", 1).
+		Where("email_verified = ?", false).
+		OrderBy("id").
+		Limit(5)
+	defer wq.Close()
+
+	res = userResult{}
+	err = xdb.ExecuteQueryWithPagination[user](ctx, p, &res, wq.String(), wq)
+	require.NoError(t, err)
+	assert.Len(t, res.Rows, 2)
+	assert.False(t, res.HasNextPage)
+	assert.Equal(t, uint32(0), res.NextOffset)
+
+	nq := xsql.NoDialect.From("users").
+		Select("id, email, email_verified, name").
+		Where("id > ?", 1).
+		Where("email_verified = ?", false)
+	defer nq.Close()
+	err = xdb.ExecuteQueryWithPagination[user](ctx, p, &res, nq.String(), nq)
+	assert.EqualError(t, err, "paginated statement must have LIMIT clause")
+
+	list, total, err := xdb.ExecuteQueryWithTotal[user](ctx, p, pq)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, int64(3), total)
+}
+
+func TestMaxRows(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := xsql.NoDialect.New("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, email_verified BOOLEAN, name TEXT)").
+		ExecAndClose(ctx, p)
+	require.NoError(t, err)
+
+	for i, name := range []string{"A", "B", "C"} {
+		_, err = xsql.NoDialect.InsertInto("users").
+			Set("id", i+1).
+			Set("email", name+"@x").
+			Set("email_verified", false).
+			Set("name", name).
+			ExecAndClose(ctx, p)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, uint32(0), p.MaxRows())
+	p.WithMaxRows(2)
+	assert.Equal(t, uint32(2), p.MaxRows())
+
+	q := xsql.NoDialect.From("users").
+		Select("id, email, email_verified, name").
+		OrderBy("id")
+	defer q.Close()
+
+	list, err := xdb.ExecuteListQuery[user](ctx, p, q.String(), q)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+	// the statement is not changed, and can be reused
+	assert.False(t, q.HasLimit())
+	assert.Empty(t, q.Args())
+	list, err = xdb.ExecuteListQuery[user](ctx, p, q.String(), q)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	// explicit LIMIT is preserved
+	lq := xsql.NoDialect.From("users").
+		Select("id, email, email_verified, name").
+		OrderBy("id").
+		Limit(1)
+	defer lq.Close()
+
+	list, err = xdb.ExecuteListQuery[user](ctx, p, lq.String(), lq)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	uq := xsql.NoDialect.From("users").
+		Select("id, email, email_verified, name").
+		OrderBy("id").
+		Unbounded()
+	defer uq.Close()
+
+	list, err = xdb.ExecuteListQuery[user](ctx, p, uq.String(), uq)
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+
+	// raw SQL is not modified
+	list, err = xdb.ExecuteListQuery[user](ctx, p, "SELECT id, email, email_verified, name FROM users")
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+
+	// the policy is inherited by transactions
+	tx, err := p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	tq := xsql.NoDialect.From("users").
+		Select("id, email, email_verified, name").
+		OrderBy("id")
+	defer tq.Close()
+
+	rows, err := tx.QueryContext(ctx, tq.String(), tq)
+	require.NoError(t, err)
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	require.NoError(t, rows.Close())
+	assert.Equal(t, 2, count)
+}
+
+func TestResultLimit(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := xsql.NoDialect.New("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, email_verified BOOLEAN, name TEXT)").
+		ExecAndClose(ctx, p)
+	require.NoError(t, err)
+
+	for i, name := range []string{"A", "B", "C"} {
+		_, err = xsql.NoDialect.InsertInto("users").
+			Set("id", i+1).
+			Set("email", name+"@x").
+			Set("email_verified", false).
+			Set("name", name).
+			ExecAndClose(ctx, p)
+		require.NoError(t, err)
+	}
+
+	query := "SELECT id, email, email_verified, name FROM users ORDER BY id"
+
+	_, ok := xdb.ResultLimitFromContext(ctx)
+	assert.False(t, ok)
+
+	list, err := xdb.ExecuteListQuery[user](xdb.WithResultLimit(ctx, xdb.ResultLimit{MaxRows: 3}), p, query)
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+
+	lctx := xdb.WithResultLimit(ctx, xdb.ResultLimit{MaxRows: 2})
+	limit, ok := xdb.ResultLimitFromContext(lctx)
+	require.True(t, ok)
+	assert.Equal(t, 2, limit.MaxRows)
+
+	_, err = xdb.ExecuteListQuery[user](lctx, p, query)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, xdb.ErrResultTooLarge)
+	var tooLarge *xdb.ResultTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	assert.Equal(t, 3, tooLarge.Rows)
+	assert.Equal(t, int64(0), tooLarge.Bytes)
+	assert.Equal(t, "result is too large: 3 rows, 0 bytes read, limit: 2 rows, 0 bytes", err.Error())
+
+	_, err = xdb.ExecuteListQuery[user](xdb.WithResultLimit(ctx, xdb.ResultLimit{MaxBytes: 10}), p, query)
+	require.ErrorAs(t, err, &tooLarge)
+	assert.Equal(t, 1, tooLarge.Rows)
+	assert.Greater(t, tooLarge.Bytes, int64(10))
+
+	list, err = xdb.ExecuteListQuery[user](xdb.WithResultLimit(ctx, xdb.ResultLimit{MaxBytes: 1 << 20}), p, query)
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+}
+
+func TestListCapacity(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := xsql.NoDialect.New("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, email_verified BOOLEAN, name TEXT)").
+		ExecAndClose(ctx, p)
+	require.NoError(t, err)
+
+	for i, name := range []string{"A", "B", "C"} {
+		_, err = xsql.NoDialect.InsertInto("users").
+			Set("id", i+1).
+			Set("email", name+"@x").
+			Set("email_verified", false).
+			Set("name", name).
+			ExecAndClose(ctx, p)
+		require.NoError(t, err)
+	}
+
+	query := "SELECT id, email, email_verified, name FROM users ORDER BY id"
+
+	list, err := xdb.ExecuteListQuery[user](ctx, p, query)
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+	assert.Equal(t, xdb.DefaultPageSize, cap(list))
+
+	list, err = xdb.ExecuteListQuery[user](xdb.WithListCapacity(ctx, 4), p, query)
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+	assert.Equal(t, 4, cap(list))
+
+	// the capacity does not exceed the row limit
+	list, err = xdb.ExecuteListQuery[user](xdb.WithResultLimit(ctx, xdb.ResultLimit{MaxRows: 3}), p, query)
+	require.NoError(t, err)
+	assert.Equal(t, 3, cap(list))
+
+	buf := make([]*user, 0, 8)
+	list, err = xdb.AppendListQuery[user](ctx, p, buf, query)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Same(t, &buf[:1][0], &list[0])
+	assert.Equal(t, "A", list[0].Name)
+
+	list, err = xdb.AppendListQuery[user](ctx, p, list, "SELECT id, email, email_verified, name FROM users WHERE id = ?", 3)
+	require.NoError(t, err)
+	require.Len(t, list, 4)
+	assert.Equal(t, "C", list[3].Name)
+
+	// the rows in the list are not counted by the limit
+	list, err = xdb.AppendListQuery[user](xdb.WithResultLimit(ctx, xdb.ResultLimit{MaxRows: 3}), p, list[:2], query)
+	require.NoError(t, err)
+	assert.Len(t, list, 5)
+
+	_, err = xdb.AppendListQuery[user](xdb.WithResultLimit(ctx, xdb.ResultLimit{MaxRows: 2}), p, list[:0], query)
+	assert.ErrorIs(t, err, xdb.ErrResultTooLarge)
+}
+
+func TestMaxRowsNamed(t *testing.T) {
+	ctx := context.Background()
+	fake := xdbtest.New("postgres")
+	p := fake.Provider().WithMaxRows(100)
+	defer p.Close()
+
+	fake.ExpectQuery(`^SELECT id, email, email_verified, name\s+FROM users\s+WHERE id > \$1\s+LIMIT \$2$`).
+		WithArgs(1, uint32(100)).
+		WillReturnRows([]string{"id", "email", "email_verified", "name"}, nil).
+		Times(2)
+
+	q := xsql.Postgres.From("users").
+		Select("id, email, email_verified, name").
+		Where("id > ?", 1).
+		SetName("ListUsersCapped")
+	defer q.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err := xdb.ExecuteListQuery[user](ctx, p, q.String(), q)
+		require.NoError(t, err)
+	}
+	require.NoError(t, fake.ExpectationsWereMet())
+	assert.Equal(t, "SELECT id, email, email_verified, name \nFROM users \nWHERE id > $1", q.String())
+	assert.Equal(t, []any{1}, q.Args())
+}
+
+func TestMaxRowsSQLServer(t *testing.T) {
+	ctx := context.Background()
+	fake := xdbtest.New("sqlserver")
+	p := fake.Provider().WithMaxRows(100)
+	defer p.Close()
+
+	fake.ExpectQuery(`^SELECT TOP \(\?\) id, email, email_verified, name\s+FROM users\s+WHERE id > \?$`).
+		WithArgs(uint32(100), 1).
+		WillReturnRows([]string{"id", "email", "email_verified", "name"}, nil)
+
+	q := xsql.SQLServer.From("users").
+		Select("id, email, email_verified, name").
+		Where("id > ?", 1)
+	defer q.Close()
+
+	_, err := xdb.ExecuteListQuery[user](ctx, p, q.String(), q)
+	require.NoError(t, err)
+
+	// TOP can't be combined with OFFSET
+	fake.ExpectQuery(`^SELECT id, email, email_verified, name\s+FROM users\s+WHERE id > \?\s+ORDER BY id\s+OFFSET \? ROWS FETCH NEXT \? ROWS ONLY$`).
+		WithArgs(1, 20, uint32(100)).
+		WillReturnRows([]string{"id", "email", "email_verified", "name"}, nil)
+
+	q2 := xsql.SQLServer.From("users").
+		Select("id, email, email_verified, name").
+		Where("id > ?", 1).
+		OrderBy("id").
+		Offset(20)
+	defer q2.Close()
+
+	_, err = xdb.ExecuteListQuery[user](ctx, p, q2.String(), q2)
+	require.NoError(t, err)
+	require.NoError(t, fake.ExpectationsWereMet())
+}
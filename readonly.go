@@ -0,0 +1,240 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+)
+
+// HealthState describes the availability of the primary for writes
+type HealthState int
+
+const (
+	// HealthOK indicates that writes are executed on the primary
+	HealthOK HealthState = iota
+	// HealthReadOnly indicates that the primary is unavailable,
+	// writes fail fast with ReadOnlyError, and reads are served by replicas
+	HealthReadOnly
+)
+
+// String returns the state name
+func (s HealthState) String() string {
+	switch s {
+	case HealthOK:
+		return "ok"
+	case HealthReadOnly:
+		return "read_only"
+	default:
+		return fmt.Sprintf("HealthState(%d)", int(s))
+	}
+}
+
+// HealthEvent describes the change of the health state
+type HealthEvent struct {
+	State HealthState
+	// Since is the time the state changed
+	Since time.Time
+	// Err is the write failure that opened the circuit, for HealthReadOnly
+	Err error
+}
+
+// HealthHandler is called when the provider switches to or from read-only mode,
+// the application can use it to show a maintenance banner
+type HealthHandler func(HealthEvent)
+
+// ReadOnlyError is returned for writes while the provider is in read-only mode
+type ReadOnlyError struct {
+	// Since is the time the provider switched to read-only mode
+	Since time.Time
+	// Err is the write failure that opened the circuit
+	Err error
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("database is in read-only mode since %s: %v", e.Since.Format(time.RFC3339), e.Err)
+}
+
+// Unwrap returns the write failure that opened the circuit
+func (e *ReadOnlyError) Unwrap() error {
+	return e.Err
+}
+
+// writeBreaker is the circuit breaker of writes on the primary
+type writeBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	lock     sync.Mutex
+	failures int
+	state    HealthState
+	since    time.Time
+	// probeAt is the time when the next write is allowed to probe the primary
+	probeAt time.Time
+	err     error
+}
+
+// WithReadOnlyDegradation enables switching to read-only mode
+// after threshold consecutive writes failed with a bad connection error.
+// In read-only mode writes fail fast with ReadOnlyError,
+// and all reads, except snapshots, are routed to replicas.
+// After cooldown a single write is allowed to probe the primary,
+// and the provider returns to normal mode when it succeeds.
+// Zero threshold disables the degradation.
+func (p *ReplicaProvider) WithReadOnlyDegradation(threshold int, cooldown time.Duration) *ReplicaProvider {
+	if threshold <= 0 {
+		p.breaker = nil
+		return p
+	}
+	p.breaker = &writeBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+	return p
+}
+
+// WithHealthHandler sets the handler called when the health state changes
+func (p *ReplicaProvider) WithHealthHandler(handler HealthHandler) *ReplicaProvider {
+	p.onHealth = handler
+	return p
+}
+
+// Health returns the current health state
+func (p *ReplicaProvider) Health() HealthState {
+	if p.breaker == nil {
+		return HealthOK
+	}
+	p.breaker.lock.Lock()
+	defer p.breaker.lock.Unlock()
+	return p.breaker.state
+}
+
+// ReadOnly returns true if the provider is in read-only mode
+func (p *ReplicaProvider) ReadOnly() bool {
+	return p.Health() == HealthReadOnly
+}
+
+// allowWrite returns ReadOnlyError if the write must fail fast
+func (p *ReplicaProvider) allowWrite() error {
+	b := p.breaker
+	if b == nil {
+		return nil
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.state == HealthOK {
+		return nil
+	}
+	now := p.Clock().Now()
+	if now.Before(b.probeAt) {
+		return &ReadOnlyError{Since: b.since, Err: b.err}
+	}
+	// let this write to probe the primary, others fail fast until the result
+	b.probeAt = now.Add(b.cooldown)
+	return nil
+}
+
+// writeDone updates the breaker with the result of the write
+func (p *ReplicaProvider) writeDone(ctx context.Context, err error) {
+	b := p.breaker
+	if b == nil {
+		return
+	}
+	if err != nil && !IsBadConnectionError(err) {
+		// the primary responded
+		err = nil
+	}
+
+	var ev *HealthEvent
+	b.lock.Lock()
+	switch {
+	case err == nil:
+		b.failures = 0
+		if b.state == HealthReadOnly {
+			b.state = HealthOK
+			b.since = p.Clock().Now()
+			b.err = nil
+			ev = &HealthEvent{State: HealthOK, Since: b.since}
+		}
+	case b.state == HealthReadOnly:
+		b.err = err
+		b.probeAt = p.Clock().Now().Add(b.cooldown)
+	default:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.state = HealthReadOnly
+			b.since = p.Clock().Now()
+			b.probeAt = b.since.Add(b.cooldown)
+			b.err = err
+			ev = &HealthEvent{State: HealthReadOnly, Since: b.since, Err: err}
+		}
+	}
+	b.lock.Unlock()
+
+	if ev == nil {
+		return
+	}
+	if ev.State == HealthReadOnly {
+		logger.KV(xlog.ERROR,
+			"reason", "read_only",
+			"statement", StatementName(ctx),
+			"err", ev.Err)
+	} else {
+		logger.KV(xlog.NOTICE, "reason", "read_write")
+	}
+	if p.onHealth != nil {
+		p.onHealth(*ev)
+	}
+}
+
+// errConnector fails to connect with the error,
+// it's used to return *sql.Row with the error
+type errConnector struct {
+	err error
+}
+
+func (c errConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, c.err
+}
+
+func (c errConnector) Driver() driver.Driver {
+	return c
+}
+
+func (c errConnector) Open(string) (driver.Conn, error) {
+	return nil, c.err
+}
+
+// errRow returns *sql.Row that reports err on Scan,
+// as sql.Row can't be created with an error
+func errRow(ctx context.Context, err error) *sql.Row {
+	db := sql.OpenDB(errConnector{err: err})
+	defer db.Close()
+	return db.QueryRowContext(ctx, "")
+}
+
+// ExecContext executes a query without returning any rows on the primary.
+// In read-only mode it fails fast with ReadOnlyError.
+func (p *ReplicaProvider) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := p.allowWrite(); err != nil {
+		return nil, err
+	}
+	res, err := p.SQLProvider.ExecContext(ctx, query, args...)
+	p.writeDone(ctx, err)
+	return res, err
+}
+
+// BeginTx starts a transaction on the primary.
+// In read-only mode it fails fast with ReadOnlyError.
+func (p *ReplicaProvider) BeginTx(ctx context.Context, opts *sql.TxOptions) (Provider, error) {
+	if err := p.allowWrite(); err != nil {
+		return nil, err
+	}
+	tx, err := p.SQLProvider.BeginTx(ctx, opts)
+	p.writeDone(ctx, err)
+	return tx, err
+}
@@ -0,0 +1,117 @@
+package xdb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyDriver opens sqlite3 connections while the primary is up
+type flakyDriver struct {
+	down *atomic.Bool
+}
+
+func (d flakyDriver) Open(name string) (driver.Conn, error) {
+	if d.down.Load() {
+		return nil, driver.ErrBadConn
+	}
+	return (&sqlite3.SQLiteDriver{}).Open(name)
+}
+
+var primaryDown atomic.Bool
+
+func init() {
+	sql.Register("flaky", flakyDriver{down: &primaryDown})
+}
+
+func TestReplicaProviderReadOnly(t *testing.T) {
+	ctx := context.Background()
+	clock := xdb.NewFixedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	d, err := sql.Open("flaky", ":memory:")
+	require.NoError(t, err)
+	// every statement opens a new connection
+	d.SetMaxIdleConns(-1)
+	primary, err := xdb.New("flaky", d, nil)
+	require.NoError(t, err)
+	primary.WithClock(clock)
+
+	var events []xdb.HealthEvent
+	p := xdb.NewReplicaProvider(primary,
+		xdb.Replica{Name: "r1", Provider: openNode(t, "r1")},
+	).
+		WithDefaultRoute(xdb.RoutePrimary).
+		WithReadOnlyDegradation(2, time.Minute).
+		WithHealthHandler(func(ev xdb.HealthEvent) {
+			events = append(events, ev)
+		})
+	defer p.Close()
+	assert.Equal(t, xdb.HealthOK, p.Health())
+
+	_, err = p.ExecContext(ctx, "SELECT 1")
+	require.NoError(t, err)
+
+	primaryDown.Store(true)
+	defer primaryDown.Store(false)
+
+	_, err = p.ExecContext(ctx, "SELECT 1")
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.False(t, p.ReadOnly())
+	_, err = p.BeginTx(ctx, nil)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.True(t, p.ReadOnly())
+	require.Len(t, events, 1)
+	assert.Equal(t, xdb.HealthReadOnly, events[0].State)
+	assert.Equal(t, clock.Now(), events[0].Since)
+	assert.Equal(t, "read_only", events[0].State.String())
+
+	// writes fail fast
+	_, err = p.ExecContext(ctx, "SELECT 1")
+	var roErr *xdb.ReadOnlyError
+	require.True(t, errors.As(err, &roErr))
+	assert.Equal(t, clock.Now(), roErr.Since)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	_, err = p.BeginTx(ctx, nil)
+	assert.True(t, errors.As(err, &roErr))
+
+	// reads are served by replicas
+	assert.Equal(t, "r1", nodeName(t, ctx, p))
+	assert.Equal(t, "r1", nodeName(t, xdb.PreferPrimary(ctx), p))
+
+	// the probe fails
+	clock.Add(time.Minute)
+	_, err = p.ExecContext(ctx, "SELECT 1")
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.False(t, errors.As(err, &roErr))
+	_, err = p.ExecContext(ctx, "SELECT 1")
+	assert.True(t, errors.As(err, &roErr))
+	assert.Len(t, events, 1)
+
+	// the probe succeeds
+	primaryDown.Store(false)
+	clock.Add(time.Minute)
+	_, err = p.ExecContext(ctx, "SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, xdb.HealthOK, p.Health())
+	require.Len(t, events, 2)
+	assert.Equal(t, xdb.HealthEvent{State: xdb.HealthOK, Since: clock.Now()}, events[1])
+
+	var one int
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT 1").Scan(&one))
+
+	// errors reported by the primary don't open the circuit
+	for i := 0; i < 3; i++ {
+		_, err = p.ExecContext(ctx, "INSERT INTO missing VALUES (1)")
+		require.Error(t, err)
+	}
+	assert.False(t, p.ReadOnly())
+}
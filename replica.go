@@ -0,0 +1,617 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// Route specifies where a query is executed
+type Route int
+
+const (
+	// RouteDefault uses the routing rules of the provider
+	RouteDefault Route = iota
+	// RoutePrimary executes the query on the primary
+	RoutePrimary
+	// RouteReplica executes the query on one of the replicas
+	RouteReplica
+)
+
+type routeKey struct{}
+type idempotentKey struct{}
+
+// PreferReplica returns a context that routes queries to replicas,
+// it takes precedence over the statement routing rules
+func PreferReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, RouteReplica)
+}
+
+// PreferPrimary returns a context that routes queries to the primary,
+// for example to read own writes.
+// It takes precedence over the statement routing rules
+func PreferPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, RoutePrimary)
+}
+
+// RouteFromContext returns the route set by PreferReplica or PreferPrimary
+func RouteFromContext(ctx context.Context) Route {
+	if r, ok := ctx.Value(routeKey{}).(Route); ok {
+		return r
+	}
+	return RouteDefault
+}
+
+// WithStatementName returns a context with the statement name for routing rules,
+// the name of xsql.Builder passed as the query argument is used by default
+func WithStatementName(ctx context.Context, name string) context.Context {
+	return xsql.WithStatementName(ctx, name)
+}
+
+// StatementName returns the statement name from the context,
+// or the name of xsql.Builder executed by Exec, Query or QueryRow
+func StatementName(ctx context.Context) string {
+	return xsql.StatementName(ctx)
+}
+
+// Idempotent returns a context that marks queries as read-only and safe to retry,
+// the query is retried on another replica when the replica connection fails.
+// xsql.Builder marked with Idempotent, and passed as the query argument, sets the hint as well
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// IsIdempotent returns true if the context is marked with Idempotent
+func IsIdempotent(ctx context.Context) bool {
+	v, _ := ctx.Value(idempotentKey{}).(bool)
+	return v
+}
+
+// ReplicaLagFunc returns the replication lag of the replica in bytes
+type ReplicaLagFunc func(ctx context.Context, primary, replica DB) (int64, error)
+
+// PostgresReplicaLag returns the number of bytes of WAL
+// that the replica has not replayed yet
+func PostgresReplicaLag(ctx context.Context, primary, replica DB) (int64, error) {
+	var lsn string
+	err := primary.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed to query primary WAL position")
+	}
+
+	// pg_last_wal_replay_lsn returns NULL if the server is not in recovery
+	var lag sql.NullInt64
+	err = replica.QueryRowContext(ctx, "SELECT pg_wal_lsn_diff($1::pg_lsn, pg_last_wal_replay_lsn())::bigint", lsn).Scan(&lag)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed to query replica WAL position")
+	}
+	return max(lag.Int64, 0), nil
+}
+
+// PingReplica verifies the replica responds to queries,
+// and reports zero lag. It is used as the health check
+// for providers without replication lag measurement
+func PingReplica(ctx context.Context, _, replica DB) (int64, error) {
+	var n int
+	err := replica.QueryRowContext(ctx, "SELECT 1").Scan(&n)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed to ping replica")
+	}
+	return 0, nil
+}
+
+// DefaultReplicaHealthCheckPeriod specifies how often
+// NewProviderWithReplicas checks the replicas
+const DefaultReplicaHealthCheckPeriod = 30 * time.Second
+
+// Replica describes a read replica
+type Replica struct {
+	// Name of the replica for logs and status
+	Name string
+	// Provider for the replica connection
+	Provider *SQLProvider
+	// Weight specifies the share of queries routed to the replica,
+	// relative to other replicas, default is 1
+	Weight int
+}
+
+// ReplicaStatus describes the state of the replica
+type ReplicaStatus struct {
+	Name   string
+	Weight int
+	// Lag is the last measured replication lag in bytes
+	Lag int64
+	// Lagging is true when the replica is excluded from routing
+	Lagging bool
+	// Err is the last error of the lag measurement
+	Err error
+	// Failovers is the number of queries retried on another node,
+	// after the replica connection failed
+	Failovers uint64
+}
+
+// FailoverEvent describes a query retried on another node
+type FailoverEvent struct {
+	// Replica is the name of the failed replica
+	Replica string
+	// Target is the name of the replica to retry the query,
+	// or empty if the query is retried on the primary
+	Target string
+	// Statement is the name of the statement, if provided
+	Statement string
+	// Err is the error of the failed replica
+	Err error
+}
+
+// FailoverHandler is called on every failover event,
+// for example to report metrics
+type FailoverHandler func(FailoverEvent)
+
+type replicaState struct {
+	Replica
+	lag       int64
+	lagging   bool
+	err       error
+	failovers uint64
+}
+
+type routingRule struct {
+	pattern string
+	route   Route
+}
+
+// ReplicaProvider routes read queries to replicas,
+// while transactions, ExecContext and the queries that modify data
+// or lock rows are executed on the primary.
+// The route of a query is chosen in order by:
+// PreferPrimary or PreferReplica in the context,
+// the first routing rule matching the statement name,
+// and the default route, which is RouteReplica.
+//
+// Idempotent queries that fail on a replica with a bad connection error
+// are retried on other replicas, and then on the primary.
+//
+// With WithReadOnlyDegradation, the provider switches to read-only mode
+// when the primary is unavailable for writes.
+type ReplicaProvider struct {
+	*SQLProvider
+
+	lock         sync.RWMutex
+	replicas     []*replicaState
+	rules        []routingRule
+	defaultRoute Route
+	maxLag       int64
+	lagFunc      ReplicaLagFunc
+	stopCheck    chan struct{}
+	checkStopped chan struct{}
+	onFailover   FailoverHandler
+	breaker      *writeBreaker
+	onHealth     HealthHandler
+	roundRobin   bool
+	next         atomic.Uint64
+}
+
+// NewReplicaProvider returns a provider with read replicas
+func NewReplicaProvider(primary *SQLProvider, replicas ...Replica) *ReplicaProvider {
+	p := &ReplicaProvider{
+		SQLProvider:  primary,
+		defaultRoute: RouteReplica,
+	}
+	if primary.Name() == "postgres" {
+		p.lagFunc = PostgresReplicaLag
+	}
+	for _, r := range replicas {
+		if r.Weight <= 0 {
+			r.Weight = 1
+		}
+		p.replicas = append(p.replicas, &replicaState{Replica: r})
+	}
+	return p
+}
+
+/*
+NewProviderWithReplicas opens the primary and replicas,
+and returns the provider that routes read queries to the replicas in round-robin order,
+while transactions, ExecContext and the queries that modify data
+or lock rows are executed on the primary:
+
+	p, err := xdb.NewProviderWithReplicas(
+		"postgres://primary:5432/orgsdb?sslmode=disable",
+		"postgres://replica1:5432/orgsdb?sslmode=disable",
+		"postgres://replica2:5432/orgsdb?sslmode=disable",
+	)
+
+The replicas are checked every DefaultReplicaHealthCheckPeriod,
+the replicas that fail the check are excluded from routing until they recover.
+The replication lag is measured on Postgres, and PingReplica is used for other providers,
+use WithMaxReplicaLag to change the limit and the period.
+*/
+func NewProviderWithReplicas(primaryDSN string, replicaDSNs ...string) (*ReplicaProvider, error) {
+	var opened []*SQLProvider
+	open := func(dsn string) (*SQLProvider, error) {
+		d, provider, connstr, err := Open(dsn, "")
+		if err != nil {
+			return nil, err
+		}
+		p, err := New(provider, d, nil)
+		if err != nil {
+			_ = d.Close()
+			return nil, err
+		}
+		p.WithConnectionString(connstr)
+		opened = append(opened, p)
+		return p, nil
+	}
+	closeAll := func() {
+		for _, p := range opened {
+			_ = p.Close()
+		}
+	}
+
+	primary, err := open(primaryDSN)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to open primary DB")
+	}
+	var replicas []Replica
+	for i, dsn := range replicaDSNs {
+		r, err := open(dsn)
+		if err != nil {
+			closeAll()
+			return nil, errors.WithMessagef(err, "failed to open replica %d", i+1)
+		}
+		replicas = append(replicas, Replica{Name: fmt.Sprintf("replica%d", i+1), Provider: r})
+	}
+
+	p := NewReplicaProvider(primary, replicas...).WithRoundRobin()
+	if p.lagFunc == nil {
+		p.lagFunc = PingReplica
+	}
+	return p.WithMaxReplicaLag(0, DefaultReplicaHealthCheckPeriod), nil
+}
+
+// WithRoundRobin routes the queries to the replicas in turn, proportionally to the weights,
+// instead of the random choice
+func (p *ReplicaProvider) WithRoundRobin() *ReplicaProvider {
+	p.roundRobin = true
+	return p
+}
+
+// WithDefaultRoute sets the route for queries not matched by the rules
+func (p *ReplicaProvider) WithDefaultRoute(route Route) *ReplicaProvider {
+	p.defaultRoute = route
+	return p
+}
+
+// WithRoutingRule adds the rule to route statements by name,
+// the pattern has path.Match syntax, for example "report.*".
+// The name is taken from xsql.Builder passed as the query argument,
+// or from WithStatementName.
+func (p *ReplicaProvider) WithRoutingRule(pattern string, route Route) *ReplicaProvider {
+	p.rules = append(p.rules, routingRule{pattern: pattern, route: route})
+	return p
+}
+
+// WithReplicaLagFunc sets the function to measure replication lag,
+// PostgresReplicaLag is used by default for Postgres
+func (p *ReplicaProvider) WithReplicaLagFunc(fn ReplicaLagFunc) *ReplicaProvider {
+	p.lagFunc = fn
+	return p
+}
+
+// WithFailoverHandler sets the handler called when a query is retried on another node
+func (p *ReplicaProvider) WithFailoverHandler(handler FailoverHandler) *ReplicaProvider {
+	p.onFailover = handler
+	return p
+}
+
+// WithMaxReplicaLag excludes replicas with replication lag over maxLag bytes from routing,
+// the lag is measured every period, if period is positive
+func (p *ReplicaProvider) WithMaxReplicaLag(maxLag int64, period time.Duration) *ReplicaProvider {
+	p.maxLag = maxLag
+	p.stopHealthCheck()
+	if period > 0 && p.lagFunc != nil {
+		stop, stopped := make(chan struct{}), make(chan struct{})
+		p.stopCheck, p.checkStopped = stop, stopped
+		go func() {
+			defer close(stopped)
+			ticker := time.NewTicker(period)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					p.CheckReplicaLag(context.Background())
+				}
+			}
+		}()
+	}
+	return p
+}
+
+// stopHealthCheck stops the periodic check of the replicas,
+// and waits for the running check to complete
+func (p *ReplicaProvider) stopHealthCheck() {
+	if p.stopCheck != nil {
+		close(p.stopCheck)
+		<-p.checkStopped
+		p.stopCheck, p.checkStopped = nil, nil
+	}
+}
+
+// CheckReplicaLag measures replication lag of the replicas,
+// and excludes the lagging ones from routing
+func (p *ReplicaProvider) CheckReplicaLag(ctx context.Context) {
+	if p.lagFunc == nil {
+		return
+	}
+
+	for _, r := range p.replicas {
+		lag, err := p.lagFunc(ctx, p.SQLProvider.DB(), r.Provider.DB())
+		lagging := err != nil || (p.maxLag > 0 && lag > p.maxLag)
+
+		p.lock.Lock()
+		if lagging != r.lagging {
+			logger.KV(xlog.WARNING,
+				"reason", "replica_lag",
+				"replica", r.Name,
+				"lag", lag,
+				"lagging", lagging,
+				"err", err)
+		}
+		r.lag = lag
+		r.err = err
+		r.lagging = lagging
+		p.lock.Unlock()
+	}
+}
+
+// Replicas returns the state of the replicas
+func (p *ReplicaProvider) Replicas() []ReplicaStatus {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	list := make([]ReplicaStatus, 0, len(p.replicas))
+	for _, r := range p.replicas {
+		list = append(list, ReplicaStatus{
+			Name:      r.Name,
+			Weight:    r.Weight,
+			Lag:       r.lag,
+			Lagging:   r.lagging,
+			Err:       r.err,
+			Failovers: r.failovers,
+		})
+	}
+	return list
+}
+
+// route returns the replica to execute the query,
+// or nil for the primary
+func (p *ReplicaProvider) route(ctx context.Context) *replicaState {
+	if SnapshotFromContext(ctx) != nil || p.contextTx(ctx) != nil {
+		return nil
+	}
+
+	route := RouteFromContext(ctx)
+	if route == RouteDefault {
+		if name := StatementName(ctx); name != "" {
+			for _, rule := range p.rules {
+				if ok, _ := path.Match(rule.pattern, name); ok {
+					route = rule.route
+					break
+				}
+			}
+		}
+	}
+	if route == RouteDefault {
+		route = p.defaultRoute
+	}
+	if route != RouteReplica && !p.ReadOnly() {
+		return nil
+	}
+	return p.pickReplica(nil)
+}
+
+var (
+	// readVerbs are the statements that can be executed on a replica
+	readVerbs = map[string]bool{
+		"SELECT":  true,
+		"WITH":    true,
+		"VALUES":  true,
+		"TABLE":   true,
+		"SHOW":    true,
+		"EXPLAIN": true,
+	}
+	// writeClauses match the clauses that modify data or lock rows,
+	// including writable CTE, RETURNING, OUTPUT, SELECT INTO and lock hints
+	writeClauses = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|RETURNING|INTO|NEXTVAL|SETVAL|UPDLOCK|XLOCK|HOLDLOCK)\b|\bOUTPUT\s+(INSERTED|DELETED)\b|\bFOR\s+(KEY\s+)?SHARE\b|\bLOCK\s+IN\s+SHARE\s+MODE\b`)
+	// leadingComments match the comments and parentheses before the verb
+	leadingComments = regexp.MustCompile(`^(\s+|--[^\n]*|/\*(?s:.*?)\*/|\()*`)
+)
+
+// isReadQuery returns true if the query is a plain SELECT,
+// that does not modify data or lock rows, and can be executed on a replica
+func isReadQuery(query string) bool {
+	query = leadingComments.ReplaceAllString(query, "")
+	verb := query
+	if idx := strings.IndexAny(query, " \t\r\n("); idx >= 0 {
+		verb = query[:idx]
+	}
+	if !readVerbs[strings.ToUpper(verb)] {
+		return false
+	}
+	return !writeClauses.MatchString(query)
+}
+
+// isWrite returns true if the query must be executed on the primary as a write,
+// the queries in the transaction or snapshot are executed in it
+func (p *ReplicaProvider) isWrite(ctx context.Context, query string) bool {
+	if SnapshotFromContext(ctx) != nil || p.contextTx(ctx) != nil {
+		return false
+	}
+	return !isReadQuery(query)
+}
+
+// provider returns the provider of the replica, or the primary for nil
+func (p *ReplicaProvider) provider(r *replicaState) *SQLProvider {
+	if r == nil {
+		return p.SQLProvider
+	}
+	return r.Provider
+}
+
+// pickReplica returns a random replica by weight,
+// or the next one in round-robin mode,
+// excluding lagging and already tried ones
+func (p *ReplicaProvider) pickReplica(tried []*replicaState) *replicaState {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	total := 0
+	for _, r := range p.replicas {
+		if !r.lagging && !slices.Contains(tried, r) {
+			total += r.Weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	var n int
+	if p.roundRobin {
+		n = int((p.next.Add(1) - 1) % uint64(total))
+	} else {
+		n = rand.IntN(total)
+	}
+	for _, r := range p.replicas {
+		if r.lagging || slices.Contains(tried, r) {
+			continue
+		}
+		if n < r.Weight {
+			return r
+		}
+		n -= r.Weight
+	}
+	return nil
+}
+
+// failover returns the next node to retry the query failed on the replica,
+// it returns false if the query can not be retried
+func (p *ReplicaProvider) failover(ctx context.Context, tried []*replicaState, err error) (*replicaState, bool) {
+	failed := tried[len(tried)-1]
+	if !IsIdempotent(ctx) || !IsBadConnectionError(err) {
+		return nil, false
+	}
+
+	next := p.pickReplica(tried)
+	ev := FailoverEvent{
+		Replica:   failed.Name,
+		Statement: StatementName(ctx),
+		Err:       err,
+	}
+	if next != nil {
+		ev.Target = next.Name
+	}
+
+	p.lock.Lock()
+	failed.failovers++
+	p.lock.Unlock()
+
+	logger.KV(xlog.WARNING,
+		"reason", "replica_failover",
+		"replica", ev.Replica,
+		"target", ev.Target,
+		"statement", ev.Statement,
+		"err", err)
+
+	if p.onFailover != nil {
+		p.onFailover(ev)
+	}
+	return next, true
+}
+
+// QueryContext executes a query that returns rows on the routed provider.
+// The statements that modify data or lock rows, such as INSERT ... RETURNING
+// or SELECT ... FOR UPDATE, are executed on the primary,
+// and fail fast with ReadOnlyError in read-only mode.
+// If xsql.Builder is passed as the only argument, its arguments are used.
+func (p *ReplicaProvider) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, query, args = stmtQuery(ctx, p.SQLProvider, query, args)
+	if p.isWrite(ctx, query) {
+		if err := p.allowWrite(); err != nil {
+			return nil, err
+		}
+		rows, err := p.SQLProvider.QueryContext(ctx, query, args...)
+		p.writeDone(ctx, err)
+		return rows, err
+	}
+
+	var tried []*replicaState
+	r := p.route(ctx)
+	for {
+		rows, err := p.provider(r).QueryContext(ctx, query, args...)
+		if err == nil || r == nil {
+			return rows, err
+		}
+		tried = append(tried, r)
+		next, ok := p.failover(ctx, tried, err)
+		if !ok {
+			return rows, err
+		}
+		r = next
+	}
+}
+
+// QueryRowContext executes a query that is expected to return at most one row
+// on the routed provider.
+// The statements that modify data or lock rows are executed on the primary,
+// see QueryContext.
+// If xsql.Builder is passed as the only argument, its arguments are used.
+func (p *ReplicaProvider) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, query, args = stmtQuery(ctx, p.SQLProvider, query, args)
+	if p.isWrite(ctx, query) {
+		if err := p.allowWrite(); err != nil {
+			return errRow(ctx, err)
+		}
+		row := p.SQLProvider.QueryRowContext(ctx, query, args...)
+		p.writeDone(ctx, row.Err())
+		return row
+	}
+
+	var tried []*replicaState
+	r := p.route(ctx)
+	for {
+		row := p.provider(r).QueryRowContext(ctx, query, args...)
+		err := row.Err()
+		if err == nil || r == nil {
+			return row
+		}
+		tried = append(tried, r)
+		next, ok := p.failover(ctx, tried, err)
+		if !ok {
+			return row
+		}
+		r = next
+	}
+}
+
+// Close connections of the primary and replicas
+func (p *ReplicaProvider) Close() error {
+	p.stopHealthCheck()
+	for _, r := range p.replicas {
+		_ = r.Provider.Close()
+	}
+	return p.SQLProvider.Close()
+}
@@ -0,0 +1,381 @@
+package xdb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/xdbtest"
+	"github.com/effective-security/xdb/schema"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openNode(t *testing.T, name string) *xdb.SQLProvider {
+	p := openSQLite(t)
+	ctx := context.Background()
+	_, err := p.ExecContext(ctx, "CREATE TABLE node (name TEXT)")
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, "INSERT INTO node VALUES (?)", name)
+	require.NoError(t, err)
+	return p
+}
+
+func nodeName(t *testing.T, ctx context.Context, db xdb.DB, args ...any) string {
+	var name string
+	err := db.QueryRowContext(ctx, "SELECT name FROM node", args...).Scan(&name)
+	require.NoError(t, err)
+	return name
+}
+
+func TestReplicaProvider(t *testing.T) {
+	ctx := context.Background()
+
+	lags := map[string]int64{}
+	lagFunc := func(_ context.Context, _, replica xdb.DB) (int64, error) {
+		return lags[nodeName(t, ctx, replica)], nil
+	}
+
+	p := xdb.NewReplicaProvider(openNode(t, "primary"),
+		xdb.Replica{Name: "r1", Provider: openNode(t, "r1"), Weight: 3},
+		xdb.Replica{Name: "r2", Provider: openNode(t, "r2")},
+	).
+		WithRoutingRule("admin.*", xdb.RoutePrimary).
+		WithReplicaLagFunc(lagFunc).
+		WithMaxReplicaLag(1024, 0)
+	var _ xdb.Provider = p
+
+	counts := map[string]int{}
+	for i := 0; i < 200; i++ {
+		counts[nodeName(t, ctx, p)]++
+	}
+	assert.Equal(t, 0, counts["primary"])
+	assert.Greater(t, counts["r1"], counts["r2"])
+	assert.Greater(t, counts["r2"], 0)
+
+	assert.Equal(t, "primary", nodeName(t, xdb.PreferPrimary(ctx), p))
+
+	q := xsql.NoDialect.From("node").Select("name").SetName("admin.node")
+	defer q.Close()
+	assert.Equal(t, "primary", nodeName(t, ctx, p, q))
+	assert.Equal(t, "primary", nodeName(t, xdb.WithStatementName(ctx, "admin.raw"), p))
+	assert.NotEqual(t, "primary", nodeName(t, xdb.PreferReplica(xdb.WithStatementName(ctx, "admin.raw")), p))
+
+	list, err := xdb.ExecuteListQuery[nodeRow](ctx, p, q.String(), q)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "primary", list[0].Name)
+
+	// r1 is lagging
+	lags["r1"] = 2048
+	p.CheckReplicaLag(ctx)
+	status := p.Replicas()
+	require.Len(t, status, 2)
+	assert.Equal(t, xdb.ReplicaStatus{Name: "r1", Weight: 3, Lag: 2048, Lagging: true}, status[0])
+	assert.Equal(t, xdb.ReplicaStatus{Name: "r2", Weight: 1}, status[1])
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "r2", nodeName(t, ctx, p))
+	}
+
+	// all replicas are lagging
+	lags["r2"] = 4096
+	p.CheckReplicaLag(ctx)
+	assert.Equal(t, "primary", nodeName(t, ctx, p))
+
+	// writes and transactions use the primary
+	_, err = p.ExecContext(ctx, "UPDATE node SET name = ?", "primary2")
+	require.NoError(t, err)
+	assert.Equal(t, "primary2", nodeName(t, xdb.PreferPrimary(ctx), p))
+
+	lags["r2"] = 0
+	p.CheckReplicaLag(ctx)
+	assert.Equal(t, "r2", nodeName(t, ctx, p))
+
+	tx, err := p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "primary2", nodeName(t, ctx, tx))
+	require.NoError(t, tx.Commit())
+
+	p.WithDefaultRoute(xdb.RoutePrimary)
+	assert.Equal(t, "primary2", nodeName(t, ctx, p))
+	assert.Equal(t, "r2", nodeName(t, xdb.PreferReplica(ctx), p))
+
+	require.NoError(t, p.Close())
+}
+
+func TestReplicaProviderLagError(t *testing.T) {
+	ctx := context.Background()
+
+	p := xdb.NewReplicaProvider(openNode(t, "primary"),
+		xdb.Replica{Name: "r1", Provider: openNode(t, "r1")},
+	).WithReplicaLagFunc(func(context.Context, xdb.DB, xdb.DB) (int64, error) {
+		return 0, errors.New("replica is down")
+	})
+	assert.Equal(t, "r1", nodeName(t, ctx, p))
+
+	p.CheckReplicaLag(ctx)
+	status := p.Replicas()
+	require.Len(t, status, 1)
+	assert.True(t, status[0].Lagging)
+	assert.EqualError(t, status[0].Err, "replica is down")
+	assert.Equal(t, "primary", nodeName(t, ctx, p))
+}
+
+// healthChecks returns the number of running health check goroutines
+func healthChecks() int {
+	buf := make([]byte, 1<<20)
+	n := runtime.Stack(buf, true)
+	return strings.Count(string(buf[:n]), "(*ReplicaProvider).WithMaxReplicaLag.func")
+}
+
+func TestReplicaProviderHealthCheckStopped(t *testing.T) {
+	var checks atomic.Int32
+	p := xdb.NewReplicaProvider(openNode(t, "primary"),
+		xdb.Replica{Name: "r1", Provider: openNode(t, "r1")},
+	).WithReplicaLagFunc(func(context.Context, xdb.DB, xdb.DB) (int64, error) {
+		checks.Add(1)
+		return 0, nil
+	})
+	base := healthChecks()
+
+	p.WithMaxReplicaLag(1024, time.Millisecond)
+	require.Eventually(t, func() bool {
+		return checks.Load() > 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, base+1, healthChecks())
+
+	// the previous check is stopped when the period is changed
+	p.WithMaxReplicaLag(1024, time.Millisecond)
+	assert.Equal(t, base+1, healthChecks())
+	p.WithMaxReplicaLag(1024, 0)
+	assert.Equal(t, base, healthChecks())
+
+	p.WithMaxReplicaLag(1024, time.Millisecond)
+	require.NoError(t, p.Close())
+	assert.Equal(t, base, healthChecks())
+	stopped := checks.Load()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, stopped, checks.Load())
+}
+
+// deadDriver fails to open connections, as a replica that went down
+type deadDriver struct{}
+
+func (deadDriver) Open(string) (driver.Conn, error) {
+	return nil, driver.ErrBadConn
+}
+
+func init() {
+	sql.Register("dead", deadDriver{})
+}
+
+func openDeadNode(t *testing.T) *xdb.SQLProvider {
+	d, err := sql.Open("dead", "")
+	require.NoError(t, err)
+	p, err := xdb.New("dead", d, nil)
+	require.NoError(t, err)
+	return p
+}
+
+func TestReplicaProviderFailover(t *testing.T) {
+	ctx := context.Background()
+
+	var events []xdb.FailoverEvent
+	p := xdb.NewReplicaProvider(openNode(t, "primary"),
+		xdb.Replica{Name: "dead1", Provider: openDeadNode(t)},
+		xdb.Replica{Name: "dead2", Provider: openDeadNode(t)},
+	).WithFailoverHandler(func(ev xdb.FailoverEvent) {
+		events = append(events, ev)
+	})
+	defer p.Close()
+
+	// not idempotent
+	var name string
+	err := p.QueryRowContext(ctx, "SELECT name FROM node").Scan(&name)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	_, err = p.QueryContext(ctx, "SELECT name FROM node")
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Empty(t, events)
+
+	// both replicas are down, retried on the primary
+	assert.Equal(t, "primary", nodeName(t, xdb.Idempotent(ctx), p))
+	require.Len(t, events, 2)
+	assert.NotEqual(t, events[0].Replica, events[1].Replica)
+	assert.Equal(t, events[1].Replica, events[0].Target)
+	assert.Empty(t, events[1].Target)
+	assert.ErrorIs(t, events[0].Err, driver.ErrBadConn)
+
+	events = nil
+	q := xsql.NoDialect.From("node").Select("name").SetName("node.list").Idempotent()
+	defer q.Close()
+	list, err := xdb.ExecuteListQuery[nodeRow](ctx, p, q.String(), q)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "primary", list[0].Name)
+	require.Len(t, events, 2)
+	assert.Equal(t, "node.list", events[0].Statement)
+
+	var failovers uint64
+	for _, r := range p.Replicas() {
+		failovers += r.Failovers
+	}
+	assert.Equal(t, uint64(4), failovers)
+}
+
+func TestReplicaProviderFailoverToReplica(t *testing.T) {
+	ctx := xdb.Idempotent(context.Background())
+
+	p := xdb.NewReplicaProvider(openNode(t, "primary"),
+		xdb.Replica{Name: "dead", Provider: openDeadNode(t), Weight: 100},
+		xdb.Replica{Name: "r1", Provider: openNode(t, "r1")},
+	)
+	defer p.Close()
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "r1", nodeName(t, ctx, p))
+	}
+	status := p.Replicas()
+	assert.Greater(t, status[0].Failovers, uint64(0))
+	assert.Zero(t, status[1].Failovers)
+}
+
+type nodeRow struct {
+	Name string
+}
+
+func (m *nodeRow) ScanRow(row xdb.Row) error {
+	return row.Scan(&m.Name)
+}
+
+func TestNewProviderWithReplicas(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	dsn := func(name string) string {
+		return "sqlite3://" + filepath.Join(dir, name+".db")
+	}
+	for _, name := range []string{"primary", "r1", "r2"} {
+		n, err := xdb.NewProvider(dsn(name), "", nil, nil)
+		require.NoError(t, err)
+		_, err = n.ExecContext(ctx, "CREATE TABLE node (name TEXT)")
+		require.NoError(t, err)
+		_, err = n.ExecContext(ctx, "INSERT INTO node VALUES (?)", name)
+		require.NoError(t, err)
+		require.NoError(t, n.Close())
+	}
+
+	_, err := xdb.NewProviderWithReplicas(dsn("primary"), dsn("r1"), "unknown://host")
+	assert.EqualError(t, err, "failed to open replica 2: unable to open DB: sql: unknown driver \"unknown\" (forgotten import?)")
+
+	p, err := xdb.NewProviderWithReplicas(dsn("primary"), dsn("r1"), dsn("r2"))
+	require.NoError(t, err)
+	defer p.Close()
+
+	// round-robin
+	var names []string
+	for i := 0; i < 4; i++ {
+		names = append(names, nodeName(t, ctx, p))
+	}
+	assert.Equal(t, []string{"r1", "r2", "r1", "r2"}, names)
+	assert.Equal(t, "primary", nodeName(t, xdb.PreferPrimary(ctx), p))
+
+	// health check
+	p.CheckReplicaLag(ctx)
+	status := p.Replicas()
+	require.Len(t, status, 2)
+	assert.Equal(t, xdb.ReplicaStatus{Name: "replica1", Weight: 1}, status[0])
+
+	_, err = xdb.PingReplica(ctx, nil, p.DB())
+	assert.NoError(t, err)
+	closed := openNode(t, "closed")
+	require.NoError(t, closed.Close())
+	_, err = xdb.PingReplica(ctx, nil, closed.DB())
+	assert.EqualError(t, err, "failed to ping replica: sql: database is closed")
+}
+
+func TestReplicaProviderWrites(t *testing.T) {
+	ctx := context.Background()
+	primary := xdbtest.New("postgres")
+	replica := xdbtest.New("postgres")
+
+	p := xdb.NewReplicaProvider(primary.Provider(),
+		xdb.Replica{Name: "r1", Provider: replica.Provider()},
+	).WithReadOnlyDegradation(1, time.Minute)
+	defer p.Close()
+
+	// the replica fails the statements without expectations
+	ti := &schema.TableInfo{
+		Name:    "items",
+		Columns: []string{"id", "name"},
+		Dialect: xsql.Postgres,
+	}
+	primary.ExpectQuery(`^INSERT INTO items \(name\) VALUES \(\$1\) RETURNING id$`).
+		WithArgs("a").
+		WillReturnRows([]string{"id"}, [][]any{{int64(1)}})
+	ids, err := xdb.InsertReturningIDs(ctx, p, ti, []item{{Name: "a"}}, "id")
+	require.NoError(t, err)
+	assert.Equal(t, xdb.IDArray{xdb.NewID(1)}, ids)
+
+	primary.ExpectQuery(`(?s)^INSERT INTO org .*RETURNING id, plan$`).
+		WillReturnRows([]string{"id", "plan"}, [][]any{{int64(2), "free"}})
+	org := &modelOrg{Name: "acme"}
+	require.NoError(t, xdb.InsertModel(ctx, p, "org", org))
+	assert.Equal(t, int64(2), org.ID)
+	assert.Equal(t, "free", org.Plan)
+
+	var name string
+	primary.ExpectQuery(`(?s)^UPDATE org .*RETURNING name$`).
+		WillReturnRows([]string{"name"}, [][]any{{"acme2"}})
+	err = xsql.Postgres.Update("org").
+		Set("name", "acme2").
+		Where("id = ?", 2).
+		Returning("name").
+		To(&name).
+		QueryRowAndClose(ctx, p)
+	require.NoError(t, err)
+	assert.Equal(t, "acme2", name)
+
+	primary.ExpectQuery(`FOR UPDATE SKIP LOCKED$`).
+		WillReturnRows([]string{"id"}, [][]any{{int64(2)}})
+	q := xsql.Postgres.From("org").
+		Select("id").
+		Where("plan = ?", "free").
+		ForUpdate(xsql.SkipLocked())
+	defer q.Close()
+	rows, err := p.QueryContext(ctx, q.String(), q)
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	// plain SELECT is executed on the replica
+	replica.ExpectQuery(`^SELECT name FROM org`).
+		WillReturnRows([]string{"name"}, [][]any{{"acme2"}})
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT name FROM org WHERE id = $1", 2).Scan(&name))
+	require.NoError(t, primary.ExpectationsWereMet())
+	require.NoError(t, replica.ExpectationsWereMet())
+
+	// the writes fail fast in read-only mode
+	primary.ExpectQuery(`^INSERT INTO org`).
+		WillReturnError(&net.OpError{Op: "read", Err: errors.New("connection reset by peer")})
+	err = xdb.InsertModel(ctx, p, "org", &modelOrg{Name: "acme"})
+	require.Error(t, err)
+	require.True(t, p.ReadOnly())
+
+	var roErr *xdb.ReadOnlyError
+	err = xdb.InsertModel(ctx, p, "org", &modelOrg{Name: "acme"})
+	assert.True(t, errors.As(err, &roErr), "%v", err)
+	_, err = p.QueryContext(ctx, q.String(), q)
+	assert.True(t, errors.As(err, &roErr), "%v", err)
+	_, err = xdb.InsertReturningIDs(ctx, p, ti, []item{{Name: "b"}}, "id")
+	assert.True(t, errors.As(err, &roErr), "%v", err)
+	require.NoError(t, primary.ExpectationsWereMet())
+}
@@ -0,0 +1,129 @@
+package xdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// ErrResultTooLarge is matched by ResultTooLargeError with errors.Is
+var ErrResultTooLarge = errors.New("result is too large")
+
+// ResultLimit limits the rows accumulated in memory by ExecuteListQuery
+type ResultLimit struct {
+	// MaxRows is the maximum number of rows, zero disables the limit
+	MaxRows int
+	// MaxBytes is the maximum estimated size of rows, zero disables the limit
+	MaxBytes int64
+}
+
+// ResultTooLargeError is returned by ExecuteListQuery when the result exceeds the limit
+type ResultTooLargeError struct {
+	// Limit is the exceeded limit
+	Limit ResultLimit
+	// Rows is the number of rows read before the query was aborted
+	Rows int
+	// Bytes is the estimated size of rows read before the query was aborted
+	Bytes int64
+}
+
+func (e *ResultTooLargeError) Error() string {
+	return fmt.Sprintf("result is too large: %d rows, %d bytes read, limit: %d rows, %d bytes",
+		e.Rows, e.Bytes, e.Limit.MaxRows, e.Limit.MaxBytes)
+}
+
+// Is returns true for ErrResultTooLarge
+func (e *ResultTooLargeError) Is(target error) bool {
+	return target == ErrResultTooLarge
+}
+
+type resultLimitKey struct{}
+
+// WithResultLimit returns a context that limits the rows accumulated by ExecuteListQuery,
+// the query is aborted with ResultTooLargeError when the limit is exceeded
+func WithResultLimit(ctx context.Context, limit ResultLimit) context.Context {
+	return context.WithValue(ctx, resultLimitKey{}, limit)
+}
+
+// ResultLimitFromContext returns the result limit of the context,
+// or false if the limit is not set
+func ResultLimitFromContext(ctx context.Context) (ResultLimit, bool) {
+	limit, ok := ctx.Value(resultLimitKey{}).(ResultLimit)
+	return limit, ok
+}
+
+// resultCounter accumulates the rows of a list query
+type resultCounter struct {
+	limit ResultLimit
+	rows  int
+	bytes int64
+}
+
+func newResultCounter(ctx context.Context) *resultCounter {
+	limit, ok := ResultLimitFromContext(ctx)
+	if !ok || (limit.MaxRows <= 0 && limit.MaxBytes <= 0) {
+		return nil
+	}
+	return &resultCounter{limit: limit}
+}
+
+// add accounts the row, and returns ResultTooLargeError if the limit is exceeded
+func (c *resultCounter) add(row any) error {
+	if c == nil {
+		return nil
+	}
+	c.rows++
+	if c.limit.MaxBytes > 0 {
+		c.bytes += sizeOf(reflect.ValueOf(row), 0)
+	}
+	if (c.limit.MaxRows > 0 && c.rows > c.limit.MaxRows) ||
+		(c.limit.MaxBytes > 0 && c.bytes > c.limit.MaxBytes) {
+		return &ResultTooLargeError{Limit: c.limit, Rows: c.rows, Bytes: c.bytes}
+	}
+	return nil
+}
+
+// maxSizeDepth limits the depth of nested values in sizeOf
+const maxSizeDepth = 8
+
+// sizeOf returns the estimated memory size of the value
+func sizeOf(v reflect.Value, depth int) int64 {
+	if !v.IsValid() || depth > maxSizeDepth {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return int64(v.Type().Size())
+		}
+		return int64(v.Type().Size()) + sizeOf(v.Elem(), depth+1)
+	case reflect.String:
+		return int64(v.Type().Size()) + int64(v.Len())
+	case reflect.Slice:
+		size := int64(v.Type().Size())
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return size + int64(v.Len())
+		}
+		for i := 0; i < v.Len(); i++ {
+			size += sizeOf(v.Index(i), depth+1)
+		}
+		return size
+	case reflect.Map:
+		size := int64(v.Type().Size())
+		iter := v.MapRange()
+		for iter.Next() {
+			size += sizeOf(iter.Key(), depth+1) + sizeOf(iter.Value(), depth+1)
+		}
+		return size
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += sizeOf(v.Field(i), depth+1)
+		}
+		return max(size, int64(v.Type().Size()))
+	default:
+		return int64(v.Type().Size())
+	}
+}
@@ -0,0 +1,138 @@
+package xdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy defines the retry of operations failed with transient errors,
+// for example serialization failures, deadlocks or connection resets
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry,
+	// the delay is doubled on each next retry
+	InitialBackoff time.Duration
+	// MaxBackoff limits the delay between the retries
+	MaxBackoff time.Duration
+	// IsRetriable classifies the errors, default: IsRetriableError
+	IsRetriable func(err error) bool
+}
+
+// DefaultRetryPolicy returns the policy with 3 attempts
+// and exponential backoff starting from 50ms
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+}
+
+// Backoff returns the delay before the retry, attempt starts from 1
+func (r *RetryPolicy) Backoff(attempt int) time.Duration {
+	d := r.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		if r.MaxBackoff > 0 && d >= r.MaxBackoff {
+			break
+		}
+		d *= 2
+	}
+	if r.MaxBackoff > 0 && d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	return d
+}
+
+func (r *RetryPolicy) isRetriable(err error) bool {
+	if r.IsRetriable != nil {
+		return r.IsRetriable(err)
+	}
+	return IsRetriableError(err)
+}
+
+/*
+Do calls fn until it succeeds, fails with the error that is not retriable,
+or the attempts are exhausted, and returns the last error:
+
+	err := xdb.DefaultRetryPolicy().Do(ctx, func(ctx context.Context) error {
+		tx, err := p.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+		...
+		return tx.Commit()
+	})
+
+Nil policy calls fn once.
+*/
+func (r *RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r == nil {
+		return fn(ctx)
+	}
+	return r.do(ctx, r.isRetriable, fn)
+}
+
+// DoExec calls fn as Do, for the statement that modifies data.
+// The statement may be already applied by the server, when the connection
+// fails after it was sent, so unless the context is marked with Idempotent,
+// only the errors reported by IsExecRetriableError are retried.
+func (r *RetryPolicy) DoExec(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r == nil {
+		return fn(ctx)
+	}
+	if IsIdempotent(ctx) {
+		return r.do(ctx, r.isRetriable, fn)
+	}
+	return r.do(ctx, func(err error) bool {
+		return IsExecRetriableError(err) && r.isRetriable(err)
+	}, fn)
+}
+
+// doTx calls fn as Do, for the transaction executed by fn.
+// The broken connection is retried only when BeginTx failed,
+// other errors are retried if transient, for example serialization failure.
+// The error of Commit is not retried, as the transaction may be already committed.
+func (r *RetryPolicy) doTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r == nil {
+		return fn(ctx)
+	}
+	return r.do(ctx, func(err error) bool {
+		var terr *txError
+		if errors.As(err, &terr) {
+			if terr.commit {
+				return false
+			}
+			return IsExecRetriableError(err) && r.isRetriable(err)
+		}
+		return IsTransientError(err) && r.isRetriable(err)
+	}, fn)
+}
+
+func (r *RetryPolicy) do(ctx context.Context, isRetriable func(err error) bool, fn func(ctx context.Context) error) error {
+	err := fn(ctx)
+	for attempt := 1; attempt < r.MaxAttempts; attempt++ {
+		if err == nil || !isRetriable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := r.Backoff(attempt)
+		logger.KV(xlog.DEBUG,
+			"reason", "retry",
+			"attempt", attempt,
+			"delay", delay,
+			"err", err.Error())
+
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+		err = fn(ctx)
+	}
+	return err
+}
@@ -0,0 +1,214 @@
+package xdb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/pkg/xdbtest"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	r := &xdb.RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     3 * time.Millisecond,
+	}
+	assert.Equal(t, time.Millisecond, r.Backoff(1))
+	assert.Equal(t, 2*time.Millisecond, r.Backoff(2))
+	assert.Equal(t, 3*time.Millisecond, r.Backoff(3))
+	assert.Equal(t, 3*time.Millisecond, r.Backoff(100))
+
+	calls := 0
+	err := r.Do(ctx, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = r.Do(ctx, func(context.Context) error {
+		calls++
+		return &pq.Error{Code: "40P01"}
+	})
+	assert.Equal(t, "40P01", string(err.(*pq.Error).Code))
+	assert.Equal(t, 4, calls)
+
+	calls = 0
+	err = r.Do(ctx, func(context.Context) error {
+		calls++
+		return errors.New("syntax error")
+	})
+	assert.EqualError(t, err, "syntax error")
+	assert.Equal(t, 1, calls)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	calls = 0
+	err = r.Do(cctx, func(context.Context) error {
+		calls++
+		return sql.ErrConnDone
+	})
+	assert.Equal(t, sql.ErrConnDone, err)
+	assert.Equal(t, 1, calls)
+
+	var nilPolicy *xdb.RetryPolicy
+	calls = 0
+	err = nilPolicy.Do(ctx, func(context.Context) error {
+		calls++
+		return sql.ErrConnDone
+	})
+	assert.Equal(t, sql.ErrConnDone, err)
+	assert.Equal(t, 1, calls)
+
+	def := xdb.DefaultRetryPolicy()
+	assert.Equal(t, 3, def.MaxAttempts)
+	assert.Equal(t, 50*time.Millisecond, def.Backoff(1))
+}
+
+func TestProviderWithRetry(t *testing.T) {
+	ctx := context.Background()
+
+	attempts := 0
+	p := openSQLite(t).WithRetry(&xdb.RetryPolicy{
+		MaxAttempts: 3,
+		IsRetriable: func(err error) bool {
+			attempts++
+			return true
+		},
+	})
+	require.NotNil(t, p.RetryPolicy())
+
+	_, err := p.ExecContext(xdb.Idempotent(ctx), "INSERT INTO missing (id) VALUES (1)")
+	require.Error(t, err)
+	assert.Equal(t, 2, attempts)
+
+	// the statement that is not idempotent is retried only on bad connection or transient errors
+	attempts = 0
+	_, err = p.ExecContext(ctx, "INSERT INTO missing (id) VALUES (1)")
+	require.Error(t, err)
+	assert.Equal(t, 0, attempts)
+
+	attempts = 0
+	_, err = p.QueryContext(ctx, "SELECT id FROM missing")
+	require.Error(t, err)
+	assert.Equal(t, 2, attempts)
+
+	attempts = 0
+	var id int
+	err = p.QueryRowContext(ctx, "SELECT id FROM missing").Scan(&id)
+	require.Error(t, err)
+	assert.Equal(t, 2, attempts)
+
+	_, err = p.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	attempts = 0
+	err = p.QueryRowContext(ctx, "SELECT COUNT(*) FROM t").Scan(&id)
+	require.NoError(t, err)
+	assert.Equal(t, 0, attempts)
+
+	// statements in transaction are not retried
+	tx, err := p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+	_, err = tx.ExecContext(ctx, "INSERT INTO missing (id) VALUES (1)")
+	require.Error(t, err)
+	assert.Equal(t, 0, attempts)
+}
+
+func TestExecRetry(t *testing.T) {
+	ctx := context.Background()
+	fake := xdbtest.New("postgres")
+	p := fake.Provider().WithRetry(&xdb.RetryPolicy{MaxAttempts: 3})
+	defer p.Close()
+
+	timeout := errors.New("read tcp 127.0.0.1:5432: i/o timeout")
+	require.True(t, xdb.IsRetriableError(timeout))
+	require.False(t, xdb.IsExecRetriableError(timeout))
+	require.True(t, xdb.IsExecRetriableError(errors.WithStack(driver.ErrBadConn)))
+
+	// the insert may be applied by the server, so it's not retried
+	fake.ExpectExec(`^INSERT INTO orders`).WillReturnError(timeout).Times(3)
+	_, err := p.ExecContext(ctx, "INSERT INTO orders (id) VALUES ($1)", 1)
+	assert.EqualError(t, err, timeout.Error())
+	assert.EqualError(t, fake.ExpectationsWereMet(), "expected exec ^INSERT INTO orders: called 1 of 3 times")
+
+	fake.Reset()
+	fake.ExpectExec(`^INSERT INTO orders`).WillReturnError(timeout).Times(3)
+	_, err = p.ExecContext(xdb.Idempotent(ctx), "INSERT INTO orders (id) VALUES ($1) ON CONFLICT DO NOTHING", 1)
+	assert.EqualError(t, err, timeout.Error())
+	require.NoError(t, fake.ExpectationsWereMet())
+
+	// transient errors are retried
+	fake.Reset()
+	fake.ExpectExec(`^INSERT INTO orders`).WillReturnError(&pq.Error{Code: "40P01"})
+	fake.ExpectExec(`^INSERT INTO orders`).WillReturnResult(0, 1)
+	_, err = p.ExecContext(ctx, "INSERT INTO orders (id) VALUES ($1)", 1)
+	require.NoError(t, err)
+	require.NoError(t, fake.ExpectationsWereMet())
+}
+
+func TestTxRetry(t *testing.T) {
+	ctx := context.Background()
+	fake := xdbtest.New("postgres")
+	p := fake.Provider().WithRetry(&xdb.RetryPolicy{MaxAttempts: 3})
+	defer p.Close()
+
+	insert := func(calls *int) xdb.TxFunc {
+		return func(ctx context.Context, tx xdb.Provider) error {
+			*calls++
+			_, err := tx.ExecContext(ctx, "INSERT INTO orders (id) VALUES ($1)", 1)
+			return err
+		}
+	}
+
+	// the transaction may be committed by the server, so it's not retried
+	fake.ExpectBegin()
+	fake.ExpectExec(`^INSERT INTO orders`).WillReturnResult(0, 1)
+	fake.ExpectCommit().WillReturnError(io.EOF)
+	calls := 0
+	err := p.WithTx(ctx, nil, insert(&calls))
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, 1, calls)
+	require.NoError(t, fake.ExpectationsWereMet())
+
+	// broken connection in the transaction is not retried
+	fake.Reset()
+	timeout := errors.New("read tcp 127.0.0.1:5432: i/o timeout")
+	fake.ExpectBegin()
+	fake.ExpectExec(`^INSERT INTO orders`).WillReturnError(timeout)
+	fake.ExpectRollback()
+	calls = 0
+	err = p.WithTx(ctx, nil, insert(&calls))
+	assert.EqualError(t, err, timeout.Error())
+	assert.Equal(t, 1, calls)
+	require.NoError(t, fake.ExpectationsWereMet())
+
+	// transient errors are retried
+	fake.Reset()
+	fake.ExpectBegin()
+	fake.ExpectExec(`^INSERT INTO orders`).WillReturnError(&pq.Error{Code: "40001"})
+	fake.ExpectRollback()
+	fake.ExpectBegin()
+	fake.ExpectExec(`^INSERT INTO orders`).WillReturnResult(0, 1)
+	fake.ExpectCommit()
+	calls = 0
+	err = p.WithTx(ctx, nil, insert(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	require.NoError(t, fake.ExpectationsWereMet())
+}
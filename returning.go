@@ -0,0 +1,137 @@
+package xdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+)
+
+// InsertReturningIDs inserts rows with a multi-row statement per batch,
+// and returns the IDs generated by the database in the order of rows.
+// idColumn specifies the column with generated ID, it's excluded from the inserted columns.
+// rows must be structs or pointers to structs with db tags for other columns of the table.
+// RETURNING clause is used on Postgres and SQLite, and MERGE with OUTPUT clause on SQL Server.
+// Use a transaction to apply all batches atomically.
+func InsertReturningIDs[T any](ctx context.Context, db DB, t UpsertTable, rows []T, idColumn string) (IDArray, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	var columns []string
+	for _, c := range t.ColumnNames() {
+		if !strings.EqualFold(c, idColumn) {
+			columns = append(columns, c)
+		}
+	}
+	if len(columns) == len(t.ColumnNames()) {
+		return nil, errors.Errorf("column %s is not in the table", idColumn)
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("no columns to insert")
+	}
+
+	values, err := upsertValues(columns, nil, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	dialect := t.SQLDialect()
+	if dialect == nil {
+		dialect = xsql.NoDialect
+	}
+	provider := dialect.Provider()
+	if provider == "mysql" {
+		return nil, errors.Errorf("returning IDs is not supported by %s", provider)
+	}
+	maxParams := dialect.Capabilities().MaxParams
+	if maxParams == 0 {
+		maxParams = defaultMaxParams
+	}
+	batchSize := max(maxParams/len(columns), 1)
+
+	ids := make(IDArray, len(values))
+	for start := 0; start < len(values); start += batchSize {
+		end := min(start+batchSize, len(values))
+		batch := values[start:end]
+
+		query := insertReturningQuery(provider, t.TableName(), columns, len(batch), idColumn)
+		var args []any
+		for _, v := range batch {
+			args = append(args, v...)
+		}
+
+		if err = scanReturnedIDs(ctx, db, dialect.New(query, args...), provider, ids[start:end]); err != nil {
+			return nil, errors.WithMessagef(err, "failed to insert %d rows into %s", len(batch), t.TableName())
+		}
+	}
+	return ids, nil
+}
+
+// scanReturnedIDs scans the IDs returned by the statement into ids,
+// SQL Server returns the ordinal of the row with ID
+func scanReturnedIDs(ctx context.Context, db DB, q xsql.Builder, provider string, ids IDArray) error {
+	defer q.Close()
+
+	rows, err := db.QueryContext(ctx, q.String(), q.Args()...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var id ID
+		pos := count
+		if provider == "sqlserver" {
+			err = rows.Scan(&pos, &id)
+		} else {
+			err = rows.Scan(&id)
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if pos < 0 || pos >= len(ids) {
+			return errors.Errorf("unexpected row position: %d", pos)
+		}
+		ids[pos] = id
+		count++
+	}
+	if err = rows.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+	if count != len(ids) {
+		return errors.Errorf("expected %d IDs, returned %d", len(ids), count)
+	}
+	return nil
+}
+
+// insertReturningQuery returns the insert statement for the provider,
+// with ? placeholders for count rows
+func insertReturningQuery(provider, table string, columns []string, count int, idColumn string) string {
+	row := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	cols := strings.Join(columns, ", ")
+
+	var sb strings.Builder
+	switch provider {
+	case "sqlserver":
+		// OUTPUT does not guarantee the order of rows,
+		// the ordinal of the source row is returned with ID
+		fmt.Fprintf(&sb, "MERGE INTO %s AS target USING (VALUES ", table)
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "(%s, %d)", row, i)
+		}
+		fmt.Fprintf(&sb, ") AS source (%s, xdb_ord) ON 1 = 0", cols)
+		fmt.Fprintf(&sb, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (source.%s)", cols, strings.Join(columns, ", source."))
+		fmt.Fprintf(&sb, " OUTPUT source.xdb_ord, inserted.%s;", idColumn)
+	default:
+		rows := strings.TrimSuffix(strings.Repeat("("+row+"), ", count), ", ")
+		fmt.Fprintf(&sb, "INSERT INTO %s (%s) VALUES %s RETURNING %s", table, cols, rows, idColumn)
+	}
+	return sb.String()
+}
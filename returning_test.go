@@ -0,0 +1,73 @@
+package xdb_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/schema"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	ID   xdb.ID `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestInsertReturningIDs(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)")
+	require.NoError(t, err)
+
+	ti := &schema.TableInfo{
+		Name:    "items",
+		Columns: []string{"id", "name"},
+		Dialect: xsql.NoDialect,
+	}
+
+	// 1500 rows are split into 2 batches by 999 parameters
+	var rows []*item
+	for i := 0; i < 1500; i++ {
+		rows = append(rows, &item{Name: fmt.Sprintf("n%d", i)})
+	}
+	ids, err := xdb.InsertReturningIDs(ctx, p, ti, rows, "id")
+	require.NoError(t, err)
+	require.Len(t, ids, len(rows))
+
+	for i, id := range ids {
+		var name string
+		require.NoError(t, p.QueryRowContext(ctx, "SELECT name FROM items WHERE id = ?", id).Scan(&name))
+		assert.Equal(t, rows[i].Name, name)
+	}
+
+	ids, err = xdb.InsertReturningIDs(ctx, p, ti, []item{}, "id")
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	_, err = xdb.InsertReturningIDs(ctx, p, ti, rows, "uid")
+	assert.EqualError(t, err, "column uid is not in the table")
+
+	_, err = xdb.InsertReturningIDs(ctx, p, &schema.TableInfo{
+		Name:    "items",
+		Columns: []string{"id"},
+	}, rows, "id")
+	assert.EqualError(t, err, "no columns to insert")
+
+	_, err = xdb.InsertReturningIDs(ctx, p, &schema.TableInfo{
+		Name:    "items",
+		Columns: []string{"id", "name"},
+		Dialect: xsql.MySQL,
+	}, rows, "id")
+	assert.EqualError(t, err, "returning IDs is not supported by mysql")
+
+	_, err = xdb.InsertReturningIDs(ctx, p, &schema.TableInfo{
+		Name:    "missing",
+		Columns: []string{"id", "name"},
+	}, rows[:2], "id")
+	assert.ErrorContains(t, err, "failed to insert 2 rows into missing")
+}
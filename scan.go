@@ -0,0 +1,45 @@
+package xdb
+
+import (
+	"database/sql"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+)
+
+// rowScanner scans the rows into the models by ScanRow of RowScanner,
+// or by "db" tags of the fields for the models without generated ScanRow
+type rowScanner struct {
+	rows    *sql.Rows
+	columns []string
+}
+
+// first advances to the first row, or returns sql.ErrNoRows
+func (s *rowScanner) first() error {
+	if !s.rows.Next() {
+		if err := s.rows.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithStack(sql.ErrNoRows)
+	}
+	return nil
+}
+
+// scan scans the current row into the model
+func (s *rowScanner) scan(m any) error {
+	if rs, ok := m.(RowScanner); ok {
+		return rs.ScanRow(s.rows)
+	}
+	if s.columns == nil {
+		columns, err := s.rows.Columns()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		s.columns = columns
+	}
+	dest, err := xsql.StructDest(m, s.columns)
+	if err != nil {
+		return err
+	}
+	return s.rows.Scan(dest...)
+}
@@ -0,0 +1,64 @@
+package xdb_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// userName is an ad-hoc struct without ScanRow
+type userName struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name,text"`
+}
+
+func TestScanByTags(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := xsql.NoDialect.New("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, email_verified BOOLEAN, name TEXT)").
+		ExecAndClose(ctx, p)
+	require.NoError(t, err)
+
+	for i, name := range []string{"A", "B"} {
+		_, err = xsql.NoDialect.InsertInto("users").
+			Set("id", i+1).
+			Set("email", name+"@x").
+			Set("email_verified", false).
+			Set("name", name).
+			ExecAndClose(ctx, p)
+		require.NoError(t, err)
+	}
+
+	list, err := xdb.ExecuteListQuery[userName](ctx, p, "SELECT id, email, name FROM users ORDER BY id")
+	require.NoError(t, err)
+	assert.Equal(t, []*userName{{ID: 1, Name: "A"}, {ID: 2, Name: "B"}}, list)
+
+	u, err := xdb.QueryRow[userName](ctx, p, "SELECT name, id FROM users WHERE id = ?", 2)
+	require.NoError(t, err)
+	assert.Equal(t, &userName{ID: 2, Name: "B"}, u)
+
+	_, err = xdb.QueryRow[userName](ctx, p, "SELECT id, name FROM users WHERE id = ?", 3)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = xdb.QueryRow[userName](ctx, p, "SELECT id, name FROM missing")
+	assert.Error(t, err)
+
+	it, err := xdb.ExecuteQueryIterator[userName](ctx, p, "SELECT id, name FROM users ORDER BY id")
+	require.NoError(t, err)
+	defer it.Close()
+	var names []string
+	for it.Next() {
+		names = append(names, it.Value().Name)
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []string{"A", "B"}, names)
+
+	_, err = xdb.ExecuteListQuery[int](ctx, p, "SELECT id FROM users")
+	assert.EqualError(t, err, "expected struct type: int")
+}
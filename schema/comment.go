@@ -0,0 +1,66 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommentOnTable returns the statement to change the comment of the table
+// from t.Comment, an empty comment removes the description.
+// The empty string is returned if the provider does not support comments.
+func CommentOnTable(provider string, t *Table, comment string) string {
+	table := t.Schema + "." + t.Name
+	switch provider {
+	case "sqlserver", "mssql":
+		return msDescriptionDDL(t.Comment, comment, fmt.Sprintf("'SCHEMA', '%s', 'TABLE', '%s'", t.Schema, t.Name))
+	case "mysql":
+		return fmt.Sprintf("ALTER TABLE %s COMMENT = %s;", table, quoteComment(comment))
+	case "sqlite3", "sqlite":
+		return ""
+	default:
+		return fmt.Sprintf("COMMENT ON TABLE %s IS %s;", table, commentOrNull(comment))
+	}
+}
+
+// CommentOnColumn returns the statement to change the comment of the column
+// from c.Comment, an empty comment removes the description.
+// The empty string is returned if the provider does not support comments.
+func CommentOnColumn(provider string, t *Table, c *Column, comment string) string {
+	table := t.Schema + "." + t.Name
+	switch provider {
+	case "sqlserver", "mssql":
+		return msDescriptionDDL(c.Comment, comment,
+			fmt.Sprintf("'SCHEMA', '%s', 'TABLE', '%s', 'COLUMN', '%s'", t.Schema, t.Name, c.Name))
+	case "mysql":
+		// MySQL requires the full column definition to change the comment
+		return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s COMMENT %s;", table, columnDDL(c), quoteComment(comment))
+	case "sqlite3", "sqlite":
+		return ""
+	default:
+		return fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;", table, c.Name, commentOrNull(comment))
+	}
+}
+
+// msDescriptionDDL returns the statement to add, update or drop
+// MS_Description extended property of SQL Server object
+func msDescriptionDDL(old, comment, object string) string {
+	switch {
+	case comment == "":
+		return fmt.Sprintf("EXEC sp_dropextendedproperty 'MS_Description', %s;", object)
+	case old == "":
+		return fmt.Sprintf("EXEC sp_addextendedproperty 'MS_Description', N%s, %s;", quoteComment(comment), object)
+	default:
+		return fmt.Sprintf("EXEC sp_updateextendedproperty 'MS_Description', N%s, %s;", quoteComment(comment), object)
+	}
+}
+
+func commentOrNull(comment string) string {
+	if comment == "" {
+		return "NULL"
+	}
+	return quoteComment(comment)
+}
+
+func quoteComment(comment string) string {
+	return "'" + strings.ReplaceAll(comment, "'", "''") + "'"
+}
@@ -0,0 +1,60 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentOn(t *testing.T) {
+	c := &Column{Name: "name", Type: "varchar", MaxLength: 64, Comment: "old"}
+	tbl := &Table{Schema: "public", Name: "users", Comment: "old", Columns: Columns{c}}
+
+	tcases := []struct {
+		provider string
+		comment  string
+		table    string
+		column   string
+	}{
+		{
+			provider: "postgres",
+			comment:  "user's name",
+			table:    "COMMENT ON TABLE public.users IS 'user''s name';",
+			column:   "COMMENT ON COLUMN public.users.name IS 'user''s name';",
+		},
+		{
+			provider: "postgres",
+			table:    "COMMENT ON TABLE public.users IS NULL;",
+			column:   "COMMENT ON COLUMN public.users.name IS NULL;",
+		},
+		{
+			provider: "mysql",
+			comment:  "display name",
+			table:    "ALTER TABLE public.users COMMENT = 'display name';",
+			column:   "ALTER TABLE public.users MODIFY COLUMN name varchar(64) NOT NULL COMMENT 'display name';",
+		},
+		{
+			provider: "sqlserver",
+			comment:  "display name",
+			table:    "EXEC sp_updateextendedproperty 'MS_Description', N'display name', 'SCHEMA', 'public', 'TABLE', 'users';",
+			column:   "EXEC sp_updateextendedproperty 'MS_Description', N'display name', 'SCHEMA', 'public', 'TABLE', 'users', 'COLUMN', 'name';",
+		},
+		{
+			provider: "sqlserver",
+			table:    "EXEC sp_dropextendedproperty 'MS_Description', 'SCHEMA', 'public', 'TABLE', 'users';",
+			column:   "EXEC sp_dropextendedproperty 'MS_Description', 'SCHEMA', 'public', 'TABLE', 'users', 'COLUMN', 'name';",
+		},
+		{
+			provider: "sqlite3",
+			comment:  "display name",
+		},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.table, CommentOnTable(tc.provider, tbl, tc.comment), tc.provider)
+		assert.Equal(t, tc.column, CommentOnColumn(tc.provider, tbl, c, tc.comment), tc.provider)
+	}
+
+	added := &Table{Schema: "dbo", Name: "users"}
+	assert.Equal(t, "EXEC sp_addextendedproperty 'MS_Description', N'users', 'SCHEMA', 'dbo', 'TABLE', 'users';",
+		CommentOnTable("sqlserver", added, "users"))
+}
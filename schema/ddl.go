@@ -0,0 +1,284 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/effective-security/x/values"
+)
+
+// typeKinds maps the column types of the providers to portable kinds
+var typeKinds = map[string]string{
+	"bigint": "bigint",
+	"int8":   "bigint",
+
+	"integer":   "int",
+	"int":       "int",
+	"int4":      "int",
+	"mediumint": "int",
+
+	"smallint": "smallint",
+	"int2":     "smallint",
+	"tinyint":  "smallint",
+	"year":     "smallint",
+
+	"boolean": "bool",
+	"bool":    "bool",
+	"bit":     "bool",
+
+	"character varying": "varchar",
+	"varchar":           "varchar",
+	"nvarchar":          "varchar",
+
+	"character": "char",
+	"char":      "char",
+	"bpchar":    "char",
+	"nchar":     "char",
+
+	"text":       "text",
+	"ntext":      "text",
+	"tinytext":   "text",
+	"mediumtext": "text",
+	"longtext":   "text",
+	"xml":        "text",
+	"enum":       "text",
+
+	"timestamp with time zone": "timestamptz",
+	"timestamptz":              "timestamptz",
+	"datetimeoffset":           "timestamptz",
+
+	"timestamp without time zone": "timestamp",
+	"timestamp":                   "timestamp",
+	"datetime":                    "timestamp",
+	"datetime2":                   "timestamp",
+	"smalldatetime":               "timestamp",
+
+	"date": "date",
+
+	"time without time zone": "time",
+	"time":                   "time",
+
+	"numeric":    "decimal",
+	"decimal":    "decimal",
+	"money":      "decimal",
+	"smallmoney": "decimal",
+
+	"real":   "real",
+	"float4": "real",
+
+	"double precision": "double",
+	"double":           "double",
+	"float8":           "double",
+	"float":            "double",
+
+	"bytea":      "binary",
+	"binary":     "binary",
+	"varbinary":  "binary",
+	"image":      "binary",
+	"blob":       "binary",
+	"tinyblob":   "binary",
+	"mediumblob": "binary",
+	"longblob":   "binary",
+
+	"json":  "json",
+	"jsonb": "json",
+
+	"uuid":             "uuid",
+	"uniqueidentifier": "uuid",
+
+	"interval": "interval",
+}
+
+// typesByProvider maps the portable kinds to the column types of the providers,
+// the types with %d are rendered with the max length of the column
+var typesByProvider = map[string]map[string]string{
+	"postgres": {
+		"bigint":      "bigint",
+		"int":         "integer",
+		"smallint":    "smallint",
+		"bool":        "boolean",
+		"varchar":     "varchar(%d)",
+		"char":        "char(%d)",
+		"text":        "text",
+		"timestamptz": "timestamp with time zone",
+		"timestamp":   "timestamp",
+		"date":        "date",
+		"time":        "time",
+		"decimal":     "numeric",
+		"real":        "real",
+		"double":      "double precision",
+		"binary":      "bytea",
+		"json":        "jsonb",
+		"uuid":        "uuid",
+		"interval":    "interval",
+	},
+	"sqlserver": {
+		"bigint":      "bigint",
+		"int":         "int",
+		"smallint":    "smallint",
+		"bool":        "bit",
+		"varchar":     "nvarchar(%d)",
+		"char":        "nchar(%d)",
+		"text":        "nvarchar(max)",
+		"timestamptz": "datetimeoffset",
+		"timestamp":   "datetime2",
+		"date":        "date",
+		"time":        "time",
+		"decimal":     "decimal(38, 10)",
+		"real":        "real",
+		"double":      "float",
+		"binary":      "varbinary(max)",
+		"json":        "nvarchar(max)",
+		"uuid":        "uniqueidentifier",
+		"interval":    "bigint",
+	},
+	"mysql": {
+		"bigint":      "bigint",
+		"int":         "int",
+		"smallint":    "smallint",
+		"bool":        "boolean",
+		"varchar":     "varchar(%d)",
+		"char":        "char(%d)",
+		"text":        "longtext",
+		"timestamptz": "datetime(6)",
+		"timestamp":   "datetime(6)",
+		"date":        "date",
+		"time":        "time(6)",
+		"decimal":     "decimal(38, 10)",
+		"real":        "float",
+		"double":      "double",
+		"binary":      "longblob",
+		"json":        "json",
+		"uuid":        "char(36)",
+		"interval":    "bigint",
+	},
+	"sqlite3": {
+		"bigint":      "integer",
+		"int":         "integer",
+		"smallint":    "integer",
+		"bool":        "boolean",
+		"varchar":     "varchar(%d)",
+		"char":        "char(%d)",
+		"text":        "text",
+		"timestamptz": "timestamp",
+		"timestamp":   "timestamp",
+		"date":        "date",
+		"time":        "time",
+		"decimal":     "numeric",
+		"real":        "real",
+		"double":      "real",
+		"binary":      "blob",
+		"json":        "text",
+		"uuid":        "text",
+		"interval":    "integer",
+	},
+}
+
+// textTypes are used for the strings without max length
+var textTypes = map[string]string{
+	"postgres":  "text",
+	"sqlserver": "nvarchar(max)",
+	"mysql":     "longtext",
+	"sqlite3":   "text",
+}
+
+// ConvertType returns the type of the column from the source provider
+// for the target provider. The unknown types are returned as is,
+// and intervals are converted to bigint, outside of Postgres.
+func ConvertType(source, target string, c *Column) string {
+	target = providerName(target)
+	if providerName(source) == target {
+		return columnType(c)
+	}
+
+	typ := strings.ToLower(values.StringsCoalesce(c.UdtType, c.Type))
+	if len(c.Enum) > 0 {
+		typ = "enum"
+	}
+	kind, ok := typeKinds[typ]
+	if !ok {
+		kind, ok = typeKinds[strings.ToLower(c.Type)]
+	}
+	types := typesByProvider[target]
+	if !ok || types == nil {
+		return columnType(c)
+	}
+
+	res := types[kind]
+	if strings.Contains(res, "%d") {
+		// -1 is reported by SQL Server for max
+		if c.MaxLength == 0 || c.MaxLength > 0x7fffffff {
+			return textTypes[target]
+		}
+		return fmt.Sprintf(res, c.MaxLength)
+	}
+	return res
+}
+
+// providerName returns the canonical name of the provider
+func providerName(provider string) string {
+	switch provider {
+	case "mssql":
+		return "sqlserver"
+	case "sqlite":
+		return "sqlite3"
+	case "pgx":
+		return "postgres"
+	}
+	return provider
+}
+
+/*
+CreateDDL returns the statements to create the tables, indexes and foreign keys
+in the target provider, from the tables introspected in the source provider:
+
+	CREATE TABLE public.org (
+		id bigint NOT NULL,
+		name varchar(64) NOT NULL,
+		PRIMARY KEY (id)
+	);
+	CREATE UNIQUE INDEX uq_org_name ON public.org (name);
+	ALTER TABLE public.orgmember ADD CONSTRAINT fk_orgmember_org FOREIGN KEY (org_id) REFERENCES public.org (id);
+
+The column types are converted by ConvertType,
+the default values are kept only for the same provider, as the expressions are not portable.
+The foreign keys are not supported by ALTER TABLE in SQLite, and are skipped.
+*/
+func CreateDDL(source, target string, tables Tables, fks ForeignKeys) []string {
+	sameProvider := providerName(source) == providerName(target)
+
+	var list []string
+	for _, t := range sortedTables(tables) {
+		cols := make([]string, 0, len(t.Columns)+1)
+		for _, c := range t.Columns {
+			def := c.Name + " " + ConvertType(source, target, c)
+			if !c.Nullable {
+				def += " NOT NULL"
+			}
+			if c.Default != "" && sameProvider {
+				def += " DEFAULT " + c.Default
+			}
+			cols = append(cols, "\t"+def)
+		}
+		if t.PrimaryKey != nil {
+			cols = append(cols, fmt.Sprintf("\tPRIMARY KEY (%s)", t.PrimaryKey.Name))
+		}
+		list = append(list, fmt.Sprintf("CREATE TABLE %s.%s (\n%s\n);", t.Schema, t.Name, strings.Join(cols, ",\n")))
+
+		for _, idx := range t.Indexes {
+			if idx.IsPrimary {
+				continue
+			}
+			list = append(list, CreateIndexDDL(providerName(target), t, idx))
+		}
+	}
+
+	if providerName(target) == "sqlite3" {
+		return list
+	}
+	for _, fk := range fks {
+		list = append(list, fmt.Sprintf("ALTER TABLE %s.%s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s.%s (%s);",
+			fk.Schema, fk.Table, fk.Name, fk.Column, fk.RefSchema, fk.RefTable, fk.RefColumn))
+	}
+	return list
+}
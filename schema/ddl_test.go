@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertType(t *testing.T) {
+	tcases := []struct {
+		source string
+		target string
+		col    Column
+		exp    string
+	}{
+		{"postgres", "postgres", Column{Type: "character varying", UdtType: "varchar", MaxLength: 64}, "character varying(64)"},
+		{"postgres", "sqlserver", Column{Type: "character varying", UdtType: "varchar", MaxLength: 64}, "nvarchar(64)"},
+		{"postgres", "sqlserver", Column{Type: "character varying", UdtType: "varchar"}, "nvarchar(max)"},
+		{"postgres", "mysql", Column{Type: "timestamp with time zone", UdtType: "timestamptz"}, "datetime(6)"},
+		{"postgres", "sqlite3", Column{Type: "jsonb", UdtType: "jsonb"}, "text"},
+		{"postgres", "sqlserver", Column{Type: "USER-DEFINED", UdtType: "status", Enum: []string{"active"}}, "nvarchar(max)"},
+		{"sqlserver", "postgres", Column{Type: "nvarchar", MaxLength: 0xffffffff}, "text"},
+		{"sqlserver", "postgres", Column{Type: "uniqueidentifier"}, "uuid"},
+		{"sqlserver", "postgres", Column{Type: "datetime2"}, "timestamp"},
+		{"sqlserver", "postgres", Column{Type: "bit"}, "boolean"},
+		{"mssql", "postgres", Column{Type: "money"}, "numeric"},
+		{"mysql", "postgres", Column{Type: "longblob"}, "bytea"},
+		{"mysql", "postgres", Column{Type: "geometry"}, "geometry"},
+		{"postgres", "oracle", Column{Type: "bigint"}, "bigint"},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.exp, ConvertType(tc.source, tc.target, &tc.col), "%s => %s: %s", tc.source, tc.target, tc.col.Type)
+	}
+}
+
+func TestCreateDDL(t *testing.T) {
+	id := &Column{Name: "id", Type: "bigint"}
+	tables := Tables{
+		{
+			Schema: "dbo",
+			Name:   "user",
+			Columns: Columns{
+				{Name: "id", Type: "bigint"},
+				{Name: "org_id", Type: "bigint"},
+				{Name: "created_at", Type: "datetime2", Default: "getdate()"},
+			},
+			Indexes: Indexes{
+				{Name: "PK_user", IsPrimary: true, ColumnNames: []string{"id"}},
+				{Name: "IX_user_org_id", ColumnNames: []string{"org_id"}},
+			},
+		},
+		{
+			Schema:     "dbo",
+			Name:       "org",
+			Columns:    Columns{id, {Name: "name", Type: "nvarchar", MaxLength: 64, Nullable: true}},
+			PrimaryKey: id,
+		},
+	}
+	fks := ForeignKeys{
+		{Name: "FK_user_org", Schema: "dbo", Table: "user", Column: "org_id", RefSchema: "dbo", RefTable: "org", RefColumn: "id"},
+	}
+
+	assert.Equal(t, []string{
+		"CREATE TABLE dbo.org (\n\tid bigint NOT NULL,\n\tname varchar(64),\n\tPRIMARY KEY (id)\n);",
+		"CREATE TABLE dbo.user (\n\tid bigint NOT NULL,\n\torg_id bigint NOT NULL,\n\tcreated_at timestamp NOT NULL\n);",
+		"CREATE INDEX IX_user_org_id ON dbo.user (org_id);",
+		"ALTER TABLE dbo.user ADD CONSTRAINT FK_user_org FOREIGN KEY (org_id) REFERENCES dbo.org (id);",
+	}, CreateDDL("sqlserver", "postgres", tables, fks))
+
+	// the defaults are kept for the same provider
+	list := CreateDDL("sqlserver", "sqlserver", tables, fks)
+	assert.Contains(t, list[1], "\tcreated_at datetime2 NOT NULL DEFAULT getdate()\n")
+
+	// the foreign keys are not supported by SQLite
+	list = CreateDDL("sqlserver", "sqlite3", tables, fks)
+	assert.Len(t, list, 3)
+	assert.Equal(t, "CREATE INDEX IX_user_org_id ON user (org_id);", list[2])
+}
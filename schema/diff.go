@@ -0,0 +1,315 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/effective-security/x/values"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Renames provides the mapping of renamed columns between schema versions,
+// it's consulted by Diff to produce RENAME instead of DROP and ADD,
+// and by the model generator to keep deprecated aliases of the old fields.
+// Remove the entries after one release cycle, when the aliases are not used.
+type Renames struct {
+	// Columns maps the old column in schema.table.column format to the new column name
+	Columns map[string]string `json:"columns" yaml:"columns"`
+}
+
+// LoadRenames loads the renames from YAML or JSON file,
+// a missing file returns empty Renames
+func LoadRenames(file string) (*Renames, error) {
+	r := &Renames{Columns: map[string]string{}}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load renames")
+	}
+	if err = yaml.Unmarshal(data, r); err != nil {
+		return nil, errors.WithMessagef(err, "failed to load renames %s", file)
+	}
+	if r.Columns == nil {
+		r.Columns = map[string]string{}
+	}
+	return r, nil
+}
+
+// Save writes the renames to the file in YAML format
+func (r *Renames) Save(file string) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(file, data, 0666))
+}
+
+// AddColumn records the column rename,
+// oldColumn is in schema.table.column format
+func (r *Renames) AddColumn(oldColumn, newName string) error {
+	if strings.Count(oldColumn, ".") != 2 {
+		return errors.Errorf("invalid column name %q, expected schema.table.column format", oldColumn)
+	}
+	if newName == "" || strings.Contains(newName, ".") {
+		return errors.Errorf("invalid new column name %q", newName)
+	}
+	if r.Columns == nil {
+		r.Columns = map[string]string{}
+	}
+	r.Columns[oldColumn] = newName
+	return nil
+}
+
+// TableColumns returns the map of old to new column names for the table
+func (r *Renames) TableColumns(schemaName, table string) map[string]string {
+	res := map[string]string{}
+	if r == nil {
+		return res
+	}
+	prefix := strings.ToLower(schemaName + "." + table + ".")
+	for k, v := range r.Columns {
+		if strings.HasPrefix(strings.ToLower(k), prefix) {
+			res[k[len(prefix):]] = v
+		}
+	}
+	return res
+}
+
+// Diff returns the statements to migrate the schema from the previous version,
+// renamed columns are taken from renames, that can be nil.
+// The statements are returned in the order to apply:
+// new tables, renamed, added, altered and dropped columns, and dropped tables.
+// The columns are altered if the type, max length, nullability or default value is changed.
+func Diff(provider string, from, to Tables, renames *Renames) []string {
+	fromMap := map[string]*Table{}
+	for _, t := range from {
+		fromMap[strings.ToLower(t.Schema+"."+t.Name)] = t
+	}
+	toMap := map[string]*Table{}
+	for _, t := range to {
+		toMap[strings.ToLower(t.Schema+"."+t.Name)] = t
+	}
+
+	var list []string
+	for _, t := range sortedTables(to) {
+		old := fromMap[strings.ToLower(t.Schema+"."+t.Name)]
+		if old == nil {
+			list = append(list, createTableDDL(provider, t))
+			continue
+		}
+		list = append(list, diffColumns(provider, old, t, renames.TableColumns(t.Schema, t.Name))...)
+	}
+
+	for _, t := range sortedTables(from) {
+		if toMap[strings.ToLower(t.Schema+"."+t.Name)] == nil {
+			list = append(list, fmt.Sprintf("DROP TABLE %s.%s;", t.Schema, t.Name))
+		}
+	}
+	return list
+}
+
+func diffColumns(provider string, from, to *Table, renamed map[string]string) []string {
+	table := to.Schema + "." + to.Name
+	fromCols := columnsMap(from.Columns)
+	toCols := columnsMap(to.Columns)
+
+	var renames, adds, alters, drops []string
+	// new column name => old column
+	renamedTo := map[string]*Column{}
+	for oldName, newName := range renamed {
+		old := fromCols[strings.ToLower(oldName)]
+		if old == nil || toCols[strings.ToLower(newName)] == nil || toCols[strings.ToLower(oldName)] != nil {
+			continue
+		}
+		renamedTo[strings.ToLower(newName)] = old
+	}
+
+	for _, c := range to.Columns {
+		old := renamedTo[strings.ToLower(c.Name)]
+		if old != nil {
+			renames = append(renames, renameColumnDDL(provider, to, old.Name, c.Name))
+		} else if old = fromCols[strings.ToLower(c.Name)]; old == nil {
+			adds = append(adds, addColumnDDL(provider, table, c))
+			continue
+		}
+		if columnChanged(old, c) {
+			alters = append(alters, alterColumnDDL(provider, table, old, c)...)
+		}
+	}
+
+	for _, c := range from.Columns {
+		if toCols[strings.ToLower(c.Name)] != nil {
+			continue
+		}
+		isRenamed := false
+		for _, old := range renamedTo {
+			if old == c {
+				isRenamed = true
+				break
+			}
+		}
+		if !isRenamed {
+			drops = append(drops, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, c.Name))
+		}
+	}
+
+	sort.Strings(renames)
+	return append(append(append(renames, adds...), alters...), drops...)
+}
+
+// columnChanged returns true if the type, max length, nullability or default value of the column is changed
+func columnChanged(from, to *Column) bool {
+	return !strings.EqualFold(from.Type, to.Type) ||
+		from.MaxLength != to.MaxLength ||
+		from.Nullable != to.Nullable ||
+		defaultChanged(from, to)
+}
+
+// defaultChanged returns true if the default value of the column is changed,
+// the defaults of identity columns are generated with the sequence names and are not compared
+func defaultChanged(from, to *Column) bool {
+	if from.Identity && to.Identity {
+		return false
+	}
+	return strings.TrimSpace(from.Default) != strings.TrimSpace(to.Default)
+}
+
+func columnsMap(cols Columns) map[string]*Column {
+	m := make(map[string]*Column, len(cols))
+	for _, c := range cols {
+		m[strings.ToLower(c.Name)] = c
+	}
+	return m
+}
+
+func sortedTables(list Tables) Tables {
+	res := make(Tables, 0, len(list))
+	for _, t := range list {
+		if !t.IsView {
+			res = append(res, t)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Schema+"."+res[i].Name < res[j].Schema+"."+res[j].Name
+	})
+	return res
+}
+
+func renameColumnDDL(provider string, t *Table, oldName, newName string) string {
+	if provider == "sqlserver" || provider == "mssql" {
+		return fmt.Sprintf("EXEC sp_rename '%s.%s.%s', '%s', 'COLUMN';", t.Schema, t.Name, oldName, newName)
+	}
+	return fmt.Sprintf("ALTER TABLE %s.%s RENAME COLUMN %s TO %s;", t.Schema, t.Name, oldName, newName)
+}
+
+func addColumnDDL(provider, table string, c *Column) string {
+	if provider == "sqlserver" || provider == "mssql" {
+		return fmt.Sprintf("ALTER TABLE %s ADD %s;", table, columnDDL(c))
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, columnDDL(c))
+}
+
+// alterColumnDDL returns the statements to change the type, nullability or default value of the column
+func alterColumnDDL(provider, table string, from, to *Column) []string {
+	switch provider {
+	case "sqlserver", "mssql":
+		var list []string
+		if !strings.EqualFold(from.Type, to.Type) || from.MaxLength != to.MaxLength || from.Nullable != to.Nullable {
+			// SQL Server requires the type and nullability
+			list = append(list, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s %s;",
+				table, to.Name, columnType(to), values.Select(to.Nullable, "NULL", "NOT NULL")))
+		}
+		if defaultChanged(from, to) {
+			if from.Default != "" {
+				// the default is the named constraint in SQL Server
+				list = append(list, fmt.Sprintf("DECLARE @df sysname = (SELECT name FROM sys.default_constraints "+
+					"WHERE parent_object_id = OBJECT_ID('%s') AND parent_column_id = COLUMNPROPERTY(OBJECT_ID('%s'), '%s', 'ColumnId')); "+
+					"IF @df IS NOT NULL EXEC('ALTER TABLE %s DROP CONSTRAINT ' + @df);",
+					table, table, to.Name, table))
+			}
+			if to.Default != "" {
+				list = append(list, fmt.Sprintf("ALTER TABLE %s ADD DEFAULT %s FOR %s;", table, to.Default, to.Name))
+			}
+		}
+		return list
+	case "mysql":
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", table, columnDDL(to))}
+	}
+
+	var list []string
+	if !strings.EqualFold(from.Type, to.Type) || from.MaxLength != to.MaxLength {
+		list = append(list, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s;", table, to.Name, columnType(to)))
+	}
+	if from.Nullable != to.Nullable {
+		list = append(list, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s NOT NULL;",
+			table, to.Name, values.Select(to.Nullable, "DROP", "SET")))
+	}
+	if defaultChanged(from, to) {
+		if to.Default != "" {
+			list = append(list, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s;", table, to.Name, to.Default))
+		} else {
+			list = append(list, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;", table, to.Name))
+		}
+	}
+	return list
+}
+
+func createTableDDL(_ string, t *Table) string {
+	cols := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		cols = append(cols, "\t"+columnDDL(c))
+	}
+	if t.PrimaryKey != nil {
+		cols = append(cols, fmt.Sprintf("\tPRIMARY KEY (%s)", t.PrimaryKey.Name))
+	}
+	return fmt.Sprintf("CREATE TABLE %s.%s (\n%s\n);", t.Schema, t.Name, strings.Join(cols, ",\n"))
+}
+
+func columnType(c *Column) string {
+	if c.MaxLength > 0 {
+		return fmt.Sprintf("%s(%d)", c.Type, c.MaxLength)
+	}
+	return c.Type
+}
+
+func columnDDL(c *Column) string {
+	def := c.Name + " " + columnType(c)
+	if !c.Nullable {
+		def += " NOT NULL"
+	}
+	if c.Default != "" {
+		def += " DEFAULT " + c.Default
+	}
+	return def
+}
+
+// CreateIndexDDL returns the statement to create the index of the table
+func CreateIndexDDL(provider string, t *Table, idx *Index) string {
+	table := t.Schema + "." + t.Name
+	if provider == "sqlite3" || provider == "sqlite" {
+		// SQLite does not allow the schema in the table name of the index
+		table = t.Name
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);",
+		values.Select(idx.IsUnique, "UNIQUE ", ""),
+		idx.Name, table, strings.Join(idx.ColumnNames, ", "))
+}
+
+// DropIndexDDL returns the statement to drop the index of the table
+func DropIndexDDL(provider string, t *Table, idx *Index) string {
+	switch provider {
+	case "postgres":
+		return fmt.Sprintf("DROP INDEX IF EXISTS %s.%s;", t.Schema, idx.Name)
+	case "sqlserver", "mssql":
+		return fmt.Sprintf("DROP INDEX IF EXISTS %s ON %s.%s;", idx.Name, t.Schema, t.Name)
+	case "mysql":
+		return fmt.Sprintf("DROP INDEX %s ON %s.%s;", idx.Name, t.Schema, t.Name)
+	default:
+		return fmt.Sprintf("DROP INDEX IF EXISTS %s;", idx.Name)
+	}
+}
@@ -0,0 +1,189 @@
+package schema
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenames(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "renames.yaml")
+
+	r, err := LoadRenames(file)
+	require.NoError(t, err)
+	assert.Empty(t, r.Columns)
+
+	require.NoError(t, r.AddColumn("public.users.fullname", "display_name"))
+	assert.EqualError(t, r.AddColumn("users.fullname", "display_name"),
+		`invalid column name "users.fullname", expected schema.table.column format`)
+	assert.EqualError(t, r.AddColumn("public.users.fullname", "users.name"),
+		`invalid new column name "users.name"`)
+	require.NoError(t, r.Save(file))
+
+	r, err = LoadRenames(file)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"public.users.fullname": "display_name"}, r.Columns)
+	assert.Equal(t, map[string]string{"fullname": "display_name"}, r.TableColumns("Public", "Users"))
+	assert.Empty(t, r.TableColumns("public", "orgs"))
+
+	var nilRenames *Renames
+	assert.Empty(t, nilRenames.TableColumns("public", "users"))
+}
+
+func TestDiff(t *testing.T) {
+	id := &Column{Name: "id", Type: "bigint"}
+	from := Tables{
+		{
+			Schema:     "public",
+			Name:       "users",
+			PrimaryKey: id,
+			Columns: Columns{
+				id,
+				{Name: "fullname", Type: "character varying", MaxLength: 64, Nullable: true},
+				{Name: "legacy", Type: "text", Nullable: true},
+			},
+		},
+		{Schema: "public", Name: "old", Columns: Columns{{Name: "id", Type: "bigint"}}},
+	}
+	to := Tables{
+		{
+			Schema:     "public",
+			Name:       "users",
+			PrimaryKey: id,
+			Columns: Columns{
+				id,
+				{Name: "display_name", Type: "character varying", MaxLength: 64, Nullable: true},
+				{Name: "created_at", Type: "timestamp", Default: "now()"},
+			},
+		},
+		{
+			Schema:     "public",
+			Name:       "orgs",
+			PrimaryKey: id,
+			Columns:    Columns{id, {Name: "name", Type: "text", Nullable: true}},
+		},
+	}
+	renames := &Renames{Columns: map[string]string{"public.users.fullname": "display_name"}}
+
+	assert.Equal(t, []string{
+		"CREATE TABLE public.orgs (\n\tid bigint NOT NULL,\n\tname text,\n\tPRIMARY KEY (id)\n);",
+		"ALTER TABLE public.users RENAME COLUMN fullname TO display_name;",
+		"ALTER TABLE public.users ADD COLUMN created_at timestamp NOT NULL DEFAULT now();",
+		"ALTER TABLE public.users DROP COLUMN legacy;",
+		"DROP TABLE public.old;",
+	}, Diff("postgres", from, to, renames))
+
+	// without renames the column is dropped and added
+	assert.Equal(t, []string{
+		"ALTER TABLE public.users ADD display_name character varying(64);",
+		"ALTER TABLE public.users ADD created_at timestamp NOT NULL DEFAULT now();",
+		"ALTER TABLE public.users DROP COLUMN fullname;",
+		"ALTER TABLE public.users DROP COLUMN legacy;",
+	}, Diff("sqlserver", from[:1], to[:1], nil))
+
+	assert.Equal(t, []string{
+		"EXEC sp_rename 'public.users.fullname', 'display_name', 'COLUMN';",
+		"ALTER TABLE public.users ADD created_at timestamp NOT NULL DEFAULT now();",
+		"ALTER TABLE public.users DROP COLUMN legacy;",
+	}, Diff("sqlserver", from[:1], to[:1], renames))
+
+	assert.Empty(t, Diff("postgres", to, to, renames))
+}
+
+func TestDiffAlterColumns(t *testing.T) {
+	from := Tables{
+		{
+			Schema: "dbo",
+			Name:   "users",
+			Columns: Columns{
+				{Name: "id", Type: "bigint"},
+				{Name: "email", Type: "varchar", MaxLength: 64},
+				{Name: "fullname", Type: "varchar", MaxLength: 64, Nullable: true},
+				{Name: "age", Type: "int", Nullable: true},
+			},
+		},
+	}
+	to := Tables{
+		{
+			Schema: "dbo",
+			Name:   "users",
+			Columns: Columns{
+				{Name: "id", Type: "bigint"},
+				{Name: "email", Type: "varchar", MaxLength: 160, Nullable: true},
+				{Name: "display_name", Type: "varchar", MaxLength: 128, Nullable: true},
+				{Name: "age", Type: "int"},
+			},
+		},
+	}
+	renames := &Renames{Columns: map[string]string{"dbo.users.fullname": "display_name"}}
+
+	assert.Equal(t, []string{
+		"ALTER TABLE dbo.users RENAME COLUMN fullname TO display_name;",
+		"ALTER TABLE dbo.users ALTER COLUMN email TYPE varchar(160);",
+		"ALTER TABLE dbo.users ALTER COLUMN email DROP NOT NULL;",
+		"ALTER TABLE dbo.users ALTER COLUMN display_name TYPE varchar(128);",
+		"ALTER TABLE dbo.users ALTER COLUMN age SET NOT NULL;",
+	}, Diff("postgres", from, to, renames))
+
+	assert.Equal(t, []string{
+		"EXEC sp_rename 'dbo.users.fullname', 'display_name', 'COLUMN';",
+		"ALTER TABLE dbo.users ALTER COLUMN email varchar(160) NULL;",
+		"ALTER TABLE dbo.users ALTER COLUMN display_name varchar(128) NULL;",
+		"ALTER TABLE dbo.users ALTER COLUMN age int NOT NULL;",
+	}, Diff("sqlserver", from, to, renames))
+
+	assert.Equal(t, []string{
+		"ALTER TABLE dbo.users RENAME COLUMN fullname TO display_name;",
+		"ALTER TABLE dbo.users MODIFY COLUMN email varchar(160);",
+		"ALTER TABLE dbo.users MODIFY COLUMN display_name varchar(128);",
+		"ALTER TABLE dbo.users MODIFY COLUMN age int NOT NULL;",
+	}, Diff("mysql", from, to, renames))
+}
+
+func TestDiffDefaults(t *testing.T) {
+	from := Tables{
+		{
+			Schema: "dbo",
+			Name:   "users",
+			Columns: Columns{
+				{Name: "id", Type: "bigint", Identity: true, Default: "nextval('users_id_seq'::regclass)"},
+				{Name: "role", Type: "text", Default: "'user'::text"},
+				{Name: "status", Type: "text", Default: "'active'::text"},
+				{Name: "age", Type: "int", Nullable: true},
+			},
+		},
+	}
+	to := Tables{
+		{
+			Schema: "dbo",
+			Name:   "users",
+			Columns: Columns{
+				{Name: "id", Type: "bigint", Identity: true, Default: "nextval('members_id_seq'::regclass)"},
+				{Name: "role", Type: "text", Default: "'member'::text"},
+				{Name: "status", Type: "text"},
+				{Name: "age", Type: "int", Nullable: true, Default: "0"},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"ALTER TABLE dbo.users ALTER COLUMN role SET DEFAULT 'member'::text;",
+		"ALTER TABLE dbo.users ALTER COLUMN status DROP DEFAULT;",
+		"ALTER TABLE dbo.users ALTER COLUMN age SET DEFAULT 0;",
+	}, Diff("postgres", from, to, nil))
+
+	assert.Equal(t, []string{
+		"DECLARE @df sysname = (SELECT name FROM sys.default_constraints WHERE parent_object_id = OBJECT_ID('dbo.users') AND parent_column_id = COLUMNPROPERTY(OBJECT_ID('dbo.users'), 'role', 'ColumnId')); IF @df IS NOT NULL EXEC('ALTER TABLE dbo.users DROP CONSTRAINT ' + @df);",
+		"ALTER TABLE dbo.users ADD DEFAULT 'member'::text FOR role;",
+		"DECLARE @df sysname = (SELECT name FROM sys.default_constraints WHERE parent_object_id = OBJECT_ID('dbo.users') AND parent_column_id = COLUMNPROPERTY(OBJECT_ID('dbo.users'), 'status', 'ColumnId')); IF @df IS NOT NULL EXEC('ALTER TABLE dbo.users DROP CONSTRAINT ' + @df);",
+		"ALTER TABLE dbo.users ADD DEFAULT 0 FOR age;",
+	}, Diff("sqlserver", from, to, nil))
+
+	assert.Equal(t, []string{
+		"ALTER TABLE dbo.users MODIFY COLUMN role text NOT NULL DEFAULT 'member'::text;",
+		"ALTER TABLE dbo.users MODIFY COLUMN status text NOT NULL;",
+		"ALTER TABLE dbo.users MODIFY COLUMN age int DEFAULT 0;",
+	}, Diff("mysql", from, to, nil))
+}
@@ -0,0 +1,294 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/pkg/errors"
+)
+
+// FixtureLoader inserts the fixtures into the tables described by TableInfo,
+// for example the generated <DB>Tables map, and deletes them on cleanup.
+//
+// The values are converted by the column types:
+// the integer columns accept numbers and strings, such as xdb.ID values,
+// the time columns accept RFC3339 or date strings, and relative time, see xdb.ParseRelativeTime,
+// the bool columns accept strings, and the JSON columns accept objects and lists.
+//
+// The fixtures of the tables referenced by the foreign keys are inserted first,
+// regardless of the order in the file.
+type FixtureLoader struct {
+	tables map[string]*TableInfo
+}
+
+// NewFixtureLoader returns the loader for the tables,
+// the fixture table is matched by the name or schema.name
+func NewFixtureLoader(tables map[string]*TableInfo) *FixtureLoader {
+	l := &FixtureLoader{
+		tables: make(map[string]*TableInfo, len(tables)*2),
+	}
+	for _, t := range tables {
+		l.tables[strings.ToLower(t.Name)] = t
+		l.tables[strings.ToLower(t.TableName())] = t
+	}
+	return l
+}
+
+// Table returns the table of the fixture, or nil if the table is not known
+func (l *FixtureLoader) Table(name string) *TableInfo {
+	return l.tables[strings.ToLower(name)]
+}
+
+// Load inserts the fixtures rows in the order of the table dependencies,
+// relative time values are resolved against p.Now()
+func (l *FixtureLoader) Load(ctx context.Context, p *xdb.SQLProvider, fixtures xdb.Fixtures) error {
+	ordered, err := l.Order(fixtures)
+	if err != nil {
+		return err
+	}
+
+	for _, fx := range ordered.Resolve(time.Time(p.Now())) {
+		t := l.Table(fx.Table)
+		for i, row := range fx.Rows {
+			columns, values, err := l.values(p, t, row)
+			if err != nil {
+				return errors.WithMessagef(err, "invalid row %d in %s", i, fx.Table)
+			}
+
+			q := t.InsertInto()
+			for idx, c := range columns {
+				q.Set(t.quote(c), values[idx])
+			}
+			if _, err := q.ExecAndClose(ctx, p); err != nil {
+				return errors.WithMessagef(err, "failed to load %s", fx.Table)
+			}
+		}
+	}
+	return nil
+}
+
+// Cleanup deletes the fixtures rows by the primary key,
+// in the reverse order of Load
+func (l *FixtureLoader) Cleanup(ctx context.Context, p *xdb.SQLProvider, fixtures xdb.Fixtures) error {
+	ordered, err := l.Order(fixtures)
+	if err != nil {
+		return err
+	}
+
+	for i := len(ordered) - 1; i >= 0; i-- {
+		fx := ordered[i]
+		t := l.Table(fx.Table)
+		if t.PrimaryKey == "" {
+			return errors.Errorf("primary key is not specified for %s", fx.Table)
+		}
+		pk := t.PrimaryKey
+		for _, row := range fx.Rows {
+			val, ok := rowValue(row, pk)
+			if !ok {
+				return errors.Errorf("primary key %s is not specified in %s", pk, fx.Table)
+			}
+			val, err := coerceValue(p, t.ColumnType(pk), val)
+			if err != nil {
+				return errors.WithMessagef(err, "invalid %s in %s", pk, fx.Table)
+			}
+
+			q := t.DeleteFrom().Where(t.quote(pk)+" = ?", val)
+			if _, err := q.ExecAndClose(ctx, p); err != nil {
+				return errors.WithMessagef(err, "failed to cleanup %s", fx.Table)
+			}
+		}
+	}
+	return nil
+}
+
+// Order returns the fixtures sorted in the order of the table dependencies,
+// the fixtures of the independent tables keep the original order
+func (l *FixtureLoader) Order(fixtures xdb.Fixtures) (xdb.Fixtures, error) {
+	for _, fx := range fixtures {
+		if l.Table(fx.Table) == nil {
+			return nil, errors.Errorf("unknown table: %s", fx.Table)
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[*TableInfo]int{}
+	var ordered xdb.Fixtures
+	var visit func(t *TableInfo) error
+	visit = func(t *TableInfo) error {
+		switch state[t] {
+		case visited:
+			return nil
+		case visiting:
+			return errors.Errorf("circular reference: %s", t.TableName())
+		}
+		state[t] = visiting
+		for _, ref := range t.References {
+			if rt := l.Table(ref); rt != nil && rt != t {
+				if err := visit(rt); err != nil {
+					return err
+				}
+			}
+		}
+		state[t] = visited
+		for _, fx := range fixtures {
+			if l.Table(fx.Table) == t {
+				ordered = append(ordered, fx)
+			}
+		}
+		return nil
+	}
+
+	for _, fx := range fixtures {
+		if err := visit(l.Table(fx.Table)); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// values returns the sorted columns of the row and the converted values
+func (l *FixtureLoader) values(p *xdb.SQLProvider, t *TableInfo, row map[string]any) ([]string, []any, error) {
+	columns := make([]string, 0, len(row))
+	for k := range row {
+		idx := columnIndex(t.Columns, k)
+		if idx < 0 {
+			return nil, nil, errors.Errorf("unknown column: %s", k)
+		}
+		columns = append(columns, t.Columns[idx])
+	}
+	sort.Strings(columns)
+
+	values := make([]any, len(columns))
+	for i, c := range columns {
+		v, _ := rowValue(row, c)
+		v, err := coerceValue(p, t.ColumnType(c), v)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "invalid %s", c)
+		}
+		values[i] = v
+	}
+	return columns, values, nil
+}
+
+// rowValue returns the value of the column, the name is case insensitive
+func rowValue(row map[string]any, column string) (any, bool) {
+	if v, ok := row[column]; ok {
+		return v, true
+	}
+	for k, v := range row {
+		if strings.EqualFold(k, column) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// columnIndex returns the index of the column, the name is case insensitive
+func columnIndex(list []string, name string) int {
+	for i, s := range list {
+		if strings.EqualFold(s, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// coerceValue converts the fixture value to the value of the column type
+func coerceValue(p *xdb.SQLProvider, typ string, v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	typ = strings.ToLower(typ)
+	switch {
+	case isIntType(typ):
+		return coerceInt(v)
+	case isTimeType(typ):
+		return coerceTime(p, v)
+	case typ == "bool" || typ == "boolean" || typ == "bit":
+		if s, ok := v.(string); ok {
+			b, err := strconv.ParseBool(s)
+			return b, errors.WithStack(err)
+		}
+	case typ == "json" || typ == "jsonb":
+		switch v.(type) {
+		case map[string]any, []any:
+			js, err := json.Marshal(v)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return string(js), nil
+		}
+	}
+	if t, ok := v.(time.Time); ok {
+		return p.UTC(t), nil
+	}
+	return v, nil
+}
+
+func isIntType(typ string) bool {
+	switch typ {
+	case "int", "int2", "int4", "int8", "integer", "smallint", "bigint", "tinyint", "serial", "bigserial":
+		return true
+	}
+	return false
+}
+
+func isTimeType(typ string) bool {
+	return typ == "date" ||
+		strings.HasPrefix(typ, "timestamp") ||
+		strings.HasPrefix(typ, "datetime")
+}
+
+func coerceInt(v any) (any, error) {
+	switch val := v.(type) {
+	case string:
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return n, nil
+		}
+		// xdb.ID values above max int64 are stored as negative int64
+		n, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("not an integer: %q", val)
+		}
+		return int64(n), nil
+	case float64:
+		if val != math.Trunc(val) {
+			return nil, errors.Errorf("not an integer: %v", val)
+		}
+		return int64(val), nil
+	case uint64:
+		return int64(val), nil
+	}
+	return v, nil
+}
+
+// timeLayouts are the layouts of the time values in the fixtures
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	time.DateOnly,
+}
+
+func coerceTime(p *xdb.SQLProvider, v any) (any, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return p.UTC(val), nil
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, val); err == nil {
+				return p.UTC(t), nil
+			}
+		}
+		return nil, errors.Errorf("not a time: %q", val)
+	}
+	return v, nil
+}
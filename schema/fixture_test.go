@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fixtureTables = map[string]*TableInfo{
+	"org": {
+		SchemaName: "main.org",
+		Schema:     "main",
+		Name:       "org",
+		PrimaryKey: "id",
+		Columns:    []string{"id", "name", "meta", "active", "created_at"},
+		Types:      []string{"int8", "text", "json", "bool", "datetime"},
+		Dialect:    xsql.NoDialect,
+	},
+	"member": {
+		SchemaName: "main.member",
+		Schema:     "main",
+		Name:       "member",
+		PrimaryKey: "id",
+		Columns:    []string{"id", "org_id", "joined_at"},
+		Types:      []string{"int8", "int8", "date"},
+		References: []string{"main.org"},
+		Dialect:    xsql.NoDialect,
+	},
+}
+
+const fixturesYAML = `
+member:
+  - id: "1"
+    org_id: "18446744073709551615"
+    joined_at: 2024-01-02
+  - id: 2
+    org_id: 1001
+    joined_at: "@now-24h"
+org:
+  - id: 1001
+    name: acme
+    meta: {"tier": "gold"}
+    active: "true"
+    created_at: "2024-01-02T03:04:05.123456Z"
+  - id: "18446744073709551615"
+    name: max
+`
+
+func TestFixtureLoader(t *testing.T) {
+	ctx := context.Background()
+	d, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// :memory: database is per connection
+	d.SetMaxOpenConns(1)
+
+	now := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+	p, err := xdb.New("sqlite3", d, nil)
+	require.NoError(t, err)
+	p = p.WithClock(xdb.NewFixedClock(now))
+	defer p.Close()
+
+	for _, ddl := range []string{
+		`PRAGMA foreign_keys = ON`,
+		`CREATE TABLE org (id INTEGER PRIMARY KEY, name TEXT NOT NULL, meta JSON, active BOOLEAN, created_at DATETIME)`,
+		`CREATE TABLE member (id INTEGER PRIMARY KEY, org_id INTEGER NOT NULL REFERENCES org, joined_at DATE)`,
+	} {
+		_, err = p.ExecContext(ctx, ddl)
+		require.NoError(t, err)
+	}
+
+	fixtures, err := xdb.ParseFixtures([]byte(fixturesYAML))
+	require.NoError(t, err)
+
+	l := NewFixtureLoader(fixtureTables)
+	ordered, err := l.Order(fixtures)
+	require.NoError(t, err)
+	require.Len(t, ordered, 2)
+	assert.Equal(t, "org", ordered[0].Table)
+	assert.Equal(t, "member", ordered[1].Table)
+
+	require.NoError(t, l.Load(ctx, p, fixtures))
+
+	var (
+		name    string
+		meta    string
+		active  bool
+		created time.Time
+	)
+	err = p.QueryRowContext(ctx, "SELECT name, meta, active, created_at FROM org WHERE id = ?", 1001).
+		Scan(&name, &meta, &active, &created)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", name)
+	assert.JSONEq(t, `{"tier":"gold"}`, meta)
+	assert.True(t, active)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC), created)
+
+	var id xdb.ID
+	err = p.QueryRowContext(ctx, "SELECT org_id FROM member WHERE id = ?", 1).Scan(&id)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(18446744073709551615), id.UInt64())
+
+	var joined time.Time
+	err = p.QueryRowContext(ctx, "SELECT joined_at FROM member WHERE id = ?", 2).Scan(&joined)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-24*time.Hour), joined)
+
+	require.NoError(t, l.Cleanup(ctx, p, fixtures))
+	var n int
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT COUNT(*) FROM org").Scan(&n))
+	assert.Equal(t, 0, n)
+}
+
+func TestFixtureLoaderErrors(t *testing.T) {
+	l := NewFixtureLoader(fixtureTables)
+	assert.Same(t, fixtureTables["org"], l.Table("main.ORG"))
+	assert.Nil(t, l.Table("user"))
+
+	_, err := l.Order(xdb.Fixtures{{Table: "user"}})
+	assert.EqualError(t, err, "unknown table: user")
+
+	_, _, err = l.values(nil, fixtureTables["org"], map[string]any{"email": "a"})
+	assert.EqualError(t, err, "unknown column: email")
+	_, _, err = l.values(nil, fixtureTables["org"], map[string]any{"id": "acme"})
+	assert.EqualError(t, err, `invalid id: not an integer: "acme"`)
+	_, _, err = l.values(nil, fixtureTables["org"], map[string]any{"id": 1.5})
+	assert.EqualError(t, err, "invalid id: not an integer: 1.5")
+	_, _, err = l.values(nil, fixtureTables["org"], map[string]any{"created_at": "yesterday"})
+	assert.EqualError(t, err, `invalid created_at: not a time: "yesterday"`)
+
+	cyclic := NewFixtureLoader(map[string]*TableInfo{
+		"a": {SchemaName: "main.a", Name: "a", References: []string{"main.b"}},
+		"b": {SchemaName: "main.b", Name: "b", References: []string{"main.a"}},
+	})
+	_, err = cyclic.Order(xdb.Fixtures{{Table: "a"}})
+	assert.EqualError(t, err, "circular reference: main.a")
+}
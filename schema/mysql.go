@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/effective-security/xdb"
+)
+
+type mysql struct {
+	db xdb.DB
+}
+
+// MySQL has no schemas, the database name is reported as schema
+const mysqlTableNamesWithSchema = `
+	SELECT
+		table_schema,
+		table_name,
+		'' AS partition_key,
+		table_comment
+	FROM
+		information_schema.tables
+	WHERE
+		table_type = 'BASE TABLE' AND
+		table_schema NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')`
+
+func (p mysql) QueryTables(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("table_schema", "table_name", schema, tables, questionPlaceholder)
+	return p.db.QueryContext(ctx, mysqlTableNamesWithSchema+where+"\nORDER BY table_schema, table_name", args...)
+}
+
+const mysqlQueryColumns = `
+	SELECT column_name, data_type, data_type, is_nullable, character_maximum_length, ordinal_position,
+		column_default, NULLIF(column_comment, ''), IF(data_type = 'enum', column_type, NULL),
+		IF(extra LIKE '%auto_increment%', 'YES', 'NO'), NULL
+	FROM information_schema.columns
+	WHERE table_schema = ? AND table_name = ?
+`
+
+func (p mysql) QueryColumns(ctx context.Context, schema, table string) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, mysqlQueryColumns, schema, table)
+}
+
+const mysqlQueryViews = `
+SELECT
+	c.table_schema,
+	c.table_name,
+	c.column_name,
+	c.data_type,
+	c.data_type,
+	c.is_nullable,
+	c.character_maximum_length,
+	c.ordinal_position
+FROM information_schema.views v
+JOIN information_schema.columns c
+	ON v.table_schema = c.table_schema
+	AND v.table_name = c.table_name
+WHERE v.table_schema NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')`
+
+func (p mysql) QueryViews(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("c.table_schema", "c.table_name", schema, tables, questionPlaceholder)
+	return p.db.QueryContext(ctx, mysqlQueryViews+where+"\nORDER BY c.table_schema, c.table_name", args...)
+}
+
+const mysqlQueryIndexes = `
+SELECT
+	index_name,
+	index_name = 'PRIMARY' AS is_pk,
+	non_unique = 0 AS is_unique,
+	GROUP_CONCAT(column_name ORDER BY seq_in_index SEPARATOR ',') AS column_names
+FROM information_schema.statistics
+WHERE table_schema = ? AND table_name = ?
+GROUP BY
+	index_name,
+	non_unique
+ORDER BY
+	index_name;
+`
+
+func (p mysql) QueryIndexes(ctx context.Context, schema, table string) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, mysqlQueryIndexes, schema, table)
+}
+
+const mysqlQueryForeignKeys = `
+SELECT
+	constraint_name,
+	table_schema,
+	table_name,
+	column_name,
+	referenced_table_schema,
+	referenced_table_name,
+	referenced_column_name
+FROM information_schema.key_column_usage
+WHERE referenced_table_name IS NOT NULL
+	AND table_schema NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')`
+
+func (p mysql) QueryForeignKeys(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("table_schema", "table_name", schema, tables, questionPlaceholder)
+	return p.db.QueryContext(ctx, mysqlQueryForeignKeys+where, args...)
+}
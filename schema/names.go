@@ -0,0 +1,184 @@
+package schema
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxIdentifierLength returns the maximum length in bytes of identifiers
+// supported by the provider, or 0 if the length is not limited
+func MaxIdentifierLength(provider string) int {
+	switch provider {
+	case "postgres":
+		return 63
+	case "sqlserver", "mssql":
+		return 128
+	case "mysql":
+		return 64
+	default:
+		return 0
+	}
+}
+
+// reservedWords provides the keywords reserved by all dialects
+var reservedWords = map[string]bool{
+	"all": true, "alter": true, "and": true, "as": true, "asc": true,
+	"between": true, "by": true, "case": true, "check": true, "column": true,
+	"constraint": true, "create": true, "cross": true, "default": true, "delete": true,
+	"desc": true, "distinct": true, "drop": true, "else": true, "end": true,
+	"exists": true, "foreign": true, "from": true, "grant": true, "group": true,
+	"having": true, "in": true, "index": true, "inner": true, "insert": true,
+	"into": true, "is": true, "join": true, "left": true, "like": true,
+	"not": true, "null": true, "on": true, "or": true, "order": true,
+	"primary": true, "references": true, "right": true, "select": true, "set": true,
+	"table": true, "then": true, "to": true, "union": true, "unique": true,
+	"update": true, "values": true, "when": true, "where": true, "with": true,
+}
+
+// reservedWordsByProvider provides the keywords reserved by the dialect
+var reservedWordsByProvider = map[string]map[string]bool{
+	"postgres": {
+		"analyse": true, "analyze": true, "array": true, "current_date": true, "current_time": true,
+		"current_timestamp": true, "current_user": true, "limit": true, "offset": true, "only": true,
+		"returning": true, "session_user": true, "user": true, "window": true,
+	},
+	"sqlserver": {
+		"backup": true, "file": true, "function": true, "identity": true, "key": true,
+		"open": true, "percent": true, "plan": true, "procedure": true, "rule": true,
+		"schema": true, "top": true, "tran": true, "user": true, "view": true,
+	},
+	"mysql": {
+		"condition": true, "interval": true, "key": true, "keys": true, "limit": true,
+		"range": true, "rank": true, "read": true, "usage": true, "window": true,
+	},
+}
+
+// IsReservedWord returns true if the name is the keyword reserved by the provider,
+// that must be quoted to be used as identifier
+func IsReservedWord(provider, name string) bool {
+	name = strings.ToLower(name)
+	if provider == "mssql" {
+		provider = "sqlserver"
+	}
+	return reservedWords[name] || reservedWordsByProvider[provider][name]
+}
+
+// NameWarning describes the name of database object,
+// that exceeds the identifier length limit or is reserved keyword of the dialect
+type NameWarning struct {
+	// Kind of the object: table, column, index or foreign key
+	Kind string
+	// Name of the object in schema.table.name format
+	Name string
+	// Message describes the issue
+	Message string
+	// Suggested provides truncated name, unique within the scope of the object,
+	// it's empty if the name does not need to be changed
+	Suggested string
+}
+
+// String returns the warning message
+func (w *NameWarning) String() string {
+	s := fmt.Sprintf("%s %s: %s", w.Kind, w.Name, w.Message)
+	if w.Suggested != "" {
+		s += ", suggested: " + w.Suggested
+	}
+	return s
+}
+
+/*
+ValidateNames validates the names of tables, columns, indexes and foreign keys
+against the identifier length limit and reserved keywords of the provider.
+
+The suggested names of tables, indexes and foreign keys are unique within the schema,
+as Postgres keeps them in the same namespace, and the names of columns within the table.
+*/
+func ValidateNames(provider string, tables Tables, fks ForeignKeys) []*NameWarning {
+	limit := MaxIdentifierLength(provider)
+	// lower case names by schema, or by schema.table for columns
+	taken := map[string]map[string]bool{}
+	take := func(scope, name string) {
+		scope = strings.ToLower(scope)
+		if taken[scope] == nil {
+			taken[scope] = map[string]bool{}
+		}
+		taken[scope][strings.ToLower(name)] = true
+	}
+	for _, t := range tables {
+		take(t.Schema, t.Name)
+		for _, c := range t.Columns {
+			take(t.Schema+"."+t.Name, c.Name)
+		}
+		for _, idx := range t.Indexes {
+			take(t.Schema, idx.Name)
+		}
+	}
+	for _, fk := range fks {
+		take(fk.Schema, fk.Name)
+	}
+
+	var list []*NameWarning
+	check := func(kind, scope, fqn, name string, reserved bool) {
+		if limit > 0 && len(name) > limit {
+			suggested := shortenName(name, limit, taken[strings.ToLower(scope)])
+			take(scope, suggested)
+			list = append(list, &NameWarning{
+				Kind:      kind,
+				Name:      fqn,
+				Message:   fmt.Sprintf("exceeds %d bytes limit of %s", limit, provider),
+				Suggested: suggested,
+			})
+		}
+		if reserved && IsReservedWord(provider, name) {
+			list = append(list, &NameWarning{
+				Kind:    kind,
+				Name:    fqn,
+				Message: fmt.Sprintf("%q is reserved keyword of %s and must be quoted", name, provider),
+			})
+		}
+	}
+
+	for _, t := range tables {
+		table := t.Schema + "." + t.Name
+		check("table", t.Schema, table, t.Name, true)
+		for _, c := range t.Columns {
+			check("column", table, table+"."+c.Name, c.Name, true)
+		}
+		for _, idx := range t.Indexes {
+			check("index", t.Schema, table+"."+idx.Name, idx.Name, false)
+		}
+	}
+	for _, fk := range fks {
+		check("foreign key", fk.Schema, fk.Schema+"."+fk.Table+"."+fk.Name, fk.Name, false)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// shortenName returns the name truncated to limit bytes,
+// with the hash suffix to keep it unique within the taken names
+func shortenName(name string, limit int, taken map[string]bool) string {
+	for i := 0; ; i++ {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(name))
+		if i > 0 {
+			_, _ = fmt.Fprintf(h, "#%d", i)
+		}
+		suffix := fmt.Sprintf("_%08x", h.Sum32())
+
+		prefix := name[:limit-len(suffix)]
+		for !utf8.ValidString(prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+		res := strings.TrimRight(prefix, "_") + suffix
+		if !taken[strings.ToLower(res)] {
+			return res
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReservedWord(t *testing.T) {
+	assert.True(t, IsReservedWord("postgres", "User"))
+	assert.True(t, IsReservedWord("mssql", "user"))
+	assert.True(t, IsReservedWord("sqlite3", "select"))
+	assert.True(t, IsReservedWord("mysql", "key"))
+	assert.False(t, IsReservedWord("postgres", "key"))
+	assert.False(t, IsReservedWord("mysql", "user"))
+	assert.False(t, IsReservedWord("postgres", "users"))
+}
+
+func TestValidateNames(t *testing.T) {
+	long := strings.Repeat("a", 70)
+	tables := Tables{
+		{
+			Schema:  "public",
+			Name:    "user",
+			Columns: Columns{{Name: "id"}, {Name: "order"}, {Name: long}},
+			Indexes: Indexes{{Name: "idx_" + long}, {Name: "idx_" + long + "_2"}},
+		},
+	}
+	fks := ForeignKeys{{Schema: "public", Table: "user", Name: "fk_" + long}}
+
+	list := ValidateNames("postgres", tables, fks)
+	require.Len(t, list, 6)
+
+	var msgs []string
+	suggested := map[string]bool{}
+	for _, w := range list {
+		msgs = append(msgs, w.String())
+		if w.Suggested != "" {
+			assert.Len(t, w.Suggested, 63)
+			assert.False(t, suggested[w.Suggested], w.Suggested)
+			suggested[w.Suggested] = true
+		}
+	}
+	assert.Equal(t, `table public.user: "user" is reserved keyword of postgres and must be quoted`, msgs[0])
+	assert.Contains(t, msgs, `column public.user.order: "order" is reserved keyword of postgres and must be quoted`)
+	assert.True(t, strings.HasPrefix(msgs[1], "column public.user."+long+": exceeds 63 bytes limit of postgres, suggested: aaaa"), msgs[1])
+	// indexes are truncated to the same prefix, but the suggested names are unique
+	assert.Len(t, suggested, 4)
+
+	list = ValidateNames("sqlserver", tables, fks)
+	require.Len(t, list, 2)
+	assert.Equal(t, "table", list[0].Kind)
+	assert.Equal(t, "column", list[1].Kind)
+	assert.Empty(t, list[1].Suggested)
+
+	assert.Empty(t, ValidateNames("sqlite3", Tables{{Schema: "main", Name: long}}, nil))
+}
+
+func TestShortenName(t *testing.T) {
+	name := strings.Repeat("a", 60) + "_bbbbb"
+	res := shortenName(name, 63, nil)
+	assert.Len(t, res, 63)
+	assert.Equal(t, strings.Repeat("a", 54), res[:54])
+
+	res2 := shortenName(name, 63, map[string]bool{res: true})
+	assert.NotEqual(t, res, res2)
+	assert.Len(t, res2, 63)
+
+	// does not split multi-byte characters
+	res = shortenName(strings.Repeat("é", 40), 63, nil)
+	assert.LessOrEqual(t, len(res), 63)
+	assert.True(t, strings.HasPrefix(res, "éé"))
+}
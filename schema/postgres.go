@@ -14,25 +14,50 @@ type postgres struct {
 
 const postgresTableNamesWithSchema = `
 	SELECT
-		table_schema,
-		table_name
+		t.table_schema,
+		t.table_name,
+		COALESCE((
+			SELECT a.attname
+			FROM pg_partitioned_table pt
+			JOIN pg_attribute a ON a.attrelid = pt.partrelid AND a.attnum = pt.partattrs[0]
+			WHERE pt.partrelid = format('%I.%I', t.table_schema, t.table_name)::regclass
+				AND pt.partstrat = 'r' AND pt.partnatts = 1
+		), '') AS partition_key,
+		COALESCE(obj_description(format('%I.%I', t.table_schema, t.table_name)::regclass, 'pg_class'), '') AS comment
 	FROM
-		information_schema.tables
+		information_schema.tables t
 	WHERE
 		table_type = 'BASE TABLE' AND
-		table_schema NOT IN ('pg_catalog', 'information_schema')
-	ORDER BY
-		table_schema,
-		table_name
-`
+		table_schema NOT IN ('pg_catalog', 'information_schema')`
+
+func postgresPlaceholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
 
-func (p postgres) QueryTables(ctx context.Context) (*sql.Rows, error) {
-	return p.db.QueryContext(ctx, postgresTableNamesWithSchema)
+func (p postgres) QueryTables(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("t.table_schema", "t.table_name", schema, tables, postgresPlaceholder)
+	return p.db.QueryContext(ctx, postgresTableNamesWithSchema+where+"\nORDER BY table_schema, table_name", args...)
 }
 
 func (p postgres) QueryColumns(ctx context.Context, schema, table string) (*sql.Rows, error) {
 	qry := fmt.Sprintf(`
-	SELECT column_name, data_type, udt_name, is_nullable, character_maximum_length, ordinal_position 
+	SELECT column_name, data_type, udt_name, is_nullable, character_maximum_length, ordinal_position,
+		column_default, col_description(format('%%I.%%I', table_schema, table_name)::regclass, ordinal_position),
+		COALESCE(
+			(SELECT array_to_json(array_agg(e.enumlabel ORDER BY e.enumsortorder))::text
+				FROM pg_enum e
+				JOIN pg_type t ON t.oid = e.enumtypid
+				JOIN pg_namespace n ON n.oid = t.typnamespace
+				WHERE t.typname = udt_name AND n.nspname = udt_schema),
+			(SELECT pg_get_constraintdef(con.oid)
+				FROM pg_constraint con
+				WHERE con.contype = 'c'
+				AND con.conrelid = format('%%I.%%I', table_schema, table_name)::regclass
+				AND array_length(con.conkey, 1) = 1
+				AND con.conkey[1] = ordinal_position::int
+				LIMIT 1)),
+		CASE WHEN is_identity = 'YES' OR column_default LIKE 'nextval(%%' THEN 'YES' ELSE 'NO' END,
+		pg_get_serial_sequence(format('%%I.%%I', table_schema, table_name), column_name)
   	FROM information_schema.columns
  	WHERE table_schema = '%s'
    	AND table_name = '%s';
@@ -56,11 +81,11 @@ LEFT JOIN information_schema.columns c
 	   ON t.table_schema = c.table_schema 
 	   AND t.table_name = c.table_name
 WHERE table_type = 'VIEW' 
-	AND t.table_schema not in ('information_schema', 'pg_catalog')
-ORDER BY table_schema, table_name;`
+	AND t.table_schema not in ('information_schema', 'pg_catalog')`
 
-func (p postgres) QueryViews(ctx context.Context) (*sql.Rows, error) {
-	return p.db.QueryContext(ctx, postgresQueryViews)
+func (p postgres) QueryViews(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("t.table_schema", "t.table_name", schema, tables, postgresPlaceholder)
+	return p.db.QueryContext(ctx, postgresQueryViews+where+"\nORDER BY table_schema, table_name", args...)
 }
 
 const postgresQueryIndexes = `
@@ -111,9 +136,9 @@ JOIN information_schema.key_column_usage AS kcu
     AND tc.table_schema = kcu.table_schema
 JOIN information_schema.constraint_column_usage AS ccu
     ON ccu.constraint_name = tc.constraint_name
-WHERE tc.constraint_type = 'FOREIGN KEY';
-`
+WHERE tc.constraint_type = 'FOREIGN KEY'`
 
-func (p postgres) QueryForeignKeys(ctx context.Context) (*sql.Rows, error) {
-	return p.db.QueryContext(ctx, postgresQueryForeignKeys)
+func (p postgres) QueryForeignKeys(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("tc.table_schema", "tc.table_name", schema, tables, postgresPlaceholder)
+	return p.db.QueryContext(ctx, postgresQueryForeignKeys+where, args...)
 }
@@ -3,6 +3,7 @@ package schema
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -12,13 +13,46 @@ import (
 	"github.com/pkg/errors"
 )
 
-// Dialect interface
+// Dialect interface,
+// schema and tables are optional parameters to filter the catalog queries
 type Dialect interface {
-	QueryTables(ctx context.Context) (*sql.Rows, error)
-	QueryViews(ctx context.Context) (*sql.Rows, error)
+	QueryTables(ctx context.Context, schema string, tables []string) (*sql.Rows, error)
+	QueryViews(ctx context.Context, schema string, tables []string) (*sql.Rows, error)
 	QueryColumns(ctx context.Context, schema, table string) (*sql.Rows, error)
 	QueryIndexes(ctx context.Context, schema, table string) (*sql.Rows, error)
-	QueryForeignKeys(ctx context.Context) (*sql.Rows, error)
+	QueryForeignKeys(ctx context.Context, schema string, tables []string) (*sql.Rows, error)
+}
+
+// placeholder returns the query parameter placeholder by position, starting at 1
+type placeholder func(n int) string
+
+// questionPlaceholder is the placeholder of MySQL and SQLite
+func questionPlaceholder(int) string {
+	return "?"
+}
+
+// nameFilter returns the condition to filter the catalog query by schema and table names,
+// so the database filters the objects instead of scanning the whole catalog.
+// The names are compared case-insensitive.
+func nameFilter(schemaCol, tableCol, schema string, tables []string, ph placeholder) (string, []any) {
+	var sb strings.Builder
+	var args []any
+	if schema != "" {
+		args = append(args, strings.ToLower(schema))
+		fmt.Fprintf(&sb, "\n\tAND LOWER(%s) = %s", schemaCol, ph(len(args)))
+	}
+	if len(tables) > 0 {
+		fmt.Fprintf(&sb, "\n\tAND LOWER(%s) IN (", tableCol)
+		for i, t := range tables {
+			args = append(args, strings.ToLower(t))
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(ph(len(args)))
+		}
+		sb.WriteString(")")
+	}
+	return sb.String(), args
 }
 
 // SQLServerProvider implementation
@@ -41,6 +75,10 @@ func NewProvider(db xdb.DB, provider string) Provider {
 		dialect = &sqlserver{db: db}
 	case "postgres":
 		dialect = &postgres{db: db}
+	case "mysql":
+		dialect = &mysql{db: db}
+	case "sqlite3", "sqlite":
+		dialect = &sqlite{db: db}
 	}
 
 	p := &SQLServerProvider{
@@ -65,82 +103,121 @@ func (r *SQLServerProvider) Name() string {
 // schema and tables are optional parameters to filter,
 // if not provided, then all items are returned
 func (r *SQLServerProvider) ListTables(ctx context.Context, schema string, tables []string, withDependencies bool) (Tables, error) {
-	rows, err := r.dialect.QueryTables(ctx)
+	tt := Tables{}
+	err := r.StreamTables(ctx, schema, tables, func(t *Table) error {
+		r.cacheTable(t)
+		tt = append(tt, t)
+		return nil
+	})
 	if err != nil {
-		return nil, errors.WithMessagef(err, "failed to query tables")
+		return nil, err
 	}
 
-	tt := Tables{}
-	for rows.Next() {
-		t := new(Table)
-		if err := rows.Scan(&t.Schema, &t.Name); err != nil {
-			return nil, errors.WithMessagef(err, "failed to scan")
+	if withDependencies {
+		tt, err = r.discover(ctx)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		if schema != "" && !strings.EqualFold(t.Schema, schema) {
-			continue
-		}
+	sort.Slice(tt, func(i int, j int) bool {
+		return tt[i].SchemaName < tt[j].SchemaName
+	})
 
-		if len(tables) > 0 && !slices.ContainsStringEqualFold(tables, t.Name) {
-			continue
-		}
+	return tt, nil
+}
 
-		t.SchemaName = fmt.Sprintf("%s.%s", t.Schema, t.Name)
+// StreamTables reads the tables in the order of schema and name,
+// and calls fn for each table, without keeping the tables in memory.
+// schema and tables are optional parameters to filter,
+// if not provided, then all items are returned
+func (r *SQLServerProvider) StreamTables(ctx context.Context, schema string, tables []string, fn func(*Table) error) error {
+	rows, err := r.dialect.QueryTables(ctx, schema, tables)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to query tables")
+	}
+	defer rows.Close()
 
-		cc, err := r.readColumnsSchema(ctx, t.Schema, t.Name)
-		if err != nil {
-			return nil, errors.WithMessagef(err, "failed to read columns: %s", t.SchemaName)
+	// the names are read first, as the columns and indexes are queried per table
+	var list Tables
+	for rows.Next() {
+		t := new(Table)
+		if err := rows.Scan(&t.Schema, &t.Name, &t.PartitionKey, &t.Comment); err != nil {
+			return errors.WithMessagef(err, "failed to scan")
 		}
+		t.SchemaName = fmt.Sprintf("%s.%s", t.Schema, t.Name)
+		list = append(list, t)
+	}
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+	_ = rows.Close()
 
-		t.Columns = cc
-
-		ii, _, err := r.readIndexesSchema(ctx, t.Schema, t.Name)
-		if err != nil {
-			return nil, errors.WithMessagef(err, "failed to read indexes: %s", t.SchemaName)
+	for i, t := range list {
+		if err = r.readTable(ctx, t); err != nil {
+			return err
 		}
-		t.Indexes = ii
-
-		for _, idx := range ii {
-			for _, cn := range idx.ColumnNames {
-				colShemaName := fmt.Sprintf("%s.%s", t.SchemaName, cn)
-				col := r.columns[colShemaName]
-				col.Indexes = append(col.Indexes, idx)
-				if idx.IsPrimary && len(idx.ColumnNames) == 1 {
-					t.PrimaryKey = col
-				}
-			}
+		if err = fn(t); err != nil {
+			return err
 		}
+		list[i] = nil
+	}
+	return nil
+}
 
-		r.tables[t.SchemaName] = t
-		tt = append(tt, t)
+// readTable reads columns and indexes of the table
+func (r *SQLServerProvider) readTable(ctx context.Context, t *Table) error {
+	cc, err := r.readColumnsSchema(ctx, t.Schema, t.Name)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to read columns: %s", t.SchemaName)
 	}
+	t.Columns = cc
 
-	if rows.Err() != nil {
-		return nil, rows.Err()
+	ii, _, err := r.readIndexesSchema(ctx, t.Schema, t.Name)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to read indexes: %s", t.SchemaName)
 	}
+	t.Indexes = ii
 
-	if withDependencies {
-		tt, err = r.discover(ctx)
-		if err != nil {
-			return nil, err
+	columns := make(map[string]*Column, len(cc))
+	for _, c := range cc {
+		columns[c.Name] = c
+	}
+	for _, idx := range ii {
+		for _, cn := range idx.ColumnNames {
+			col := columns[cn]
+			if col == nil {
+				continue
+			}
+			col.Indexes = append(col.Indexes, idx)
+			if idx.IsPrimary && len(idx.ColumnNames) == 1 {
+				t.PrimaryKey = col
+			}
 		}
 	}
+	return nil
+}
 
-	sort.Slice(tt, func(i int, j int) bool {
-		return tt[i].SchemaName < tt[j].SchemaName
-	})
-
-	return tt, nil
+// cacheTable adds the table, its columns and indexes to the cache for discovery
+func (r *SQLServerProvider) cacheTable(t *Table) {
+	r.tables[t.SchemaName] = t
+	for _, c := range t.Columns {
+		r.columns[c.SchemaName] = c
+	}
+	for _, idx := range t.Indexes {
+		r.indexes[idx.SchemaName] = idx
+	}
 }
 
 // ListViews returns a list of views in database.
 // schemaName and tableNames are optional parameters to filter,
 // if not provided, then all items are returned
 func (r *SQLServerProvider) ListViews(ctx context.Context, schema string, tables []string) (Tables, error) {
-	rows, err := r.dialect.QueryViews(ctx)
+	rows, err := r.dialect.QueryViews(ctx, schema, tables)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to query tables")
 	}
+	defer rows.Close()
 
 	tablesMap := map[string]*Table{} // map of Table FQN => table
 
@@ -154,13 +231,6 @@ func (r *SQLServerProvider) ListViews(ctx context.Context, schema string, tables
 		if err := rows.Scan(&schemaName, &tableName, &c.Name, &c.Type, &c.UdtType, &nullable, &max, &ordinal); err != nil {
 			return nil, errors.WithStack(err)
 		}
-		if schema != "" && !strings.EqualFold(schema, schemaName) {
-			continue
-		}
-
-		if len(tables) > 0 && !slices.ContainsStringEqualFold(tables, tableName) {
-			continue
-		}
 		c.Nullable = slices.ContainsStringEqualFold(nullableVals, nullable)
 		c.MaxLength = maxLength(max)
 		c.Name = columnName(c.Name)
@@ -201,29 +271,35 @@ func (r *SQLServerProvider) ListViews(ctx context.Context, schema string, tables
 }
 
 var nullableVals = []string{"YES", "TRUE", "NULL"}
+var identityVals = []string{"YES", "TRUE", "1"}
 
 func (r *SQLServerProvider) readColumnsSchema(ctx context.Context, schema, table string) (Columns, error) {
 	rows, err := r.dialect.QueryColumns(ctx, schema, table)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	defer rows.Close()
 
 	cc := Columns{}
 	for rows.Next() {
 		c := &Column{}
-		var nullable string
+		var nullable, identity string
 		var max *int
 		var ordinal int
-		if err := rows.Scan(&c.Name, &c.Type, &c.UdtType, &nullable, &max, &ordinal); err != nil {
+		var def, comment, enum, sequence sql.NullString
+		if err := rows.Scan(&c.Name, &c.Type, &c.UdtType, &nullable, &max, &ordinal, &def, &comment, &enum, &identity, &sequence); err != nil {
 			return nil, errors.WithStack(err)
 		}
 		c.Position = uint32(ordinal)
+		c.Default = def.String
+		c.Identity = slices.ContainsStringEqualFold(identityVals, identity)
+		c.Sequence = sequence.String
+		c.Comment = comment.String
+		c.Enum = enumValues(enum.String)
 		c.Nullable = slices.ContainsStringEqualFold(nullableVals, nullable)
 		c.MaxLength = maxLength(max)
 		c.Name = columnName(c.Name)
 		c.SchemaName = fmt.Sprintf("%s.%s.%s", schema, table, c.Name)
-		r.columns[c.SchemaName] = c
-
 		cc = append(cc, c)
 	}
 
@@ -243,6 +319,7 @@ func (r *SQLServerProvider) readIndexesSchema(ctx context.Context, schema, table
 	if err != nil {
 		return nil, nil, errors.WithStack(err)
 	}
+	defer rows.Close()
 
 	var pk *Index
 	cc := Indexes{}
@@ -259,8 +336,6 @@ func (r *SQLServerProvider) readIndexesSchema(ctx context.Context, schema, table
 			c.ColumnNames = append(c.ColumnNames, cn)
 		}
 		c.SchemaName = fmt.Sprintf("%s.%s.%s", schema, table, c.Name)
-		r.indexes[c.SchemaName] = c
-
 		cc = append(cc, c)
 
 		if c.IsPrimary {
@@ -279,10 +354,11 @@ func (r *SQLServerProvider) readIndexesSchema(ctx context.Context, schema, table
 // schema and tables are optional parameters to filter on source tables,
 // if not provided, then all items are returned
 func (r *SQLServerProvider) ListForeignKeys(ctx context.Context, schema string, tables []string) (ForeignKeys, error) {
-	rows, err := r.dialect.QueryForeignKeys(ctx)
+	rows, err := r.dialect.QueryForeignKeys(ctx, schema, tables)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to query foreign keys")
 	}
+	defer rows.Close()
 
 	keys := ForeignKeys{}
 	for rows.Next() {
@@ -299,13 +375,6 @@ func (r *SQLServerProvider) ListForeignKeys(ctx context.Context, schema string,
 			return nil, errors.WithMessagef(err, "failed to scan foreign keys")
 		}
 
-		if schema != "" && !strings.EqualFold(k.Schema, schema) {
-			continue
-		}
-		if len(tables) > 0 && !slices.ContainsStringEqualFold(tables, k.Table) {
-			continue
-		}
-
 		k.Column = columnName(k.Column)
 		k.RefColumn = columnName(k.RefColumn)
 		k.SchemaName = fmt.Sprintf("%s.%s.%s", k.Schema, k.Table, k.Name)
@@ -368,7 +437,7 @@ func (r *SQLServerProvider) discoverTable(ctx context.Context, schema, table str
 	}
 
 	t.Columns = cc
-	r.tables[t.SchemaName] = t
+	r.cacheTable(t)
 
 	// traverse columns
 	for _, c := range cc {
@@ -403,3 +472,85 @@ func maxLength(v *int) uint32 {
 	}
 	return uint32(*v)
 }
+
+// enumValues returns the values of enum type,
+// provided as JSON array by Postgres, or as column type by MySQL: enum('a','b'),
+// or the values allowed by the check constraint: CHECK (status IN ('a','b'))
+func enumValues(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var list []string
+	if strings.HasPrefix(s, "[") {
+		_ = json.Unmarshal([]byte(s), &list)
+		return list
+	}
+	if strings.HasPrefix(s, "CHECK") {
+		return checkValues(s)
+	}
+
+	s = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(s, "enum("), "ENUM("), ")")
+	for len(s) > 0 && s[0] == '\'' {
+		val, n := quotedValue(s)
+		list = append(list, val)
+		s = strings.TrimPrefix(s[n:], ",")
+	}
+	return list
+}
+
+// checkValues returns the string literals of the check constraint,
+// if the constraint only compares the column with the list of values:
+//
+//	CHECK ((status = ANY (ARRAY['active'::text, 'disabled'::text])))
+//	CHECK ([status]='disabled' OR [status]='active')
+//
+// nil is returned for other constraints, like ranges or patterns.
+func checkValues(def string) []string {
+	var list []string
+	var rest strings.Builder
+	for s := def; len(s) > 0; {
+		if s[0] != '\'' {
+			rest.WriteByte(s[0])
+			s = s[1:]
+			continue
+		}
+		val, n := quotedValue(s)
+		if !slices.Contains(list, val) {
+			list = append(list, val)
+		}
+		s = s[n:]
+	}
+
+	expr := strings.ToUpper(rest.String())
+	if strings.ContainsAny(expr, "<>!~") {
+		return nil
+	}
+	for _, word := range strings.FieldsFunc(expr, func(r rune) bool {
+		return !(r == '_' || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	}) {
+		switch word {
+		case "AND", "NOT", "LIKE", "ILIKE", "BETWEEN", "SIMILAR":
+			return nil
+		}
+	}
+	return list
+}
+
+// quotedValue returns the value of the quoted literal at the start of s,
+// and the number of consumed bytes
+func quotedValue(s string) (string, int) {
+	var val strings.Builder
+	i := 1
+	for ; i < len(s); i++ {
+		if s[i] == '\'' {
+			if i+1 < len(s) && s[i+1] == '\'' {
+				val.WriteByte('\'')
+				i++
+				continue
+			}
+			break
+		}
+		val.WriteByte(s[i])
+	}
+	return val.String(), min(i+1, len(s))
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/effective-security/xdb"
 	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
 )
 
 //go:generate mockgen -source=schema.go -destination=../mocks/mockschema/schema_mock.go -package mockschema
@@ -18,6 +20,22 @@ type TableInfo struct {
 	PrimaryKey string
 	Columns    []string
 	Indexes    []string
+	// PartitionKey is the column of the range partition key,
+	// for partitioned tables
+	PartitionKey string `json:",omitempty" yaml:",omitempty"`
+	// Comment provides the table description
+	Comment string `json:",omitempty" yaml:",omitempty"`
+	// SoftDeleteColumn is the timestamp column that marks the deleted rows,
+	// for tables with soft delete
+	SoftDeleteColumn string `json:",omitempty" yaml:",omitempty"`
+	// QuoteNames specifies to quote the names of the table and columns
+	// in the statements, for tables with the names that are reserved keywords
+	QuoteNames bool `json:",omitempty" yaml:",omitempty"`
+	// Types provides the UDT types of the columns, in the order of Columns
+	Types []string `json:",omitempty" yaml:",omitempty"`
+	// References provides the tables referenced by the foreign keys,
+	// in schema.name format
+	References []string `json:",omitempty" yaml:",omitempty"`
 
 	Dialect xsql.SQLDialect `json:"-" yaml:"-"`
 
@@ -27,50 +45,158 @@ type TableInfo struct {
 	allColumns string `json:"-" yaml:"-"`
 }
 
+// TableName returns FQN of the table in schema.name format
+func (t *TableInfo) TableName() string {
+	if t.SchemaName == "" {
+		return t.Name
+	}
+	return t.SchemaName
+}
+
+// ColumnType returns the UDT type of the column,
+// or empty string if the type is not known
+func (t *TableInfo) ColumnType(name string) string {
+	for i, c := range t.Columns {
+		if strings.EqualFold(c, name) && i < len(t.Types) {
+			return t.Types[i]
+		}
+	}
+	return ""
+}
+
+// PartitionColumn returns the column of the partition key,
+// or empty string if the table is not partitioned
+func (t *TableInfo) PartitionColumn() string {
+	return t.PartitionKey
+}
+
+// ColumnNames returns the list of columns
+func (t *TableInfo) ColumnNames() []string {
+	return t.Columns
+}
+
+// KeyColumns returns the columns of the primary key,
+// or nil if the table has no primary key
+func (t *TableInfo) KeyColumns() []string {
+	if t.PrimaryKey == "" {
+		return nil
+	}
+	return []string{t.PrimaryKey}
+}
+
+// SQLDialect returns the dialect of the table
+func (t *TableInfo) SQLDialect() xsql.SQLDialect {
+	return t.Dialect
+}
+
+// quote returns the name quoted by the dialect, if QuoteNames is set
+func (t *TableInfo) quote(name string) string {
+	if !t.QuoteNames {
+		return name
+	}
+	return t.Dialect.Quote(name)
+}
+
 // From starts FROM expression
 func (t *TableInfo) From() xsql.Builder {
-	return t.Dialect.From(t.SchemaName)
+	return t.Dialect.From(t.quote(t.SchemaName))
+}
+
+// HasIndex returns true if the table has the index
+func (t *TableInfo) HasIndex(name string) bool {
+	for _, idx := range t.Indexes {
+		if strings.EqualFold(idx, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromIndex starts FROM expression with the index hint,
+// the index is validated against the table metadata
+func (t *TableInfo) FromIndex(name string) (xsql.Builder, error) {
+	if !t.HasIndex(name) {
+		return nil, errors.Errorf("index %q does not exist in %s", name, t.SchemaName)
+	}
+	return t.Dialect.From(t.quote(t.SchemaName)).UseIndex(name), nil
 }
 
 // DeleteFrom starts DELETE FROM expression
 func (t *TableInfo) DeleteFrom() xsql.Builder {
-	return t.Dialect.DeleteFrom(t.SchemaName)
+	return t.Dialect.DeleteFrom(t.quote(t.SchemaName))
 }
 
 // InsertInto starts INSERT expression
 func (t *TableInfo) InsertInto() xsql.Builder {
-	return t.Dialect.InsertInto(t.SchemaName)
+	return t.Dialect.InsertInto(t.quote(t.SchemaName))
 }
 
 // Update starts UPDATE expression
 func (t *TableInfo) Update() xsql.Builder {
-	return t.Dialect.Update(t.SchemaName)
+	return t.Dialect.Update(t.quote(t.SchemaName))
 }
 
-// Select starts SELECT FROM  expression
+// Select starts SELECT FROM  expression,
+// the deleted rows are filtered for tables with soft delete
 func (t *TableInfo) Select(cols ...string) xsql.Builder {
+	q := t.SelectWithDeleted(cols...)
+	if t.SoftDeleteColumn != "" {
+		q.Where(t.quote(t.SoftDeleteColumn) + " IS NULL")
+	}
+	return q
+}
+
+// SelectWithDeleted starts SELECT FROM  expression,
+// including the deleted rows for tables with soft delete.
+// The provided columns are not quoted, as they can be expressions.
+func (t *TableInfo) SelectWithDeleted(cols ...string) xsql.Builder {
 	var expr string
 	if len(cols) > 0 {
 		expr = strings.Join(cols, ",")
 	} else {
 		expr = t.AllColumns()
 	}
-	return t.Dialect.From(t.SchemaName).Select(expr)
+	return t.Dialect.From(t.quote(t.SchemaName)).Select(expr)
 }
 
-// Select starts SELECT FROM  expression
+// Select starts SELECT FROM  expression,
+// the deleted rows are filtered for tables with soft delete
 func (t *TableInfo) SelectAliased(prefix string, nulls map[string]bool) xsql.Builder {
-	tn := t.SchemaName
+	tn := t.quote(t.SchemaName)
 	if prefix != "" {
 		tn = tn + " " + prefix
 	}
-	return t.Dialect.From(tn).Select(t.AliasedColumns(prefix, nulls))
+	q := t.Dialect.From(tn).Select(t.AliasedColumns(prefix, nulls))
+	if t.SoftDeleteColumn != "" {
+		col := t.quote(t.SoftDeleteColumn)
+		if prefix != "" {
+			col = prefix + "." + col
+		}
+		q.Where(col + " IS NULL")
+	}
+	return q
+}
+
+// SoftDelete starts UPDATE expression that marks the rows as deleted,
+// or DELETE FROM expression for tables without soft delete
+func (t *TableInfo) SoftDelete() xsql.Builder {
+	if t.SoftDeleteColumn == "" {
+		return t.DeleteFrom()
+	}
+	return t.Update().Set(t.quote(t.SoftDeleteColumn), xdb.Now())
 }
 
 // AllColumns returns list of all columns separated by comma
 func (t *TableInfo) AllColumns() string {
 	if t.allColumns == "" {
-		t.allColumns = strings.Join(t.Columns, ", ")
+		cols := t.Columns
+		if t.QuoteNames {
+			cols = make([]string, len(t.Columns))
+			for i, c := range t.Columns {
+				cols[i] = t.quote(c)
+			}
+		}
+		t.allColumns = strings.Join(cols, ", ")
 	}
 	return t.allColumns
 }
@@ -85,9 +211,9 @@ func (t *TableInfo) AliasedColumns(prefix string, nulls map[string]bool) string
 			prefixed[i] = "NULL"
 		} else {
 			if prefix == "" {
-				prefixed[i] = c
+				prefixed[i] = t.quote(c)
 			} else {
-				prefixed[i] = prefix + "." + c
+				prefixed[i] = prefix + "." + t.quote(c)
 			}
 		}
 	}
@@ -104,6 +230,12 @@ type Table struct {
 
 	PrimaryKey *Column
 
+	// PartitionKey is the column of the single-column range partition key,
+	// for Postgres partitioned tables
+	PartitionKey string `json:",omitempty" yaml:",omitempty"`
+	// Comment provides the table description
+	Comment string `json:",omitempty" yaml:",omitempty"`
+
 	// FKMap provides the cache of the FK
 	FKMap map[string]*ForeignKey `json:"-" yaml:"-"`
 
@@ -130,6 +262,17 @@ type Column struct {
 	Nullable  bool
 	MaxLength uint32
 	Position  uint32
+	// Default provides the default value expression
+	Default string `json:",omitempty" yaml:",omitempty"`
+	// Comment provides the column description
+	Comment string `json:",omitempty" yaml:",omitempty"`
+	// Enum provides the values of enum type
+	Enum []string `json:",omitempty" yaml:",omitempty"`
+	// Identity is true for identity and auto-increment columns,
+	// the values are generated by the database on insert
+	Identity bool `json:",omitempty" yaml:",omitempty"`
+	// Sequence provides the name of the sequence that generates the values, in Postgres
+	Sequence string `json:",omitempty" yaml:",omitempty"`
 
 	// GoName string
 	// GoType string
@@ -147,6 +290,9 @@ func (c *Column) StructString() string {
 	if c.MaxLength > 0 {
 		ml = fmt.Sprintf(", MaxLength: %d ", c.MaxLength)
 	}
+	if c.Identity {
+		ml += ", Identity: true "
+	}
 	return fmt.Sprintf(`{ Name: "%s", Position: %d, Type: "%s", UdtType: "%s", Nullable: %t %s}`,
 		c.Name, c.Position, c.Type, c.UdtType, c.Nullable, ml,
 	)
@@ -281,3 +427,12 @@ type Provider interface {
 	// if not provided, then all items are returned
 	ListForeignKeys(ctx context.Context, schemaName string, tableNames []string) (ForeignKeys, error)
 }
+
+// TableStreamer is implemented by the providers that read tables one at a time,
+// so the callers don't keep the whole catalog in memory
+type TableStreamer interface {
+	// StreamTables calls fn for each table in the order of schema and name.
+	// schemaName and tableNames are optional parameters to filter,
+	// if not provided, then all items are returned
+	StreamTables(ctx context.Context, schemaName string, tableNames []string, fn func(*Table) error) error
+}
@@ -64,6 +64,8 @@ func TestModel(t *testing.T) {
 	assert.True(t, c2.IsPrimary())
 	assert.Equal(t, `db:"id,int8,max:32,index,primary,fk:smb.t2.c2" json:",omitempty"`, c2.Tag())
 	assert.Equal(t, `{ Name: "id", Position: 0, Type: "bigint", UdtType: "int8", Nullable: false , MaxLength: 32 }`, c2.StructString())
+	c2.Identity = true
+	assert.Equal(t, `{ Name: "id", Position: 0, Type: "bigint", UdtType: "int8", Nullable: false , MaxLength: 32 , Identity: true }`, c2.StructString())
 
 	cols := Columns{c, c2}
 	assert.Equal(t, []string{"org_id", "id"}, cols.Names())
@@ -188,6 +190,8 @@ func TestTableInfo(t *testing.T) {
 		Dialect:    xsql.Postgres,
 	}
 	assert.Equal(t, "id, meta, name", ti.AllColumns())
+	assert.Equal(t, []string{"id"}, ti.KeyColumns())
+	assert.Nil(t, (&TableInfo{}).KeyColumns())
 	assert.Equal(t, "a.id, NULL, a.name", ti.AliasedColumns("a", nulls))
 	assert.Equal(t, "id, NULL, name", ti.AliasedColumns("", nulls))
 
@@ -199,3 +203,107 @@ func TestTableInfo(t *testing.T) {
 	assert.Equal(t, "DELETE FROM public.org \nWHERE id = $1", ti.DeleteFrom().Where("id = ?", nil).String())
 	assert.Equal(t, "INSERT INTO public.org \n( id \n) VALUES ( $1 \n)", ti.InsertInto().Set("id", nil).String())
 }
+
+func TestTableInfoSoftDelete(t *testing.T) {
+	ti := TableInfo{
+		Schema:           "public",
+		Name:             "org",
+		SchemaName:       "public.org",
+		Columns:          []string{"id", "name", "deleted_at"},
+		PrimaryKey:       "id",
+		SoftDeleteColumn: "deleted_at",
+		Dialect:          xsql.Postgres,
+	}
+	assert.Equal(t, "SELECT id, name, deleted_at \nFROM public.org \nWHERE deleted_at IS NULL", ti.Select().String())
+	assert.Equal(t, "SELECT id \nFROM public.org \nWHERE deleted_at IS NULL AND name = $1", ti.Select("id").Where("name = ?", "a").String())
+	assert.Equal(t, "SELECT o.id, o.name, o.deleted_at \nFROM public.org o \nWHERE o.deleted_at IS NULL", ti.SelectAliased("o", nil).String())
+	assert.Equal(t, "SELECT id \nFROM public.org", ti.SelectWithDeleted("id").String())
+
+	q := ti.SoftDelete().Where("id = ?", 1)
+	assert.Equal(t, "UPDATE public.org \nSET deleted_at=$1 \nWHERE id = $2", q.String())
+	assert.IsType(t, xdb.Time{}, q.Args()[0])
+
+	ti.SoftDeleteColumn = ""
+	assert.Equal(t, "DELETE FROM public.org \nWHERE id = $1", ti.SoftDelete().Where("id = ?", 1).String())
+}
+
+func TestTableInfoQuoteNames(t *testing.T) {
+	ti := TableInfo{
+		Schema:           "public",
+		Name:             "user",
+		SchemaName:       "public.user",
+		Columns:          []string{"id", "order", "deleted_at"},
+		PrimaryKey:       "id",
+		SoftDeleteColumn: "deleted_at",
+		QuoteNames:       true,
+		Dialect:          xsql.Postgres,
+	}
+	assert.Equal(t, `"id", "order", "deleted_at"`, ti.AllColumns())
+	assert.Equal(t, `o."id", NULL, o."deleted_at"`, ti.AliasedColumns("o", map[string]bool{"order": true}))
+	assert.Equal(t, "SELECT \"id\", \"order\", \"deleted_at\" \nFROM \"public\".\"user\" \nWHERE \"deleted_at\" IS NULL", ti.Select().String())
+	assert.Equal(t, "SELECT o.\"id\", o.\"order\", o.\"deleted_at\" \nFROM \"public\".\"user\" o \nWHERE o.\"deleted_at\" IS NULL", ti.SelectAliased("o", nil).String())
+	assert.Equal(t, "DELETE FROM \"public\".\"user\" \nWHERE id = $1", ti.DeleteFrom().Where("id = ?", 1).String())
+	assert.Equal(t, "UPDATE \"public\".\"user\" \nSET \"deleted_at\"=$1", ti.SoftDelete().String())
+
+	ti = TableInfo{
+		SchemaName: "dbo.user",
+		Columns:    []string{"id", "key"},
+		QuoteNames: true,
+		Dialect:    xsql.SQLServer,
+	}
+	assert.Equal(t, "SELECT [id], [key] \nFROM [dbo].[user]", ti.Select().String())
+}
+
+func TestTableInfoIndex(t *testing.T) {
+	ti := TableInfo{
+		Schema:     "dbo",
+		Name:       "org",
+		SchemaName: "dbo.org",
+		Columns:    []string{"id", "name"},
+		Indexes:    []string{"PK_org", "IX_org_name"},
+		Dialect:    xsql.SQLServer,
+	}
+	assert.True(t, ti.HasIndex("ix_org_name"))
+	assert.False(t, ti.HasIndex("IX_org_email"))
+
+	q, err := ti.FromIndex("IX_org_name")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id \nFROM dbo.org WITH (INDEX(IX_org_name)) \nWHERE name = ?",
+		q.Select("id").Where("name = ?", "a").String())
+
+	_, err = ti.FromIndex("IX_org_email")
+	assert.EqualError(t, err, `index "IX_org_email" does not exist in dbo.org`)
+}
+
+func TestEnumValues(t *testing.T) {
+	assert.Nil(t, enumValues(""))
+	assert.Equal(t, []string{"active", "disabled"}, enumValues(`["active","disabled"]`))
+	assert.Equal(t, []string{"a", "b,c", "it's"}, enumValues(`enum('a','b,c','it''s')`))
+	assert.Equal(t, []string{""}, enumValues(`enum('')`))
+
+	assert.Equal(t, []string{"active", "disabled"},
+		enumValues(`CHECK (((status)::text = ANY ((ARRAY['active'::character varying, 'disabled'::character varying])::text[])))`))
+	assert.Equal(t, []string{"disabled", "active"}, enumValues(`CHECK ([status]='disabled' OR [status]='active')`))
+	assert.Equal(t, []string{"a", "it's"}, enumValues(`CHECK (kind IN ('a', 'it''s', 'a'))`))
+	assert.Nil(t, enumValues(`CHECK (amount > 0)`))
+	assert.Nil(t, enumValues(`CHECK ((status)::text <> 'deleted'::text)`))
+	assert.Nil(t, enumValues(`CHECK (name LIKE 'a%')`))
+	assert.Nil(t, enumValues(`CHECK (status IS NOT NULL AND status = 'a')`))
+}
+
+func TestNameFilter(t *testing.T) {
+	where, args := nameFilter("s", "t", "", nil, postgresPlaceholder)
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+
+	where, args = nameFilter("s", "t", "Public", []string{"Org", "user"}, postgresPlaceholder)
+	assert.Equal(t, "\n\tAND LOWER(s) = $1\n\tAND LOWER(t) IN ($2, $3)", where)
+	assert.Equal(t, []any{"public", "org", "user"}, args)
+
+	where, args = nameFilter("s", "t", "", []string{"org"}, mssqlPlaceholder)
+	assert.Equal(t, "\n\tAND LOWER(t) IN (@p1)", where)
+	assert.Equal(t, []any{"org"}, args)
+
+	where, _ = nameFilter("s", "t", "dbo", nil, questionPlaceholder)
+	assert.Equal(t, "\n\tAND LOWER(s) = ?", where)
+}
@@ -0,0 +1,272 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/effective-security/x/slices"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// SnapshotVersion is the current version of the snapshot format.
+// Version 0 is a legacy format with JSON array of tables.
+const SnapshotVersion = 1
+
+// Snapshot provides an offline copy of database schema,
+// that can be used by tools and tests without database connection
+type Snapshot struct {
+	Version     int         `json:"version" yaml:"version"`
+	Provider    string      `json:"provider" yaml:"provider"`
+	Tables      Tables      `json:"tables" yaml:"tables"`
+	Views       Tables      `json:"views,omitempty" yaml:"views,omitempty"`
+	ForeignKeys ForeignKeys `json:"foreign_keys,omitempty" yaml:"foreign_keys,omitempty"`
+}
+
+// NewSnapshot returns a snapshot of tables, views and foreign keys from the provider.
+// schemaName is optional parameter to filter
+func NewSnapshot(ctx context.Context, p Provider, schemaName string) (*Snapshot, error) {
+	tables, err := p.ListTables(ctx, schemaName, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	views, err := p.ListViews(ctx, schemaName, nil)
+	if err != nil {
+		return nil, err
+	}
+	fks, err := p.ListForeignKeys(ctx, schemaName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the stable order keeps the snapshots comparable in source control
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].SchemaName < views[j].SchemaName
+	})
+	sort.Slice(fks, func(i, j int) bool {
+		return fks[i].ColumnSchemaName() < fks[j].ColumnSchemaName()
+	})
+
+	s := &Snapshot{
+		Version:     SnapshotVersion,
+		Provider:    p.Name(),
+		Tables:      tables,
+		Views:       views,
+		ForeignKeys: fks,
+	}
+	s.link()
+	return s, nil
+}
+
+// LoadSnapshot loads a snapshot from the file
+func LoadSnapshot(file string) (*Snapshot, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load snapshot")
+	}
+	s, err := ParseSnapshot(data)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load snapshot %s", file)
+	}
+	return s, nil
+}
+
+// ParseSnapshot parses a snapshot in JSON or YAML format,
+// the legacy format with JSON array of tables is supported as version 0
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	data = bytes.TrimSpace(data)
+
+	s := new(Snapshot)
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &s.Tables); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		var err error
+		if bytes.HasPrefix(data, []byte("{")) {
+			err = json.Unmarshal(data, s)
+		} else {
+			err = yaml.Unmarshal(data, s)
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if s.Version < 1 || s.Version > SnapshotVersion {
+			return nil, errors.Errorf("unsupported snapshot version: %d", s.Version)
+		}
+	}
+
+	s.link()
+	return s, nil
+}
+
+// Save writes the snapshot in JSON format
+func (s *Snapshot) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.WithStack(enc.Encode(s))
+}
+
+// SaveYAML writes the snapshot in YAML format
+func (s *Snapshot) SaveYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(s); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(enc.Close())
+}
+
+// link restores the fields that are not serialized:
+// FQN names, column indexes, primary keys and FK references
+func (s *Snapshot) link() {
+	fkeys := map[string]*ForeignKey{}
+	for _, k := range s.ForeignKeys {
+		k.SchemaName = fmt.Sprintf("%s.%s.%s", k.Schema, k.Table, k.Name)
+		fkeys[k.ColumnSchemaName()] = k
+	}
+
+	for _, list := range []Tables{s.Tables, s.Views} {
+		for _, t := range list {
+			t.SchemaName = fmt.Sprintf("%s.%s", t.Schema, t.Name)
+
+			columns := map[string]*Column{}
+			for _, c := range t.Columns {
+				c.SchemaName = fmt.Sprintf("%s.%s", t.SchemaName, c.Name)
+				c.Indexes = nil
+				c.Ref = fkeys[c.SchemaName]
+				columns[c.Name] = c
+			}
+
+			for _, idx := range t.Indexes {
+				idx.SchemaName = fmt.Sprintf("%s.%s", t.SchemaName, idx.Name)
+				for _, cn := range idx.ColumnNames {
+					if c := columns[cn]; c != nil {
+						c.Indexes = append(c.Indexes, idx)
+					}
+				}
+			}
+
+			if t.PrimaryKey != nil {
+				if c := columns[t.PrimaryKey.Name]; c != nil {
+					t.PrimaryKey = c
+				}
+			}
+		}
+	}
+}
+
+// SnapshotProvider implements read-only Provider over a Snapshot
+type SnapshotProvider struct {
+	snapshot *Snapshot
+	tables   map[string]*Table
+}
+
+// NewSnapshotProvider returns Provider for the snapshot
+func NewSnapshotProvider(s *Snapshot) Provider {
+	p := &SnapshotProvider{
+		snapshot: s,
+		tables:   map[string]*Table{},
+	}
+	for _, t := range s.Tables {
+		p.tables[strings.ToLower(t.SchemaName)] = t
+	}
+	return p
+}
+
+// Name returns provider name
+func (p *SnapshotProvider) Name() string {
+	return p.snapshot.Provider
+}
+
+// ListTables returns a list of tables in the snapshot.
+// schemaName and tableNames are optional parameters to filter,
+// if not provided, then all items are returned
+func (p *SnapshotProvider) ListTables(_ context.Context, schemaName string, tableNames []string, withDependencies bool) (Tables, error) {
+	found := map[string]*Table{}
+	for _, t := range filterTables(p.snapshot.Tables, schemaName, tableNames) {
+		found[t.SchemaName] = t
+		if withDependencies {
+			p.discover(t, found)
+		}
+	}
+
+	res := Tables{}
+	for _, t := range found {
+		res = append(res, t)
+	}
+	sort.Slice(res, func(i int, j int) bool {
+		return res[i].SchemaName < res[j].SchemaName
+	})
+	return res, nil
+}
+
+// StreamTables calls fn for each table in the snapshot.
+// schemaName and tableNames are optional parameters to filter,
+// if not provided, then all items are returned
+func (p *SnapshotProvider) StreamTables(_ context.Context, schemaName string, tableNames []string, fn func(*Table) error) error {
+	for _, t := range filterTables(p.snapshot.Tables, schemaName, tableNames) {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *SnapshotProvider) discover(t *Table, found map[string]*Table) {
+	for _, c := range t.Columns {
+		if c.Ref == nil {
+			continue
+		}
+		ref := p.tables[strings.ToLower(c.Ref.RefSchema+"."+c.Ref.RefTable)]
+		if ref == nil || found[ref.SchemaName] != nil {
+			continue
+		}
+		found[ref.SchemaName] = ref
+		p.discover(ref, found)
+	}
+}
+
+// ListViews returns a list of views in the snapshot.
+// schemaName and tableNames are optional parameters to filter,
+// if not provided, then all items are returned
+func (p *SnapshotProvider) ListViews(_ context.Context, schemaName string, tableNames []string) (Tables, error) {
+	return filterTables(p.snapshot.Views, schemaName, tableNames), nil
+}
+
+// ListForeignKeys returns a list of FK in the snapshot.
+// schemaName and tableNames are optional parameters to filter on source tables,
+// if not provided, then all items are returned
+func (p *SnapshotProvider) ListForeignKeys(_ context.Context, schemaName string, tableNames []string) (ForeignKeys, error) {
+	keys := ForeignKeys{}
+	for _, k := range p.snapshot.ForeignKeys {
+		if schemaName != "" && !strings.EqualFold(k.Schema, schemaName) {
+			continue
+		}
+		if len(tableNames) > 0 && !slices.ContainsStringEqualFold(tableNames, k.Table) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func filterTables(list Tables, schemaName string, tableNames []string) Tables {
+	res := Tables{}
+	for _, t := range list {
+		if schemaName != "" && !strings.EqualFold(t.Schema, schemaName) {
+			continue
+		}
+		if len(tableNames) > 0 && !slices.ContainsStringEqualFold(tableNames, t.Name) {
+			continue
+		}
+		res = append(res, t)
+	}
+	return res
+}
@@ -0,0 +1,119 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := LoadSnapshot("../internal/cli/schema/testdata/pg_columns.json")
+	require.NoError(t, err)
+	assert.Equal(t, 0, s.Version)
+	require.Len(t, s.Tables, 4)
+
+	org := s.Tables[0]
+	assert.Equal(t, "public.org", org.SchemaName)
+	require.NotNil(t, org.PrimaryKey)
+	assert.Same(t, org.Columns[0], org.PrimaryKey)
+	assert.True(t, org.PrimaryKey.IsPrimary())
+	assert.Equal(t, "public.org.email", org.Columns[2].SchemaName)
+	assert.True(t, org.Columns[2].IsIndex())
+
+	s.Version = SnapshotVersion
+	s.Provider = "postgres"
+	s.ForeignKeys = ForeignKeys{
+		{
+			Name:      "fk_orgmember_org",
+			Schema:    "public",
+			Table:     "orgmember",
+			Column:    "org_id",
+			RefSchema: "public",
+			RefTable:  "org",
+			RefColumn: "id",
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, s.Save(&buf))
+
+	s2, err := ParseSnapshot(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, SnapshotVersion, s2.Version)
+	require.Len(t, s2.Tables, 4)
+
+	member := s2.Tables[1]
+	assert.Equal(t, "public.orgmember", member.SchemaName)
+	require.NotNil(t, member.Columns[1].Ref)
+	assert.Equal(t, "public.org.id", member.Columns[1].Ref.RefColumnSchemaName())
+
+	buf.Reset()
+	require.NoError(t, s.SaveYAML(&buf))
+	assert.Contains(t, buf.String(), "version: 1\nprovider: postgres\ntables:\n")
+
+	s4, err := ParseSnapshot(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, s4.Tables, 4)
+	assert.Equal(t, s2.Tables, s4.Tables)
+	assert.Equal(t, s2.ForeignKeys, s4.ForeignKeys)
+
+	p := NewSnapshotProvider(s2)
+	assert.Equal(t, "postgres", p.Name())
+
+	tables, err := p.ListTables(ctx, "", nil, false)
+	require.NoError(t, err)
+	assert.Len(t, tables, 4)
+
+	tables, err = p.ListTables(ctx, "public", []string{"OrgMember"}, false)
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	assert.Equal(t, "orgmember", tables[0].Name)
+
+	tables, err = p.ListTables(ctx, "public", []string{"orgmember"}, true)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	assert.Equal(t, "public.org", tables[0].SchemaName)
+	assert.Equal(t, "public.orgmember", tables[1].SchemaName)
+
+	tables, err = p.ListTables(ctx, "dbo", nil, false)
+	require.NoError(t, err)
+	assert.Empty(t, tables)
+
+	views, err := p.ListViews(ctx, "", nil)
+	require.NoError(t, err)
+	assert.Empty(t, views)
+
+	fks, err := p.ListForeignKeys(ctx, "public", []string{"orgmember"})
+	require.NoError(t, err)
+	assert.Len(t, fks, 1)
+	fks, err = p.ListForeignKeys(ctx, "public", []string{"org"})
+	require.NoError(t, err)
+	assert.Empty(t, fks)
+
+	s3, err := NewSnapshot(ctx, p, "public")
+	require.NoError(t, err)
+	assert.Equal(t, SnapshotVersion, s3.Version)
+	assert.Equal(t, "postgres", s3.Provider)
+	assert.Len(t, s3.Tables, 4)
+	assert.Len(t, s3.ForeignKeys, 1)
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	_, err := LoadSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "failed to load snapshot")
+
+	_, err = ParseSnapshot([]byte(`{"version": 2}`))
+	assert.EqualError(t, err, "unsupported snapshot version: 2")
+
+	_, err = ParseSnapshot([]byte(`{"tables": []}`))
+	assert.EqualError(t, err, "unsupported snapshot version: 0")
+
+	_, err = ParseSnapshot([]byte(`{`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/effective-security/xdb"
+)
+
+type sqlite struct {
+	db xdb.DB
+}
+
+// SQLite has no schemas, the tables of the main database are reported in "main" schema
+const sqliteTableNamesWithSchema = `
+	SELECT
+		'main',
+		name,
+		'' AS partition_key,
+		'' AS comment
+	FROM
+		sqlite_master
+	WHERE
+		type = 'table' AND
+		name NOT LIKE 'sqlite_%'`
+
+func (p sqlite) QueryTables(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("'main'", "name", schema, tables, questionPlaceholder)
+	return p.db.QueryContext(ctx, sqliteTableNamesWithSchema+where+"\nORDER BY name", args...)
+}
+
+// the declared type VARCHAR(64) is reported as varchar with max length 64
+const sqliteQueryColumns = `
+	SELECT
+		name,
+		type,
+		lower(CASE WHEN instr(type, '(') > 0 THEN trim(substr(type, 1, instr(type, '(') - 1)) ELSE type END),
+		CASE WHEN "notnull" = 1 OR pk > 0 THEN 'NO' ELSE 'YES' END,
+		CASE WHEN instr(type, '(') > 0 THEN CAST(substr(type, instr(type, '(') + 1) AS INTEGER) ELSE NULL END,
+		cid + 1,
+		dflt_value,
+		NULL,
+		NULL,
+		-- INTEGER PRIMARY KEY is the alias of rowid, that is assigned on insert
+		CASE WHEN pk = 1 AND lower(type) = 'integer'
+			AND (SELECT count(*) FROM pragma_table_info(?1, ?2) WHERE pk > 0) = 1
+			THEN 'YES' ELSE 'NO' END,
+		NULL
+	FROM pragma_table_info(?1, ?2)
+`
+
+func (p sqlite) QueryColumns(ctx context.Context, schema, table string) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, sqliteQueryColumns, table, schema)
+}
+
+const sqliteQueryViews = `
+SELECT
+	'main',
+	m.name,
+	c.name,
+	c.type,
+	lower(CASE WHEN instr(c.type, '(') > 0 THEN trim(substr(c.type, 1, instr(c.type, '(') - 1)) ELSE c.type END),
+	CASE WHEN c."notnull" = 1 THEN 'NO' ELSE 'YES' END,
+	CASE WHEN instr(c.type, '(') > 0 THEN CAST(substr(c.type, instr(c.type, '(') + 1) AS INTEGER) ELSE NULL END,
+	c.cid + 1
+FROM sqlite_master m
+JOIN pragma_table_info(m.name) c
+WHERE m.type = 'view'`
+
+func (p sqlite) QueryViews(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("'main'", "m.name", schema, tables, questionPlaceholder)
+	return p.db.QueryContext(ctx, sqliteQueryViews+where+"\nORDER BY m.name", args...)
+}
+
+// INTEGER PRIMARY KEY is an alias of rowid and has no index,
+// it's reported as the primary key index named pk_<table>
+const sqliteQueryIndexes = `
+SELECT
+	il.name,
+	il.origin = 'pk',
+	il."unique" = 1,
+	(SELECT group_concat(name, ',') FROM (
+		SELECT name FROM pragma_index_info(il.name, ?2) ORDER BY seqno
+	))
+FROM pragma_index_list(?1, ?2) il
+UNION ALL
+SELECT
+	'pk_' || ?1,
+	1,
+	1,
+	name
+FROM pragma_table_info(?1, ?2)
+WHERE pk = 1 AND NOT EXISTS (
+	SELECT 1 FROM pragma_index_list(?1, ?2) WHERE origin = 'pk'
+)
+ORDER BY 1;
+`
+
+func (p sqlite) QueryIndexes(ctx context.Context, schema, table string) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, sqliteQueryIndexes, table, schema)
+}
+
+// foreign keys are not named in SQLite, fk_<table>_<column> name is reported
+const sqliteQueryForeignKeys = `
+SELECT
+	'fk_' || m.name || '_' || fk."from",
+	'main',
+	m.name,
+	fk."from",
+	'main',
+	fk."table",
+	COALESCE(fk."to", (SELECT name FROM pragma_table_info(fk."table") WHERE pk = 1))
+FROM sqlite_master m
+JOIN pragma_foreign_key_list(m.name) fk
+WHERE m.type = 'table'`
+
+func (p sqlite) QueryForeignKeys(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("'main'", "m.name", schema, tables, questionPlaceholder)
+	return p.db.QueryContext(ctx, sqliteQueryForeignKeys+where, args...)
+}
@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	// register sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestListSQLite(t *testing.T) {
+	ctx := context.Background()
+	prov, err := xdb.NewProvider("sqlite3://"+t.TempDir(), "test.db", nil, nil)
+	require.NoError(t, err)
+	defer prov.Close()
+	assert.Equal(t, "sqlite3", prov.Name())
+
+	for _, ddl := range []string{
+		`CREATE TABLE org (id INTEGER PRIMARY KEY, name VARCHAR(64) NOT NULL, created_at DATETIME)`,
+		`CREATE TABLE orgmember (
+			org_id INTEGER NOT NULL REFERENCES org,
+			user_id BIGINT NOT NULL,
+			role TEXT DEFAULT 'user',
+			PRIMARY KEY (org_id, user_id)
+		)`,
+		`CREATE UNIQUE INDEX idx_org_name ON org (name)`,
+		`CREATE VIEW orgview AS SELECT id, name FROM org`,
+	} {
+		_, err = prov.ExecContext(ctx, ddl)
+		require.NoError(t, err)
+	}
+
+	r := NewProvider(prov, prov.Name())
+	tables, err := r.ListTables(ctx, "main", []string{"orgmember"}, true)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+
+	org := tables[0]
+	assert.Equal(t, "main.org", org.SchemaName)
+	require.Len(t, org.Columns, 3)
+	assert.Equal(t, "INTEGER", org.Columns[0].Type)
+	assert.Equal(t, "integer", org.Columns[0].UdtType)
+	assert.False(t, org.Columns[0].Nullable)
+	assert.True(t, org.Columns[0].Identity)
+	assert.False(t, org.Columns[1].Identity)
+	assert.Equal(t, "varchar", org.Columns[1].UdtType)
+	assert.Equal(t, uint32(64), org.Columns[1].MaxLength)
+	assert.False(t, org.Columns[1].Nullable)
+	assert.True(t, org.Columns[2].Nullable)
+
+	member := tables[1]
+	assert.Equal(t, "main.orgmember", member.SchemaName)
+	require.Len(t, member.Indexes, 1)
+	assert.True(t, member.Indexes[0].IsPrimary)
+	assert.Equal(t, []string{"org_id", "user_id"}, member.Indexes[0].ColumnNames)
+	assert.Equal(t, "'user'", member.Columns[2].Default)
+	// INTEGER column of composite primary key is not assigned on insert
+	assert.False(t, member.Columns[0].Identity)
+	require.NotNil(t, member.Columns[0].Ref)
+	assert.Equal(t, "main.org.id", member.Columns[0].Ref.RefColumnSchemaName())
+
+	tables, err = r.ListTables(ctx, "", []string{"org"}, false)
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	org = tables[0]
+	require.NotNil(t, org.PrimaryKey)
+	assert.Equal(t, "id", org.PrimaryKey.Name)
+	require.Len(t, org.Indexes, 2)
+	assert.Equal(t, "idx_org_name", org.Indexes[0].Name)
+	assert.True(t, org.Indexes[0].IsUnique)
+	assert.False(t, org.Indexes[0].IsPrimary)
+	assert.Equal(t, "pk_org", org.Indexes[1].Name)
+
+	fks, err := r.ListForeignKeys(ctx, "", nil)
+	require.NoError(t, err)
+	require.Len(t, fks, 1)
+	assert.Equal(t, ForeignKey{
+		Name:       "fk_orgmember_org_id",
+		Schema:     "main",
+		Table:      "orgmember",
+		Column:     "org_id",
+		RefSchema:  "main",
+		RefTable:   "org",
+		RefColumn:  "id",
+		SchemaName: "main.orgmember.fk_orgmember_org_id",
+	}, *fks[0])
+
+	views, err := r.ListViews(ctx, "", nil)
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	assert.Equal(t, "main.orgview", views[0].SchemaName)
+	require.Len(t, views[0].Columns, 2)
+	assert.Equal(t, "name", views[0].Columns[1].Name)
+	assert.Equal(t, "varchar", views[0].Columns[1].UdtType)
+}
+
+func TestStreamSQLite(t *testing.T) {
+	ctx := context.Background()
+	prov, err := xdb.NewProvider("sqlite3://"+t.TempDir(), "test.db", nil, nil)
+	require.NoError(t, err)
+	defer prov.Close()
+
+	for _, ddl := range []string{
+		`CREATE TABLE org (id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE orgmember (org_id INTEGER NOT NULL REFERENCES org, user_id BIGINT NOT NULL)`,
+		`CREATE TABLE audit (id INTEGER PRIMARY KEY, org_id INTEGER REFERENCES org)`,
+	} {
+		_, err = prov.ExecContext(ctx, ddl)
+		require.NoError(t, err)
+	}
+
+	r := NewProvider(prov, prov.Name())
+	var _ TableStreamer = r.(*SQLServerProvider)
+
+	var names []string
+	err = r.(TableStreamer).StreamTables(ctx, "", nil, func(tbl *Table) error {
+		names = append(names, tbl.SchemaName)
+		assert.NotEmpty(t, tbl.Columns)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.audit", "main.org", "main.orgmember"}, names)
+
+	err = r.(TableStreamer).StreamTables(ctx, "main", []string{"ORG"}, func(tbl *Table) error {
+		return errors.Errorf("stop at %s", tbl.Name)
+	})
+	assert.EqualError(t, err, "stop at org")
+
+	// the filter is case-insensitive
+	tables, err := r.ListTables(ctx, "MAIN", []string{"OrgMember", "Audit"}, false)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	assert.Equal(t, "main.audit", tables[0].SchemaName)
+	require.NotNil(t, tables[0].PrimaryKey)
+	assert.Equal(t, "id", tables[0].PrimaryKey.Name)
+
+	tables, err = r.ListTables(ctx, "public", nil, false)
+	require.NoError(t, err)
+	assert.Empty(t, tables)
+
+	fks, err := r.ListForeignKeys(ctx, "", []string{"audit"})
+	require.NoError(t, err)
+	require.Len(t, fks, 1)
+	assert.Equal(t, "fk_audit_org_id", fks[0].Name)
+}
@@ -15,7 +15,13 @@ type sqlserver struct {
 const mssqlTableNamesWithSchema = `
 	SELECT
 		schema_name(t.schema_id),
-		t.name
+		t.name,
+		'' AS partition_key,
+		COALESCE((
+			SELECT CAST(d.value AS NVARCHAR(4000))
+			FROM sys.extended_properties d
+			WHERE d.major_id = t.[object_id] AND d.minor_id = 0 AND d.class = 1 AND d.[name] = 'MS_Description'
+		), '') AS comment
 	FROM
 		sys.tables t
 	INNER JOIN
@@ -27,19 +33,35 @@ const mssqlTableNamesWithSchema = `
 	WHERE
 		t.is_ms_shipped = 0 AND
 		(ep.class_desc IS NULL OR (ep.class_desc <> 'OBJECT_OR_COLUMN' AND
-			ep.[name] <> 'microsoft_database_tools_support'))
-	ORDER BY
-		schema_name(t.schema_id),
-		t.name
-`
+			ep.[name] <> 'microsoft_database_tools_support'))`
+
+func mssqlPlaceholder(n int) string {
+	return fmt.Sprintf("@p%d", n)
+}
 
-func (p sqlserver) QueryTables(ctx context.Context) (*sql.Rows, error) {
-	return p.db.QueryContext(ctx, mssqlTableNamesWithSchema)
+func (p sqlserver) QueryTables(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("s.name", "t.name", schema, tables, mssqlPlaceholder)
+	return p.db.QueryContext(ctx, mssqlTableNamesWithSchema+where+"\nORDER BY s.name, t.name", args...)
 }
 
 func (p sqlserver) QueryColumns(ctx context.Context, schema, table string) (*sql.Rows, error) {
 	qry := fmt.Sprintf(`
-	SELECT COLUMN_NAME, DATA_TYPE, DATA_TYPE, IS_NULLABLE, CHARACTER_MAXIMUM_LENGTH, ORDINAL_POSITION FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA=N'%s' AND TABLE_NAME = N'%s'`,
+	SELECT c.COLUMN_NAME, c.DATA_TYPE, c.DATA_TYPE, c.IS_NULLABLE, c.CHARACTER_MAXIMUM_LENGTH, c.ORDINAL_POSITION,
+		c.COLUMN_DEFAULT, CAST(ep.value AS NVARCHAR(4000)),
+		(SELECT TOP 1 'CHECK ' + cc.definition
+			FROM sys.check_constraints cc
+			WHERE cc.parent_object_id = OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME))
+			AND cc.parent_column_id = COLUMNPROPERTY(cc.parent_object_id, c.COLUMN_NAME, 'ColumnId')),
+		CASE WHEN COLUMNPROPERTY(OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME)), c.COLUMN_NAME, 'IsIdentity') = 1
+			THEN 'YES' ELSE 'NO' END,
+		NULL
+	FROM INFORMATION_SCHEMA.COLUMNS c
+	LEFT JOIN sys.extended_properties ep
+		ON ep.major_id = OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME))
+		AND ep.minor_id = COLUMNPROPERTY(ep.major_id, c.COLUMN_NAME, 'ColumnId')
+		AND ep.class = 1
+		AND ep.name = 'MS_Description'
+	WHERE c.TABLE_SCHEMA=N'%s' AND c.TABLE_NAME = N'%s'`,
 		schema, table)
 
 	return p.db.QueryContext(ctx, qry)
@@ -47,11 +69,12 @@ func (p sqlserver) QueryColumns(ctx context.Context, schema, table string) (*sql
 
 const mssqlQueryViews = `
 SELECT TABLE_SCHEMA, TABLE_NAME, COLUMN_NAME, DATA_TYPE, DATA_TYPE, IS_NULLABLE, CHARACTER_MAXIMUM_LENGTH, ORDINAL_POSITION FROM INFORMATION_SCHEMA.COLUMNS s
-JOIN sys.views v ON v.name = s.TABLE_NAME;
-`
+JOIN sys.views v ON v.name = s.TABLE_NAME
+WHERE v.schema_id = SCHEMA_ID(s.TABLE_SCHEMA)`
 
-func (p sqlserver) QueryViews(ctx context.Context) (*sql.Rows, error) {
-	return p.db.QueryContext(ctx, mssqlQueryViews)
+func (p sqlserver) QueryViews(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("s.TABLE_SCHEMA", "s.TABLE_NAME", schema, tables, mssqlPlaceholder)
+	return p.db.QueryContext(ctx, mssqlQueryViews+where, args...)
 }
 
 const mssqlQueryIndexKeys = `
@@ -107,8 +130,9 @@ INNER JOIN sys.schemas sch2
     ON tab2.schema_id = sch2.schema_id
 INNER JOIN sys.columns col2
     ON col2.column_id = referenced_column_id AND col2.object_id = tab2.object_id
-`
+WHERE tab1.is_ms_shipped = 0`
 
-func (p sqlserver) QueryForeignKeys(ctx context.Context) (*sql.Rows, error) {
-	return p.db.QueryContext(ctx, mssqlQueryForeignKeys)
+func (p sqlserver) QueryForeignKeys(ctx context.Context, schema string, tables []string) (*sql.Rows, error) {
+	where, args := nameFilter("sch.name", "tab1.name", schema, tables, mssqlPlaceholder)
+	return p.db.QueryContext(ctx, mssqlQueryForeignKeys+where, args...)
 }
@@ -0,0 +1,82 @@
+package xdb
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+type sessionSettingsKey struct{}
+
+// WithSessionSettings returns a context with the session settings,
+// that are applied by BeginTx at the transaction start:
+// SET LOCAL via set_config for Postgres, or sp_set_session_context for SQL Server.
+// The settings are scoped to the transaction, and can be used by row-level security policies,
+// for example to enforce tenant isolation with current_setting('app.tenant_id').
+//
+//	ctx = xdb.WithSessionSettings(ctx, map[string]string{"app.tenant_id": tenantID})
+//	err := p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {...})
+func WithSessionSettings(ctx context.Context, settings map[string]string) context.Context {
+	return context.WithValue(ctx, sessionSettingsKey{}, settings)
+}
+
+// SessionSettingsFromContext returns the session settings of the context
+func SessionSettingsFromContext(ctx context.Context) map[string]string {
+	settings, _ := ctx.Value(sessionSettingsKey{}).(map[string]string)
+	return settings
+}
+
+// sessionStatement is a statement that applies a session setting
+type sessionStatement struct {
+	Query string
+	Args  []any
+}
+
+// sessionStatements returns the statements to apply the settings, sorted by name
+func sessionStatements(provider string, settings map[string]string) ([]sessionStatement, error) {
+	if len(settings) == 0 {
+		return nil, nil
+	}
+
+	var query string
+	switch provider {
+	case "postgres":
+		// set_config with is_local=true is equivalent to SET LOCAL, and accepts parameters
+		query = "SELECT set_config($1, $2, true)"
+	case "sqlserver":
+		// the values are cleared when the connection is returned to the pool
+		query = "EXEC sp_set_session_context @key = @p1, @value = @p2"
+	default:
+		return nil, errors.Errorf("session settings are not supported by %s", provider)
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		if name == "" {
+			return nil, errors.New("session setting name is empty")
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := make([]sessionStatement, 0, len(names))
+	for _, name := range names {
+		list = append(list, sessionStatement{Query: query, Args: []any{name, settings[name]}})
+	}
+	return list, nil
+}
+
+// applySessionSettings applies the session settings from context to the transaction
+func (p *SQLProvider) applySessionSettings(ctx context.Context) error {
+	list, err := sessionStatements(p.name, SessionSettingsFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	for _, s := range list {
+		if _, err = p.tx.ExecContext(ctx, s.Query, s.Args...); err != nil {
+			return errors.WithMessagef(err, "failed to apply session setting %s", s.Args[0])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionSettings(t *testing.T) {
+	p := openSQLite(t)
+
+	ctx := context.Background()
+	assert.Nil(t, xdb.SessionSettingsFromContext(ctx))
+
+	tx, err := p.BeginTx(xdb.WithSessionSettings(ctx, nil), nil)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	settings := map[string]string{"app.tenant_id": "1"}
+	ctx = xdb.WithSessionSettings(ctx, settings)
+	assert.Equal(t, settings, xdb.SessionSettingsFromContext(ctx))
+
+	_, err = p.BeginTx(ctx, nil)
+	assert.EqualError(t, err, "session settings are not supported by sqlite3")
+
+	// the failed transaction is rolled back
+	tx, err = p.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+}
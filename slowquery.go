@@ -0,0 +1,85 @@
+package xdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/effective-security/xlog"
+)
+
+// MaxSlowQueryLength limits the length of SQL logged for slow statements
+const MaxSlowQueryLength = 1024
+
+// ArgsRedactor returns the statement arguments to log
+type ArgsRedactor func(args []any) []any
+
+// LogArgs logs the arguments as is
+func LogArgs(args []any) []any {
+	return args
+}
+
+// RedactArgs replaces the arguments with their types,
+// to log the shape of the statement without the values
+func RedactArgs(args []any) []any {
+	res := make([]any, len(args))
+	for i, a := range args {
+		if a == nil {
+			res[i] = "NULL"
+		} else {
+			res[i] = fmt.Sprintf("<%T>", a)
+		}
+	}
+	return res
+}
+
+// WithSlowQueryThreshold enables logging and EventSlowQuery for statements
+// that took longer than the threshold, including the statements built by xsql.
+// The log contains the statement name, the duration and SQL truncated to MaxSlowQueryLength.
+// Zero disables the logging and the event.
+func (p *SQLProvider) WithSlowQueryThreshold(threshold time.Duration) *SQLProvider {
+	p.slowQuery = threshold
+	return p
+}
+
+// WithSlowQueryArgs enables logging of the arguments of slow statements,
+// use RedactArgs to hide the values, or LogArgs to log them as is.
+// Nil disables the logging of arguments.
+func (p *SQLProvider) WithSlowQueryArgs(redactor ArgsRedactor) *SQLProvider {
+	p.slowQueryArgs = redactor
+	return p
+}
+
+// slowQueryDone logs the slow statement, and publishes EventSlowQuery
+func (p *SQLProvider) slowQueryDone(ctx context.Context, query string, args []any, d time.Duration) {
+	name := StatementName(ctx)
+
+	kv := []any{
+		"reason", "slow_query",
+		"provider", p.name,
+		"name", name,
+		"duration", d.String(),
+		"sql", truncateSQL(query, MaxSlowQueryLength),
+	}
+	if p.pool != "" {
+		kv = append(kv, "pool", p.pool)
+	}
+	if p.slowQueryArgs != nil && len(args) > 0 {
+		kv = append(kv, "args", p.slowQueryArgs(args))
+	}
+	logger.KV(xlog.WARNING, kv...)
+
+	e := p.event(EventSlowQuery)
+	e.Name = name
+	e.SQL = query
+	e.Duration = d
+	publishEvent(e)
+}
+
+// truncateSQL returns the query limited to max bytes
+func truncateSQL(query string, max int) string {
+	if len(query) <= max {
+		return query
+	}
+	return query[:max] + "..."
+}
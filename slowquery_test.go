@@ -0,0 +1,68 @@
+package xdb_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&buf))
+	defer xlog.SetFormatter(xlog.NewDefaultFormatter(os.Stderr))
+
+	p := openSQLite(t).WithSlowQueryThreshold(time.Nanosecond)
+	_, err := xsql.NoDialect.New("CREATE TABLE t (id INTEGER, name TEXT)").SetName("slow.create").ExecAndClose(ctx, p)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `reason="slow_query"`)
+	assert.Contains(t, buf.String(), `name="slow.create"`)
+	assert.Contains(t, buf.String(), `sql="CREATE TABLE t (id INTEGER, name TEXT)"`)
+
+	// arguments are not logged by default
+	buf.Reset()
+	_, err = p.ExecContext(ctx, "INSERT INTO t (id, name) VALUES (?, ?)", 1, "secret")
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "secret")
+	assert.NotContains(t, buf.String(), "args=")
+
+	buf.Reset()
+	p.WithSlowQueryArgs(xdb.RedactArgs)
+	_, err = p.ExecContext(ctx, "INSERT INTO t (id, name) VALUES (?, ?)", 2, "secret")
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "secret")
+	assert.Contains(t, buf.String(), "<string>")
+
+	buf.Reset()
+	p.WithSlowQueryArgs(xdb.LogArgs)
+	var name string
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT name FROM t WHERE id = ?", 2).Scan(&name))
+	assert.Contains(t, buf.String(), "args=")
+
+	// SQL is truncated
+	buf.Reset()
+	long := "SELECT id FROM t WHERE name <> '" + strings.Repeat("x", xdb.MaxSlowQueryLength) + "'"
+	rows, err := p.QueryContext(ctx, long)
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	assert.Contains(t, buf.String(), "...")
+	assert.NotContains(t, buf.String(), "x'")
+
+	// disabled
+	buf.Reset()
+	p.WithSlowQueryThreshold(0)
+	_, err = p.ExecContext(ctx, "DELETE FROM t")
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "slow_query")
+
+	assert.Equal(t, []any{"NULL", "<int>"}, xdb.RedactArgs([]any{nil, 1}))
+}
@@ -0,0 +1,71 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+type snapshotKey struct{}
+
+// SnapshotFromContext returns the snapshot transaction started by WithSnapshot,
+// or nil if the context does not have one
+func SnapshotFromContext(ctx context.Context) Provider {
+	if p, ok := ctx.Value(snapshotKey{}).(Provider); ok {
+		return p
+	}
+	return nil
+}
+
+// snapshotIsolation returns isolation level that provides a consistent view
+// of the data for the lifetime of a transaction
+func snapshotIsolation(provider string) sql.IsolationLevel {
+	switch provider {
+	case "postgres":
+		return sql.LevelRepeatableRead
+	case "sqlserver":
+		return sql.LevelSnapshot
+	default:
+		return sql.LevelDefault
+	}
+}
+
+// WithSnapshot begins a read-only REPEATABLE READ (Postgres) or SNAPSHOT (SQL Server)
+// transaction for multi-query workflows that require a consistent view of the data,
+// such as pagination or exports.
+// The returned context carries the transaction, and statements executed
+// by the provider with this context use the transaction when present.
+// The caller must call Commit or Rollback on the returned Provider.
+func (p *SQLProvider) WithSnapshot(ctx context.Context) (context.Context, Provider, error) {
+	if p.tx != nil {
+		return nil, nil, errors.New("transaction already started")
+	}
+	if SnapshotFromContext(ctx) != nil {
+		return nil, nil, errors.New("snapshot already started")
+	}
+
+	tx, err := p.BeginTx(ctx, &sql.TxOptions{
+		Isolation: snapshotIsolation(p.name),
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return context.WithValue(ctx, snapshotKey{}, tx), tx, nil
+}
+
+// contextDB returns the DB to execute a statement with:
+// transaction or snapshot transaction from the context, if started on the same connection pool,
+// or the provider's DB otherwise
+func (p *SQLProvider) contextDB(ctx context.Context) (DB, bool) {
+	if p.tx == nil {
+		if s := p.contextTx(ctx); s != nil {
+			return s.db, true
+		}
+		if s, ok := SnapshotFromContext(ctx).(*SQLProvider); ok && s.conn == p.conn {
+			return s.db, true
+		}
+	}
+	return p.db, false
+}
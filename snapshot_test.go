@@ -0,0 +1,48 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSnapshot(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, "INSERT INTO t (id) VALUES (1), (2)")
+	require.NoError(t, err)
+
+	assert.Nil(t, xdb.SnapshotFromContext(ctx))
+
+	sctx, snap, err := p.WithSnapshot(ctx)
+	require.NoError(t, err)
+	defer snap.Rollback()
+	assert.Equal(t, snap, xdb.SnapshotFromContext(sctx))
+
+	_, _, err = p.WithSnapshot(sctx)
+	assert.EqualError(t, err, "snapshot already started")
+	_, _, err = snap.WithSnapshot(ctx)
+	assert.EqualError(t, err, "transaction already started")
+
+	// the only connection is held by the snapshot,
+	// the statements must be executed in the snapshot transaction
+	qctx, cancel := context.WithTimeout(sctx, 5*time.Second)
+	defer cancel()
+
+	var count int
+	require.NoError(t, p.QueryRowContext(qctx, "SELECT COUNT(*) FROM t").Scan(&count))
+	assert.Equal(t, 2, count)
+
+	rows, err := p.QueryContext(qctx, "SELECT id FROM t")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	require.NoError(t, snap.Commit())
+}
@@ -0,0 +1,193 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"io"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+)
+
+// DefaultColumnChunkSize is the default size of a chunk read by ColumnReader
+var DefaultColumnChunkSize = 1024 * 1024
+
+// ExecuteStreamQuery runs a query and calls the handler for each model,
+// without loading the entire result in memory.
+// Use it instead of ExecuteListQuery to export tables with large payloads.
+// The iteration stops on the first error returned by the handler.
+// args can be a xsql.Builder or a list of arguments.
+// See ExecuteQueryIterator to pull the models instead.
+func ExecuteStreamQuery[T any, TPointer RowPointer[T]](ctx context.Context, sql DB, handler func(m TPointer) error, query string, args ...any) error {
+	it, err := ExecuteQueryIterator[T, TPointer](ctx, sql, query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = it.Close()
+	}()
+
+	for it.Next() {
+		if err = handler(it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// RowIterator iterates over the rows of a query and scans each row into a model,
+// see ExecuteQueryIterator
+type RowIterator[T any, TPointer RowPointer[T]] struct {
+	rows    *sql.Rows
+	scanner *rowScanner
+	value   TPointer
+	err     error
+}
+
+/*
+ExecuteQueryIterator runs a query and returns an iterator over the models,
+without loading the entire result in memory:
+
+	it, err := xdb.ExecuteQueryIterator[user](ctx, db, "SELECT id, email, name FROM users")
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		u := it.Value()
+		...
+	}
+	return it.Err()
+
+The iterator must be closed to release the connection.
+args can be a xsql.Builder or a list of arguments.
+*/
+func ExecuteQueryIterator[T any, TPointer RowPointer[T]](ctx context.Context, sql DB, query string, args ...any) (*RowIterator[T, TPointer], error) {
+	ctx, query, args = stmtQuery(ctx, sql, query, args)
+	rows, err := sql.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &RowIterator[T, TPointer]{rows: rows, scanner: &rowScanner{rows: rows}}, nil
+}
+
+// Next scans the next row, and returns false at the end of the result or on error
+func (it *RowIterator[T, TPointer]) Next() bool {
+	if it.err != nil || !it.rows.Next() {
+		return false
+	}
+	var m TPointer = new(T)
+	if err := it.scanner.scan(m); err != nil {
+		it.err = errors.WithStack(err)
+		return false
+	}
+	it.value = m
+	return true
+}
+
+// Value returns the model of the current row
+func (it *RowIterator[T, TPointer]) Value() TPointer {
+	return it.value
+}
+
+// Err returns the error occurred during the iteration
+func (it *RowIterator[T, TPointer]) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return errors.WithStack(it.rows.Err())
+}
+
+// Close closes the rows and releases the connection,
+// it's safe to call Close multiple times
+func (it *RowIterator[T, TPointer]) Close() error {
+	return errors.WithStack(it.rows.Close())
+}
+
+// ColumnReader implements io.Reader for a large text or binary column,
+// the value is read in chunks, so it's never loaded entirely in memory.
+type ColumnReader struct {
+	ctx       context.Context
+	db        DB
+	query     string
+	args      []any
+	chunkSize int
+	offset    int
+	buf       []byte
+	eof       bool
+}
+
+// NewColumnReader returns a reader for the column of a single row
+// of the table, selected by the where condition:
+//
+//	r := xdb.NewColumnReader(ctx, p, "public.doc", "payload", "id = ?", id)
+//	_, err := io.Copy(w, r)
+//
+// Note that for text columns the chunks are counted in characters.
+// Use a snapshot or transaction, if the row can be modified while reading.
+func NewColumnReader(ctx context.Context, p Provider, table, column, where string, args ...any) *ColumnReader {
+	r := &ColumnReader{
+		ctx:       ctx,
+		db:        p,
+		chunkSize: DefaultColumnChunkSize,
+	}
+
+	dialect := xsql.DialectFor(p.Name())
+	fn := "substr"
+	if dialect.Provider() == "sqlserver" {
+		fn = "SUBSTRING"
+	}
+
+	// the offset and size placeholders are replaced on each read
+	q := dialect.From(table).
+		Select(fn+"("+column+", ?, ?)", 0, 0).
+		Where(where, args...)
+	r.query = q.String()
+	r.args = append([]any{}, q.Args()...)
+	q.Close()
+
+	return r
+}
+
+// WithChunkSize sets the size of a chunk to read
+func (r *ColumnReader) WithChunkSize(size int) *ColumnReader {
+	if size > 0 {
+		r.chunkSize = size
+	}
+	return r
+}
+
+// Read implements io.Reader
+func (r *ColumnReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+		if len(r.buf) == 0 {
+			r.eof = true
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *ColumnReader) next() error {
+	// SQL substring is 1-based
+	r.args[0] = r.offset + 1
+	r.args[1] = r.chunkSize
+
+	var chunk []byte
+	err := r.db.QueryRowContext(r.ctx, r.query, r.args...).Scan(&chunk)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.offset += r.chunkSize
+	r.buf = chunk
+	return nil
+}
@@ -0,0 +1,104 @@
+package xdb_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteStreamQuery(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, email_verified BOOLEAN, name TEXT)")
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, "INSERT INTO users VALUES (1, 'a@x', 1, 'A'), (2, 'b@x', 0, 'B'), (3, 'c@x', 0, 'C')")
+	require.NoError(t, err)
+
+	var names []string
+	err = xdb.ExecuteStreamQuery(ctx, p, func(m *user) error {
+		names = append(names, m.Name)
+		return nil
+	}, "SELECT id, email, email_verified, name FROM users ORDER BY id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A", "B", "C"}, names)
+
+	count := 0
+	err = xdb.ExecuteStreamQuery(ctx, p, func(m *user) error {
+		count++
+		return errors.New("stop")
+	}, "SELECT id, email, email_verified, name FROM users ORDER BY id")
+	assert.EqualError(t, err, "stop")
+	assert.Equal(t, 1, count)
+}
+
+func TestExecuteQueryIterator(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, email_verified BOOLEAN, name TEXT)")
+	require.NoError(t, err)
+	_, err = p.ExecContext(ctx, "INSERT INTO users VALUES (1, 'a@x', 1, 'A'), (2, 'b@x', 0, 'B'), (3, 'c@x', 0, 'C')")
+	require.NoError(t, err)
+
+	it, err := xdb.ExecuteQueryIterator[user](ctx, p, "SELECT id, email, email_verified, name FROM users WHERE id > ? ORDER BY id", 1)
+	require.NoError(t, err)
+
+	var names []string
+	for it.Next() {
+		names = append(names, it.Value().Name)
+	}
+	require.NoError(t, it.Err())
+	require.NoError(t, it.Close())
+	require.NoError(t, it.Close())
+	assert.Equal(t, []string{"B", "C"}, names)
+
+	// scan error
+	it, err = xdb.ExecuteQueryIterator[user](ctx, p, "SELECT id, email FROM users")
+	require.NoError(t, err)
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+	require.NoError(t, it.Close())
+
+	_, err = xdb.ExecuteQueryIterator[user](ctx, p, "SELECT * FROM missing")
+	assert.Error(t, err)
+}
+
+func TestColumnReader(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE doc (id INTEGER PRIMARY KEY, payload BLOB, body TEXT)")
+	require.NoError(t, err)
+
+	payload := bytes.Repeat([]byte("0123456789"), 250)
+	body := strings.Repeat("abc", 1000)
+	_, err = p.ExecContext(ctx, "INSERT INTO doc (id, payload, body) VALUES (1, ?, ?), (2, NULL, '')", payload, body)
+	require.NoError(t, err)
+
+	r := xdb.NewColumnReader(ctx, p, "doc", "payload", "id = ?", 1).WithChunkSize(1000)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, payload, data)
+
+	r = xdb.NewColumnReader(ctx, p, "doc", "body", "id = ?", 1).WithChunkSize(7)
+	data, err = io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(data))
+
+	for _, col := range []string{"payload", "body"} {
+		data, err = io.ReadAll(xdb.NewColumnReader(ctx, p, "doc", col, "id = ?", 2))
+		require.NoError(t, err)
+		assert.Empty(t, data)
+	}
+
+	_, err = io.ReadAll(xdb.NewColumnReader(ctx, p, "doc", "payload", "id = ?", 3))
+	assert.EqualError(t, err, "sql: no rows in result set")
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -17,7 +18,47 @@ var DefaultTimeFormat = "2006-01-02T15:04:05.999Z07:00"
 // However, JavaScript and AWS accept time milliseconds only, 3 digits, so we truncate to 3
 var DefaultTrucate = time.Millisecond
 
-// Time implements sql.Time functionality and always returns UTC
+// PreserveTimeZone specifies to keep the location of Time values
+// in Scan, Value, ParseTime, TruncateTime, Add and String,
+// for timestamptz round trips. By default the values are converted to UTC.
+var PreserveTimeZone = false
+
+// Time precisions supported by the databases,
+// see SQLProvider.WithTimePrecision
+const (
+	PrecisionMillisecond = time.Millisecond
+	PrecisionMicrosecond = time.Microsecond
+	// PrecisionDatetime2 is the precision of SQL Server datetime2(7) columns
+	PrecisionDatetime2  = 100 * time.Nanosecond
+	PrecisionNanosecond = time.Nanosecond
+)
+
+// timeFormat returns RFC3339 format with the number of fraction digits
+// matching the precision, zero or negative precision preserves nanoseconds
+func timeFormat(precision time.Duration) string {
+	if precision <= 0 {
+		precision = time.Nanosecond
+	}
+	digits := 0
+	for d := time.Second; d > precision && digits < 9; d /= 10 {
+		digits++
+	}
+	if digits == 0 {
+		return "2006-01-02T15:04:05Z07:00"
+	}
+	return "2006-01-02T15:04:05." + strings.Repeat("9", digits) + "Z07:00"
+}
+
+// inZone returns t in UTC, or in its location if PreserveTimeZone is set
+func inZone(t time.Time) time.Time {
+	if PreserveTimeZone {
+		return t
+	}
+	return t.UTC()
+}
+
+// Time implements sql.Time functionality and returns UTC,
+// unless PreserveTimeZone is set
 type Time time.Time
 
 // Scan implements the Scanner interface.
@@ -34,7 +75,7 @@ func (ns *Time) Scan(value any) error {
 	}
 	var zero Time
 	if v.Valid {
-		zero = Time(v.Time.UTC())
+		zero = Time(inZone(v.Time))
 	}
 	*ns = zero
 
@@ -46,16 +87,18 @@ func (ns Time) Value() (driver.Value, error) {
 	nst := time.Time(ns)
 	return sql.NullTime{
 		Valid: !nst.IsZero(),
-		Time:  nst.UTC(),
+		Time:  inZone(nst),
 	}.Value()
 }
 
-// Now returns Time in UTC
+// Now returns Time in UTC truncated to DefaultTrucate,
+// use SQLProvider.Now for the precision of the provider
 func Now() Time {
 	return Time(time.Now().Truncate(DefaultTrucate).UTC())
 }
 
-// UTC returns Time in UTC,
+// UTC returns Time in UTC truncated to DefaultTrucate,
+// use SQLProvider.UTC for the precision of the provider
 func UTC(t time.Time) Time {
 	return Time(t.Truncate(DefaultTrucate).UTC())
 }
@@ -73,12 +116,17 @@ func FromUnixMilli(tm int64) Time {
 	return Time(time.Unix(sec, msec*int64(time.Millisecond)).UTC())
 }
 
-// ParseTime returns Time from RFC3339 format
+// ParseTime returns Time from RFC3339 format truncated to DefaultTrucate,
+// use SQLProvider.ParseTime for the precision of the provider
 func ParseTime(val string) Time {
 	if val == "" {
 		return Time{}
 	}
+	return Time(inZone(parseTime(val).Truncate(DefaultTrucate)))
+}
 
+// parseTime returns time from RFC3339 format without truncation
+func parseTime(val string) time.Time {
 	var t time.Time
 	switch len(val) {
 	case len(DefaultTimeFormat):
@@ -92,7 +140,28 @@ func ParseTime(val string) Time {
 	default:
 		t, _ = time.Parse(time.RFC3339Nano, val)
 	}
-	return Time(t.Truncate(DefaultTrucate).UTC())
+	return t
+}
+
+// TruncateTime returns Time in UTC truncated to the precision,
+// zero or negative precision preserves nanoseconds.
+// The monotonic clock reading is always stripped.
+func TruncateTime(t time.Time, precision time.Duration) Time {
+	if precision <= 0 {
+		return Time(inZone(StripMonotonic(t)))
+	}
+	return Time(inZone(t.Truncate(precision)))
+}
+
+// StripMonotonic returns t without the monotonic clock reading,
+// so the values compare equal with == after a round trip to DB
+func StripMonotonic(t time.Time) time.Time {
+	return t.Round(0)
+}
+
+// StripMonotonic returns Time without the monotonic clock reading
+func (ns Time) StripMonotonic() Time {
+	return Time(StripMonotonic(time.Time(ns)))
 }
 
 // UnixMilli returns t as a Unix time, the number of milliseconds elapsed since January 1, 1970 UTC.
@@ -103,7 +172,13 @@ func (ns Time) UnixMilli() int64 {
 // Add returns Time in UTC after this thime,
 // with Second presicions
 func (ns Time) Add(after time.Duration) Time {
-	return Time(time.Time(ns).Add(after).Truncate(DefaultTrucate).UTC())
+	return Time(inZone(time.Time(ns).Add(after).Truncate(DefaultTrucate)))
+}
+
+// In returns Time in the location, the location is retained by Scan and Value
+// only if PreserveTimeZone is set
+func (ns Time) In(loc *time.Location) Time {
+	return Time(time.Time(ns).In(loc))
 }
 
 // UTC returns t with the location set to UTC.
@@ -133,7 +208,7 @@ func (ns Time) Ptr() *time.Time {
 // String returns string in RFC3339 format,
 // if it's Zero time, an empty string is returned
 func (ns Time) String() string {
-	t := ns.UTC()
+	t := inZone(time.Time(ns))
 	if t.IsZero() {
 		return ""
 	}
@@ -143,7 +218,7 @@ func (ns Time) String() string {
 // MarshalJSON implements the json.Marshaler interface.
 // The time is a quoted string in RFC 3339 format, with sub-second precision added if present.
 func (ns Time) MarshalJSON() ([]byte, error) {
-	t := ns.UTC()
+	t := inZone(time.Time(ns))
 	if t.IsZero() {
 		return []byte(`""`), nil
 	}
@@ -109,3 +109,55 @@ func TestTimeTruncate(t *testing.T) {
 	now = nowBackFromString.Add(time.Second)
 	assert.Equal(t, now.UTC(), xdb.ParseTime(now.String()).UTC())
 }
+
+func TestTruncateTime(t *testing.T) {
+	d := time.Date(2019, 1, 2, 3, 4, 5, 1234567, time.UTC)
+
+	assert.Equal(t, d, xdb.TruncateTime(d, 0).UTC())
+	assert.Equal(t, time.Date(2019, 1, 2, 3, 4, 5, 1234000, time.UTC), xdb.TruncateTime(d, time.Microsecond).UTC())
+	assert.Equal(t, time.Date(2019, 1, 2, 3, 4, 5, 1000000, time.UTC), xdb.TruncateTime(d, time.Millisecond).UTC())
+
+	now := time.Now()
+	stripped := xdb.StripMonotonic(now)
+	assert.True(t, now.Equal(stripped))
+	assert.NotEqual(t, now.String(), stripped.String())
+	assert.Equal(t, stripped, time.Time(xdb.Time(now).StripMonotonic()))
+	assert.Equal(t, stripped.UTC(), time.Time(xdb.TruncateTime(now, 0)))
+}
+
+func TestPreserveTimeZone(t *testing.T) {
+	loc := time.FixedZone("PST", -8*3600)
+	d := time.Date(2019, 1, 2, 3, 4, 5, 0, loc)
+
+	var ts xdb.Time
+	assert.NoError(t, ts.Scan(d))
+	assert.Equal(t, time.UTC, time.Time(ts).Location())
+	assert.Equal(t, "2019-01-02T11:04:05Z", ts.String())
+	v, err := ts.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, v.(time.Time).Location())
+
+	xdb.PreserveTimeZone = true
+	defer func() { xdb.PreserveTimeZone = false }()
+
+	assert.NoError(t, ts.Scan(d))
+	assert.Equal(t, loc, time.Time(ts).Location())
+	assert.Equal(t, "2019-01-02T03:04:05-08:00", ts.String())
+	v, err = ts.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, d, v)
+
+	js, err := ts.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `"2019-01-02T03:04:05-08:00"`, string(js))
+
+	parsed := xdb.ParseTime("2019-01-02T03:04:05-08:00")
+	assert.Equal(t, "-08:00", time.Time(parsed).Format("Z07:00"))
+	assert.True(t, d.Equal(time.Time(parsed)))
+
+	assert.Equal(t, loc, time.Time(xdb.TruncateTime(d, time.Second)).Location())
+	assert.Equal(t, loc, time.Time(ts.Add(time.Hour)).Location())
+
+	inUTC := ts.In(time.UTC)
+	assert.Equal(t, "2019-01-02T11:04:05Z", inUTC.String())
+}
@@ -0,0 +1,136 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+type txKey struct{}
+
+/*
+ContextWithTx returns a context that carries the transaction,
+statements executed by the provider with this context use the transaction,
+if it was started on the same connection pool:
+
+	tx, err := p.BeginTx(ctx, nil)
+	...
+	ctx = xdb.ContextWithTx(ctx, tx)
+	// the repository executes the statements in tx
+	err = repo.CreateOrder(ctx, p, order)
+
+WithTx stores the transaction in the context passed to fn.
+*/
+func ContextWithTx(ctx context.Context, tx Provider) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction stored by ContextWithTx,
+// or nil if the context does not have one
+func TxFromContext(ctx context.Context) Provider {
+	if tx, ok := ctx.Value(txKey{}).(Provider); ok {
+		return tx
+	}
+	return nil
+}
+
+// contextTx returns the transaction from the context,
+// if started on the same connection pool
+func (p *SQLProvider) contextTx(ctx context.Context) *SQLProvider {
+	if tx, ok := TxFromContext(ctx).(*SQLProvider); ok && tx.tx != nil && tx.conn == p.conn {
+		return tx
+	}
+	return nil
+}
+
+// TxFunc is the function executed in a transaction by WithTx
+type TxFunc func(ctx context.Context, tx Provider) error
+
+/*
+WithTx begins a transaction, runs fn, and commits the transaction if fn succeeds:
+
+	err := p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		...
+		return nil
+	})
+
+The transaction is rolled back if fn returns an error or panics.
+The context passed to fn carries the transaction, see ContextWithTx.
+If the context already carries a transaction started on the same connection pool,
+fn is executed in that transaction, and the caller is responsible to commit it.
+If the retry policy is set by WithRetry, the whole transaction is retried
+on serialization failures and deadlocks, or when the connection is broken
+before the transaction is started, so fn must not have side effects
+outside of the transaction. The transaction is not retried after Commit
+was attempted, as it may be already committed by the server.
+*/
+func (p *SQLProvider) WithTx(ctx context.Context, opts *sql.TxOptions, fn TxFunc) error {
+	if tx := p.contextTx(ctx); tx != nil && p.tx == nil {
+		return fn(ctx, tx)
+	}
+	return runTx(ctx, p, p.retry, opts, fn)
+}
+
+// WithTx runs fn in the transaction on the primary, see SQLProvider.WithTx.
+// In read-only mode it fails fast with ReadOnlyError.
+func (p *ReplicaProvider) WithTx(ctx context.Context, opts *sql.TxOptions, fn TxFunc) error {
+	if tx := p.contextTx(ctx); tx != nil {
+		return fn(ctx, tx)
+	}
+	return runTx(ctx, p, p.retry, opts, fn)
+}
+
+// runTx runs fn in the transaction started by p, with the retry policy
+func runTx(ctx context.Context, p Provider, retry *RetryPolicy, opts *sql.TxOptions, fn TxFunc) error {
+	if p.Tx() != nil {
+		return errors.New("transaction already started")
+	}
+	err := retry.doTx(ctx, func(ctx context.Context) error {
+		return execTx(ctx, p, opts, fn)
+	})
+	var terr *txError
+	if errors.As(err, &terr) {
+		return terr.err
+	}
+	return err
+}
+
+// txError is the error of BeginTx or Commit,
+// which are classified separately by the retry of the transaction
+type txError struct {
+	err    error
+	commit bool
+}
+
+func (e *txError) Error() string {
+	return e.err.Error()
+}
+
+func (e *txError) Unwrap() error {
+	return e.err
+}
+
+func execTx(ctx context.Context, p Provider, opts *sql.TxOptions, fn TxFunc) (err error) {
+	tx, err := p.BeginTx(ctx, opts)
+	if err != nil {
+		return &txError{err: err}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = fn(ContextWithTx(ctx, tx), tx); err != nil {
+		return err
+	}
+	if cerr := tx.Commit(); cerr != nil {
+		return &txError{err: errors.WithStack(cerr), commit: true}
+	}
+	return nil
+}
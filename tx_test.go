@@ -0,0 +1,140 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+
+	count := func() int {
+		var n int
+		require.NoError(t, p.QueryRowContext(ctx, "SELECT COUNT(*) FROM t").Scan(&n))
+		return n
+	}
+
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO t (id, name) VALUES (?, ?)", 1, "one")
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count())
+
+	// rolled back on error
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO t (id, name) VALUES (?, ?)", 2, "two")
+		require.NoError(t, err)
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, 1, count())
+
+	// rolled back on panic
+	assert.PanicsWithValue(t, "oops", func() {
+		_ = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO t (id, name) VALUES (?, ?)", 3, "three")
+			require.NoError(t, err)
+			panic("oops")
+		})
+	})
+	assert.Equal(t, 1, count())
+
+	// nested transaction
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		return tx.WithTx(ctx, nil, func(context.Context, xdb.Provider) error { return nil })
+	})
+	assert.EqualError(t, err, "transaction already started")
+
+	// not retried without the policy
+	attempts := 0
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		attempts++
+		return &pq.Error{Code: "40001"}
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, attempts)
+
+	// retried on serialization failure
+	p.WithRetry(&xdb.RetryPolicy{MaxAttempts: 3})
+	attempts = 0
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		attempts++
+		_, err := tx.ExecContext(ctx, "INSERT INTO t (id, name) VALUES (?, ?)", 4, "four")
+		require.NoError(t, err)
+		if attempts < 2 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 2, count())
+}
+
+func TestContextWithTx(t *testing.T) {
+	ctx := context.Background()
+	p := openSQLite(t)
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+
+	assert.Nil(t, xdb.TxFromContext(ctx))
+
+	// the repository function takes only DB
+	insert := func(ctx context.Context, db xdb.DB, id int) error {
+		_, err := db.ExecContext(ctx, "INSERT INTO t (id, name) VALUES (?, ?)", id, "name")
+		return err
+	}
+	count := func(ctx context.Context, db xdb.DB) int {
+		var n int
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM t").Scan(&n))
+		return n
+	}
+
+	tx, err := p.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	tctx := xdb.ContextWithTx(ctx, tx)
+	assert.Equal(t, tx, xdb.TxFromContext(tctx))
+
+	require.NoError(t, insert(tctx, p, 1))
+	assert.Equal(t, 1, count(tctx, p))
+	require.NoError(t, tx.Rollback())
+
+	// the connection is released by the rollback
+	assert.Equal(t, 0, count(ctx, p))
+
+	// WithTx stores the transaction in the context
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		assert.Equal(t, tx, xdb.TxFromContext(ctx))
+		if err := insert(ctx, p, 2); err != nil {
+			return err
+		}
+		// joins the surrounding transaction
+		return p.WithTx(ctx, nil, func(ctx context.Context, inner xdb.Provider) error {
+			assert.Equal(t, tx, inner)
+			return insert(ctx, p, 3)
+		})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count(ctx, p))
+
+	err = p.WithTx(ctx, nil, func(ctx context.Context, tx xdb.Provider) error {
+		if err := insert(ctx, p, 4); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, 2, count(ctx, p))
+}
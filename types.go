@@ -234,49 +234,17 @@ func (ns NULLString) Value() (driver.Value, error) {
 	return string(ns), nil
 }
 
-// UUID de/encodes the string a SQL string.
-type UUID string
-
-// String returns string
-func (ns UUID) String() string {
-	return string(ns)
-}
-
-// Scan implements the Scanner interface.
-func (ns *UUID) Scan(value any) error {
-	if value == nil {
-		*ns = ""
+// DecodeJSON decodes JSON value into v,
+// empty value and JSON null are ignored
+func DecodeJSON(val string, v any) error {
+	if val == "" || val == "null" {
 		return nil
 	}
-
-	var s string
-	var err error
-	switch vid := value.(type) {
-	case []byte:
-		if len(vid) != 16 {
-			return errors.WithMessagef(err, "failed to parse UUID: %v", vid)
-		}
-		s = fmt.Sprintf("%02X%02X%02X%02X-%02X%02X-%02X%02X-%02X%02X-%02X%02X%02X%02X%02X%02X",
-			vid[3], vid[2], vid[1], vid[0], vid[5], vid[4], vid[7], vid[6], vid[8], vid[9], vid[10], vid[11], vid[12], vid[13], vid[14], vid[15])
-	case string:
-		s = vid
-	default:
-		return errors.Errorf("unsupported scan type: %T", value)
-	}
-
-	*ns = UUID(s)
-	return nil
-}
-
-// Value implements the driver Valuer interface.
-func (ns UUID) Value() (driver.Value, error) {
-	if ns == "" {
-		return nil, nil
-	}
-	return string(ns), nil
+	return errors.WithStack(json.Unmarshal([]byte(val), v))
 }
 
-// Int64 represents SQL int64 NULL
+// Int64 represents SQL int64 NULL,
+// zero value is stored as NULL, use Nullable[int64] to store 0
 type Int64 int64
 
 // MarshalJSON implements json.Marshaler interface
@@ -346,7 +314,8 @@ func (v Int64) Value() (driver.Value, error) {
 	return int64(v), nil
 }
 
-// Int32 represents SQL int NULL
+// Int32 represents SQL int NULL,
+// zero value is stored as NULL, use Nullable[int32] to store 0
 type Int32 int32
 
 // MarshalJSON implements json.Marshaler interface
@@ -420,7 +389,8 @@ func (v Int32) Value() (driver.Value, error) {
 	return int64(v), nil
 }
 
-// Float represents SQL float64 NULL
+// Float represents SQL float64 NULL,
+// zero value is stored as NULL, use Nullable[float64] to store 0
 type Float float64
 
 // MarshalJSON implements json.Marshaler interface
@@ -500,7 +470,8 @@ func (v Float) Value() (driver.Value, error) {
 	return float64(v), nil
 }
 
-// Bool represents SQL bool NULL
+// Bool represents SQL bool NULL,
+// zero value is stored as NULL, use Nullable[bool] to store false
 type Bool bool
 
 // MarshalJSON implements json.Marshaler interface
@@ -597,3 +597,14 @@ func TestCursor(t *testing.T) {
 	assert.Equal(t, uint64(0x12d687), m.UInt64("after"))
 
 }
+
+func TestDecodeJSON(t *testing.T) {
+	var m map[string]any
+	require.NoError(t, xdb.DecodeJSON("", &m))
+	assert.Nil(t, m)
+	require.NoError(t, xdb.DecodeJSON("null", &m))
+	assert.Nil(t, m)
+	require.NoError(t, xdb.DecodeJSON(`{"a":"b"}`, &m))
+	assert.Equal(t, map[string]any{"a": "b"}, m)
+	assert.Error(t, xdb.DecodeJSON(`{`, &m))
+}
@@ -0,0 +1,244 @@
+package xdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/pkg/errors"
+)
+
+// defaultMaxParams is used when the dialect does not specify
+// the maximum number of parameters, the default limit of SQLite
+const defaultMaxParams = 999
+
+// UpsertTable describes the table for UpsertAll,
+// it's implemented by schema.TableInfo
+type UpsertTable interface {
+	// TableName returns FQN of the table in schema.name format
+	TableName() string
+	// ColumnNames returns the list of columns
+	ColumnNames() []string
+	// SQLDialect returns the dialect of the table
+	SQLDialect() xsql.SQLDialect
+}
+
+// UpsertAll inserts or updates rows with a multi-row statement per batch,
+// batches are split by the parameters limit of the dialect.
+// rows must be structs or pointers to structs with db tags for every column of the table.
+// conflictCols specifies the columns of the unique index,
+// updateCols specifies the columns to update on conflict,
+// if empty then existing rows are not modified.
+// Rows with duplicate keys are collapsed, the last one wins.
+// Use a transaction to apply all batches atomically.
+// Returns the number of affected rows as reported by the driver.
+func UpsertAll[T any](ctx context.Context, db DB, t UpsertTable, rows []T, conflictCols []string, updateCols []string) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if len(conflictCols) == 0 {
+		return 0, errors.New("conflict columns are required")
+	}
+
+	columns := t.ColumnNames()
+	values, err := upsertValues(columns, conflictCols, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	dialect := t.SQLDialect()
+	if dialect == nil {
+		dialect = xsql.NoDialect
+	}
+	maxParams := dialect.Capabilities().MaxParams
+	if maxParams == 0 {
+		maxParams = defaultMaxParams
+	}
+	batchSize := max(maxParams/len(columns), 1)
+	if dialect.Provider() == "sqlserver" {
+		batchSize = min(batchSize, maxSQLServerRows)
+	}
+
+	var total int64
+	for start := 0; start < len(values); start += batchSize {
+		end := min(start+batchSize, len(values))
+		batch := values[start:end]
+
+		query := upsertQuery(dialect.Provider(), t.TableName(), columns, len(batch), conflictCols, updateCols)
+		var args []any
+		for _, v := range batch {
+			args = append(args, v...)
+		}
+
+		q := dialect.New(query, args...)
+		res, err := q.Exec(ctx, db)
+		q.Close()
+		if err != nil {
+			return total, errors.WithMessagef(err, "failed to upsert %d rows into %s", len(batch), t.TableName())
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			total += n
+		}
+	}
+	return total, nil
+}
+
+// KeyedTable describes the table with the primary key for BulkUpsert,
+// it's implemented by schema.TableInfo
+type KeyedTable interface {
+	UpsertTable
+	// KeyColumns returns the columns of the primary key
+	KeyColumns() []string
+}
+
+// BulkUpsert inserts or updates rows by the primary key of the table,
+// with INSERT ... ON CONFLICT DO UPDATE on Postgres and SQLite,
+// ON DUPLICATE KEY UPDATE on MySQL, or MERGE on SQL Server.
+// All columns except the primary key are updated for the existing rows.
+// See UpsertAll for batching and the requirements of rows.
+func BulkUpsert[T any](ctx context.Context, db DB, t KeyedTable, rows []T) (int64, error) {
+	keys := t.KeyColumns()
+	if len(keys) == 0 {
+		return 0, errors.Errorf("table %s has no primary key", t.TableName())
+	}
+
+	var updateCols []string
+	for _, c := range t.ColumnNames() {
+		isKey := false
+		for _, k := range keys {
+			isKey = isKey || strings.EqualFold(c, k)
+		}
+		if !isKey {
+			updateCols = append(updateCols, c)
+		}
+	}
+	return UpsertAll(ctx, db, t, rows, keys, updateCols)
+}
+
+// upsertValues returns column values of the rows,
+// rows with duplicate keys are collapsed, if conflictCols are specified
+func upsertValues[T any](columns, conflictCols []string, rows []T) ([][]any, error) {
+	typ := reflect.TypeOf(rows).Elem()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, errors.Errorf("unsupported row type: %s", typ.String())
+	}
+
+	structFields, err := xsql.StructFields(typ)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string][]int{}
+	for _, f := range structFields {
+		fields[strings.ToLower(f.Column)] = f.Index
+	}
+
+	index := make([][]int, len(columns))
+	keyPos := map[string]int{}
+	for i, c := range columns {
+		idx, ok := fields[strings.ToLower(c)]
+		if !ok {
+			return nil, errors.Errorf("column %s is not mapped in %s", c, typ.String())
+		}
+		index[i] = idx
+		keyPos[strings.ToLower(c)] = i
+	}
+	var keys []int
+	for _, c := range conflictCols {
+		pos, ok := keyPos[strings.ToLower(c)]
+		if !ok {
+			return nil, errors.Errorf("conflict column %s is not in the table", c)
+		}
+		keys = append(keys, pos)
+	}
+
+	values := make([][]any, 0, len(rows))
+	seen := map[string]int{}
+	for _, row := range rows {
+		v := reflect.Indirect(reflect.ValueOf(row))
+		if !v.IsValid() {
+			return nil, errors.New("nil row")
+		}
+		vals := make([]any, len(columns))
+		for i, idx := range index {
+			vals[i] = v.FieldByIndex(idx).Interface()
+		}
+
+		if len(keys) == 0 {
+			values = append(values, vals)
+			continue
+		}
+		var sb strings.Builder
+		for _, pos := range keys {
+			fmt.Fprintf(&sb, "%v\x00", vals[pos])
+		}
+		key := sb.String()
+		if i, ok := seen[key]; ok {
+			values[i] = vals
+			continue
+		}
+		seen[key] = len(values)
+		values = append(values, vals)
+	}
+	return values, nil
+}
+
+// upsertQuery returns the upsert statement for the provider,
+// with ? placeholders for count rows
+func upsertQuery(provider, table string, columns []string, count int, conflictCols, updateCols []string) string {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	rows := strings.TrimSuffix(strings.Repeat(row+", ", count), ", ")
+	cols := strings.Join(columns, ", ")
+
+	var sb strings.Builder
+	switch provider {
+	case "sqlserver":
+		fmt.Fprintf(&sb, "MERGE INTO %s WITH (HOLDLOCK) AS target USING (VALUES %s) AS source (%s) ON ", table, rows, cols)
+		for i, c := range conflictCols {
+			if i > 0 {
+				sb.WriteString(" AND ")
+			}
+			fmt.Fprintf(&sb, "target.%s = source.%s", c, c)
+		}
+		if len(updateCols) > 0 {
+			sb.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+			for i, c := range updateCols {
+				if i > 0 {
+					sb.WriteString(", ")
+				}
+				fmt.Fprintf(&sb, "target.%s = source.%s", c, c)
+			}
+		}
+		fmt.Fprintf(&sb, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (source.%s);", cols, strings.Join(columns, ", source."))
+	case "mysql":
+		fmt.Fprintf(&sb, "INSERT INTO %s (%s) VALUES %s ON DUPLICATE KEY UPDATE ", table, cols, rows)
+		if len(updateCols) == 0 {
+			// no-op update to skip existing rows
+			fmt.Fprintf(&sb, "%s = %s", conflictCols[0], conflictCols[0])
+		}
+		for i, c := range updateCols {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "%s = VALUES(%s)", c, c)
+		}
+	default:
+		fmt.Fprintf(&sb, "INSERT INTO %s (%s) VALUES %s ON CONFLICT (%s) ", table, cols, rows, strings.Join(conflictCols, ", "))
+		if len(updateCols) == 0 {
+			sb.WriteString("DO NOTHING")
+		} else {
+			sb.WriteString("DO UPDATE SET ")
+			for i, c := range updateCols {
+				if i > 0 {
+					sb.WriteString(", ")
+				}
+				fmt.Fprintf(&sb, "%s = EXCLUDED.%s", c, c)
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,135 @@
+package xdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/schema"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type setting struct {
+	OrgID int64  `db:"org_id"`
+	Name  string `db:"name"`
+	Value string `db:"value,null"`
+	Extra string
+}
+
+func TestUpsertAll(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE settings (org_id INTEGER, name TEXT, value TEXT, UNIQUE(org_id, name))")
+	require.NoError(t, err)
+
+	ti := &schema.TableInfo{
+		Name:    "settings",
+		Columns: []string{"org_id", "name", "value"},
+		Dialect: xsql.NoDialect,
+	}
+
+	var rows []*setting
+	for i := 0; i < 700; i++ {
+		rows = append(rows, &setting{OrgID: int64(i % 7), Name: "n" + string(rune('a'+i/7%26)) + string(rune('a'+i/182)), Value: "v1"})
+	}
+
+	// 700 rows * 3 columns are split into 3 batches by 999 parameters
+	n, err := xdb.UpsertAll(ctx, p, ti, rows, []string{"org_id", "name"}, []string{"value"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(700), n)
+
+	for _, r := range rows {
+		r.Value = "v2"
+	}
+	// duplicates are collapsed, the last wins
+	rows = append(rows, &setting{OrgID: 0, Name: "naa", Value: "v3"})
+
+	n, err = xdb.UpsertAll(ctx, p, ti, rows, []string{"org_id", "name"}, []string{"value"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(700), n)
+
+	var count int
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT COUNT(*) FROM settings WHERE value = 'v2'").Scan(&count))
+	assert.Equal(t, 699, count)
+	var value string
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT value FROM settings WHERE org_id = 0 AND name = 'naa'").Scan(&value))
+	assert.Equal(t, "v3", value)
+
+	// without update columns existing rows are preserved
+	n, err = xdb.UpsertAll(ctx, p, ti, []setting{
+		{OrgID: 0, Name: "naa", Value: "v4"},
+		{OrgID: 100, Name: "new", Value: "v4"},
+	}, []string{"org_id", "name"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	require.NoError(t, p.QueryRowContext(ctx, "SELECT value FROM settings WHERE org_id = 0 AND name = 'naa'").Scan(&value))
+	assert.Equal(t, "v3", value)
+
+	n, err = xdb.UpsertAll(ctx, p, ti, []setting{}, []string{"org_id"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	_, err = xdb.UpsertAll(ctx, p, ti, rows, nil, nil)
+	assert.EqualError(t, err, "conflict columns are required")
+
+	_, err = xdb.UpsertAll(ctx, p, ti, rows, []string{"id"}, nil)
+	assert.EqualError(t, err, "conflict column id is not in the table")
+
+	_, err = xdb.UpsertAll(ctx, p, ti, []int{1}, []string{"org_id"}, nil)
+	assert.EqualError(t, err, "unsupported row type: int")
+
+	type partial struct {
+		OrgID int64 `db:"org_id"`
+	}
+	_, err = xdb.UpsertAll(ctx, p, ti, []partial{{OrgID: 1}}, []string{"org_id"}, nil)
+	assert.EqualError(t, err, "column name is not mapped in xdb_test.partial")
+
+	_, err = xdb.UpsertAll(ctx, p, &schema.TableInfo{
+		Name:    "missing",
+		Columns: []string{"org_id", "name", "value"},
+	}, rows, []string{"org_id", "name"}, nil)
+	assert.ErrorContains(t, err, "failed to upsert 333 rows into missing")
+}
+
+func TestBulkUpsert(t *testing.T) {
+	p := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := p.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, email_verified BOOLEAN, name TEXT)")
+	require.NoError(t, err)
+
+	ti := &schema.TableInfo{
+		Name:       "users",
+		PrimaryKey: "id",
+		Columns:    []string{"id", "email", "email_verified", "name"},
+		Dialect:    xsql.NoDialect,
+	}
+
+	rows := []*user{
+		{ID: xdb.NewID(1), Email: "a@x", Name: "A"},
+		{ID: xdb.NewID(2), Email: "b@x", Name: "B"},
+	}
+	n, err := xdb.BulkUpsert(ctx, p, ti, rows)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	rows[1].Name = "B2"
+	rows = append(rows, &user{ID: xdb.NewID(3), Email: "c@x", Name: "C"})
+	n, err = xdb.BulkUpsert(ctx, p, ti, rows)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	var names []string
+	err = xdb.ExecuteStreamQuery(ctx, p, func(m *user) error {
+		names = append(names, m.Name)
+		return nil
+	}, "SELECT id, email, email_verified, name FROM users ORDER BY id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A", "B2", "C"}, names)
+
+	_, err = xdb.BulkUpsert(ctx, p, &schema.TableInfo{Name: "users", Columns: ti.Columns}, rows)
+	assert.EqualError(t, err, "table users has no primary key")
+}
@@ -0,0 +1,195 @@
+package xdb
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NilUUID is the UUID with all bits set to zero,
+// it's stored as NULL the same way as empty UUID
+const NilUUID = UUID("00000000-0000-0000-0000-000000000000")
+
+// UUIDBinaryValue specifies to encode UUID values as 16 bytes
+// in SQL Server uniqueidentifier byte order,
+// by default the values are encoded as strings
+var UUIDBinaryValue = false
+
+// UUID de/encodes the string a SQL string.
+type UUID string
+
+// NewUUID returns random UUID version 4
+func NewUUID() UUID {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return formatUUID(b)
+}
+
+// NewUUIDv7 returns UUID version 7, ordered by the creation time
+// with millisecond precision, suitable for primary keys
+func NewUUIDv7() UUID {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+	ms := uint64(time.Now().UnixMilli())
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	binary.BigEndian.PutUint32(b[2:], uint32(ms))
+	b[6] = (b[6] & 0x0f) | 0x70
+	b[8] = (b[8] & 0x3f) | 0x80
+	return formatUUID(b)
+}
+
+// ParseUUID returns UUID in the canonical lower case format,
+// the value can be in xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx format,
+// without hyphens, in braces, or with urn:uuid: prefix.
+// Empty string returns empty UUID.
+func ParseUUID(val string) (UUID, error) {
+	if val == "" {
+		return "", nil
+	}
+	b, err := parseUUID(val)
+	if err != nil {
+		return "", err
+	}
+	return formatUUID(b), nil
+}
+
+// MustParseUUID returns UUID from the string, or panics
+func MustParseUUID(val string) UUID {
+	u, err := ParseUUID(val)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func parseUUID(val string) ([16]byte, error) {
+	var b [16]byte
+
+	s := strings.TrimPrefix(strings.ToLower(val), "urn:uuid:")
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = s[1 : len(s)-1]
+	}
+	if len(s) == 36 {
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return b, errors.Errorf("invalid UUID: %q", val)
+		}
+		s = s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	}
+	if len(s) != 32 {
+		return b, errors.Errorf("invalid UUID: %q", val)
+	}
+	if _, err := hex.Decode(b[:], []byte(s)); err != nil {
+		return b, errors.Errorf("invalid UUID: %q", val)
+	}
+	return b, nil
+}
+
+func formatUUID(b [16]byte) UUID {
+	return UUID(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
+
+// String returns string
+func (ns UUID) String() string {
+	return string(ns)
+}
+
+// IsZero reports whether the UUID is empty or NilUUID
+func (ns UUID) IsZero() bool {
+	if ns == "" || ns == NilUUID {
+		return true
+	}
+	b, err := parseUUID(string(ns))
+	return err == nil && b == [16]byte{}
+}
+
+// Validate returns error if the UUID is not empty and not in the valid format
+func (ns UUID) Validate() error {
+	if ns == "" {
+		return nil
+	}
+	_, err := parseUUID(string(ns))
+	return err
+}
+
+// Version returns the version of UUID, or 0 if it's not valid
+func (ns UUID) Version() int {
+	b, err := parseUUID(string(ns))
+	if err != nil {
+		return 0
+	}
+	return int(b[6] >> 4)
+}
+
+// Bytes returns 16 bytes of UUID in RFC 4122 byte order
+func (ns UUID) Bytes() ([]byte, error) {
+	b, err := parseUUID(string(ns))
+	if err != nil {
+		return nil, err
+	}
+	return b[:], nil
+}
+
+// Scan implements the Scanner interface.
+func (ns *UUID) Scan(value any) error {
+	if value == nil {
+		*ns = ""
+		return nil
+	}
+
+	var s string
+	switch vid := value.(type) {
+	case []byte:
+		switch len(vid) {
+		case 16:
+			// SQL Server uniqueidentifier has the first 3 groups in little-endian order
+			s = fmt.Sprintf("%02X%02X%02X%02X-%02X%02X-%02X%02X-%02X%02X-%02X%02X%02X%02X%02X%02X",
+				vid[3], vid[2], vid[1], vid[0], vid[5], vid[4], vid[7], vid[6], vid[8], vid[9], vid[10], vid[11], vid[12], vid[13], vid[14], vid[15])
+		case 0:
+		default:
+			s = string(vid)
+			if err := UUID(s).Validate(); err != nil {
+				return errors.WithMessagef(err, "failed to parse UUID")
+			}
+		}
+	case string:
+		s = vid
+	default:
+		return errors.Errorf("unsupported scan type: %T", value)
+	}
+
+	if UUID(s).IsZero() {
+		s = ""
+	}
+	*ns = UUID(s)
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+// Empty and NilUUID are encoded as NULL,
+// the value is encoded as bytes if UUIDBinaryValue is set.
+func (ns UUID) Value() (driver.Value, error) {
+	if ns.IsZero() {
+		return nil, nil
+	}
+	b, err := parseUUID(string(ns))
+	if err != nil {
+		return nil, err
+	}
+	if UUIDBinaryValue {
+		// SQL Server uniqueidentifier has the first 3 groups in little-endian order
+		b[0], b[1], b[2], b[3] = b[3], b[2], b[1], b[0]
+		b[4], b[5] = b[5], b[4]
+		b[6], b[7] = b[7], b[6]
+		return b[:], nil
+	}
+	return string(formatUUID(b)), nil
+}
@@ -0,0 +1,118 @@
+package xdb_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUUID(t *testing.T) {
+	u := xdb.NewUUID()
+	require.NoError(t, u.Validate())
+	assert.Equal(t, 4, u.Version())
+	assert.NotEqual(t, u, xdb.NewUUID())
+
+	v7 := xdb.NewUUIDv7()
+	require.NoError(t, v7.Validate())
+	assert.Equal(t, 7, v7.Version())
+	assert.Len(t, v7.String(), 36)
+	// time ordered
+	assert.LessOrEqual(t, v7.String()[:13], xdb.NewUUIDv7().String()[:13])
+}
+
+func TestParseUUID(t *testing.T) {
+	exp := xdb.UUID("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11")
+	for _, val := range []string{
+		"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11",
+		"{a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11}",
+		"urn:uuid:a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"a0eebc999c0b4ef8bb6d6bb9bd380a11",
+	} {
+		u, err := xdb.ParseUUID(val)
+		require.NoError(t, err, val)
+		assert.Equal(t, exp, u)
+		assert.Equal(t, 4, u.Version())
+	}
+
+	u, err := xdb.ParseUUID("")
+	require.NoError(t, err)
+	assert.Empty(t, u)
+
+	for _, val := range []string{
+		"a0eebc99-9c0b-4ef8-bb6d",
+		"a0eebc99x9c0b-4ef8-bb6d-6bb9bd380a11",
+		"g0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"a0eebc999c0b4ef8bb6d6bb9bd380a1",
+	} {
+		_, err := xdb.ParseUUID(val)
+		assert.Error(t, err, val)
+		assert.Error(t, xdb.UUID(val).Validate(), val)
+		assert.Equal(t, 0, xdb.UUID(val).Version())
+	}
+
+	assert.Equal(t, exp, xdb.MustParseUUID(exp.String()))
+	assert.Panics(t, func() { xdb.MustParseUUID("bad") })
+
+	b, err := exp.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}, b)
+	_, err = xdb.UUID("bad").Bytes()
+	assert.Error(t, err)
+}
+
+func TestUUIDValue(t *testing.T) {
+	mssql := []byte{0x99, 0xbc, 0xee, 0xa0, 0x0b, 0x9c, 0xf8, 0x4e, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}
+
+	for _, u := range []xdb.UUID{"", xdb.NilUUID, "00000000000000000000000000000000"} {
+		assert.True(t, u.IsZero())
+		v, err := u.Value()
+		require.NoError(t, err)
+		assert.Nil(t, v)
+	}
+
+	u := xdb.UUID("A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11")
+	assert.False(t, u.IsZero())
+	v, err := u.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", v)
+
+	_, err = xdb.UUID("bad").Value()
+	assert.Error(t, err)
+
+	xdb.UUIDBinaryValue = true
+	defer func() { xdb.UUIDBinaryValue = false }()
+
+	v, err = u.Value()
+	require.NoError(t, err)
+	assert.Equal(t, mssql, v)
+
+	var scanned xdb.UUID
+	require.NoError(t, scanned.Scan(v))
+	assert.Equal(t, u, scanned)
+}
+
+func TestUUIDScan(t *testing.T) {
+	tcases := []struct {
+		val any
+		exp xdb.UUID
+	}{
+		{nil, ""},
+		{"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		{[]byte("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"), "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		{[]byte{}, ""},
+		{string(xdb.NilUUID), ""},
+		{make([]byte, 16), ""},
+	}
+	for _, tc := range tcases {
+		u := xdb.UUID("x")
+		require.NoError(t, u.Scan(tc.val))
+		assert.Equal(t, tc.exp, u)
+	}
+
+	var u xdb.UUID
+	assert.Error(t, u.Scan([]byte{1, 2, 3}))
+	assert.Error(t, u.Scan(42))
+}
@@ -0,0 +1,186 @@
+package xsql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+/*
+WhereContains adds a filter for array column that contains all the values:
+
+	xsql.Postgres.From("doc").
+		Select("id").
+		WhereContains("tags", []string{"a", "b"})
+
+produces on Postgres
+
+	SELECT id FROM doc WHERE tags @> $1
+
+where the values are bound with pq.Array.
+On other dialects the column is expected to store JSON array,
+and EXISTS-based predicate is rendered with the values bound as JSON.
+
+An empty list of values matches all rows.
+*/
+func (q *Stmt) WhereContains(column string, values any) Builder {
+	n := sliceLen(values)
+	if n == 0 {
+		q.Where("1=1")
+		return q
+	}
+
+	if q.dialect.Capabilities().Arrays {
+		q.Where(column+" @> ?", pgArray(values))
+	} else {
+		fn := jsonTableFunc(q.dialect.Provider())
+		q.Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s(?) v WHERE v.value NOT IN (SELECT value FROM %s(%s)))", fn, fn, column),
+			jsonArray(values))
+	}
+	return q
+}
+
+/*
+WhereOverlaps adds a filter for array column that contains any of the values:
+
+	xsql.Postgres.From("doc").
+		Select("id").
+		WhereOverlaps("tags", []string{"a", "b"})
+
+produces on Postgres
+
+	SELECT id FROM doc WHERE tags && $1
+
+where the values are bound with pq.Array.
+On other dialects the column is expected to store JSON array,
+and EXISTS-based predicate is rendered with the values bound as JSON.
+
+An empty list of values matches no rows.
+*/
+func (q *Stmt) WhereOverlaps(column string, values any) Builder {
+	n := sliceLen(values)
+	if n == 0 {
+		q.Where("1=0")
+		return q
+	}
+
+	if q.dialect.Capabilities().Arrays {
+		q.Where(column+" && ?", pgArray(values))
+	} else {
+		fn := jsonTableFunc(q.dialect.Provider())
+		q.Where(fmt.Sprintf("EXISTS (SELECT 1 FROM %s(?) v WHERE v.value IN (SELECT value FROM %s(%s)))", fn, fn, column),
+			jsonArray(values))
+	}
+	return q
+}
+
+/*
+WhereIn adds a filter for the column that equals any of the values:
+
+	xsql.From("doc").
+		Select("id").
+		WhereIn("owner_id", []int64{1, 2})
+
+produces
+
+	SELECT id FROM doc WHERE owner_id IN (?, ?)
+
+On Postgres the values are bound as a single pq.Array argument:
+
+	SELECT id FROM doc WHERE owner_id = ANY($1)
+
+An empty list of values matches no rows.
+*/
+func (q *Stmt) WhereIn(column string, values any) Builder {
+	n := sliceLen(values)
+	if n == 0 {
+		q.Where("1=0")
+		return q
+	}
+
+	if q.dialect.Capabilities().Arrays {
+		q.Where(column+" = ANY(?)", pgArray(values))
+	} else {
+		q.Where(column+" IN ("+placeholders(n)+")", sliceArgs(values)...)
+	}
+	return q
+}
+
+/*
+WhereNotIn adds a filter for the column that does not equal any of the values,
+on Postgres the values are bound as a single pq.Array argument:
+
+	SELECT id FROM doc WHERE owner_id <> ALL($1)
+
+An empty list of values matches all rows.
+*/
+func (q *Stmt) WhereNotIn(column string, values any) Builder {
+	n := sliceLen(values)
+	if n == 0 {
+		q.Where("1=1")
+		return q
+	}
+
+	if q.dialect.Capabilities().Arrays {
+		q.Where(column+" <> ALL(?)", pgArray(values))
+	} else {
+		q.Where(column+" NOT IN ("+placeholders(n)+")", sliceArgs(values)...)
+	}
+	return q
+}
+
+// placeholders returns n comma separated placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// sliceArgs returns the elements of the slice as query arguments
+func sliceArgs(values any) []any {
+	v := reflect.ValueOf(values)
+	args := make([]any, v.Len())
+	for i := range args {
+		args[i] = v.Index(i).Interface()
+	}
+	return args
+}
+
+// jsonTableFunc returns the name of table-valued function to expand JSON array
+func jsonTableFunc(provider string) string {
+	if provider == "sqlserver" {
+		return "OPENJSON"
+	}
+	return "json_each"
+}
+
+func sliceLen(values any) int {
+	v := reflect.ValueOf(values)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return v.Len()
+	case reflect.Invalid:
+		return 0
+	}
+	panic(fmt.Sprintf("expected slice of values: %T", values))
+}
+
+// pgArray returns the values as Postgres array argument,
+// types that implement driver.Valuer, like xdb.IDArray, are used as is.
+func pgArray(values any) any {
+	if _, ok := values.(driver.Valuer); ok {
+		return values
+	}
+	return pq.Array(values)
+}
+
+// jsonArray returns the values as JSON array argument
+func jsonArray(values any) string {
+	js, err := json.Marshal(values)
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode values: %s", err.Error()))
+	}
+	return string(js)
+}
@@ -0,0 +1,131 @@
+package xsql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/effective-security/xdb"
+	"github.com/effective-security/xdb/xsql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhereContainsPostgres(t *testing.T) {
+	q := xsql.Postgres.From("doc").
+		Select("id").
+		WhereContains("tags", []string{"a", "b"}).
+		WhereOverlaps("owner_ids", xdb.NewIDArray([]uint64{1, 2}))
+	defer q.Close()
+	assert.Equal(t, "SELECT id \nFROM doc \nWHERE tags @> $1 AND owner_ids && $2", q.String())
+	require.Len(t, q.Args(), 2)
+	assert.Equal(t, pq.Array([]string{"a", "b"}), q.Args()[0])
+	assert.Equal(t, xdb.NewIDArray([]uint64{1, 2}), q.Args()[1])
+}
+
+func TestWhereContainsEmpty(t *testing.T) {
+	q := xsql.Postgres.From("doc").
+		Select("id").
+		WhereContains("tags", []string{}).
+		WhereOverlaps("tags", nil)
+	defer q.Close()
+	assert.Equal(t, "SELECT id \nFROM doc \nWHERE 1=1 AND 1=0", q.String())
+	assert.Empty(t, q.Args())
+
+	assert.Panics(t, func() {
+		xsql.Postgres.From("doc").WhereContains("tags", "a")
+	})
+}
+
+func TestWhereContainsSQLServer(t *testing.T) {
+	q := xsql.SQLServer.From("doc").
+		Select("id").
+		WhereContains("tags", []string{"a", "b"}).
+		WhereOverlaps("owner_ids", xdb.NewIDArray([]uint64{1, 2}))
+	defer q.Close()
+	assert.Equal(t, "SELECT id \nFROM doc \n"+
+		"WHERE NOT EXISTS (SELECT 1 FROM OPENJSON(?) v WHERE v.value NOT IN (SELECT value FROM OPENJSON(tags)))"+
+		" AND EXISTS (SELECT 1 FROM OPENJSON(?) v WHERE v.value IN (SELECT value FROM OPENJSON(owner_ids)))", q.String())
+	assert.Equal(t, []any{`["a","b"]`, `[1,2]`}, q.Args())
+}
+
+func TestWhereContainsJSON(t *testing.T) {
+	ctx := context.Background()
+	for _, env := range envs {
+		_, err := env.db.ExecContext(ctx, "CREATE TABLE docs (id INTEGER PRIMARY KEY, tags TEXT)")
+		require.NoError(t, err)
+		_, err = env.db.ExecContext(ctx, `INSERT INTO docs (id, tags) VALUES (1, '["a","b","c"]'), (2, '["b"]'), (3, '[]')`)
+		require.NoError(t, err)
+
+		find := func(q xsql.Builder) []int {
+			var ids []int
+			var id int
+			err := q.Select("id").To(&id).OrderBy("id").QueryAndClose(ctx, env.db, func(_ *sql.Rows) {
+				ids = append(ids, id)
+			})
+			require.NoError(t, err)
+			return ids
+		}
+
+		assert.Equal(t, []int{1}, find(env.xsql.From("docs").WhereContains("tags", xdb.Strings{"a", "b"})))
+		assert.Equal(t, []int{1, 2}, find(env.xsql.From("docs").WhereContains("tags", []string{"b", "b"})))
+		assert.Equal(t, []int{1, 2}, find(env.xsql.From("docs").WhereOverlaps("tags", []string{"c", "b"})))
+		assert.Equal(t, []int{1}, find(env.xsql.From("docs").WhereOverlaps("tags", []string{"a", "x"})))
+		assert.Empty(t, find(env.xsql.From("docs").WhereOverlaps("tags", []string{})))
+		assert.Equal(t, []int{1, 2, 3}, find(env.xsql.From("docs").WhereContains("tags", []string{})))
+
+		_, err = env.db.ExecContext(ctx, "DROP TABLE docs")
+		require.NoError(t, err)
+	}
+}
+
+func TestWhereIn(t *testing.T) {
+	q := xsql.Postgres.From("doc").
+		Select("id").
+		WhereIn("owner_id", []int64{1, 2}).
+		WhereNotIn("status", []string{"deleted"})
+	defer q.Close()
+	assert.Equal(t, "SELECT id \nFROM doc \nWHERE owner_id = ANY($1) AND status <> ALL($2)", q.String())
+	assert.Equal(t, []any{pq.Array([]int64{1, 2}), pq.Array([]string{"deleted"})}, q.Args())
+
+	q2 := xsql.SQLServer.From("doc").
+		Select("id").
+		WhereIn("owner_id", []int64{1, 2}).
+		WhereNotIn("status", []string{"deleted", "archived"})
+	defer q2.Close()
+	assert.Equal(t, "SELECT id \nFROM doc \nWHERE owner_id IN (?, ?) AND status NOT IN (?, ?)", q2.String())
+	assert.Equal(t, []any{int64(1), int64(2), "deleted", "archived"}, q2.Args())
+
+	q3 := xsql.Postgres.From("doc").
+		Select("id").
+		WhereIn("owner_id", []int64{}).
+		WhereNotIn("status", nil)
+	defer q3.Close()
+	assert.Equal(t, "SELECT id \nFROM doc \nWHERE 1=0 AND 1=1", q3.String())
+	assert.Empty(t, q3.Args())
+
+	assert.Panics(t, func() {
+		xsql.From("doc").WhereIn("owner_id", 1)
+	})
+}
+
+func TestWhereInExec(t *testing.T) {
+	forEveryDB(t, func(ctx context.Context, env *dbEnv) {
+		find := func(q xsql.Builder) []int {
+			var ids []int
+			var id int
+			err := q.Select("id").To(&id).OrderBy("id").QueryAndClose(ctx, env.db, func(_ *sql.Rows) {
+				ids = append(ids, id)
+			})
+			require.NoError(t, err)
+			return ids
+		}
+
+		assert.Equal(t, []int{1, 3}, find(env.xsql.From("users").WhereIn("id", []int{1, 3, 100})))
+		assert.Equal(t, []int{2}, find(env.xsql.From("users").WhereIn("name", []string{"User 2"})))
+		assert.Empty(t, find(env.xsql.From("users").WhereIn("id", []int{})))
+		assert.Equal(t, []int{2, 3}, find(env.xsql.From("users").WhereNotIn("id", []int{1})))
+		assert.Equal(t, []int{1, 2, 3}, find(env.xsql.From("users").WhereNotIn("id", []int{})))
+	})
+}
@@ -6,17 +6,26 @@ import (
 	"github.com/valyala/bytebufferpool"
 )
 
+// GetCachedQuery returns a cached query by name
 func (d *Dialect) GetCachedQuery(name string) (string, bool) {
-	res, ok := d.cache.Load(name)
-	if ok {
-		return res.(string), ok
-	}
-
-	return "", ok
+	return d.cache.get(name)
 }
 
+// PutCachedQuery stores a query in the cache,
+// the least recently used queries are evicted when the cache is full
 func (d *Dialect) PutCachedQuery(name, sql string) {
-	d.cache.Store(name, sql)
+	d.cache.put(name, sql)
+}
+
+// SetQueryCacheSize sets the maximum number of cached queries,
+// the default is DefaultQueryCacheSize, zero disables the limit
+func (d *Dialect) SetQueryCacheSize(maxEntries int) {
+	d.cache.setLimit(maxEntries)
+}
+
+// QueryCacheStats returns the counters of the query cache
+func (d *Dialect) QueryCacheStats() QueryCacheStats {
+	return d.cache.stats()
 }
 
 // GetOrCreateQuery returns a cached query by name or creates a new one.
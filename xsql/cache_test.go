@@ -45,12 +45,7 @@ func TestSQLCache(t *testing.T) {
 	assert.Equal(t, exp, q)
 	assert.Equal(t, "test3", name)
 
-	count := 0
-	dialect.cache.Range(func(key, value any) bool {
-		count++
-		return true
-	})
-	assert.Equal(t, 3, count)
+	assert.Equal(t, 3, dialect.cache.len())
 }
 
 func TestReusePool(t *testing.T) {
@@ -92,3 +87,44 @@ func TestReusePool(t *testing.T) {
 	assert.Equal(t, "WITH t AS (SELECT id, quantity \nFROM orders \nWHERE ts < ?) \nSELECT id, quantity \nFROM t", q4.String())
 	q4.Close()
 }
+
+func TestQueryCacheEviction(t *testing.T) {
+	dialect := &Dialect{provider: "postgres"}
+	assert.Equal(t, DefaultQueryCacheSize, dialect.QueryCacheStats().MaxEntries)
+
+	dialect.SetQueryCacheSize(2)
+	dialect.PutCachedQuery("q1", "SQL1")
+	dialect.PutCachedQuery("q2", "SQL2")
+
+	// q1 becomes the most recently used
+	_, ok := dialect.GetCachedQuery("q1")
+	require.True(t, ok)
+
+	dialect.PutCachedQuery("q3", "SQL3")
+	_, ok = dialect.GetCachedQuery("q2")
+	assert.False(t, ok)
+	sql, ok := dialect.GetCachedQuery("q1")
+	require.True(t, ok)
+	assert.Equal(t, "SQL1", sql)
+
+	assert.Equal(t, QueryCacheStats{
+		Hits:       2,
+		Misses:     1,
+		Evictions:  1,
+		Size:       2,
+		MaxEntries: 2,
+	}, dialect.QueryCacheStats())
+
+	dialect.SetQueryCacheSize(1)
+	stats := dialect.QueryCacheStats()
+	assert.Equal(t, 1, stats.Size)
+	assert.Equal(t, uint64(2), stats.Evictions)
+
+	dialect.SetQueryCacheSize(0)
+	for i := 0; i < 10; i++ {
+		dialect.PutCachedQuery(time.Duration(i).String(), "SQL")
+	}
+	stats = dialect.QueryCacheStats()
+	assert.Equal(t, 11, stats.Size)
+	assert.Equal(t, 0, stats.MaxEntries)
+}
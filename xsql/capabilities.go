@@ -0,0 +1,68 @@
+package xsql
+
+// Capabilities describes SQL features supported by a dialect,
+// to branch shared code on features instead of provider names.
+type Capabilities struct {
+	// Returning specifies support of RETURNING clause in INSERT, UPDATE and DELETE
+	Returning bool
+	// OnConflict specifies support of INSERT ... ON CONFLICT clause
+	OnConflict bool
+	// SkipLocked specifies support of skipping locked rows,
+	// FOR UPDATE SKIP LOCKED or READPAST hint
+	SkipLocked bool
+	// Arrays specifies support of array columns and operators
+	Arrays bool
+	// CTEDML specifies support of INSERT, UPDATE and DELETE statements in WITH clause
+	CTEDML bool
+	// IndexHints specifies support of index hints, see UseIndex
+	IndexHints bool
+	// MaxParams specifies the maximum number of parameters in a statement,
+	// zero if unknown
+	MaxParams int
+}
+
+var (
+	noCapabilities = Capabilities{}
+
+	postgresCapabilities = Capabilities{
+		Returning:  true,
+		OnConflict: true,
+		SkipLocked: true,
+		Arrays:     true,
+		CTEDML:     true,
+		MaxParams:  65535,
+	}
+
+	sqlServerCapabilities = Capabilities{
+		SkipLocked: true,
+		CTEDML:     true,
+		IndexHints: true,
+		MaxParams:  2100,
+	}
+
+	mySQLCapabilities = Capabilities{
+		SkipLocked: true,
+		IndexHints: true,
+		MaxParams:  65535,
+	}
+)
+
+// Capabilities returns SQL features supported by the dialect
+func (b *Dialect) Capabilities() Capabilities {
+	return b.caps
+}
+
+// DialectFor returns the dialect for the provider name:
+// postgres, sqlserver or mysql, or NoDialect for unknown providers
+func DialectFor(provider string) SQLDialect {
+	switch provider {
+	case "postgres":
+		return Postgres
+	case "sqlserver":
+		return SQLServer
+	case "mysql":
+		return MySQL
+	default:
+		return NoDialect
+	}
+}
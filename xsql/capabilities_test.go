@@ -0,0 +1,39 @@
+package xsql_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilities(t *testing.T) {
+	assert.Equal(t, xsql.Capabilities{}, xsql.NoDialect.Capabilities())
+
+	pg := xsql.Postgres.Capabilities()
+	assert.True(t, pg.Returning)
+	assert.True(t, pg.OnConflict)
+	assert.True(t, pg.SkipLocked)
+	assert.True(t, pg.Arrays)
+	assert.True(t, pg.CTEDML)
+	assert.False(t, pg.IndexHints)
+	assert.Equal(t, 65535, pg.MaxParams)
+
+	ms := xsql.SQLServer.Capabilities()
+	assert.False(t, ms.Returning)
+	assert.False(t, ms.OnConflict)
+	assert.False(t, ms.Arrays)
+	assert.True(t, ms.SkipLocked)
+	assert.True(t, ms.IndexHints)
+	assert.Equal(t, 2100, ms.MaxParams)
+
+	assert.True(t, xsql.MySQL.Capabilities().IndexHints)
+	assert.False(t, xsql.MySQL.Capabilities().Arrays)
+}
+
+func TestDialectFor(t *testing.T) {
+	assert.Equal(t, xsql.Postgres, xsql.DialectFor("postgres"))
+	assert.Equal(t, xsql.SQLServer, xsql.DialectFor("sqlserver"))
+	assert.Equal(t, xsql.MySQL, xsql.DialectFor("mysql"))
+	assert.Equal(t, xsql.NoDialect, xsql.DialectFor("sqlite3"))
+}
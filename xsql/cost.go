@@ -0,0 +1,129 @@
+package xsql
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Cost describes the estimated complexity of a statement.
+// It's a lightweight estimate based on the statement text,
+// to throttle or reject expensive user-constructed filters
+// before sending them to the database.
+type Cost struct {
+	// Joins is the number of JOIN clauses, including joins in subqueries
+	Joins int
+	// Subqueries is the number of nested SELECT statements
+	Subqueries int
+	// Unions is the number of UNION clauses
+	Unions int
+	// OrConditions is the number of OR operators
+	OrConditions int
+	// LeadingWildcards is the number of LIKE patterns starting with '%',
+	// that can't use an index
+	LeadingWildcards int
+	// HasWhere is true if the statement has a WHERE clause
+	HasWhere bool
+	// HasLimit is true if the statement has a LIMIT clause
+	HasLimit bool
+	// FullScans is the number of full table scans in the query plan,
+	// it's not set by the statement and can be provided from EXPLAIN
+	FullScans int
+}
+
+// CostWeights specifies the weights of Cost elements in Score
+type CostWeights struct {
+	Join            int
+	Subquery        int
+	Union           int
+	OrCondition     int
+	LeadingWildcard int
+	NoWhere         int
+	NoLimit         int
+	FullScan        int
+}
+
+// DefaultCostWeights is used by Cost.Score
+var DefaultCostWeights = CostWeights{
+	Join:            10,
+	Subquery:        10,
+	Union:           5,
+	OrCondition:     2,
+	LeadingWildcard: 20,
+	NoWhere:         20,
+	NoLimit:         5,
+	FullScan:        50,
+}
+
+// Score returns the weighted cost with DefaultCostWeights
+func (c Cost) Score() int {
+	return c.WeightedScore(DefaultCostWeights)
+}
+
+// WeightedScore returns the weighted cost
+func (c Cost) WeightedScore(w CostWeights) int {
+	score := c.Joins*w.Join +
+		c.Subqueries*w.Subquery +
+		c.Unions*w.Union +
+		c.OrConditions*w.OrCondition +
+		c.LeadingWildcards*w.LeadingWildcard +
+		c.FullScans*w.FullScan
+	if !c.HasWhere {
+		score += w.NoWhere
+	}
+	if !c.HasLimit {
+		score += w.NoLimit
+	}
+	return score
+}
+
+var (
+	reCostJoin     = regexp.MustCompile(`\bJOIN\b`)
+	reCostSubquery = regexp.MustCompile(`\(\s*SELECT\b`)
+	reCostUnion    = regexp.MustCompile(`\bUNION\b`)
+	reCostOr       = regexp.MustCompile(`\bOR\b`)
+	reCostWhere    = regexp.MustCompile(`\bWHERE\b`)
+	reCostLike     = regexp.MustCompile(`\bI?LIKE\s+`)
+)
+
+/*
+Cost returns the estimated complexity of the statement:
+
+	q := xsql.From("users").
+		Select("id").
+		Where("name LIKE ?", "%"+name+"%")
+	if q.Cost().Score() > maxScore {
+		return errors.New("the filter is too expensive")
+	}
+
+The estimate is based on the text of the statement,
+and LIKE patterns passed as arguments.
+*/
+func (q *Stmt) Cost() Cost {
+	text := q.buf.String()
+	upper := strings.ToUpper(text)
+
+	c := Cost{
+		Joins:        len(reCostJoin.FindAllStringIndex(upper, -1)),
+		Subqueries:   len(reCostSubquery.FindAllStringIndex(upper, -1)),
+		Unions:       len(reCostUnion.FindAllStringIndex(upper, -1)),
+		OrConditions: len(reCostOr.FindAllStringIndex(upper, -1)),
+		HasWhere:     reCostWhere.MatchString(upper),
+		HasLimit:     q.HasLimit(),
+	}
+
+	for _, m := range reCostLike.FindAllStringIndex(upper, -1) {
+		pattern := text[m[1]:]
+		switch {
+		case strings.HasPrefix(pattern, "'%"):
+			c.LeadingWildcards++
+		case strings.HasPrefix(pattern, "?"):
+			idx := strings.Count(text[:m[1]], "?")
+			if idx < len(q.args) {
+				if s, ok := q.args[idx].(string); ok && strings.HasPrefix(s, "%") {
+					c.LeadingWildcards++
+				}
+			}
+		}
+	}
+	return c
+}
@@ -0,0 +1,50 @@
+package xsql_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCost(t *testing.T) {
+	q := xsql.From("users").Select("id")
+	c := q.Cost()
+	assert.Equal(t, xsql.Cost{}, c)
+	assert.Equal(t, 25, c.Score())
+	q.Close()
+
+	q = xsql.From("users u").
+		Select("u.id").
+		Join("orgs o", "o.id = u.org_id").
+		LeftJoin("teams t", "t.id = u.team_id").
+		Where("u.name LIKE ?", "%smith%").
+		Where("u.email LIKE ?", "smith%").
+		Where("u.login LIKE '%admin'").
+		Where("(u.active = ? OR u.role = ?)", true, "admin").
+		Where("u.org_id IN (SELECT id FROM orgs WHERE name LIKE ?)", "%corp").
+		Limit(10)
+	defer q.Close()
+
+	c = q.Cost()
+	assert.Equal(t, xsql.Cost{
+		Joins:            2,
+		Subqueries:       1,
+		OrConditions:     1,
+		LeadingWildcards: 3,
+		HasWhere:         true,
+		HasLimit:         true,
+	}, c)
+	assert.Equal(t, 2*10+10+2+3*20, c.Score())
+
+	c.FullScans = 1
+	assert.Equal(t, 1, c.WeightedScore(xsql.CostWeights{FullScan: 1}))
+
+	u := xsql.From("a").Select("id").Where("id > ?", 1).
+		Union(true, xsql.From("b").Select("id"))
+	defer u.Close()
+	c = u.Cost()
+	assert.Equal(t, 1, c.Unions)
+	assert.True(t, c.HasWhere)
+	assert.False(t, c.HasLimit)
+}
@@ -0,0 +1,82 @@
+package xsql
+
+import (
+	"slices"
+	"strings"
+)
+
+/*
+CountQuery returns a new statement that counts the rows of the SELECT statement
+over the same FROM, JOIN and WHERE clauses, without ORDER BY, LIMIT, OFFSET
+and locking clauses:
+
+	q := xsql.From("users").
+		Select("id, name").
+		Where("org_id = ?", orgID).
+		OrderBy("name").
+		Limit(20)
+
+	cq := q.CountQuery()
+
+produces
+
+	SELECT COUNT(*) FROM users WHERE org_id = ?
+
+The statements with GROUP BY, UNION or DISTINCT are counted as a subquery:
+
+	SELECT COUNT(*) FROM (SELECT DISTINCT org_id FROM users) AS t
+
+The returned statement is unbounded, and should be closed separately.
+*/
+func (q *Stmt) CountQuery() Builder {
+	subquery := false
+	for _, chunk := range q.chunks {
+		switch chunk.pos {
+		case posGroupBy, posUnion:
+			subquery = true
+		case posSelect:
+			s := strings.ToUpper(strings.TrimSpace(string(q.buf.B[chunk.bufLow:chunk.bufHigh])))
+			subquery = subquery || strings.HasPrefix(s, "SELECT DISTINCT")
+		}
+	}
+
+	var stmt *Stmt
+	if subquery {
+		inner := q.without(posOrderBy, posLimit, posOffset, posLock)
+		stmt = q.dialect.(*Dialect).getStmt()
+		stmt.useNewLines = q.useNewLines
+		stmt.addChunk(posFrom, "FROM", "", nil, ", ")
+		stmt.SubQuery("(", ") AS t", inner)
+	} else {
+		stmt = q.without(posSelect, posOrderBy, posLimit, posOffset, posLock)
+	}
+	stmt.addChunk(posSelect, "SELECT", "COUNT(*)", nil, ", ")
+	if q.name != "" {
+		// the cache is keyed by the name of the statement
+		stmt.name = q.name + ".count"
+	}
+	stmt.unbounded = true
+	return stmt
+}
+
+// without returns a copy of the statement without the clauses at the positions
+func (q *Stmt) without(skip ...chunkPos) *Stmt {
+	stmt := q.dialect.(*Dialect).getStmt()
+	stmt.useNewLines = q.useNewLines
+	argNo := 0
+	for _, chunk := range q.chunks {
+		args := q.args[argNo : argNo+chunk.argLen]
+		argNo += chunk.argLen
+		if slices.Contains(skip, chunk.pos) {
+			continue
+		}
+
+		bufLow := len(stmt.buf.B)
+		_, _ = stmt.buf.Write(q.buf.B[chunk.bufLow:chunk.bufHigh])
+		chunk.bufLow = bufLow
+		chunk.bufHigh = len(stmt.buf.B)
+		stmt.chunks = append(stmt.chunks, chunk)
+		stmt.args = append(stmt.args, args...)
+	}
+	return stmt
+}
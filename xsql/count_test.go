@@ -0,0 +1,78 @@
+package xsql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountQuery(t *testing.T) {
+	q := xsql.Postgres.From("users u").
+		Select("u.id, u.name").
+		SelectExpr(xsql.As(xsql.Coalesce(xsql.Col("u.nickname"), "x"), "nick")).
+		Join("orgs o", "o.id = u.org_id").
+		Where("o.name = ?", "acme").
+		OrderBy("u.name").
+		Limit(20).
+		Offset(40).
+		ForUpdate()
+	defer q.Close()
+
+	cq := q.CountQuery()
+	defer cq.Close()
+	assert.Equal(t, "SELECT COUNT(*) \nFROM users u JOIN orgs o ON (o.id = u.org_id) \nWHERE o.name = $1", cq.String())
+	assert.Equal(t, []any{"acme"}, cq.Args())
+	assert.True(t, cq.IsUnbounded())
+
+	// the original statement is not changed
+	assert.Equal(t, "SELECT u.id, u.name, COALESCE(u.nickname, $1) AS nick \nFROM users u JOIN orgs o ON (o.id = u.org_id) \nWHERE o.name = $2 \nORDER BY u.name \nLIMIT $3 \nOFFSET $4 \nFOR UPDATE", q.String())
+	assert.Equal(t, []any{"x", "acme", 20, 40}, q.Args())
+
+	q2 := xsql.Postgres.From("users").
+		Select("org_id, COUNT(*)").
+		Where("active = ?", true).
+		GroupBy("org_id").
+		Having("COUNT(*) > ?", 1).
+		OrderBy("org_id").
+		Limit(10).
+		SetName("TestCountQueryGroupBy")
+	defer q2.Close()
+
+	cq2 := q2.CountQuery()
+	defer cq2.Close()
+	assert.Equal(t, "TestCountQueryGroupBy.count", cq2.Name())
+	assert.Equal(t, "SELECT COUNT(*) \nFROM (SELECT org_id, COUNT(*) \nFROM users \nWHERE active = $1 \nGROUP BY org_id \nHAVING COUNT(*) > $2) AS t", cq2.String())
+	assert.Equal(t, []any{true, 1}, cq2.Args())
+
+	q3 := xsql.NoDialect.From("users").Select("DISTINCT org_id")
+	defer q3.Close()
+	cq3 := q3.CountQuery()
+	defer cq3.Close()
+	assert.Equal(t, "SELECT COUNT(*) \nFROM (SELECT DISTINCT org_id \nFROM users) AS t", cq3.String())
+}
+
+func TestCountQueryExec(t *testing.T) {
+	forEveryDB(t, func(ctx context.Context, env *dbEnv) {
+		q := env.xsql.From("incomes").
+			Select("id").
+			Where("amount >= ?", 200).
+			OrderBy("amount").
+			Limit(2)
+		defer q.Close()
+
+		var total int64
+		err := q.CountQuery().To(&total).QueryRowAndClose(ctx, env.db)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), total)
+
+		q2 := env.xsql.From("incomes").
+			Select("DISTINCT user_id")
+		defer q2.Close()
+		err = q2.CountQuery().To(&total).QueryRowAndClose(ctx, env.db)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+	})
+}
@@ -0,0 +1,108 @@
+package xsql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// truncIntervals lists supported intervals for DateTrunc
+var truncIntervals = map[string]bool{
+	"second":  true,
+	"minute":  true,
+	"hour":    true,
+	"day":     true,
+	"week":    true,
+	"month":   true,
+	"quarter": true,
+	"year":    true,
+}
+
+/*
+DateTrunc returns an expression that truncates the column to the interval:
+second, minute, hour, day, week, month, quarter or year.
+
+	q := xsql.From("events").
+		Select(xsql.DateTrunc("hour", "created_at") + " AS bucket, COUNT(*)").
+		GroupBy("bucket")
+
+renders date_trunc on Postgres and DATEADD/DATEDIFF on SQL Server.
+
+DateTrunc panics if the interval is not supported.
+*/
+func (b *Dialect) DateTrunc(interval, column string) string {
+	interval = strings.ToLower(interval)
+	if !truncIntervals[interval] {
+		panic(fmt.Sprintf("unsupported interval: %q", interval))
+	}
+
+	switch b.provider {
+	case "sqlserver":
+		if interval == "week" {
+			// 1900-01-01 is Monday, consistent with ISO weeks on Postgres
+			return fmt.Sprintf("DATEADD(week, DATEDIFF(day, 0, %s) / 7, 0)", column)
+		}
+		if interval == "second" || interval == "minute" {
+			// count from the start of the day, as the seconds since 1900
+			// overflow int in DATEDIFF
+			day := fmt.Sprintf("DATEADD(day, DATEDIFF(day, 0, %s), 0)", column)
+			return fmt.Sprintf("DATEADD(%s, DATEDIFF(%s, %s, %s), %s)", interval, interval, day, column, day)
+		}
+		return fmt.Sprintf("DATEADD(%s, DATEDIFF(%s, 0, %s), 0)", interval, interval, column)
+	default:
+		return fmt.Sprintf("date_trunc('%s', %s)", interval, column)
+	}
+}
+
+/*
+TimeBucket returns an expression that aligns the column to buckets of the width,
+counting from the Unix epoch. The width must be a positive multiple of a second.
+
+	q := xsql.From("events").
+		Select(xsql.TimeBucket(5*time.Minute, "created_at") + " AS bucket, COUNT(*)").
+		GroupBy("bucket")
+
+TimeBucket panics if the width is not supported.
+*/
+func (b *Dialect) TimeBucket(width time.Duration, column string) string {
+	if width < time.Second || width%time.Second != 0 {
+		panic(fmt.Sprintf("unsupported bucket width: %s", width))
+	}
+
+	switch b.provider {
+	case "sqlserver":
+		// choose the largest unit to avoid int overflow in DATEDIFF,
+		// the buckets count from the Unix epoch as on Postgres
+		unit, size := "second", int64(width/time.Second)
+		anchor := "'1970-01-01'"
+		switch {
+		case width%(24*time.Hour) == 0:
+			unit, size = "day", int64(width/(24*time.Hour))
+		case width%time.Hour == 0:
+			unit, size = "hour", int64(width/time.Hour)
+		case width%time.Minute == 0:
+			unit, size = "minute", int64(width/time.Minute)
+		}
+		return fmt.Sprintf("DATEADD(%s, (DATEDIFF(%s, %s, %s) / %d) * %d, %s)",
+			unit, unit, anchor, column, size, size, anchor)
+	default:
+		secs := int64(width / time.Second)
+		return fmt.Sprintf("to_timestamp(floor(extract(epoch from %s) / %d) * %d)", column, secs, secs)
+	}
+}
+
+/*
+DateTrunc returns an expression that truncates the column to the interval,
+using the default dialect.
+*/
+func DateTrunc(interval, column string) string {
+	return defaultDialect.Load().(SQLDialect).DateTrunc(interval, column)
+}
+
+/*
+TimeBucket returns an expression that aligns the column to buckets of the width,
+using the default dialect.
+*/
+func TimeBucket(width time.Duration, column string) string {
+	return defaultDialect.Load().(SQLDialect).TimeBucket(width, column)
+}
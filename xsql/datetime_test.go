@@ -0,0 +1,62 @@
+package xsql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateTrunc(t *testing.T) {
+	assert.Equal(t, "date_trunc('hour', created_at)", xsql.Postgres.DateTrunc("HOUR", "created_at"))
+	assert.Equal(t, "date_trunc('week', created_at)", xsql.NoDialect.DateTrunc("week", "created_at"))
+	assert.Equal(t, "DATEADD(month, DATEDIFF(month, 0, created_at), 0)", xsql.SQLServer.DateTrunc("month", "created_at"))
+	assert.Equal(t, "DATEADD(week, DATEDIFF(day, 0, created_at) / 7, 0)", xsql.SQLServer.DateTrunc("week", "created_at"))
+	assert.Equal(t, "DATEADD(second, DATEDIFF(second, DATEADD(day, DATEDIFF(day, 0, created_at), 0), created_at), DATEADD(day, DATEDIFF(day, 0, created_at), 0))",
+		xsql.SQLServer.DateTrunc("second", "created_at"))
+	assert.Equal(t, "DATEADD(minute, DATEDIFF(minute, DATEADD(day, DATEDIFF(day, 0, created_at), 0), created_at), DATEADD(day, DATEDIFF(day, 0, created_at), 0))",
+		xsql.SQLServer.DateTrunc("minute", "created_at"))
+	assert.Panics(t, func() { xsql.Postgres.DateTrunc("hour'); DROP TABLE x; --", "created_at") })
+
+	xsql.SetDialect(xsql.Postgres)
+	defer xsql.SetDialect(xsql.NoDialect)
+	assert.Equal(t, "date_trunc('day', t.ts)", xsql.DateTrunc("day", "t.ts"))
+
+	q := xsql.From("events").
+		Select(xsql.DateTrunc("day", "created_at")+" AS bucket, COUNT(*)").
+		Where("org_id = ?", 1).
+		GroupBy("bucket")
+	defer q.Close()
+	assert.Equal(t, "SELECT date_trunc('day', created_at) AS bucket, COUNT(*) \nFROM events \nWHERE org_id = $1 \nGROUP BY bucket", q.String())
+}
+
+func TestTimeBucket(t *testing.T) {
+	assert.Equal(t, "to_timestamp(floor(extract(epoch from created_at) / 300) * 300)", xsql.Postgres.TimeBucket(5*time.Minute, "created_at"))
+	assert.Equal(t, "DATEADD(minute, (DATEDIFF(minute, '1970-01-01', created_at) / 5) * 5, '1970-01-01')", xsql.SQLServer.TimeBucket(5*time.Minute, "created_at"))
+	assert.Equal(t, "DATEADD(second, (DATEDIFF(second, '1970-01-01', created_at) / 30) * 30, '1970-01-01')", xsql.SQLServer.TimeBucket(30*time.Second, "created_at"))
+
+	// the buckets of both dialects count from the Unix epoch
+	for _, tc := range []struct {
+		width    time.Duration
+		postgres string
+		mssql    string
+	}{
+		{7 * 24 * time.Hour,
+			"to_timestamp(floor(extract(epoch from created_at) / 604800) * 604800)",
+			"DATEADD(day, (DATEDIFF(day, '1970-01-01', created_at) / 7) * 7, '1970-01-01')"},
+		{5 * time.Hour,
+			"to_timestamp(floor(extract(epoch from created_at) / 18000) * 18000)",
+			"DATEADD(hour, (DATEDIFF(hour, '1970-01-01', created_at) / 5) * 5, '1970-01-01')"},
+		{7 * time.Second,
+			"to_timestamp(floor(extract(epoch from created_at) / 7) * 7)",
+			"DATEADD(second, (DATEDIFF(second, '1970-01-01', created_at) / 7) * 7, '1970-01-01')"},
+	} {
+		assert.Equal(t, tc.postgres, xsql.Postgres.TimeBucket(tc.width, "created_at"))
+		assert.Equal(t, tc.mssql, xsql.SQLServer.TimeBucket(tc.width, "created_at"))
+	}
+	assert.Equal(t, "to_timestamp(floor(extract(epoch from ts) / 60) * 60)", xsql.TimeBucket(time.Minute, "ts"))
+
+	assert.Panics(t, func() { xsql.Postgres.TimeBucket(0, "created_at") })
+	assert.Panics(t, func() { xsql.Postgres.TimeBucket(1500*time.Millisecond, "created_at") })
+}
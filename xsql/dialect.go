@@ -3,8 +3,8 @@ package xsql
 import (
 	"strconv"
 	"strings"
-	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // SQLDialect is an interface for SQL statement builders.
@@ -12,6 +12,9 @@ type SQLDialect interface {
 	// Provider returns the name of the SQL dialect.
 	Provider() string
 
+	// Capabilities returns SQL features supported by the dialect
+	Capabilities() Capabilities
+
 	// UseNewLines specifies an option to add new lines for each clause
 	UseNewLines(op bool)
 
@@ -21,6 +24,13 @@ type SQLDialect interface {
 	// PutCachedQuery stores a query in the cache.
 	PutCachedQuery(name, query string)
 
+	// SetQueryCacheSize sets the maximum number of cached queries,
+	// zero disables the limit
+	SetQueryCacheSize(maxEntries int)
+
+	// QueryCacheStats returns the counters of the query cache
+	QueryCacheStats() QueryCacheStats
+
 	// GetOrCreateQuery returns a cached query by name or creates a new one.
 	// The function will close the Builder
 	GetOrCreateQuery(name string, create func(name string) Builder) (query string, key string)
@@ -59,6 +69,30 @@ type SQLDialect interface {
 		and closes a subquery passed as an argument.
 	*/
 	With(queryName string, query Builder) Builder
+
+	// DateTrunc returns an expression that truncates the column to the interval
+	DateTrunc(interval, column string) string
+
+	// TimeBucket returns an expression that aligns the column to buckets of the width
+	TimeBucket(width time.Duration, column string) string
+
+	// JSONValue returns an expression that extracts a scalar value from a JSON column
+	JSONValue(column, path string) string
+
+	// JSONExists returns a predicate that checks if the path exists in a JSON column
+	JSONExists(column, path string) string
+
+	// Excluded returns an expression that refers the value proposed for insertion
+	// in DoUpdateSet clause of UPSERT statement
+	Excluded(column string) string
+
+	// Quote returns the identifier quoted by the rules of the dialect,
+	// the parts of dotted names are quoted separately
+	Quote(ident string) string
+
+	// BindNamed returns the query with :name and @name parameters replaced
+	// by the placeholders of the dialect, and the arguments from params
+	BindNamed(query string, params any) (string, []any, error)
 }
 
 // Dialect defines the method SQL statement is to be built.
@@ -82,17 +116,20 @@ type SQLDialect interface {
 // replaced with numbered positional arguments like $1, $2...
 type Dialect struct {
 	provider    string
-	cache       sync.Map
+	cache       queryCache
 	useNewLines bool
+	caps        Capabilities
 }
 
 var (
 	// NoDialect is a default statement builder mode.
-	NoDialect = SQLDialect(&Dialect{provider: "default", useNewLines: true})
+	NoDialect = SQLDialect(&Dialect{provider: "default", useNewLines: true, caps: noCapabilities})
 	// Postgres mode is to be used to automatically replace ? placeholders with $1, $2...
-	Postgres = SQLDialect(&Dialect{provider: "postgres", useNewLines: true})
+	Postgres = SQLDialect(&Dialect{provider: "postgres", useNewLines: true, caps: postgresCapabilities})
 
-	SQLServer = SQLDialect(&Dialect{provider: "sqlserver", useNewLines: true})
+	SQLServer = SQLDialect(&Dialect{provider: "sqlserver", useNewLines: true, caps: sqlServerCapabilities})
+	// MySQL mode is to be used to render MySQL specific clauses, like index hints
+	MySQL = SQLDialect(&Dialect{provider: "mysql", useNewLines: true, caps: mySQLCapabilities})
 )
 
 var defaultDialect atomic.Value // *SQLDialect
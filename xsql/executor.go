@@ -7,18 +7,37 @@ import (
 
 // Executor performs SQL queries.
 // It's an interface accepted by Query, QueryRow and Exec methods.
-// Both sql.DB, sql.Conn and sql.Tx can be passed as executor.
+// Both sql.DB, sql.Conn and sql.Tx can be passed as executor,
+// as well as xdb.Provider, xdb.Tx and xdb.DB.
 type Executor interface {
-	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	// QueryContext executes a query that returns rows, typically a SELECT.
+	// The args are for any placeholder parameters in the query.
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	// QueryRowContext executes a query that is expected to return at most one row.
+	// QueryRowContext always returns a non-nil value. Errors are deferred until
+	// Row's Scan method is called.
+	// If the query selects no rows, the *Row's Scan will return ErrNoRows.
+	// Otherwise, the *Row's Scan scans the first selected row and discards
+	// the rest.
 	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	// ExecContext executes a query without returning any rows.
+	// The args are for any placeholder parameters in the query.
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
 // Query executes the statement.
 // For every row of a returned dataset it calls a handler function.
 // If scan targets were set via To method calls, Query method
 // executes rows.Scan right before calling a handler function.
-func (q *Stmt) Query(ctx context.Context, db Executor, handler func(rows *sql.Rows)) error {
+func (q *Stmt) Query(ctx context.Context, db Executor, handler func(rows *sql.Rows)) (err error) {
+	if q.err != nil {
+		return q.err
+	}
+	ctx, e := q.beforeQuery(ctx, OpQuery)
+	defer func() {
+		afterQuery(ctx, e, err)
+	}()
+
 	// Fetch rows
 	rows, err := db.QueryContext(ctx, q.String(), q.args...)
 	if err != nil {
@@ -68,8 +87,14 @@ func (q *Stmt) QueryAndClose(ctx context.Context, db Executor, handler func(rows
 // QueryRow executes the statement via Executor methods
 // and scans values to variables bound via To method calls.
 func (q *Stmt) QueryRow(ctx context.Context, db Executor) error {
+	if q.err != nil {
+		return q.err
+	}
+	ctx, e := q.beforeQuery(ctx, OpQueryRow)
 	row := db.QueryRowContext(ctx, q.String(), q.args...)
-	return row.Scan(q.dest...)
+	err := row.Scan(q.dest...)
+	afterQuery(ctx, e, err)
+	return err
 }
 
 // QueryRowAndClose executes the statement via Executor methods
@@ -86,7 +111,13 @@ func (q *Stmt) QueryRowAndClose(ctx context.Context, db Executor) error {
 
 // Exec executes the statement.
 func (q *Stmt) Exec(ctx context.Context, db Executor) (sql.Result, error) {
-	return db.ExecContext(ctx, q.String(), q.args...)
+	if q.err != nil {
+		return nil, q.err
+	}
+	ctx, e := q.beforeQuery(ctx, OpExec)
+	res, err := db.ExecContext(ctx, q.String(), q.args...)
+	afterQuery(ctx, e, err)
+	return res, err
 }
 
 // ExecAndClose executes the statement and releases all the objects
@@ -0,0 +1,128 @@
+package xsql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ExplainOptions specifies the options of EXPLAIN statement
+type ExplainOptions struct {
+	// Analyze executes the statement to report the actual time and rows,
+	// supported by Postgres and MySQL.
+	// Note that on Postgres and MySQL INSERT, UPDATE and DELETE statements
+	// are executed and their changes are applied,
+	// run them in a transaction that is rolled back.
+	Analyze bool
+	// Verbose reports additional information, supported by Postgres
+	Verbose bool
+}
+
+// Plan is the query plan of the statement
+type Plan struct {
+	// Query is the explained statement
+	Query string
+	// Lines of the plan, in the order reported by the database
+	Lines []string
+}
+
+// String returns the plan lines separated by new line
+func (p *Plan) String() string {
+	return strings.Join(p.Lines, "\n")
+}
+
+// explainPrefix returns EXPLAIN clause of the dialect
+func explainPrefix(provider string, opts *ExplainOptions) (string, error) {
+	if opts == nil {
+		opts = &ExplainOptions{}
+	}
+	switch provider {
+	case "postgres":
+		var options []string
+		if opts.Analyze {
+			options = append(options, "ANALYZE")
+		}
+		if opts.Verbose {
+			options = append(options, "VERBOSE")
+		}
+		if len(options) == 0 {
+			return "EXPLAIN ", nil
+		}
+		return "EXPLAIN (" + strings.Join(options, ", ") + ") ", nil
+	case "mysql":
+		if opts.Analyze {
+			return "EXPLAIN ANALYZE ", nil
+		}
+		return "EXPLAIN FORMAT=TREE ", nil
+	case "sqlite3", "default":
+		// SQLite is used with NoDialect
+		if opts.Analyze {
+			return "", errors.Errorf("explain analyze is not supported by %s", provider)
+		}
+		return "EXPLAIN QUERY PLAN ", nil
+	default:
+		// SQL Server: SHOWPLAN must be set in a separate batch on the same connection
+		return "", errors.Errorf("explain is not supported by %s", provider)
+	}
+}
+
+/*
+Explain returns the query plan of the statement,
+with EXPLAIN syntax of the dialect:
+
+	plan, err := q.Explain(ctx, db, &xsql.ExplainOptions{Analyze: true})
+	if err == nil {
+		log.Printf("%s:\n%s", q.Name(), plan)
+	}
+
+Postgres, MySQL and SQLite are supported.
+*/
+func (q *Stmt) Explain(ctx context.Context, db Executor, opts *ExplainOptions) (*Plan, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	return ExplainQuery(ctx, db, q.dialect.Provider(), q.String(), q.args, opts)
+}
+
+// ExplainQuery returns the query plan of the query,
+// with EXPLAIN syntax of the provider: postgres, mysql, sqlite3,
+// or default for SQLite used with NoDialect
+func ExplainQuery(ctx context.Context, db Executor, provider, query string, args []any, opts *ExplainOptions) (*Plan, error) {
+	prefix, err := explainPrefix(provider, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, prefix+query, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to explain query")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	plan := &Plan{Query: query}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// the plan line is the last column,
+		// MySQL returns the tree in a single row
+		line := values[len(values)-1].String
+		plan.Lines = append(plan.Lines, strings.Split(strings.TrimRight(line, "\n"), "\n")...)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return plan, nil
+}
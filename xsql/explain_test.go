@@ -0,0 +1,33 @@
+package xsql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	forEveryDB(t, func(ctx context.Context, env *dbEnv) {
+		q := env.xsql.From("users").
+			Select("name").
+			Where("id = ?", 1)
+		defer q.Close()
+
+		plan, err := q.Explain(ctx, env.db, nil)
+		require.NoError(t, err)
+		assert.Equal(t, q.String(), plan.Query)
+		require.NotEmpty(t, plan.Lines)
+		assert.Contains(t, plan.String(), "users")
+
+		_, err = q.Explain(ctx, env.db, &xsql.ExplainOptions{Analyze: true})
+		assert.EqualError(t, err, "explain analyze is not supported by default")
+
+		q2 := env.xsql.From("missing").Select("name")
+		defer q2.Close()
+		_, err = q2.Explain(ctx, env.db, nil)
+		assert.ErrorContains(t, err, "failed to explain query")
+	})
+}
@@ -0,0 +1,283 @@
+package xsql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expression is an SQL expression with its arguments,
+// the arguments are referred by ? placeholders
+type Expression interface {
+	String() string
+	Args() []any
+}
+
+type rawExpr struct {
+	sql  string
+	args []any
+}
+
+func (e *rawExpr) String() string {
+	return e.sql
+}
+
+func (e *rawExpr) Args() []any {
+	return e.args
+}
+
+// Raw returns an expression from SQL fragment and its arguments:
+//
+//	xsql.Raw("amount > ?", 1000)
+func Raw(expr string, args ...any) Expression {
+	return &rawExpr{sql: expr, args: args}
+}
+
+// Col returns an expression that refers the column,
+// to distinguish it from the values bound as arguments
+func Col(name string) Expression {
+	return &rawExpr{sql: name}
+}
+
+// As returns the expression with the alias, to be used in SELECT clause
+func As(expr Expression, alias string) Expression {
+	return &rawExpr{sql: expr.String() + " AS " + alias, args: expr.Args()}
+}
+
+// operand returns SQL and the arguments of the operand:
+// Expression is embedded, nil is rendered as NULL,
+// and other values are bound as arguments
+func operand(v any) (string, []any) {
+	switch val := v.(type) {
+	case nil:
+		return "NULL", nil
+	case Expression:
+		return val.String(), val.Args()
+	default:
+		return "?", []any{v}
+	}
+}
+
+// CaseExpr builds CASE expression
+type CaseExpr struct {
+	buf  strings.Builder
+	args []any
+	els  string
+	// elseArgs follow the arguments of WHEN clauses
+	elseArgs []any
+}
+
+/*
+Case starts a searched CASE expression:
+
+	tier := xsql.Case().
+		When(xsql.Raw("amount >= ?", 1000), "gold").
+		When("amount >= 100", "silver").
+		Else("bronze")
+
+	q := xsql.From("orders").
+		Select("id").To(&id).
+		SelectExpr(xsql.As(tier, "tier")).To(&tierName)
+
+produces
+
+	SELECT id, CASE WHEN amount >= ? THEN ? WHEN amount >= 100 THEN ? ELSE ? END AS tier FROM orders
+
+The values are bound as arguments, use Col or Raw to refer columns and expressions.
+*/
+func Case() *CaseExpr {
+	return &CaseExpr{}
+}
+
+// When adds WHEN clause with the condition and the result value,
+// the condition is SQL string or Expression, such as Raw("amount >= ?", 1000)
+func (c *CaseExpr) When(cond any, value any) *CaseExpr {
+	var condSQL string
+	switch v := cond.(type) {
+	case Expression:
+		condSQL = v.String()
+		c.args = append(c.args, v.Args()...)
+	default:
+		condSQL = fmt.Sprint(cond)
+	}
+	val, args := operand(value)
+
+	c.buf.WriteString(" WHEN ")
+	c.buf.WriteString(condSQL)
+	c.buf.WriteString(" THEN ")
+	c.buf.WriteString(val)
+	c.args = append(c.args, args...)
+	return c
+}
+
+// Else sets the result value if no conditions are met, NULL by default
+func (c *CaseExpr) Else(value any) *CaseExpr {
+	c.els, c.elseArgs = operand(value)
+	return c
+}
+
+// String returns SQL of the expression
+func (c *CaseExpr) String() string {
+	if c.buf.Len() == 0 {
+		// CASE requires at least one WHEN clause
+		if c.els == "" {
+			return "NULL"
+		}
+		return c.els
+	}
+	s := "CASE" + c.buf.String()
+	if c.els != "" {
+		s += " ELSE " + c.els
+	}
+	return s + " END"
+}
+
+// Args returns the arguments of the expression
+func (c *CaseExpr) Args() []any {
+	if c.buf.Len() == 0 {
+		return c.elseArgs
+	}
+	return append(c.args[:len(c.args):len(c.args)], c.elseArgs...)
+}
+
+/*
+Coalesce returns COALESCE expression, that evaluates to the first non-NULL operand:
+
+	xsql.Coalesce(xsql.Col("nickname"), xsql.Col("name"), "anonymous")
+
+produces
+
+	COALESCE(nickname, name, ?)
+
+The values are bound as arguments, use Col or Raw to refer columns and expressions.
+*/
+func Coalesce(operands ...any) Expression {
+	e := &rawExpr{}
+	list := make([]string, len(operands))
+	for i, op := range operands {
+		var args []any
+		list[i], args = operand(op)
+		e.args = append(e.args, args...)
+	}
+	e.sql = "COALESCE(" + strings.Join(list, ", ") + ")"
+	return e
+}
+
+// SelectExpr adds the expression to SELECT clause
+func (q *Stmt) SelectExpr(expr Expression) Builder {
+	return q.Select(expr.String(), expr.Args()...)
+}
+
+// WhereExpr adds the condition built by And, Or, Not or Raw to WHERE clause
+func (q *Stmt) WhereExpr(cond Expression) Builder {
+	return q.Where(cond.String(), cond.Args()...)
+}
+
+// predicate is a condition built by And, Or and Not
+type predicate struct {
+	rawExpr
+	// grouped is true if the condition is enclosed in parentheses
+	grouped bool
+}
+
+/*
+And returns a condition that is true if all the conditions are true,
+the conditions are SQL strings or Expressions, such as Raw("a = ?", 1) or Or(...):
+
+	q := xsql.From("users").
+		Select("id").
+		WhereExpr(xsql.And(
+			xsql.Or(xsql.Raw("a = ?", 1), xsql.Raw("b = ?", 2)),
+			xsql.Raw("c = ?", 3),
+		))
+
+produces
+
+	SELECT id FROM users WHERE ((a = ? OR b = ?) AND c = ?)
+
+Empty conditions are skipped, and And without conditions is always true.
+*/
+func And(conds ...any) Expression {
+	return group(" AND ", "1=1", conds)
+}
+
+/*
+Or returns a condition that is true if any of the conditions is true,
+the conditions are SQL strings or Expressions, such as Raw("a = ?", 1) or And(...):
+
+	xsql.Or(xsql.Raw("a = ?", 1), "b IS NULL")
+
+produces
+
+	(a = ? OR b IS NULL)
+
+Empty conditions are skipped, and Or without conditions is always false.
+*/
+func Or(conds ...any) Expression {
+	return group(" OR ", "1=0", conds)
+}
+
+// Not returns a condition that negates cond, the SQL string or Expression,
+// empty condition is skipped and Not is always true
+func Not(cond any) Expression {
+	sql, args := condition(cond)
+	if sql == "" {
+		return &predicate{rawExpr: rawExpr{sql: "1=1"}}
+	}
+	return &predicate{rawExpr: rawExpr{sql: "NOT (" + unwrap(sql, cond) + ")", args: args}}
+}
+
+func group(op, empty string, conds []any) Expression {
+	e := &predicate{}
+	list := make([]string, 0, len(conds))
+	for _, c := range conds {
+		sql, args := condition(c)
+		if sql == "" {
+			continue
+		}
+		e.grouped = isGrouped(c)
+		// OR has lower precedence than AND
+		if op == " AND " && !e.grouped && strings.Contains(strings.ToUpper(sql), " OR ") {
+			sql = "(" + sql + ")"
+			e.grouped = true
+		}
+		list = append(list, sql)
+		e.args = append(e.args, args...)
+	}
+
+	switch len(list) {
+	case 0:
+		e.sql = empty
+		e.grouped = false
+	case 1:
+		e.sql = list[0]
+	default:
+		e.sql = "(" + strings.Join(list, op) + ")"
+		e.grouped = true
+	}
+	return e
+}
+
+// condition returns SQL and the arguments of the condition
+func condition(cond any) (string, []any) {
+	switch v := cond.(type) {
+	case nil:
+		return "", nil
+	case Expression:
+		return v.String(), v.Args()
+	default:
+		return fmt.Sprint(cond), nil
+	}
+}
+
+func isGrouped(cond any) bool {
+	p, ok := cond.(*predicate)
+	return ok && p.grouped
+}
+
+// unwrap removes the parentheses of the grouped condition
+func unwrap(sql string, cond any) string {
+	if isGrouped(cond) {
+		return sql[1 : len(sql)-1]
+	}
+	return sql
+}
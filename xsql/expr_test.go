@@ -0,0 +1,132 @@
+package xsql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCase(t *testing.T) {
+	tier := xsql.Case().
+		When(xsql.Raw("amount >= ?", 1000), "gold").
+		When("amount >= 100", "silver").
+		Else(xsql.Col("source"))
+	assert.Equal(t, "CASE WHEN amount >= ? THEN ? WHEN amount >= 100 THEN ? ELSE source END", tier.String())
+	assert.Equal(t, []any{1000, "gold", "silver"}, tier.Args())
+
+	q := xsql.Postgres.From("incomes").
+		Select("id").
+		SelectExpr(xsql.As(tier, "tier")).
+		Where("user_id = ?", 1)
+	defer q.Close()
+	assert.Equal(t, "SELECT id, CASE WHEN amount >= $1 THEN $2 WHEN amount >= 100 THEN $3 ELSE source END AS tier \nFROM incomes \nWHERE user_id = $4", q.String())
+	assert.Equal(t, []any{1000, "gold", "silver", 1}, q.Args())
+
+	assert.Equal(t, "CASE WHEN deleted THEN NULL END", xsql.Case().When("deleted", nil).String())
+	assert.Equal(t, "NULL", xsql.Case().String())
+	empty := xsql.Case().Else(1)
+	assert.Equal(t, "?", empty.String())
+	assert.Equal(t, []any{1}, empty.Args())
+}
+
+func TestCoalesce(t *testing.T) {
+	e := xsql.Coalesce(xsql.Col("nickname"), xsql.Col("name"), xsql.Raw("lower(?)", "X"), "anonymous", nil)
+	assert.Equal(t, "COALESCE(nickname, name, lower(?), ?, NULL)", e.String())
+	assert.Equal(t, []any{"X", "anonymous"}, e.Args())
+
+	c := xsql.Case().When("id = 1", xsql.Coalesce(xsql.Col("name"), "none")).Else("other")
+	assert.Equal(t, "CASE WHEN id = 1 THEN COALESCE(name, ?) ELSE ? END", c.String())
+	assert.Equal(t, []any{"none", "other"}, c.Args())
+}
+
+func TestCaseExec(t *testing.T) {
+	forEveryDB(t, func(ctx context.Context, env *dbEnv) {
+		var amount float64
+		var tier string
+		var list []string
+		err := env.xsql.From("incomes").
+			Select("amount").To(&amount).
+			SelectExpr(xsql.As(xsql.Case().
+				When(xsql.Raw("amount >= ?", 400), "gold").
+				When(xsql.Raw("amount >= ?", 200), "silver").
+				Else("bronze"), "tier")).To(&tier).
+			OrderBy("amount").
+			QueryAndClose(ctx, env.db, func(_ *sql.Rows) {
+				list = append(list, tier)
+			})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bronze", "silver", "silver", "gold", "gold"}, list)
+
+		var name string
+		err = env.xsql.From("users").
+			SelectExpr(xsql.Coalesce(nil, xsql.Col("name"), "none")).To(&name).
+			Where("id = ?", 2).
+			QueryRowAndClose(ctx, env.db)
+		require.NoError(t, err)
+		assert.Equal(t, "User 2", name)
+	})
+}
+
+func TestPredicates(t *testing.T) {
+	cond := xsql.And(
+		xsql.Or(xsql.Raw("a = ?", 1), xsql.Raw("b = ?", 2)),
+		xsql.Raw("c = ?", 3),
+	)
+	assert.Equal(t, "((a = ? OR b = ?) AND c = ?)", cond.String())
+	assert.Equal(t, []any{1, 2, 3}, cond.Args())
+
+	tcases := []struct {
+		cond xsql.Expression
+		exp  string
+	}{
+		{xsql.And(), "1=1"},
+		{xsql.Or(), "1=0"},
+		{xsql.And(nil, ""), "1=1"},
+		{xsql.And("a = 1"), "a = 1"},
+		{xsql.Or("a = 1", nil, "b = 2"), "(a = 1 OR b = 2)"},
+		// OR is enclosed in parentheses within AND
+		{xsql.And("a = 1 OR b = 2", "c = 3"), "((a = 1 OR b = 2) AND c = 3)"},
+		{xsql.And("a = 1 or b = 2"), "(a = 1 or b = 2)"},
+		{xsql.And(xsql.Or("a = 1", "b = 2")), "(a = 1 OR b = 2)"},
+		{xsql.Or(xsql.And("a = 1", "b = 2"), "c = 3"), "((a = 1 AND b = 2) OR c = 3)"},
+		{xsql.Not("deleted"), "NOT (deleted)"},
+		{xsql.Not(xsql.Or("a = 1", "b = 2")), "NOT (a = 1 OR b = 2)"},
+		{xsql.Not(nil), "1=1"},
+		{xsql.And(xsql.Not("a = 1"), xsql.Or()), "(NOT (a = 1) AND 1=0)"},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.exp, tc.cond.String())
+	}
+
+	q := xsql.Postgres.From("users").
+		Select("id").
+		Where("active = ?", true).
+		WhereExpr(xsql.Or(xsql.Raw("a = ?", 1), xsql.And(xsql.Raw("b = ?", 2), xsql.Raw("c IN (?, ?)", 3, 4)))).
+		Where("d = ?", 5)
+	defer q.Close()
+	assert.Equal(t, "SELECT id \nFROM users \nWHERE active = $1 AND (a = $2 OR (b = $3 AND c IN ($4, $5))) AND d = $6", q.String())
+	assert.Equal(t, []any{true, 1, 2, 3, 4, 5}, q.Args())
+}
+
+func TestPredicatesExec(t *testing.T) {
+	forEveryDB(t, func(ctx context.Context, env *dbEnv) {
+		var amount float64
+		var list []float64
+		err := env.xsql.From("incomes").
+			Select("amount").To(&amount).
+			WhereExpr(xsql.Or(
+				xsql.Raw("amount < ?", 200),
+				xsql.And(xsql.Raw("amount >= ?", 400), xsql.Not(xsql.Raw("amount > ?", 10000))),
+			)).
+			OrderBy("amount").
+			QueryAndClose(ctx, env.db, func(_ *sql.Rows) {
+				list = append(list, amount)
+			})
+		require.NoError(t, err)
+		assert.Equal(t, []float64{100, 400, 500}, list)
+	})
+}
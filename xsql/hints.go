@@ -0,0 +1,43 @@
+package xsql
+
+import (
+	"github.com/effective-security/xlog"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/xdb", "xsql")
+
+/*
+UseIndex adds an index hint for the table in the FROM clause,
+to force a query plan when the optimizer picks a wrong one:
+
+	xsql.SQLServer.From("orders").
+		UseIndex("ix_orders_created_at").
+		Select("id").
+		Where("created_at > ?", since)
+
+produces
+
+	SELECT id FROM orders WITH (INDEX(ix_orders_created_at)) WHERE created_at > ?
+
+The hint is rendered as WITH (INDEX(...)) for SQL Server and
+USE INDEX (...) for MySQL, and must be added right after From
+and before any joins.
+Postgres does not support index hints, the call is a no-op
+that logs a warning.
+*/
+func (q *Stmt) UseIndex(name string) Builder {
+	if !q.dialect.Capabilities().IndexHints {
+		logger.KV(xlog.WARNING,
+			"reason", "index_hint_not_supported",
+			"provider", q.dialect.Provider(),
+			"index", name)
+		return q
+	}
+
+	if q.dialect.Provider() == "sqlserver" {
+		q.addChunk(posFrom, "", "WITH (INDEX("+name+"))", nil, " ")
+	} else {
+		q.addChunk(posFrom, "", "USE INDEX ("+name+")", nil, " ")
+	}
+	return q
+}
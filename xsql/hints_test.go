@@ -0,0 +1,30 @@
+package xsql_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseIndex(t *testing.T) {
+	q := xsql.SQLServer.From("orders o").
+		UseIndex("ix_orders_created_at").
+		Join("users u", "u.id = o.user_id").
+		Select("o.id").
+		Where("o.created_at > ?", 1)
+	defer q.Close()
+	assert.Equal(t, "SELECT o.id \nFROM orders o WITH (INDEX(ix_orders_created_at)) JOIN users u ON (u.id = o.user_id) \nWHERE o.created_at > ?", q.String())
+
+	q2 := xsql.MySQL.From("orders").
+		UseIndex("ix_orders_created_at").
+		Select("id")
+	defer q2.Close()
+	assert.Equal(t, "SELECT id \nFROM orders USE INDEX (ix_orders_created_at)", q2.String())
+
+	q3 := xsql.Postgres.From("orders").
+		UseIndex("ix_orders_created_at").
+		Select("id")
+	defer q3.Close()
+	assert.Equal(t, "SELECT id \nFROM orders", q3.String())
+}
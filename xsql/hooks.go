@@ -0,0 +1,145 @@
+package xsql
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueryOp defines the execution path of the statement
+type QueryOp string
+
+const (
+	// OpExec is used by Exec and ExecAndClose
+	OpExec QueryOp = "exec"
+	// OpQuery is used by Query and QueryAndClose
+	OpQuery QueryOp = "query"
+	// OpQueryRow is used by QueryRow and QueryRowAndClose
+	OpQueryRow QueryOp = "query_row"
+)
+
+// QueryEvent describes the statement executed by Exec, Query or QueryRow
+type QueryEvent struct {
+	Op QueryOp
+	// Name is the statement name, see SetName
+	Name string
+	// Provider is the provider of the dialect: postgres, sqlserver, etc
+	Provider string
+	SQL      string
+	Args     []any
+	// Started is the time the execution started
+	Started time.Time
+	// Duration and Err are set for AfterQuery
+	Duration time.Duration
+	Err      error
+}
+
+// Hook observes the statements executed via Executor methods,
+// for example to trace or to measure the queries
+type Hook interface {
+	// BeforeQuery is called before the statement is executed,
+	// the returned context is used to execute the statement and is passed to AfterQuery
+	BeforeQuery(ctx context.Context, e *QueryEvent) context.Context
+	// AfterQuery is called after the statement is executed,
+	// for Query the rows are iterated and closed
+	AfterQuery(ctx context.Context, e *QueryEvent)
+}
+
+type statementNameKey struct{}
+
+// WithStatementName returns a context with the statement name,
+// Exec, Query and QueryRow set the name specified by SetName, if the context does not have one
+func WithStatementName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, statementNameKey{}, name)
+}
+
+// StatementName returns the statement name from the context
+func StatementName(ctx context.Context) string {
+	name, _ := ctx.Value(statementNameKey{}).(string)
+	return name
+}
+
+var (
+	hooksLock sync.Mutex
+	hooks     atomic.Pointer[[]Hook]
+)
+
+// AddHook registers the hook for statements of all dialects,
+// and returns the function to remove the hook
+func AddHook(h Hook) (remove func()) {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+
+	var list []Hook
+	if cur := hooks.Load(); cur != nil {
+		list = append(list, *cur...)
+	}
+	list = append(list, h)
+	hooks.Store(&list)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { removeHook(h) })
+	}
+}
+
+func removeHook(h Hook) {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+
+	cur := hooks.Load()
+	if cur == nil {
+		return
+	}
+	var list []Hook
+	for _, x := range *cur {
+		if x != h {
+			list = append(list, x)
+		}
+	}
+	hooks.Store(&list)
+}
+
+// beforeQuery adds the statement name to the context, and calls BeforeQuery of the hooks,
+// returns nil event if no hooks are registered
+func (q *Stmt) beforeQuery(ctx context.Context, op QueryOp) (context.Context, *QueryEvent) {
+	if q.name != "" && StatementName(ctx) == "" {
+		ctx = WithStatementName(ctx, q.name)
+	}
+	list := hooks.Load()
+	if list == nil || len(*list) == 0 {
+		return ctx, nil
+	}
+	e := &QueryEvent{
+		Op:   op,
+		Name: q.name,
+		SQL:  q.String(),
+		// the arguments are released to the pool on Close
+		Args:    append([]any(nil), q.args...),
+		Started: time.Now(),
+	}
+	if q.dialect != nil {
+		e.Provider = q.dialect.Provider()
+	}
+	for _, h := range *list {
+		ctx = h.BeforeQuery(ctx, e)
+	}
+	return ctx, e
+}
+
+// afterQuery calls AfterQuery of the hooks in reverse order
+func afterQuery(ctx context.Context, e *QueryEvent, err error) {
+	if e == nil {
+		return
+	}
+	list := hooks.Load()
+	if list == nil {
+		return
+	}
+	e.Duration = time.Since(e.Started)
+	e.Err = err
+	for i := len(*list) - 1; i >= 0; i-- {
+		(*list)[i].AfterQuery(ctx, e)
+	}
+}
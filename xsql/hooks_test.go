@@ -0,0 +1,73 @@
+package xsql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey struct{}
+
+type recordingHook struct {
+	before []xsql.QueryEvent
+	after  []xsql.QueryEvent
+	ctx    []any
+}
+
+func (h *recordingHook) BeforeQuery(ctx context.Context, e *xsql.QueryEvent) context.Context {
+	h.before = append(h.before, *e)
+	return context.WithValue(ctx, ctxKey{}, e.Op)
+}
+
+func (h *recordingHook) AfterQuery(ctx context.Context, e *xsql.QueryEvent) {
+	h.after = append(h.after, *e)
+	h.ctx = append(h.ctx, ctx.Value(ctxKey{}))
+}
+
+func TestHooks(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	h := &recordingHook{}
+	remove := xsql.AddHook(h)
+	defer remove()
+
+	_, err = xsql.NoDialect.New("CREATE TABLE t (id INTEGER)").SetName("t.create").ExecAndClose(ctx, db)
+	require.NoError(t, err)
+
+	var id int
+	err = xsql.NoDialect.From("t").Select("id").To(&id).Where("id = ?", 1).QueryRowAndClose(ctx, db)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	err = xsql.NoDialect.From("missing").Select("id").QueryAndClose(ctx, db, nil)
+	require.Error(t, err)
+
+	require.Len(t, h.before, 3)
+	require.Len(t, h.after, 3)
+	assert.Equal(t, []any{xsql.OpExec, xsql.OpQueryRow, xsql.OpQuery}, h.ctx)
+
+	assert.Equal(t, xsql.OpExec, h.after[0].Op)
+	assert.Equal(t, "t.create", h.after[0].Name)
+	assert.Equal(t, "default", h.after[0].Provider)
+	assert.Equal(t, "CREATE TABLE t (id INTEGER)", h.after[0].SQL)
+	assert.NoError(t, h.after[0].Err)
+	assert.False(t, h.after[0].Started.IsZero())
+
+	assert.Equal(t, []any{1}, h.after[1].Args)
+	assert.Equal(t, sql.ErrNoRows, h.after[1].Err)
+	assert.Error(t, h.after[2].Err)
+	assert.Nil(t, h.before[2].Err)
+
+	remove()
+	remove()
+	_, err = xsql.NoDialect.New("DELETE FROM t").ExecAndClose(ctx, db)
+	require.NoError(t, err)
+	assert.Len(t, h.after, 3)
+}
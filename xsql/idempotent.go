@@ -0,0 +1,20 @@
+package xsql
+
+/*
+Idempotent marks the statement as read-only and safe to execute more than once.
+A provider with read replicas retries such statements on another replica,
+when the connection to the replica fails:
+
+	q := xsql.From("countries").
+		Select("code, name").
+		Idempotent()
+*/
+func (q *Stmt) Idempotent() Builder {
+	q.idempotent = true
+	return q
+}
+
+// IsIdempotent returns true if the statement was marked with Idempotent
+func (q *Stmt) IsIdempotent() bool {
+	return q.idempotent
+}
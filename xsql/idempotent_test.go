@@ -0,0 +1,25 @@
+package xsql_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotent(t *testing.T) {
+	q := xsql.From("users").Select("id")
+	assert.False(t, q.IsIdempotent())
+	q.Close()
+
+	q = xsql.From("users").Select("id").Idempotent()
+	assert.True(t, q.IsIdempotent())
+	q2 := q.Clone()
+	assert.True(t, q2.IsIdempotent())
+	q.Close()
+	q2.Close()
+
+	q = xsql.From("users").Select("id")
+	assert.False(t, q.IsIdempotent())
+	q.Close()
+}
@@ -0,0 +1,167 @@
+package xsql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonOps lists comparison operators supported by WhereJSON
+var jsonOps = map[string]bool{
+	"=":        true,
+	"<>":       true,
+	"!=":       true,
+	"<":        true,
+	"<=":       true,
+	">":        true,
+	">=":       true,
+	"LIKE":     true,
+	"NOT LIKE": true,
+}
+
+// jsonPath splits dotted path into segments
+func jsonPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		panic("empty JSON path")
+	}
+	// ? would be replaced with a placeholder on Postgres
+	if strings.Contains(path, "?") {
+		panic(fmt.Sprintf("unsupported JSON path: %q", path))
+	}
+	return strings.Split(path, ".")
+}
+
+func isIndex(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
+
+func isSimpleKey(s string) bool {
+	for i, r := range s {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return s != ""
+}
+
+// sqlString returns a quoted SQL string literal
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// jsonPathLiteral returns SQL/JSON path literal, such as '$.a.b[0]'
+func jsonPathLiteral(segments []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, s := range segments {
+		switch {
+		case isIndex(s):
+			b.WriteString("[" + s + "]")
+		case isSimpleKey(s):
+			b.WriteString("." + s)
+		default:
+			b.WriteString(".\"" + strings.ReplaceAll(s, "\"", "\\\"") + "\"")
+		}
+	}
+	return sqlString(b.String())
+}
+
+/*
+JSONValue returns an expression that extracts a scalar value as text
+from a JSON column by the dotted path:
+
+	xsql.Postgres.JSONValue("settings", "billing.plan")
+
+produces
+
+	settings->'billing'->>'plan'
+
+on Postgres and JSON_VALUE(settings, '$.billing.plan') on other dialects.
+Numeric segments are treated as array indexes.
+*/
+func (b *Dialect) JSONValue(column, path string) string {
+	segments := jsonPath(path)
+	switch b.provider {
+	case "postgres":
+		var e strings.Builder
+		e.WriteString(column)
+		last := len(segments) - 1
+		for i, s := range segments {
+			if i == last {
+				e.WriteString("->>")
+			} else {
+				e.WriteString("->")
+			}
+			if isIndex(s) {
+				e.WriteString(s)
+			} else {
+				e.WriteString(sqlString(s))
+			}
+		}
+		return e.String()
+	default:
+		return fmt.Sprintf("JSON_VALUE(%s, %s)", column, jsonPathLiteral(segments))
+	}
+}
+
+/*
+JSONExists returns a predicate that checks if the dotted path exists in a JSON column.
+
+On Postgres it renders jsonb_path_exists, instead of ? operator
+that clashes with placeholders. The column is cast to jsonb,
+as the function does not accept json columns:
+
+	jsonb_path_exists(settings::jsonb, '$.billing.plan')
+*/
+func (b *Dialect) JSONExists(column, path string) string {
+	lit := jsonPathLiteral(jsonPath(path))
+	switch b.provider {
+	case "postgres":
+		return fmt.Sprintf("jsonb_path_exists(%s::jsonb, %s)", column, lit)
+	default:
+		return fmt.Sprintf("(JSON_VALUE(%s, %s) IS NOT NULL OR JSON_QUERY(%s, %s) IS NOT NULL)", column, lit, column, lit)
+	}
+}
+
+/*
+WhereJSON adds a filter on a scalar value extracted from a JSON column:
+
+	xsql.Postgres.From("org").
+		Select("id").
+		WhereJSON("settings", "billing.plan", "=", "pro")
+
+produces
+
+	SELECT id FROM org WHERE settings->'billing'->>'plan' = $1
+*/
+func (q *Stmt) WhereJSON(column, path, op string, value any) Builder {
+	op = strings.ToUpper(strings.TrimSpace(op))
+	if !jsonOps[op] {
+		panic(fmt.Sprintf("unsupported JSON operator: %q", op))
+	}
+	q.Where(q.dialect.JSONValue(column, path)+" "+op+" ?", value)
+	return q
+}
+
+// WhereJSONExists adds a filter that checks if the path exists in a JSON column
+func (q *Stmt) WhereJSONExists(column, path string) Builder {
+	q.Where(q.dialect.JSONExists(column, path))
+	return q
+}
+
+/*
+JSONValue returns an expression that extracts a scalar value as text
+from a JSON column, using the default dialect.
+*/
+func JSONValue(column, path string) string {
+	return defaultDialect.Load().(SQLDialect).JSONValue(column, path)
+}
+
+// JSONExists returns a predicate that checks if the path exists in a JSON column,
+// using the default dialect.
+func JSONExists(column, path string) string {
+	return defaultDialect.Load().(SQLDialect).JSONExists(column, path)
+}
@@ -0,0 +1,47 @@
+package xsql_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONValue(t *testing.T) {
+	assert.Equal(t, "settings->>'plan'", xsql.Postgres.JSONValue("settings", "plan"))
+	assert.Equal(t, "settings->'billing'->>'plan'", xsql.Postgres.JSONValue("settings", "$.billing.plan"))
+	assert.Equal(t, "settings->'items'->0->>'it''s'", xsql.Postgres.JSONValue("settings", "items.0.it's"))
+	assert.Equal(t, "JSON_VALUE(settings, '$.billing.plan')", xsql.SQLServer.JSONValue("settings", "billing.plan"))
+	assert.Equal(t, `JSON_VALUE(settings, '$.items[0]."my key"')`, xsql.SQLServer.JSONValue("settings", "items.0.my key"))
+	assert.Equal(t, "JSON_VALUE(settings, '$.plan')", xsql.JSONValue("settings", "plan"))
+
+	assert.Panics(t, func() { xsql.Postgres.JSONValue("settings", "") })
+	assert.Panics(t, func() { xsql.Postgres.JSONValue("settings", "a?b") })
+}
+
+func TestJSONExists(t *testing.T) {
+	assert.Equal(t, "jsonb_path_exists(settings::jsonb, '$.billing.plan')", xsql.Postgres.JSONExists("settings", "billing.plan"))
+	assert.Equal(t, "(JSON_VALUE(settings, '$.plan') IS NOT NULL OR JSON_QUERY(settings, '$.plan') IS NOT NULL)", xsql.SQLServer.JSONExists("settings", "plan"))
+	assert.Equal(t, "(JSON_VALUE(s, '$.a') IS NOT NULL OR JSON_QUERY(s, '$.a') IS NOT NULL)", xsql.JSONExists("s", "a"))
+}
+
+func TestWhereJSON(t *testing.T) {
+	q := xsql.Postgres.From("org").
+		Select("id").
+		Where("id > ?", 1).
+		WhereJSON("settings", "billing.plan", "=", "pro").
+		WhereJSONExists("quota", "users")
+	defer q.Close()
+	assert.Equal(t, "SELECT id \nFROM org \nWHERE id > $1 AND settings->'billing'->>'plan' = $2 AND jsonb_path_exists(quota::jsonb, '$.users')", q.String())
+	assert.Equal(t, []any{1, "pro"}, q.Args())
+
+	q2 := xsql.SQLServer.From("org").
+		Select("id").
+		WhereJSON("settings", "billing.plan", "like", "pro%")
+	defer q2.Close()
+	assert.Equal(t, "SELECT id \nFROM org \nWHERE JSON_VALUE(settings, '$.billing.plan') LIKE ?", q2.String())
+
+	assert.Panics(t, func() {
+		xsql.Postgres.From("org").WhereJSON("settings", "plan", "; DROP", 1)
+	})
+}
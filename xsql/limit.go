@@ -0,0 +1,198 @@
+package xsql
+
+import (
+	"strings"
+)
+
+/*
+Unbounded marks SELECT statement as intentionally reading all rows.
+A provider configured with the maximum number of rows
+does not append the LIMIT clause to such statements:
+
+	q := xsql.From("countries").
+		Select("code, name").
+		Unbounded()
+*/
+func (q *Stmt) Unbounded() Builder {
+	q.unbounded = true
+	return q
+}
+
+// IsUnbounded returns true if the statement was marked with Unbounded
+func (q *Stmt) IsUnbounded() bool {
+	return q.unbounded
+}
+
+// IsSelect returns true if the statement is a SELECT statement,
+// INSERT ... SELECT statements are not considered as SELECT
+func (q *Stmt) IsSelect() bool {
+	isSelect := false
+	for _, chunk := range q.chunks {
+		switch chunk.pos {
+		case posInsert, posUpdate, posDelete:
+			return false
+		case posSelect:
+			isSelect = true
+		}
+	}
+	return isSelect
+}
+
+// HasLimit returns true if the statement has a LIMIT clause
+func (q *Stmt) HasLimit() bool {
+	for _, chunk := range q.chunks {
+		if chunk.pos == posLimit {
+			return true
+		}
+	}
+	return false
+}
+
+// PageArgs returns the values bound to LIMIT and OFFSET clauses,
+// or nil if the clause is not set
+func (q *Stmt) PageArgs() (limit any, offset any) {
+	argNo := 0
+	for _, chunk := range q.chunks {
+		if chunk.argLen > 0 {
+			switch chunk.pos {
+			case posLimit:
+				limit = q.args[argNo]
+			case posOffset:
+				offset = q.args[argNo]
+			}
+		}
+		argNo += chunk.argLen
+	}
+	return limit, offset
+}
+
+/*
+CapRows returns a copy of the statement limited to the number of rows,
+the statement itself is not changed:
+
+	q := xsql.Postgres.From("users").Select("id").Where("org_id = ?", 1)
+	capped := q.CapRows(1000)
+
+produces
+
+	SELECT id FROM users WHERE org_id = $1 LIMIT $2
+
+For SQL Server the limit is rendered as TOP,
+or as OFFSET ... FETCH for UNION statements and the statements with OFFSET,
+as TOP can't be combined with OFFSET:
+
+	SELECT TOP (?) id FROM users WHERE org_id = ?
+
+The copy is not named, as its SQL differs from the named statement,
+and should be closed separately.
+*/
+func (q *Stmt) CapRows(limit uint32) Builder {
+	if q.dialect.Provider() != "sqlserver" {
+		return q.Clone().Limit(limit)
+	}
+
+	var hasUnion, hasOffset, hasOrderBy bool
+	for _, chunk := range q.chunks {
+		switch chunk.pos {
+		case posUnion:
+			hasUnion = true
+		case posOffset:
+			hasOffset = true
+		case posOrderBy:
+			hasOrderBy = true
+		}
+	}
+
+	if hasOffset {
+		return q.withFetch(limit)
+	}
+	if !hasUnion {
+		if stmt := q.withTop(limit); stmt != nil {
+			return stmt
+		}
+	}
+
+	stmt := q.Clone().(*Stmt)
+	if !hasOrderBy {
+		stmt.addChunk(posOrderBy, "ORDER BY", "(SELECT NULL)", nil, ", ")
+	}
+	stmt.addChunk(posLimit, "OFFSET 0 ROWS FETCH NEXT ? ROWS ONLY", "", []any{limit}, "")
+	return stmt
+}
+
+// withFetch returns a copy of the statement with FETCH NEXT after OFFSET
+func (q *Stmt) withFetch(limit uint32) *Stmt {
+	stmt := q.Clone().(*Stmt)
+	stmt.buf.Reset()
+	stmt.args = stmt.args[:0]
+
+	argNo := 0
+	for i := range stmt.chunks {
+		chunk := &stmt.chunks[i]
+		args := q.args[argNo : argNo+chunk.argLen]
+		argNo += chunk.argLen
+
+		s := string(q.buf.B[chunk.bufLow:chunk.bufHigh])
+		if chunk.pos == posOffset {
+			s = strings.TrimRight(s, "\n\r\t ")
+			if upper := strings.ToUpper(s); !strings.HasSuffix(upper, " ROWS") && !strings.HasSuffix(upper, " ROW") {
+				s += " ROWS"
+			}
+			s += " FETCH NEXT ? ROWS ONLY"
+			args = append(append([]any{}, args...), limit)
+			chunk.argLen++
+		}
+
+		chunk.bufLow = len(stmt.buf.B)
+		stmt.WriteString(s)
+		chunk.bufHigh = len(stmt.buf.B)
+		stmt.args = append(stmt.args, args...)
+	}
+	stmt.Invalidate()
+	return stmt
+}
+
+// withTop returns a copy of the statement with TOP after SELECT or SELECT DISTINCT,
+// or nil if the statement does not start with SELECT
+func (q *Stmt) withTop(limit uint32) *Stmt {
+	stmt := q.dialect.(*Dialect).getStmt()
+	stmt.useNewLines = q.useNewLines
+	stmt.unbounded = q.unbounded
+	stmt.idempotent = q.idempotent
+
+	added := false
+	argNo := 0
+	for _, chunk := range q.chunks {
+		args := q.args[argNo : argNo+chunk.argLen]
+		argNo += chunk.argLen
+
+		s := string(q.buf.B[chunk.bufLow:chunk.bufHigh])
+		if chunk.pos == posSelect && !added {
+			trimmed := strings.TrimLeft(s, "\n\r\t ")
+			verb := len("SELECT")
+			if len(trimmed) < verb || !strings.EqualFold(trimmed[:verb], "SELECT") {
+				stmt.Close()
+				return nil
+			}
+			if rest := strings.ToUpper(trimmed[verb:]); strings.HasPrefix(rest, " DISTINCT") {
+				verb += len(" DISTINCT")
+			}
+			s = s[:len(s)-len(trimmed)] + trimmed[:verb] + " TOP (?)" + trimmed[verb:]
+			args = append([]any{limit}, args...)
+			chunk.argLen++
+			added = true
+		}
+
+		bufLow := len(stmt.buf.B)
+		stmt.WriteString(s)
+		chunk.bufLow = bufLow
+		chunk.bufHigh = len(stmt.buf.B)
+		stmt.chunks = append(stmt.chunks, chunk)
+		stmt.args = append(stmt.args, args...)
+	}
+	if !added {
+		stmt.Close()
+		return nil
+	}
+	return stmt
+}
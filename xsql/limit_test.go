@@ -0,0 +1,128 @@
+package xsql_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitMetadata(t *testing.T) {
+	q := xsql.From("users").Select("id")
+	assert.True(t, q.IsSelect())
+	assert.False(t, q.HasLimit())
+	assert.False(t, q.IsUnbounded())
+
+	q.Limit(10)
+	assert.True(t, q.HasLimit())
+
+	q2 := q.Clone()
+	assert.True(t, q2.HasLimit())
+	q.Close()
+	q2.Close()
+
+	q = xsql.From("users").Select("id").Unbounded()
+	assert.True(t, q.IsUnbounded())
+	q2 = q.Clone()
+	assert.True(t, q2.IsUnbounded())
+	q.Close()
+	q2.Close()
+
+	q = xsql.From("users").Select("id")
+	assert.False(t, q.IsUnbounded())
+	q.Close()
+
+	q = xsql.InsertInto("users").Select("id").From("old_users")
+	assert.False(t, q.IsSelect())
+	q.Close()
+
+	q = xsql.Update("users").Set("name", "x")
+	assert.False(t, q.IsSelect())
+	q.Close()
+
+	q = xsql.DeleteFrom("users")
+	assert.False(t, q.IsSelect())
+	q.Close()
+}
+
+func TestPageArgs(t *testing.T) {
+	q := xsql.From("users").Select("id").Where("org_id = ?", 1).Where("name <> ?", "")
+	defer q.Close()
+
+	limit, offset := q.PageArgs()
+	assert.Nil(t, limit)
+	assert.Nil(t, offset)
+
+	q.Limit(10)
+	limit, offset = q.PageArgs()
+	assert.Equal(t, 10, limit)
+	assert.Nil(t, offset)
+
+	q.Offset(uint32(20))
+	limit, offset = q.PageArgs()
+	assert.Equal(t, 10, limit)
+	assert.Equal(t, uint32(20), offset)
+}
+
+func TestCapRows(t *testing.T) {
+	q := xsql.NoDialect.From("users").Select("id").Where("org_id = ?", 1).SetName("ListUsers")
+	defer q.Close()
+	sql := q.String()
+
+	capped := q.CapRows(100)
+	assert.Equal(t, "SELECT id \nFROM users \nWHERE org_id = ? \nLIMIT ?", capped.String())
+	assert.Equal(t, []any{1, uint32(100)}, capped.Args())
+	assert.Empty(t, capped.Name())
+	capped.Close()
+
+	// the statement is not changed
+	assert.Equal(t, sql, q.String())
+	assert.Equal(t, []any{1}, q.Args())
+	assert.False(t, q.HasLimit())
+
+	pq := xsql.Postgres.From("users").Select("id").Where("org_id = ?", 1)
+	defer pq.Close()
+	capped = pq.CapRows(100)
+	assert.Equal(t, "SELECT id \nFROM users \nWHERE org_id = $1 \nLIMIT $2", capped.String())
+	capped.Close()
+}
+
+func TestCapRowsSQLServer(t *testing.T) {
+	tcases := []struct {
+		q    xsql.Builder
+		sql  string
+		args []any
+	}{
+		{
+			q:    xsql.SQLServer.From("users").Select("id").Where("org_id = ?", 1),
+			sql:  "SELECT TOP (?) id \nFROM users \nWHERE org_id = ?",
+			args: []any{uint32(100), 1},
+		},
+		{
+			q:    xsql.SQLServer.From("users").Select("DISTINCT org_id").OrderBy("org_id"),
+			sql:  "SELECT DISTINCT TOP (?) org_id \nFROM users \nORDER BY org_id",
+			args: []any{uint32(100)},
+		},
+		{
+			q: xsql.SQLServer.From("users").Select("id").Where("org_id = ?", 1).
+				Union(false, xsql.SQLServer.From("admins").Select("id")),
+			sql:  "SELECT id \nFROM users \nWHERE org_id = ? \nUNION SELECT id \nFROM admins \nORDER BY (SELECT NULL) \nOFFSET 0 ROWS FETCH NEXT ? ROWS ONLY",
+			args: []any{1, uint32(100)},
+		},
+		{
+			q:    xsql.SQLServer.From("users").Select("id").Where("org_id = ?", 1).OrderBy("id").Offset(20),
+			sql:  "SELECT id \nFROM users \nWHERE org_id = ? \nORDER BY id \nOFFSET ? ROWS FETCH NEXT ? ROWS ONLY",
+			args: []any{1, 20, uint32(100)},
+		},
+	}
+	for _, tc := range tcases {
+		args := append([]any{}, tc.q.Args()...)
+		capped := tc.q.CapRows(100)
+		assert.Equal(t, tc.sql, capped.String())
+		assert.Equal(t, tc.args, capped.Args())
+		capped.Close()
+		// the statement is not changed
+		assert.Equal(t, args, tc.q.Args())
+		tc.q.Close()
+	}
+}
@@ -0,0 +1,101 @@
+package xsql
+
+import (
+	"strings"
+
+	"github.com/effective-security/xlog"
+)
+
+// LockOption specifies an option of the locking clause, see ForUpdate
+type LockOption func(*lockOptions)
+
+type lockOptions struct {
+	of         []string
+	noWait     bool
+	skipLocked bool
+}
+
+// LockOf limits the locking to the rows of the tables, FOR UPDATE OF ...
+// It's not supported by SQL Server, where the hint applies to the table in FROM clause.
+func LockOf(tables ...string) LockOption {
+	return func(o *lockOptions) {
+		o.of = append(o.of, tables...)
+	}
+}
+
+// NoWait reports an error instead of waiting for the locked rows,
+// FOR UPDATE NOWAIT or NOWAIT hint on SQL Server
+func NoWait() LockOption {
+	return func(o *lockOptions) {
+		o.noWait = true
+	}
+}
+
+// SkipLocked skips the locked rows,
+// FOR UPDATE SKIP LOCKED or READPAST hint on SQL Server
+func SkipLocked() LockOption {
+	return func(o *lockOptions) {
+		o.skipLocked = true
+		o.noWait = false
+	}
+}
+
+/*
+ForUpdate locks the selected rows for update until the end of the transaction:
+
+	xsql.Postgres.From("jobs").
+		Select("id").
+		Where("status = ?", "pending").
+		OrderBy("id").
+		Limit(10).
+		ForUpdate(xsql.SkipLocked())
+
+produces
+
+	SELECT id FROM jobs WHERE status = $1 ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED
+
+For SQL Server the lock is rendered as WITH (UPDLOCK, ROWLOCK) table hint,
+with READPAST or NOWAIT for the options,
+and must be added right after From and before any joins:
+
+	SELECT TOP 10 id FROM jobs WITH (UPDLOCK, ROWLOCK, READPAST) WHERE status = ?
+
+Use UseIndex or ForUpdate for a table on SQL Server, as the table hints
+are not merged.
+SkipLocked is a no-op that logs a warning if the dialect does not support it.
+*/
+func (q *Stmt) ForUpdate(opts ...LockOption) Builder {
+	o := &lockOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.skipLocked && !q.dialect.Capabilities().SkipLocked {
+		logger.KV(xlog.WARNING,
+			"reason", "skip_locked_not_supported",
+			"provider", q.dialect.Provider())
+		o.skipLocked = false
+	}
+
+	if q.dialect.Provider() == "sqlserver" {
+		hints := "UPDLOCK, ROWLOCK"
+		if o.skipLocked {
+			hints += ", READPAST"
+		} else if o.noWait {
+			hints += ", NOWAIT"
+		}
+		q.addChunk(posFrom, "", "WITH ("+hints+")", nil, " ")
+		return q
+	}
+
+	clause := "FOR UPDATE"
+	if len(o.of) > 0 {
+		clause += " OF " + strings.Join(o.of, ", ")
+	}
+	if o.skipLocked {
+		clause += " SKIP LOCKED"
+	} else if o.noWait {
+		clause += " NOWAIT"
+	}
+	q.addChunk(posLock, clause, "", nil, "")
+	return q
+}
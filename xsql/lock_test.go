@@ -0,0 +1,53 @@
+package xsql_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForUpdate(t *testing.T) {
+	q := xsql.Postgres.From("jobs").
+		Select("id").
+		Where("status = ?", "pending").
+		OrderBy("id").
+		Limit(10).
+		ForUpdate(xsql.SkipLocked())
+	defer q.Close()
+	assert.Equal(t, "SELECT id \nFROM jobs \nWHERE status = $1 \nORDER BY id \nLIMIT $2 \nFOR UPDATE SKIP LOCKED", q.String())
+
+	q2 := xsql.Postgres.From("jobs j").
+		Join("tasks t", "t.job_id = j.id").
+		Select("j.id").
+		ForUpdate(xsql.LockOf("j"), xsql.NoWait()).
+		Where("j.id = ?", 1)
+	defer q2.Close()
+	assert.Equal(t, "SELECT j.id \nFROM jobs j JOIN tasks t ON (t.job_id = j.id) \nWHERE j.id = $1 \nFOR UPDATE OF j NOWAIT", q2.String())
+
+	q3 := xsql.MySQL.From("jobs").
+		Select("id").
+		ForUpdate()
+	defer q3.Close()
+	assert.Equal(t, "SELECT id \nFROM jobs \nFOR UPDATE", q3.String())
+
+	q4 := xsql.SQLServer.From("jobs").
+		ForUpdate(xsql.SkipLocked()).
+		Select("id").
+		Where("status = ?", "pending")
+	defer q4.Close()
+	assert.Equal(t, "SELECT id \nFROM jobs WITH (UPDLOCK, ROWLOCK, READPAST) \nWHERE status = ?", q4.String())
+
+	q5 := xsql.SQLServer.From("jobs").
+		ForUpdate(xsql.NoWait()).
+		Select("id")
+	defer q5.Close()
+	assert.Equal(t, "SELECT id \nFROM jobs WITH (UPDLOCK, ROWLOCK, NOWAIT)", q5.String())
+
+	// SKIP LOCKED is not supported
+	q6 := xsql.NoDialect.From("jobs").
+		Select("id").
+		ForUpdate(xsql.SkipLocked())
+	defer q6.Close()
+	assert.Equal(t, "SELECT id \nFROM jobs \nFOR UPDATE", q6.String())
+}
@@ -0,0 +1,281 @@
+package xsql
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// paramRef is a reference to a parameter in SQL,
+// name is empty for ? placeholder
+type paramRef struct {
+	start  int
+	end    int
+	name   string
+	quoted bool
+}
+
+// findParams returns ? placeholders, :name and @name parameters in the order of appearance.
+// The names in quoted strings, :: casts, @@ variables and escaped \? are skipped.
+func findParams(s string) []paramRef {
+	var refs []paramRef
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			} else if c == '?' {
+				// ? placeholders are replaced regardless of quotes, see writePg
+				refs = append(refs, paramRef{start: i, end: i + 1, quoted: true})
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"':
+			quote = c
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '?' {
+				i++
+			}
+		case '?':
+			refs = append(refs, paramRef{start: i, end: i + 1})
+		case ':', '@':
+			if i+1 < len(s) && s[i+1] == c {
+				// :: cast or @@ variable
+				i++
+				for i+1 < len(s) && isNameChar(s[i+1]) {
+					i++
+				}
+				continue
+			}
+			if i > 0 && isNameChar(s[i-1]) {
+				continue
+			}
+			end := i + 1
+			for end < len(s) && isNameChar(s[end]) {
+				end++
+			}
+			if end == i+1 || (s[i+1] >= '0' && s[i+1] <= '9') {
+				continue
+			}
+			refs = append(refs, paramRef{start: i, end: end, name: s[i+1 : end]})
+			i = end - 1
+		}
+	}
+	return refs
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// namedValues returns the lookup function of the parameter values
+// from a map with string keys, a struct or a pointer to struct with "db" tags
+func namedValues(params any) (func(name string) (any, bool), error) {
+	if m, ok := params.(map[string]any); ok {
+		return func(name string) (any, bool) {
+			v, ok := m[name]
+			return v, ok
+		}, nil
+	}
+
+	v := reflect.ValueOf(params)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, errors.Errorf("named parameters must not be nil")
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, errors.Errorf("unsupported type of named parameters: %T", params)
+		}
+		return func(name string) (any, bool) {
+			val := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !val.IsValid() {
+				return nil, false
+			}
+			return val.Interface(), true
+		}, nil
+	case reflect.Struct:
+		fields, err := getStructFields(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		return func(name string) (any, bool) {
+			index, ok := fields.index[strings.ToLower(name)]
+			if !ok {
+				return nil, false
+			}
+			return v.FieldByIndex(index).Interface(), true
+		}, nil
+	default:
+		return nil, errors.Errorf("unsupported type of named parameters: %T", params)
+	}
+}
+
+/*
+BindNamed returns the query with :name and @name parameters replaced
+by the placeholders of the dialect, and the arguments from params.
+The params is map[string]any, or a struct with "db" tags:
+
+	query, args, err := xsql.Postgres.BindNamed(
+		"SELECT id FROM users WHERE org_id = :org AND (name = :name OR email = :name)",
+		map[string]any{"org": 1, "name": "bob"})
+
+produces
+
+	SELECT id FROM users WHERE org_id = $1 AND (name = $2 OR email = $2)
+
+For SQL Server the parameters are rendered as @name with sql.Named arguments,
+and for other dialects as ? placeholders with the argument per occurrence.
+An error is returned if a parameter is missing in params,
+or if the query has ? placeholders, that can not be mixed with the named parameters.
+*/
+func (b *Dialect) BindNamed(query string, params any) (string, []any, error) {
+	lookup, err := namedValues(params)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buf strings.Builder
+	var args []any
+	index := map[string]int{}
+	start := 0
+	for _, ref := range findParams(query) {
+		if ref.name == "" {
+			if !ref.quoted {
+				return "", nil, errors.Errorf("positional and named parameters can not be mixed")
+			}
+			continue
+		}
+		v, ok := lookup(ref.name)
+		if !ok {
+			return "", nil, errors.Errorf("missing named parameter: %s", ref.name)
+		}
+		buf.WriteString(query[start:ref.start])
+		start = ref.end
+
+		n, seen := index[ref.name]
+		switch b.provider {
+		case "postgres":
+			if !seen {
+				args = append(args, v)
+				n = len(args)
+				index[ref.name] = n
+			}
+			buf.WriteByte('$')
+			buf.WriteString(strconv.Itoa(n))
+		case "sqlserver":
+			if !seen {
+				args = append(args, sql.Named(ref.name, v))
+				index[ref.name] = len(args)
+			}
+			buf.WriteByte('@')
+			buf.WriteString(ref.name)
+		default:
+			args = append(args, v)
+			buf.WriteByte('?')
+		}
+	}
+	buf.WriteString(query[start:])
+	return buf.String(), args, nil
+}
+
+/*
+BindNamed returns the query with :name and @name parameters replaced
+by the placeholders, and the arguments from params,
+using the default dialect.
+*/
+func BindNamed(query string, params any) (string, []any, error) {
+	return defaultDialect.Load().(SQLDialect).BindNamed(query, params)
+}
+
+/*
+BindParams binds :name and @name parameters of the statement to the values
+from params, map[string]any or a struct with "db" tags:
+
+	q := xsql.From("users").
+		Select("id").To(&id).
+		Where("org_id = :org").
+		Where("name = :name OR email = :name").
+		BindParams(map[string]any{"org": orgID, "name": name})
+
+The parameters are rendered as ? placeholders bound in the order of appearance,
+and as @name with sql.Named arguments for SQL Server.
+Call BindParams after the clauses with the parameters are added,
+the names missing in params are left unchanged.
+
+The error of invalid params is recorded on the statement,
+and returned by Query, QueryRow and Exec, see Err.
+*/
+func (q *Stmt) BindParams(params any) Builder {
+	lookup, err := namedValues(params)
+	if err != nil {
+		q.err = err
+		return q
+	}
+	sqlServer := q.dialect.Provider() == "sqlserver"
+
+	buf := getBuffer()
+	args := make([]any, 0, len(q.args))
+	seen := map[string]bool{}
+	argNo := 0
+	for i := range q.chunks {
+		chunk := &q.chunks[i]
+		s := string(q.buf.B[chunk.bufLow:chunk.bufHigh])
+		chunkArgs := q.args[argNo : argNo+chunk.argLen]
+		argNo += chunk.argLen
+
+		bufLow := len(buf.B)
+		argLen := len(args)
+		used := 0
+		var named []any
+		start := 0
+		for _, ref := range findParams(s) {
+			if ref.name == "" {
+				if used < len(chunkArgs) {
+					args = append(args, chunkArgs[used])
+					used++
+				}
+				continue
+			}
+			v, ok := lookup(ref.name)
+			if !ok {
+				continue
+			}
+			_, _ = buf.WriteString(s[start:ref.start])
+			start = ref.end
+			if sqlServer {
+				_, _ = buf.WriteString("@" + ref.name)
+				if !seen[ref.name] {
+					seen[ref.name] = true
+					named = append(named, sql.Named(ref.name, v))
+				}
+				continue
+			}
+			_ = buf.WriteByte('?')
+			args = append(args, v)
+		}
+		_, _ = buf.WriteString(s[start:])
+		// the arguments without placeholders, such as sql.Named, follow
+		args = append(args, chunkArgs[used:]...)
+		args = append(args, named...)
+
+		chunk.bufLow = bufLow
+		chunk.bufHigh = len(buf.B)
+		chunk.argLen = len(args) - argLen
+	}
+
+	putBuffer(q.buf)
+	q.buf = buf
+	q.args = args
+	q.Invalidate()
+	return q
+}
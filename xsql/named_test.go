@@ -0,0 +1,131 @@
+package xsql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindNamed(t *testing.T) {
+	query := "SELECT id FROM users WHERE org_id = :org AND (name = @name OR email = :name) AND created::date > ':skip' AND @@ROWCOUNT > 0"
+	params := map[string]any{"org": 1, "name": "bob"}
+
+	s, args, err := xsql.Postgres.BindNamed(query, params)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users WHERE org_id = $1 AND (name = $2 OR email = $2) AND created::date > ':skip' AND @@ROWCOUNT > 0", s)
+	assert.Equal(t, []any{1, "bob"}, args)
+
+	s, args, err = xsql.SQLServer.BindNamed(query, params)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users WHERE org_id = @org AND (name = @name OR email = @name) AND created::date > ':skip' AND @@ROWCOUNT > 0", s)
+	assert.Equal(t, []any{sql.Named("org", 1), sql.Named("name", "bob")}, args)
+
+	s, args, err = xsql.NoDialect.BindNamed(query, params)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users WHERE org_id = ? AND (name = ? OR email = ?) AND created::date > ':skip' AND @@ROWCOUNT > 0", s)
+	assert.Equal(t, []any{1, "bob", "bob"}, args)
+
+	type filter struct {
+		OrgID int64  `db:"org"`
+		Name  string `db:"name"`
+	}
+	s, args, err = xsql.Postgres.BindNamed("SELECT id FROM users WHERE org_id = :org AND name = :Name", &filter{OrgID: 2, Name: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users WHERE org_id = $1 AND name = $2", s)
+	assert.Equal(t, []any{int64(2), "alice"}, args)
+
+	_, _, err = xsql.Postgres.BindNamed(query, map[string]any{"org": 1})
+	assert.EqualError(t, err, "missing named parameter: name")
+	_, _, err = xsql.Postgres.BindNamed(query, 1)
+	assert.EqualError(t, err, "unsupported type of named parameters: int")
+	_, _, err = xsql.Postgres.BindNamed(query, (*filter)(nil))
+	assert.EqualError(t, err, "named parameters must not be nil")
+
+	for _, d := range []xsql.SQLDialect{xsql.Postgres, xsql.SQLServer, xsql.NoDialect} {
+		_, _, err = d.BindNamed("SELECT id FROM users WHERE org_id = :org AND name = ?", params)
+		assert.EqualError(t, err, "positional and named parameters can not be mixed")
+	}
+	// quoted and escaped ? are not placeholders
+	s, args, err = xsql.Postgres.BindNamed(`SELECT id FROM users WHERE org_id = :org AND name <> 'who?' AND data \? 'key'`, params)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT id FROM users WHERE org_id = $1 AND name <> 'who?' AND data \? 'key'`, s)
+	assert.Equal(t, []any{1}, args)
+}
+
+func TestBindParams(t *testing.T) {
+	params := map[string]any{"org": 1, "name": "bob"}
+
+	q := xsql.Postgres.From("users").
+		Select("id").
+		Where("status = ?", "active").
+		Where("org_id = :org").
+		Where("(name = :name OR email = :name)").
+		OrderBy("id").
+		BindParams(params)
+	defer q.Close()
+	assert.Equal(t, "SELECT id \nFROM users \nWHERE status = $1 AND org_id = $2 AND (name = $3 OR email = $4) \nORDER BY id", q.String())
+	assert.Equal(t, []any{"active", 1, "bob", "bob"}, q.Args())
+
+	// the clauses can be added after the parameters are bound
+	q.Where("id > ?", 10)
+	assert.Equal(t, "SELECT id \nFROM users \nWHERE status = $1 AND org_id = $2 AND (name = $3 OR email = $4) AND id > $5 \nORDER BY id", q.String())
+	assert.Equal(t, []any{"active", 1, "bob", "bob", 10}, q.Args())
+
+	q2 := xsql.SQLServer.From("users").
+		Select("id").
+		Where("status = ?", "active").
+		Where("org_id = :org AND name = @name OR email = :name").
+		Where("id <> :missing").
+		BindParams(params)
+	defer q2.Close()
+	assert.Equal(t, "SELECT id \nFROM users \nWHERE status = ? AND org_id = @org AND name = @name OR email = @name AND id <> :missing", q2.String())
+	assert.Equal(t, []any{"active", sql.Named("org", 1), sql.Named("name", "bob")}, q2.Args())
+}
+
+func TestBindParamsError(t *testing.T) {
+	ctx := context.Background()
+	var id int
+	q := xsql.NoDialect.From("users").
+		Select("id").To(&id).
+		Where("org_id = :org").
+		BindParams(1)
+	defer q.Close()
+	assert.EqualError(t, q.Err(), "unsupported type of named parameters: int")
+
+	// the statement is not executed
+	assert.EqualError(t, q.QueryRow(ctx, nil), "unsupported type of named parameters: int")
+	assert.EqualError(t, q.Query(ctx, nil, nil), "unsupported type of named parameters: int")
+	_, err := q.Exec(ctx, nil)
+	assert.EqualError(t, err, "unsupported type of named parameters: int")
+
+	q2 := q.Clone()
+	defer q2.Close()
+	assert.Error(t, q2.Err())
+}
+
+func TestBindParamsExec(t *testing.T) {
+	type filter struct {
+		Min float64 `db:"min"`
+		Max float64 `db:"max"`
+	}
+
+	forEveryDB(t, func(ctx context.Context, env *dbEnv) {
+		var amount float64
+		var list []float64
+		err := env.xsql.From("incomes").
+			Select("amount").To(&amount).
+			Where("amount >= :min").
+			Where("amount <= :max").
+			OrderBy("amount").
+			BindParams(filter{Min: 200, Max: 500}).
+			QueryAndClose(ctx, env.db, func(_ *sql.Rows) {
+				list = append(list, amount)
+			})
+		require.NoError(t, err)
+		assert.Equal(t, []float64{200, 350, 400, 500}, list)
+	})
+}
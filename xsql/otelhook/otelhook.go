@@ -0,0 +1,86 @@
+// Package otelhook provides OpenTelemetry tracing of the statements executed by xsql
+package otelhook
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/effective-security/xdb/xsql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer of the hook
+const TracerName = "github.com/effective-security/xdb/xsql"
+
+// Hook starts a client span for every statement executed by xsql
+type Hook struct {
+	tracer trace.Tracer
+	// withArgs specifies to record the statement arguments
+	withArgs bool
+}
+
+/*
+New returns the tracing hook, nil provider uses the global tracer provider:
+
+	remove := xsql.AddHook(otelhook.New(nil))
+	defer remove()
+
+The span is named by the statement name, see Builder.SetName,
+or by the operation for unnamed statements.
+*/
+func New(tp trace.TracerProvider) *Hook {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Hook{tracer: tp.Tracer(TracerName)}
+}
+
+// WithArgs enables recording of the statement arguments,
+// do not enable it if the arguments contain sensitive data
+func (h *Hook) WithArgs() *Hook {
+	h.withArgs = true
+	return h
+}
+
+// BeforeQuery starts the span
+func (h *Hook) BeforeQuery(ctx context.Context, e *xsql.QueryEvent) context.Context {
+	name := e.Name
+	if name == "" {
+		name = "xsql." + string(e.Op)
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", e.Provider),
+		attribute.String("db.operation", string(e.Op)),
+		attribute.String("db.statement", e.SQL),
+	}
+	if e.Name != "" {
+		attrs = append(attrs, attribute.String("db.statement.name", e.Name))
+	}
+	if h.withArgs && len(e.Args) > 0 {
+		args := make([]string, len(e.Args))
+		for i, a := range e.Args {
+			args[i] = toString(a)
+		}
+		attrs = append(attrs, attribute.StringSlice("db.statement.args", args))
+	}
+
+	ctx, _ = h.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(e.Started),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx
+}
+
+// AfterQuery ends the span, and records the error
+func (h *Hook) AfterQuery(ctx context.Context, e *xsql.QueryEvent) {
+	span := trace.SpanFromContext(ctx)
+	if e.Err != nil && e.Err != sql.ErrNoRows {
+		span.RecordError(e.Err)
+		span.SetStatus(codes.Error, e.Err.Error())
+	}
+	span.End(trace.WithTimestamp(e.Started.Add(e.Duration)))
+}
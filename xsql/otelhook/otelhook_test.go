@@ -0,0 +1,87 @@
+package otelhook_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/effective-security/xdb/xsql/otelhook"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type span struct {
+	noop.Span
+	name   string
+	attrs  []attribute.KeyValue
+	status codes.Code
+	ended  bool
+}
+
+func (s *span) SetStatus(code codes.Code, _ string) { s.status = code }
+func (s *span) End(...trace.SpanEndOption)          { s.ended = true }
+
+type tracer struct {
+	noop.Tracer
+	spans []*span
+}
+
+func (t *tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	s := &span{name: name, attrs: cfg.Attributes()}
+	t.spans = append(t.spans, s)
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+type provider struct {
+	noop.TracerProvider
+	tracer *tracer
+}
+
+func (p *provider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return p.tracer
+}
+
+func TestHook(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	tp := &provider{tracer: &tracer{}}
+	remove := xsql.AddHook(otelhook.New(tp).WithArgs())
+	defer remove()
+
+	_, err = xsql.NoDialect.New("CREATE TABLE t (id INTEGER)").SetName("t.create").ExecAndClose(ctx, db)
+	require.NoError(t, err)
+	var id int
+	err = xsql.NoDialect.From("t").Select("id").To(&id).Where("id = ?", 1).QueryRowAndClose(ctx, db)
+	assert.Equal(t, sql.ErrNoRows, err)
+	err = xsql.NoDialect.From("missing").Select("id").QueryAndClose(ctx, db, nil)
+	require.Error(t, err)
+
+	spans := tp.tracer.spans
+	require.Len(t, spans, 3)
+	assert.Equal(t, "t.create", spans[0].name)
+	assert.Contains(t, spans[0].attrs, attribute.String("db.statement.name", "t.create"))
+	assert.Contains(t, spans[0].attrs, attribute.String("db.system", "default"))
+	assert.Equal(t, codes.Unset, spans[0].status)
+
+	assert.Equal(t, "xsql.query_row", spans[1].name)
+	assert.Contains(t, spans[1].attrs, attribute.StringSlice("db.statement.args", []string{"1"}))
+	// no rows is not an error
+	assert.Equal(t, codes.Unset, spans[1].status)
+
+	assert.Equal(t, "xsql.query", spans[2].name)
+	assert.Equal(t, codes.Error, spans[2].status)
+	for _, s := range spans {
+		assert.True(t, s.ended)
+	}
+}
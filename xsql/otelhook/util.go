@@ -0,0 +1,23 @@
+package otelhook
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// toString returns the string value of the statement argument
+func toString(v any) string {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if val, err := valuer.Value(); err == nil {
+			v = val
+		}
+	}
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return fmt.Sprintf("[%d bytes]", len(val))
+	default:
+		return fmt.Sprint(val)
+	}
+}
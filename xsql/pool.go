@@ -22,6 +22,11 @@ func (b *Dialect) getStmt() *Stmt {
 	stmt.buf = getBuffer()
 	stmt.name = ""
 	stmt.sql = ""
+	stmt.unbounded = false
+	stmt.idempotent = false
+	stmt.upsert = false
+	stmt.conflict = stmt.conflict[:0]
+	stmt.err = nil
 	stmt.useNewLines = b.useNewLines
 	return stmt
 }
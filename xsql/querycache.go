@@ -0,0 +1,122 @@
+package xsql
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultQueryCacheSize is the default maximum number of SQL statements
+// cached by a dialect
+const DefaultQueryCacheSize = 10000
+
+// QueryCacheStats provides the counters of the dialect's query cache
+type QueryCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+	// MaxEntries is the maximum number of cached statements, zero if unlimited
+	MaxEntries int
+}
+
+type cachedQuery struct {
+	key string
+	sql string
+}
+
+// queryCache is LRU cache of SQL statements,
+// the zero value is the cache with DefaultQueryCacheSize limit
+type queryCache struct {
+	lock  sync.Mutex
+	items map[string]*list.Element
+	lru   list.List
+	// maxEntries is the limit of entries, negative for unlimited, zero for default
+	maxEntries int
+	hits       uint64
+	misses     uint64
+	evictions  uint64
+}
+
+func (c *queryCache) limit() int {
+	switch {
+	case c.maxEntries < 0:
+		return 0
+	case c.maxEntries == 0:
+		return DefaultQueryCacheSize
+	default:
+		return c.maxEntries
+	}
+}
+
+func (c *queryCache) get(key string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.hits++
+		c.lru.MoveToFront(e)
+		return e.Value.(*cachedQuery).sql, true
+	}
+	c.misses++
+	return "", false
+}
+
+func (c *queryCache) put(key, sql string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.items == nil {
+		c.items = map[string]*list.Element{}
+	}
+	if e, ok := c.items[key]; ok {
+		e.Value.(*cachedQuery).sql = sql
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.lru.PushFront(&cachedQuery{key: key, sql: sql})
+	c.evict()
+}
+
+// evict removes the least recently used entries over the limit,
+// the lock must be held
+func (c *queryCache) evict() {
+	limit := c.limit()
+	if limit == 0 {
+		return
+	}
+	for c.lru.Len() > limit {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.items, e.Value.(*cachedQuery).key)
+		c.evictions++
+	}
+}
+
+func (c *queryCache) setLimit(maxEntries int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if maxEntries == 0 {
+		// unlimited
+		maxEntries = -1
+	}
+	c.maxEntries = maxEntries
+	c.evict()
+}
+
+func (c *queryCache) stats() QueryCacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return QueryCacheStats{
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Evictions:  c.evictions,
+		Size:       c.lru.Len(),
+		MaxEntries: c.limit(),
+	}
+}
+
+func (c *queryCache) len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Len()
+}
@@ -0,0 +1,43 @@
+package xsql
+
+import "strings"
+
+/*
+Quote returns the identifier quoted by the rules of the dialect,
+so it can be used in the statement even if it's a reserved keyword:
+
+	xsql.Postgres.Quote("public.order") // "public"."order"
+	xsql.SQLServer.Quote("dbo.user")    // [dbo].[user]
+
+The parts of dotted names are quoted separately, and * is not quoted.
+The quote characters in the identifier are escaped,
+so the result is safe to use in the statement text.
+*/
+func (b *Dialect) Quote(ident string) string {
+	open, closing := `"`, `"`
+	switch b.provider {
+	case "sqlserver":
+		open, closing = "[", "]"
+	case "mysql":
+		open, closing = "`", "`"
+	}
+
+	parts := strings.Split(ident, ".")
+	for i, part := range parts {
+		if part == "*" {
+			continue
+		}
+		parts[i] = open + strings.ReplaceAll(part, closing, closing+closing) + closing
+	}
+	return strings.Join(parts, ".")
+}
+
+// Quote returns the identifier quoted by the rules of the dialect of the statement
+func (q *Stmt) Quote(ident string) string {
+	return q.dialect.Quote(ident)
+}
+
+// Quote returns the identifier quoted by the rules of the default dialect
+func Quote(ident string) string {
+	return defaultDialect.Load().(SQLDialect).Quote(ident)
+}
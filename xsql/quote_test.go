@@ -0,0 +1,38 @@
+package xsql_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuote(t *testing.T) {
+	tcases := []struct {
+		dialect xsql.SQLDialect
+		ident   string
+		exp     string
+	}{
+		{dialect: xsql.Postgres, ident: "order", exp: `"order"`},
+		{dialect: xsql.Postgres, ident: "public.user", exp: `"public"."user"`},
+		{dialect: xsql.Postgres, ident: `a"b`, exp: `"a""b"`},
+		{dialect: xsql.Postgres, ident: "o.*", exp: `"o".*`},
+		{dialect: xsql.SQLServer, ident: "dbo.user", exp: `[dbo].[user]`},
+		{dialect: xsql.SQLServer, ident: "a]; DROP TABLE t; --", exp: `[a]]; DROP TABLE t; --]`},
+		{dialect: xsql.MySQL, ident: "order", exp: "`order`"},
+		{dialect: xsql.MySQL, ident: "a`b", exp: "`a``b`"},
+		{dialect: xsql.NoDialect, ident: "order", exp: `"order"`},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.dialect.Provider()+"/"+tc.ident, func(t *testing.T) {
+			assert.Equal(t, tc.exp, tc.dialect.Quote(tc.ident))
+		})
+	}
+
+	assert.Equal(t, `"order"`, xsql.Quote("order"))
+
+	q := xsql.SQLServer.Update("dbo.orders")
+	defer q.Close()
+	q.Set(q.Quote("order"), 1).Where(q.Quote("key")+" = ?", "a")
+	assert.Equal(t, "UPDATE dbo.orders \nSET [order]=? \nWHERE [key] = ?", q.String())
+}
@@ -33,6 +33,23 @@ type Builder interface {
 	// Note: this method does no type checks and returns no errors.
 	Bind(data any) Builder
 
+	/*
+		BindParams binds :name and @name parameters of the statement
+		to the values from map[string]any or a struct with "db" tags:
+
+			xsql.From("users").
+				Select("id").
+				Where("org_id = :org").
+				BindParams(map[string]any{"org": orgID})
+
+		Call BindParams after the clauses with the parameters are added.
+		The error of invalid params is returned by Query, QueryRow and Exec,
+		see Err.
+	*/
+	BindParams(params any) Builder
+	// Err returns the error recorded while building the statement
+	Err() error
+
 	/*
 		Clause appends a raw SQL fragment to the statement.
 
@@ -54,6 +71,14 @@ type Builder interface {
 	*/
 	Close()
 
+	/*
+		CountQuery returns a new statement that counts the rows of the SELECT statement
+		over the same FROM, JOIN and WHERE clauses, without ORDER BY, LIMIT and OFFSET.
+
+		The returned statement should be closed separately.
+	*/
+	CountQuery() Builder
+
 	/*
 		DeleteFrom starts a DELETE statement.
 
@@ -90,6 +115,19 @@ type Builder interface {
 	*/
 	Expr(expr string, args ...any) Builder
 
+	/*
+		ForUpdate locks the selected rows for update until the end of the transaction,
+		FOR UPDATE [OF ...] [NOWAIT | SKIP LOCKED] clause, or
+		WITH (UPDLOCK, ROWLOCK) table hint on SQL Server:
+
+			xsql.From("jobs").
+				Select("id").
+				Where("status = ?", "pending").
+				Limit(10).
+				ForUpdate(xsql.SkipLocked())
+	*/
+	ForUpdate(opts ...LockOption) Builder
+
 	/*
 		From starts a SELECT statement.
 
@@ -169,6 +207,10 @@ type Builder interface {
 	// executes rows.Scan right before calling a handler function.
 	QueryAndClose(ctx context.Context, db Executor, handler func(rows *sql.Rows)) error
 
+	// Explain returns the query plan of the statement,
+	// opts is optional and may be nil
+	Explain(ctx context.Context, db Executor, opts *ExplainOptions) (*Plan, error)
+
 	// QueryRow executes the statement via Executor methods
 	// and scans values to variables bound via To method calls.
 	QueryRow(ctx context.Context, db Executor) error
@@ -206,6 +248,14 @@ type Builder interface {
 	*/
 	Select(expr string, args ...any) Builder
 
+	/*
+		SelectExpr adds the expression built by Case, Coalesce or Raw
+		to SELECT clause, with its arguments:
+
+			q.SelectExpr(xsql.As(xsql.Coalesce(xsql.Col("nickname"), xsql.Col("name")), "name")).To(&name)
+	*/
+	SelectExpr(expr Expression) Builder
+
 	/*
 		Set method:
 
@@ -280,6 +330,99 @@ type Builder interface {
 	*/
 	Where(expr string, args ...any) Builder
 
+	/*
+		WhereExpr adds the condition built by And, Or, Not or Raw,
+		the nested conditions are enclosed in parentheses:
+
+			xsql.From("users").
+				Select("id").
+				WhereExpr(xsql.Or(xsql.Raw("a = ?", 1), xsql.Raw("b = ?", 2))).
+				Where("c = ?", 3)
+	*/
+	WhereExpr(cond Expression) Builder
+
+	/*
+		WhereJSON adds a filter on a scalar value extracted from a JSON column:
+
+			xsql.Postgres.From("org").
+				Select("id").
+				WhereJSON("settings", "billing.plan", "=", "pro")
+	*/
+	WhereJSON(column, path, op string, value any) Builder
+
+	// WhereJSONExists adds a filter that checks if the path exists in a JSON column
+	WhereJSONExists(column, path string) Builder
+
+	// WhereContains adds a filter for array column that contains all the values
+	WhereContains(column string, values any) Builder
+
+	// WhereOverlaps adds a filter for array column that contains any of the values
+	WhereOverlaps(column string, values any) Builder
+
+	// WhereIn adds a filter for the column that equals any of the values,
+	// an empty list of values matches no rows
+	WhereIn(column string, values any) Builder
+
+	// WhereNotIn adds a filter for the column that does not equal any of the values,
+	// an empty list of values matches all rows
+	WhereNotIn(column string, values any) Builder
+
+	// UseIndex adds an index hint for the table in the FROM clause
+	UseIndex(name string) Builder
+
+	// Unbounded marks SELECT statement as intentionally reading all rows,
+	// the provider will not inject the default LIMIT
+	Unbounded() Builder
+	// IsUnbounded returns true if the statement was marked with Unbounded
+	IsUnbounded() bool
+	// IsSelect returns true if the statement is a SELECT statement
+	IsSelect() bool
+	// HasLimit returns true if the statement has a LIMIT clause
+	HasLimit() bool
+	// PageArgs returns the values bound to LIMIT and OFFSET clauses,
+	// or nil if the clause is not set
+	PageArgs() (limit any, offset any)
+	// CapRows returns a copy of the statement limited to the number of rows,
+	// rendered for the dialect, the statement is not changed
+	CapRows(limit uint32) Builder
+
+	// Idempotent marks the statement as read-only and safe to retry
+	// on another replica
+	Idempotent() Builder
+	// IsIdempotent returns true if the statement was marked with Idempotent
+	IsIdempotent() bool
+
+	// Cost returns the estimated complexity of the statement
+	Cost() Cost
+
+	/*
+		OnConflict starts an UPSERT clause of INSERT statement,
+		columns specify the unique key that identifies the conflicting row:
+
+			xsql.Postgres.InsertInto("org").
+				Set("id", id).
+				Set("name", name).
+				OnConflict("id").
+				DoUpdateSet("name", xsql.Postgres.Excluded("name"))
+
+		The clause is rendered as ON CONFLICT for Postgres and SQLite,
+		ON DUPLICATE KEY UPDATE for MySQL, and MERGE for SQL Server.
+	*/
+	OnConflict(columns ...string) Builder
+	// DoNothing skips the row that conflicts with the existing one
+	DoNothing() Builder
+	// DoUpdateSet adds the column to update in the row that conflicts with the existing one
+	DoUpdateSet(field, expr string, args ...any) Builder
+	// Excluded returns an expression that refers the value proposed for insertion
+	// in DoUpdateSet, using the dialect of the statement
+	Excluded(column string) string
+
+	// Quote returns the identifier quoted by the rules of the dialect of the statement,
+	// to be used for the names that are reserved keywords:
+	//
+	//	q.Set(q.Quote("order"), order)
+	Quote(ident string) string
+
 	// With prepends a statement with an WITH clause.
 	// With method calls a Close method of a given query, so
 	// make sure not to reuse it afterwards.
@@ -484,6 +627,13 @@ type Stmt struct {
 	args        []any
 	dest        []any
 	useNewLines bool
+	unbounded   bool
+	idempotent  bool
+	// upsert is set by OnConflict, conflict specifies the unique key columns
+	upsert   bool
+	conflict []string
+	// err is recorded by BindParams
+	err error
 }
 
 // UseNewLines specifies an option to add new lines for each clause
@@ -497,6 +647,11 @@ func (q *Stmt) Name() string {
 	return q.name
 }
 
+// Err returns the error recorded while building the statement
+func (q *Stmt) Err() error {
+	return q.err
+}
+
 // SetName sets the name of the statement
 func (q *Stmt) SetName(name string) Builder {
 	q.name = name
@@ -654,9 +809,9 @@ produces
 	INSERT INTO table (field) VALUES (42)
 
 Do not use it to construct ON CONFLICT DO UPDATE SET or similar clauses.
-Use generic Clause and Expr methods instead:
+Use OnConflict and DoUpdateSet methods instead:
 
-	q.Clause("ON CONFLICT DO UPDATE SET").Expr("column_name = ?", value)
+	q.OnConflict("id").DoUpdateSet("column_name", "?", value)
 */
 func (q *Stmt) Set(field string, value any) Builder {
 	return q.SetExpr(field, "?", value)
@@ -710,6 +865,8 @@ func (q *Stmt) Where(expr string, args ...any) Builder {
 In adds IN expression to the current filter.
 
 In method must be called after a Where method call.
+The list of args must not be empty, use WhereIn for lists
+that can be empty.
 */
 func (q *Stmt) In(args ...any) Builder {
 	buf := getBuffer()
@@ -915,6 +1072,9 @@ func (q *Stmt) String() string {
 		sql, ok := q.dialect.GetCachedQuery(bufStrKey)
 		if ok {
 			q.sql = sql
+		} else if q.upsert && q.dialect.Provider() == "sqlserver" {
+			q.sql = q.mergeString()
+			q.dialect.PutCachedQuery(bufStrKey, q.sql)
 		} else {
 			// Build a query
 			var argNo = 1
@@ -1007,6 +1167,11 @@ func (q *Stmt) Clone() Builder {
 	stmt.dest = insertAt(stmt.dest, q.dest, 0)
 	_, _ = stmt.buf.Write(q.buf.B)
 	stmt.sql = q.sql
+	stmt.unbounded = q.unbounded
+	stmt.idempotent = q.idempotent
+	stmt.upsert = q.upsert
+	stmt.conflict = append(stmt.conflict[:0], q.conflict...)
+	stmt.err = q.err
 
 	return stmt
 }
@@ -1018,21 +1183,13 @@ func (q *Stmt) Clone() Builder {
 //
 // Note: this method does no type checks and returns no errors.
 func (q *Stmt) Bind(data any) Builder {
-	typ := reflect.TypeOf(data).Elem()
 	val := reflect.ValueOf(data).Elem()
-
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		t := typ.Field(i)
-		if field.Kind() == reflect.Struct && t.Anonymous {
-			q.Bind(field.Addr().Interface())
-		} else {
-			dbFieldName := t.Tag.Get("db")
-			if dbFieldName != "" {
-				tokens := strings.Split(dbFieldName, ",")
-				q.Select(tokens[0]).To(field.Addr().Interface())
-			}
-		}
+	fields, err := getStructFields(val.Type())
+	if err != nil {
+		return q
+	}
+	for _, f := range fields.fields {
+		q.Select(f.Column).To(val.FieldByIndex(f.Index).Addr().Interface())
 	}
 	return q
 }
@@ -1211,9 +1368,9 @@ produces
 	INSERT INTO table (field) VALUES (42)
 
 Do not use it to construct ON CONFLICT DO UPDATE SET or similar clauses.
-Use generic Clause and Expr methods instead:
+Use OnConflict and DoUpdateSet methods instead:
 
-	q.Clause("ON CONFLICT DO UPDATE SET").Expr("column_name = ?", value)
+	q.OnConflict("id").DoUpdateSet("column_name", "?", value)
 */
 func (row newRow) Set(field string, value any) Row {
 	return row.SetExpr(field, "?", value)
@@ -1275,6 +1432,7 @@ const (
 	posOrderBy
 	posLimit
 	posOffset
+	posLock
 	posReturning
 	posEnd
 )
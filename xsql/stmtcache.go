@@ -0,0 +1,204 @@
+package xsql
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/pkg/errors"
+	"sync"
+)
+
+// PreparerExecutor is an Executor that prepares statements,
+// both sql.DB, sql.Conn and sql.Tx implement it
+type PreparerExecutor interface {
+	Executor
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// StmtCacheStats provides the counters of the prepared statements cache
+type StmtCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+type preparedStmt struct {
+	name  string
+	query string
+	stmt  *sql.Stmt
+}
+
+// StmtCache is an Executor that executes named statements
+// with database/sql prepared statements.
+// The statement name is taken from the context, see StatementName,
+// so the statements built by xsql with SetName are prepared once
+// and reused by Exec, Query and QueryRow.
+// Unnamed statements are executed by the underlying Executor.
+// The least recently used statements are closed when the cache is full,
+// and the statements are invalidated when the connection is closed.
+type StmtCache struct {
+	db         PreparerExecutor
+	maxEntries int
+
+	lock  sync.Mutex
+	items map[string]*list.Element
+	lru   *list.List
+	stats StmtCacheStats
+}
+
+// DefaultStmtCacheSize is the default maximum number of prepared statements
+const DefaultStmtCacheSize = 256
+
+// NewStmtCache returns the prepared statements cache for the executor,
+// DefaultStmtCacheSize is used if maxEntries is not positive
+func NewStmtCache(db PreparerExecutor, maxEntries int) *StmtCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultStmtCacheSize
+	}
+	return &StmtCache{
+		db:         db,
+		maxEntries: maxEntries,
+		items:      map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// Stats returns the counters of the cache
+func (c *StmtCache) Stats() StmtCacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s := c.stats
+	s.Size = c.lru.Len()
+	return s
+}
+
+// MaxEntries returns the maximum number of prepared statements
+func (c *StmtCache) MaxEntries() int {
+	return c.maxEntries
+}
+
+// QueryContext executes a query that returns rows, typically a SELECT.
+func (c *StmtCache) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ps, err := c.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if ps == nil {
+		return c.db.QueryContext(ctx, query, args...)
+	}
+	rows, err := ps.stmt.QueryContext(ctx, args...)
+	c.checkErr(ps, err)
+	return rows, err
+}
+
+// QueryRowContext executes a query that is expected to return at most one row.
+func (c *StmtCache) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ps, err := c.prepare(ctx, query)
+	if err != nil || ps == nil {
+		// the error of prepare is reported by Row's Scan
+		return c.db.QueryRowContext(ctx, query, args...)
+	}
+	row := ps.stmt.QueryRowContext(ctx, args...)
+	c.checkErr(ps, row.Err())
+	return row
+}
+
+// ExecContext executes a query without returning any rows.
+func (c *StmtCache) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ps, err := c.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if ps == nil {
+		return c.db.ExecContext(ctx, query, args...)
+	}
+	res, err := ps.stmt.ExecContext(ctx, args...)
+	c.checkErr(ps, err)
+	return res, err
+}
+
+// Close closes the prepared statements
+func (c *StmtCache) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var err error
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		if cerr := e.Value.(*preparedStmt).stmt.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	c.items = map[string]*list.Element{}
+	c.lru.Init()
+	return err
+}
+
+// prepare returns the prepared statement by the name from the context,
+// or nil for unnamed statements
+func (c *StmtCache) prepare(ctx context.Context, query string) (*preparedStmt, error) {
+	name := StatementName(ctx)
+	if name == "" {
+		return nil, nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.items[name]; ok {
+		ps := e.Value.(*preparedStmt)
+		if ps.query == query {
+			c.stats.Hits++
+			c.lru.MoveToFront(e)
+			return ps, nil
+		}
+		// the name is reused for another query
+		c.remove(e)
+	}
+
+	c.stats.Misses++
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	ps := &preparedStmt{name: name, query: query, stmt: stmt}
+	c.items[name] = c.lru.PushFront(ps)
+
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+	return ps, nil
+}
+
+// remove closes the statement and removes it from the cache,
+// the lock must be held
+func (c *StmtCache) remove(e *list.Element) {
+	ps := e.Value.(*preparedStmt)
+	_ = ps.stmt.Close()
+	c.lru.Remove(e)
+	if cur, ok := c.items[ps.name]; ok && cur == e {
+		delete(c.items, ps.name)
+	}
+}
+
+// checkErr invalidates the statement, if the connection is closed
+func (c *StmtCache) checkErr(ps *preparedStmt, err error) {
+	if err == nil || !isClosedErr(err) {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.items[ps.name]; ok && e.Value == ps {
+		c.remove(e)
+	}
+}
+
+func isClosedErr(err error) bool {
+	return errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, sql.ErrTxDone) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		err.Error() == "sql: statement is closed" ||
+		err.Error() == "sql: database is closed"
+}
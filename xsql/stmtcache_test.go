@@ -0,0 +1,75 @@
+package xsql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStmtCache(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+
+	c := xsql.NewStmtCache(db, 2)
+	_, err = xsql.NoDialect.New("CREATE TABLE items (id INTEGER, name TEXT)").ExecAndClose(ctx, c)
+	require.NoError(t, err)
+	// unnamed statements are not prepared
+	assert.Equal(t, xsql.StmtCacheStats{}, c.Stats())
+
+	for i, name := range []string{"a", "b", "c"} {
+		_, err = xsql.NoDialect.InsertInto("items").
+			Set("id", i+1).
+			Set("name", name).
+			SetName("stmtcache.insert").
+			ExecAndClose(ctx, c)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, xsql.StmtCacheStats{Hits: 2, Misses: 1, Size: 1}, c.Stats())
+
+	get := func(id int) (string, error) {
+		var name string
+		err := xsql.NoDialect.From("items").
+			Select("name").To(&name).
+			Where("id = ?", id).
+			SetName("stmtcache.get").
+			QueryRowAndClose(ctx, c)
+		return name, err
+	}
+	name, err := get(2)
+	require.NoError(t, err)
+	assert.Equal(t, "b", name)
+	_, err = get(5)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	count := 0
+	err = xsql.NoDialect.From("items").Select("id").SetName("stmtcache.list").
+		QueryAndClose(ctx, c, func(*sql.Rows) { count++ })
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// the least recently used insert is evicted
+	assert.Equal(t, xsql.StmtCacheStats{Hits: 3, Misses: 3, Evictions: 1, Size: 2}, c.Stats())
+
+	// the name is reused for another query
+	_, err = c.ExecContext(xsql.WithStatementName(ctx, "stmtcache.get"), "DELETE FROM items WHERE id = ?", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Stats().Size)
+
+	_, err = c.QueryContext(xsql.WithStatementName(ctx, "stmtcache.bad"), "SELECT FROM")
+	require.Error(t, err)
+
+	// the statements are invalidated when the connection is closed
+	require.NoError(t, db.Close())
+	_, err = c.ExecContext(xsql.WithStatementName(ctx, "stmtcache.get"), "DELETE FROM items WHERE id = ?", 3)
+	require.Error(t, err)
+	assert.Equal(t, 1, c.Stats().Size)
+
+	require.NoError(t, c.Close())
+	assert.Equal(t, 0, c.Stats().Size)
+}
@@ -0,0 +1,252 @@
+package xsql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// StructField describes the field of a struct mapped to a column by "db" tag
+type StructField struct {
+	// Name of the field
+	Name string
+	// Column name from the tag
+	Column string
+	// Options of the tag after the column name,
+	// for example: int8, index, primary, omitempty
+	Options []string
+	// Index of the field for reflect.Value.FieldByIndex
+	Index []int
+}
+
+// HasOption returns true if the tag has the option
+func (f *StructField) HasOption(op string) bool {
+	for _, o := range f.Options {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// structFields describes the fields of a struct annotated with "db" tag
+type structFields struct {
+	// fields in the order of the struct
+	fields []*StructField
+	// columns in the order of the fields
+	columns []string
+	// index of the field by lower case column name
+	index map[string][]int
+}
+
+var structFieldsCache sync.Map // reflect.Type => *structFields
+
+func getStructFields(typ reflect.Type) (*structFields, error) {
+	if v, ok := structFieldsCache.Load(typ); ok {
+		return v.(*structFields), nil
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, errors.Errorf("expected struct type: %s", typ.String())
+	}
+
+	fields := &structFields{index: map[string][]int{}}
+	for _, f := range reflect.VisibleFields(typ) {
+		if f.Anonymous || !f.IsExported() || throughPointer(typ, f.Index) {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("db"), ",")
+		name := tag[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		key := strings.ToLower(name)
+		if _, ok := fields.index[key]; ok {
+			continue
+		}
+		fields.fields = append(fields.fields, &StructField{
+			Name:    f.Name,
+			Column:  name,
+			Options: tag[1:],
+			Index:   f.Index,
+		})
+		fields.columns = append(fields.columns, name)
+		fields.index[key] = f.Index
+	}
+
+	v, _ := structFieldsCache.LoadOrStore(typ, fields)
+	return v.(*structFields), nil
+}
+
+/*
+StructFields returns the fields of the struct type mapped to columns by "db" tag,
+in the order of the fields:
+
+	type org struct {
+		ID   int64  `db:"id,int8,index,primary"`
+		Name string `db:"name,varchar,max:64"`
+	}
+
+The fields of embedded structs are included,
+except the fields promoted from embedded pointers.
+If the columns differ only in case, the first field is used.
+The fields of the struct type are cached, and must not be modified.
+*/
+func StructFields(typ reflect.Type) ([]*StructField, error) {
+	fields, err := getStructFields(typ)
+	if err != nil {
+		return nil, err
+	}
+	return fields.fields, nil
+}
+
+// dest sets the destinations to scan the columns into the fields of val,
+// the columns without matching fields are discarded
+func (f *structFields) dest(val reflect.Value, columns []string, dest []any) {
+	for i, c := range columns {
+		if index, ok := f.index[strings.ToLower(c)]; ok {
+			dest[i] = val.FieldByIndex(index).Addr().Interface()
+		} else {
+			dest[i] = new(any)
+		}
+	}
+}
+
+/*
+StructDest returns the destinations to scan the columns
+into the fields of the struct pointed by ptr, mapped by "db" tag of the fields:
+
+	dest, err := xsql.StructDest(&u, columns)
+	if err != nil {
+		return err
+	}
+	err = rows.Scan(dest...)
+
+The columns without matching fields are discarded.
+The fields of the struct type are cached.
+*/
+func StructDest(ptr any, columns []string) ([]any, error) {
+	val := reflect.ValueOf(ptr)
+	if val.Kind() != reflect.Pointer || val.IsNil() {
+		return nil, errors.Errorf("expected pointer to struct: %T", ptr)
+	}
+	val = val.Elem()
+	fields, err := getStructFields(val.Type())
+	if err != nil {
+		return nil, err
+	}
+	dest := make([]any, len(columns))
+	fields.dest(val, columns, dest)
+	return dest, nil
+}
+
+// throughPointer returns true if the field is promoted from embedded pointer
+func throughPointer(typ reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		typ = typ.Field(i).Type
+		if typ.Kind() == reflect.Pointer {
+			return true
+		}
+	}
+	return false
+}
+
+// needsColumns returns true for SELECT statement without columns
+func (q *Stmt) needsColumns() bool {
+	hasFrom := false
+	for _, chunk := range q.chunks {
+		switch chunk.pos {
+		case posSelect, posInsert, posUpdate, posDelete, posReturning:
+			return false
+		case posFrom:
+			hasFrom = true
+		}
+	}
+	return hasFrom
+}
+
+/*
+QueryStructs executes the statement and returns the rows mapped to structs
+by "db" tag of the fields:
+
+	type user struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	q := xsql.From("users").Where("org_id = ?", orgID)
+	defer q.Close()
+	list, err := xsql.QueryStructs[user](ctx, db, q)
+
+If the statement has no SELECT clause, the columns are selected by the tags.
+The result columns without matching fields are ignored.
+*/
+func QueryStructs[T any](ctx context.Context, db Executor, q Builder) ([]T, error) {
+	var list []T
+	err := queryStructs(ctx, db, q, func(v T) bool {
+		list = append(list, v)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// QueryStruct executes the statement and returns the first row mapped to struct
+// by "db" tag of the fields, or sql.ErrNoRows if no rows selected.
+// See QueryStructs for details.
+func QueryStruct[T any](ctx context.Context, db Executor, q Builder) (T, error) {
+	var res T
+	found := false
+	err := queryStructs(ctx, db, q, func(v T) bool {
+		res = v
+		found = true
+		return false
+	})
+	if err == nil && !found {
+		err = sql.ErrNoRows
+	}
+	return res, err
+}
+
+func queryStructs[T any](ctx context.Context, db Executor, q Builder, handler func(T) bool) error {
+	var zero T
+	fields, err := getStructFields(reflect.TypeOf(zero))
+	if err != nil {
+		return err
+	}
+	if s, ok := q.(*Stmt); ok && s.needsColumns() {
+		q.Select(strings.Join(fields.columns, ", "))
+	}
+
+	rows, err := db.QueryContext(ctx, q.String(), q.Args()...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	dest := make([]any, len(columns))
+	for rows.Next() {
+		var v T
+		fields.dest(reflect.ValueOf(&v).Elem(), columns, dest)
+		if err = rows.Scan(dest...); err != nil {
+			return err
+		}
+		if !handler(v) {
+			break
+		}
+	}
+	if err = rows.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
@@ -0,0 +1,116 @@
+package xsql_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userRow struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+	// Note is not a column
+	Note string
+}
+
+type incomeRow struct {
+	userRow
+	Amount float64 `db:"amount,omitempty"`
+	Skip   string  `db:"-"`
+}
+
+func TestQueryStructs(t *testing.T) {
+	forEveryDB(t, func(ctx context.Context, env *dbEnv) {
+		q := env.xsql.From("users").OrderBy("id")
+		users, err := xsql.QueryStructs[userRow](ctx, env.db, q)
+		assert.Equal(t, "SELECT id, name \nFROM users \nORDER BY id", q.String())
+		q.Close()
+		require.NoError(t, err)
+		assert.Equal(t, []userRow{
+			{ID: 1, Name: "User 1"},
+			{ID: 2, Name: "User 2"},
+			{ID: 3, Name: "User 3"},
+		}, users)
+
+		q = env.xsql.From("incomes i").
+			Join("users u", "u.id = i.from_user_id").
+			Select("u.id, u.name, i.amount, i.user_id").
+			Where("i.user_id = ?", 1).
+			OrderBy("i.amount")
+		incomes, err := xsql.QueryStructs[incomeRow](ctx, env.db, q)
+		q.Close()
+		require.NoError(t, err)
+		require.Len(t, incomes, 3)
+		assert.Equal(t, incomeRow{userRow: userRow{ID: 3, Name: "User 3"}, Amount: 350}, incomes[2])
+
+		q = env.xsql.From("users").Where("id = ?", 2)
+		user, err := xsql.QueryStruct[userRow](ctx, env.db, q)
+		q.Close()
+		require.NoError(t, err)
+		assert.Equal(t, userRow{ID: 2, Name: "User 2"}, user)
+
+		q = env.xsql.From("users").Where("id = ?", 100)
+		_, err = xsql.QueryStruct[userRow](ctx, env.db, q)
+		q.Close()
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		q = env.xsql.From("users").Where("id = ?", 100)
+		users, err = xsql.QueryStructs[userRow](ctx, env.db, q)
+		q.Close()
+		require.NoError(t, err)
+		assert.Empty(t, users)
+
+		q = env.xsql.From("users")
+		_, err = xsql.QueryStructs[int](ctx, env.db, q)
+		q.Close()
+		assert.EqualError(t, err, "expected struct type: int")
+	})
+}
+
+func TestStructDest(t *testing.T) {
+	var row incomeRow
+	dest, err := xsql.StructDest(&row, []string{"ID", "amount", "other"})
+	require.NoError(t, err)
+	require.Len(t, dest, 3)
+	assert.Same(t, &row.ID, dest[0])
+	assert.Same(t, &row.Amount, dest[1])
+	assert.IsType(t, new(any), dest[2])
+
+	_, err = xsql.StructDest(row, nil)
+	assert.EqualError(t, err, "expected pointer to struct: xsql_test.incomeRow")
+	n := 1
+	_, err = xsql.StructDest(&n, nil)
+	assert.EqualError(t, err, "expected struct type: int")
+}
+
+func TestStructFields(t *testing.T) {
+	type withPointer struct {
+		*userRow
+		Code string `db:"code,varchar,primary"`
+	}
+
+	fields, err := xsql.StructFields(reflect.TypeOf(incomeRow{}))
+	require.NoError(t, err)
+	require.Len(t, fields, 3)
+	assert.Equal(t, xsql.StructField{Name: "ID", Column: "id", Options: []string{}, Index: []int{0, 0}}, *fields[0])
+	assert.Equal(t, "name", fields[1].Column)
+	assert.Equal(t, "Amount", fields[2].Name)
+	assert.Equal(t, []int{1}, fields[2].Index)
+	assert.True(t, fields[2].HasOption("omitempty"))
+	assert.False(t, fields[2].HasOption("primary"))
+
+	// the fields of embedded pointer are skipped
+	fields, err = xsql.StructFields(reflect.TypeOf(withPointer{}))
+	require.NoError(t, err)
+	require.Len(t, fields, 1)
+	assert.Equal(t, "code", fields[0].Column)
+	assert.Equal(t, []string{"varchar", "primary"}, fields[0].Options)
+
+	_, err = xsql.StructFields(reflect.TypeOf(1))
+	assert.EqualError(t, err, "expected struct type: int")
+}
@@ -0,0 +1,169 @@
+package xsql
+
+import (
+	"strings"
+)
+
+const (
+	posOnConflict     = posValues + 10
+	posConflictAction = posValues + 20
+)
+
+/*
+OnConflict starts an UPSERT clause of INSERT statement,
+columns specify the unique key that identifies the conflicting row:
+
+	xsql.Postgres.InsertInto("org").
+		Set("id", id).
+		Set("name", name).
+		OnConflict("id").
+		DoUpdateSet("name", xsql.Postgres.Excluded("name"))
+
+produces
+
+	INSERT INTO org (id, name) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name
+
+The clause is rendered as ON CONFLICT for Postgres and SQLite,
+ON DUPLICATE KEY UPDATE for MySQL, where columns are ignored,
+and the statement is rewritten to MERGE for SQL Server.
+
+OnConflict must be called after all the values are Set,
+and followed by DoNothing or DoUpdateSet.
+*/
+func (q *Stmt) OnConflict(columns ...string) Builder {
+	q.upsert = true
+	q.conflict = append(q.conflict[:0], columns...)
+	switch q.dialect.Provider() {
+	case "mysql", "sqlserver":
+	default:
+		clause := "ON CONFLICT"
+		if len(columns) > 0 {
+			clause += " (" + strings.Join(columns, ", ") + ")"
+		}
+		q.addChunk(posOnConflict, clause, "", nil, ", ")
+	}
+	return q
+}
+
+// DoNothing skips the row that conflicts with the existing one
+func (q *Stmt) DoNothing() Builder {
+	switch q.dialect.Provider() {
+	case "sqlserver":
+		// MERGE without WHEN MATCHED clause
+	case "mysql":
+		column := ""
+		if len(q.conflict) > 0 {
+			column = q.conflict[0]
+		} else {
+			column, _, _ = strings.Cut(q.chunkText(posInsertFields), ",")
+		}
+		column = strings.TrimSpace(column)
+		q.addChunk(posConflictAction, "ON DUPLICATE KEY UPDATE", column+" = "+column, nil, ", ")
+	default:
+		q.addChunk(posConflictAction, "DO NOTHING", "", nil, ", ")
+	}
+	return q
+}
+
+/*
+DoUpdateSet adds the column to update in the row that conflicts with the existing one,
+use Excluded to refer the value proposed for insertion:
+
+	q.OnConflict("id").
+		DoUpdateSet("name", q.Excluded("name")).
+		DoUpdateSet("updated_at", "?", now)
+*/
+func (q *Stmt) DoUpdateSet(field, expr string, args ...any) Builder {
+	clause := "DO UPDATE SET"
+	switch q.dialect.Provider() {
+	case "sqlserver":
+		clause = "WHEN MATCHED THEN UPDATE SET"
+	case "mysql":
+		clause = "ON DUPLICATE KEY UPDATE"
+	}
+	q.addChunk(posConflictAction, clause, field+" = "+expr, args, ", ")
+	return q
+}
+
+// Excluded returns an expression that refers the value proposed for insertion
+// in DoUpdateSet, using the dialect of the statement
+func (q *Stmt) Excluded(column string) string {
+	return q.dialect.Excluded(column)
+}
+
+/*
+Excluded returns an expression that refers the value proposed for insertion
+in DoUpdateSet clause:
+
+	EXCLUDED.column
+
+for Postgres and SQLite, VALUES(column) for MySQL and src.column for SQL Server.
+*/
+func (b *Dialect) Excluded(column string) string {
+	switch b.provider {
+	case "sqlserver":
+		return "src." + column
+	case "mysql":
+		return "VALUES(" + column + ")"
+	default:
+		return "EXCLUDED." + column
+	}
+}
+
+// chunkText returns the text of the chunks at the position
+func (q *Stmt) chunkText(pos chunkPos) string {
+	var text strings.Builder
+	for _, chunk := range q.chunks {
+		if chunk.pos == pos {
+			text.Write(q.buf.B[chunk.bufLow:chunk.bufHigh])
+		}
+	}
+	return strings.TrimSpace(text.String())
+}
+
+// mergeString renders INSERT statement with OnConflict as SQL Server MERGE:
+//
+//	MERGE INTO table AS tgt USING (VALUES (...)) AS src (columns) ON tgt.id = src.id
+//	WHEN MATCHED THEN UPDATE SET ...
+//	WHEN NOT MATCHED THEN INSERT (columns) VALUES (src.columns);
+func (q *Stmt) mergeString() string {
+	sep := " "
+	if q.useNewLines {
+		sep = "\n"
+	}
+
+	table := strings.TrimSpace(strings.TrimPrefix(q.chunkText(posInsert), "INSERT INTO"))
+	fields := q.chunkText(posInsertFields)
+	values := q.chunkText(posValues)
+	update := q.chunkText(posConflictAction)
+
+	var prefix, suffix []string
+	for _, chunk := range q.chunks {
+		s := strings.TrimSpace(string(q.buf.B[chunk.bufLow:chunk.bufHigh]))
+		switch {
+		case chunk.pos < posInsert:
+			prefix = append(prefix, s)
+		case chunk.pos > posConflictAction:
+			suffix = append(suffix, s)
+		}
+	}
+
+	columns := strings.Split(fields, ",")
+	srcColumns := make([]string, len(columns))
+	for i, c := range columns {
+		srcColumns[i] = "src." + strings.TrimSpace(c)
+	}
+	on := make([]string, len(q.conflict))
+	for i, c := range q.conflict {
+		on[i] = "tgt." + c + " = src." + c
+	}
+
+	parts := append(prefix,
+		"MERGE INTO "+table+" AS tgt USING (VALUES ("+values+")) AS src ("+fields+") ON "+strings.Join(on, " AND "))
+	if update != "" {
+		parts = append(parts, update)
+	}
+	parts = append(parts, "WHEN NOT MATCHED THEN INSERT ("+fields+") VALUES ("+strings.Join(srcColumns, ", ")+")")
+	parts = append(parts, suffix...)
+	return strings.Join(parts, sep) + ";"
+}
@@ -0,0 +1,142 @@
+package xsql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/effective-security/xdb/xsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnConflict(t *testing.T) {
+	tcases := []struct {
+		dialect xsql.SQLDialect
+		update  string
+		nothing string
+	}{
+		{
+			dialect: xsql.Postgres,
+			update:  "INSERT INTO org \n( id, name \n) VALUES ( $1, $2 \n) \nON CONFLICT (id) \nDO UPDATE SET name = EXCLUDED.name, updated_at = $3 \nRETURNING id",
+			nothing: "INSERT INTO org \n( id, name \n) VALUES ( $1, $2 \n) \nON CONFLICT (id) \nDO NOTHING \nRETURNING id",
+		},
+		{
+			dialect: xsql.NoDialect,
+			update:  "INSERT INTO org \n( id, name \n) VALUES ( ?, ? \n) \nON CONFLICT (id) \nDO UPDATE SET name = EXCLUDED.name, updated_at = ? \nRETURNING id",
+			nothing: "INSERT INTO org \n( id, name \n) VALUES ( ?, ? \n) \nON CONFLICT (id) \nDO NOTHING \nRETURNING id",
+		},
+		{
+			dialect: xsql.MySQL,
+			update:  "INSERT INTO org \n( id, name \n) VALUES ( ?, ? \n) \nON DUPLICATE KEY UPDATE name = VALUES(name), updated_at = ? \nRETURNING id",
+			nothing: "INSERT INTO org \n( id, name \n) VALUES ( ?, ? \n) \nON DUPLICATE KEY UPDATE id = id \nRETURNING id",
+		},
+		{
+			dialect: xsql.SQLServer,
+			update: "MERGE INTO org AS tgt USING (VALUES (?, ?)) AS src (id, name) ON tgt.id = src.id\n" +
+				"WHEN MATCHED THEN UPDATE SET name = src.name, updated_at = ?\n" +
+				"WHEN NOT MATCHED THEN INSERT (id, name) VALUES (src.id, src.name);",
+			nothing: "MERGE INTO org AS tgt USING (VALUES (?, ?)) AS src (id, name) ON tgt.id = src.id\n" +
+				"WHEN NOT MATCHED THEN INSERT (id, name) VALUES (src.id, src.name);",
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.dialect.Provider(), func(t *testing.T) {
+			q := tc.dialect.InsertInto("org").
+				Set("id", 1).
+				Set("name", "acme").
+				OnConflict("id")
+			q.DoUpdateSet("name", q.Excluded("name")).
+				DoUpdateSet("updated_at", "?", 100)
+			if tc.dialect.Provider() != "sqlserver" {
+				q.Returning("id")
+			}
+			assert.Equal(t, tc.update, q.String())
+			assert.Equal(t, []any{1, "acme", 100}, q.Args())
+
+			c := q.Clone()
+			assert.Equal(t, tc.update, c.String())
+			c.Close()
+			q.Close()
+
+			q = tc.dialect.InsertInto("org").
+				Set("id", 1).
+				Set("name", "acme").
+				OnConflict("id").
+				DoNothing()
+			if tc.dialect.Provider() != "sqlserver" {
+				q.Returning("id")
+			}
+			assert.Equal(t, tc.nothing, q.String())
+			assert.Equal(t, []any{1, "acme"}, q.Args())
+			q.Close()
+		})
+	}
+}
+
+func TestOnConflictMultiRow(t *testing.T) {
+	q := xsql.SQLServer.InsertInto("orgmember")
+	q.NewRow().Set("org_id", 1).Set("user_id", 2).Set("role", "admin")
+	q.NewRow().Set("org_id", 1).Set("user_id", 3).Set("role", "user")
+	q.OnConflict("org_id", "user_id").DoUpdateSet("role", q.Excluded("role"))
+	defer q.Close()
+
+	assert.Equal(t, "MERGE INTO orgmember AS tgt USING (VALUES (?, ?, ? ), ( ?, ?, ?)) AS src (org_id, user_id, role) "+
+		"ON tgt.org_id = src.org_id AND tgt.user_id = src.user_id\n"+
+		"WHEN MATCHED THEN UPDATE SET role = src.role\n"+
+		"WHEN NOT MATCHED THEN INSERT (org_id, user_id, role) VALUES (src.org_id, src.user_id, src.role);", q.String())
+	assert.Equal(t, []any{1, 2, "admin", 1, 3, "user"}, q.Args())
+
+	pg := xsql.Postgres.InsertInto("orgmember").
+		Set("org_id", 1).
+		Set("user_id", 2).
+		OnConflict().
+		DoNothing()
+	defer pg.Close()
+	assert.Equal(t, "INSERT INTO orgmember \n( org_id, user_id \n) VALUES ( $1, $2 \n) \nON CONFLICT \nDO NOTHING", pg.String())
+
+	my := xsql.MySQL.InsertInto("orgmember").
+		Set("org_id", 1).
+		Set("user_id", 2).
+		OnConflict().
+		DoNothing()
+	defer my.Close()
+	assert.Equal(t, "INSERT INTO orgmember \n( org_id, user_id \n) VALUES ( ?, ? \n) \nON DUPLICATE KEY UPDATE org_id = org_id", my.String())
+}
+
+func TestOnConflictExec(t *testing.T) {
+	forEveryDB(t, func(ctx context.Context, env *dbEnv) {
+		upsert := func(id int, name string) {
+			q := env.xsql.InsertInto("users").
+				Set("id", id).
+				Set("name", name).
+				OnConflict("id")
+			q.DoUpdateSet("name", q.Excluded("name"))
+			_, err := q.ExecAndClose(ctx, env.db)
+			require.NoError(t, err)
+		}
+		upsert(1, "User 1 updated")
+		upsert(10, "User 10")
+
+		q := env.xsql.InsertInto("users").
+			Set("id", 2).
+			Set("name", "User 2 updated").
+			OnConflict("id").
+			DoNothing()
+		_, err := q.ExecAndClose(ctx, env.db)
+		require.NoError(t, err)
+
+		var names []string
+		var name string
+		err = env.xsql.From("users").
+			Select("name").To(&name).
+			Where("id IN (1, 2, 10)").
+			OrderBy("id").
+			QueryAndClose(ctx, env.db, func(_ *sql.Rows) {
+				names = append(names, name)
+			})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"User 1 updated", "User 2", "User 10"}, names)
+	})
+}
@@ -19,3 +19,32 @@ func TestInsertAt(t *testing.T) {
 	a = insertAt([]any{1, 2}, []any{3}, 1)
 	require.Equal(t, a, []any{1, 3, 2})
 }
+
+func TestExplainPrefix(t *testing.T) {
+	tcases := []struct {
+		provider string
+		opts     *ExplainOptions
+		exp      string
+		err      string
+	}{
+		{provider: "postgres", exp: "EXPLAIN "},
+		{provider: "postgres", opts: &ExplainOptions{Analyze: true}, exp: "EXPLAIN (ANALYZE) "},
+		{provider: "postgres", opts: &ExplainOptions{Analyze: true, Verbose: true}, exp: "EXPLAIN (ANALYZE, VERBOSE) "},
+		{provider: "mysql", exp: "EXPLAIN FORMAT=TREE "},
+		{provider: "mysql", opts: &ExplainOptions{Analyze: true}, exp: "EXPLAIN ANALYZE "},
+		{provider: "default", exp: "EXPLAIN QUERY PLAN "},
+		{provider: "default", opts: &ExplainOptions{Analyze: true}, err: "explain analyze is not supported by default"},
+		{provider: "sqlite3", exp: "EXPLAIN QUERY PLAN "},
+		{provider: "sqlserver", err: "explain is not supported by sqlserver"},
+		{provider: "oracle", err: "explain is not supported by oracle"},
+	}
+	for _, tc := range tcases {
+		prefix, err := explainPrefix(tc.provider, tc.opts)
+		if tc.err != "" {
+			require.EqualError(t, err, tc.err)
+		} else {
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, prefix)
+		}
+	}
+}